@@ -1,6 +1,7 @@
 package gortsplib
 
 import (
+	"context"
 	"crypto/tls"
 	"net"
 	"time"
@@ -27,38 +28,107 @@ func DialPublish(address string, tracks Tracks) (*ClientConn, error) {
 	return DefaultClientConf.DialPublish(address, tracks)
 }
 
+// NewClientConnFromConn allocates a ClientConn that uses an existing connection.
+func NewClientConnFromConn(nconn net.Conn) *ClientConn {
+	return DefaultClientConf.NewClientConnFromConn(nconn)
+}
+
 // ClientConf allows to initialize a ClientConn.
 // All fields are optional.
 type ClientConf struct {
 	// the stream protocol (UDP or TCP).
-	// If nil, it is chosen automatically (first UDP, then, if it fails, TCP).
+	// If nil, it is chosen automatically: first UDP, then TCP if the server
+	// doesn't support UDP or if no packets are received within InitialUDPReadTimeout.
 	// It defaults to nil.
 	StreamProtocol *StreamProtocol
 
+	// when the stream protocol is chosen automatically, the period after which,
+	// if no UDP packets have been received, the client switches to TCP.
+	// It defaults to 3 seconds.
+	InitialUDPReadTimeout time.Duration
+
 	// a TLS configuration to connect to TLS (RTSPS) servers.
+	// Client certificates can be presented by setting Certificates.
 	// It defaults to &tls.Config{InsecureSkipVerify:true}
 	TLSConfig *tls.Config
 
+	// (optional) SHA256 fingerprint of the certificate of TLS (RTSPS) servers,
+	// in hex format, with or without colons.
+	// When set, the server certificate is accepted only if its fingerprint
+	// matches, allowing to connect to servers with self-signed certificates.
+	TLSFingerprint string
+
 	// disable being redirected to other servers, that can happen during Describe().
 	// It defaults to false.
 	RedirectDisable bool
 
+	// maximum number of consecutive redirects that are followed during Describe().
+	// It defaults to 10.
+	RedirectMaxCount int
+
 	// enable communication with servers which don't provide server ports.
+	// Packets are accepted from any port of the server until the first one is
+	// received, then the client locks onto its source port.
 	// this can be a security issue.
 	// It defaults to false.
 	AnyPortEnable bool
 
+	// disable the filter that discards RTP packets received with UDP whose SSRC
+	// is different from the one provided by the server or, if the server didn't
+	// provide it, from the one of the stream that is being received.
+	// It defaults to false.
+	SSRCFilterDisable bool
+
+	// when the stream protocol is chosen automatically, offer both UDP and TCP
+	// in the first SETUP request, and let the server choose, instead of
+	// switching to TCP after the server has rejected UDP.
+	// Some servers don't support multiple transports in a single request.
+	// It defaults to false.
+	SetupTransportAlternatives bool
+
+	// format of the transport mode inside SETUP requests, since some servers
+	// accept only a specific casing.
+	// It defaults to headers.TransportModeFormatLowercase (mode=play).
+	TransportModeFormat headers.TransportModeFormat
+
+	// request ONVIF back channels, that are tracks sent by the client to the server
+	// while reading (https://www.onvif.org/specs/stream/ONVIF-Streaming-Spec.pdf).
+	// It defaults to false.
+	BackChannelEnable bool
+
 	// timeout of read operations.
 	// It defaults to 10 seconds.
 	ReadTimeout time.Duration
 
+	// the period after which, while reading, if no RTP packets have been
+	// received, reading stops with a liberrors.ErrClientNoRTPPacketsRecently.
+	// It is independent from ReadTimeout, since some servers keep the
+	// control connection alive while the stream is stalled.
+	// It defaults to 0 (disabled).
+	NoRTPTimeout time.Duration
+
 	// timeout of write operations.
 	// It defaults to 10 seconds.
 	WriteTimeout time.Duration
 
+	// period of keepalive requests, that are sent to prevent the server
+	// from closing the session.
+	// If the server provides a session timeout, the period is
+	// reduced to half the timeout when needed.
+	// It defaults to 30 seconds.
+	KeepalivePeriod time.Duration
+
+	// method of keepalive requests (OPTIONS or GET_PARAMETER).
+	// It defaults to GET_PARAMETER if the server advertises it, OPTIONS otherwise.
+	KeepaliveMethod base.Method
+
 	// read buffer count.
 	// If greater than 1, allows to pass buffers to routines different than the one
-	// that is reading frames.
+	// that is reading frames. Furthermore, frames received with UDP are queued
+	// into a ring buffer of this size, and passed to the read callback by a
+	// dedicated routine, so that a slow callback doesn't block reading;
+	// when the queue is full, the oldest frames are discarded and counted
+	// by ClientConn.DroppedFrames().
 	// It defaults to 1.
 	ReadBufferCount int
 
@@ -67,16 +137,148 @@ type ClientConf struct {
 	// It defaults to 2048.
 	ReadBufferSize int
 
-	// callback called before every request.
+	// limits on the size of the responses and requests sent by the server.
+	// Reading fails with a base.ErrLimitExceeded when they are exceeded.
+	// Fields that are not set use the defaults of base.ReadLimits.
+	ReadLimits base.ReadLimits
+
+	// size of the buffer used to reorder RTP packets received with UDP, in packets.
+	// If greater than zero, RTP packets are passed to the read callback
+	// in sequence number order.
+	// It defaults to 0 (disabled), or to 64 if UDPReorderBufferDuration is set.
+	UDPReorderBufferSize int
+
+	// maximum time RTP packets received with UDP can wait in the reorder buffer
+	// for the missing ones. If greater than zero, RTP packets are reordered and
+	// packets that wait for longer are passed to the read callback,
+	// skipping the missing ones; this limits the latency added by reordering
+	// when packets are lost.
+	// It defaults to 0 (packets wait until the buffer is full).
+	UDPReorderBufferDuration time.Duration
+
+	// size of the kernel read buffer (SO_RCVBUF) of UDP sockets, in bytes.
+	// It must be increased when receiving high-bitrate streams with UDP.
+	// It defaults to 512KiB.
+	UDPReadBufferSize int
+
+	// disable sending dummy packets from the UDP ports of the client to the
+	// ones of the server after SETUP and PLAY, that open NAT mappings and firewalls
+	// in order to allow packets of the server to reach the client.
+	// It defaults to false.
+	UDPHolePunchingDisable bool
+
+	// number of dummy packets sent from each UDP port in order to open NAT mappings.
+	// It defaults to 1.
+	UDPHolePunchingCount int
+
+	// size of the kernel write buffer (SO_SNDBUF) of UDP sockets, in bytes.
+	// It defaults to 0 (operating system default).
+	UDPWriteBufferSize int
+
+	// maximum size of the payload of RTP packets.
+	// It must be decreased when the network has a small MTU, like with VPNs and tunnels,
+	// and can be increased on networks that support jumbo frames.
+	// Encoders can be configured with ClientConn.PayloadMaxSize().
+	// It defaults to 1460, that is suitable for a 1500 bytes MTU.
+	PayloadMaxSize int
+
+	// function used to retrieve the credentials when the server requires
+	// authentication and the URL doesn't contain any.
+	// It defaults to nil.
+	AuthCredentials func(u *base.URL) (string, string)
+
+	// (optional) URL of a RTSP proxy, in the format rtsp://[user:pass@]host[:port].
+	// If set, the connection is opened with the proxy, and requests, that
+	// always contain the absolute URL of the server, are forwarded by it.
+	// Credentials of the URL are used to answer Proxy-Authenticate challenges.
+	// Via headers added by the proxy are available in responses.
+	Proxy *base.URL
+
+	// headers that are added to every request, unless the request
+	// already contains them. It can be used to set the User-Agent or
+	// vendor-specific headers required by some servers.
+	// It defaults to nil.
+	DefaultHeader base.Header
+
+	// callback called before sending every request, and after receiving
+	// a request from the server.
+	// It can be used to log RTSP exchanges or to edit requests.
 	OnRequest func(req *base.Request)
 
-	// callback called after very response.
+	// callback called after receiving every response, and before sending
+	// a response to a request of the server.
+	// It can be used to log RTSP exchanges or to edit responses.
 	OnResponse func(res *base.Response)
 
+	// callback called when the server sends a REDIRECT request while
+	// reading or publishing. The connection is then closed with ErrClientRedirected,
+	// and the caller can connect to the new location.
+	OnRedirect func(location *base.URL)
+
+	// callback called when the server sends an ANNOUNCE request while
+	// reading or publishing, in order to notify a change of the stream
+	// or its end.
+	OnAnnounce func(req *base.Request)
+
+	// disable the detection of the end of the stream through RTCP BYE packets.
+	// It defaults to false.
+	EndOfStreamDisable bool
+
+	// callback called when the server has sent a RTCP BYE packet for every
+	// track that is being read, that means that the stream has ended.
+	// BYE packets are taken into account only when they refer to the source
+	// that is sending the track.
+	// Reading then stops with liberrors.ErrClientEndOfStream.
+	OnEndOfStream func()
+
+	// callback called when a UDP packet is discarded since it comes from an
+	// unexpected address, since it can't be decrypted with SRTP or, in case of
+	// RTP packets, since its SSRC is different from the one provided by the server
+	// or from the one of the stream that is being received.
+	// It can be called by multiple routines.
+	OnUDPPacketDiscarded func(err error)
+
+	// decode the SDP returned by DESCRIBE leniently, in order to support
+	// non-compliant cameras: lines that can't be decoded are skipped instead
+	// of causing an error, and are reported through OnSDPWarning.
+	// It defaults to false.
+	LenientSDPEnable bool
+
+	// callback called when the SDP returned by DESCRIBE contains lines that
+	// can't be decoded and LenientSDPEnable is true.
+	OnSDPWarning func(warning string)
+
+	// (optional) logger that receives messages about events that are
+	// handled without closing the connection, like discarded packets.
+	// It can be called by multiple routines.
+	Logger Logger
+
 	// function used to initialize the TCP client.
 	// It defaults to net.DialTimeout.
 	DialTimeout func(network, address string, timeout time.Duration) (net.Conn, error)
 
+	// function used to initialize the TCP client, that allows to route
+	// the connection through proxies or tunnels.
+	// The context expires after ReadTimeout.
+	// If set, it is used in place of DialTimeout.
+	// It defaults to nil.
+	DialContext func(ctx context.Context, network, address string) (net.Conn, error)
+
+	// (optional) function used to resolve host names.
+	// When it returns multiple addresses, they are tried as described in
+	// RFC 8305 (happy eyeballs): IPv6 and IPv4 addresses are interleaved,
+	// and a new connection attempt is started every HappyEyeballsDelay,
+	// or as soon as the previous attempt fails.
+	// It can be set to net.DefaultResolver.LookupHost.
+	// It defaults to nil, in which case host names are resolved by
+	// DialTimeout or DialContext.
+	LookupHost func(ctx context.Context, host string) ([]string, error)
+
+	// delay between connection attempts when LookupHost returns
+	// multiple addresses.
+	// It defaults to 250ms.
+	HappyEyeballsDelay time.Duration
+
 	// function used to initialize UDP listeners.
 	// It defaults to net.ListenPacket.
 	ListenPacket func(network, address string) (net.PacketConn, error)
@@ -87,6 +289,16 @@ func (c ClientConf) Dial(scheme string, host string) (*ClientConn, error) {
 	return newClientConn(c, scheme, host)
 }
 
+// NewClientConnFromConn allocates a ClientConn that uses an existing connection,
+// that can be routed through proxies or tunnels, or be a in-memory pipe.
+// TLS, if needed, must be already set up on the connection.
+// The stream protocol defaults to TCP, since the connection can't be reopened
+// in order to switch protocol; UDP can be used only if it is set explicitly
+// and the connection is a TCP one.
+func (c ClientConf) NewClientConnFromConn(nconn net.Conn) *ClientConn {
+	return newClientConnFromConn(c, nconn)
+}
+
 // DialRead connects to the address and starts reading all tracks.
 func (c ClientConf) DialRead(address string) (*ClientConn, error) {
 	u, err := base.ParseURL(address)
@@ -112,14 +324,19 @@ func (c ClientConf) DialRead(address string) (*ClientConn, error) {
 	}
 
 	for _, track := range tracks {
-		_, err := conn.Setup(headers.TransportModePlay, track, 0, 0)
+		mode := headers.TransportModePlay
+		if track.IsBackChannel() {
+			mode = headers.TransportModeRecord
+		}
+
+		_, err := conn.Setup(mode, track, 0, 0)
 		if err != nil {
 			conn.Close()
 			return nil, err
 		}
 	}
 
-	_, err = conn.Play()
+	_, err = conn.Play(nil)
 	if err != nil {
 		conn.Close()
 		return nil, err
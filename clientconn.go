@@ -9,10 +9,13 @@ package gortsplib
 
 import (
 	"bufio"
+	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"math/rand"
 	"net"
+	"net/url"
 	"strconv"
 	"strings"
 	"sync"
@@ -23,8 +26,11 @@ import (
 	"github.com/majoyz/gortsplib/pkg/headers"
 	"github.com/majoyz/gortsplib/pkg/liberrors"
 	"github.com/majoyz/gortsplib/pkg/multibuffer"
+	"github.com/majoyz/gortsplib/pkg/rtcpcompound"
 	"github.com/majoyz/gortsplib/pkg/rtcpreceiver"
 	"github.com/majoyz/gortsplib/pkg/rtcpsender"
+	"github.com/majoyz/gortsplib/pkg/sdp"
+	"github.com/majoyz/gortsplib/pkg/srtp"
 )
 
 const (
@@ -33,9 +39,17 @@ const (
 	clientConnReceiverReportPeriod = 10 * time.Second
 	clientConnSenderReportPeriod   = 10 * time.Second
 	clientConnUDPCheckStreamPeriod = 5 * time.Second
-	clientConnUDPKeepalivePeriod   = 30 * time.Second
+	clientConnNoRTPCheckPeriod     = 1 * time.Second
+	clientConnBackChannelRequire   = "www.onvif.org/ver20/backchannel"
+
+	// requests sent by the server are rare, a small queue is enough
+	clientConnServerRequestQueueSize = 8
 )
 
+// errClientSwitchToTCP is returned by backgroundPlayUDP when no packets
+// have been received and the stream protocol must be switched to TCP.
+var errClientSwitchToTCP = errors.New("switching to TCP")
+
 type clientConnState int
 
 const (
@@ -65,27 +79,39 @@ func (s clientConnState) String() string {
 // ClientConn is a client-side RTSP connection.
 type ClientConn struct {
 	conf                  ClientConf
+	host                  string
 	nconn                 net.Conn
+	nconnProvided         bool
 	isTLS                 bool
 	br                    *bufio.Reader
 	bw                    *bufio.Writer
-	session               string
+	session               *headers.Session
 	cseq                  int
 	sender                *auth.Sender
+	proxySender           *auth.Sender
 	state                 clientConnState
+	baseURL               *base.URL
 	streamURL             *base.URL
 	streamProtocol        *StreamProtocol
 	tracks                Tracks
 	udpRTPListeners       map[int]*clientConnUDPListener
 	udpRTCPListeners      map[int]*clientConnUDPListener
-	getParameterSupported bool
+	srtpContexts          map[int]*srtp.Context
+	publicMethods         map[base.Method]struct{}
 
 	// read only
+	playRange         *headers.Range
+	udpFramesReceived int32
+	droppedFrames     uint64
 	rtpInfo           *headers.RTPInfo
+	trackRTPInfos     map[int]*headers.RTPInfoEntry
 	rtcpReceivers     map[int]*rtcpreceiver.RTCPReceiver
+	rtpReorderers     map[int]*udpReorderer
 	udpLastFrameTimes map[int]*int64
+	lastRTPTime       *int64
 	tcpFrameBuffer    *multibuffer.MultiBuffer
 	readCB            func(int, StreamType, []byte)
+	byeRecv           chan int
 
 	// publish only
 	rtcpSenders       map[int]*rtcpsender.RTCPSender
@@ -101,82 +127,328 @@ type ClientConn struct {
 }
 
 func newClientConn(conf ClientConf, scheme string, host string) (*ClientConn, error) {
+	if scheme != "rtsp" && scheme != "rtsps" {
+		return nil, liberrors.ErrClientUnsupportedScheme{Scheme: scheme}
+	}
+
+	if scheme == "rtsps" && conf.StreamProtocol != nil &&
+		*conf.StreamProtocol == StreamProtocolUDP {
+		return nil, liberrors.ErrClientRTSPSAndUDP{}
+	}
+
+	// add the default port of the scheme
+	host = (&base.URL{Scheme: scheme, Host: host}).HostWithPort()
+
+	c := newClientConnBase(conf)
+	c.host = host
+	c.isTLS = (scheme == "rtsps")
+
+	// connect to the proxy, with the TLS setting of the proxy
+	if conf.Proxy != nil {
+		c.host = conf.Proxy.HostWithPort()
+		c.isTLS = conf.Proxy.IsSecure()
+	}
+
+	err := c.connOpen(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func newClientConnFromConn(conf ClientConf, nconn net.Conn) *ClientConn {
+	c := newClientConnBase(conf)
+	c.nconn = nconn
+	c.nconnProvided = true
+	c.br = bufio.NewReaderSize(nconn, clientConnReadBufferSize)
+	c.bw = bufio.NewWriterSize(nconn, clientConnWriteBufferSize)
+	return c
+}
+
+func newClientConnBase(conf ClientConf) *ClientConn {
 	if conf.TLSConfig == nil {
 		conf.TLSConfig = &tls.Config{InsecureSkipVerify: true}
 	}
+	if conf.TLSFingerprint != "" {
+		conf.TLSConfig = tlsConfigWithFingerprint(conf.TLSConfig, conf.TLSFingerprint)
+	}
 	if conf.ReadTimeout == 0 {
 		conf.ReadTimeout = 10 * time.Second
 	}
+	if conf.RedirectMaxCount == 0 {
+		conf.RedirectMaxCount = 10
+	}
 	if conf.WriteTimeout == 0 {
 		conf.WriteTimeout = 10 * time.Second
 	}
+	if conf.InitialUDPReadTimeout == 0 {
+		conf.InitialUDPReadTimeout = 3 * time.Second
+	}
+	if conf.KeepalivePeriod == 0 {
+		conf.KeepalivePeriod = 30 * time.Second
+	}
 	if conf.ReadBufferCount == 0 {
 		conf.ReadBufferCount = 1
 	}
 	if conf.ReadBufferSize == 0 {
 		conf.ReadBufferSize = 2048
 	}
+	if conf.UDPReadBufferSize == 0 {
+		conf.UDPReadBufferSize = udpKernelReadBufferSize
+	}
+	if conf.UDPHolePunchingCount == 0 {
+		conf.UDPHolePunchingCount = 1
+	}
+	if conf.PayloadMaxSize == 0 {
+		conf.PayloadMaxSize = 1460 // 1500 (mtu) - 20 (ip header) - 8 (udp header) - 12 (rtp header)
+	}
 	if conf.DialTimeout == nil {
 		conf.DialTimeout = net.DialTimeout
 	}
+	if conf.HappyEyeballsDelay == 0 {
+		conf.HappyEyeballsDelay = 250 * time.Millisecond
+	}
+	if conf.Logger == nil {
+		conf.Logger = nilLogger{}
+	}
 	if conf.ListenPacket == nil {
 		conf.ListenPacket = net.ListenPacket
 	}
 
-	if scheme != "rtsp" && scheme != "rtsps" {
-		return nil, fmt.Errorf("unsupported scheme '%s'", scheme)
-	}
-
-	v := StreamProtocolUDP
-	if scheme == "rtsps" && conf.StreamProtocol == &v {
-		return nil, fmt.Errorf("RTSPS can't be used with UDP")
+	return &ClientConn{
+		conf:              conf,
+		udpRTPListeners:   make(map[int]*clientConnUDPListener),
+		udpRTCPListeners:  make(map[int]*clientConnUDPListener),
+		srtpContexts:      make(map[int]*srtp.Context),
+		rtcpReceivers:     make(map[int]*rtcpreceiver.RTCPReceiver),
+		trackRTPInfos:     make(map[int]*headers.RTPInfoEntry),
+		rtpReorderers:     make(map[int]*udpReorderer),
+		udpLastFrameTimes: make(map[int]*int64),
+		lastRTPTime:       new(int64),
+		tcpFrameBuffer:    multibuffer.New(uint64(conf.ReadBufferCount), uint64(conf.ReadBufferSize)),
+		rtcpSenders:       make(map[int]*rtcpsender.RTCPSender),
+		publishError:      liberrors.ErrClientNotRunning{},
 	}
+}
 
-	if !strings.Contains(host, ":") {
-		host += ":554"
+func (c *ClientConn) connOpen(ctx context.Context) error {
+	if c.nconnProvided {
+		return liberrors.ErrClientConnProvided{}
 	}
 
-	nconn, err := conf.DialTimeout("tcp", host, conf.ReadTimeout)
+	nconn, err := c.dial(ctx)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	conn := func() net.Conn {
-		if scheme == "rtsps" {
-			return tls.Client(nconn, conf.TLSConfig)
+		if c.isTLS {
+			return tls.Client(nconn, c.conf.TLSConfig)
 		}
 		return nconn
 	}()
 
-	return &ClientConn{
-		conf:              conf,
-		nconn:             nconn,
-		isTLS:             (scheme == "rtsps"),
-		br:                bufio.NewReaderSize(conn, clientConnReadBufferSize),
-		bw:                bufio.NewWriterSize(conn, clientConnWriteBufferSize),
-		udpRTPListeners:   make(map[int]*clientConnUDPListener),
-		udpRTCPListeners:  make(map[int]*clientConnUDPListener),
-		rtcpReceivers:     make(map[int]*rtcpreceiver.RTCPReceiver),
-		udpLastFrameTimes: make(map[int]*int64),
-		tcpFrameBuffer:    multibuffer.New(uint64(conf.ReadBufferCount), uint64(conf.ReadBufferSize)),
-		rtcpSenders:       make(map[int]*rtcpsender.RTCPSender),
-		publishError:      fmt.Errorf("not running"),
-	}, nil
+	c.nconn = nconn
+	c.br = bufio.NewReaderSize(conn, clientConnReadBufferSize)
+	c.bw = bufio.NewWriterSize(conn, clientConnWriteBufferSize)
+	return nil
+}
+
+func (c *ClientConn) dial(ctx context.Context) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.conf.ReadTimeout)
+	defer cancel()
+
+	dial := func(ctx context.Context, address string) (net.Conn, error) {
+		if c.conf.DialContext != nil {
+			return c.conf.DialContext(ctx, "tcp", address)
+		}
+		return c.conf.DialTimeout("tcp", address, c.conf.ReadTimeout)
+	}
+
+	if c.conf.LookupHost == nil {
+		return dial(ctx, c.host)
+	}
+
+	hostname, port, err := net.SplitHostPort(c.host)
+	if err != nil {
+		return nil, err
+	}
+
+	// IP addresses don't need to be resolved
+	if net.ParseIP(hostname) != nil {
+		return dial(ctx, c.host)
+	}
+
+	addrs, err := c.conf.LookupHost(ctx, hostname)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(addrs) == 0 {
+		return nil, liberrors.ErrClientHostNotResolved{Host: hostname}
+	}
+
+	addrs = sortAddresses(addrs)
+	for i, addr := range addrs {
+		addrs[i] = net.JoinHostPort(addr, port)
+	}
+
+	return dialHappyEyeballs(ctx, addrs, c.conf.HappyEyeballsDelay, dial)
 }
 
 // Close closes all the ClientConn resources.
+// If a session is active, it is closed with Teardown() before closing the connection,
+// in order to prevent the server from keeping it alive.
 func (c *ClientConn) Close() error {
-	if c.state == clientConnStatePlay || c.state == clientConnStateRecord {
-		close(c.backgroundTerminate)
-		<-c.backgroundDone
+	if c.backgroundTerminate != nil || c.state != clientConnStateInitial {
+		c.Teardown()
+	}
+
+	err := c.nconn.Close()
+	return err
+}
+
+// Teardown writes a TEARDOWN request and reads a Response.
+// It stops reading or publishing, closes UDP listeners and resets the
+// state of the connection, therefore tracks can be setupped again.
+// The response is awaited for at most ReadTimeout.
+// This can be called only after Setup().
+func (c *ClientConn) Teardown() (*base.Response, error) {
+	backgroundStopped := c.backgroundStop()
 
-		c.Do(&base.Request{
-			Method:       base.Teardown,
-			URL:          c.streamURL,
-			SkipResponse: true,
-		})
+	err := c.checkState(map[clientConnState]struct{}{
+		clientConnStatePrePlay:   {},
+		clientConnStatePlay:      {},
+		clientConnStatePreRecord: {},
+		clientConnStateRecord:    {},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if backgroundStopped {
+		c.writeBye()
 	}
 
+	res, err := c.Do(&base.Request{
+		Method: base.Teardown,
+		URL:    c.streamURL,
+	})
+
+	c.reset()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != base.StatusOK {
+		return res, liberrors.ErrClientWrongStatusCode{Code: res.StatusCode, Message: res.StatusMessage}
+	}
+
+	return res, nil
+}
+
+// backgroundStop stops the routine started by ReadFrames() or Record(),
+// and returns false if it was not started.
+// The state must be read only after the routine has been stopped,
+// since the routine changes it when switching protocol.
+func (c *ClientConn) backgroundStop() bool {
+	if c.backgroundTerminate == nil {
+		return false
+	}
+
+	close(c.backgroundTerminate)
+	<-c.backgroundDone
+	c.backgroundTerminate = nil
+	return true
+}
+
+// writeBye notifies the server that the sources of the session are leaving,
+// by sending a RTCP BYE packet for each track.
+func (c *ClientConn) writeBye() {
+	cname, _, _ := net.SplitHostPort(c.nconn.LocalAddr().String())
+
+	for _, track := range c.tracks {
+		var ssrc uint32
+		if rs, ok := c.rtcpSenders[track.ID]; ok {
+			v, ok := rs.SenderSSRC()
+			if !ok {
+				continue
+			}
+			ssrc = v
+		} else if rr, ok := c.rtcpReceivers[track.ID]; ok {
+			ssrc = rr.ReceiverSSRC()
+		} else {
+			continue
+		}
+
+		byts, err := rtcpcompound.Bye(ssrc, cname, "teardown")
+		if err != nil {
+			continue
+		}
+
+		byts, err = srtpEncryptFrame(c.srtpContexts[track.ID], StreamTypeRTCP, byts)
+		if err != nil {
+			c.conf.Logger.Warnf("RTCP packet of track %d discarded: %s", track.ID, err)
+			continue
+		}
+
+		if *c.streamProtocol == StreamProtocolUDP {
+			c.udpRTCPListeners[track.ID].write(byts)
+			continue
+		}
+
+		c.nconn.SetWriteDeadline(time.Now().Add(c.conf.WriteTimeout))
+		frame := base.InterleavedFrame{
+			TrackID:    track.ID,
+			StreamType: StreamTypeRTCP,
+			Payload:    byts,
+		}
+		frame.Write(c.bw)
+	}
+}
+
+// onBye is called when a RTCP packet is received. If the packet contains
+// a BYE of the source that is sending the track, the track is reported as ended
+// to the background routine. BYE packets received before any RTP packet
+// can't be matched with a source and are always taken into account.
+func (c *ClientConn) onBye(trackID int, payload []byte) {
+	if c.conf.EndOfStreamDisable {
+		return
+	}
+
+	pkts, err := rtcpcompound.Unmarshal(payload)
+	if err != nil {
+		return
+	}
+
+	sources := rtcpcompound.ByeSources(pkts)
+	if len(sources) == 0 {
+		return
+	}
+
+	if ssrc, ok := c.rtcpReceivers[trackID].SenderSSRC(); ok {
+		found := false
+		for _, source := range sources {
+			if source == ssrc {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return
+		}
+	}
+
+	select {
+	case c.byeRecv <- trackID:
+	default:
+	}
+}
+
+// reset closes UDP listeners and clears the session state.
+func (c *ClientConn) reset() {
 	for _, l := range c.udpRTPListeners {
 		l.close()
 	}
@@ -185,8 +457,21 @@ func (c *ClientConn) Close() error {
 		l.close()
 	}
 
-	err := c.nconn.Close()
-	return err
+	c.state = clientConnStateInitial
+	c.session = nil
+	c.streamURL = nil
+	c.streamProtocol = nil
+	c.tracks = nil
+	c.udpRTPListeners = make(map[int]*clientConnUDPListener)
+	c.udpRTCPListeners = make(map[int]*clientConnUDPListener)
+	c.srtpContexts = make(map[int]*srtp.Context)
+	c.playRange = nil
+	c.rtpInfo = nil
+	c.trackRTPInfos = make(map[int]*headers.RTPInfoEntry)
+	c.rtcpReceivers = make(map[int]*rtcpreceiver.RTCPReceiver)
+	c.rtpReorderers = make(map[int]*udpReorderer)
+	c.udpLastFrameTimes = make(map[int]*int64)
+	c.rtcpSenders = make(map[int]*rtcpsender.RTCPSender)
 }
 
 func (c *ClientConn) checkState(allowed map[clientConnState]struct{}) error {
@@ -208,6 +493,87 @@ func (c *ClientConn) NetConn() net.Conn {
 	return c.nconn
 }
 
+// UDPBufferSizes returns the kernel read and write buffer sizes of the UDP
+// socket of a track, as reported by the operating system.
+// Some operating systems (like Linux) report twice the configured size.
+func (c *ClientConn) UDPBufferSizes(trackID int, streamType StreamType) (int, int, error) {
+	var l *clientConnUDPListener
+	if streamType == StreamTypeRTP {
+		l = c.udpRTPListeners[trackID]
+	} else {
+		l = c.udpRTCPListeners[trackID]
+	}
+
+	if l == nil {
+		return 0, 0, liberrors.ErrClientTrackNotSetupWithUDP{TrackID: trackID}
+	}
+
+	return udpBufferSizes(l.pc.(*net.UDPConn))
+}
+
+// PayloadMaxSize returns the maximum size of the payload of RTP packets
+// of a track, that encoders must be configured with.
+// It is equal to ClientConf.PayloadMaxSize, minus the space taken by
+// encryption when the track uses SRTP.
+func (c *ClientConn) PayloadMaxSize(trackID int) int {
+	ret := c.conf.PayloadMaxSize
+	if _, ok := c.srtpContexts[trackID]; ok {
+		ret -= srtp.RTPOverhead
+	}
+	return ret
+}
+
+// PacketNTPTime returns the absolute time of a RTP packet of a track that is
+// being read, computed by using the RTCP sender reports sent by the server.
+// It returns false if no sender reports have been received yet.
+func (c *ClientConn) PacketNTPTime(trackID int, rtpTimestamp uint32) (time.Time, bool) {
+	rr, ok := c.rtcpReceivers[trackID]
+	if !ok {
+		return time.Time{}, false
+	}
+	return rr.PacketNTPTime(rtpTimestamp)
+}
+
+// Session returns the Session header sent by the server, that contains
+// the session ID and the session timeout, or nil if no session is active.
+// The session ID is attached automatically to all requests.
+func (c *ClientConn) Session() *headers.Session {
+	return c.session
+}
+
+// keepalivePeriod returns the period of keepalive requests, that is
+// KeepalivePeriod or half the session timeout, if shorter.
+func (c *ClientConn) keepalivePeriod() time.Duration {
+	period := c.conf.KeepalivePeriod
+
+	if c.session != nil && c.session.Timeout != nil && *c.session.Timeout > 0 {
+		v := time.Duration(*c.session.Timeout) * time.Second / 2
+		if v < period {
+			period = v
+		}
+	}
+
+	return period
+}
+
+// StreamProtocol returns the stream protocol of the setupped tracks.
+// When the protocol is chosen automatically, it can switch from UDP to TCP
+// after ReadFrames() has been called.
+func (c *ClientConn) StreamProtocol() *StreamProtocol {
+	c.publishWriteMutex.RLock()
+	defer c.publishWriteMutex.RUnlock()
+	return c.streamProtocol
+}
+
+// BaseURL returns the URL against which the control attributes of the tracks
+// are resolved, that is the Content-Base or the Content-Location header of the
+// last DESCRIBE response or, if they are not provided, the DESCRIBE URL.
+// SETUP and PLAY requests are sent to URLs derived from it.
+// It returns nil if Describe() has not been called.
+func (c *ClientConn) BaseURL() *base.URL {
+	return c.baseURL
+}
+
 // Tracks returns all the tracks that the connection is reading or publishing.
 func (c *ClientConn) Tracks() Tracks {
 	return c.tracks
@@ -215,14 +581,98 @@ func (c *ClientConn) Tracks() Tracks {
 
 // Do writes a Request and reads a Response.
 // Interleaved frames received before the response are ignored.
+// If the server requires authentication, the request is sent again
+// with the credentials contained in the URL or provided by AuthCredentials.
 func (c *ClientConn) Do(req *base.Request) (*base.Response, error) {
+	return c.DoContext(context.Background(), req)
+}
+
+// DoContext is like Do, but the request can be canceled through a context.
+// After a cancellation, the connection is in an undefined state and must be closed.
+func (c *ClientConn) DoContext(ctx context.Context, req *base.Request) (*base.Response, error) {
+	stop := watchContext(ctx, c.nconn)
+	res, err := c.do(req, false, false)
+	stop()
+
+	if err != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	return res, err
+}
+
+func (c *ClientConn) credentials(u *base.URL) (string, string, bool) {
+	if user, pass, ok := u.Credentials(); ok {
+		return user, pass, true
+	}
+
+	if c.conf.AuthCredentials != nil {
+		user, pass := c.conf.AuthCredentials(u)
+		if user != "" {
+			return user, pass, true
+		}
+	}
+
+	return "", "", false
+}
+
+func (c *ClientConn) do(req *base.Request, authRetried bool, proxyAuthRetried bool) (*base.Response, error) {
+	err := c.writeRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.SkipResponse {
+		return nil, nil
+	}
+
+	res, err := c.readResponse()
+	if err != nil {
+		return nil, err
+	}
+
+	// setup authentication.
+	// This is performed even if a sender is already present, since servers
+	// can renew the nonce; in that case, the request is sent again only once.
+	if res.StatusCode == base.StatusUnauthorized && !authRetried {
+		if user, pass, ok := c.credentials(req.URL); ok {
+			sender, err := auth.NewSender(res.Header["WWW-Authenticate"], user, pass)
+			if err != nil {
+				return nil, liberrors.ErrClientAuthSetup{Err: err}
+			}
+			c.sender = sender
+
+			// send request again
+			return c.do(req, true, proxyAuthRetried)
+		}
+	}
+
+	// setup proxy authentication, in the same way.
+	if res.StatusCode == base.StatusProxyAuthRequired && !proxyAuthRetried && c.conf.Proxy != nil {
+		if user, pass, ok := c.conf.Proxy.Credentials(); ok {
+			sender, err := auth.NewSender(res.Header["Proxy-Authenticate"], user, pass)
+			if err != nil {
+				return nil, liberrors.ErrClientAuthSetup{Err: err}
+			}
+			c.proxySender = sender
+
+			// send request again
+			return c.do(req, authRetried, true)
+		}
+	}
+
+	return res, nil
+}
+
+// writeRequest fills the headers of a request and writes it.
+func (c *ClientConn) writeRequest(req *base.Request) error {
 	if req.Header == nil {
 		req.Header = make(base.Header)
 	}
 
 	// add session
-	if c.session != "" {
-		req.Header["Session"] = base.HeaderValue{c.session}
+	if c.session != nil {
+		req.Header["Session"] = base.HeaderValue{c.session.Session}
 	}
 
 	// add auth
@@ -230,34 +680,52 @@ func (c *ClientConn) Do(req *base.Request) (*base.Response, error) {
 		req.Header["Authorization"] = c.sender.GenerateHeader(req.Method, req.URL)
 	}
 
+	// add proxy auth
+	if c.proxySender != nil {
+		req.Header["Proxy-Authorization"] = c.proxySender.GenerateHeader(req.Method, req.URL)
+	}
+
 	// add cseq
 	c.cseq++
 	req.Header["CSeq"] = base.HeaderValue{strconv.FormatInt(int64(c.cseq), 10)}
 
+	// add default headers
+	for k, v := range c.conf.DefaultHeader {
+		if _, ok := req.Header[k]; !ok {
+			req.Header[k] = append(base.HeaderValue(nil), v...)
+		}
+	}
+
 	// add user agent
-	req.Header["User-Agent"] = base.HeaderValue{"gortsplib"}
+	if _, ok := req.Header["User-Agent"]; !ok {
+		req.Header["User-Agent"] = base.HeaderValue{"gortsplib"}
+	}
+
+	// request back channels
+	if c.conf.BackChannelEnable && (req.Method == base.Describe ||
+		req.Method == base.Setup || req.Method == base.Play) {
+		if !headerContains(req.Header["Require"], clientConnBackChannelRequire) {
+			req.Header["Require"] = append(req.Header["Require"], clientConnBackChannelRequire)
+		}
+	}
 
 	if c.conf.OnRequest != nil {
 		c.conf.OnRequest(req)
 	}
 
 	c.nconn.SetWriteDeadline(time.Now().Add(c.conf.WriteTimeout))
-	err := req.Write(c.bw)
-	if err != nil {
-		return nil, err
-	}
-
-	if req.SkipResponse {
-		return nil, nil
-	}
+	return req.Write(c.bw)
+}
 
+// readResponse reads a response and updates the session.
+func (c *ClientConn) readResponse() (*base.Response, error) {
 	// read the response and ignore interleaved frames in between;
 	// interleaved frames are sent in two situations:
 	// * when the server is v4lrtspserver, before the PLAY response
 	// * when the stream is already playing
-	var res base.Response
+	res := base.Response{Limits: &c.conf.ReadLimits}
 	c.nconn.SetReadDeadline(time.Now().Add(c.conf.ReadTimeout))
-	err = res.ReadIgnoreFrames(c.br, c.tcpFrameBuffer.Next())
+	err := res.ReadIgnoreFrames(c.br, c.tcpFrameBuffer.Next())
 	if err != nil {
 		return nil, err
 	}
@@ -273,25 +741,102 @@ func (c *ClientConn) Do(req *base.Request) (*base.Response, error) {
 		if err != nil {
 			return nil, liberrors.ErrClientSessionHeaderInvalid{Err: err}
 		}
-		c.session = sx.Session
+		c.session = &sx
 	}
 
-	// setup authentication
-	if res.StatusCode == base.StatusUnauthorized && req.URL.User != nil && c.sender == nil {
-		pass, _ := req.URL.User.Password()
-		user := req.URL.User.Username()
+	return &res, nil
+}
 
-		sender, err := auth.NewSender(res.Header["WWW-Authenticate"], user, pass)
-		if err != nil {
-			return nil, fmt.Errorf("unable to setup authentication: %s", err)
+// keepalive sends a keepalive request without reading the response.
+func (c *ClientConn) keepalive() error {
+	_, err := c.Do(&base.Request{
+		Method: func() base.Method {
+			if c.conf.KeepaliveMethod != "" {
+				return c.conf.KeepaliveMethod
+			}
+
+			// the vlc integrated rtsp server requires GET_PARAMETER
+			if _, ok := c.publicMethods[base.GetParameter]; ok {
+				return base.GetParameter
+			}
+			return base.Options
+		}(),
+		// use the stream path, otherwise some cameras do not reply
+		URL:          c.streamURL,
+		SkipResponse: true,
+	})
+	return err
+}
+
+// queueServerRequest passes a request sent by the server to the background routine.
+// The request is discarded if the queue is full, in order not to block reading.
+func (c *ClientConn) queueServerRequest(queue chan base.Request, req base.Request) {
+	select {
+	case queue <- req:
+	default:
+		c.conf.Logger.Warnf("%s request of the server discarded, queue is full", req.Method)
+	}
+}
+
+// handleServerRequest answers a request sent by the server.
+// It returns an error when the connection must be closed.
+func (c *ClientConn) handleServerRequest(req *base.Request) error {
+	if c.conf.OnRequest != nil {
+		c.conf.OnRequest(req)
+	}
+
+	res := base.Response{
+		StatusCode: base.StatusOK,
+		Header: base.Header{
+			"CSeq": req.Header["CSeq"],
+		},
+	}
+
+	var returnErr error
+
+	switch req.Method {
+	case base.Redirect:
+		var location *base.URL
+		if v, ok := req.Header["Location"]; ok && len(v) == 1 {
+			location, _ = base.ParseURL(v[0])
+		}
+
+		if location == nil {
+			res.StatusCode = base.StatusBadRequest
+			break
+		}
+
+		if c.conf.OnRedirect != nil {
+			c.conf.OnRedirect(location)
+		}
+		returnErr = liberrors.ErrClientRedirected{Location: location}
+
+	case base.Options:
+		res.Header["Public"] = base.HeaderValue{strings.Join([]string{
+			string(base.Options),
+			string(base.Announce),
+			string(base.Redirect),
+		}, ", ")}
+
+	case base.Announce:
+		if c.conf.OnAnnounce != nil {
+			c.conf.OnAnnounce(req)
 		}
-		c.sender = sender
 
-		// send request again
-		return c.Do(req)
+	default:
+		res.StatusCode = base.StatusNotImplemented
 	}
 
-	return &res, nil
+	if c.conf.OnResponse != nil {
+		c.conf.OnResponse(&res)
+	}
+
+	c.publishWriteMutex.Lock()
+	c.nconn.SetWriteDeadline(time.Now().Add(c.conf.WriteTimeout))
+	res.Write(c.bw)
+	c.publishWriteMutex.Unlock()
+
+	return returnErr
 }
 
 // Options writes an OPTIONS request and reads a response.
@@ -322,43 +867,125 @@ func (c *ClientConn) Options(u *base.URL) (*base.Response, error) {
 		return res, liberrors.ErrClientWrongStatusCode{Code: res.StatusCode, Message: res.StatusMessage}
 	}
 
-	c.getParameterSupported = func() bool {
-		pub, ok := res.Header["Public"]
-		if !ok || len(pub) != 1 {
-			return false
-		}
+	c.publicMethods = nil
 
-		for _, m := range strings.Split(pub[0], ",") {
-			if base.Method(m) == base.GetParameter {
-				return true
+	if pub, ok := res.Header["Public"]; ok {
+		c.publicMethods = make(map[base.Method]struct{})
+
+		for _, v := range pub {
+			for _, m := range strings.Split(v, ",") {
+				m = strings.TrimSpace(m)
+				if m != "" {
+					c.publicMethods[base.Method(m)] = struct{}{}
+				}
 			}
 		}
-		return false
-	}()
+	}
 
 	return res, nil
 }
 
+// PublicMethods returns the methods advertised by the server in the Public
+// header of the OPTIONS response.
+// It returns nil if Options() has not been called or if the server
+// didn't provide the Public header.
+func (c *ClientConn) PublicMethods() map[base.Method]struct{} {
+	return c.publicMethods
+}
+
+// checkMethodSupported returns an error if the server has advertised
+// its methods and the given one is not among them.
+func (c *ClientConn) checkMethodSupported(method base.Method) error {
+	if c.publicMethods == nil {
+		return nil
+	}
+
+	if _, ok := c.publicMethods[method]; !ok {
+		return liberrors.ErrClientMethodNotSupported{Method: method}
+	}
+
+	return nil
+}
+
 // Describe writes a DESCRIBE request and reads a Response.
 func (c *ClientConn) Describe(u *base.URL) (Tracks, *base.Response, error) {
+	return c.DescribeContext(context.Background(), u)
+}
+
+// DescribeContext is like Describe, but the request can be canceled through a context.
+func (c *ClientConn) DescribeContext(ctx context.Context, u *base.URL) (Tracks, *base.Response, error) {
+	dres, err := c.DescribeFullContext(ctx, u)
+	if err != nil {
+		if dres != nil {
+			return nil, dres.Res, err
+		}
+		return nil, nil, err
+	}
+
+	if len(dres.Skipped) != 0 {
+		return nil, nil, fmt.Errorf("unable to get clock rate of track %d: %s",
+			dres.Skipped[0].ID, dres.SkippedErrors[0])
+	}
+
+	return dres.Tracks, dres.Res, nil
+}
+
+// DescribeResponse is the result of a DESCRIBE request.
+type DescribeResponse struct {
+	// tracks that have been decoded.
+	Tracks Tracks
+
+	// tracks that have been skipped since their clock rate can't be read,
+	// for instance metadata tracks of some ONVIF devices.
+	// They can still be set up manually.
+	Skipped Tracks
+
+	// the reason why each track in Skipped has been skipped.
+	SkippedErrors []error
+
+	// session description.
+	Session *sdp.SessionDescription
+
+	// response.
+	Res *base.Response
+}
+
+// DescribeFull writes a DESCRIBE request and reads a Response.
+// Unlike Describe, it doesn't fail when the clock rate of a track can't be read,
+// and it returns the session description, in order to allow setting up
+// tracks that can't be decoded.
+// In both Tracks and Skipped, the ID of each track is its position
+// inside the session description.
+func (c *ClientConn) DescribeFull(u *base.URL) (*DescribeResponse, error) {
+	return c.DescribeFullContext(context.Background(), u)
+}
+
+// DescribeFullContext is like DescribeFull, but the request can be canceled through a context.
+func (c *ClientConn) DescribeFullContext(ctx context.Context, u *base.URL) (*DescribeResponse, error) {
+	return c.describeFull(ctx, u, 0)
+}
+
+func (c *ClientConn) describeFull(ctx context.Context, u *base.URL, redirectCount int) (*DescribeResponse, error) {
 	err := c.checkState(map[clientConnState]struct{}{
 		clientConnStateInitial:   {},
 		clientConnStatePrePlay:   {},
 		clientConnStatePreRecord: {},
 	})
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	res, err := c.Do(&base.Request{
+	req := &base.Request{
 		Method: base.Describe,
 		URL:    u,
 		Header: base.Header{
 			"Accept": base.HeaderValue{"application/sdp"},
 		},
-	})
+	}
+
+	res, err := c.DoContext(ctx, req)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
 	if res.StatusCode != base.StatusOK {
@@ -368,45 +995,130 @@ func (c *ClientConn) Describe(u *base.URL) (Tracks, *base.Response, error) {
 			res.StatusCode <= base.StatusUseProxy &&
 			len(res.Header["Location"]) == 1 {
 
-			c.Close()
+			if redirectCount >= c.conf.RedirectMaxCount {
+				return &DescribeResponse{Res: res},
+					liberrors.ErrClientTooManyRedirects{Count: redirectCount}
+			}
 
-			u, err := base.ParseURL(res.Header["Location"][0])
+			// the location can be relative to the request URL
+			ref, err := url.Parse(res.Header["Location"][0])
 			if err != nil {
-				return nil, nil, err
+				return nil, err
 			}
 
-			nc, err := c.conf.Dial(u.Scheme, u.Host)
+			nu, err := base.ParseURL((*url.URL)(u).ResolveReference(ref).String())
 			if err != nil {
-				return nil, nil, err
+				return nil, err
 			}
-			*c = *nc //nolint:govet
 
-			_, err = c.Options(u)
-			if err != nil {
-				return nil, nil, err
+			// a new connection is needed only if the server is different
+			if nu.Scheme != u.Scheme || !nu.SameHost(u) {
+				c.Close()
+
+				nc, err := c.conf.Dial(nu.Scheme, nu.Host)
+				if err != nil {
+					return nil, err
+				}
+				*c = *nc //nolint:govet
+
+				_, err = c.Options(nu)
+				if err != nil {
+					return nil, err
+				}
 			}
 
-			return c.Describe(u)
+			return c.describeFull(ctx, nu, redirectCount+1)
 		}
 
-		return nil, res, liberrors.ErrClientWrongStatusCode{Code: res.StatusCode, Message: res.StatusMessage}
+		return &DescribeResponse{Res: res},
+			liberrors.ErrClientWrongStatusCode{Code: res.StatusCode, Message: res.StatusMessage}
 	}
 
 	ct, ok := res.Header["Content-Type"]
 	if !ok || len(ct) != 1 {
-		return nil, nil, liberrors.ErrClientContentTypeMissing{}
+		return nil, liberrors.ErrClientContentTypeMissing{}
 	}
 
 	if ct[0] != "application/sdp" {
-		return nil, nil, liberrors.ErrClientContentTypeUnsupported{CT: ct}
+		return nil, liberrors.ErrClientContentTypeUnsupported{CT: ct}
 	}
 
-	tracks, err := ReadTracks(res.Body, u)
+	baseURL, err := describeBaseURL(res, u)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	return tracks, res, nil
+	desc := &sdp.SessionDescription{}
+	var warnings []string
+	if c.conf.LenientSDPEnable {
+		warnings = desc.UnmarshalLenient(res.Body)
+	} else {
+		err := desc.Unmarshal(res.Body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tracks, skipped, errs := readTracksPartial(desc, baseURL)
+
+	// tracks are back channels only if they have been requested
+	if headerContains(req.Header["Require"], clientConnBackChannelRequire) {
+		for _, ts := range []Tracks{tracks, skipped} {
+			for _, track := range ts {
+				if _, ok := track.Attribute("sendonly"); ok {
+					track.backChannel = true
+				}
+			}
+		}
+	}
+
+	c.baseURL = baseURL
+
+	for _, w := range warnings {
+		c.conf.Logger.Warnf("SDP line skipped: %s", w)
+
+		if c.conf.OnSDPWarning != nil {
+			c.conf.OnSDPWarning(w)
+		}
+	}
+
+	return &DescribeResponse{
+		Tracks:        tracks,
+		Skipped:       skipped,
+		SkippedErrors: errs,
+		Session:       desc,
+		Res:           res,
+	}, nil
+}
+
+// describeBaseURL returns the URL against which the control attributes of
+// the tracks of a DESCRIBE response are resolved, that is, in order of priority,
+// the Content-Base header, the Content-Location header or the request URL
+// (RFC 2326, appendix C.1.1).
+func describeBaseURL(res *base.Response, u *base.URL) (*base.URL, error) {
+	for _, key := range []string{"Content-Base", "Content-Location"} {
+		v, ok := res.Header[key]
+		if !ok || len(v) != 1 || v[0] == "" {
+			continue
+		}
+
+		// the header can contain a URL that is relative to the request URL
+		ref, err := url.Parse(v[0])
+		if err != nil {
+			return nil, liberrors.ErrClientContentBaseInvalid{Header: key, Err: err}
+		}
+
+		ur, err := base.ParseURL((*url.URL)(u).ResolveReference(ref).String())
+		if err != nil {
+			return nil, liberrors.ErrClientContentBaseInvalid{Header: key, Err: err}
+		}
+
+		// credentials are not sent by servers, copy them from the request
+		ur.User = u.User
+		return ur, nil
+	}
+
+	return u, nil
 }
 
 // Setup writes a SETUP request and reads a Response.
@@ -414,6 +1126,42 @@ func (c *ClientConn) Describe(u *base.URL) (Tracks, *base.Response, error) {
 // if rtpPort and rtcpPort are zero, they are chosen automatically.
 func (c *ClientConn) Setup(mode headers.TransportMode, track *Track,
 	rtpPort int, rtcpPort int) (*base.Response, error) {
+	return c.SetupContext(context.Background(), mode, track, rtpPort, rtcpPort)
+}
+
+// SetupContext is like Setup, but the request can be canceled through a context.
+func (c *ClientConn) SetupContext(ctx context.Context, mode headers.TransportMode, track *Track,
+	rtpPort int, rtcpPort int) (*base.Response, error) {
+	s, err := c.setupPrepare(mode, track, rtpPort, rtcpPort)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.DoContext(ctx, s.req)
+	if err != nil {
+		s.closeListeners()
+		return nil, err
+	}
+
+	return c.setupProcess(ctx, s, res)
+}
+
+// SetupAll writes a SETUP request for each track and reads the responses.
+// Tracks are set up for reading, or for publishing if Announce() has been
+// called; back channels are always set up for publishing.
+// The first request is used to obtain the session and the stream protocol,
+// while the remaining requests are written at once, without waiting for
+// responses in between, in order to reduce the startup latency with
+// distant servers. UDP ports are chosen automatically and allocated
+// before writing the requests.
+// If proto is not nil, it overrides ClientConf.StreamProtocol.
+func (c *ClientConn) SetupAll(tracks Tracks, proto *StreamProtocol) ([]*base.Response, error) {
+	return c.SetupAllContext(context.Background(), tracks, proto)
+}
+
+// SetupAllContext is like SetupAll, but the requests can be canceled through a context.
+func (c *ClientConn) SetupAllContext(ctx context.Context, tracks Tracks,
+	proto *StreamProtocol) ([]*base.Response, error) {
 	err := c.checkState(map[clientConnState]struct{}{
 		clientConnStateInitial:   {},
 		clientConnStatePrePlay:   {},
@@ -423,7 +1171,130 @@ func (c *ClientConn) Setup(mode headers.TransportMode, track *Track,
 		return nil, err
 	}
 
-	if (mode == headers.TransportModeRecord && c.state != clientConnStatePreRecord) ||
+	if len(tracks) == 0 {
+		return nil, nil
+	}
+
+	if proto != nil && c.streamProtocol == nil {
+		v := *proto
+		c.streamProtocol = &v
+	}
+
+	trackMode := func(track *Track) headers.TransportMode {
+		if c.state == clientConnStatePreRecord || track.IsBackChannel() {
+			return headers.TransportModeRecord
+		}
+		return headers.TransportModePlay
+	}
+
+	res, err := c.SetupContext(ctx, trackMode(tracks[0]), tracks[0], 0, 0)
+	if err != nil {
+		if res != nil {
+			return []*base.Response{res}, err
+		}
+		return nil, err
+	}
+	ress := []*base.Response{res}
+
+	// allocate UDP listeners and build the remaining requests in advance
+	setups := make([]*clientConnSetup, 0, len(tracks)-1)
+	closeSetups := func(from int) {
+		for _, s := range setups[from:] {
+			s.closeListeners()
+		}
+	}
+
+	for _, track := range tracks[1:] {
+		s, err := c.setupPrepare(trackMode(track), track, 0, 0)
+		if err != nil {
+			closeSetups(0)
+			return ress, err
+		}
+		setups = append(setups, s)
+	}
+
+	stop := watchContext(ctx, c.nconn)
+	pipelinedRess, err := func() ([]*base.Response, error) {
+		for _, s := range setups {
+			err := c.writeRequest(s.req)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		ret := make([]*base.Response, len(setups))
+		for i := range setups {
+			res, err := c.readResponse()
+			if err != nil {
+				return nil, err
+			}
+			ret[i] = res
+		}
+		return ret, nil
+	}()
+	stop()
+
+	if err != nil {
+		closeSetups(0)
+		if ctx.Err() != nil {
+			return ress, ctx.Err()
+		}
+		return ress, err
+	}
+
+	for i, s := range setups {
+		res, err := c.setupProcess(ctx, s, pipelinedRess[i])
+		if res != nil {
+			ress = append(ress, res)
+		}
+		if err != nil {
+			closeSetups(i + 1)
+			return ress, err
+		}
+	}
+
+	return ress, nil
+}
+
+// clientConnSetup is a SETUP request that has been prepared but not sent yet.
+type clientConnSetup struct {
+	mode          headers.TransportMode
+	track         *Track
+	isBackChannel bool
+	proto         StreamProtocol
+	th            headers.Transport
+	thTCP         *headers.Transport
+	srtpCtx       *srtp.Context
+	rtpListener   *clientConnUDPListener
+	rtcpListener  *clientConnUDPListener
+	req           *base.Request
+}
+
+func (s *clientConnSetup) closeListeners() {
+	if s.proto == StreamProtocolUDP {
+		s.rtpListener.close()
+		s.rtcpListener.close()
+	}
+}
+
+// setupPrepare checks whether a track can be set up, allocates its UDP
+// listeners and builds the SETUP request.
+func (c *ClientConn) setupPrepare(mode headers.TransportMode, track *Track,
+	rtpPort int, rtcpPort int) (*clientConnSetup, error) {
+	err := c.checkState(map[clientConnState]struct{}{
+		clientConnStateInitial:   {},
+		clientConnStatePrePlay:   {},
+		clientConnStatePreRecord: {},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// back channels are published while reading
+	isBackChannel := (mode == headers.TransportModeRecord && track.IsBackChannel() &&
+		(c.state == clientConnStatePrePlay || c.state == clientConnStateInitial))
+
+	if (mode == headers.TransportModeRecord && c.state != clientConnStatePreRecord && !isBackChannel) ||
 		(mode == headers.TransportModePlay && c.state != clientConnStatePrePlay &&
 			c.state != clientConnStateInitial) {
 		return nil, liberrors.ErrClientCannotReadPublishAtSameTime{}
@@ -433,8 +1304,11 @@ func (c *ClientConn) Setup(mode headers.TransportMode, track *Track,
 		return nil, liberrors.ErrClientCannotSetupTracksDifferentURLs{}
 	}
 
-	var rtpListener *clientConnUDPListener
-	var rtcpListener *clientConnUDPListener
+	s := &clientConnSetup{
+		mode:          mode,
+		track:         track,
+		isBackChannel: isBackChannel,
+	}
 
 	// always use TCP if encrypted
 	if c.isTLS {
@@ -442,7 +1316,15 @@ func (c *ClientConn) Setup(mode headers.TransportMode, track *Track,
 		c.streamProtocol = &v
 	}
 
-	proto := func() StreamProtocol {
+	// UDP can't be used when the server address is unknown, and
+	// the automatic switch to TCP requires reopening the connection.
+	if _, ok := c.nconn.RemoteAddr().(*net.TCPAddr); !ok ||
+		(c.nconnProvided && c.conf.StreamProtocol == nil) {
+		v := StreamProtocolTCP
+		c.streamProtocol = &v
+	}
+
+	s.proto = func() StreamProtocol {
 		// protocol set by previous Setup()
 		if c.streamProtocol != nil {
 			return *c.streamProtocol
@@ -457,16 +1339,26 @@ func (c *ClientConn) Setup(mode headers.TransportMode, track *Track,
 		return StreamProtocolUDP
 	}()
 
-	th := headers.Transport{
-		Protocol: proto,
+	s.th = headers.Transport{
+		Protocol: s.proto,
 		Delivery: func() *base.StreamDelivery {
 			v := base.StreamDeliveryUnicast
 			return &v
 		}(),
-		Mode: &mode,
+		Mode:       &s.mode,
+		ModeFormat: c.conf.TransportModeFormat,
 	}
 
-	if proto == base.StreamProtocolUDP {
+	// use SRTP if the track contains a key
+	if key, err := track.ExtractSRTPKey(); err == nil {
+		s.srtpCtx, err = srtp.New(key)
+		if err != nil {
+			return nil, err
+		}
+		s.th.Profile = headers.TransportProfileSAVP
+	}
+
+	if s.proto == base.StreamProtocolUDP {
 		if (rtpPort == 0 && rtcpPort != 0) ||
 			(rtpPort != 0 && rtcpPort == 0) {
 			return nil, liberrors.ErrClientUDPPortsZero{}
@@ -477,7 +1369,7 @@ func (c *ClientConn) Setup(mode headers.TransportMode, track *Track,
 		}
 
 		var err error
-		rtpListener, rtcpListener, err = func() (*clientConnUDPListener, *clientConnUDPListener, error) {
+		s.rtpListener, s.rtcpListener, err = func() (*clientConnUDPListener, *clientConnUDPListener, error) {
 			if rtpPort != 0 {
 				rtpListener, err := newClientConnUDPListener(c, rtpPort)
 				if err != nil {
@@ -518,41 +1410,54 @@ func (c *ClientConn) Setup(mode headers.TransportMode, track *Track,
 			return nil, err
 		}
 
-		th.ClientPorts = &[2]int{rtpPort, rtcpPort}
+		s.th.ClientPorts = &[2]int{rtpPort, rtcpPort}
 
 	} else {
-		th.InterleavedIDs = &[2]int{(track.ID * 2), (track.ID * 2) + 1}
+		s.th.InterleavedIDs = &[2]int{(track.ID * 2), (track.ID * 2) + 1}
 	}
 
 	trackURL, err := track.URL()
 	if err != nil {
-		if proto == StreamProtocolUDP {
-			rtpListener.close()
-			rtcpListener.close()
-		}
+		s.closeListeners()
 		return nil, err
 	}
 
-	res, err := c.Do(&base.Request{
+	transportHeader := s.th.Write()
+
+	// offer TCP as an alternative to UDP, and let the server choose
+	if s.proto == StreamProtocolUDP && c.streamProtocol == nil &&
+		c.conf.StreamProtocol == nil && c.conf.SetupTransportAlternatives {
+		v := s.th
+		v.Protocol = StreamProtocolTCP
+		v.ClientPorts = nil
+		v.InterleavedIDs = &[2]int{(track.ID * 2), (track.ID * 2) + 1}
+		s.thTCP = &v
+		transportHeader = headers.Transports{s.th, v}.Write()
+	}
+
+	s.req = &base.Request{
 		Method: base.Setup,
 		URL:    trackURL,
 		Header: base.Header{
-			"Transport": th.Write(),
+			"Transport": transportHeader,
 		},
-	})
-	if err != nil {
-		if proto == StreamProtocolUDP {
-			rtpListener.close()
-			rtcpListener.close()
-		}
-		return nil, err
 	}
 
+	return s, nil
+}
+
+// setupProcess processes the response to a SETUP request.
+func (c *ClientConn) setupProcess(ctx context.Context, s *clientConnSetup,
+	res *base.Response) (*base.Response, error) {
+	mode := s.mode
+	track := s.track
+	proto := s.proto
+	th := s.th
+	rtpListener := s.rtpListener
+	rtcpListener := s.rtcpListener
+
 	if res.StatusCode != base.StatusOK {
-		if proto == StreamProtocolUDP {
-			rtpListener.close()
-			rtcpListener.close()
-		}
+		s.closeListeners()
 
 		// switch protocol automatically
 		if res.StatusCode == base.StatusUnsupportedTransport &&
@@ -562,22 +1467,33 @@ func (c *ClientConn) Setup(mode headers.TransportMode, track *Track,
 			v := StreamProtocolTCP
 			c.streamProtocol = &v
 
-			return c.Setup(headers.TransportModePlay, track, 0, 0)
+			return c.SetupContext(ctx, mode, track, 0, 0)
 		}
 
 		return res, liberrors.ErrClientWrongStatusCode{Code: res.StatusCode, Message: res.StatusMessage}
 	}
 
 	var thRes headers.Transport
-	err = thRes.Read(res.Header["Transport"])
+	err := thRes.Read(res.Header["Transport"])
 	if err != nil {
-		if proto == StreamProtocolUDP {
-			rtpListener.close()
-			rtcpListener.close()
-		}
+		s.closeListeners()
 		return nil, liberrors.ErrClientTransportHeaderInvalid{Err: err}
 	}
 
+	if thRes.Profile != th.Profile {
+		s.closeListeners()
+		return nil, liberrors.ErrClientTransportHeaderWrongProfile{
+			Expected: th.Profile, Value: thRes.Profile}
+	}
+
+	// the server chose the TCP alternative
+	if s.thTCP != nil && thRes.Protocol == StreamProtocolTCP {
+		rtpListener.close()
+		rtcpListener.close()
+		proto = StreamProtocolTCP
+		th = *s.thTCP
+	}
+
 	if proto == StreamProtocolUDP {
 		if thRes.ServerPorts != nil {
 			if (thRes.ServerPorts[0] == 0 && thRes.ServerPorts[1] != 0) ||
@@ -608,6 +1524,8 @@ func (c *ClientConn) Setup(mode headers.TransportMode, track *Track,
 		}
 	}
 
+	// if the clock rate is unknown, it is zero and sender reports are not
+	// generated, since their RTP timestamp would be wrong.
 	clockRate, _ := track.ClockRate()
 
 	if mode == headers.TransportModePlay {
@@ -616,36 +1534,63 @@ func (c *ClientConn) Setup(mode headers.TransportMode, track *Track,
 		if proto == StreamProtocolUDP {
 			v := time.Now().Unix()
 			c.udpLastFrameTimes[track.ID] = &v
+
+			if r := newUDPReorderer(c.conf.UDPReorderBufferSize, c.conf.UDPReorderBufferDuration); r != nil {
+				c.rtpReorderers[track.ID] = r
+			}
 		}
 	} else {
 		c.rtcpSenders[track.ID] = rtcpsender.New(clockRate)
 	}
 
+	if s.srtpCtx != nil {
+		c.srtpContexts[track.ID] = s.srtpCtx
+	}
+
 	c.streamURL = track.BaseURL
 	c.streamProtocol = &proto
 	c.tracks = append(c.tracks, track)
 
 	if proto == StreamProtocolUDP {
-		rtpListener.remoteIP = c.nconn.RemoteAddr().(*net.TCPAddr).IP
-		rtpListener.remoteZone = c.nconn.RemoteAddr().(*net.TCPAddr).Zone
+		remoteIP := c.nconn.RemoteAddr().(*net.TCPAddr).IP
+		remoteZone := c.nconn.RemoteAddr().(*net.TCPAddr).Zone
+
+		// the server can send packets from a different address
+		if thRes.Source != nil {
+			if ip := net.ParseIP(*thRes.Source); ip != nil {
+				remoteIP = ip
+			}
+		}
+
+		rtpListener.remoteIP = remoteIP
+		rtpListener.remoteZone = remoteZone
 		if thRes.ServerPorts != nil {
 			rtpListener.remotePort = thRes.ServerPorts[0]
 		}
 		rtpListener.trackID = track.ID
 		rtpListener.streamType = StreamTypeRTP
+		if !c.conf.SSRCFilterDisable {
+			rtpListener.ssrcFilter = newSSRCFilter(thRes.SSRC)
+		}
 		c.udpRTPListeners[track.ID] = rtpListener
 
-		rtcpListener.remoteIP = c.nconn.RemoteAddr().(*net.TCPAddr).IP
-		rtcpListener.remoteZone = c.nconn.RemoteAddr().(*net.TCPAddr).Zone
+		rtcpListener.remoteIP = remoteIP
+		rtcpListener.remoteZone = remoteZone
 		if thRes.ServerPorts != nil {
 			rtcpListener.remotePort = thRes.ServerPorts[1]
 		}
 		rtcpListener.trackID = track.ID
 		rtcpListener.streamType = StreamTypeRTCP
 		c.udpRTCPListeners[track.ID] = rtcpListener
+
+		// open NAT mappings as soon as the server ports are known,
+		// since the server may start sending packets right after PLAY
+		if mode == headers.TransportModePlay && rtpListener.remotePort != 0 {
+			c.punchHoles(track.ID)
+		}
 	}
 
-	if mode == headers.TransportModePlay {
+	if mode == headers.TransportModePlay || s.isBackChannel {
 		c.state = clientConnStatePrePlay
 	} else {
 		c.state = clientConnStatePreRecord
@@ -655,9 +1600,19 @@ func (c *ClientConn) Setup(mode headers.TransportMode, track *Track,
 }
 
 // Pause writes a PAUSE request and reads a Response.
+// It stops reading or publishing without tearing down tracks, and keeps
+// UDP listeners bound, therefore reading or publishing can be resumed
+// with Play() or Record().
 // This can be called only after Play() or Record().
 func (c *ClientConn) Pause() (*base.Response, error) {
-	err := c.checkState(map[clientConnState]struct{}{
+	err := c.checkMethodSupported(base.Pause)
+	if err != nil {
+		return nil, err
+	}
+
+	c.backgroundStop()
+
+	err = c.checkState(map[clientConnState]struct{}{
 		clientConnStatePlay:   {},
 		clientConnStateRecord: {},
 	})
@@ -665,9 +1620,6 @@ func (c *ClientConn) Pause() (*base.Response, error) {
 		return nil, err
 	}
 
-	close(c.backgroundTerminate)
-	<-c.backgroundDone
-
 	res, err := c.Do(&base.Request{
 		Method: base.Pause,
 		URL:    c.streamURL,
@@ -690,3 +1642,13 @@ func (c *ClientConn) Pause() (*base.Response, error) {
 
 	return res, nil
 }
+
+// headerContains checks whether a header contains a value.
+func headerContains(v base.HeaderValue, value string) bool {
+	for _, entry := range v {
+		if entry == value {
+			return true
+		}
+	}
+	return false
+}
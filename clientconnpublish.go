@@ -1,12 +1,10 @@
 package gortsplib
 
 import (
-	"fmt"
+	"context"
 	"strconv"
 	"time"
 
-	psdp "github.com/pion/sdp/v3"
-
 	"github.com/majoyz/gortsplib/pkg/base"
 	"github.com/majoyz/gortsplib/pkg/liberrors"
 )
@@ -25,10 +23,7 @@ func (c *ClientConn) Announce(u *base.URL, tracks Tracks) (*base.Response, error
 		t.ID = i
 		t.BaseURL = u
 
-		t.Media.Attributes = append(t.Media.Attributes, psdp.Attribute{
-			Key:   "control",
-			Value: "trackID=" + strconv.FormatInt(int64(i), 10),
-		})
+		t.SetControl("trackID=" + strconv.FormatInt(int64(i), 10))
 	}
 
 	res, err := c.Do(&base.Request{
@@ -57,6 +52,11 @@ func (c *ClientConn) Announce(u *base.URL, tracks Tracks) (*base.Response, error
 // Record writes a RECORD request and reads a Response.
 // This can be called only after Announce() and Setup().
 func (c *ClientConn) Record() (*base.Response, error) {
+	return c.RecordContext(context.Background())
+}
+
+// RecordContext is like Record, but the request can be canceled through a context.
+func (c *ClientConn) RecordContext(ctx context.Context) (*base.Response, error) {
 	err := c.checkState(map[clientConnState]struct{}{
 		clientConnStatePreRecord: {},
 	})
@@ -64,7 +64,12 @@ func (c *ClientConn) Record() (*base.Response, error) {
 		return nil, err
 	}
 
-	res, err := c.Do(&base.Request{
+	err = c.checkMethodSupported(base.Record)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.DoContext(ctx, &base.Request{
 		Method: base.Record,
 		URL:    c.streamURL,
 	})
@@ -104,39 +109,73 @@ func (c *ClientConn) backgroundRecordUDP() {
 	c.nconn.SetReadDeadline(time.Time{})
 
 	readerDone := make(chan error)
+	serverRequestRecv := make(chan base.Request, clientConnServerRequestQueueSize)
 	go func() {
 		for {
-			var res base.Response
-			err := res.Read(c.br)
+			req := base.Request{Limits: &c.conf.ReadLimits}
+			res := base.Response{Limits: &c.conf.ReadLimits}
+			what, err := base.ReadRequestOrResponse(&req, &res, c.br)
 			if err != nil {
 				readerDone <- err
 				return
 			}
+
+			if _, ok := what.(*base.Request); ok {
+				c.queueServerRequest(serverRequestRecv, req)
+			}
 		}
 	}()
 
 	reportTicker := time.NewTicker(clientConnSenderReportPeriod)
 	defer reportTicker.Stop()
 
+	keepaliveTicker := time.NewTicker(c.keepalivePeriod())
+	defer keepaliveTicker.Stop()
+
 	for {
 		select {
 		case <-c.backgroundTerminate:
 			c.nconn.SetReadDeadline(time.Now())
 			<-readerDone
-			c.publishError = fmt.Errorf("terminated")
+			c.publishError = liberrors.ErrClientTerminated{}
 			return
 
+		case <-keepaliveTicker.C:
+			err := c.keepalive()
+			if err != nil {
+				c.nconn.SetReadDeadline(time.Now())
+				<-readerDone
+				c.publishError = err
+				return
+			}
+
 		case <-reportTicker.C:
 			c.publishWriteMutex.Lock()
 			now := time.Now()
 			for trackID := range c.rtcpSenders {
 				r := c.rtcpSenders[trackID].Report(now)
 				if r != nil {
+					var err error
+					r, err = srtpEncryptFrame(c.srtpContexts[trackID], StreamTypeRTCP, r)
+					if err != nil {
+						c.conf.Logger.Warnf("RTCP packet of track %d discarded: %s", trackID, err)
+						continue
+					}
+
 					c.udpRTCPListeners[trackID].write(r)
 				}
 			}
 			c.publishWriteMutex.Unlock()
 
+		case req := <-serverRequestRecv:
+			err := c.handleServerRequest(&req)
+			if err != nil {
+				c.nconn.SetReadDeadline(time.Now())
+				<-readerDone
+				c.publishError = err
+				return
+			}
+
 		case err := <-readerDone:
 			c.publishError = err
 			return
@@ -153,12 +192,68 @@ func (c *ClientConn) backgroundRecordTCP() {
 		c.publishOpen = false
 	}()
 
+	// disable deadline
+	c.nconn.SetReadDeadline(time.Time{})
+
+	// read and discard responses to keepalives and frames sent by the server
+	readerDone := make(chan error)
+	serverRequestRecv := make(chan base.Request, clientConnServerRequestQueueSize)
+	go func() {
+		frame := base.InterleavedFrame{
+			Payload: make([]byte, c.conf.ReadBufferSize),
+		}
+
+		for {
+			req := base.Request{Limits: &c.conf.ReadLimits}
+			res := base.Response{Limits: &c.conf.ReadLimits}
+			what, err := base.ReadInterleavedFrameOrRequestOrResponse(&frame, &req, &res, c.br)
+			if err != nil {
+				readerDone <- err
+				return
+			}
+
+			if _, ok := what.(*base.Request); ok {
+				c.queueServerRequest(serverRequestRecv, req)
+			}
+		}
+	}()
+
 	reportTicker := time.NewTicker(clientConnSenderReportPeriod)
 	defer reportTicker.Stop()
 
+	keepaliveTicker := time.NewTicker(c.keepalivePeriod())
+	defer keepaliveTicker.Stop()
+
 	for {
 		select {
 		case <-c.backgroundTerminate:
+			c.nconn.SetReadDeadline(time.Now())
+			<-readerDone
+			c.publishError = liberrors.ErrClientTerminated{}
+			return
+
+		case <-keepaliveTicker.C:
+			c.publishWriteMutex.Lock()
+			err := c.keepalive()
+			c.publishWriteMutex.Unlock()
+			if err != nil {
+				c.nconn.SetReadDeadline(time.Now())
+				<-readerDone
+				c.publishError = err
+				return
+			}
+
+		case req := <-serverRequestRecv:
+			err := c.handleServerRequest(&req)
+			if err != nil {
+				c.nconn.SetReadDeadline(time.Now())
+				<-readerDone
+				c.publishError = err
+				return
+			}
+
+		case err := <-readerDone:
+			c.publishError = err
 			return
 
 		case <-reportTicker.C:
@@ -167,6 +262,13 @@ func (c *ClientConn) backgroundRecordTCP() {
 			for trackID := range c.rtcpSenders {
 				r := c.rtcpSenders[trackID].Report(now)
 				if r != nil {
+					var err error
+					r, err = srtpEncryptFrame(c.srtpContexts[trackID], StreamTypeRTCP, r)
+					if err != nil {
+						c.conf.Logger.Warnf("RTCP packet of track %d discarded: %s", trackID, err)
+						continue
+					}
+
 					c.nconn.SetWriteDeadline(time.Now().Add(c.conf.WriteTimeout))
 					frame := base.InterleavedFrame{
 						TrackID:    trackID,
@@ -182,7 +284,8 @@ func (c *ClientConn) backgroundRecordTCP() {
 }
 
 // WriteFrame writes a frame.
-// This can be called only after Record().
+// This can be called only after Record(), or after ReadFrames()
+// when the frame belongs to a back channel.
 func (c *ClientConn) WriteFrame(trackID int, streamType StreamType, payload []byte) error {
 	c.publishWriteMutex.RLock()
 	defer c.publishWriteMutex.RUnlock()
@@ -195,6 +298,11 @@ func (c *ClientConn) WriteFrame(trackID int, streamType StreamType, payload []by
 
 	c.rtcpSenders[trackID].ProcessFrame(now, streamType, payload)
 
+	payload, err := srtpEncryptFrame(c.srtpContexts[trackID], streamType, payload)
+	if err != nil {
+		return err
+	}
+
 	if *c.streamProtocol == StreamProtocolUDP {
 		if streamType == StreamTypeRTP {
 			return c.udpRTPListeners[trackID].write(payload)
@@ -131,6 +131,8 @@ func TestClientPublishSerial(t *testing.T) {
 				Tracks{track})
 			require.NoError(t, err)
 
+			require.Equal(t, 1460, conn.PayloadMaxSize(track.ID))
+
 			err = conn.WriteFrame(track.ID, StreamTypeRTP,
 				[]byte{0x01, 0x02, 0x03, 0x04})
 			require.NoError(t, err)
@@ -591,3 +593,141 @@ func TestClientPublishPauseParallel(t *testing.T) {
 		})
 	}
 }
+
+func TestClientPublishKeepalive(t *testing.T) {
+	for _, ca := range []struct {
+		proto  string
+		method base.Method
+	}{
+		{"udp", ""},
+		{"tcp", ""},
+		{"tcp", base.Options},
+	} {
+		t.Run(ca.proto+"_"+string(ca.method), func(t *testing.T) {
+			l, err := net.Listen("tcp", "localhost:8554")
+			require.NoError(t, err)
+			defer l.Close()
+
+			serverDone := make(chan struct{})
+			defer func() { <-serverDone }()
+			go func() {
+				defer close(serverDone)
+
+				conn, err := l.Accept()
+				require.NoError(t, err)
+				defer conn.Close()
+				bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+				var req base.Request
+				err = req.Read(bconn.Reader)
+				require.NoError(t, err)
+				require.Equal(t, base.Options, req.Method)
+
+				err = base.Response{
+					StatusCode: base.StatusOK,
+					Header: base.Header{
+						"Public": base.HeaderValue{strings.Join([]string{
+							string(base.Announce),
+							string(base.Setup),
+							string(base.Record),
+							string(base.GetParameter),
+						}, ", ")},
+					},
+				}.Write(bconn.Writer)
+				require.NoError(t, err)
+
+				err = req.Read(bconn.Reader)
+				require.NoError(t, err)
+				require.Equal(t, base.Announce, req.Method)
+
+				err = base.Response{
+					StatusCode: base.StatusOK,
+				}.Write(bconn.Writer)
+				require.NoError(t, err)
+
+				err = req.Read(bconn.Reader)
+				require.NoError(t, err)
+				require.Equal(t, base.Setup, req.Method)
+
+				var inTH headers.Transport
+				err = inTH.Read(req.Header["Transport"])
+				require.NoError(t, err)
+
+				th := headers.Transport{
+					Delivery: func() *base.StreamDelivery {
+						v := base.StreamDeliveryUnicast
+						return &v
+					}(),
+				}
+
+				if ca.proto == "udp" {
+					th.Protocol = StreamProtocolUDP
+					th.ServerPorts = &[2]int{34556, 34557}
+					th.ClientPorts = inTH.ClientPorts
+
+				} else {
+					th.Protocol = StreamProtocolTCP
+					th.InterleavedIDs = inTH.InterleavedIDs
+				}
+
+				err = base.Response{
+					StatusCode: base.StatusOK,
+					Header: base.Header{
+						"Transport": th.Write(),
+					},
+				}.Write(bconn.Writer)
+				require.NoError(t, err)
+
+				err = req.Read(bconn.Reader)
+				require.NoError(t, err)
+				require.Equal(t, base.Record, req.Method)
+
+				err = base.Response{
+					StatusCode: base.StatusOK,
+				}.Write(bconn.Writer)
+				require.NoError(t, err)
+
+				expectedMethod := ca.method
+				if expectedMethod == "" {
+					expectedMethod = base.GetParameter
+				}
+
+				for i := 0; i < 2; i++ {
+					buf := make([]byte, 2048)
+					err = req.ReadIgnoreFrames(bconn.Reader, buf)
+					require.NoError(t, err)
+					require.Equal(t, expectedMethod, req.Method)
+					require.Equal(t, base.MustParseURL("rtsp://localhost:8554/teststream"), req.URL)
+
+					err = base.Response{
+						StatusCode: base.StatusOK,
+					}.Write(bconn.Writer)
+					require.NoError(t, err)
+				}
+			}()
+
+			track, err := NewTrackH264(96, []byte("123456"), []byte("123456"))
+			require.NoError(t, err)
+
+			conf := ClientConf{
+				StreamProtocol: func() *StreamProtocol {
+					if ca.proto == "udp" {
+						v := StreamProtocolUDP
+						return &v
+					}
+					v := StreamProtocolTCP
+					return &v
+				}(),
+				KeepalivePeriod: 200 * time.Millisecond,
+				KeepaliveMethod: ca.method,
+			}
+
+			conn, err := conf.DialPublish("rtsp://localhost:8554/teststream",
+				Tracks{track})
+			require.NoError(t, err)
+
+			<-serverDone
+			conn.Close()
+		})
+	}
+}
@@ -1,18 +1,28 @@
 package gortsplib
 
 import (
-	"fmt"
+	"context"
+	"strings"
 	"sync/atomic"
 	"time"
 
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+
 	"github.com/majoyz/gortsplib/pkg/base"
 	"github.com/majoyz/gortsplib/pkg/headers"
 	"github.com/majoyz/gortsplib/pkg/liberrors"
 )
 
 // Play writes a PLAY request and reads a Response.
+// If ra is not nil, it is sent as Range header, in order to seek the stream.
 // This can be called only after Setup().
-func (c *ClientConn) Play() (*base.Response, error) {
+func (c *ClientConn) Play(ra *headers.Range) (*base.Response, error) {
+	return c.PlayContext(context.Background(), ra)
+}
+
+// PlayContext is like Play, but the request can be canceled through a context.
+func (c *ClientConn) PlayContext(ctx context.Context, ra *headers.Range) (*base.Response, error) {
 	err := c.checkState(map[clientConnState]struct{}{
 		clientConnStatePrePlay: {},
 	})
@@ -20,14 +30,27 @@ func (c *ClientConn) Play() (*base.Response, error) {
 		return nil, err
 	}
 
-	res, err := c.Do(&base.Request{
+	err = c.checkMethodSupported(base.Play)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make(base.Header)
+	if ra != nil {
+		header["Range"] = ra.Write()
+	}
+
+	res, err := c.DoContext(ctx, &base.Request{
 		Method: base.Play,
 		URL:    c.streamURL,
+		Header: header,
 	})
 	if err != nil {
 		return nil, err
 	}
 
+	c.playRange = ra
+
 	if res.StatusCode != base.StatusOK {
 		return nil, liberrors.ErrClientWrongStatusCode{
 			Code: res.StatusCode, Message: res.StatusMessage}
@@ -40,6 +63,20 @@ func (c *ClientConn) Play() (*base.Response, error) {
 			return nil, liberrors.ErrClientRTPInfoInvalid{Err: err}
 		}
 		c.rtpInfo = &ri
+		c.trackRTPInfos = make(map[int]*headers.RTPInfoEntry)
+
+		for _, track := range c.tracks {
+			e := rtpInfoEntryForTrack(ri, c.tracks, track)
+			if e == nil {
+				continue
+			}
+
+			c.trackRTPInfos[track.ID] = e
+
+			if rr, ok := c.rtcpReceivers[track.ID]; ok {
+				rr.SetInitialSequenceNumber(e.SequenceNumber)
+			}
+		}
 	}
 
 	return res, nil
@@ -50,27 +87,82 @@ func (c *ClientConn) RTPInfo() *headers.RTPInfo {
 	return c.rtpInfo
 }
 
-func (c *ClientConn) backgroundPlayUDP(done chan error) {
-	defer close(c.backgroundDone)
+// TrackRTPInfo returns the entry of the RTP-Info header that refers to a track,
+// that contains the sequence number and the RTP timestamp of the first packet
+// sent by the server after PLAY.
+// It returns nil if the server didn't provide it.
+func (c *ClientConn) TrackRTPInfo(trackID int) *headers.RTPInfoEntry {
+	return c.trackRTPInfos[trackID]
+}
+
+// DroppedFrames returns the number of frames received with UDP that have been
+// discarded because the read queue was full (see ClientConf.ReadBufferCount).
+func (c *ClientConn) DroppedFrames() uint64 {
+	return atomic.LoadUint64(&c.droppedFrames)
+}
+
+// rtpInfoEntryForTrack returns the entry of a RTP-Info header that refers to a track.
+func rtpInfoEntryForTrack(ri headers.RTPInfo, tracks Tracks, track *Track) *headers.RTPInfoEntry {
+	if u, err := track.URL(); err == nil {
+		trackPath, ok := u.RTSPPathAndQuery()
+		if ok {
+			trackPath = strings.TrimSuffix(trackPath, "/")
 
-	var returnError error
+			for _, e := range ri {
+				if e.URL == nil {
+					continue
+				}
+
+				entryPath, ok := e.URL.RTSPPathAndQuery()
+				if ok && strings.TrimSuffix(entryPath, "/") == trackPath {
+					return e
+				}
+			}
+		}
+	}
 
+	// some servers use URLs that don't match the track ones,
+	// but it's safe to use them when there's a single track
+	if len(ri) == 1 && len(tracks) == 1 {
+		return ri[0]
+	}
+
+	return nil
+}
+
+func (c *ClientConn) noRTPCheckPeriod() time.Duration {
+	if c.conf.NoRTPTimeout < clientConnNoRTPCheckPeriod {
+		return c.conf.NoRTPTimeout
+	}
+	return clientConnNoRTPCheckPeriod
+}
+
+func (c *ClientConn) noRTPTimedOut() bool {
+	last := time.Unix(0, atomic.LoadInt64(c.lastRTPTime))
+	return time.Since(last) >= c.conf.NoRTPTimeout
+}
+
+func (c *ClientConn) backgroundPlayUDP() error {
 	defer func() {
 		for trackID := range c.udpRTPListeners {
 			c.udpRTPListeners[trackID].stop()
 			c.udpRTCPListeners[trackID].stop()
 		}
-
-		done <- returnError
 	}()
 
+	// reset stream state, since reading may have been paused
+	now := time.Now()
+	for trackID, lastUnix := range c.udpLastFrameTimes {
+		atomic.StoreInt64(lastUnix, now.Unix())
+
+		if r, ok := c.rtpReorderers[trackID]; ok {
+			r.reset()
+		}
+	}
+
 	// open the firewall by sending packets to the counterpart
 	for trackID := range c.udpRTPListeners {
-		c.udpRTPListeners[trackID].write(
-			[]byte{0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00})
-
-		c.udpRTCPListeners[trackID].write(
-			[]byte{0x80, 0xc9, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00})
+		c.punchHoles(trackID)
 	}
 
 	for trackID := range c.udpRTPListeners {
@@ -82,59 +174,118 @@ func (c *ClientConn) backgroundPlayUDP(done chan error) {
 	c.nconn.SetReadDeadline(time.Time{})
 
 	readerDone := make(chan error)
+	serverRequestRecv := make(chan base.Request, clientConnServerRequestQueueSize)
 	go func() {
 		for {
-			var res base.Response
-			err := res.Read(c.br)
+			req := base.Request{Limits: &c.conf.ReadLimits}
+			res := base.Response{Limits: &c.conf.ReadLimits}
+			what, err := base.ReadRequestOrResponse(&req, &res, c.br)
 			if err != nil {
 				readerDone <- err
 				return
 			}
+
+			if _, ok := what.(*base.Request); ok {
+				c.queueServerRequest(serverRequestRecv, req)
+			}
 		}
 	}()
 
 	reportTicker := time.NewTicker(clientConnReceiverReportPeriod)
 	defer reportTicker.Stop()
 
-	keepaliveTicker := time.NewTicker(clientConnUDPKeepalivePeriod)
+	// tracks for which the server has sent a RTCP BYE packet
+	ended := make(map[int]struct{})
+
+	keepaliveTicker := time.NewTicker(c.keepalivePeriod())
 	defer keepaliveTicker.Stop()
 
 	checkStreamTicker := time.NewTicker(clientConnUDPCheckStreamPeriod)
 	defer checkStreamTicker.Stop()
 
+	// release reordered packets that have been waiting for too long
+	var reorderFlushTickerC <-chan time.Time
+	if len(c.rtpReorderers) > 0 && c.conf.UDPReorderBufferDuration > 0 {
+		t := time.NewTicker(udpReorderFlushPeriod(c.conf.UDPReorderBufferDuration))
+		defer t.Stop()
+		reorderFlushTickerC = t.C
+	}
+
+	// stop reading if no RTP packets have been received within NoRTPTimeout
+	var noRTPTickerC <-chan time.Time
+	if c.conf.NoRTPTimeout > 0 {
+		t := time.NewTicker(c.noRTPCheckPeriod())
+		defer t.Stop()
+		noRTPTickerC = t.C
+	}
+
+	// when the protocol is chosen automatically, switch to TCP
+	// if no packets have been received within InitialUDPReadTimeout
+	var initialUDPReadTimer <-chan time.Time
+	if c.conf.StreamProtocol == nil && len(c.udpLastFrameTimes) > 0 &&
+		atomic.LoadInt32(&c.udpFramesReceived) == 0 {
+		t := time.NewTimer(c.conf.InitialUDPReadTimeout)
+		defer t.Stop()
+		initialUDPReadTimer = t.C
+	}
+
 	for {
 		select {
 		case <-c.backgroundTerminate:
 			c.nconn.SetReadDeadline(time.Now())
 			<-readerDone
-			returnError = fmt.Errorf("terminated")
-			return
+			return liberrors.ErrClientTerminated{}
 
 		case <-reportTicker.C:
 			now := time.Now()
 			for trackID := range c.rtcpReceivers {
 				r := c.rtcpReceivers[trackID].Report(now)
+				if r == nil {
+					continue
+				}
+
+				r, err := srtpEncryptFrame(c.srtpContexts[trackID], StreamTypeRTCP, r)
+				if err != nil {
+					c.conf.Logger.Warnf("RTCP packet of track %d discarded: %s", trackID, err)
+					continue
+				}
+				c.udpRTCPListeners[trackID].write(r)
+			}
+
+			c.publishWriteMutex.Lock()
+			for trackID := range c.rtcpSenders {
+				r := c.rtcpSenders[trackID].Report(now)
+				if r == nil {
+					continue
+				}
+
+				r, err := srtpEncryptFrame(c.srtpContexts[trackID], StreamTypeRTCP, r)
+				if err != nil {
+					c.conf.Logger.Warnf("RTCP packet of track %d discarded: %s", trackID, err)
+					continue
+				}
 				c.udpRTCPListeners[trackID].write(r)
 			}
+			c.publishWriteMutex.Unlock()
 
 		case <-keepaliveTicker.C:
-			_, err := c.Do(&base.Request{
-				Method: func() base.Method {
-					// the vlc integrated rtsp server requires GET_PARAMETER
-					if c.getParameterSupported {
-						return base.GetParameter
-					}
-					return base.Options
-				}(),
-				// use the stream path, otherwise some cameras do not reply
-				URL:          c.streamURL,
-				SkipResponse: true,
-			})
+			err := c.keepalive()
 			if err != nil {
 				c.nconn.SetReadDeadline(time.Now())
 				<-readerDone
-				returnError = err
-				return
+				return err
+			}
+
+		case <-initialUDPReadTimer:
+			if atomic.LoadInt32(&c.udpFramesReceived) == 0 {
+				c.nconn.SetReadDeadline(time.Now())
+				<-readerDone
+				return errClientSwitchToTCP
+			}
+
+		case <-reorderFlushTickerC:
+			for trackID, r := range c.rtpReorderers {
+				c.udpRTPListeners[trackID].flushReorderer(r)
 			}
 
 		case <-checkStreamTicker.C:
@@ -146,53 +297,119 @@ func (c *ClientConn) backgroundPlayUDP(done chan error) {
 				if now.Sub(last) >= c.conf.ReadTimeout {
 					c.nconn.SetReadDeadline(time.Now())
 					<-readerDone
-					returnError = liberrors.ErrClientNoUDPPacketsRecently{}
-					return
+					return liberrors.ErrClientNoUDPPacketsRecently{}
 				}
 			}
 
+		case <-noRTPTickerC:
+			if c.noRTPTimedOut() {
+				c.nconn.SetReadDeadline(time.Now())
+				<-readerDone
+				return liberrors.ErrClientNoRTPPacketsRecently{}
+			}
+
+		case req := <-serverRequestRecv:
+			err := c.handleServerRequest(&req)
+			if err != nil {
+				c.nconn.SetReadDeadline(time.Now())
+				<-readerDone
+				return err
+			}
+
+		case trackID := <-c.byeRecv:
+			ended[trackID] = struct{}{}
+			if len(ended) >= len(c.rtcpReceivers) {
+				if c.conf.OnEndOfStream != nil {
+					c.conf.OnEndOfStream()
+				}
+
+				c.nconn.SetReadDeadline(time.Now())
+				<-readerDone
+				return liberrors.ErrClientEndOfStream{}
+			}
+
 		case err := <-readerDone:
-			returnError = err
-			return
+			return err
 		}
 	}
 }
 
-func (c *ClientConn) backgroundPlayTCP(done chan error) {
-	defer close(c.backgroundDone)
-
-	var returnError error
-
-	defer func() {
-		done <- returnError
-	}()
-
+func (c *ClientConn) backgroundPlayTCP() error {
 	readerDone := make(chan error)
+	serverRequestRecv := make(chan base.Request, clientConnServerRequestQueueSize)
 	go func() {
 		for {
 			frame := base.InterleavedFrame{
 				Payload: c.tcpFrameBuffer.Next(),
 			}
-			err := frame.Read(c.br)
+			req := base.Request{Limits: &c.conf.ReadLimits}
+			res := base.Response{Limits: &c.conf.ReadLimits}
+			what, err := base.ReadInterleavedFrameOrRequestOrResponse(&frame, &req, &res, c.br)
 			if err != nil {
 				readerDone <- err
 				return
 			}
 
-			c.rtcpReceivers[frame.TrackID].ProcessFrame(time.Now(), frame.StreamType, frame.Payload)
-			c.readCB(frame.TrackID, frame.StreamType, frame.Payload)
+			switch what.(type) {
+			case *base.Request:
+				c.queueServerRequest(serverRequestRecv, req)
+				continue
+
+			// ignore responses to keepalives
+			case *base.Response:
+				c.conf.Logger.Debugf("response ignored: %d %s", res.StatusCode, res.StatusMessage)
+				continue
+			}
+
+			payload, err := srtpDecryptFrame(c.srtpContexts[frame.TrackID], frame.StreamType, frame.Payload)
+			if err != nil {
+				c.conf.Logger.Warnf("frame discarded: %s", liberrors.ErrClientSRTPDecryptFailed{
+					TrackID: frame.TrackID,
+					Err:     err,
+				})
+				continue
+			}
+
+			now := time.Now()
+
+			if frame.StreamType == StreamTypeRTP {
+				atomic.StoreInt64(c.lastRTPTime, now.UnixNano())
+			}
+
+			if rr, ok := c.rtcpReceivers[frame.TrackID]; ok {
+				rr.ProcessFrame(now, frame.StreamType, payload)
+
+				if frame.StreamType == StreamTypeRTCP {
+					c.onBye(frame.TrackID, payload)
+				}
+			}
+			c.readCB(frame.TrackID, frame.StreamType, payload)
 		}
 	}()
 
 	reportTicker := time.NewTicker(clientConnReceiverReportPeriod)
 	defer reportTicker.Stop()
 
+	// tracks for which the server has sent a RTCP BYE packet
+	ended := make(map[int]struct{})
+
+	keepaliveTicker := time.NewTicker(c.keepalivePeriod())
+	defer keepaliveTicker.Stop()
+
 	// for some reason, SetReadDeadline() must always be called in the same
 	// goroutine, otherwise Read() freezes.
 	// therefore, we call it with a ticker.
 	deadlineTicker := time.NewTicker(1 * time.Second)
 	defer deadlineTicker.Stop()
 
+	// stop reading if no RTP packets have been received within NoRTPTimeout
+	var noRTPTickerC <-chan time.Time
+	if c.conf.NoRTPTimeout > 0 {
+		t := time.NewTicker(c.noRTPCheckPeriod())
+		defer t.Stop()
+		noRTPTickerC = t.C
+	}
+
 	for {
 		select {
 		case <-deadlineTicker.C:
@@ -201,13 +418,22 @@ func (c *ClientConn) backgroundPlayTCP(done chan error) {
 		case <-c.backgroundTerminate:
 			c.nconn.SetReadDeadline(time.Now())
 			<-readerDone
-			returnError = fmt.Errorf("terminated")
-			return
+			return liberrors.ErrClientTerminated{}
 
 		case <-reportTicker.C:
+			c.publishWriteMutex.Lock()
 			now := time.Now()
 			for trackID := range c.rtcpReceivers {
 				r := c.rtcpReceivers[trackID].Report(now)
+				if r == nil {
+					continue
+				}
+
+				r, err := srtpEncryptFrame(c.srtpContexts[trackID], StreamTypeRTCP, r)
+				if err != nil {
+					c.conf.Logger.Warnf("RTCP packet of track %d discarded: %s", trackID, err)
+					continue
+				}
 				c.nconn.SetWriteDeadline(time.Now().Add(c.conf.WriteTimeout))
 				frame := base.InterleavedFrame{
 					TrackID:    trackID,
@@ -217,13 +443,87 @@ func (c *ClientConn) backgroundPlayTCP(done chan error) {
 				frame.Write(c.bw)
 			}
 
+			for trackID := range c.rtcpSenders {
+				r := c.rtcpSenders[trackID].Report(now)
+				if r == nil {
+					continue
+				}
+
+				r, err := srtpEncryptFrame(c.srtpContexts[trackID], StreamTypeRTCP, r)
+				if err != nil {
+					c.conf.Logger.Warnf("RTCP packet of track %d discarded: %s", trackID, err)
+					continue
+				}
+				c.nconn.SetWriteDeadline(time.Now().Add(c.conf.WriteTimeout))
+				frame := base.InterleavedFrame{
+					TrackID:    trackID,
+					StreamType: StreamTypeRTCP,
+					Payload:    r,
+				}
+				frame.Write(c.bw)
+			}
+			c.publishWriteMutex.Unlock()
+
+		case <-keepaliveTicker.C:
+			c.publishWriteMutex.Lock()
+			err := c.keepalive()
+			c.publishWriteMutex.Unlock()
+			if err != nil {
+				c.nconn.SetReadDeadline(time.Now())
+				<-readerDone
+				return err
+			}
+
+		case <-noRTPTickerC:
+			if c.noRTPTimedOut() {
+				c.nconn.SetReadDeadline(time.Now())
+				<-readerDone
+				return liberrors.ErrClientNoRTPPacketsRecently{}
+			}
+
+		case req := <-serverRequestRecv:
+			err := c.handleServerRequest(&req)
+			if err != nil {
+				c.nconn.SetReadDeadline(time.Now())
+				<-readerDone
+				return err
+			}
+
+		case trackID := <-c.byeRecv:
+			ended[trackID] = struct{}{}
+			if len(ended) >= len(c.rtcpReceivers) {
+				if c.conf.OnEndOfStream != nil {
+					c.conf.OnEndOfStream()
+				}
+
+				c.nconn.SetReadDeadline(time.Now())
+				<-readerDone
+				return liberrors.ErrClientEndOfStream{}
+			}
+
 		case err := <-readerDone:
-			returnError = err
-			return
+			return err
 		}
 	}
 }
 
+// ReadPackets is like ReadFrames, but frames are parsed and passed to
+// onPacketRTP or onPacketRTCP. Both callbacks are optional.
+// Frames that can't be parsed are discarded.
+// The payload of RTP packets is valid only until the callback returns.
+// This can be called only after Play().
+func (c *ClientConn) ReadPackets(
+	onPacketRTP func(int, *rtp.Packet),
+	onPacketRTCP func(int, []rtcp.Packet),
+) chan error {
+	onFrame := wrapOnFrame(c.conf.Logger, nil, onPacketRTP, onPacketRTCP)
+	if onFrame == nil {
+		onFrame = func(int, StreamType, []byte) {}
+	}
+
+	return c.ReadFrames(onFrame)
+}
+
 // ReadFrames starts reading frames.
 // it returns a channel that is written when the reading stops.
 // This can be called only after Play().
@@ -241,14 +541,146 @@ func (c *ClientConn) ReadFrames(onFrame func(int, StreamType, []byte)) chan erro
 
 	c.state = clientConnStatePlay
 	c.readCB = onFrame
+	c.byeRecv = make(chan int, len(c.tracks))
+
+	// reset the RTP timeout, since reading may have been paused
+	atomic.StoreInt64(c.lastRTPTime, time.Now().UnixNano())
+
+	// allow writing frames to back channels
+	if len(c.rtcpSenders) > 0 {
+		c.publishOpen = true
+	}
 	c.backgroundTerminate = make(chan struct{})
 	c.backgroundDone = make(chan struct{})
 
-	if *c.streamProtocol == StreamProtocolUDP {
-		go c.backgroundPlayUDP(done)
-	} else {
-		go c.backgroundPlayTCP(done)
-	}
+	go c.backgroundPlay(done)
 
 	return done
 }
+
+func (c *ClientConn) backgroundPlay(done chan error) {
+	defer close(c.backgroundDone)
+
+	err := func() error {
+		if *c.streamProtocol == StreamProtocolUDP {
+			err := c.backgroundPlayUDP()
+			if err != errClientSwitchToTCP {
+				return err
+			}
+
+			err = c.switchProtocolToTCP()
+			if err != nil {
+				return err
+			}
+		}
+
+		return c.backgroundPlayTCP()
+	}()
+
+	c.publishWriteMutex.Lock()
+	c.publishOpen = false
+	c.publishError = err
+	c.publishWriteMutex.Unlock()
+
+	done <- err
+}
+
+// punchHoles sends dummy packets from the UDP ports of a track to the ones
+// of the server, in order to open NAT mappings and firewalls.
+func (c *ClientConn) punchHoles(trackID int) {
+	if c.conf.UDPHolePunchingDisable {
+		return
+	}
+
+	for i := 0; i < c.conf.UDPHolePunchingCount; i++ {
+		c.udpRTPListeners[trackID].write(
+			[]byte{0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00})
+
+		c.udpRTCPListeners[trackID].write(
+			[]byte{0x80, 0xc9, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00})
+	}
+}
+
+// switchProtocolToTCP closes the connection, opens a new one and
+// setups again all tracks with TCP.
+// The switch is interrupted when backgroundTerminate is closed.
+func (c *ClientConn) switchProtocolToTCP() error {
+	// stop writing frames to back channels until the switch is complete.
+	// The mutex is not held during the switch, therefore WriteFrame()
+	// does not block.
+	c.publishWriteMutex.Lock()
+	c.publishOpen = false
+	c.publishWriteMutex.Unlock()
+
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	defer ctxCancel()
+
+	terminate := c.backgroundTerminate
+	go func() {
+		select {
+		case <-terminate:
+			ctxCancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	err := c.switchProtocolToTCPInner(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return liberrors.ErrClientTerminated{}
+		}
+		return err
+	}
+
+	c.publishWriteMutex.Lock()
+	c.publishOpen = len(c.rtcpSenders) > 0
+	c.publishWriteMutex.Unlock()
+
+	return nil
+}
+
+func (c *ClientConn) switchProtocolToTCPInner(ctx context.Context) error {
+	c.DoContext(ctx, &base.Request{
+		Method:       base.Teardown,
+		URL:          c.streamURL,
+		SkipResponse: true,
+	})
+
+	c.nconn.Close()
+
+	tracks := c.tracks
+	modes := make(map[int]headers.TransportMode)
+	for _, track := range tracks {
+		if _, ok := c.rtcpSenders[track.ID]; ok {
+			modes[track.ID] = headers.TransportModeRecord
+		} else {
+			modes[track.ID] = headers.TransportModePlay
+		}
+	}
+	playRange := c.playRange
+
+	c.reset()
+
+	v := StreamProtocolTCP
+	c.streamProtocol = &v
+
+	err := c.connOpen(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, track := range tracks {
+		_, err := c.SetupContext(ctx, modes[track.ID], track, 0, 0)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = c.PlayContext(ctx, playRange)
+	if err != nil {
+		return err
+	}
+
+	c.state = clientConnStatePlay
+	return nil
+}
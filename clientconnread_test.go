@@ -4,15 +4,22 @@ import (
 	"bufio"
 	"crypto/tls"
 	"net"
+	"strconv"
 	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
 	"github.com/stretchr/testify/require"
 
 	"github.com/majoyz/gortsplib/pkg/base"
 	"github.com/majoyz/gortsplib/pkg/headers"
+	"github.com/majoyz/gortsplib/pkg/liberrors"
+	"github.com/majoyz/gortsplib/pkg/rtcpcompound"
+	"github.com/majoyz/gortsplib/pkg/rtpaac"
+	"github.com/majoyz/gortsplib/pkg/rtpmetadata"
 )
 
 func TestClientRead(t *testing.T) {
@@ -213,6 +220,9 @@ func TestClientReadAnyPort(t *testing.T) {
 			require.NoError(t, err)
 			defer l.Close()
 
+			discarded := make(chan struct{})
+			discardErr := make(chan error, 1)
+
 			serverDone := make(chan struct{})
 			defer func() { <-serverDone }()
 			go func() {
@@ -304,10 +314,28 @@ func TestClientReadAnyPort(t *testing.T) {
 					IP:   net.ParseIP("127.0.0.1"),
 					Port: th.ClientPorts[0],
 				})
+
+				<-discarded
+
+				// the client locks onto the port of the first packet
+				l2, err := net.ListenPacket("udp", "localhost:0")
+				require.NoError(t, err)
+				defer l2.Close()
+
+				l2.WriteTo([]byte("\x00\x00\x00\x00"), &net.UDPAddr{
+					IP:   net.ParseIP("127.0.0.1"),
+					Port: th.ClientPorts[0],
+				})
 			}()
 
 			conf := ClientConf{
 				AnyPortEnable: true,
+				OnUDPPacketDiscarded: func(err error) {
+					select {
+					case discardErr <- err:
+					default:
+					}
+				},
 			}
 
 			conn, err := conf.DialRead("rtsp://localhost:8554/teststream")
@@ -319,13 +347,18 @@ func TestClientReadAnyPort(t *testing.T) {
 			})
 
 			<-frameRecv
+			close(discarded)
+
+			err = <-discardErr
+			require.IsType(t, liberrors.ErrClientUDPSourceUnexpected{}, err)
+
 			conn.Close()
 			<-done
 		})
 	}
 }
 
-func TestClientReadAutomaticProtocol(t *testing.T) {
+func TestClientReadUDPQueue(t *testing.T) {
 	l, err := net.Listen("tcp", "localhost:8554")
 	require.NoError(t, err)
 	defer l.Close()
@@ -377,25 +410,25 @@ func TestClientReadAutomaticProtocol(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, base.Setup, req.Method)
 
-		err = base.Response{
-			StatusCode: base.StatusUnsupportedTransport,
-		}.Write(bconn.Writer)
+		var th headers.Transport
+		err = th.Read(req.Header["Transport"])
 		require.NoError(t, err)
 
-		err = req.Read(bconn.Reader)
+		l1, err := net.ListenPacket("udp", "localhost:34556")
 		require.NoError(t, err)
-		require.Equal(t, base.Setup, req.Method)
+		defer l1.Close()
 
 		err = base.Response{
 			StatusCode: base.StatusOK,
 			Header: base.Header{
 				"Transport": headers.Transport{
-					Protocol: StreamProtocolTCP,
+					Protocol: StreamProtocolUDP,
 					Delivery: func() *base.StreamDelivery {
 						v := base.StreamDeliveryUnicast
 						return &v
 					}(),
-					InterleavedIDs: &[2]int{0, 1},
+					ClientPorts: th.ClientPorts,
+					ServerPorts: &[2]int{34556, 34557},
 				}.Write(),
 			},
 		}.Write(bconn.Writer)
@@ -410,34 +443,63 @@ func TestClientReadAutomaticProtocol(t *testing.T) {
 		}.Write(bconn.Writer)
 		require.NoError(t, err)
 
-		err = base.InterleavedFrame{
-			TrackID:    0,
-			StreamType: StreamTypeRTP,
-			Payload:    []byte("\x00\x00\x00\x00"),
-		}.Write(bconn.Writer)
-		require.NoError(t, err)
+		time.Sleep(500 * time.Millisecond)
+
+		for i := byte(0); i < 20; i++ {
+			l1.WriteTo([]byte{0x80, 0x60, 0x00, i + 1, 0, 0, 0, 0, 0, 0, 0, 0, i}, &net.UDPAddr{
+				IP:   net.ParseIP("127.0.0.1"),
+				Port: th.ClientPorts[0],
+			})
+			time.Sleep(1 * time.Millisecond)
+		}
+
+		req.Read(bconn.Reader)
 	}()
 
-	conf := ClientConf{StreamProtocol: nil}
+	conf := ClientConf{
+		StreamProtocol: func() *StreamProtocol {
+			v := StreamProtocolUDP
+			return &v
+		}(),
+		ReadBufferCount: 8,
+	}
 
 	conn, err := conf.DialRead("rtsp://localhost:8554/teststream")
 	require.NoError(t, err)
 
-	frameRecv := make(chan struct{})
+	unblock := make(chan struct{})
+	frameRecv := make(chan []byte, 20)
 	done := conn.ReadFrames(func(id int, typ StreamType, payload []byte) {
-		close(frameRecv)
+		// the callback is slow, but reading goes on
+		<-unblock
+		frameRecv <- append([]byte(nil), payload...)
 	})
 
-	<-frameRecv
+	time.Sleep(1 * time.Second)
+	close(unblock)
+	time.Sleep(500 * time.Millisecond)
+
+	// the queue can't contain all frames, the oldest ones are discarded,
+	// while queued frames are not overwritten by new ones
+	require.Equal(t, uint64(20-1-8), conn.DroppedFrames())
+	require.Equal(t, 1+8, len(frameRecv))
+
+	for i := 0; i < 1+8; i++ {
+		payload := <-frameRecv
+		require.Equal(t, payload[3], payload[12]+1)
+	}
+
 	conn.Close()
 	<-done
 }
 
-func TestClientReadRedirect(t *testing.T) {
+func TestClientReadUDPHolePunching(t *testing.T) {
 	l, err := net.Listen("tcp", "localhost:8554")
 	require.NoError(t, err)
 	defer l.Close()
 
+	punched := make(chan struct{})
+
 	serverDone := make(chan struct{})
 	defer func() { <-serverDone }()
 	go func() {
@@ -445,6 +507,7 @@ func TestClientReadRedirect(t *testing.T) {
 
 		conn, err := l.Accept()
 		require.NoError(t, err)
+		defer conn.Close()
 		bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
 
 		var req base.Request
@@ -468,41 +531,6 @@ func TestClientReadRedirect(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, base.Describe, req.Method)
 
-		err = base.Response{
-			StatusCode: base.StatusMovedPermanently,
-			Header: base.Header{
-				"Location": base.HeaderValue{"rtsp://localhost:8554/test"},
-			},
-		}.Write(bconn.Writer)
-		require.NoError(t, err)
-
-		conn.Close()
-
-		conn, err = l.Accept()
-		require.NoError(t, err)
-		defer conn.Close()
-		bconn = bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
-
-		err = req.Read(bconn.Reader)
-		require.NoError(t, err)
-		require.Equal(t, base.Options, req.Method)
-
-		err = base.Response{
-			StatusCode: base.StatusOK,
-			Header: base.Header{
-				"Public": base.HeaderValue{strings.Join([]string{
-					string(base.Describe),
-					string(base.Setup),
-					string(base.Play),
-				}, ", ")},
-			},
-		}.Write(bconn.Writer)
-		require.NoError(t, err)
-
-		err = req.Read(bconn.Reader)
-		require.NoError(t, err)
-		require.Equal(t, base.Describe, req.Method)
-
 		track, err := NewTrackH264(96, []byte("123456"), []byte("123456"))
 		require.NoError(t, err)
 
@@ -523,6 +551,10 @@ func TestClientReadRedirect(t *testing.T) {
 		err = th.Read(req.Header["Transport"])
 		require.NoError(t, err)
 
+		l1, err := net.ListenPacket("udp", "localhost:34556")
+		require.NoError(t, err)
+		defer l1.Close()
+
 		err = base.Response{
 			StatusCode: base.StatusOK,
 			Header: base.Header{
@@ -539,89 +571,57 @@ func TestClientReadRedirect(t *testing.T) {
 		}.Write(bconn.Writer)
 		require.NoError(t, err)
 
-		err = req.Read(bconn.Reader)
-		require.NoError(t, err)
-		require.Equal(t, base.Play, req.Method)
-
-		err = base.Response{
-			StatusCode: base.StatusOK,
-		}.Write(bconn.Writer)
-		require.NoError(t, err)
+		// dummy packets are sent after SETUP and after PLAY
+		for i := 0; i < 4; i++ {
+			if i == 2 {
+				err = req.Read(bconn.Reader)
+				require.NoError(t, err)
+				require.Equal(t, base.Play, req.Method)
 
-		time.Sleep(1 * time.Second)
+				err = base.Response{
+					StatusCode: base.StatusOK,
+				}.Write(bconn.Writer)
+				require.NoError(t, err)
+			}
 
-		l1, err := net.ListenPacket("udp", "localhost:34556")
-		require.NoError(t, err)
-		defer l1.Close()
+			buf := make([]byte, 2048)
+			l1.SetReadDeadline(time.Now().Add(2 * time.Second))
+			n, addr, err := l1.ReadFrom(buf)
+			require.NoError(t, err)
+			require.Equal(t, th.ClientPorts[0], addr.(*net.UDPAddr).Port)
+			require.Equal(t, []byte{0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, buf[:n])
+		}
 
-		l1.WriteTo([]byte("\x00\x00\x00\x00"), &net.UDPAddr{
-			IP:   net.ParseIP("127.0.0.1"),
-			Port: th.ClientPorts[0],
-		})
+		close(punched)
+		req.Read(bconn.Reader)
 	}()
 
-	conn, err := DialRead("rtsp://localhost:8554/path1")
+	conf := ClientConf{
+		StreamProtocol: func() *StreamProtocol {
+			v := StreamProtocolUDP
+			return &v
+		}(),
+		UDPHolePunchingCount: 2,
+	}
+
+	conn, err := conf.DialRead("rtsp://localhost:8554/teststream")
 	require.NoError(t, err)
 
-	frameRecv := make(chan struct{})
 	done := conn.ReadFrames(func(id int, typ StreamType, payload []byte) {
-		close(frameRecv)
 	})
 
-	<-frameRecv
+	<-punched
+
 	conn.Close()
 	<-done
 }
 
-func TestClientReadPause(t *testing.T) {
-	writeFrames := func(inTH *headers.Transport, bconn *bufio.ReadWriter) (chan struct{}, chan struct{}) {
-		writerTerminate := make(chan struct{})
-		writerDone := make(chan struct{})
-
-		go func() {
-			defer close(writerDone)
-
-			var l1 net.PacketConn
-			if inTH.Protocol == StreamProtocolUDP {
-				var err error
-				l1, err = net.ListenPacket("udp", "localhost:34556")
-				require.NoError(t, err)
-				defer l1.Close()
-			}
-
-			t := time.NewTicker(50 * time.Millisecond)
-			defer t.Stop()
-
-			for {
-				select {
-				case <-t.C:
-					if inTH.Protocol == StreamProtocolUDP {
-						l1.WriteTo([]byte("\x00\x00\x00\x00"), &net.UDPAddr{
-							IP:   net.ParseIP("127.0.0.1"),
-							Port: inTH.ClientPorts[0],
-						})
-					} else {
-						base.InterleavedFrame{
-							TrackID:    0,
-							StreamType: StreamTypeRTP,
-							Payload:    []byte("\x00\x00\x00\x00"),
-						}.Write(bconn.Writer)
-					}
-
-				case <-writerTerminate:
-					return
-				}
-			}
-		}()
-
-		return writerTerminate, writerDone
-	}
-
-	for _, proto := range []string{
-		"udp",
-		"tcp",
+func TestClientReadUDPFilter(t *testing.T) {
+	for _, ca := range []string{
+		"ssrc filter enabled",
+		"ssrc filter disabled",
 	} {
-		t.Run(proto, func(t *testing.T) {
+		t.Run(ca, func(t *testing.T) {
 			l, err := net.Listen("tcp", "localhost:8554")
 			require.NoError(t, err)
 			defer l.Close()
@@ -633,6 +633,7 @@ func TestClientReadPause(t *testing.T) {
 
 				conn, err := l.Accept()
 				require.NoError(t, err)
+				defer conn.Close()
 				bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
 
 				var req base.Request
@@ -672,31 +673,30 @@ func TestClientReadPause(t *testing.T) {
 				require.NoError(t, err)
 				require.Equal(t, base.Setup, req.Method)
 
-				var inTH headers.Transport
-				err = inTH.Read(req.Header["Transport"])
+				var th headers.Transport
+				err = th.Read(req.Header["Transport"])
 				require.NoError(t, err)
 
-				th := headers.Transport{
-					Delivery: func() *base.StreamDelivery {
-						v := base.StreamDeliveryUnicast
-						return &v
-					}(),
-				}
-
-				if proto == "udp" {
-					th.Protocol = StreamProtocolUDP
-					th.ServerPorts = &[2]int{34556, 34557}
-					th.ClientPorts = inTH.ClientPorts
-
-				} else {
-					th.Protocol = StreamProtocolTCP
-					th.InterleavedIDs = inTH.InterleavedIDs
-				}
+				l1, err := net.ListenPacket("udp", "localhost:34556")
+				require.NoError(t, err)
+				defer l1.Close()
 
 				err = base.Response{
 					StatusCode: base.StatusOK,
 					Header: base.Header{
-						"Transport": th.Write(),
+						"Transport": headers.Transport{
+							Protocol: StreamProtocolUDP,
+							Delivery: func() *base.StreamDelivery {
+								v := base.StreamDeliveryUnicast
+								return &v
+							}(),
+							ClientPorts: th.ClientPorts,
+							ServerPorts: &[2]int{34556, 34557},
+							SSRC: func() *uint32 {
+								v := uint32(0x11223344)
+								return &v
+							}(),
+						}.Write(),
 					},
 				}.Write(bconn.Writer)
 				require.NoError(t, err)
@@ -710,82 +710,2289 @@ func TestClientReadPause(t *testing.T) {
 				}.Write(bconn.Writer)
 				require.NoError(t, err)
 
-				writerTerminate, writerDone := writeFrames(&inTH, bconn)
-
-				err = req.Read(bconn.Reader)
-				require.NoError(t, err)
-				require.Equal(t, base.Pause, req.Method)
-
-				close(writerTerminate)
-				<-writerDone
-
-				err = base.Response{
-					StatusCode: base.StatusOK,
-				}.Write(bconn.Writer)
-				require.NoError(t, err)
+				time.Sleep(500 * time.Millisecond)
 
-				err = req.Read(bconn.Reader)
+				l2, err := net.ListenPacket("udp", "localhost:34558")
 				require.NoError(t, err)
-				require.Equal(t, base.Play, req.Method)
+				defer l2.Close()
 
-				err = base.Response{
-					StatusCode: base.StatusOK,
-				}.Write(bconn.Writer)
-				require.NoError(t, err)
+				clientAddr := &net.UDPAddr{
+					IP:   net.ParseIP("127.0.0.1"),
+					Port: th.ClientPorts[0],
+				}
 
-				writerTerminate, writerDone = writeFrames(&inTH, bconn)
+				// wrong source port
+				l2.WriteTo([]byte{0x80, 0x60, 0x00, 0x01, 0, 0, 0, 0, 0x11, 0x22, 0x33, 0x44, 0x01}, clientAddr)
 
-				err = req.Read(bconn.Reader)
-				require.NoError(t, err)
-				require.Equal(t, base.Teardown, req.Method)
+				// wrong SSRC
+				l1.WriteTo([]byte{0x80, 0x60, 0x00, 0x02, 0, 0, 0, 0, 0x55, 0x66, 0x77, 0x88, 0x02}, clientAddr)
 
-				close(writerTerminate)
-				<-writerDone
+				l1.WriteTo([]byte{0x80, 0x60, 0x00, 0x03, 0, 0, 0, 0, 0x11, 0x22, 0x33, 0x44, 0x03}, clientAddr)
 
-				conn.Close()
+				req.Read(bconn.Reader)
 			}()
 
 			conf := ClientConf{
 				StreamProtocol: func() *StreamProtocol {
-					if proto == "udp" {
-						v := StreamProtocolUDP
-						return &v
-					}
-					v := StreamProtocolTCP
+					v := StreamProtocolUDP
 					return &v
 				}(),
+				SSRCFilterDisable: ca == "ssrc filter disabled",
+			}
+
+			discarded := make(chan error, 2)
+			conf.OnUDPPacketDiscarded = func(err error) {
+				discarded <- err
 			}
 
 			conn, err := conf.DialRead("rtsp://localhost:8554/teststream")
 			require.NoError(t, err)
 
-			firstFrame := int32(0)
-			frameRecv := make(chan struct{})
+			frameRecv := make(chan []byte, 1)
 			done := conn.ReadFrames(func(id int, typ StreamType, payload []byte) {
-				if atomic.SwapInt32(&firstFrame, 1) == 0 {
-					close(frameRecv)
-				}
+				frameRecv <- append([]byte(nil), payload...)
 			})
 
-			<-frameRecv
-			_, err = conn.Pause()
-			require.NoError(t, err)
-			<-done
+			err = <-discarded
+			require.Equal(t, "received a UDP packet of track 0 from an unexpected address (127.0.0.1:34558)", err.Error())
 
-			_, err = conn.Play()
-			require.NoError(t, err)
+			if ca == "ssrc filter enabled" {
+				err = <-discarded
+				require.Equal(t, liberrors.ErrClientRTPSSRCUnexpected{
+					TrackID:  0,
+					Expected: 0x11223344,
+					Value:    0x55667788,
+				}, err)
+			} else {
+				payload := <-frameRecv
+				require.Equal(t, byte(0x02), payload[12])
+			}
 
-			firstFrame = int32(0)
-			frameRecv = make(chan struct{})
-			done = conn.ReadFrames(func(id int, typ StreamType, payload []byte) {
-				if atomic.SwapInt32(&firstFrame, 1) == 0 {
-					close(frameRecv)
-				}
-			})
+			payload := <-frameRecv
+			require.Equal(t, byte(0x03), payload[12])
 
-			<-frameRecv
 			conn.Close()
 			<-done
 		})
 	}
 }
+
+func TestClientReadMetadata(t *testing.T) {
+	s, err := Serve("127.0.0.1:8554")
+	require.NoError(t, err)
+
+	track, err := NewTrackH264(96, []byte("123456"), []byte("123456"))
+	require.NoError(t, err)
+
+	stream := NewServerStream(Tracks{track, NewTrackMetadata(107)})
+	defer stream.Close()
+
+	handleDone := make(chan struct{})
+	defer func() { <-handleDone }()
+	defer s.Close()
+
+	go func() {
+		defer close(handleDone)
+
+		s.Handle(ServerHandler{
+			OnConnOpen: func(sc *ServerConn) ServerConnReadHandlers {
+				return ServerConnReadHandlers{
+					OnDescribe: func(ctx *ServerConnDescribeCtx) (*base.Response, []byte, error) {
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, stream.Tracks().Write(), nil
+					},
+					OnSetup: func(ctx *ServerConnSetupCtx) (*base.Response, error) {
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, nil
+					},
+					OnPlay: func(ctx *ServerConnPlayCtx) (*base.Response, error) {
+						stream.AddReader(sc)
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, nil
+					},
+				}
+			},
+			OnConnClose: func(sc *ServerConn, err error) {
+				stream.RemoveReader(sc)
+			},
+		})
+	}()
+
+	v := StreamProtocolTCP
+	conf := ClientConf{StreamProtocol: &v}
+
+	conn, err := conf.DialRead("rtsp://localhost:8554/teststream")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.Equal(t, true, conn.Tracks()[1].IsMetadata())
+
+	docRecv := make(chan []byte)
+	dec := rtpmetadata.NewDecoder()
+	conn.ReadFrames(func(id int, typ StreamType, payload []byte) {
+		if id != 1 || typ != StreamTypeRTP {
+			return
+		}
+
+		doc, err := dec.Decode(payload)
+		if err != nil {
+			return
+		}
+		docRecv <- doc.Document
+	})
+
+	for i, part := range []string{`<tt:MetadataStream>`, `</tt:MetadataStream>`} {
+		pkt, err := (&rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				Marker:         i == 1,
+				PayloadType:    107,
+				SequenceNumber: uint16(100 + i),
+				Timestamp:      90000,
+				SSRC:           0x9dbb7812,
+			},
+			Payload: []byte(part),
+		}).Marshal()
+		require.NoError(t, err)
+		stream.WriteFrame(1, StreamTypeRTP, pkt)
+	}
+
+	require.Equal(t, []byte(`<tt:MetadataStream></tt:MetadataStream>`), <-docRecv)
+}
+
+func TestClientReadAutomaticProtocol(t *testing.T) {
+	l, err := net.Listen("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer l.Close()
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		conn, err := l.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+		bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+		var req base.Request
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Options, req.Method)
+
+		err = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Public": base.HeaderValue{strings.Join([]string{
+					string(base.Describe),
+					string(base.Setup),
+					string(base.Play),
+				}, ", ")},
+			},
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Describe, req.Method)
+
+		track, err := NewTrackH264(96, []byte("123456"), []byte("123456"))
+		require.NoError(t, err)
+
+		err = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Content-Type": base.HeaderValue{"application/sdp"},
+			},
+			Body: Tracks{track}.Write(),
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Setup, req.Method)
+
+		err = base.Response{
+			StatusCode: base.StatusUnsupportedTransport,
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Setup, req.Method)
+
+		err = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Transport": headers.Transport{
+					Protocol: StreamProtocolTCP,
+					Delivery: func() *base.StreamDelivery {
+						v := base.StreamDeliveryUnicast
+						return &v
+					}(),
+					InterleavedIDs: &[2]int{0, 1},
+				}.Write(),
+			},
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Play, req.Method)
+
+		err = base.Response{
+			StatusCode: base.StatusOK,
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		err = base.InterleavedFrame{
+			TrackID:    0,
+			StreamType: StreamTypeRTP,
+			Payload:    []byte("\x00\x00\x00\x00"),
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+	}()
+
+	conf := ClientConf{StreamProtocol: nil}
+
+	conn, err := conf.DialRead("rtsp://localhost:8554/teststream")
+	require.NoError(t, err)
+
+	frameRecv := make(chan struct{})
+	done := conn.ReadFrames(func(id int, typ StreamType, payload []byte) {
+		close(frameRecv)
+	})
+
+	<-frameRecv
+	conn.Close()
+	<-done
+}
+
+func TestClientReadBye(t *testing.T) {
+	for _, ca := range []string{
+		"enabled",
+		"disabled",
+	} {
+		t.Run(ca, func(t *testing.T) {
+			l, err := net.Listen("tcp", "localhost:8554")
+			require.NoError(t, err)
+			defer l.Close()
+
+			serverDone := make(chan struct{})
+			defer func() { <-serverDone }()
+			go func() {
+				defer close(serverDone)
+
+				conn, err := l.Accept()
+				require.NoError(t, err)
+				defer conn.Close()
+				bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+				var req base.Request
+				err = req.Read(bconn.Reader)
+				require.NoError(t, err)
+				require.Equal(t, base.Options, req.Method)
+
+				err = base.Response{
+					StatusCode: base.StatusOK,
+					Header: base.Header{
+						"Public": base.HeaderValue{strings.Join([]string{
+							string(base.Describe),
+							string(base.Setup),
+							string(base.Play),
+						}, ", ")},
+					},
+				}.Write(bconn.Writer)
+				require.NoError(t, err)
+
+				err = req.Read(bconn.Reader)
+				require.NoError(t, err)
+				require.Equal(t, base.Describe, req.Method)
+
+				track, err := NewTrackH264(96, []byte("123456"), []byte("123456"))
+				require.NoError(t, err)
+
+				err = base.Response{
+					StatusCode: base.StatusOK,
+					Header: base.Header{
+						"Content-Type": base.HeaderValue{"application/sdp"},
+					},
+					Body: Tracks{track}.Write(),
+				}.Write(bconn.Writer)
+				require.NoError(t, err)
+
+				err = req.Read(bconn.Reader)
+				require.NoError(t, err)
+				require.Equal(t, base.Setup, req.Method)
+
+				err = base.Response{
+					StatusCode: base.StatusOK,
+					Header: base.Header{
+						"Transport": headers.Transport{
+							Protocol: StreamProtocolTCP,
+							Delivery: func() *base.StreamDelivery {
+								v := base.StreamDeliveryUnicast
+								return &v
+							}(),
+							InterleavedIDs: &[2]int{0, 1},
+						}.Write(),
+					},
+				}.Write(bconn.Writer)
+				require.NoError(t, err)
+
+				err = req.Read(bconn.Reader)
+				require.NoError(t, err)
+				require.Equal(t, base.Play, req.Method)
+
+				err = base.Response{
+					StatusCode: base.StatusOK,
+				}.Write(bconn.Writer)
+				require.NoError(t, err)
+
+				// BYE packets of other sources are ignored
+				for i, ssrc := range []uint32{0x05060708, 0x01020304} {
+					err = base.InterleavedFrame{
+						TrackID:    0,
+						StreamType: StreamTypeRTP,
+						Payload:    []byte{0x80, 0x60, 0x00, byte(i + 1), 0, 0, 0, 0, 0x01, 0x02, 0x03, 0x04},
+					}.Write(bconn.Writer)
+					require.NoError(t, err)
+
+					bye, err := rtcpcompound.Bye(ssrc, "server", "")
+					require.NoError(t, err)
+
+					err = base.InterleavedFrame{
+						TrackID:    0,
+						StreamType: StreamTypeRTCP,
+						Payload:    bye,
+					}.Write(bconn.Writer)
+					require.NoError(t, err)
+
+					time.Sleep(100 * time.Millisecond)
+				}
+
+				if ca == "disabled" {
+					err = req.ReadIgnoreFrames(bconn.Reader, make([]byte, 1024))
+					require.NoError(t, err)
+					require.Equal(t, base.Teardown, req.Method)
+					return
+				}
+
+				// the client replies with its own BYE, then tears down the session
+				var frame base.InterleavedFrame
+				frame.Payload = make([]byte, 1024)
+				err = frame.Read(bconn.Reader)
+				require.NoError(t, err)
+				require.Equal(t, StreamTypeRTCP, frame.StreamType)
+				require.Equal(t, true, rtcpcompound.IsBye(frame.Payload))
+
+				err = req.Read(bconn.Reader)
+				require.NoError(t, err)
+				require.Equal(t, base.Teardown, req.Method)
+
+				err = base.Response{
+					StatusCode: base.StatusOK,
+				}.Write(bconn.Writer)
+				require.NoError(t, err)
+			}()
+
+			v := StreamProtocolTCP
+			conf := ClientConf{
+				StreamProtocol:     &v,
+				EndOfStreamDisable: ca == "disabled",
+			}
+
+			conn, err := conf.DialRead("rtsp://localhost:8554/teststream")
+			require.NoError(t, err)
+			defer conn.Close()
+
+			secondRecv := make(chan struct{})
+			done := conn.ReadFrames(func(id int, typ StreamType, payload []byte) {
+				if typ == StreamTypeRTP && payload[3] == 0x02 {
+					close(secondRecv)
+				}
+			})
+
+			if ca == "disabled" {
+				<-secondRecv
+				time.Sleep(200 * time.Millisecond)
+				conn.Close()
+				<-done
+				return
+			}
+
+			err = <-done
+			require.Equal(t, liberrors.ErrClientEndOfStream{}, err)
+
+			// reading stopped after the BYE of the source of the track
+			select {
+			case <-secondRecv:
+			default:
+				t.Errorf("reading stopped too early")
+			}
+		})
+	}
+}
+
+func TestClientReadSetupTransportAlternatives(t *testing.T) {
+	l, err := net.Listen("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer l.Close()
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		conn, err := l.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+		bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+		var req base.Request
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Options, req.Method)
+
+		err = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Public": base.HeaderValue{strings.Join([]string{
+					string(base.Describe),
+					string(base.Setup),
+					string(base.Play),
+				}, ", ")},
+			},
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Describe, req.Method)
+
+		track, err := NewTrackH264(96, []byte("123456"), []byte("123456"))
+		require.NoError(t, err)
+
+		err = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Content-Type": base.HeaderValue{"application/sdp"},
+			},
+			Body: Tracks{track}.Write(),
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Setup, req.Method)
+
+		var ths headers.Transports
+		err = ths.Read(req.Header["Transport"])
+		require.NoError(t, err)
+		require.Equal(t, 2, len(ths))
+		require.Equal(t, StreamProtocolUDP, ths[0].Protocol)
+		require.NotNil(t, ths[0].ClientPorts)
+		require.Equal(t, StreamProtocolTCP, ths[1].Protocol)
+		require.Equal(t, &[2]int{0, 1}, ths[1].InterleavedIDs)
+
+		err = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Transport": headers.Transport{
+					Protocol: StreamProtocolTCP,
+					Delivery: func() *base.StreamDelivery {
+						v := base.StreamDeliveryUnicast
+						return &v
+					}(),
+					InterleavedIDs: &[2]int{0, 1},
+				}.Write(),
+			},
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Play, req.Method)
+
+		err = base.Response{
+			StatusCode: base.StatusOK,
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		err = base.InterleavedFrame{
+			TrackID:    0,
+			StreamType: StreamTypeRTP,
+			Payload:    []byte("\x00\x00\x00\x00"),
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+	}()
+
+	conf := ClientConf{SetupTransportAlternatives: true}
+
+	conn, err := conf.DialRead("rtsp://localhost:8554/teststream")
+	require.NoError(t, err)
+
+	frameRecv := make(chan struct{})
+	done := conn.ReadFrames(func(id int, typ StreamType, payload []byte) {
+		close(frameRecv)
+	})
+
+	<-frameRecv
+	conn.Close()
+	<-done
+}
+
+func TestClientReadSetupAll(t *testing.T) {
+	for _, proto := range []string{
+		"udp",
+		"tcp",
+	} {
+		t.Run(proto, func(t *testing.T) {
+			l, err := net.Listen("tcp", "localhost:8554")
+			require.NoError(t, err)
+			defer l.Close()
+
+			track1, err := NewTrackH264(96, []byte("123456"), []byte("123456"))
+			require.NoError(t, err)
+
+			track2, err := NewTrackH264(97, []byte("123456"), []byte("123456"))
+			require.NoError(t, err)
+
+			track3, err := NewTrackH264(98, []byte("123456"), []byte("123456"))
+			require.NoError(t, err)
+
+			tracks := Tracks{track1, track2, track3}
+
+			serverDone := make(chan struct{})
+			defer func() { <-serverDone }()
+			go func() {
+				defer close(serverDone)
+
+				conn, err := l.Accept()
+				require.NoError(t, err)
+				defer conn.Close()
+				bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+				var req base.Request
+				err = req.Read(bconn.Reader)
+				require.NoError(t, err)
+				require.Equal(t, base.Options, req.Method)
+
+				err = base.Response{
+					StatusCode: base.StatusOK,
+					Header: base.Header{
+						"Public": base.HeaderValue{strings.Join([]string{
+							string(base.Describe),
+							string(base.Setup),
+							string(base.Play),
+						}, ", ")},
+					},
+				}.Write(bconn.Writer)
+				require.NoError(t, err)
+
+				err = req.Read(bconn.Reader)
+				require.NoError(t, err)
+				require.Equal(t, base.Describe, req.Method)
+
+				err = base.Response{
+					StatusCode: base.StatusOK,
+					Header: base.Header{
+						"Content-Type": base.HeaderValue{"application/sdp"},
+					},
+					Body: tracks.Write(),
+				}.Write(bconn.Writer)
+				require.NoError(t, err)
+
+				transportResponse := func(th headers.Transport, trackID int) base.HeaderValue {
+					ret := headers.Transport{
+						Protocol: th.Protocol,
+						Delivery: func() *base.StreamDelivery {
+							v := base.StreamDeliveryUnicast
+							return &v
+						}(),
+					}
+					if th.Protocol == StreamProtocolUDP {
+						ret.ClientPorts = th.ClientPorts
+						ret.ServerPorts = &[2]int{34556 + trackID*2, 34557 + trackID*2}
+					} else {
+						ret.InterleavedIDs = th.InterleavedIDs
+					}
+					return ret.Write()
+				}
+
+				err = req.Read(bconn.Reader)
+				require.NoError(t, err)
+				require.Equal(t, base.Setup, req.Method)
+				require.Equal(t, base.MustParseURL("rtsp://localhost:8554/teststream/trackID=0"), req.URL)
+
+				var th headers.Transport
+				err = th.Read(req.Header["Transport"])
+				require.NoError(t, err)
+
+				err = base.Response{
+					StatusCode: base.StatusOK,
+					Header: base.Header{
+						"Transport": transportResponse(th, 0),
+						"Session":   base.HeaderValue{"ABCDE"},
+					},
+				}.Write(bconn.Writer)
+				require.NoError(t, err)
+
+				// the remaining requests are received before the responses are sent
+				var ths []headers.Transport
+				for i := 1; i < 3; i++ {
+					err = req.Read(bconn.Reader)
+					require.NoError(t, err)
+					require.Equal(t, base.Setup, req.Method)
+					require.Equal(t, base.MustParseURL(
+						"rtsp://localhost:8554/teststream/trackID="+strconv.Itoa(i)), req.URL)
+					require.Equal(t, base.HeaderValue{"ABCDE"}, req.Header["Session"])
+
+					var th2 headers.Transport
+					err = th2.Read(req.Header["Transport"])
+					require.NoError(t, err)
+
+					if proto == "udp" {
+						require.Equal(t, StreamProtocolUDP, th2.Protocol)
+						require.NotEqual(t, th.ClientPorts, th2.ClientPorts)
+					} else {
+						require.Equal(t, StreamProtocolTCP, th2.Protocol)
+						require.Equal(t, &[2]int{i * 2, i*2 + 1}, th2.InterleavedIDs)
+					}
+
+					ths = append(ths, th2)
+				}
+
+				for i, th2 := range ths {
+					err = base.Response{
+						StatusCode: base.StatusOK,
+						Header: base.Header{
+							"Transport": transportResponse(th2, i+1),
+							"Session":   base.HeaderValue{"ABCDE"},
+						},
+					}.Write(bconn.Writer)
+					require.NoError(t, err)
+				}
+
+				err = req.Read(bconn.Reader)
+				require.NoError(t, err)
+				require.Equal(t, base.Play, req.Method)
+
+				err = base.Response{
+					StatusCode: base.StatusOK,
+				}.Write(bconn.Writer)
+				require.NoError(t, err)
+
+				err = req.Read(bconn.Reader)
+				require.NoError(t, err)
+				require.Equal(t, base.Teardown, req.Method)
+
+				err = base.Response{
+					StatusCode: base.StatusOK,
+				}.Write(bconn.Writer)
+				require.NoError(t, err)
+			}()
+
+			u, err := base.ParseURL("rtsp://localhost:8554/teststream")
+			require.NoError(t, err)
+
+			conn, err := ClientConf{}.Dial(u.Scheme, u.Host)
+			require.NoError(t, err)
+			defer conn.Close()
+
+			_, err = conn.Options(u)
+			require.NoError(t, err)
+
+			describedTracks, _, err := conn.Describe(u)
+			require.NoError(t, err)
+
+			v := StreamProtocolUDP
+			if proto == "tcp" {
+				v = StreamProtocolTCP
+			}
+
+			ress, err := conn.SetupAll(describedTracks, &v)
+			require.NoError(t, err)
+			require.Equal(t, 3, len(ress))
+			require.Equal(t, 3, len(conn.Tracks()))
+			require.Equal(t, v, *conn.StreamProtocol())
+
+			_, err = conn.Play(nil)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestClientReadAutomaticProtocolNoUDPPackets(t *testing.T) {
+	l, err := net.Listen("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer l.Close()
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		conn, err := l.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+		bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+		var req base.Request
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Options, req.Method)
+
+		err = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Public": base.HeaderValue{strings.Join([]string{
+					string(base.Describe),
+					string(base.Setup),
+					string(base.Play),
+				}, ", ")},
+			},
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Describe, req.Method)
+
+		track, err := NewTrackH264(96, []byte("123456"), []byte("123456"))
+		require.NoError(t, err)
+
+		err = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Content-Type": base.HeaderValue{"application/sdp"},
+			},
+			Body: Tracks{track}.Write(),
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Setup, req.Method)
+
+		var th headers.Transport
+		err = th.Read(req.Header["Transport"])
+		require.NoError(t, err)
+		require.Equal(t, StreamProtocolUDP, th.Protocol)
+
+		err = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Transport": headers.Transport{
+					Protocol: StreamProtocolUDP,
+					Delivery: func() *base.StreamDelivery {
+						v := base.StreamDeliveryUnicast
+						return &v
+					}(),
+					ClientPorts: th.ClientPorts,
+					ServerPorts: &[2]int{34556, 34557},
+				}.Write(),
+				"Session": base.HeaderValue{"ABCDEF"},
+			},
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Play, req.Method)
+
+		err = base.Response{
+			StatusCode: base.StatusOK,
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		// no UDP packets are sent, therefore the client tears down the session
+		// and setups the track again with TCP, in a new connection.
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Teardown, req.Method)
+
+		conn2, err := l.Accept()
+		require.NoError(t, err)
+		defer conn2.Close()
+		bconn = bufio.NewReadWriter(bufio.NewReader(conn2), bufio.NewWriter(conn2))
+
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Setup, req.Method)
+		require.Equal(t, base.HeaderValue(nil), req.Header["Session"])
+
+		th = headers.Transport{}
+		err = th.Read(req.Header["Transport"])
+		require.NoError(t, err)
+		require.Equal(t, StreamProtocolTCP, th.Protocol)
+
+		err = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Transport": headers.Transport{
+					Protocol: StreamProtocolTCP,
+					Delivery: func() *base.StreamDelivery {
+						v := base.StreamDeliveryUnicast
+						return &v
+					}(),
+					InterleavedIDs: &[2]int{0, 1},
+				}.Write(),
+			},
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Play, req.Method)
+
+		err = base.Response{
+			StatusCode: base.StatusOK,
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		err = base.InterleavedFrame{
+			TrackID:    0,
+			StreamType: StreamTypeRTP,
+			Payload:    []byte("\x00\x00\x00\x00"),
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+	}()
+
+	conf := ClientConf{
+		StreamProtocol:        nil,
+		InitialUDPReadTimeout: 500 * time.Millisecond,
+	}
+
+	conn, err := conf.DialRead("rtsp://localhost:8554/teststream")
+	require.NoError(t, err)
+
+	frameRecv := make(chan struct{})
+	done := conn.ReadFrames(func(id int, typ StreamType, payload []byte) {
+		require.Equal(t, 0, id)
+		require.Equal(t, StreamTypeRTP, typ)
+		require.Equal(t, []byte("\x00\x00\x00\x00"), payload)
+		close(frameRecv)
+	})
+
+	<-frameRecv
+	require.Equal(t, StreamProtocolTCP, *conn.StreamProtocol())
+	conn.Close()
+	<-done
+}
+
+func TestClientReadAutomaticProtocolSwitchClose(t *testing.T) {
+	l, err := net.Listen("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer l.Close()
+
+	setupRecv := make(chan struct{})
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		conn, err := l.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+		bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+		var req base.Request
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Options, req.Method)
+
+		err = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Public": base.HeaderValue{strings.Join([]string{
+					string(base.Describe),
+					string(base.Setup),
+					string(base.Play),
+				}, ", ")},
+			},
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Describe, req.Method)
+
+		track, err := NewTrackH264(96, []byte("123456"), []byte("123456"))
+		require.NoError(t, err)
+
+		err = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Content-Type": base.HeaderValue{"application/sdp"},
+			},
+			Body: Tracks{track}.Write(),
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Setup, req.Method)
+
+		var th headers.Transport
+		err = th.Read(req.Header["Transport"])
+		require.NoError(t, err)
+		require.Equal(t, StreamProtocolUDP, th.Protocol)
+
+		err = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Transport": headers.Transport{
+					Protocol: StreamProtocolUDP,
+					Delivery: func() *base.StreamDelivery {
+						v := base.StreamDeliveryUnicast
+						return &v
+					}(),
+					ClientPorts: th.ClientPorts,
+					ServerPorts: &[2]int{34556, 34557},
+				}.Write(),
+				"Session": base.HeaderValue{"ABCDEF"},
+			},
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Play, req.Method)
+
+		err = base.Response{
+			StatusCode: base.StatusOK,
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		// no UDP packets are sent, therefore the client tears down the session
+		// and setups the track again with TCP, in a new connection.
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Teardown, req.Method)
+
+		conn2, err := l.Accept()
+		require.NoError(t, err)
+		defer conn2.Close()
+		bconn = bufio.NewReadWriter(bufio.NewReader(conn2), bufio.NewWriter(conn2))
+
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Setup, req.Method)
+		close(setupRecv)
+
+		// the response is not sent, therefore the switch can be interrupted
+		// only by closing the client.
+		_, err = bconn.Reader.ReadByte()
+		require.Error(t, err)
+	}()
+
+	conf := ClientConf{
+		StreamProtocol:        nil,
+		InitialUDPReadTimeout: 500 * time.Millisecond,
+	}
+
+	conn, err := conf.DialRead("rtsp://localhost:8554/teststream")
+	require.NoError(t, err)
+
+	done := conn.ReadFrames(func(id int, typ StreamType, payload []byte) {
+	})
+
+	<-setupRecv
+
+	closeDone := make(chan struct{})
+	go func() {
+		defer close(closeDone)
+		conn.Close()
+	}()
+
+	select {
+	case <-closeDone:
+	case <-time.After(2 * time.Second):
+		t.Errorf("Close() did not interrupt the switch")
+	}
+
+	err = <-done
+	require.Equal(t, liberrors.ErrClientTerminated{}, err)
+}
+
+func TestClientReadRedirect(t *testing.T) {
+	for _, ca := range []string{
+		"same host",
+		"same host, different case",
+		"different host",
+	} {
+		t.Run(ca, func(t *testing.T) {
+			var location string
+			switch ca {
+			case "same host":
+				location = "rtsp://localhost:8554/test"
+			case "same host, different case":
+				location = "rtsp://LOCALHOST:8554/test"
+			default:
+				location = "rtsp://127.0.0.1:8554/test"
+			}
+
+			l, err := net.Listen("tcp", "localhost:8554")
+			require.NoError(t, err)
+			defer l.Close()
+
+			serverDone := make(chan struct{})
+			defer func() { <-serverDone }()
+			go func() {
+				defer close(serverDone)
+
+				conn, err := l.Accept()
+				require.NoError(t, err)
+				bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+				var req base.Request
+				err = req.Read(bconn.Reader)
+				require.NoError(t, err)
+				require.Equal(t, base.Options, req.Method)
+
+				err = base.Response{
+					StatusCode: base.StatusOK,
+					Header: base.Header{
+						"Public": base.HeaderValue{strings.Join([]string{
+							string(base.Describe),
+							string(base.Setup),
+							string(base.Play),
+						}, ", ")},
+					},
+				}.Write(bconn.Writer)
+				require.NoError(t, err)
+
+				err = req.Read(bconn.Reader)
+				require.NoError(t, err)
+				require.Equal(t, base.Describe, req.Method)
+
+				err = base.Response{
+					StatusCode: base.StatusMovedPermanently,
+					Header: base.Header{
+						"Location": base.HeaderValue{location},
+					},
+				}.Write(bconn.Writer)
+				require.NoError(t, err)
+
+				// the connection is reused if the host is the same,
+				// otherwise the client connects to the new host
+				if ca == "different host" {
+					conn.Close()
+
+					conn, err = l.Accept()
+					require.NoError(t, err)
+					bconn = bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+					err = req.Read(bconn.Reader)
+					require.NoError(t, err)
+					require.Equal(t, base.Options, req.Method)
+
+					err = base.Response{
+						StatusCode: base.StatusOK,
+						Header: base.Header{
+							"Public": base.HeaderValue{strings.Join([]string{
+								string(base.Describe),
+								string(base.Setup),
+								string(base.Play),
+							}, ", ")},
+						},
+					}.Write(bconn.Writer)
+					require.NoError(t, err)
+				}
+				defer conn.Close()
+
+				// the request is sent to the new location
+				err = req.Read(bconn.Reader)
+				require.NoError(t, err)
+				require.Equal(t, base.Describe, req.Method)
+				require.Equal(t, base.MustParseURL(location), req.URL)
+
+				track, err := NewTrackH264(96, []byte("123456"), []byte("123456"))
+				require.NoError(t, err)
+
+				err = base.Response{
+					StatusCode: base.StatusOK,
+					Header: base.Header{
+						"Content-Type": base.HeaderValue{"application/sdp"},
+					},
+					Body: Tracks{track}.Write(),
+				}.Write(bconn.Writer)
+				require.NoError(t, err)
+
+				err = req.Read(bconn.Reader)
+				require.NoError(t, err)
+				require.Equal(t, base.Setup, req.Method)
+
+				var th headers.Transport
+				err = th.Read(req.Header["Transport"])
+				require.NoError(t, err)
+
+				err = base.Response{
+					StatusCode: base.StatusOK,
+					Header: base.Header{
+						"Transport": headers.Transport{
+							Protocol: StreamProtocolUDP,
+							Delivery: func() *base.StreamDelivery {
+								v := base.StreamDeliveryUnicast
+								return &v
+							}(),
+							ClientPorts: th.ClientPorts,
+							ServerPorts: &[2]int{34556, 34557},
+						}.Write(),
+					},
+				}.Write(bconn.Writer)
+				require.NoError(t, err)
+
+				err = req.Read(bconn.Reader)
+				require.NoError(t, err)
+				require.Equal(t, base.Play, req.Method)
+
+				err = base.Response{
+					StatusCode: base.StatusOK,
+				}.Write(bconn.Writer)
+				require.NoError(t, err)
+
+				time.Sleep(1 * time.Second)
+
+				l1, err := net.ListenPacket("udp", "localhost:34556")
+				require.NoError(t, err)
+				defer l1.Close()
+
+				l1.WriteTo([]byte("\x00\x00\x00\x00"), &net.UDPAddr{
+					IP:   net.ParseIP("127.0.0.1"),
+					Port: th.ClientPorts[0],
+				})
+			}()
+
+			conn, err := DialRead("rtsp://localhost:8554/path1")
+			require.NoError(t, err)
+
+			frameRecv := make(chan struct{})
+			done := conn.ReadFrames(func(id int, typ StreamType, payload []byte) {
+				close(frameRecv)
+			})
+
+			<-frameRecv
+			conn.Close()
+			<-done
+		})
+	}
+}
+
+func TestClientReadRedirectMaxCount(t *testing.T) {
+	l, err := net.Listen("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer l.Close()
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		conn, err := l.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+		bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+		var req base.Request
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Options, req.Method)
+
+		err = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Public": base.HeaderValue{strings.Join([]string{
+					string(base.Describe),
+					string(base.Setup),
+					string(base.Play),
+				}, ", ")},
+			},
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		// redirects to the same server are followed on the same connection
+		// and locations can be relative
+		for _, ca := range []struct {
+			path     string
+			location string
+		}{
+			{"/path1", "rtsp://localhost:8554/path2"},
+			{"/path2", "path3"},
+			{"/path3", "/path4"},
+		} {
+			err = req.Read(bconn.Reader)
+			require.NoError(t, err)
+			require.Equal(t, base.Describe, req.Method)
+			require.Equal(t, base.MustParseURL("rtsp://localhost:8554"+ca.path), req.URL)
+
+			err = base.Response{
+				StatusCode: base.StatusFound,
+				Header: base.Header{
+					"Location": base.HeaderValue{ca.location},
+				},
+			}.Write(bconn.Writer)
+			require.NoError(t, err)
+		}
+	}()
+
+	conf := ClientConf{RedirectMaxCount: 2}
+
+	_, err = conf.DialRead("rtsp://localhost:8554/path1")
+	require.Equal(t, liberrors.ErrClientTooManyRedirects{Count: 2}, err)
+}
+
+func TestClientReadPause(t *testing.T) {
+	writeFrames := func(inTH *headers.Transport, bconn *bufio.ReadWriter) (chan struct{}, chan struct{}) {
+		writerTerminate := make(chan struct{})
+		writerDone := make(chan struct{})
+
+		go func() {
+			defer close(writerDone)
+
+			var l1 net.PacketConn
+			if inTH.Protocol == StreamProtocolUDP {
+				var err error
+				l1, err = net.ListenPacket("udp", "localhost:34556")
+				require.NoError(t, err)
+				defer l1.Close()
+			}
+
+			t := time.NewTicker(50 * time.Millisecond)
+			defer t.Stop()
+
+			for {
+				select {
+				case <-t.C:
+					if inTH.Protocol == StreamProtocolUDP {
+						l1.WriteTo([]byte("\x00\x00\x00\x00"), &net.UDPAddr{
+							IP:   net.ParseIP("127.0.0.1"),
+							Port: inTH.ClientPorts[0],
+						})
+					} else {
+						base.InterleavedFrame{
+							TrackID:    0,
+							StreamType: StreamTypeRTP,
+							Payload:    []byte("\x00\x00\x00\x00"),
+						}.Write(bconn.Writer)
+					}
+
+				case <-writerTerminate:
+					return
+				}
+			}
+		}()
+
+		return writerTerminate, writerDone
+	}
+
+	for _, proto := range []string{
+		"udp",
+		"tcp",
+	} {
+		t.Run(proto, func(t *testing.T) {
+			l, err := net.Listen("tcp", "localhost:8554")
+			require.NoError(t, err)
+			defer l.Close()
+
+			serverDone := make(chan struct{})
+			defer func() { <-serverDone }()
+			go func() {
+				defer close(serverDone)
+
+				conn, err := l.Accept()
+				require.NoError(t, err)
+				bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+				var req base.Request
+				err = req.Read(bconn.Reader)
+				require.NoError(t, err)
+				require.Equal(t, base.Options, req.Method)
+
+				err = base.Response{
+					StatusCode: base.StatusOK,
+					Header: base.Header{
+						"Public": base.HeaderValue{strings.Join([]string{
+							string(base.Describe),
+							string(base.Setup),
+							string(base.Play),
+							string(base.Pause),
+						}, ", ")},
+					},
+				}.Write(bconn.Writer)
+				require.NoError(t, err)
+
+				err = req.Read(bconn.Reader)
+				require.NoError(t, err)
+				require.Equal(t, base.Describe, req.Method)
+
+				track, err := NewTrackH264(96, []byte("123456"), []byte("123456"))
+				require.NoError(t, err)
+
+				err = base.Response{
+					StatusCode: base.StatusOK,
+					Header: base.Header{
+						"Content-Type": base.HeaderValue{"application/sdp"},
+					},
+					Body: Tracks{track}.Write(),
+				}.Write(bconn.Writer)
+				require.NoError(t, err)
+
+				err = req.Read(bconn.Reader)
+				require.NoError(t, err)
+				require.Equal(t, base.Setup, req.Method)
+
+				var inTH headers.Transport
+				err = inTH.Read(req.Header["Transport"])
+				require.NoError(t, err)
+
+				th := headers.Transport{
+					Delivery: func() *base.StreamDelivery {
+						v := base.StreamDeliveryUnicast
+						return &v
+					}(),
+				}
+
+				if proto == "udp" {
+					th.Protocol = StreamProtocolUDP
+					th.ServerPorts = &[2]int{34556, 34557}
+					th.ClientPorts = inTH.ClientPorts
+
+				} else {
+					th.Protocol = StreamProtocolTCP
+					th.InterleavedIDs = inTH.InterleavedIDs
+				}
+
+				err = base.Response{
+					StatusCode: base.StatusOK,
+					Header: base.Header{
+						"Transport": th.Write(),
+					},
+				}.Write(bconn.Writer)
+				require.NoError(t, err)
+
+				err = req.Read(bconn.Reader)
+				require.NoError(t, err)
+				require.Equal(t, base.Play, req.Method)
+
+				err = base.Response{
+					StatusCode: base.StatusOK,
+				}.Write(bconn.Writer)
+				require.NoError(t, err)
+
+				writerTerminate, writerDone := writeFrames(&inTH, bconn)
+
+				err = req.Read(bconn.Reader)
+				require.NoError(t, err)
+				require.Equal(t, base.Pause, req.Method)
+
+				close(writerTerminate)
+				<-writerDone
+
+				err = base.Response{
+					StatusCode: base.StatusOK,
+				}.Write(bconn.Writer)
+				require.NoError(t, err)
+
+				err = req.Read(bconn.Reader)
+				require.NoError(t, err)
+				require.Equal(t, base.Play, req.Method)
+
+				err = base.Response{
+					StatusCode: base.StatusOK,
+				}.Write(bconn.Writer)
+				require.NoError(t, err)
+
+				writerTerminate, writerDone = writeFrames(&inTH, bconn)
+
+				err = req.ReadIgnoreFrames(bconn.Reader, make([]byte, 1024))
+				require.NoError(t, err)
+				require.Equal(t, base.Teardown, req.Method)
+
+				close(writerTerminate)
+				<-writerDone
+
+				conn.Close()
+			}()
+
+			conf := ClientConf{
+				StreamProtocol: func() *StreamProtocol {
+					if proto == "udp" {
+						v := StreamProtocolUDP
+						return &v
+					}
+					v := StreamProtocolTCP
+					return &v
+				}(),
+			}
+
+			conn, err := conf.DialRead("rtsp://localhost:8554/teststream")
+			require.NoError(t, err)
+
+			firstFrame := int32(0)
+			frameRecv := make(chan struct{})
+			done := conn.ReadFrames(func(id int, typ StreamType, payload []byte) {
+				if atomic.SwapInt32(&firstFrame, 1) == 0 {
+					close(frameRecv)
+				}
+			})
+
+			<-frameRecv
+			_, err = conn.Pause()
+			require.NoError(t, err)
+			<-done
+
+			_, err = conn.Play(nil)
+			require.NoError(t, err)
+
+			firstFrame = int32(0)
+			frameRecv = make(chan struct{})
+			done = conn.ReadFrames(func(id int, typ StreamType, payload []byte) {
+				if atomic.SwapInt32(&firstFrame, 1) == 0 {
+					close(frameRecv)
+				}
+			})
+
+			<-frameRecv
+			conn.Close()
+			<-done
+		})
+	}
+}
+
+func TestClientReadBackChannel(t *testing.T) {
+	l, err := net.Listen("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer l.Close()
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		conn, err := l.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+		bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+		var req base.Request
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Options, req.Method)
+
+		err = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Public": base.HeaderValue{strings.Join([]string{
+					string(base.Describe),
+					string(base.Setup),
+					string(base.Play),
+				}, ", ")},
+			},
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Describe, req.Method)
+		require.Equal(t, base.HeaderValue{"www.onvif.org/ver20/backchannel"}, req.Header["Require"])
+
+		track1, err := NewTrackH264(96, []byte("123456"), []byte("123456"))
+		require.NoError(t, err)
+
+		track2 := NewTrackPCMU()
+		track2.SetAttribute("sendonly", "")
+
+		err = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Content-Type": base.HeaderValue{"application/sdp"},
+			},
+			Body: Tracks{track1, track2}.Write(),
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		for i, mode := range []headers.TransportMode{
+			headers.TransportModePlay,
+			headers.TransportModeRecord,
+		} {
+			err = req.Read(bconn.Reader)
+			require.NoError(t, err)
+			require.Equal(t, base.Setup, req.Method)
+			require.Equal(t, base.HeaderValue{"www.onvif.org/ver20/backchannel"}, req.Header["Require"])
+
+			var th headers.Transport
+			err = th.Read(req.Header["Transport"])
+			require.NoError(t, err)
+			require.Equal(t, mode, *th.Mode)
+			require.Equal(t, &[2]int{i * 2, i*2 + 1}, th.InterleavedIDs)
+
+			err = base.Response{
+				StatusCode: base.StatusOK,
+				Header: base.Header{
+					"Transport": headers.Transport{
+						Protocol: StreamProtocolTCP,
+						Delivery: func() *base.StreamDelivery {
+							v := base.StreamDeliveryUnicast
+							return &v
+						}(),
+						InterleavedIDs: th.InterleavedIDs,
+					}.Write(),
+				},
+			}.Write(bconn.Writer)
+			require.NoError(t, err)
+		}
+
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Play, req.Method)
+		require.Equal(t, base.HeaderValue{"www.onvif.org/ver20/backchannel"}, req.Header["Require"])
+
+		err = base.Response{
+			StatusCode: base.StatusOK,
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		err = base.InterleavedFrame{
+			TrackID:    0,
+			StreamType: StreamTypeRTP,
+			Payload:    []byte("\x00\x00\x00\x00"),
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		var f base.InterleavedFrame
+		f.Payload = make([]byte, 2048)
+		err = f.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, 1, f.TrackID)
+		require.Equal(t, StreamTypeRTP, f.StreamType)
+		require.Equal(t, []byte("\x01\x02\x03\x04"), f.Payload)
+	}()
+
+	conf := ClientConf{
+		StreamProtocol: func() *StreamProtocol {
+			v := StreamProtocolTCP
+			return &v
+		}(),
+		BackChannelEnable: true,
+	}
+
+	conn, err := conf.DialRead("rtsp://localhost:8554/teststream")
+	require.NoError(t, err)
+
+	require.Equal(t, false, conn.Tracks()[0].IsBackChannel())
+	require.Equal(t, true, conn.Tracks()[1].IsBackChannel())
+
+	frameRecv := make(chan struct{})
+	done := conn.ReadFrames(func(id int, typ StreamType, payload []byte) {
+		require.Equal(t, 0, id)
+		close(frameRecv)
+	})
+
+	<-frameRecv
+
+	err = conn.WriteFrame(1, StreamTypeRTP, []byte("\x01\x02\x03\x04"))
+	require.NoError(t, err)
+
+	<-serverDone
+	conn.Close()
+	<-done
+
+	err = conn.WriteFrame(1, StreamTypeRTP, []byte("\x01\x02\x03\x04"))
+	require.Error(t, err)
+}
+
+func TestClientReadSendOnlyTrack(t *testing.T) {
+	l, err := net.Listen("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer l.Close()
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		conn, err := l.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+		bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+		var req base.Request
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Options, req.Method)
+
+		err = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Public": base.HeaderValue{strings.Join([]string{
+					string(base.Describe),
+					string(base.Setup),
+					string(base.Play),
+				}, ", ")},
+			},
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Describe, req.Method)
+		_, ok := req.Header["Require"]
+		require.Equal(t, false, ok)
+
+		track1, err := NewTrackH264(96, []byte("123456"), []byte("123456"))
+		require.NoError(t, err)
+
+		// some servers mark tracks that are sent to clients as sendonly
+		track2 := NewTrackPCMU()
+		track2.SetAttribute("sendonly", "")
+
+		err = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Content-Type": base.HeaderValue{"application/sdp"},
+			},
+			Body: Tracks{track1, track2}.Write(),
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		for i := 0; i < 2; i++ {
+			err = req.Read(bconn.Reader)
+			require.NoError(t, err)
+			require.Equal(t, base.Setup, req.Method)
+
+			var th headers.Transport
+			err = th.Read(req.Header["Transport"])
+			require.NoError(t, err)
+			require.Equal(t, headers.TransportModePlay, *th.Mode)
+
+			err = base.Response{
+				StatusCode: base.StatusOK,
+				Header: base.Header{
+					"Transport": headers.Transport{
+						Protocol: StreamProtocolTCP,
+						Delivery: func() *base.StreamDelivery {
+							v := base.StreamDeliveryUnicast
+							return &v
+						}(),
+						InterleavedIDs: th.InterleavedIDs,
+					}.Write(),
+				},
+			}.Write(bconn.Writer)
+			require.NoError(t, err)
+		}
+
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Play, req.Method)
+
+		err = base.Response{
+			StatusCode: base.StatusOK,
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		err = base.InterleavedFrame{
+			TrackID:    1,
+			StreamType: StreamTypeRTP,
+			Payload:    []byte("\x00\x00\x00\x00"),
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+	}()
+
+	conf := ClientConf{
+		StreamProtocol: func() *StreamProtocol {
+			v := StreamProtocolTCP
+			return &v
+		}(),
+	}
+
+	conn, err := conf.DialRead("rtsp://localhost:8554/teststream")
+	require.NoError(t, err)
+
+	require.Equal(t, 2, len(conn.Tracks()))
+	require.Equal(t, false, conn.Tracks()[1].IsBackChannel())
+
+	frameRecv := make(chan struct{})
+	done := conn.ReadFrames(func(id int, typ StreamType, payload []byte) {
+		require.Equal(t, 1, id)
+		close(frameRecv)
+	})
+
+	<-frameRecv
+	conn.Close()
+	<-done
+}
+
+func TestClientReadRTPInfo(t *testing.T) {
+	l, err := net.Listen("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer l.Close()
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		conn, err := l.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+		bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+		var req base.Request
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Options, req.Method)
+
+		err = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Public": base.HeaderValue{strings.Join([]string{
+					string(base.Describe),
+					string(base.Setup),
+					string(base.Play),
+				}, ", ")},
+			},
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Describe, req.Method)
+
+		track1, err := NewTrackH264(96, []byte("123456"), []byte("123456"))
+		require.NoError(t, err)
+
+		track2, err := NewTrackAAC(97, rtpaac.MPEG4AudioTypeAACLC, 48000, 2)
+		require.NoError(t, err)
+
+		err = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Content-Type": base.HeaderValue{"application/sdp"},
+			},
+			Body: Tracks{track1, track2}.Write(),
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		for i := 0; i < 2; i++ {
+			err = req.Read(bconn.Reader)
+			require.NoError(t, err)
+			require.Equal(t, base.Setup, req.Method)
+
+			err = base.Response{
+				StatusCode: base.StatusOK,
+				Header: base.Header{
+					"Transport": headers.Transport{
+						Protocol: StreamProtocolTCP,
+						Delivery: func() *base.StreamDelivery {
+							v := base.StreamDeliveryUnicast
+							return &v
+						}(),
+						InterleavedIDs: &[2]int{i * 2, i*2 + 1},
+					}.Write(),
+				},
+			}.Write(bconn.Writer)
+			require.NoError(t, err)
+		}
+
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Play, req.Method)
+
+		err = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"RTP-Info": headers.RTPInfo{
+					{
+						URL:            base.MustParseURL("rtsp://localhost:8554/teststream/trackID=1"),
+						SequenceNumber: 556,
+						Timestamp:      984512368,
+					},
+					{
+						URL:            base.MustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
+						SequenceNumber: 87,
+						Timestamp:      756436454,
+					},
+				}.Write(),
+			},
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Teardown, req.Method)
+
+		err = base.Response{
+			StatusCode: base.StatusOK,
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+	}()
+
+	v := StreamProtocolTCP
+	conf := ClientConf{
+		StreamProtocol: &v,
+	}
+
+	conn, err := conf.DialRead("rtsp://localhost:8554/teststream")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.Equal(t, &headers.RTPInfoEntry{
+		URL:            base.MustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
+		SequenceNumber: 87,
+		Timestamp:      756436454,
+	}, conn.TrackRTPInfo(0))
+
+	require.Equal(t, &headers.RTPInfoEntry{
+		URL:            base.MustParseURL("rtsp://localhost:8554/teststream/trackID=1"),
+		SequenceNumber: 556,
+		Timestamp:      984512368,
+	}, conn.TrackRTPInfo(1))
+
+	require.Nil(t, conn.TrackRTPInfo(2))
+}
+
+func TestClientReadNoRTPTimeout(t *testing.T) {
+	l, err := net.Listen("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer l.Close()
+
+	stopWriting := make(chan struct{})
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		conn, err := l.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+		bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+		var req base.Request
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Options, req.Method)
+
+		err = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Public": base.HeaderValue{strings.Join([]string{
+					string(base.Describe),
+					string(base.Setup),
+					string(base.Play),
+				}, ", ")},
+			},
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Describe, req.Method)
+
+		track, err := NewTrackH264(96, []byte("123456"), []byte("123456"))
+		require.NoError(t, err)
+
+		err = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Content-Type": base.HeaderValue{"application/sdp"},
+			},
+			Body: Tracks{track}.Write(),
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Setup, req.Method)
+
+		err = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Transport": headers.Transport{
+					Protocol: StreamProtocolTCP,
+					Delivery: func() *base.StreamDelivery {
+						v := base.StreamDeliveryUnicast
+						return &v
+					}(),
+					InterleavedIDs: &[2]int{0, 1},
+				}.Write(),
+			},
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Play, req.Method)
+
+		err = base.Response{
+			StatusCode: base.StatusOK,
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		err = base.InterleavedFrame{
+			TrackID:    0,
+			StreamType: StreamTypeRTP,
+			Payload:    []byte{0x01, 0x02, 0x03, 0x04},
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		// the control connection is kept alive with RTCP packets only
+	outer:
+		for {
+			select {
+			case <-time.After(100 * time.Millisecond):
+				err = base.InterleavedFrame{
+					TrackID:    0,
+					StreamType: StreamTypeRTCP,
+					Payload:    []byte{0x05, 0x06, 0x07, 0x08},
+				}.Write(bconn.Writer)
+				require.NoError(t, err)
+
+			case <-stopWriting:
+				break outer
+			}
+		}
+
+		err = req.ReadIgnoreFrames(bconn.Reader, make([]byte, 1024))
+		require.NoError(t, err)
+		require.Equal(t, base.Teardown, req.Method)
+
+		err = base.Response{
+			StatusCode: base.StatusOK,
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+	}()
+
+	v := StreamProtocolTCP
+	conf := ClientConf{
+		StreamProtocol: &v,
+		NoRTPTimeout:   500 * time.Millisecond,
+	}
+
+	conn, err := conf.DialRead("rtsp://localhost:8554/teststream")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	rtpRecv := make(chan struct{}, 1)
+	done := conn.ReadFrames(func(id int, typ StreamType, payload []byte) {
+		if typ == StreamTypeRTP {
+			select {
+			case rtpRecv <- struct{}{}:
+			default:
+			}
+		}
+	})
+
+	<-rtpRecv
+
+	err = <-done
+	require.Equal(t, liberrors.ErrClientNoRTPPacketsRecently{}, err)
+
+	close(stopWriting)
+}
+
+func TestClientReadPackets(t *testing.T) {
+	rtpPkt := &rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			Marker:         true,
+			PayloadType:    96,
+			SequenceNumber: 946,
+			Timestamp:      54352,
+			SSRC:           753621,
+		},
+		Payload: []byte{0x01, 0x02, 0x03, 0x04},
+	}
+	rtcpPkt := &rtcp.SenderReport{
+		SSRC:        753621,
+		NTPTime:     0xcbddcc34999997ff,
+		RTPTime:     54352,
+		PacketCount: 1,
+		OctetCount:  4,
+	}
+
+	l, err := net.Listen("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer l.Close()
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		conn, err := l.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+		bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+		var req base.Request
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Options, req.Method)
+
+		err = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Public": base.HeaderValue{strings.Join([]string{
+					string(base.Describe),
+					string(base.Setup),
+					string(base.Play),
+				}, ", ")},
+			},
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Describe, req.Method)
+
+		track, err := NewTrackH264(96, []byte("123456"), []byte("123456"))
+		require.NoError(t, err)
+
+		err = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Content-Type": base.HeaderValue{"application/sdp"},
+			},
+			Body: Tracks{track}.Write(),
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Setup, req.Method)
+
+		err = base.Response{
+			StatusCode: base.StatusOK,
+			Header: base.Header{
+				"Transport": headers.Transport{
+					Protocol: StreamProtocolTCP,
+					Delivery: func() *base.StreamDelivery {
+						v := base.StreamDeliveryUnicast
+						return &v
+					}(),
+					InterleavedIDs: &[2]int{0, 1},
+				}.Write(),
+			},
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		err = req.Read(bconn.Reader)
+		require.NoError(t, err)
+		require.Equal(t, base.Play, req.Method)
+
+		err = base.Response{
+			StatusCode: base.StatusOK,
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		// invalid packet, that is discarded
+		err = base.InterleavedFrame{
+			TrackID:    0,
+			StreamType: StreamTypeRTP,
+			Payload:    []byte("\x00\x00\x00\x00"),
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		byts, err := rtpPkt.Marshal()
+		require.NoError(t, err)
+		err = base.InterleavedFrame{
+			TrackID:    0,
+			StreamType: StreamTypeRTP,
+			Payload:    byts,
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		byts, err = rtcpPkt.Marshal()
+		require.NoError(t, err)
+		err = base.InterleavedFrame{
+			TrackID:    0,
+			StreamType: StreamTypeRTCP,
+			Payload:    byts,
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+	}()
+
+	v := StreamProtocolTCP
+	conf := ClientConf{
+		StreamProtocol: &v,
+	}
+
+	conn, err := conf.DialRead("rtsp://localhost:8554/teststream")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	rtpRecv := make(chan *rtp.Packet, 1)
+	rtcpRecv := make(chan []rtcp.Packet, 1)
+
+	conn.ReadPackets(func(trackID int, pkt *rtp.Packet) {
+		require.Equal(t, 0, trackID)
+		// copy the packet, since the payload is valid only inside the callback
+		cpy := *pkt
+		cpy.Payload = append([]byte(nil), pkt.Payload...)
+		rtpRecv <- &cpy
+	}, func(trackID int, pkts []rtcp.Packet) {
+		require.Equal(t, 0, trackID)
+		rtcpRecv <- pkts
+	})
+
+	pkt := <-rtpRecv
+	require.Equal(t, rtpPkt.Header.Marker, pkt.Header.Marker)
+	require.Equal(t, rtpPkt.Header.SequenceNumber, pkt.Header.SequenceNumber)
+	require.Equal(t, rtpPkt.Header.Timestamp, pkt.Header.Timestamp)
+	require.Equal(t, rtpPkt.Payload, pkt.Payload)
+
+	pkts := <-rtcpRecv
+	require.Equal(t, []rtcp.Packet{rtcpPkt}, pkts)
+}
+
+func TestClientReadContentBase(t *testing.T) {
+	for _, ca := range []struct {
+		name     string
+		header   base.Header
+		control  string
+		baseURL  string
+		setupURL string
+		playURL  string
+	}{
+		{
+			"content-base",
+			base.Header{
+				"Content-Base": base.HeaderValue{"rtsp://localhost:8554/teststream/base/"},
+			},
+			"a=control:trackID=0\r\n",
+			"rtsp://localhost:8554/teststream/base/",
+			"rtsp://localhost:8554/teststream/base/trackID=0",
+			"rtsp://localhost:8554/teststream/base/",
+		},
+		{
+			"relative content-location",
+			base.Header{
+				"Content-Location": base.HeaderValue{"other"},
+			},
+			"",
+			"rtsp://localhost:8554/other",
+			"rtsp://localhost:8554/other",
+			"rtsp://localhost:8554/other",
+		},
+		{
+			"aggregate control",
+			base.Header{},
+			"a=control:*\r\n",
+			"rtsp://localhost:8554/teststream",
+			"rtsp://localhost:8554/teststream",
+			"rtsp://localhost:8554/teststream",
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			l, err := net.Listen("tcp", "localhost:8554")
+			require.NoError(t, err)
+			defer l.Close()
+
+			serverDone := make(chan struct{})
+			defer func() { <-serverDone }()
+			go func() {
+				defer close(serverDone)
+
+				conn, err := l.Accept()
+				require.NoError(t, err)
+				defer conn.Close()
+				bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+				var req base.Request
+				err = req.Read(bconn.Reader)
+				require.NoError(t, err)
+				require.Equal(t, base.Options, req.Method)
+
+				err = base.Response{
+					StatusCode: base.StatusOK,
+					Header: base.Header{
+						"Public": base.HeaderValue{strings.Join([]string{
+							string(base.Describe),
+							string(base.Setup),
+							string(base.Play),
+						}, ", ")},
+					},
+				}.Write(bconn.Writer)
+				require.NoError(t, err)
+
+				err = req.Read(bconn.Reader)
+				require.NoError(t, err)
+				require.Equal(t, base.Describe, req.Method)
+
+				header := base.Header{
+					"Content-Type": base.HeaderValue{"application/sdp"},
+				}
+				for k, v := range ca.header {
+					header[k] = v
+				}
+
+				err = base.Response{
+					StatusCode: base.StatusOK,
+					Header:     header,
+					Body: []byte("v=0\r\n" +
+						"o=- 0 0 IN IP4 127.0.0.1\r\n" +
+						"s=Stream\r\n" +
+						"c=IN IP4 0.0.0.0\r\n" +
+						"t=0 0\r\n" +
+						"m=video 0 RTP/AVP 96\r\n" +
+						"a=rtpmap:96 H264/90000\r\n" +
+						"a=fmtp:96 packetization-mode=1\r\n" +
+						ca.control),
+				}.Write(bconn.Writer)
+				require.NoError(t, err)
+
+				err = req.Read(bconn.Reader)
+				require.NoError(t, err)
+				require.Equal(t, base.Setup, req.Method)
+				require.Equal(t, base.MustParseURL(ca.setupURL), req.URL)
+
+				var th headers.Transport
+				err = th.Read(req.Header["Transport"])
+				require.NoError(t, err)
+
+				err = base.Response{
+					StatusCode: base.StatusOK,
+					Header: base.Header{
+						"Transport": headers.Transport{
+							Protocol: StreamProtocolTCP,
+							Delivery: func() *base.StreamDelivery {
+								v := base.StreamDeliveryUnicast
+								return &v
+							}(),
+							InterleavedIDs: th.InterleavedIDs,
+						}.Write(),
+					},
+				}.Write(bconn.Writer)
+				require.NoError(t, err)
+
+				err = req.Read(bconn.Reader)
+				require.NoError(t, err)
+				require.Equal(t, base.Play, req.Method)
+				require.Equal(t, base.MustParseURL(ca.playURL), req.URL)
+
+				err = base.Response{
+					StatusCode: base.StatusOK,
+				}.Write(bconn.Writer)
+				require.NoError(t, err)
+			}()
+
+			v := StreamProtocolTCP
+			conf := ClientConf{
+				StreamProtocol: &v,
+			}
+
+			conn, err := conf.DialRead("rtsp://localhost:8554/teststream")
+			require.NoError(t, err)
+			defer conn.Close()
+
+			require.Equal(t, base.MustParseURL(ca.baseURL), conn.BaseURL())
+		})
+	}
+}
@@ -6,26 +6,29 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/majoyz/gortsplib/pkg/liberrors"
 	"github.com/majoyz/gortsplib/pkg/multibuffer"
-)
-
-const (
-	// use the same buffer size as gstreamer's rtspsrc
-	clientConnUDPKernelReadBufferSize = 0x80000
+	"github.com/majoyz/gortsplib/pkg/ringbuffer"
 )
 
 type clientConnUDPListener struct {
 	c              *ClientConn
 	pc             net.PacketConn
+	batchConn      *udpBatchConn
+	readBatch      int
 	remoteIP       net.IP
 	remoteZone     string
 	remotePort     int
+	sourcePort     int32
 	udpFrameBuffer *multibuffer.MultiBuffer
+	ringBuffer     *ringbuffer.RingBuffer
+	ssrcFilter     *ssrcFilter
 	trackID        int
 	streamType     StreamType
 	running        bool
 
-	done chan struct{}
+	done         chan struct{}
+	callbackDone chan struct{}
 }
 
 func newClientConnUDPListener(c *ClientConn, port int) (*clientConnUDPListener, error) {
@@ -34,16 +37,43 @@ func newClientConnUDPListener(c *ClientConn, port int) (*clientConnUDPListener,
 		return nil, err
 	}
 
-	err = pc.(*net.UDPConn).SetReadBuffer(clientConnUDPKernelReadBufferSize)
+	err = setUDPBufferSizes(pc.(*net.UDPConn), c.conf.UDPReadBufferSize, c.conf.UDPWriteBufferSize)
 	if err != nil {
+		pc.Close()
 		return nil, err
 	}
 
-	return &clientConnUDPListener{
-		c:              c,
-		pc:             pc,
-		udpFrameBuffer: multibuffer.New(uint64(c.conf.ReadBufferCount), uint64(c.conf.ReadBufferSize)),
-	}, nil
+	batchConn, err := newUDPBatchConn(pc.(*net.UDPConn))
+	if err != nil {
+		pc.Close()
+		return nil, err
+	}
+
+	// packets read together must use different buffers
+	readBatch := udpBatchSize
+	if c.conf.ReadBufferCount < readBatch {
+		readBatch = c.conf.ReadBufferCount
+	}
+
+	l := &clientConnUDPListener{
+		c:         c,
+		pc:        pc,
+		batchConn: batchConn,
+		readBatch: readBatch,
+	}
+
+	if c.conf.ReadBufferCount > 1 {
+		// frames are queued and passed to the read callback by a dedicated routine,
+		// therefore a slow callback doesn't block reading.
+		// queued frames are copied, therefore buffers are needed only by frames
+		// that are being read.
+		l.ringBuffer = ringbuffer.New(uint64(c.conf.ReadBufferCount))
+		l.udpFrameBuffer = multibuffer.New(uint64(readBatch), uint64(c.conf.ReadBufferSize))
+	} else {
+		l.udpFrameBuffer = multibuffer.New(uint64(c.conf.ReadBufferCount), uint64(c.conf.ReadBufferSize))
+	}
+
+	return l, nil
 }
 
 func (l *clientConnUDPListener) close() {
@@ -56,45 +86,189 @@ func (l *clientConnUDPListener) close() {
 func (l *clientConnUDPListener) start() {
 	l.running = true
 	l.pc.SetReadDeadline(time.Time{})
+
+	if l.ssrcFilter != nil {
+		l.ssrcFilter.reset()
+	}
+
+	if l.ringBuffer != nil {
+		l.ringBuffer.Reset()
+		l.callbackDone = make(chan struct{})
+		go l.runCallback()
+	}
+
 	l.done = make(chan struct{})
 	go l.run()
 }
 
 func (l *clientConnUDPListener) stop() {
+	l.running = false
 	l.pc.SetReadDeadline(time.Now())
 	<-l.done
+
+	if l.ringBuffer != nil {
+		l.ringBuffer.Close()
+		<-l.callbackDone
+	}
 }
 
 func (l *clientConnUDPListener) run() {
 	defer close(l.done)
 
+	msgs := make([]udpMessage, l.readBatch)
+	for i := range msgs {
+		msgs[i].buf = l.udpFrameBuffer.Next()
+	}
+
 	for {
-		buf := l.udpFrameBuffer.Next()
-		n, addr, err := l.pc.ReadFrom(buf)
+		n, err := l.batchConn.readBatch(msgs)
 		if err != nil {
 			return
 		}
 
-		uaddr := addr.(*net.UDPAddr)
+		for i := 0; i < n; i++ {
+			l.processPacket(msgs[i].buf[:msgs[i].n], &msgs[i].addr)
 
-		if !l.remoteIP.Equal(uaddr.IP) || (l.remotePort != 0 && l.remotePort != uaddr.Port) {
-			continue
+			// the buffer may still be in use by the callback, get a new one
+			msgs[i].buf = l.udpFrameBuffer.Next()
 		}
+	}
+}
 
-		now := time.Now()
-		atomic.StoreInt64(l.c.udpLastFrameTimes[l.trackID], now.Unix())
-		l.c.rtcpReceivers[l.trackID].ProcessFrame(now, l.streamType, buf[:n])
+func (l *clientConnUDPListener) runCallback() {
+	defer close(l.callbackDone)
+
+	for {
+		tmp, ok := l.ringBuffer.Pull()
+		if !ok {
+			return
+		}
 
-		l.c.readCB(l.trackID, l.streamType, buf[:n])
+		l.c.readCB(l.trackID, l.streamType, tmp.([]byte))
+	}
+}
+
+// onFrame passes a frame to the read callback, directly or through the ring buffer.
+// When the ring buffer is full, the oldest frame is discarded.
+func (l *clientConnUDPListener) onFrame(payload []byte) {
+	if l.ringBuffer != nil {
+		// the read buffer is reused as soon as the frame is queued
+		overwritten := l.ringBuffer.Push(append([]byte(nil), payload...))
+		if overwritten {
+			atomic.AddUint64(&l.c.droppedFrames, 1)
+			l.c.conf.Logger.Warnf("frame of track %d discarded, read queue is full", l.trackID)
+		}
+		return
+	}
+
+	l.c.readCB(l.trackID, l.streamType, payload)
+}
+
+func (l *clientConnUDPListener) processPacket(buf []byte, addr *net.UDPAddr) {
+	if !l.remoteIP.Equal(addr.IP) || !l.checkSourcePort(addr.Port) {
+		l.onPacketDiscarded(liberrors.ErrClientUDPSourceUnexpected{
+			TrackID: l.trackID,
+			Address: addr.String(),
+		})
+		return
+	}
+
+	if l.ssrcFilter != nil {
+		if expected, ssrc, ok := l.ssrcFilter.check(buf); !ok {
+			l.onPacketDiscarded(liberrors.ErrClientRTPSSRCUnexpected{
+				TrackID:  l.trackID,
+				Expected: expected,
+				Value:    ssrc,
+			})
+			return
+		}
+	}
+
+	payload, err := srtpDecryptFrame(l.c.srtpContexts[l.trackID], l.streamType, buf)
+	if err != nil {
+		l.onPacketDiscarded(liberrors.ErrClientSRTPDecryptFailed{
+			TrackID: l.trackID,
+			Err:     err,
+		})
+		return
+	}
+
+	atomic.StoreInt32(&l.c.udpFramesReceived, 1)
+
+	now := time.Now()
+	if lastFrameTime, ok := l.c.udpLastFrameTimes[l.trackID]; ok {
+		atomic.StoreInt64(lastFrameTime, now.Unix())
+	}
+
+	if l.streamType == StreamTypeRTP {
+		atomic.StoreInt64(l.c.lastRTPTime, now.UnixNano())
+	}
+
+	if reorderer, ok := l.c.rtpReorderers[l.trackID]; ok && l.streamType == StreamTypeRTP {
+		reorderer.process(payload, func(payload []byte) {
+			l.c.rtcpReceivers[l.trackID].ProcessFrame(now, l.streamType, payload)
+			l.onFrame(payload)
+		})
+		return
+	}
+
+	if rr, ok := l.c.rtcpReceivers[l.trackID]; ok {
+		rr.ProcessFrame(now, l.streamType, payload)
+
+		if l.streamType == StreamTypeRTCP {
+			l.c.onBye(l.trackID, payload)
+		}
+	}
+	l.onFrame(payload)
+}
+
+// flushReorderer passes to the read callback the RTP packets
+// that have been waiting in the reorder buffer for too long.
+func (l *clientConnUDPListener) flushReorderer(r *udpReorderer) {
+	r.flush(func(payload []byte) {
+		l.c.rtcpReceivers[l.trackID].ProcessFrame(time.Now(), l.streamType, payload)
+		l.onFrame(payload)
+	})
+}
+
+// checkSourcePort checks the source port of a packet.
+// When the server didn't provide its ports, the listener locks onto
+// the port of the first packet.
+func (l *clientConnUDPListener) checkSourcePort(port int) bool {
+	if l.remotePort != 0 {
+		return l.remotePort == port
+	}
+
+	if atomic.CompareAndSwapInt32(&l.sourcePort, 0, int32(port)) {
+		return true
+	}
+
+	return atomic.LoadInt32(&l.sourcePort) == int32(port)
+}
+
+func (l *clientConnUDPListener) onPacketDiscarded(err error) {
+	l.c.conf.Logger.Warnf("UDP packet discarded: %s", err)
+
+	if l.c.conf.OnUDPPacketDiscarded != nil {
+		l.c.conf.OnUDPPacketDiscarded(err)
 	}
 }
 
 func (l *clientConnUDPListener) write(buf []byte) error {
+	port := l.remotePort
+	if port == 0 {
+		// send packets to the port the server is sending from, if known
+		port = int(atomic.LoadInt32(&l.sourcePort))
+		if port == 0 {
+			return nil
+		}
+	}
+
 	l.pc.SetWriteDeadline(time.Now().Add(l.c.conf.WriteTimeout))
 	_, err := l.pc.WriteTo(buf, &net.UDPAddr{
 		IP:   l.remoteIP,
 		Zone: l.remoteZone,
-		Port: l.remotePort,
+		Port: port,
 	})
 	return err
 }
@@ -0,0 +1,159 @@
+package gortsplib
+
+import (
+	"sync"
+	"time"
+
+	"github.com/majoyz/gortsplib/pkg/liberrors"
+)
+
+// ClientPublisherConf allows to configure a ClientPublisher.
+type ClientPublisherConf struct {
+	// configuration of the client that publishes the tracks.
+	ClientConf ClientConf
+
+	// time to wait before connecting again after the first error.
+	// The pause is doubled after each consecutive error, up to MaxRetryPause,
+	// and is restored when publishing succeeds.
+	// It defaults to 1 second.
+	RetryPause time.Duration
+
+	// maximum time to wait before connecting again.
+	// It defaults to 30 seconds.
+	MaxRetryPause time.Duration
+
+	// (optional) called when the tracks have been announced and
+	// frames can be written.
+	OnReady func()
+
+	// (optional) called when publishing fails, before connecting again.
+	OnError func(err error)
+}
+
+// ClientPublisher publishes tracks to a server with ANNOUNCE and RECORD.
+// If the server disconnects, the publisher connects again and announces
+// the tracks again.
+type ClientPublisher struct {
+	conf    ClientPublisherConf
+	address string
+	tracks  Tracks
+
+	mutex sync.RWMutex
+	conn  *ClientConn
+
+	terminate chan struct{}
+	done      chan struct{}
+}
+
+// Start starts a ClientPublisher that publishes the tracks to the address.
+func (c ClientPublisherConf) Start(address string, tracks Tracks) *ClientPublisher {
+	if c.RetryPause == 0 {
+		c.RetryPause = 1 * time.Second
+	}
+	if c.MaxRetryPause == 0 {
+		c.MaxRetryPause = 30 * time.Second
+	}
+
+	p := &ClientPublisher{
+		conf:      c,
+		address:   address,
+		tracks:    tracks,
+		terminate: make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+
+	go p.run()
+
+	return p
+}
+
+// Close stops the publisher and closes its connection.
+func (p *ClientPublisher) Close() {
+	close(p.terminate)
+	<-p.done
+}
+
+// WriteFrame writes a frame.
+// Frames written while the publisher is connecting are discarded and
+// a liberrors.ErrClientNotRunning is returned.
+func (p *ClientPublisher) WriteFrame(trackID int, streamType StreamType, payload []byte) error {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	if p.conn == nil {
+		return liberrors.ErrClientNotRunning{}
+	}
+
+	return p.conn.WriteFrame(trackID, streamType, payload)
+}
+
+func (p *ClientPublisher) run() {
+	defer close(p.done)
+
+	pause := p.conf.RetryPause
+
+	for {
+		ready, err := p.runInner()
+		if err == errTerminated {
+			return
+		}
+
+		if ready {
+			pause = p.conf.RetryPause
+		}
+
+		if p.conf.OnError != nil {
+			p.conf.OnError(err)
+		}
+
+		select {
+		case <-time.After(pause):
+		case <-p.terminate:
+			return
+		}
+
+		pause *= 2
+		if pause > p.conf.MaxRetryPause {
+			pause = p.conf.MaxRetryPause
+		}
+	}
+}
+
+func (p *ClientPublisher) runInner() (bool, error) {
+	// Announce() edits the tracks, therefore they are copied
+	tracks := make(Tracks, len(p.tracks))
+	for i, track := range p.tracks {
+		tracks[i] = cloneTrack(track, i)
+	}
+
+	conn, err := dialInterruptible(p.terminate, func() (*ClientConn, error) {
+		return p.conf.ClientConf.DialPublish(p.address, tracks)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	p.mutex.Lock()
+	p.conn = conn
+	p.mutex.Unlock()
+
+	if p.conf.OnReady != nil {
+		p.conf.OnReady()
+	}
+
+	defer func() {
+		p.mutex.Lock()
+		p.conn = nil
+		p.mutex.Unlock()
+
+		conn.Close()
+	}()
+
+	select {
+	case <-conn.backgroundDone:
+		return true, conn.publishError
+
+	case <-p.terminate:
+		return true, errTerminated
+	}
+}
@@ -0,0 +1,123 @@
+package gortsplib
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/majoyz/gortsplib/pkg/base"
+)
+
+func TestClientPublisher(t *testing.T) {
+	frameRecv := make(chan struct{})
+
+	startServer := func() func() {
+		s, err := Serve("127.0.0.1:8554")
+		require.NoError(t, err)
+
+		handleDone := make(chan struct{})
+
+		go func() {
+			defer close(handleDone)
+
+			s.Handle(ServerHandler{
+				OnConnOpen: func(sc *ServerConn) ServerConnReadHandlers {
+					return ServerConnReadHandlers{
+						OnAnnounce: func(ctx *ServerConnAnnounceCtx) (*base.Response, error) {
+							require.Equal(t, "teststream", ctx.Path)
+							return &base.Response{
+								StatusCode: base.StatusOK,
+							}, nil
+						},
+						OnSetup: func(ctx *ServerConnSetupCtx) (*base.Response, error) {
+							return &base.Response{
+								StatusCode: base.StatusOK,
+							}, nil
+						},
+						OnRecord: func(ctx *ServerConnRecordCtx) (*base.Response, error) {
+							return &base.Response{
+								StatusCode: base.StatusOK,
+							}, nil
+						},
+						OnFrame: func(trackID int, streamType StreamType, payload []byte) {
+							if streamType == StreamTypeRTP {
+								select {
+								case frameRecv <- struct{}{}:
+								default:
+								}
+							}
+						},
+					}
+				},
+			})
+		}()
+
+		return func() {
+			s.Close()
+			<-handleDone
+		}
+	}
+
+	track, err := NewTrackH264(96, []byte("123456"), []byte("123456"))
+	require.NoError(t, err)
+
+	v := StreamProtocolTCP
+	ready := make(chan struct{}, 2)
+	errs := make(chan error, 2)
+
+	stopServer := startServer()
+
+	p := ClientPublisherConf{
+		ClientConf: ClientConf{
+			StreamProtocol: &v,
+		},
+		RetryPause: 100 * time.Millisecond,
+		OnReady: func() {
+			ready <- struct{}{}
+		},
+		OnError: func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		},
+	}.Start("rtsp://localhost:8554/teststream", Tracks{track})
+	defer p.Close()
+
+	pkt := []byte{
+		0x80, 0x60, 0x12, 0x34, 0x55, 0x66, 0x77, 0x88,
+		0x9d, 0xbb, 0x78, 0x12, 0x01, 0x02, 0x03, 0x04,
+	}
+
+	// frames are written until they are received
+	waitFrame := func() {
+		for {
+			p.WriteFrame(0, StreamTypeRTP, pkt)
+
+			select {
+			case <-frameRecv:
+				return
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+	}
+
+	<-ready
+	waitFrame()
+
+	// the server is restarted, the publisher connects again
+	stopServer()
+
+	err = <-errs
+	require.Error(t, err)
+
+	stopServer = startServer()
+	defer stopServer()
+
+	<-ready
+	waitFrame()
+
+	// tracks are not edited by the publisher
+	require.Equal(t, "", track.Control())
+}
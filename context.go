@@ -0,0 +1,33 @@
+package gortsplib
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// watchContext interrupts pending reads and writes of nconn when ctx is done.
+// It returns a function that must be called once the operations are over.
+func watchContext(ctx context.Context, nconn net.Conn) func() {
+	if ctx.Done() == nil {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	exited := make(chan struct{})
+
+	go func() {
+		defer close(exited)
+
+		select {
+		case <-ctx.Done():
+			nconn.SetDeadline(time.Now())
+		case <-done:
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-exited
+	}
+}
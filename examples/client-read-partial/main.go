@@ -45,7 +45,7 @@ func main() {
 		}
 	}
 
-	_, err = conn.Play()
+	_, err = conn.Play(nil)
 	if err != nil {
 		panic(err)
 	}
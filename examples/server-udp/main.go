@@ -16,8 +16,7 @@ import (
 
 var mutex sync.Mutex
 var publisher *gortsplib.ServerConn
-var readers = make(map[*gortsplib.ServerConn]struct{})
-var sdp []byte
+var stream *gortsplib.ServerStream
 
 // this is called for each incoming connection
 func handleConn(conn *gortsplib.ServerConn) {
@@ -39,7 +38,7 @@ func handleConn(conn *gortsplib.ServerConn) {
 
 		return &base.Response{
 			StatusCode: base.StatusOK,
-		}, sdp, nil
+		}, stream.Tracks().Write(), nil
 	}
 
 	// called after receiving an ANNOUNCE request.
@@ -54,7 +53,7 @@ func handleConn(conn *gortsplib.ServerConn) {
 		}
 
 		publisher = conn
-		sdp = ctx.Tracks.Write()
+		stream = gortsplib.NewServerStream(ctx.Tracks)
 
 		return &base.Response{
 			StatusCode: base.StatusOK,
@@ -79,7 +78,13 @@ func handleConn(conn *gortsplib.ServerConn) {
 		mutex.Lock()
 		defer mutex.Unlock()
 
-		readers[conn] = struct{}{}
+		if stream == nil {
+			return &base.Response{
+				StatusCode: base.StatusNotFound,
+			}, nil
+		}
+
+		stream.AddReader(conn)
 
 		return &base.Response{
 			StatusCode: base.StatusOK,
@@ -115,9 +120,7 @@ func handleConn(conn *gortsplib.ServerConn) {
 
 		// if we are the publisher, route frames to readers
 		if conn == publisher {
-			for r := range readers {
-				r.WriteFrame(trackID, typ, buf)
-			}
+			stream.WriteFrame(trackID, typ, buf)
 		}
 	}
 
@@ -135,10 +138,11 @@ func handleConn(conn *gortsplib.ServerConn) {
 	defer mutex.Unlock()
 
 	if conn == publisher {
+		stream.Close()
 		publisher = nil
-		sdp = nil
-	} else {
-		delete(readers, conn)
+		stream = nil
+	} else if stream != nil {
+		stream.RemoveReader(conn)
 	}
 }
 
@@ -16,13 +16,10 @@ import (
 
 var mutex sync.Mutex
 var publisher *gortsplib.ServerConn
-var readers = make(map[*gortsplib.ServerConn]struct{})
-var sdp []byte
+var stream *gortsplib.ServerStream
 
 // this is called for each incoming connection
-func handleConn(conn *gortsplib.ServerConn) {
-	defer conn.Close()
-
+func onConnOpen(conn *gortsplib.ServerConn) gortsplib.ServerConnReadHandlers {
 	log.Printf("client connected")
 
 	// called after receiving a DESCRIBE request.
@@ -39,7 +36,7 @@ func handleConn(conn *gortsplib.ServerConn) {
 
 		return &base.Response{
 			StatusCode: base.StatusOK,
-		}, sdp, nil
+		}, stream.Tracks().Write(), nil
 	}
 
 	// called after receiving an ANNOUNCE request.
@@ -54,7 +51,7 @@ func handleConn(conn *gortsplib.ServerConn) {
 		}
 
 		publisher = conn
-		sdp = ctx.Tracks.Write()
+		stream = gortsplib.NewServerStream(ctx.Tracks)
 
 		return &base.Response{
 			StatusCode: base.StatusOK,
@@ -79,7 +76,13 @@ func handleConn(conn *gortsplib.ServerConn) {
 		mutex.Lock()
 		defer mutex.Unlock()
 
-		readers[conn] = struct{}{}
+		if stream == nil {
+			return &base.Response{
+				StatusCode: base.StatusNotFound,
+			}, nil
+		}
+
+		stream.AddReader(conn)
 
 		return &base.Response{
 			StatusCode: base.StatusOK,
@@ -115,30 +118,33 @@ func handleConn(conn *gortsplib.ServerConn) {
 
 		// if we are the publisher, route frames to readers
 		if conn == publisher {
-			for r := range readers {
-				r.WriteFrame(trackID, typ, buf)
-			}
+			stream.WriteFrame(trackID, typ, buf)
 		}
 	}
 
-	err := <-conn.Read(gortsplib.ServerConnReadHandlers{
+	return gortsplib.ServerConnReadHandlers{
 		OnDescribe: onDescribe,
 		OnAnnounce: onAnnounce,
 		OnSetup:    onSetup,
 		OnPlay:     onPlay,
 		OnRecord:   onRecord,
 		OnFrame:    onFrame,
-	})
+	}
+}
+
+// this is called when a connection is closed
+func onConnClose(conn *gortsplib.ServerConn, err error) {
 	log.Printf("client disconnected (%s)", err)
 
 	mutex.Lock()
 	defer mutex.Unlock()
 
 	if conn == publisher {
+		stream.Close()
 		publisher = nil
-		sdp = nil
-	} else {
-		delete(readers, conn)
+		stream = nil
+	} else if stream != nil {
+		stream.RemoveReader(conn)
 	}
 }
 
@@ -150,13 +156,10 @@ func main() {
 	}
 	log.Printf("server is ready")
 
-	// accept connections
-	for {
-		conn, err := s.Accept()
-		if err != nil {
-			panic(err)
-		}
-
-		go handleConn(conn)
-	}
+	// accept and handle connections
+	err = s.Handle(gortsplib.ServerHandler{
+		OnConnOpen:  onConnOpen,
+		OnConnClose: onConnClose,
+	})
+	panic(err)
 }
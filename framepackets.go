@@ -0,0 +1,55 @@
+package gortsplib
+
+import (
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+)
+
+// wrapOnFrame returns a frame callback that calls onFrame, then parses frames
+// and passes them to onPacketRTP and onPacketRTCP, if they are not nil.
+// Frames that can't be parsed are passed to onFrame only, and are reported
+// to the logger.
+func wrapOnFrame(
+	logger Logger,
+	onFrame func(int, StreamType, []byte),
+	onPacketRTP func(int, *rtp.Packet),
+	onPacketRTCP func(int, []rtcp.Packet),
+) func(int, StreamType, []byte) {
+	if onPacketRTP == nil && onPacketRTCP == nil {
+		return onFrame
+	}
+
+	return func(trackID int, streamType StreamType, payload []byte) {
+		if onFrame != nil {
+			onFrame(trackID, streamType, payload)
+		}
+
+		if streamType == StreamTypeRTP {
+			if onPacketRTP == nil {
+				return
+			}
+
+			var pkt rtp.Packet
+			err := pkt.Unmarshal(payload)
+			if err != nil {
+				logger.Warnf("unable to parse RTP packet of track %d: %s", trackID, err)
+				return
+			}
+
+			onPacketRTP(trackID, &pkt)
+			return
+		}
+
+		if onPacketRTCP == nil {
+			return
+		}
+
+		pkts, err := rtcp.Unmarshal(payload)
+		if err != nil {
+			logger.Warnf("unable to parse RTCP packet of track %d: %s", trackID, err)
+			return
+		}
+
+		onPacketRTCP(trackID, pkts)
+	}
+}
@@ -4,8 +4,10 @@ go 1.13
 
 require (
 	github.com/icza/bitio v1.0.0
-	github.com/pion/rtcp v1.2.4
-	github.com/pion/rtp v1.6.1
+	github.com/pion/rtcp v1.2.6
+	github.com/pion/rtp v1.6.2
 	github.com/pion/sdp/v3 v3.0.2
+	github.com/pion/srtp/v2 v2.0.1
 	github.com/stretchr/testify v1.6.1
+	golang.org/x/net v0.0.0-20201201195509-5d6afe98e0b7
 )
@@ -1,12 +1,29 @@
 package gortsplib
 
 import (
+	"fmt"
 	"os"
 	"os/exec"
 	"strconv"
 	"time"
 )
 
+// testLogger is a Logger that passes warnings to a channel.
+type testLogger struct {
+	warnings chan string
+}
+
+func (l *testLogger) Debugf(format string, args ...interface{}) {}
+
+func (l *testLogger) Warnf(format string, args ...interface{}) {
+	select {
+	case l.warnings <- fmt.Sprintf(format, args...):
+	default:
+	}
+}
+
+func (l *testLogger) Errorf(format string, args ...interface{}) {}
+
 type container struct {
 	name string
 }
@@ -0,0 +1,126 @@
+package gortsplib
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// sortAddresses sorts resolved addresses as described in RFC 8305, section 4:
+// address families are interleaved, starting with the family of the
+// first address, while the order within each family is preserved.
+func sortAddresses(addrs []string) []string {
+	var first []string
+	var second []string
+
+	firstIsV4 := false
+	for i, addr := range addrs {
+		isV4 := net.ParseIP(addr).To4() != nil
+		if i == 0 {
+			firstIsV4 = isV4
+		}
+
+		if isV4 == firstIsV4 {
+			first = append(first, addr)
+		} else {
+			second = append(second, addr)
+		}
+	}
+
+	ret := make([]string, 0, len(addrs))
+	for len(first) > 0 || len(second) > 0 {
+		if len(first) > 0 {
+			ret = append(ret, first[0])
+			first = first[1:]
+		}
+		if len(second) > 0 {
+			ret = append(ret, second[0])
+			second = second[1:]
+		}
+	}
+	return ret
+}
+
+// dialHappyEyeballs connects to multiple addresses as described in RFC 8305:
+// a new attempt is started every delay, or as soon as the previous attempt
+// fails, and the first connection that is established is returned.
+func dialHappyEyeballs(ctx context.Context, addrs []string, delay time.Duration,
+	dial func(ctx context.Context, address string) (net.Conn, error)) (net.Conn, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type dialRes struct {
+		conn net.Conn
+		err  error
+	}
+
+	// channel is buffered, therefore attempts never block
+	results := make(chan dialRes, len(addrs))
+	next := 0
+	pending := 0
+
+	startNext := func() {
+		addr := addrs[next]
+		next++
+		pending++
+
+		go func() {
+			conn, err := dial(ctx, addr)
+			results <- dialRes{conn, err}
+		}()
+	}
+
+	startNext()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var firstErr error
+
+	for {
+		var timerC <-chan time.Time
+		if next < len(addrs) {
+			timerC = timer.C
+		}
+
+		select {
+		case res := <-results:
+			pending--
+
+			if res.err == nil {
+				// close connections that are established after this one
+				go func(n int) {
+					for i := 0; i < n; i++ {
+						res := <-results
+						if res.err == nil {
+							res.conn.Close()
+						}
+					}
+				}(pending)
+
+				return res.conn, nil
+			}
+
+			if firstErr == nil {
+				firstErr = res.err
+			}
+
+			if next < len(addrs) {
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				startNext()
+				timer.Reset(delay)
+			} else if pending == 0 {
+				return nil, firstErr
+			}
+
+		case <-timerC:
+			startNext()
+			timer.Reset(delay)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package gortsplib
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSortAddresses(t *testing.T) {
+	for _, ca := range []struct {
+		name string
+		in   []string
+		out  []string
+	}{
+		{
+			"ipv6 first",
+			[]string{"2001:db8::1", "2001:db8::2", "192.0.2.1", "192.0.2.2", "192.0.2.3"},
+			[]string{"2001:db8::1", "192.0.2.1", "2001:db8::2", "192.0.2.2", "192.0.2.3"},
+		},
+		{
+			"ipv4 first",
+			[]string{"192.0.2.1", "192.0.2.2", "2001:db8::1"},
+			[]string{"192.0.2.1", "2001:db8::1", "192.0.2.2"},
+		},
+		{
+			"single family",
+			[]string{"192.0.2.1", "192.0.2.2"},
+			[]string{"192.0.2.1", "192.0.2.2"},
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			require.Equal(t, ca.out, sortAddresses(ca.in))
+		})
+	}
+}
+
+type testHappyEyeballsConn struct {
+	net.Conn
+	address string
+}
+
+func TestDialHappyEyeballs(t *testing.T) {
+	t.Run("failure", func(t *testing.T) {
+		// a failed attempt starts the next one immediately
+		start := time.Now()
+
+		conn, err := dialHappyEyeballs(context.Background(), []string{"a", "b"}, 10*time.Second,
+			func(ctx context.Context, address string) (net.Conn, error) {
+				if address == "a" {
+					return nil, fmt.Errorf("refused")
+				}
+				return &testHappyEyeballsConn{address: address}, nil
+			})
+		require.NoError(t, err)
+		require.Equal(t, "b", conn.(*testHappyEyeballsConn).address)
+		require.Less(t, int64(time.Since(start)), int64(5*time.Second))
+	})
+
+	t.Run("slow", func(t *testing.T) {
+		// a slow attempt doesn't prevent the next one from starting
+		var mutex sync.Mutex
+		var dialed []string
+
+		conn, err := dialHappyEyeballs(context.Background(), []string{"a", "b"}, 50*time.Millisecond,
+			func(ctx context.Context, address string) (net.Conn, error) {
+				mutex.Lock()
+				dialed = append(dialed, address)
+				mutex.Unlock()
+
+				if address == "a" {
+					<-ctx.Done()
+					return nil, ctx.Err()
+				}
+				return &testHappyEyeballsConn{address: address}, nil
+			})
+		require.NoError(t, err)
+		require.Equal(t, "b", conn.(*testHappyEyeballsConn).address)
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		require.Equal(t, []string{"a", "b"}, dialed)
+	})
+
+	t.Run("all failed", func(t *testing.T) {
+		_, err := dialHappyEyeballs(context.Background(), []string{"a", "b"}, 50*time.Millisecond,
+			func(ctx context.Context, address string) (net.Conn, error) {
+				return nil, fmt.Errorf("refused %s", address)
+			})
+		require.EqualError(t, err, "refused a")
+	})
+}
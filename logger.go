@@ -0,0 +1,18 @@
+package gortsplib
+
+// Logger is a logger that receives protocol-level messages about events
+// that are handled without closing the connection, like discarded packets
+// or frames. It can be implemented by wrapping any logging library.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+type nilLogger struct{}
+
+func (nilLogger) Debugf(format string, args ...interface{}) {}
+
+func (nilLogger) Warnf(format string, args ...interface{}) {}
+
+func (nilLogger) Errorf(format string, args ...interface{}) {}
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -143,3 +144,76 @@ func TestAuthHashed(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthNonceCount(t *testing.T) {
+	va := NewValidator("testuser", "testpass", []headers.AuthMethod{headers.AuthDigest})
+
+	se, err := NewSender(va.GenerateHeader(), "testuser", "testpass")
+	require.NoError(t, err)
+
+	u := base.MustParseURL("rtsp://myhost/mypath")
+
+	authorization1 := se.GenerateHeader(base.Options, u)
+	authorization2 := se.GenerateHeader(base.Options, u)
+
+	err = va.ValidateHeader(authorization2, base.Options, u, nil)
+	require.NoError(t, err)
+
+	// replayed
+	err = va.ValidateHeader(authorization2, base.Options, u, nil)
+	require.EqualError(t, err, "nonce count reused (2)")
+
+	// went backwards
+	err = va.ValidateHeader(authorization1, base.Options, u, nil)
+	require.EqualError(t, err, "nonce count reused (1)")
+
+	err = va.ValidateHeader(se.GenerateHeader(base.Options, u), base.Options, u, nil)
+	require.NoError(t, err)
+}
+
+func TestAuthStaleNonce(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	va := NewValidator("testuser", "testpass", []headers.AuthMethod{headers.AuthDigest})
+	va.now = func() time.Time { return now }
+	va.renewNonce()
+
+	wwwAuthenticate := va.GenerateHeader()
+
+	se, err := NewSender(wwwAuthenticate, "testuser", "testpass")
+	require.NoError(t, err)
+
+	u := base.MustParseURL("rtsp://myhost/mypath")
+
+	err = va.ValidateHeader(se.GenerateHeader(base.Options, u), base.Options, u, nil)
+	require.NoError(t, err)
+
+	// a nonce that is used regularly doesn't expire
+	for i := 0; i < 3; i++ {
+		now = now.Add(validatorNonceLifetime / 2)
+
+		err = va.ValidateHeader(se.GenerateHeader(base.Options, u), base.Options, u, nil)
+		require.NoError(t, err)
+	}
+
+	now = now.Add(validatorNonceLifetime)
+
+	err = va.ValidateHeader(se.GenerateHeader(base.Options, u), base.Options, u, nil)
+	require.Equal(t, ErrStaleNonce, err)
+
+	var h headers.Auth
+	err = h.Read(va.GenerateHeader())
+	require.NoError(t, err)
+	require.Equal(t, "true", *h.Stale)
+	require.NotEqual(t, wwwAuthenticate, va.GenerateHeader())
+
+	// credentials generated with the previous nonce are refused
+	err = va.ValidateHeader(se.GenerateHeader(base.Options, u), base.Options, u, nil)
+	require.EqualError(t, err, "wrong nonce")
+
+	se, err = NewSender(va.GenerateHeader(), "testuser", "testpass")
+	require.NoError(t, err)
+
+	err = va.ValidateHeader(se.GenerateHeader(base.Options, u), base.Options, u, nil)
+	require.NoError(t, err)
+}
@@ -1,7 +1,8 @@
 package auth
 
 import (
-	"encoding/base64"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"strings"
 
@@ -16,6 +17,11 @@ type Sender struct {
 	method headers.AuthMethod
 	realm  string
 	nonce  string
+
+	// filled when the quality of protection is used
+	qop    bool
+	cnonce string
+	nc     uint64
 }
 
 // NewSender allocates a Sender with the WWW-Authenticate header provided by
@@ -44,13 +50,23 @@ func NewSender(v base.HeaderValue, user string, pass string) (*Sender, error) {
 			return nil, fmt.Errorf("nonce not provided")
 		}
 
-		return &Sender{
+		se := &Sender{
 			user:   user,
 			pass:   pass,
 			method: headers.AuthDigest,
 			realm:  *auth.Realm,
 			nonce:  *auth.Nonce,
-		}, nil
+		}
+
+		if auth.Qop != nil && qopContainsAuth(*auth.Qop) {
+			cnonceByts := make([]byte, 8)
+			rand.Read(cnonceByts)
+
+			se.qop = true
+			se.cnonce = hex.EncodeToString(cnonceByts)
+		}
+
+		return se, nil
 	}
 
 	if headerAuthBasic := func() string {
@@ -89,23 +105,60 @@ func (se *Sender) GenerateHeader(method base.Method, ur *base.URL) base.HeaderVa
 
 	switch se.method {
 	case headers.AuthBasic:
-		response := base64.StdEncoding.EncodeToString([]byte(se.user + ":" + se.pass))
-
-		return base.HeaderValue{"Basic " + response}
+		return headers.Authorization{
+			Method:    headers.AuthBasic,
+			BasicUser: se.user,
+			BasicPass: se.pass,
+		}.Write()
 
 	case headers.AuthDigest:
-		response := md5Hex(md5Hex(se.user+":"+se.realm+":"+se.pass) + ":" +
-			se.nonce + ":" + md5Hex(string(method)+":"+urStr))
-
-		return headers.Auth{
-			Method:   headers.AuthDigest,
-			Username: &se.user,
-			Realm:    &se.realm,
-			Nonce:    &se.nonce,
-			URI:      &urStr,
-			Response: &response,
+		ha1 := md5Hex(se.user + ":" + se.realm + ":" + se.pass)
+		ha2 := md5Hex(string(method) + ":" + urStr)
+
+		if se.qop {
+			se.nc++
+			qop := "auth"
+			nc := fmt.Sprintf("%08x", se.nc)
+			response := md5Hex(ha1 + ":" + se.nonce + ":" + nc + ":" +
+				se.cnonce + ":" + qop + ":" + ha2)
+
+			return headers.Authorization{
+				Method: headers.AuthDigest,
+				DigestValues: headers.Auth{
+					Username: &se.user,
+					Realm:    &se.realm,
+					Nonce:    &se.nonce,
+					URI:      &urStr,
+					Response: &response,
+					Qop:      &qop,
+					Nc:       &nc,
+					Cnonce:   &se.cnonce,
+				},
+			}.Write()
+		}
+
+		response := md5Hex(ha1 + ":" + se.nonce + ":" + ha2)
+
+		return headers.Authorization{
+			Method: headers.AuthDigest,
+			DigestValues: headers.Auth{
+				Username: &se.user,
+				Realm:    &se.realm,
+				Nonce:    &se.nonce,
+				URI:      &urStr,
+				Response: &response,
+			},
 		}.Write()
 	}
 
 	return nil
 }
+
+func qopContainsAuth(v string) bool {
+	for _, qop := range strings.Split(v, ",") {
+		if strings.TrimSpace(qop) == "auth" {
+			return true
+		}
+	}
+	return false
+}
@@ -2,15 +2,30 @@ package auth
 
 import (
 	"crypto/rand"
-	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/majoyz/gortsplib/pkg/base"
 	"github.com/majoyz/gortsplib/pkg/headers"
 )
 
+const (
+	// a nonce is replaced when it hasn't been used for this period; requests
+	// that use it afterwards are refused with a stale error, and clients must
+	// use the new nonce.
+	validatorNonceLifetime = 60 * time.Second
+)
+
+// ErrStaleNonce is returned by ValidateHeader when the credentials are correct
+// but the nonce has expired, since it hasn't been used for some time. The WWW-Authenticate header generated next contains
+// a new nonce and the stale flag, that allows clients to authenticate again
+// without asking for credentials.
+var ErrStaleNonce = errors.New("stale nonce")
+
 // Validator allows to validate some credentials generated by a Sender.
 type Validator struct {
 	user       string
@@ -19,7 +34,12 @@ type Validator struct {
 	passHashed bool
 	methods    []headers.AuthMethod
 	realm      string
-	nonce      string
+	now        func() time.Time
+
+	nonce         string
+	nonceLastUsed time.Time
+	nonceCount    uint64
+	stale         bool
 }
 
 // NewValidator allocates a Validator.
@@ -50,24 +70,50 @@ func NewValidator(user string, pass string, methods []headers.AuthMethod) *Valid
 		methods = []headers.AuthMethod{headers.AuthBasic}
 	}
 
-	nonceByts := make([]byte, 16)
-	rand.Read(nonceByts)
-	nonce := hex.EncodeToString(nonceByts)
-
-	return &Validator{
+	va := &Validator{
 		user:       user,
 		userHashed: userHashed,
 		pass:       pass,
 		passHashed: passHashed,
 		methods:    methods,
 		realm:      "IPCAM",
-		nonce:      nonce,
+		now:        time.Now,
 	}
+	va.renewNonce()
+
+	return va
+}
+
+func (va *Validator) renewNonce() {
+	nonceByts := make([]byte, 16)
+	rand.Read(nonceByts)
+
+	va.nonce = hex.EncodeToString(nonceByts)
+	va.nonceLastUsed = va.now()
+	va.nonceCount = 0
+}
+
+func (va *Validator) nonceExpired() bool {
+	return va.now().Sub(va.nonceLastUsed) >= validatorNonceLifetime
 }
 
 // GenerateHeader generates the WWW-Authenticate header needed by a client to
 // authenticate.
+// If the nonce has expired, it is replaced.
 func (va *Validator) GenerateHeader() base.HeaderValue {
+	if va.nonceExpired() {
+		va.renewNonce()
+	}
+
+	var stale *string
+	if va.stale {
+		v := "true"
+		stale = &v
+		va.stale = false
+	}
+
+	qop := "auth"
+
 	var ret base.HeaderValue
 	for _, m := range va.methods {
 		switch m {
@@ -82,6 +128,8 @@ func (va *Validator) GenerateHeader() base.HeaderValue {
 				Method: headers.AuthDigest,
 				Realm:  &va.realm,
 				Nonce:  &va.nonce,
+				Stale:  stale,
+				Qop:    &qop,
 			}.Write()...)
 		}
 	}
@@ -90,6 +138,11 @@ func (va *Validator) GenerateHeader() base.HeaderValue {
 
 // ValidateHeader validates the Authorization header sent by a client after receiving the
 // WWW-Authenticate header.
+// Digest credentials are accepted only once for each nonce count, that must increase
+// at every request, and are refused with ErrStaleNonce when the nonce has expired.
+// A nonce expires when it hasn't been used for some time, therefore clients that
+// send requests regularly, including legacy clients that don't use a quality of
+// protection and a nonce count, can keep using the same nonce.
 func (va *Validator) ValidateHeader(v base.HeaderValue, method base.Method, ur *base.URL,
 	altURL *base.URL) error {
 	if len(v) == 0 {
@@ -99,48 +152,36 @@ func (va *Validator) ValidateHeader(v base.HeaderValue, method base.Method, ur *
 		return fmt.Errorf("authorization header provided multiple times")
 	}
 
-	v0 := v[0]
-
-	switch {
-	case strings.HasPrefix(v0, "Basic "):
-		inResponse := v0[len("Basic "):]
-
-		tmp, err := base64.StdEncoding.DecodeString(inResponse)
-		if err != nil {
-			return fmt.Errorf("wrong response")
-		}
-		tmp2 := strings.Split(string(tmp), ":")
-		if len(tmp2) != 2 {
-			return fmt.Errorf("wrong response")
-		}
-		user, pass := tmp2[0], tmp2[1]
+	var ah headers.Authorization
+	err := ah.Read(v)
+	if err != nil {
+		return err
+	}
 
+	switch ah.Method {
+	case headers.AuthBasic:
 		if !va.userHashed {
-			if user != va.user {
+			if ah.BasicUser != va.user {
 				return fmt.Errorf("wrong response")
 			}
 		} else {
-			if sha256Base64(user) != va.user {
+			if sha256Base64(ah.BasicUser) != va.user {
 				return fmt.Errorf("wrong response")
 			}
 		}
 
 		if !va.passHashed {
-			if pass != va.pass {
+			if ah.BasicPass != va.pass {
 				return fmt.Errorf("wrong response")
 			}
 		} else {
-			if sha256Base64(pass) != va.pass {
+			if sha256Base64(ah.BasicPass) != va.pass {
 				return fmt.Errorf("wrong response")
 			}
 		}
 
-	case strings.HasPrefix(v0, "Digest "):
-		var auth headers.Auth
-		err := auth.Read(base.HeaderValue{v0})
-		if err != nil {
-			return err
-		}
+	default: // headers.AuthDigest
+		auth := ah.DigestValues
 
 		if auth.Realm == nil {
 			return fmt.Errorf("realm not provided")
@@ -166,6 +207,27 @@ func (va *Validator) ValidateHeader(v base.HeaderValue, method base.Method, ur *
 			return fmt.Errorf("wrong nonce")
 		}
 
+		var nc uint64
+		if auth.Qop != nil {
+			if *auth.Qop != "auth" {
+				return fmt.Errorf("unsupported qop (%v)", *auth.Qop)
+			}
+
+			if auth.Nc == nil {
+				return fmt.Errorf("nonce count not provided")
+			}
+
+			if auth.Cnonce == nil {
+				return fmt.Errorf("client nonce not provided")
+			}
+
+			var err error
+			nc, err = strconv.ParseUint(*auth.Nc, 16, 32)
+			if err != nil {
+				return fmt.Errorf("invalid nonce count (%v)", *auth.Nc)
+			}
+		}
+
 		if *auth.Realm != va.realm {
 			return fmt.Errorf("wrong realm")
 		}
@@ -187,15 +249,35 @@ func (va *Validator) ValidateHeader(v base.HeaderValue, method base.Method, ur *
 			}
 		}
 
-		response := md5Hex(md5Hex(va.user+":"+va.realm+":"+va.pass) +
-			":" + va.nonce + ":" + md5Hex(string(method)+":"+urlString))
+		ha1 := md5Hex(va.user + ":" + va.realm + ":" + va.pass)
+		ha2 := md5Hex(string(method) + ":" + urlString)
+
+		var response string
+		if auth.Qop != nil {
+			response = md5Hex(ha1 + ":" + va.nonce + ":" + *auth.Nc + ":" +
+				*auth.Cnonce + ":" + *auth.Qop + ":" + ha2)
+		} else {
+			response = md5Hex(ha1 + ":" + va.nonce + ":" + ha2)
+		}
 
 		if *auth.Response != response {
 			return fmt.Errorf("wrong response")
 		}
 
-	default:
-		return fmt.Errorf("unsupported authorization header")
+		if va.nonceExpired() {
+			va.stale = true
+			return ErrStaleNonce
+		}
+
+		if auth.Qop != nil {
+			if nc <= va.nonceCount {
+				return fmt.Errorf("nonce count reused (%d)", nc)
+			}
+			va.nonceCount = nc
+		}
+
+		va.nonceLastUsed = va.now()
+
 	}
 
 	return nil
@@ -9,7 +9,7 @@ import (
 
 type payload []byte
 
-func (c *payload) read(rb *bufio.Reader, header Header) error {
+func (c *payload) read(rb *bufio.Reader, header Header, limits ReadLimits) error {
 	cls, ok := header["Content-Length"]
 	if !ok || len(cls) != 1 {
 		*c = nil
@@ -21,9 +21,12 @@ func (c *payload) read(rb *bufio.Reader, header Header) error {
 		return fmt.Errorf("invalid Content-Length")
 	}
 
-	if cl > rtspMaxContentLength {
-		return fmt.Errorf("Content-Length exceeds %d (it's %d)",
-			rtspMaxContentLength, cl)
+	if cl > int64(limits.MaxBodySize) {
+		return ErrLimitExceeded{
+			What:  "Content-Length",
+			Limit: limits.MaxBodySize,
+			Value: int(cl),
+		}
 	}
 
 	*c = make([]byte, cl)
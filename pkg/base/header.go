@@ -32,10 +32,93 @@ func headerKeyNormalize(in string) string {
 type HeaderValue []string
 
 // Header is a RTSP reader, present in both Requests and Responses.
+// Keys of decoded headers are canonicalized, therefore they can be accessed
+// with the standard notation (i.e. "CSeq"), while keys of headers that are
+// filled manually can be accessed in a case-insensitive way with Get().
+// Headers provided multiple times are preserved as multiple values.
+// Since Header is a map, the order in which headers are received or
+// inserted is not preserved; headers are always written in alphabetical
+// order of their canonical keys.
 type Header map[string]HeaderValue
 
-func (h *Header) read(rb *bufio.Reader) error {
+// Get returns the values of a header, looking up its key in a
+// case-insensitive way. Values of keys that differ only by case are merged.
+func (h Header) Get(key string) (HeaderValue, bool) {
+	key = headerKeyNormalize(key)
+
+	var ret HeaderValue
+	found := false
+
+	for _, k := range h.variants(key) {
+		ret = append(ret, h[k]...)
+		found = true
+	}
+
+	return ret, found
+}
+
+// Set sets the values of a header, replacing the values of keys that
+// differ only by case.
+func (h Header) Set(key string, v HeaderValue) {
+	h.Del(key)
+	h[headerKeyNormalize(key)] = v
+}
+
+// Add appends a value to a header.
+func (h Header) Add(key string, value string) {
+	v, _ := h.Get(key)
+	h.Set(key, append(v, value))
+}
+
+// Del deletes a header, looking up its key in a case-insensitive way.
+func (h Header) Del(key string) {
+	for _, k := range h.variants(headerKeyNormalize(key)) {
+		delete(h, k)
+	}
+}
+
+// Keys returns the canonical keys of the headers in alphabetical order,
+// that is the order in which they are written.
+func (h Header) Keys() []string {
+	keys := make([]string, 0, len(h))
+	seen := make(map[string]struct{}, len(h))
+
+	for key := range h {
+		key = headerKeyNormalize(key)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// variants returns the keys that correspond to a canonical key,
+// with the canonical one first.
+func (h Header) variants(key string) []string {
+	var ret []string
+
+	if _, ok := h[key]; ok {
+		ret = append(ret, key)
+	}
+
+	var others []string
+	for k := range h {
+		if k != key && headerKeyNormalize(k) == key {
+			others = append(others, k)
+		}
+	}
+	sort.Strings(others)
+
+	return append(ret, others...)
+}
+
+func (h *Header) read(rb *bufio.Reader, limits ReadLimits) error {
 	*h = make(Header)
+	size := 0
 
 	for {
 		byt, err := rb.ReadByte()
@@ -52,9 +135,12 @@ func (h *Header) read(rb *bufio.Reader) error {
 			break
 		}
 
-		if len(*h) >= headerMaxEntryCount {
-			return fmt.Errorf("headers count exceeds %d (it's %d)",
-				headerMaxEntryCount, len(*h))
+		if len(*h) >= limits.MaxHeaderCount {
+			return ErrLimitExceeded{
+				What:  "headers count",
+				Limit: limits.MaxHeaderCount,
+				Value: len(*h) + 1,
+			}
 		}
 
 		key := string([]byte{byt})
@@ -94,6 +180,15 @@ func (h *Header) read(rb *bufio.Reader) error {
 			return err
 		}
 
+		size += len(key) + len(val)
+		if size > limits.MaxHeaderSize {
+			return ErrLimitExceeded{
+				What:  "headers size",
+				Limit: limits.MaxHeaderSize,
+				Value: size,
+			}
+		}
+
 		(*h)[key] = append((*h)[key], val)
 	}
 
@@ -101,16 +196,11 @@ func (h *Header) read(rb *bufio.Reader) error {
 }
 
 func (h Header) write(wb *bufio.Writer) error {
-	// sort headers by key
-	// in order to obtain deterministic results
-	keys := make([]string, len(h))
-	for key := range h {
-		keys = append(keys, key)
-	}
-	sort.Strings(keys)
-
-	for _, key := range keys {
-		for _, val := range h[key] {
+	// keys are canonicalized and sorted, in order to obtain deterministic
+	// results, since the insertion order is not available
+	for _, key := range h.Keys() {
+		vals, _ := h.Get(key)
+		for _, val := range vals {
 			_, err := wb.Write([]byte(key + ": " + val + "\r\n"))
 			if err != nil {
 				return err
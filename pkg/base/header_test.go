@@ -97,7 +97,7 @@ func TestHeaderRead(t *testing.T) {
 	for _, ca := range casesHeader {
 		t.Run(ca.name, func(t *testing.T) {
 			h := make(Header)
-			err := h.read(bufio.NewReader(bytes.NewBuffer(ca.dec)))
+			err := h.read(bufio.NewReader(bytes.NewBuffer(ca.dec)), (&ReadLimits{}).withDefaults())
 			require.NoError(t, err)
 			require.Equal(t, ca.header, h)
 		})
@@ -116,3 +116,52 @@ func TestHeaderWrite(t *testing.T) {
 		})
 	}
 }
+
+func TestHeaderWriteNonCanonical(t *testing.T) {
+	var buf bytes.Buffer
+	bw := bufio.NewWriter(&buf)
+	err := Header{
+		"cseq":             HeaderValue{"1"},
+		"www-authenticate": HeaderValue{"b"},
+		"WWW-Authenticate": HeaderValue{"a"},
+	}.write(bw)
+	require.NoError(t, err)
+	bw.Flush()
+	require.Equal(t, []byte("CSeq: 1\r\n"+
+		"WWW-Authenticate: a\r\n"+
+		"WWW-Authenticate: b\r\n"+
+		"\r\n"), buf.Bytes())
+}
+
+func TestHeaderAccessors(t *testing.T) {
+	h := Header{
+		"cseq":     HeaderValue{"1"},
+		"Session":  HeaderValue{"a"},
+		"SESSION":  HeaderValue{"b"},
+		"rtp-info": HeaderValue{"url=rtsp://localhost"},
+	}
+
+	v, ok := h.Get("CSeq")
+	require.Equal(t, true, ok)
+	require.Equal(t, HeaderValue{"1"}, v)
+
+	v, ok = h.Get("session")
+	require.Equal(t, true, ok)
+	require.Equal(t, HeaderValue{"a", "b"}, v)
+
+	_, ok = h.Get("Transport")
+	require.Equal(t, false, ok)
+
+	require.Equal(t, []string{"CSeq", "RTP-Info", "Session"}, h.Keys())
+
+	h.Set("SeSsIoN", HeaderValue{"c"})
+	require.Equal(t, HeaderValue{"c"}, h["Session"])
+	_, ok = h["SESSION"]
+	require.Equal(t, false, ok)
+
+	h.Add("cseq", "2")
+	require.Equal(t, HeaderValue{"1", "2"}, h["CSeq"])
+
+	h.Del("RTP-INFO")
+	require.Equal(t, []string{"CSeq", "Session"}, h.Keys())
+}
@@ -11,7 +11,9 @@ const (
 	interleavedFrameMagicByte = 0x24
 )
 
-// ReadInterleavedFrameOrRequest reads an InterleavedFrame or a Response.
+// ReadInterleavedFrameOrRequest reads an InterleavedFrame or a Request.
+// It allows servers to receive requests, like keepalives and TEARDOWN,
+// while clients are sending frames.
 func ReadInterleavedFrameOrRequest(frame *InterleavedFrame, req *Request, br *bufio.Reader) (interface{}, error) {
 	b, err := br.ReadByte()
 	if err != nil {
@@ -35,6 +37,7 @@ func ReadInterleavedFrameOrRequest(frame *InterleavedFrame, req *Request, br *bu
 }
 
 // ReadInterleavedFrameOrResponse reads an InterleavedFrame or a Response.
+// It allows clients to receive responses while servers are sending frames.
 func ReadInterleavedFrameOrResponse(frame *InterleavedFrame, res *Response, br *bufio.Reader) (interface{}, error) {
 	b, err := br.ReadByte()
 	if err != nil {
@@ -57,6 +60,26 @@ func ReadInterleavedFrameOrResponse(frame *InterleavedFrame, res *Response, br *
 	return res, nil
 }
 
+// ReadInterleavedFrameOrRequestOrResponse reads an InterleavedFrame, a Request or a Response.
+func ReadInterleavedFrameOrRequestOrResponse(frame *InterleavedFrame, req *Request,
+	res *Response, br *bufio.Reader) (interface{}, error) {
+	b, err := br.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	br.UnreadByte()
+
+	if b == interleavedFrameMagicByte {
+		err := frame.Read(br)
+		if err != nil {
+			return nil, err
+		}
+		return frame, err
+	}
+
+	return ReadRequestOrResponse(req, res, br)
+}
+
 // InterleavedFrame is an interleaved frame, and allows to transfer binary data
 // within RTSP/TCP connections. It is used to send and receive RTP and RTCP packets with TCP.
 type InterleavedFrame struct {
@@ -116,19 +139,16 @@ func (f InterleavedFrame) Write(bw *bufio.Writer) error {
 		return uint8((f.TrackID * 2) + 1)
 	}()
 
-	_, err := bw.Write([]byte{0x24, channel})
-	if err != nil {
-		return err
-	}
-
-	buf := make([]byte, 2)
-	binary.BigEndian.PutUint16(buf, uint16(len(f.Payload)))
-	_, err = bw.Write(buf)
-	if err != nil {
-		return err
+	// write the header byte by byte in order to avoid allocations
+	le := uint16(len(f.Payload))
+	for _, b := range []byte{0x24, channel, byte(le >> 8), byte(le)} {
+		err := bw.WriteByte(b)
+		if err != nil {
+			return err
+		}
 	}
 
-	_, err = bw.Write(f.Payload)
+	_, err := bw.Write(f.Payload)
 	if err != nil {
 		return err
 	}
@@ -3,6 +3,7 @@ package base
 import (
 	"bufio"
 	"bytes"
+	"io/ioutil"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -48,3 +49,107 @@ func TestInterleavedFrameWrite(t *testing.T) {
 		})
 	}
 }
+
+func TestInterleavedFrameWriteAllocs(t *testing.T) {
+	bw := bufio.NewWriter(ioutil.Discard)
+	f := InterleavedFrame{
+		TrackID:    1,
+		StreamType: StreamTypeRTP,
+		Payload:    []byte{0x01, 0x02, 0x03, 0x04},
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		f.Write(bw)
+	})
+	require.Equal(t, float64(0), allocs)
+}
+
+func TestReadInterleavedFrameOrRequest(t *testing.T) {
+	byts := []byte{0x24, 0x6, 0x0, 0x4, 0x1, 0x2, 0x3, 0x4}
+	byts = append(byts, []byte("GET_PARAMETER rtsp://example.com/media.mp4 RTSP/1.0\r\n"+
+		"CSeq: 2\r\n"+
+		"\r\n")...)
+	byts = append(byts, []byte{0x24, 0x7, 0x0, 0x2, 0x5, 0x6}...)
+	byts = append(byts, []byte("TEARDOWN rtsp://example.com/media.mp4 RTSP/1.0\r\n"+
+		"CSeq: 3\r\n"+
+		"\r\n")...)
+	br := bufio.NewReader(bytes.NewBuffer(byts))
+
+	var f InterleavedFrame
+	var req Request
+
+	f.Payload = make([]byte, 1024)
+	what, err := ReadInterleavedFrameOrRequest(&f, &req, br)
+	require.NoError(t, err)
+	require.IsType(t, &InterleavedFrame{}, what)
+	require.Equal(t, []byte{0x1, 0x2, 0x3, 0x4}, f.Payload)
+
+	what, err = ReadInterleavedFrameOrRequest(&f, &req, br)
+	require.NoError(t, err)
+	require.IsType(t, &Request{}, what)
+	require.Equal(t, GetParameter, req.Method)
+
+	f.Payload = make([]byte, 1024)
+	what, err = ReadInterleavedFrameOrRequest(&f, &req, br)
+	require.NoError(t, err)
+	require.IsType(t, &InterleavedFrame{}, what)
+	require.Equal(t, 3, f.TrackID)
+	require.Equal(t, StreamTypeRTCP, f.StreamType)
+
+	what, err = ReadInterleavedFrameOrRequest(&f, &req, br)
+	require.NoError(t, err)
+	require.IsType(t, &Request{}, what)
+	require.Equal(t, Teardown, req.Method)
+}
+
+func TestReadInterleavedFrameOrResponse(t *testing.T) {
+	byts := []byte{0x24, 0x6, 0x0, 0x4, 0x1, 0x2, 0x3, 0x4}
+	byts = append(byts, []byte("RTSP/1.0 200 OK\r\n"+
+		"CSeq: 1\r\n"+
+		"\r\n")...)
+	br := bufio.NewReader(bytes.NewBuffer(byts))
+
+	var f InterleavedFrame
+	f.Payload = make([]byte, 1024)
+	var res Response
+
+	what, err := ReadInterleavedFrameOrResponse(&f, &res, br)
+	require.NoError(t, err)
+	require.IsType(t, &InterleavedFrame{}, what)
+
+	what, err = ReadInterleavedFrameOrResponse(&f, &res, br)
+	require.NoError(t, err)
+	require.IsType(t, &Response{}, what)
+	require.Equal(t, StatusOK, res.StatusCode)
+}
+
+func TestReadInterleavedFrameOrRequestOrResponse(t *testing.T) {
+	byts := []byte{0x24, 0x6, 0x0, 0x4, 0x1, 0x2, 0x3, 0x4}
+	byts = append(byts, []byte("REDIRECT rtsp://example.com/media.mp4 RTSP/1.0\r\n"+
+		"CSeq: 1\r\n"+
+		"Location: rtsp://example2.com/media.mp4\r\n"+
+		"\r\n")...)
+	byts = append(byts, []byte("RTSP/1.0 200 OK\r\n"+
+		"CSeq: 1\r\n"+
+		"\r\n")...)
+	br := bufio.NewReader(bytes.NewBuffer(byts))
+
+	var f InterleavedFrame
+	f.Payload = make([]byte, 1024)
+	var req Request
+	var res Response
+
+	what, err := ReadInterleavedFrameOrRequestOrResponse(&f, &req, &res, br)
+	require.NoError(t, err)
+	require.IsType(t, &InterleavedFrame{}, what)
+
+	what, err = ReadInterleavedFrameOrRequestOrResponse(&f, &req, &res, br)
+	require.NoError(t, err)
+	require.IsType(t, &Request{}, what)
+	require.Equal(t, Redirect, req.Method)
+
+	what, err = ReadInterleavedFrameOrRequestOrResponse(&f, &req, &res, br)
+	require.NoError(t, err)
+	require.IsType(t, &Response{}, what)
+	require.Equal(t, StatusOK, res.StatusCode)
+}
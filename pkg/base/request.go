@@ -5,6 +5,8 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"strconv"
 )
 
@@ -30,6 +32,7 @@ const (
 	Setup        Method = "SETUP"
 	SetParameter Method = "SET_PARAMETER"
 	Teardown     Method = "TEARDOWN"
+	Redirect     Method = "REDIRECT"
 )
 
 // Request is a RTSP request.
@@ -49,6 +52,10 @@ type Request struct {
 	// whether to wait for a response or not
 	// used only by ClientConn.Do()
 	SkipResponse bool
+
+	// (optional) limits enforced when reading the request
+	// used only by Read()
+	Limits *ReadLimits
 }
 
 // Read reads a request.
@@ -94,12 +101,14 @@ func (req *Request) Read(rb *bufio.Reader) error {
 		return err
 	}
 
-	err = req.Header.read(rb)
+	limits := req.Limits.withDefaults()
+
+	err = req.Header.read(rb, limits)
 	if err != nil {
 		return err
 	}
 
-	err = (*payload)(&req.Body).read(rb, req.Header)
+	err = (*payload)(&req.Body).read(rb, req.Header, limits)
 	if err != nil {
 		return err
 	}
@@ -128,6 +137,30 @@ func (req *Request) ReadIgnoreFrames(rb *bufio.Reader, buf []byte) error {
 	}
 }
 
+// ReadRequestOrResponse reads a Request or a Response.
+func ReadRequestOrResponse(req *Request, res *Response, br *bufio.Reader) (interface{}, error) {
+	if isResponse(br) {
+		err := res.Read(br)
+		if err != nil {
+			return nil, err
+		}
+		return res, nil
+	}
+
+	err := req.Read(br)
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// isResponse checks whether the next element in the reader is a response,
+// by checking whether it begins with the protocol.
+func isResponse(br *bufio.Reader) bool {
+	byts, err := br.Peek(len(rtspProtocol10))
+	return err == nil && string(byts) == rtspProtocol10
+}
+
 // Write writes a request.
 func (req Request) Write(bw *bufio.Writer) error {
 	urStr := req.URL.CloneWithoutCredentials().String()
@@ -153,6 +186,43 @@ func (req Request) Write(bw *bufio.Writer) error {
 	return bw.Flush()
 }
 
+// Marshal encodes a request.
+func (req Request) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	err := req.Write(bufio.NewWriter(&buf))
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes a request.
+func (req *Request) Unmarshal(byts []byte) error {
+	return req.Read(bufio.NewReader(bytes.NewReader(byts)))
+}
+
+// WriteTo implements io.WriterTo.
+func (req Request) WriteTo(w io.Writer) (int64, error) {
+	byts, err := req.Marshal()
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := w.Write(byts)
+	return int64(n), err
+}
+
+// ReadFrom implements io.ReaderFrom.
+// It reads r until EOF and decodes a request.
+func (req *Request) ReadFrom(r io.Reader) (int64, error) {
+	byts, err := ioutil.ReadAll(r)
+	if err != nil {
+		return int64(len(byts)), err
+	}
+
+	return int64(len(byts)), req.Unmarshal(byts)
+}
+
 // String implements fmt.Stringer.
 func (req Request) String() string {
 	buf := bytes.NewBuffer(nil)
@@ -153,3 +153,95 @@ func TestRequestWrite(t *testing.T) {
 		})
 	}
 }
+
+func TestRequestUnmarshal(t *testing.T) {
+	for _, c := range casesRequest {
+		t.Run(c.name, func(t *testing.T) {
+			var req Request
+			err := req.Unmarshal(c.byts)
+			require.NoError(t, err)
+			require.Equal(t, c.req, req)
+		})
+	}
+}
+
+func TestRequestMarshal(t *testing.T) {
+	for _, c := range casesRequest {
+		t.Run(c.name, func(t *testing.T) {
+			byts, err := c.req.Marshal()
+			require.NoError(t, err)
+			require.Equal(t, c.byts, byts)
+		})
+	}
+}
+
+func TestRequestReadFrom(t *testing.T) {
+	for _, c := range casesRequest {
+		t.Run(c.name, func(t *testing.T) {
+			var req Request
+			n, err := req.ReadFrom(bytes.NewReader(c.byts))
+			require.NoError(t, err)
+			require.Equal(t, int64(len(c.byts)), n)
+			require.Equal(t, c.req, req)
+		})
+	}
+}
+
+func TestRequestWriteTo(t *testing.T) {
+	for _, c := range casesRequest {
+		t.Run(c.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			n, err := c.req.WriteTo(&buf)
+			require.NoError(t, err)
+			require.Equal(t, int64(len(c.byts)), n)
+			require.Equal(t, c.byts, buf.Bytes())
+		})
+	}
+}
+
+func TestRequestReadLimits(t *testing.T) {
+	for _, ca := range []struct {
+		name   string
+		limits ReadLimits
+		byts   []byte
+		err    ErrLimitExceeded
+	}{
+		{
+			"header count",
+			ReadLimits{MaxHeaderCount: 2},
+			[]byte("OPTIONS rtsp://example.com/media.mp4 RTSP/1.0\r\n" +
+				"CSeq: 1\r\n" +
+				"Require: implicit-play\r\n" +
+				"Proxy-Require: gzipped-messages\r\n" +
+				"\r\n"),
+			ErrLimitExceeded{What: "headers count", Limit: 2, Value: 3},
+		},
+		{
+			"header size",
+			ReadLimits{MaxHeaderSize: 20},
+			[]byte("OPTIONS rtsp://example.com/media.mp4 RTSP/1.0\r\n" +
+				"CSeq: 1\r\n" +
+				"Require: implicit-play\r\n" +
+				"\r\n"),
+			ErrLimitExceeded{What: "headers size", Limit: 20, Value: 25},
+		},
+		{
+			"body size",
+			ReadLimits{MaxBodySize: 4},
+			[]byte("ANNOUNCE rtsp://example.com/media.mp4 RTSP/1.0\r\n" +
+				"CSeq: 1\r\n" +
+				"Content-Length: 5\r\n" +
+				"\r\n" +
+				"12345"),
+			ErrLimitExceeded{What: "Content-Length", Limit: 4, Value: 5},
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			req := Request{
+				Limits: &ca.limits,
+			}
+			err := req.Read(bufio.NewReader(bytes.NewBuffer(ca.byts)))
+			require.Equal(t, ca.err, err)
+		})
+	}
+}
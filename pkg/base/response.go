@@ -4,6 +4,8 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"strconv"
 )
 
@@ -131,6 +133,10 @@ type Response struct {
 
 	// optional body
 	Body []byte
+
+	// (optional) limits enforced when reading the response
+	// used only by Read()
+	Limits *ReadLimits
 }
 
 // Read reads a response.
@@ -172,12 +178,14 @@ func (res *Response) Read(rb *bufio.Reader) error {
 		return err
 	}
 
-	err = res.Header.read(rb)
+	limits := res.Limits.withDefaults()
+
+	err = res.Header.read(rb, limits)
 	if err != nil {
 		return err
 	}
 
-	err = (*payload)(&res.Body).read(rb, res.Header)
+	err = (*payload)(&res.Body).read(rb, res.Header, limits)
 	if err != nil {
 		return err
 	}
@@ -236,6 +244,43 @@ func (res Response) Write(bw *bufio.Writer) error {
 	return bw.Flush()
 }
 
+// Marshal encodes a response.
+func (res Response) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	err := res.Write(bufio.NewWriter(&buf))
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes a response.
+func (res *Response) Unmarshal(byts []byte) error {
+	return res.Read(bufio.NewReader(bytes.NewReader(byts)))
+}
+
+// WriteTo implements io.WriterTo.
+func (res Response) WriteTo(w io.Writer) (int64, error) {
+	byts, err := res.Marshal()
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := w.Write(byts)
+	return int64(n), err
+}
+
+// ReadFrom implements io.ReaderFrom.
+// It reads r until EOF and decodes a response.
+func (res *Response) ReadFrom(r io.Reader) (int64, error) {
+	byts, err := ioutil.ReadAll(r)
+	if err != nil {
+		return int64(len(byts)), err
+	}
+
+	return int64(len(byts)), res.Unmarshal(byts)
+}
+
 // String implements fmt.Stringer.
 func (res Response) String() string {
 	buf := bytes.NewBuffer(nil)
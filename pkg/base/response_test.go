@@ -132,6 +132,51 @@ func TestResponseWrite(t *testing.T) {
 	}
 }
 
+func TestResponseUnmarshal(t *testing.T) {
+	for _, c := range casesResponse {
+		t.Run(c.name, func(t *testing.T) {
+			var res Response
+			err := res.Unmarshal(c.byts)
+			require.NoError(t, err)
+			require.Equal(t, c.res, res)
+		})
+	}
+}
+
+func TestResponseMarshal(t *testing.T) {
+	for _, c := range casesResponse {
+		t.Run(c.name, func(t *testing.T) {
+			byts, err := c.res.Marshal()
+			require.NoError(t, err)
+			require.Equal(t, c.byts, byts)
+		})
+	}
+}
+
+func TestResponseReadFrom(t *testing.T) {
+	for _, c := range casesResponse {
+		t.Run(c.name, func(t *testing.T) {
+			var res Response
+			n, err := res.ReadFrom(bytes.NewReader(c.byts))
+			require.NoError(t, err)
+			require.Equal(t, int64(len(c.byts)), n)
+			require.Equal(t, c.res, res)
+		})
+	}
+}
+
+func TestResponseWriteTo(t *testing.T) {
+	for _, c := range casesResponse {
+		t.Run(c.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			n, err := c.res.WriteTo(&buf)
+			require.NoError(t, err)
+			require.Equal(t, int64(len(c.byts)), n)
+			require.Equal(t, c.byts, buf.Bytes())
+		})
+	}
+}
+
 func TestResponseWriteStatusAutofill(t *testing.T) {
 	res := &Response{
 		StatusCode: StatusMethodNotAllowed,
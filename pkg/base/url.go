@@ -2,6 +2,7 @@ package base
 
 import (
 	"fmt"
+	"net"
 	"net/url"
 	"strings"
 )
@@ -116,13 +117,104 @@ func (u *URL) RTSPPathAndQuery() (string, bool) {
 
 // AddControlAttribute adds a control attribute to a RTSP url.
 func (u *URL) AddControlAttribute(controlPath string) {
-	if controlPath[0] != '?' {
+	str := u.String()
+
+	// do not add a slash if the url already ends with one
+	if controlPath[0] != '?' && !strings.HasSuffix(str, "/") {
 		controlPath = "/" + controlPath
 	}
 
 	// insert the control attribute at the end of the url
 	// if there's a query, insert it after the query
 	// otherwise insert it after the path
-	nu, _ := ParseURL(u.String() + controlPath)
+	nu, _ := ParseURL(str + controlPath)
+	*u = *nu
+}
+
+// RemoveControlAttribute removes the last control attribute from a RTSP url,
+// and returns it. It is the inverse of AddControlAttribute.
+// It returns false if the URL doesn't contain a control attribute.
+func (u *URL) RemoveControlAttribute() (string, bool) {
+	str := u.String()
+	prefixLen := len(u.Scheme + "://" + u.Host)
+	if u.User != nil {
+		prefixLen += len(u.User.String()) + 1
+	}
+
+	i := strings.LastIndex(str, "/")
+	if i < prefixLen || i == (len(str)-1) {
+		return "", false
+	}
+
+	nu, err := ParseURL(str[:i])
+	if err != nil {
+		return "", false
+	}
+
+	control := str[i+1:]
 	*u = *nu
+	return control, true
+}
+
+// Query returns the query of the URL, without the leading question mark.
+func (u *URL) Query() string {
+	return u.RawQuery
+}
+
+// SetQuery sets the query of the URL. A leading question mark is removed.
+func (u *URL) SetQuery(query string) {
+	u.RawQuery = strings.TrimPrefix(query, "?")
+	u.ForceQuery = false
+}
+
+// Credentials returns the user and the password contained in the URL.
+// It returns false if the URL doesn't contain credentials.
+func (u *URL) Credentials() (string, string, bool) {
+	if u.User == nil {
+		return "", "", false
+	}
+
+	pass, _ := u.User.Password()
+	return u.User.Username(), pass, true
+}
+
+// SetCredentials sets the user and the password of the URL.
+// If user is empty, credentials are removed.
+func (u *URL) SetCredentials(user string, pass string) {
+	if user == "" {
+		u.User = nil
+		return
+	}
+
+	u.User = url.UserPassword(user, pass)
+}
+
+// IsSecure checks whether the URL uses the RTSPS scheme.
+func (u *URL) IsSecure() bool {
+	return u.Scheme == "rtsps"
+}
+
+// DefaultPort returns the default port of the scheme of the URL,
+// that is 322 for RTSPS and 554 for RTSP.
+func (u *URL) DefaultPort() string {
+	if u.IsSecure() {
+		return "322"
+	}
+	return "554"
+}
+
+// HostWithPort returns the host of the URL, with the default port
+// if the URL doesn't contain one.
+func (u *URL) HostWithPort() string {
+	// support IPv6 addresses with and without brackets
+	if _, _, err := net.SplitHostPort(u.Host); err != nil {
+		return net.JoinHostPort(strings.Trim(u.Host, "[]"), u.DefaultPort())
+	}
+	return u.Host
+}
+
+// SameHost checks whether two URLs point to the same host and port,
+// taking into account default ports.
+func (u *URL) SameHost(other *URL) bool {
+	return strings.EqualFold(u.HostWithPort(), other.HostWithPort())
 }
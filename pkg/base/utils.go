@@ -7,8 +7,59 @@ import (
 
 const (
 	rtspMaxContentLength = 128 * 1024
+	rtspMaxHeaderSize    = 64 * 1024
 )
 
+// ReadLimits contains the limits that are enforced when reading
+// requests and responses, in order to prevent memory exhaustion.
+// All fields are optional.
+type ReadLimits struct {
+	// maximum number of header entries.
+	// It defaults to 255.
+	MaxHeaderCount int
+
+	// maximum size of the header, that is the sum of the length of
+	// all keys and values, in bytes.
+	// It defaults to 64KiB.
+	MaxHeaderSize int
+
+	// maximum size of the body, in bytes.
+	// It defaults to 128KiB.
+	MaxBodySize int
+}
+
+func (l *ReadLimits) withDefaults() ReadLimits {
+	var ret ReadLimits
+	if l != nil {
+		ret = *l
+	}
+
+	if ret.MaxHeaderCount == 0 {
+		ret.MaxHeaderCount = headerMaxEntryCount
+	}
+	if ret.MaxHeaderSize == 0 {
+		ret.MaxHeaderSize = rtspMaxHeaderSize
+	}
+	if ret.MaxBodySize == 0 {
+		ret.MaxBodySize = rtspMaxContentLength
+	}
+
+	return ret
+}
+
+// ErrLimitExceeded is returned when a request or a response exceeds
+// one of the ReadLimits.
+type ErrLimitExceeded struct {
+	What  string
+	Limit int
+	Value int
+}
+
+// Error implements the error interface.
+func (e ErrLimitExceeded) Error() string {
+	return fmt.Sprintf("%s exceeds %d (it's %d)", e.What, e.Limit, e.Value)
+}
+
 func readByteEqual(rb *bufio.Reader, cmp byte) error {
 	byt, err := rb.ReadByte()
 	if err != nil {
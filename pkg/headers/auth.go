@@ -47,6 +47,15 @@ type Auth struct {
 
 	// (optional) algorithm
 	Algorithm *string
+
+	// (optional) quality of protection
+	Qop *string
+
+	// (optional) nonce count, filled in responses with a quality of protection
+	Nc *string
+
+	// (optional) client nonce, filled in responses with a quality of protection
+	Cnonce *string
 }
 
 func findValue(v0 string) (string, string, error) {
@@ -148,6 +157,15 @@ func (h *Auth) Read(v base.HeaderValue) error {
 		case "algorithm":
 			h.Algorithm = &val
 
+		case "qop":
+			h.Qop = &val
+
+		case "nc":
+			h.Nc = &val
+
+		case "cnonce":
+			h.Cnonce = &val
+
 			// ignore non-standard keys
 		}
 
@@ -213,6 +231,23 @@ func (h Auth) Write() base.HeaderValue {
 		rets = append(rets, "algorithm=\""+*h.Algorithm+"\"")
 	}
 
+	if h.Qop != nil {
+		// in responses, qop is a token and is not quoted
+		if h.Nc != nil {
+			rets = append(rets, "qop="+*h.Qop)
+		} else {
+			rets = append(rets, "qop=\""+*h.Qop+"\"")
+		}
+	}
+
+	if h.Nc != nil {
+		rets = append(rets, "nc="+*h.Nc)
+	}
+
+	if h.Cnonce != nil {
+		rets = append(rets, "cnonce=\""+*h.Cnonce+"\"")
+	}
+
 	ret += strings.Join(rets, ", ")
 
 	return base.HeaderValue{ret}
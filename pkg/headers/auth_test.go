@@ -170,6 +170,26 @@ var casesAuth = []struct {
 			}(),
 		},
 	},
+	{
+		"digest request with qop",
+		base.HeaderValue{`Digest realm="4419b63f5e51", nonce="8b84a3b789283a8bea8da7fa7d41f08b", qop="auth"`},
+		base.HeaderValue{`Digest realm="4419b63f5e51", nonce="8b84a3b789283a8bea8da7fa7d41f08b", qop="auth"`},
+		Auth{
+			Method: AuthDigest,
+			Realm: func() *string {
+				v := "4419b63f5e51"
+				return &v
+			}(),
+			Nonce: func() *string {
+				v := "8b84a3b789283a8bea8da7fa7d41f08b"
+				return &v
+			}(),
+			Qop: func() *string {
+				v := "auth"
+				return &v
+			}(),
+		},
+	},
 }
 
 func TestAuthRead(t *testing.T) {
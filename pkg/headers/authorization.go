@@ -0,0 +1,85 @@
+package headers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/majoyz/gortsplib/pkg/base"
+)
+
+// Authorization is an Authorization header.
+type Authorization struct {
+	// authentication method
+	Method AuthMethod
+
+	// (optional) username, filled when method is Basic
+	BasicUser string
+
+	// (optional) password, filled when method is Basic
+	BasicPass string
+
+	// (optional) values, filled when method is Digest
+	DigestValues Auth
+}
+
+// Read decodes an Authorization header.
+func (h *Authorization) Read(v base.HeaderValue) error {
+	if len(v) == 0 {
+		return fmt.Errorf("value not provided")
+	}
+
+	if len(v) > 1 {
+		return fmt.Errorf("value provided multiple times (%v)", v)
+	}
+
+	v0 := v[0]
+
+	switch {
+	case strings.HasPrefix(v0, "Basic "):
+		h.Method = AuthBasic
+
+		tmp, err := base64.StdEncoding.DecodeString(v0[len("Basic "):])
+		if err != nil {
+			return fmt.Errorf("invalid value")
+		}
+
+		// the password can contain colons
+		parts := strings.SplitN(string(tmp), ":", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid value")
+		}
+
+		h.BasicUser, h.BasicPass = parts[0], parts[1]
+
+	case strings.HasPrefix(v0, "Digest "):
+		h.Method = AuthDigest
+
+		var vals Auth
+		err := vals.Read(base.HeaderValue{v0})
+		if err != nil {
+			return err
+		}
+
+		h.DigestValues = vals
+
+	default:
+		return fmt.Errorf("invalid authorization header")
+	}
+
+	return nil
+}
+
+// Write encodes an Authorization header.
+func (h Authorization) Write() base.HeaderValue {
+	switch h.Method {
+	case AuthBasic:
+		response := base64.StdEncoding.EncodeToString([]byte(h.BasicUser + ":" + h.BasicPass))
+
+		return base.HeaderValue{"Basic " + response}
+
+	default: // AuthDigest
+		h.DigestValues.Method = AuthDigest
+		return h.DigestValues.Write()
+	}
+}
@@ -0,0 +1,155 @@
+package headers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/majoyz/gortsplib/pkg/base"
+)
+
+var casesAuthorization = []struct {
+	name string
+	vin  base.HeaderValue
+	vout base.HeaderValue
+	h    Authorization
+}{
+	{
+		"basic",
+		base.HeaderValue{"Basic bXl1c2VyOm15cGFzczpwYXJ0"},
+		base.HeaderValue{"Basic bXl1c2VyOm15cGFzczpwYXJ0"},
+		Authorization{
+			Method:    AuthBasic,
+			BasicUser: "myuser",
+			BasicPass: "mypass:part",
+		},
+	},
+	{
+		"digest",
+		base.HeaderValue{`Digest username="aa", realm="bb", nonce="cc", uri="dd", response="ee"`},
+		base.HeaderValue{`Digest username="aa", realm="bb", nonce="cc", uri="dd", response="ee"`},
+		Authorization{
+			Method: AuthDigest,
+			DigestValues: Auth{
+				Method: AuthDigest,
+				Username: func() *string {
+					v := "aa"
+					return &v
+				}(),
+				Realm: func() *string {
+					v := "bb"
+					return &v
+				}(),
+				Nonce: func() *string {
+					v := "cc"
+					return &v
+				}(),
+				URI: func() *string {
+					v := "dd"
+					return &v
+				}(),
+				Response: func() *string {
+					v := "ee"
+					return &v
+				}(),
+			},
+		},
+	},
+	{
+		"digest with qop",
+		base.HeaderValue{`Digest username="aa", realm="bb", nonce="cc", uri="dd", response="ee", qop=auth, nc=00000001, cnonce="ff"`},
+		base.HeaderValue{`Digest username="aa", realm="bb", nonce="cc", uri="dd", response="ee", qop=auth, nc=00000001, cnonce="ff"`},
+		Authorization{
+			Method: AuthDigest,
+			DigestValues: Auth{
+				Method: AuthDigest,
+				Username: func() *string {
+					v := "aa"
+					return &v
+				}(),
+				Realm: func() *string {
+					v := "bb"
+					return &v
+				}(),
+				Nonce: func() *string {
+					v := "cc"
+					return &v
+				}(),
+				URI: func() *string {
+					v := "dd"
+					return &v
+				}(),
+				Response: func() *string {
+					v := "ee"
+					return &v
+				}(),
+				Qop: func() *string {
+					v := "auth"
+					return &v
+				}(),
+				Nc: func() *string {
+					v := "00000001"
+					return &v
+				}(),
+				Cnonce: func() *string {
+					v := "ff"
+					return &v
+				}(),
+			},
+		},
+	},
+}
+
+func TestAuthorizationRead(t *testing.T) {
+	for _, c := range casesAuthorization {
+		t.Run(c.name, func(t *testing.T) {
+			var h Authorization
+			err := h.Read(c.vin)
+			require.NoError(t, err)
+			require.Equal(t, c.h, h)
+		})
+	}
+}
+
+func TestAuthorizationReadErrors(t *testing.T) {
+	for _, ca := range []struct {
+		name string
+		hv   base.HeaderValue
+	}{
+		{
+			"empty",
+			base.HeaderValue{},
+		},
+		{
+			"2 values",
+			base.HeaderValue{"a", "b"},
+		},
+		{
+			"invalid method",
+			base.HeaderValue{"Custom abc"},
+		},
+		{
+			"invalid basic encoding",
+			base.HeaderValue{"Basic aaa"},
+		},
+		{
+			"basic without colon",
+			base.HeaderValue{"Basic bXl1c2Vy"},
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			var h Authorization
+			err := h.Read(ca.hv)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestAuthorizationWrite(t *testing.T) {
+	for _, c := range casesAuthorization {
+		t.Run(c.name, func(t *testing.T) {
+			vout := c.h.Write()
+			require.Equal(t, c.vout, vout)
+		})
+	}
+}
@@ -0,0 +1,361 @@
+package headers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/majoyz/gortsplib/pkg/base"
+)
+
+const (
+	rangeUTCLayout = "20060102T150405Z"
+)
+
+func readUTCTime(s string) (time.Time, error) {
+	// the fraction and the seconds are optional, and some servers omit the trailing Z
+	s = strings.TrimSuffix(s, "Z")
+
+	for _, layout := range []string{
+		"20060102T150405.999999999",
+		"20060102T1504",
+	} {
+		t, err := time.Parse(layout, s)
+		if err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid UTC time (%v)", s)
+}
+
+func leadingZero(v uint) string {
+	ret := ""
+	if v < 10 {
+		ret += "0"
+	}
+	ret += strconv.FormatUint(uint64(v), 10)
+	return ret
+}
+
+// RangeSMPTETime is a time expressed in SMPTE unit.
+type RangeSMPTETime struct {
+	Time     time.Duration
+	Frame    uint
+	Subframe uint
+}
+
+func (t *RangeSMPTETime) read(s string) error {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 && len(parts) != 4 {
+		return fmt.Errorf("invalid SMPTE time (%v)", s)
+	}
+
+	tmp, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return err
+	}
+	hours := tmp
+
+	tmp, err = strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return err
+	}
+	mins := tmp
+
+	tmp, err = strconv.ParseUint(parts[2], 10, 64)
+	if err != nil {
+		return err
+	}
+	seconds := tmp
+
+	t.Time = time.Duration(seconds+mins*60+hours*3600) * time.Second
+
+	if len(parts) == 4 {
+		parts = strings.Split(parts[3], ".")
+		if len(parts) == 2 {
+			tmp, err := strconv.ParseUint(parts[0], 10, 64)
+			if err != nil {
+				return err
+			}
+			t.Frame = uint(tmp)
+
+			tmp, err = strconv.ParseUint(parts[1], 10, 64)
+			if err != nil {
+				return err
+			}
+			t.Subframe = uint(tmp)
+		} else {
+			tmp, err := strconv.ParseUint(parts[0], 10, 64)
+			if err != nil {
+				return err
+			}
+			t.Frame = uint(tmp)
+		}
+	}
+
+	return nil
+}
+
+func (t RangeSMPTETime) write() string {
+	d := uint64(t.Time.Seconds())
+	hours := d / 3600
+	d %= 3600
+	mins := d / 60
+	secs := d % 60
+
+	ret := strconv.FormatUint(hours, 10) + ":" + leadingZero(uint(mins)) + ":" + leadingZero(uint(secs))
+
+	if t.Frame > 0 || t.Subframe > 0 {
+		ret += ":" + leadingZero(t.Frame)
+
+		if t.Subframe > 0 {
+			ret += "." + leadingZero(t.Subframe)
+		}
+	}
+
+	return ret
+}
+
+// RangeSMPTE is a range expressed in SMPTE unit.
+type RangeSMPTE struct {
+	Start RangeSMPTETime
+	End   *RangeSMPTETime
+}
+
+func (r *RangeSMPTE) read(start string, end string) error {
+	err := r.Start.read(start)
+	if err != nil {
+		return err
+	}
+
+	if end != "" {
+		var v RangeSMPTETime
+		err := v.read(end)
+		if err != nil {
+			return err
+		}
+		r.End = &v
+	}
+
+	return nil
+}
+
+func (r RangeSMPTE) write() string {
+	ret := "smpte=" + r.Start.write() + "-"
+	if r.End != nil {
+		ret += r.End.write()
+	}
+	return ret
+}
+
+func readNPTTime(s string) (time.Duration, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid NPT time (%v)", s)
+	}
+
+	var hours uint64
+	if len(parts) == 3 {
+		tmp, err := strconv.ParseUint(parts[0], 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		hours = tmp
+		parts = parts[1:]
+	}
+
+	var mins uint64
+	if len(parts) == 2 {
+		tmp, err := strconv.ParseUint(parts[0], 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		mins = tmp
+		parts = parts[1:]
+	}
+
+	seconds, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(seconds*float64(time.Second)) +
+		time.Duration(mins*60+hours*3600)*time.Second, nil
+}
+
+func writeNPTTime(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
+
+// RangeNPT is a range expressed in NPT unit.
+type RangeNPT struct {
+	Start time.Duration
+	End   *time.Duration
+}
+
+func (r *RangeNPT) read(start string, end string) error {
+	// "now" is the live position, that is mapped to zero
+	if start != "now" {
+		var err error
+		r.Start, err = readNPTTime(start)
+		if err != nil {
+			return err
+		}
+	}
+
+	if end != "" {
+		v, err := readNPTTime(end)
+		if err != nil {
+			return err
+		}
+		r.End = &v
+	}
+
+	return nil
+}
+
+func (r RangeNPT) write() string {
+	ret := "npt=" + writeNPTTime(r.Start) + "-"
+	if r.End != nil {
+		ret += writeNPTTime(*r.End)
+	}
+	return ret
+}
+
+// RangeUTC is a range expressed in UTC unit.
+type RangeUTC struct {
+	Start time.Time
+	End   *time.Time
+}
+
+func (r *RangeUTC) read(start string, end string) error {
+	var err error
+	r.Start, err = readUTCTime(start)
+	if err != nil {
+		return err
+	}
+
+	if end != "" {
+		v, err := readUTCTime(end)
+		if err != nil {
+			return err
+		}
+		r.End = &v
+	}
+
+	return nil
+}
+
+func (r RangeUTC) write() string {
+	ret := "clock=" + r.Start.UTC().Format(rangeUTCLayout) + "-"
+	if r.End != nil {
+		ret += r.End.UTC().Format(rangeUTCLayout)
+	}
+	return ret
+}
+
+// RangeValue can be
+// - RangeSMPTE
+// - RangeNPT
+// - RangeUTC
+type RangeValue interface {
+	read(string, string) error
+	write() string
+}
+
+// Range is a Range header.
+type Range struct {
+	// range expressed in some measurement units.
+	Value RangeValue
+
+	// (optional) time at which the operation is to be made effective.
+	Time *time.Time
+}
+
+// Read decodes a Range header.
+func (h *Range) Read(v base.HeaderValue) error {
+	if len(v) == 0 {
+		return fmt.Errorf("value not provided")
+	}
+
+	if len(v) > 1 {
+		return fmt.Errorf("value provided multiple times (%v)", v)
+	}
+
+	parts := strings.Split(v[0], ";")
+
+	for i, part := range parts {
+		// remove leading spaces
+		part = strings.TrimLeft(part, " ")
+
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("invalid value (%v)", v)
+		}
+
+		key, strValue := kv[0], kv[1]
+
+		if i == 0 {
+			startEnd := strings.SplitN(strValue, "-", 2)
+			if len(startEnd) != 2 {
+				return fmt.Errorf("invalid value (%v)", v)
+			}
+
+			switch key {
+			case "smpte", "smpte-30-drop", "smpte-25":
+				s := &RangeSMPTE{}
+				err := s.read(startEnd[0], startEnd[1])
+				if err != nil {
+					return err
+				}
+				h.Value = s
+
+			case "npt":
+				s := &RangeNPT{}
+				err := s.read(startEnd[0], startEnd[1])
+				if err != nil {
+					return err
+				}
+				h.Value = s
+
+			case "clock":
+				s := &RangeUTC{}
+				err := s.read(startEnd[0], startEnd[1])
+				if err != nil {
+					return err
+				}
+				h.Value = s
+
+			default:
+				return fmt.Errorf("invalid range unit (%v)", key)
+			}
+
+			continue
+		}
+
+		if key != "time" {
+			return fmt.Errorf("invalid key '%s'", key)
+		}
+
+		t, err := readUTCTime(strValue)
+		if err != nil {
+			return err
+		}
+		h.Time = &t
+	}
+
+	return nil
+}
+
+// Write encodes a Range header.
+func (h Range) Write() base.HeaderValue {
+	ret := h.Value.write()
+
+	if h.Time != nil {
+		ret += ";time=" + h.Time.UTC().Format(rangeUTCLayout)
+	}
+
+	return base.HeaderValue{ret}
+}
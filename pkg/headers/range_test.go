@@ -0,0 +1,171 @@
+package headers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/majoyz/gortsplib/pkg/base"
+)
+
+var casesRange = []struct {
+	name string
+	vin  base.HeaderValue
+	vout base.HeaderValue
+	h    Range
+}{
+	{
+		"smpte",
+		base.HeaderValue{`smpte=10:07:00-10:07:33:05.01`},
+		base.HeaderValue{`smpte=10:07:00-10:07:33:05.01`},
+		Range{
+			Value: &RangeSMPTE{
+				Start: RangeSMPTETime{
+					Time: time.Duration(7*60+10*3600) * time.Second,
+				},
+				End: &RangeSMPTETime{
+					Time:     time.Duration(33+7*60+10*3600) * time.Second,
+					Frame:    5,
+					Subframe: 1,
+				},
+			},
+		},
+	},
+	{
+		"smpte open ended",
+		base.HeaderValue{`smpte=0:10:00-`},
+		base.HeaderValue{`smpte=0:10:00-`},
+		Range{
+			Value: &RangeSMPTE{
+				Start: RangeSMPTETime{
+					Time: time.Duration(10*60) * time.Second,
+				},
+			},
+		},
+	},
+	{
+		"npt",
+		base.HeaderValue{`npt=123.45-125`},
+		base.HeaderValue{`npt=123.45-125`},
+		Range{
+			Value: &RangeNPT{
+				Start: 123450 * time.Millisecond,
+				End: func() *time.Duration {
+					v := 125 * time.Second
+					return &v
+				}(),
+			},
+		},
+	},
+	{
+		"npt open ended",
+		base.HeaderValue{`npt=12:05:35.3-`},
+		base.HeaderValue{`npt=43535.3-`},
+		Range{
+			Value: &RangeNPT{
+				Start: 43535300 * time.Millisecond,
+			},
+		},
+	},
+	{
+		"npt now",
+		base.HeaderValue{`npt=now-`},
+		base.HeaderValue{`npt=0-`},
+		Range{
+			Value: &RangeNPT{},
+		},
+	},
+	{
+		"clock",
+		base.HeaderValue{`clock=19961108T142300Z-19961108T143520Z`},
+		base.HeaderValue{`clock=19961108T142300Z-19961108T143520Z`},
+		Range{
+			Value: &RangeUTC{
+				Start: time.Date(1996, 11, 8, 14, 23, 0, 0, time.UTC),
+				End: func() *time.Time {
+					v := time.Date(1996, 11, 8, 14, 35, 20, 0, time.UTC)
+					return &v
+				}(),
+			},
+		},
+	},
+	{
+		"with time",
+		base.HeaderValue{`clock=19961110T1925-19961110T2015; time=19970123T143720Z`},
+		base.HeaderValue{`clock=19961110T192500Z-19961110T201500Z;time=19970123T143720Z`},
+		Range{
+			Value: &RangeUTC{
+				Start: time.Date(1996, 11, 10, 19, 25, 0, 0, time.UTC),
+				End: func() *time.Time {
+					v := time.Date(1996, 11, 10, 20, 15, 0, 0, time.UTC)
+					return &v
+				}(),
+			},
+			Time: func() *time.Time {
+				v := time.Date(1997, 1, 23, 14, 37, 20, 0, time.UTC)
+				return &v
+			}(),
+		},
+	},
+}
+
+func TestRangeRead(t *testing.T) {
+	for _, c := range casesRange {
+		t.Run(c.name, func(t *testing.T) {
+			var h Range
+			err := h.Read(c.vin)
+			require.NoError(t, err)
+			require.Equal(t, c.h, h)
+		})
+	}
+}
+
+func TestRangeWrite(t *testing.T) {
+	for _, c := range casesRange {
+		t.Run(c.name, func(t *testing.T) {
+			req := c.h.Write()
+			require.Equal(t, c.vout, req)
+		})
+	}
+}
+
+func TestRangeReadErrors(t *testing.T) {
+	for _, ca := range []struct {
+		name string
+		hv   base.HeaderValue
+		err  string
+	}{
+		{
+			"empty",
+			base.HeaderValue{},
+			"value not provided",
+		},
+		{
+			"2 values",
+			base.HeaderValue{"a", "b"},
+			"value provided multiple times ([a b])",
+		},
+		{
+			"invalid unit",
+			base.HeaderValue{`bytes=0-`},
+			"invalid range unit (bytes)",
+		},
+		{
+			"missing separator",
+			base.HeaderValue{`npt=123`},
+			"invalid value ([npt=123])",
+		},
+		{
+			"invalid key",
+			base.HeaderValue{`npt=0-;a=b`},
+			"invalid key 'a'",
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			var h Range
+			err := h.Read(ca.hv)
+			require.EqualError(t, err, ca.err)
+		})
+	}
+}
@@ -28,24 +28,29 @@ func (h *Session) Read(v base.HeaderValue) error {
 	}
 
 	parts := strings.Split(v[0], ";")
-	if len(parts) == 0 {
+
+	h.Session = strings.TrimSpace(parts[0])
+	if h.Session == "" {
 		return fmt.Errorf("invalid value (%v)", v)
 	}
 
-	h.Session = parts[0]
-
 	for _, part := range parts[1:] {
-		// remove leading spaces
-		part = strings.TrimLeft(part, " ")
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
 
-		kv := strings.Split(part, "=")
+		kv := strings.SplitN(part, "=", 2)
 		if len(kv) != 2 {
-			return fmt.Errorf("invalid value")
+			return fmt.Errorf("invalid value (%v)", v)
 		}
 
-		key, strValue := kv[0], kv[1]
+		key, strValue := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+
+		// unknown parameters are ignored, since some servers
+		// append vendor-specific ones.
 		if key != "timeout" {
-			return fmt.Errorf("invalid key '%s'", key)
+			continue
 		}
 
 		iv, err := strconv.ParseUint(strValue, 10, 64)
@@ -46,6 +46,18 @@ var casesSession = []struct {
 			}(),
 		},
 	},
+	{
+		"with unknown parameter",
+		base.HeaderValue{`A3eqwsafq3rFASqew;timeout=47;foo=bar`},
+		base.HeaderValue{`A3eqwsafq3rFASqew;timeout=47`},
+		Session{
+			Session: "A3eqwsafq3rFASqew",
+			Timeout: func() *uint {
+				v := uint(47)
+				return &v
+			}(),
+		},
+	},
 }
 
 func TestSessionRead(t *testing.T) {
@@ -59,6 +71,40 @@ func TestSessionRead(t *testing.T) {
 	}
 }
 
+func TestSessionReadErrors(t *testing.T) {
+	for _, ca := range []struct {
+		name string
+		hv   base.HeaderValue
+	}{
+		{
+			"empty",
+			base.HeaderValue{},
+		},
+		{
+			"2 values",
+			base.HeaderValue{"a", "b"},
+		},
+		{
+			"empty id",
+			base.HeaderValue{";timeout=60"},
+		},
+		{
+			"invalid parameter",
+			base.HeaderValue{"A3eqwsafq3rFASqew;timeout"},
+		},
+		{
+			"invalid timeout",
+			base.HeaderValue{"A3eqwsafq3rFASqew;timeout=aaa"},
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			var h Session
+			err := h.Read(ca.hv)
+			require.Error(t, err)
+		})
+	}
+}
+
 func TestSessionWrite(t *testing.T) {
 	for _, c := range casesSession {
 		t.Run(c.name, func(t *testing.T) {
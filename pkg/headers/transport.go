@@ -31,17 +31,64 @@ func (tm TransportMode) String() string {
 	return "unknown"
 }
 
+// TransportModeFormat is the format used to write a transport mode.
+type TransportModeFormat int
+
+const (
+	// TransportModeFormatLowercase writes the mode in lowercase (mode=play)
+	TransportModeFormatLowercase TransportModeFormat = iota
+
+	// TransportModeFormatUppercase writes the mode in uppercase (mode=PLAY)
+	TransportModeFormatUppercase
+
+	// TransportModeFormatUppercaseQuoted writes the mode in uppercase,
+	// enclosed in quotes (mode="PLAY"), as in the examples of RFC2326
+	TransportModeFormatUppercaseQuoted
+)
+
+// TransportProfile is a transport profile.
+type TransportProfile int
+
+const (
+	// TransportProfileAVP is the "RTP/AVP" transport profile
+	TransportProfileAVP TransportProfile = iota
+
+	// TransportProfileSAVP is the "RTP/SAVP" transport profile, that is used
+	// to exchange SRTP packets
+	TransportProfileSAVP
+)
+
+// String implements fmt.Stringer.
+func (tp TransportProfile) String() string {
+	switch tp {
+	case TransportProfileAVP:
+		return "RTP/AVP"
+
+	case TransportProfileSAVP:
+		return "RTP/SAVP"
+	}
+	return "unknown"
+}
+
 // Transport is a Transport header.
 type Transport struct {
 	// protocol of the stream
 	Protocol base.StreamProtocol
 
+	// profile of the stream
+	Profile TransportProfile
+
 	// (optional) delivery method of the stream
 	Delivery *base.StreamDelivery
 
-	// (optional) destination
+	// (optional) destination address.
+	// IPv6 addresses are not enclosed in brackets.
 	Destination *string
 
+	// (optional) source address.
+	// IPv6 addresses are not enclosed in brackets.
+	Source *string
+
 	// (optional) TTL
 	TTL *uint
 
@@ -57,8 +104,15 @@ type Transport struct {
 	// (optional) interleaved frame ids
 	InterleavedIDs *[2]int
 
+	// (optional) SSRC of the packets of the stream
+	SSRC *uint32
+
 	// (optional) mode
 	Mode *TransportMode
+
+	// format used to write the mode.
+	// It is not filled when reading.
+	ModeFormat TransportModeFormat
 }
 
 func parsePorts(val string) (*[2]int, error) {
@@ -99,7 +153,11 @@ func (h *Transport) Read(v base.HeaderValue) error {
 		return fmt.Errorf("value provided multiple times (%v)", v)
 	}
 
-	parts := strings.Split(v[0], ";")
+	return h.read(v[0])
+}
+
+func (h *Transport) read(v string) error {
+	parts := strings.Split(v, ";")
 	if len(parts) == 0 {
 		return fmt.Errorf("invalid value (%v)", v)
 	}
@@ -111,11 +169,23 @@ func (h *Transport) Read(v base.HeaderValue) error {
 	case "RTP/AVP/TCP":
 		h.Protocol = base.StreamProtocolTCP
 
+	case "RTP/SAVP", "RTP/SAVP/UDP":
+		h.Protocol = base.StreamProtocolUDP
+		h.Profile = TransportProfileSAVP
+
+	case "RTP/SAVP/TCP":
+		h.Protocol = base.StreamProtocolTCP
+		h.Profile = TransportProfileSAVP
+
 	default:
 		return fmt.Errorf("invalid protocol (%v)", v)
 	}
 	parts = parts[1:]
 
+	if len(parts) == 0 {
+		return nil
+	}
+
 	switch parts[0] {
 	case "unicast":
 		v := base.StreamDeliveryUnicast
@@ -133,9 +203,13 @@ func (h *Transport) Read(v base.HeaderValue) error {
 	for _, t := range parts {
 		switch {
 		case strings.HasPrefix(t, "destination="):
-			v := t[len("destination="):]
+			v := readTransportAddress(t[len("destination="):])
 			h.Destination = &v
 
+		case strings.HasPrefix(t, "source="):
+			v := readTransportAddress(t[len("source="):])
+			h.Source = &v
+
 		case strings.HasPrefix(t, "ttl="):
 			v, err := strconv.ParseUint(t[len("ttl="):], 10, 64)
 			if err != nil {
@@ -172,8 +246,17 @@ func (h *Transport) Read(v base.HeaderValue) error {
 			}
 			h.InterleavedIDs = ports
 
+		case strings.HasPrefix(t, "ssrc="):
+			// the SSRC is expressed in hexadecimal format.
+			// some servers send invalid values, that are ignored.
+			v, err := strconv.ParseUint(strings.TrimSpace(t[len("ssrc="):]), 16, 32)
+			if err == nil {
+				vu := uint32(v)
+				h.SSRC = &vu
+			}
+
 		case strings.HasPrefix(t, "mode="):
-			str := strings.ToLower(t[len("mode="):])
+			str := strings.ToLower(strings.TrimSpace(t[len("mode="):]))
 			str = strings.TrimPrefix(str, "\"")
 			str = strings.TrimSuffix(str, "\"")
 
@@ -199,14 +282,26 @@ func (h *Transport) Read(v base.HeaderValue) error {
 	return nil
 }
 
+// readTransportAddress removes the brackets that may enclose IPv6 addresses.
+func readTransportAddress(v string) string {
+	if strings.HasPrefix(v, "[") && strings.HasSuffix(v, "]") {
+		return v[1 : len(v)-1]
+	}
+	return v
+}
+
 // Write encodes a Transport header
 func (h Transport) Write() base.HeaderValue {
+	return base.HeaderValue{h.write()}
+}
+
+func (h Transport) write() string {
 	var rets []string
 
 	if h.Protocol == base.StreamProtocolUDP {
-		rets = append(rets, "RTP/AVP")
+		rets = append(rets, h.Profile.String())
 	} else {
-		rets = append(rets, "RTP/AVP/TCP")
+		rets = append(rets, h.Profile.String()+"/TCP")
 	}
 
 	if h.Delivery != nil {
@@ -217,6 +312,23 @@ func (h Transport) Write() base.HeaderValue {
 		}
 	}
 
+	if h.Destination != nil {
+		rets = append(rets, "destination="+*h.Destination)
+	}
+
+	if h.Source != nil {
+		rets = append(rets, "source="+*h.Source)
+	}
+
+	if h.TTL != nil {
+		rets = append(rets, "ttl="+strconv.FormatUint(uint64(*h.TTL), 10))
+	}
+
+	if h.Ports != nil {
+		ports := *h.Ports
+		rets = append(rets, "port="+strconv.FormatInt(int64(ports[0]), 10)+"-"+strconv.FormatInt(int64(ports[1]), 10))
+	}
+
 	if h.ClientPorts != nil {
 		ports := *h.ClientPorts
 		rets = append(rets, "client_port="+strconv.FormatInt(int64(ports[0]), 10)+"-"+strconv.FormatInt(int64(ports[1]), 10))
@@ -232,13 +344,65 @@ func (h Transport) Write() base.HeaderValue {
 		rets = append(rets, "interleaved="+strconv.FormatInt(int64(ports[0]), 10)+"-"+strconv.FormatInt(int64(ports[1]), 10))
 	}
 
+	if h.SSRC != nil {
+		rets = append(rets, "ssrc="+fmt.Sprintf("%08X", *h.SSRC))
+	}
+
 	if h.Mode != nil {
+		mode := "record"
 		if *h.Mode == TransportModePlay {
-			rets = append(rets, "mode=play")
-		} else {
-			rets = append(rets, "mode=record")
+			mode = "play"
 		}
+
+		switch h.ModeFormat {
+		case TransportModeFormatUppercase:
+			mode = strings.ToUpper(mode)
+
+		case TransportModeFormatUppercaseQuoted:
+			mode = "\"" + strings.ToUpper(mode) + "\""
+		}
+
+		rets = append(rets, "mode="+mode)
+	}
+
+	return strings.Join(rets, ";")
+}
+
+// Transports is a Transport header that contains multiple alternative
+// transports, in order of preference.
+type Transports []Transport
+
+// Read decodes a Transport header that contains one or more transports.
+func (h *Transports) Read(v base.HeaderValue) error {
+	if len(v) == 0 {
+		return fmt.Errorf("value not provided")
+	}
+
+	if len(v) > 1 {
+		return fmt.Errorf("value provided multiple times (%v)", v)
+	}
+
+	*h = nil
+
+	for _, tmp := range strings.Split(v[0], ",") {
+		var t Transport
+		err := t.read(strings.TrimLeft(tmp, " "))
+		if err != nil {
+			return err
+		}
+		*h = append(*h, t)
+	}
+
+	return nil
+}
+
+// Write encodes a Transport header that contains one or more transports.
+func (h Transports) Write() base.HeaderValue {
+	rets := make([]string, len(h))
+
+	for i, t := range h {
+		rets[i] = t.write()
 	}
 
-	return base.HeaderValue{strings.Join(rets, ";")}
+	return base.HeaderValue{strings.Join(rets, ",")}
 }
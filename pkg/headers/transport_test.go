@@ -48,7 +48,7 @@ var casesTransport = []struct {
 	{
 		"udp multicast play request / response",
 		base.HeaderValue{`RTP/AVP;multicast;destination=225.219.201.15;port=7000-7001;ttl=127`},
-		base.HeaderValue{`RTP/AVP;multicast`},
+		base.HeaderValue{`RTP/AVP;multicast;destination=225.219.201.15;ttl=127;port=7000-7001`},
 		Transport{
 			Protocol: base.StreamProtocolUDP,
 			Delivery: func() *base.StreamDelivery {
@@ -75,10 +75,38 @@ var casesTransport = []struct {
 			InterleavedIDs: &[2]int{0, 1},
 		},
 	},
+	{
+		"srtp udp unicast play request",
+		base.HeaderValue{`RTP/SAVP;unicast;client_port=3456-3457;mode=play`},
+		base.HeaderValue{`RTP/SAVP;unicast;client_port=3456-3457;mode=play`},
+		Transport{
+			Protocol: base.StreamProtocolUDP,
+			Profile:  TransportProfileSAVP,
+			Delivery: func() *base.StreamDelivery {
+				v := base.StreamDeliveryUnicast
+				return &v
+			}(),
+			ClientPorts: &[2]int{3456, 3457},
+			Mode: func() *TransportMode {
+				v := TransportModePlay
+				return &v
+			}(),
+		},
+	},
+	{
+		"srtp tcp play request / response",
+		base.HeaderValue{`RTP/SAVP/TCP;interleaved=0-1`},
+		base.HeaderValue{`RTP/SAVP/TCP;interleaved=0-1`},
+		Transport{
+			Protocol:       base.StreamProtocolTCP,
+			Profile:        TransportProfileSAVP,
+			InterleavedIDs: &[2]int{0, 1},
+		},
+	},
 	{
 		"udp unicast play response with a single port",
 		base.HeaderValue{`RTP/AVP/UDP;unicast;server_port=8052;client_port=14186;ssrc=39140788;mode=PLAY`},
-		base.HeaderValue{`RTP/AVP;unicast;client_port=14186-14187;server_port=8052-8053;mode=play`},
+		base.HeaderValue{`RTP/AVP;unicast;client_port=14186-14187;server_port=8052-8053;ssrc=39140788;mode=play`},
 		Transport{
 			Protocol: base.StreamProtocolUDP,
 			Delivery: func() *base.StreamDelivery {
@@ -91,18 +119,26 @@ var casesTransport = []struct {
 			}(),
 			ClientPorts: &[2]int{14186, 14187},
 			ServerPorts: &[2]int{8052, 8053},
+			SSRC: func() *uint32 {
+				v := uint32(0x39140788)
+				return &v
+			}(),
 		},
 	},
 	{
 		"udp record response with receive",
 		base.HeaderValue{`RTP/AVP/UDP;unicast;mode=receive;source=localhost;client_port=14186-14187;server_port=5000-5001`},
-		base.HeaderValue{`RTP/AVP;unicast;client_port=14186-14187;server_port=5000-5001;mode=record`},
+		base.HeaderValue{`RTP/AVP;unicast;source=localhost;client_port=14186-14187;server_port=5000-5001;mode=record`},
 		Transport{
 			Protocol: base.StreamProtocolUDP,
 			Delivery: func() *base.StreamDelivery {
 				v := base.StreamDeliveryUnicast
 				return &v
 			}(),
+			Source: func() *string {
+				v := "localhost"
+				return &v
+			}(),
 			Mode: func() *TransportMode {
 				v := TransportModeRecord
 				return &v
@@ -111,6 +147,62 @@ var casesTransport = []struct {
 			ServerPorts: &[2]int{5000, 5001},
 		},
 	},
+	{
+		"udp multicast ipv6",
+		base.HeaderValue{`RTP/AVP;multicast;destination=[ff15::1];port=7000-7001`},
+		base.HeaderValue{`RTP/AVP;multicast;destination=ff15::1;port=7000-7001`},
+		Transport{
+			Protocol: base.StreamProtocolUDP,
+			Delivery: func() *base.StreamDelivery {
+				v := base.StreamDeliveryMulticast
+				return &v
+			}(),
+			Destination: func() *string {
+				v := "ff15::1"
+				return &v
+			}(),
+			Ports: &[2]int{7000, 7001},
+		},
+	},
+	{
+		"udp unicast ipv6 response with source",
+		base.HeaderValue{`RTP/AVP;unicast;source=fe80::1;client_port=3056-3057;server_port=5000-5001`},
+		base.HeaderValue{`RTP/AVP;unicast;source=fe80::1;client_port=3056-3057;server_port=5000-5001`},
+		Transport{
+			Protocol: base.StreamProtocolUDP,
+			Delivery: func() *base.StreamDelivery {
+				v := base.StreamDeliveryUnicast
+				return &v
+			}(),
+			Source: func() *string {
+				v := "fe80::1"
+				return &v
+			}(),
+			ClientPorts: &[2]int{3056, 3057},
+			ServerPorts: &[2]int{5000, 5001},
+		},
+	},
+}
+
+func TestTransportReadModeVariants(t *testing.T) {
+	for _, ca := range []struct {
+		v    string
+		mode TransportMode
+	}{
+		{`play`, TransportModePlay},
+		{`PLAY`, TransportModePlay},
+		{`"PLAY"`, TransportModePlay},
+		{`"Record"`, TransportModeRecord},
+		{`receive`, TransportModeRecord},
+		{` "RECEIVE"`, TransportModeRecord},
+	} {
+		t.Run(ca.v, func(t *testing.T) {
+			var h Transport
+			err := h.Read(base.HeaderValue{`RTP/AVP/TCP;unicast;interleaved=0-1;mode=` + ca.v})
+			require.NoError(t, err)
+			require.Equal(t, ca.mode, *h.Mode)
+		})
+	}
 }
 
 func TestTransportRead(t *testing.T) {
@@ -132,3 +224,92 @@ func TestTransportWrite(t *testing.T) {
 		})
 	}
 }
+
+func TestTransportWriteModeFormat(t *testing.T) {
+	for _, ca := range []struct {
+		name   string
+		format TransportModeFormat
+		v      string
+	}{
+		{"lowercase", TransportModeFormatLowercase, `RTP/AVP/TCP;interleaved=0-1;mode=record`},
+		{"uppercase", TransportModeFormatUppercase, `RTP/AVP/TCP;interleaved=0-1;mode=RECORD`},
+		{"uppercase quoted", TransportModeFormatUppercaseQuoted, `RTP/AVP/TCP;interleaved=0-1;mode="RECORD"`},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			mode := TransportModeRecord
+			h := Transport{
+				Protocol:       base.StreamProtocolTCP,
+				InterleavedIDs: &[2]int{0, 1},
+				Mode:           &mode,
+				ModeFormat:     ca.format,
+			}
+			require.Equal(t, base.HeaderValue{ca.v}, h.Write())
+		})
+	}
+}
+
+var casesTransports = []struct {
+	name string
+	vin  base.HeaderValue
+	vout base.HeaderValue
+	h    Transports
+}{
+	{
+		"single",
+		base.HeaderValue{`RTP/AVP/TCP;unicast;interleaved=0-1`},
+		base.HeaderValue{`RTP/AVP/TCP;unicast;interleaved=0-1`},
+		Transports{
+			{
+				Protocol: base.StreamProtocolTCP,
+				Delivery: func() *base.StreamDelivery {
+					v := base.StreamDeliveryUnicast
+					return &v
+				}(),
+				InterleavedIDs: &[2]int{0, 1},
+			},
+		},
+	},
+	{
+		"udp and tcp",
+		base.HeaderValue{`RTP/AVP;unicast;client_port=3456-3457, RTP/AVP/TCP;unicast;interleaved=0-1`},
+		base.HeaderValue{`RTP/AVP;unicast;client_port=3456-3457,RTP/AVP/TCP;unicast;interleaved=0-1`},
+		Transports{
+			{
+				Protocol: base.StreamProtocolUDP,
+				Delivery: func() *base.StreamDelivery {
+					v := base.StreamDeliveryUnicast
+					return &v
+				}(),
+				ClientPorts: &[2]int{3456, 3457},
+			},
+			{
+				Protocol: base.StreamProtocolTCP,
+				Delivery: func() *base.StreamDelivery {
+					v := base.StreamDeliveryUnicast
+					return &v
+				}(),
+				InterleavedIDs: &[2]int{0, 1},
+			},
+		},
+	},
+}
+
+func TestTransportsRead(t *testing.T) {
+	for _, c := range casesTransports {
+		t.Run(c.name, func(t *testing.T) {
+			var h Transports
+			err := h.Read(c.vin)
+			require.NoError(t, err)
+			require.Equal(t, c.h, h)
+		})
+	}
+}
+
+func TestTransportsWrite(t *testing.T) {
+	for _, c := range casesTransports {
+		t.Run(c.name, func(t *testing.T) {
+			req := c.h.Write()
+			require.Equal(t, c.vout, req)
+		})
+	}
+}
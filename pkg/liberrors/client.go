@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/majoyz/gortsplib/pkg/base"
+	"github.com/majoyz/gortsplib/pkg/headers"
 )
 
 // ErrClientWrongState is returned in case of a wrong client state.
@@ -28,6 +29,11 @@ func (e ErrClientSessionHeaderInvalid) Error() string {
 	return fmt.Sprintf("invalid session header: %v", e.Err)
 }
 
+// Unwrap returns the underlying error.
+func (e ErrClientSessionHeaderInvalid) Unwrap() error {
+	return e.Err
+}
+
 // ErrClientWrongStatusCode is returned in case of a wrong status code.
 type ErrClientWrongStatusCode struct {
 	Code    base.StatusCode
@@ -57,6 +63,23 @@ func (e ErrClientContentTypeUnsupported) Error() string {
 	return fmt.Sprintf("unsupported Content-Type header '%v'", e.CT)
 }
 
+// ErrClientContentBaseInvalid is returned in case the Content-Base or the
+// Content-Location header of a DESCRIBE response is invalid.
+type ErrClientContentBaseInvalid struct {
+	Header string
+	Err    error
+}
+
+// Error implements the error interface.
+func (e ErrClientContentBaseInvalid) Error() string {
+	return fmt.Sprintf("invalid %s header: %v", e.Header, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e ErrClientContentBaseInvalid) Unwrap() error {
+	return e.Err
+}
+
 // ErrClientCannotReadPublishAtSameTime is returned when the client is trying to read and publish at the same time.
 type ErrClientCannotReadPublishAtSameTime struct{}
 
@@ -115,6 +138,11 @@ func (e ErrClientTransportHeaderInvalid) Error() string {
 	return fmt.Sprintf("invalid transport header: %v", e.Err)
 }
 
+// Unwrap returns the underlying error.
+func (e ErrClientTransportHeaderInvalid) Unwrap() error {
+	return e.Err
+}
+
 // ErrClientTransportHeaderNoInterleavedIDs is returned in case the transport header doesn't contain interleaved IDs.
 type ErrClientTransportHeaderNoInterleavedIDs struct{}
 
@@ -142,6 +170,66 @@ func (e ErrClientNoUDPPacketsRecently) Error() string {
 	return "no UDP packets received recently (maybe there's a firewall/NAT in between)"
 }
 
+// ErrClientNoRTPPacketsRecently is returned when no RTP packets have been received
+// within NoRTPTimeout.
+type ErrClientNoRTPPacketsRecently struct{}
+
+// Error implements the error interface.
+func (e ErrClientNoRTPPacketsRecently) Error() string {
+	return "no RTP packets received recently"
+}
+
+// ErrClientEndOfStream is returned when the server has sent a RTCP BYE packet
+// for every track that is being read.
+type ErrClientEndOfStream struct{}
+
+// Error implements the error interface.
+func (e ErrClientEndOfStream) Error() string {
+	return "end of stream"
+}
+
+// ErrClientMethodNotSupported is returned when the server didn't advertise a method
+// in the OPTIONS response.
+type ErrClientMethodNotSupported struct {
+	Method base.Method
+}
+
+// Error implements the error interface.
+func (e ErrClientMethodNotSupported) Error() string {
+	return fmt.Sprintf("server doesn't support the %s method", e.Method)
+}
+
+// ErrClientTrackNotSetupWithUDP is returned when a track is not set up with UDP.
+type ErrClientTrackNotSetupWithUDP struct {
+	TrackID int
+}
+
+// Error implements the error interface.
+func (e ErrClientTrackNotSetupWithUDP) Error() string {
+	return fmt.Sprintf("track %d is not set up with UDP", e.TrackID)
+}
+
+// ErrClientRedirected is returned when the server sends a REDIRECT request.
+type ErrClientRedirected struct {
+	Location *base.URL
+}
+
+// Error implements the error interface.
+func (e ErrClientRedirected) Error() string {
+	return fmt.Sprintf("redirected to %s", e.Location)
+}
+
+// ErrClientTooManyRedirects is returned when the maximum number of redirects
+// has been reached during Describe().
+type ErrClientTooManyRedirects struct {
+	Count int
+}
+
+// Error implements the error interface.
+func (e ErrClientTooManyRedirects) Error() string {
+	return fmt.Sprintf("too many redirects (%d)", e.Count)
+}
+
 // ErrClientRTPInfoInvalid is returned in case of an invalid RTP-Info.
 type ErrClientRTPInfoInvalid struct {
 	Err error
@@ -151,3 +239,142 @@ type ErrClientRTPInfoInvalid struct {
 func (e ErrClientRTPInfoInvalid) Error() string {
 	return fmt.Sprintf("invalid RTP-Info: %v", e.Err)
 }
+
+// Unwrap returns the underlying error.
+func (e ErrClientRTPInfoInvalid) Unwrap() error {
+	return e.Err
+}
+
+// ErrClientTransportHeaderWrongProfile is returned in case the transport header contains a wrong profile.
+type ErrClientTransportHeaderWrongProfile struct {
+	Expected headers.TransportProfile
+	Value    headers.TransportProfile
+}
+
+// Error implements the error interface.
+func (e ErrClientTransportHeaderWrongProfile) Error() string {
+	return fmt.Sprintf("wrong transport profile, expected %v, got %v", e.Expected, e.Value)
+}
+
+// ErrClientUDPSourceUnexpected is returned when a UDP packet is received from an unexpected address.
+type ErrClientUDPSourceUnexpected struct {
+	TrackID int
+	Address string
+}
+
+// Error implements the error interface.
+func (e ErrClientUDPSourceUnexpected) Error() string {
+	return fmt.Sprintf("received a UDP packet of track %d from an unexpected address (%s)", e.TrackID, e.Address)
+}
+
+// ErrClientRTPSSRCUnexpected is returned when a RTP packet with an unexpected SSRC is received.
+type ErrClientRTPSSRCUnexpected struct {
+	TrackID  int
+	Expected uint32
+	Value    uint32
+}
+
+// Error implements the error interface.
+func (e ErrClientRTPSSRCUnexpected) Error() string {
+	return fmt.Sprintf("received a RTP packet of track %d with an unexpected SSRC, expected %08X, got %08X",
+		e.TrackID, e.Expected, e.Value)
+}
+
+// ErrClientSRTPDecryptFailed is returned when a SRTP or SRTCP packet can't be
+// decrypted, since it fails authentication or has already been received.
+type ErrClientSRTPDecryptFailed struct {
+	TrackID int
+	Err     error
+}
+
+// Error implements the error interface.
+func (e ErrClientSRTPDecryptFailed) Error() string {
+	return fmt.Sprintf("unable to decrypt a packet of track %d: %s", e.TrackID, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e ErrClientSRTPDecryptFailed) Unwrap() error {
+	return e.Err
+}
+
+// ErrClientUnsupportedScheme is returned in case the URL scheme is not supported.
+type ErrClientUnsupportedScheme struct {
+	Scheme string
+}
+
+// Error implements the error interface.
+func (e ErrClientUnsupportedScheme) Error() string {
+	return fmt.Sprintf("unsupported scheme '%s'", e.Scheme)
+}
+
+// ErrClientHostNotResolved is returned when the resolution of a host name
+// doesn't return any address.
+type ErrClientHostNotResolved struct {
+	Host string
+}
+
+// Error implements the error interface.
+func (e ErrClientHostNotResolved) Error() string {
+	return fmt.Sprintf("no addresses found for host '%s'", e.Host)
+}
+
+// ErrClientRTSPSAndUDP is returned in case RTSPS is used together with UDP.
+type ErrClientRTSPSAndUDP struct{}
+
+// Error implements the error interface.
+func (e ErrClientRTSPSAndUDP) Error() string {
+	return "RTSPS can't be used with UDP"
+}
+
+// ErrClientConnProvided is returned when trying to open again a connection
+// that has been provided by the user.
+type ErrClientConnProvided struct{}
+
+// Error implements the error interface.
+func (e ErrClientConnProvided) Error() string {
+	return "the connection has been provided by the user and can't be opened again"
+}
+
+// ErrClientAuthSetup is returned in case the authentication can't be setup.
+type ErrClientAuthSetup struct {
+	Err error
+}
+
+// Error implements the error interface.
+func (e ErrClientAuthSetup) Error() string {
+	return fmt.Sprintf("unable to setup authentication: %s", e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e ErrClientAuthSetup) Unwrap() error {
+	return e.Err
+}
+
+// ErrClientNotRunning is returned when writing frames before publishing started.
+type ErrClientNotRunning struct{}
+
+// Error implements the error interface.
+func (e ErrClientNotRunning) Error() string {
+	return "not running"
+}
+
+// ErrClientTLSFingerprintMismatch is returned when the fingerprint of the
+// server certificate doesn't match ClientConf.TLSFingerprint.
+type ErrClientTLSFingerprintMismatch struct {
+	Expected string
+	Value    string
+}
+
+// Error implements the error interface.
+func (e ErrClientTLSFingerprintMismatch) Error() string {
+	return fmt.Sprintf("server fingerprint does not match: expected %s, got %s",
+		e.Expected, e.Value)
+}
+
+// ErrClientTerminated is returned when the connection has been closed by the user.
+type ErrClientTerminated struct{}
+
+// Error implements the error interface.
+func (e ErrClientTerminated) Error() string {
+	return "terminated"
+}
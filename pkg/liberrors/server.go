@@ -71,6 +71,11 @@ func (e ErrServerSDPInvalid) Error() string {
 	return fmt.Sprintf("invalid SDP: %v", e.Err)
 }
 
+// Unwrap returns the underlying error.
+func (e ErrServerSDPInvalid) Unwrap() error {
+	return e.Err
+}
+
 // ErrServerSDPNoTracksDefined is returned in case the SDP has no tracks defined.
 type ErrServerSDPNoTracksDefined struct{}
 
@@ -89,6 +94,41 @@ func (e ErrServerTransportHeaderInvalid) Error() string {
 	return fmt.Sprintf("invalid transport header: %v", e.Err)
 }
 
+// Unwrap returns the underlying error.
+func (e ErrServerTransportHeaderInvalid) Unwrap() error {
+	return e.Err
+}
+
+// ErrServerSessionHeaderInvalid is returned in case the session header is invalid.
+type ErrServerSessionHeaderInvalid struct {
+	Err error
+}
+
+// Error implements the error interface.
+func (e ErrServerSessionHeaderInvalid) Error() string {
+	return fmt.Sprintf("invalid session header: %v", e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e ErrServerSessionHeaderInvalid) Unwrap() error {
+	return e.Err
+}
+
+// ErrServerRangeHeaderInvalid is returned in case the range header is invalid.
+type ErrServerRangeHeaderInvalid struct {
+	Err error
+}
+
+// Error implements the error interface.
+func (e ErrServerRangeHeaderInvalid) Error() string {
+	return fmt.Sprintf("invalid range header: %v", e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e ErrServerRangeHeaderInvalid) Unwrap() error {
+	return e.Err
+}
+
 // ErrServerTrackAlreadySetup is returned in case a track has already been setup.
 type ErrServerTrackAlreadySetup struct {
 	TrackID int
@@ -126,6 +166,8 @@ func (e ErrServerTransportHeaderNoInterleavedIDs) Error() string {
 }
 
 // ErrServerTransportHeaderWrongInterleavedIDs is returned in case the transport header contains wrong interleaved IDs.
+// Deprecated: the server accepts any interleaved IDs and returns
+// ErrServerTransportHeaderInvalidInterleavedIDs when they can't be used.
 type ErrServerTransportHeaderWrongInterleavedIDs struct {
 	Expected [2]int
 	Value    [2]int
@@ -136,6 +178,17 @@ func (e ErrServerTransportHeaderWrongInterleavedIDs) Error() string {
 	return fmt.Sprintf("wrong interleaved IDs, expected %v, got %v", e.Expected, e.Value)
 }
 
+// ErrServerTransportHeaderInvalidInterleavedIDs is returned in case the transport header
+// contains interleaved IDs that are out of range, equal, or already used by another track.
+type ErrServerTransportHeaderInvalidInterleavedIDs struct {
+	Value [2]int
+}
+
+// Error implements the error interface.
+func (e ErrServerTransportHeaderInvalidInterleavedIDs) Error() string {
+	return fmt.Sprintf("invalid interleaved IDs (%v)", e.Value)
+}
+
 // ErrServerTracksDifferentProtocols is returned in case the client is trying to setup tracks with different protocols.
 type ErrServerTracksDifferentProtocols struct{}
 
@@ -167,3 +220,187 @@ type ErrServerNoUDPPacketsRecently struct{}
 func (e ErrServerNoUDPPacketsRecently) Error() string {
 	return "no UDP packets received recently (maybe there's a firewall/NAT in between)"
 }
+
+// ErrServerSessionTimedOut is returned when no requests or RTCP packets
+// have been received within the session timeout.
+type ErrServerSessionTimedOut struct{}
+
+// Error implements the error interface.
+func (e ErrServerSessionTimedOut) Error() string {
+	return "session timed out"
+}
+
+// ErrServerAuthFailed is returned when a client failed to authenticate too many times.
+type ErrServerAuthFailed struct {
+	Err error
+}
+
+// Error implements the error interface.
+func (e ErrServerAuthFailed) Error() string {
+	return fmt.Sprintf("authentication failed: %v", e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e ErrServerAuthFailed) Unwrap() error {
+	return e.Err
+}
+
+// ErrServerNoResponse is returned when the client doesn't respond to a request.
+type ErrServerNoResponse struct{}
+
+// Error implements the error interface.
+func (e ErrServerNoResponse) Error() string {
+	return "client didn't respond to the request"
+}
+
+// ErrServerWriteQueueFull is returned when the write queue of a connection is full.
+type ErrServerWriteQueueFull struct{}
+
+// Error implements the error interface.
+func (e ErrServerWriteQueueFull) Error() string {
+	return "write queue is full"
+}
+
+// ErrServerWouldBlock is returned when a frame can't be written
+// because the write queue of a connection is full.
+type ErrServerWouldBlock struct{}
+
+// Error implements the error interface.
+func (e ErrServerWouldBlock) Error() string {
+	return "write queue is full, writing would block"
+}
+
+// ErrServerUDPSourceUnexpected is returned when a UDP packet is received from an unexpected address.
+type ErrServerUDPSourceUnexpected struct {
+	TrackID int
+	Address string
+}
+
+// Error implements the error interface.
+func (e ErrServerUDPSourceUnexpected) Error() string {
+	return fmt.Sprintf("received a UDP packet of track %d from an unexpected address (%s)", e.TrackID, e.Address)
+}
+
+// ErrServerRTPSSRCUnexpected is returned when a RTP packet with an unexpected SSRC is received.
+type ErrServerRTPSSRCUnexpected struct {
+	TrackID  int
+	Expected uint32
+	Value    uint32
+}
+
+// Error implements the error interface.
+func (e ErrServerRTPSSRCUnexpected) Error() string {
+	return fmt.Sprintf("received a RTP packet of track %d with an unexpected SSRC, expected %08X, got %08X",
+		e.TrackID, e.Expected, e.Value)
+}
+
+// ErrServerSRTPDecryptFailed is returned when a SRTP or SRTCP packet can't be
+// decrypted, since it fails authentication or has already been received.
+type ErrServerSRTPDecryptFailed struct {
+	TrackID int
+	Err     error
+}
+
+// Error implements the error interface.
+func (e ErrServerSRTPDecryptFailed) Error() string {
+	return fmt.Sprintf("unable to decrypt a packet of track %d: %s", e.TrackID, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e ErrServerSRTPDecryptFailed) Unwrap() error {
+	return e.Err
+}
+
+// ErrServerPathNoSlash is returned in case a path without track ID doesn't end with a slash.
+// Deprecated: paths without track ID are accepted even without a trailing slash,
+// and refer to track zero.
+type ErrServerPathNoSlash struct {
+	Path string
+}
+
+// Error implements the error interface.
+func (e ErrServerPathNoSlash) Error() string {
+	return fmt.Sprintf("path must end with a slash (%v)", e.Path)
+}
+
+// ErrServerTrackIDInvalid is returned in case the track ID can't be parsed.
+type ErrServerTrackIDInvalid struct {
+	Path string
+}
+
+// Error implements the error interface.
+func (e ErrServerTrackIDInvalid) Error() string {
+	return fmt.Sprintf("unable to parse track ID (%v)", e.Path)
+}
+
+// ErrServerTracksDifferentPaths is returned in case tracks are setup with different paths.
+type ErrServerTracksDifferentPaths struct{}
+
+// Error implements the error interface.
+func (e ErrServerTracksDifferentPaths) Error() string {
+	return "can't setup tracks with different paths"
+}
+
+// ErrServerTrackPathInvalid is returned in case the path of a SETUP request
+// doesn't correspond to any announced track.
+type ErrServerTrackPathInvalid struct {
+	Path string
+}
+
+// Error implements the error interface.
+func (e ErrServerTrackPathInvalid) Error() string {
+	return fmt.Sprintf("invalid track path (%s)", e.Path)
+}
+
+// ErrServerTrackURLInvalid is returned in case the URL of an announced track is invalid.
+// URL is nil when the URL can't be generated.
+type ErrServerTrackURLInvalid struct {
+	URL *base.URL
+}
+
+// Error implements the error interface.
+func (e ErrServerTrackURLInvalid) Error() string {
+	if e.URL == nil {
+		return "unable to generate track URL"
+	}
+	return fmt.Sprintf("invalid track URL (%v)", e.URL)
+}
+
+// ErrServerTrackPathPrefixInvalid is returned in case the path of an announced track
+// doesn't begin with the path of the stream.
+type ErrServerTrackPathPrefixInvalid struct {
+	Prefix string
+	Path   string
+}
+
+// Error implements the error interface.
+func (e ErrServerTrackPathPrefixInvalid) Error() string {
+	return fmt.Sprintf("invalid track path: must begin with '%s', but is '%s'", e.Prefix, e.Path)
+}
+
+// ErrServerUnhandledMethod is returned in case the method of a request is not handled.
+type ErrServerUnhandledMethod struct {
+	Method base.Method
+}
+
+// Error implements the error interface.
+func (e ErrServerUnhandledMethod) Error() string {
+	return fmt.Sprintf("unhandled method: %v", e.Method)
+}
+
+// ErrServerPassthroughDisabled is returned in case a function that requires
+// the passthrough mode is called when the mode is disabled.
+type ErrServerPassthroughDisabled struct{}
+
+// Error implements the error interface.
+func (e ErrServerPassthroughDisabled) Error() string {
+	return "passthrough mode is disabled"
+}
+
+// ErrServerTerminated is returned when the server has been closed by the user.
+type ErrServerTerminated struct{}
+
+// Error implements the error interface.
+func (e ErrServerTerminated) Error() string {
+	return "terminated"
+}
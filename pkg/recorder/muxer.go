@@ -0,0 +1,56 @@
+package recorder
+
+import (
+	"io"
+	"time"
+
+	"github.com/majoyz/gortsplib/pkg/base"
+	"github.com/majoyz/gortsplib/pkg/rtpdump"
+)
+
+// Muxer writes the frames of a track into a file.
+type Muxer interface {
+	// WriteFrame writes a frame, received at the given time.
+	WriteFrame(ts time.Time, streamType base.StreamType, payload []byte) error
+
+	// Close flushes buffered data. The underlying writer is closed by the Recorder.
+	Close() error
+}
+
+// NewMuxerFunc is the signature of a function that allocates a Muxer,
+// that writes the frames of a track into w, starting from the given time.
+type NewMuxerFunc func(w io.Writer, trackID int, start time.Time) (Muxer, error)
+
+type rtpdumpMuxer struct {
+	w     *rtpdump.Writer
+	start time.Time
+}
+
+// NewRTPDumpMuxer allocates a Muxer that writes frames in the rtpdump format.
+func NewRTPDumpMuxer(w io.Writer, trackID int, start time.Time) (Muxer, error) {
+	dw, err := rtpdump.NewWriter(w, rtpdump.Header{
+		Start: start,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &rtpdumpMuxer{
+		w:     dw,
+		start: start,
+	}, nil
+}
+
+// WriteFrame implements Muxer.
+func (m *rtpdumpMuxer) WriteFrame(ts time.Time, streamType base.StreamType, payload []byte) error {
+	return m.w.WritePacket(rtpdump.Packet{
+		Offset:     ts.Sub(m.start),
+		StreamType: streamType,
+		Payload:    payload,
+	})
+}
+
+// Close implements Muxer.
+func (m *rtpdumpMuxer) Close() error {
+	return nil
+}
@@ -0,0 +1,190 @@
+// Package recorder contains a utility to save the frames of a stream to disk.
+package recorder
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/majoyz/gortsplib/pkg/base"
+)
+
+// Conf allows to configure a Recorder.
+type Conf struct {
+	// function that returns the path of a file, given the ID of the track
+	// and the time of the first frame of the file.
+	// It defaults to a function that returns "trackN_YYYYMMDD_HHMMSS.NNNNNN.rtpdump".
+	PathFunc func(trackID int, start time.Time) string
+
+	// function that allocates the muxer of each file.
+	// It defaults to NewRTPDumpMuxer.
+	NewMuxer NewMuxerFunc
+
+	// (optional) a new file is created when the size of the current one
+	// reaches this value, in bytes.
+	MaxSize int64
+
+	// (optional) a new file is created when the current one contains
+	// frames that span this duration.
+	MaxDuration time.Duration
+}
+
+type countWriter struct {
+	w *bufio.Writer
+	n int64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+type segment struct {
+	f     *os.File
+	cw    *countWriter
+	muxer Muxer
+	start time.Time
+}
+
+func (s *segment) close() error {
+	err := s.muxer.Close()
+
+	err2 := s.cw.w.Flush()
+	if err == nil {
+		err = err2
+	}
+
+	err2 = s.f.Close()
+	if err == nil {
+		err = err2
+	}
+
+	return err
+}
+
+// Recorder saves the frames of a stream to disk, in a file for each track.
+// It can be fed with the frames received by OnFrame.
+type Recorder struct {
+	conf    Conf
+	timeNow func() time.Time
+
+	mutex    sync.Mutex
+	segments map[int]*segment
+	closed   bool
+}
+
+// New allocates a Recorder.
+func New(conf Conf) *Recorder {
+	if conf.PathFunc == nil {
+		conf.PathFunc = func(trackID int, start time.Time) string {
+			return fmt.Sprintf("track%d_%s.rtpdump", trackID, start.Format("20060102_150405.000000"))
+		}
+	}
+	if conf.NewMuxer == nil {
+		conf.NewMuxer = NewRTPDumpMuxer
+	}
+
+	return &Recorder{
+		conf:     conf,
+		timeNow:  time.Now,
+		segments: make(map[int]*segment),
+	}
+}
+
+// Close closes all files.
+func (r *Recorder) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.closed = true
+
+	var err error
+	for trackID, seg := range r.segments {
+		err2 := seg.close()
+		if err == nil {
+			err = err2
+		}
+		delete(r.segments, trackID)
+	}
+
+	return err
+}
+
+// WriteFrame writes a frame of a track.
+// It can be called by multiple routines.
+func (r *Recorder) WriteFrame(trackID int, streamType base.StreamType, payload []byte) error {
+	now := r.timeNow()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.closed {
+		return fmt.Errorf("terminated")
+	}
+
+	seg, ok := r.segments[trackID]
+	if ok && r.mustRotate(seg, now) {
+		delete(r.segments, trackID)
+		err := seg.close()
+		if err != nil {
+			return err
+		}
+		ok = false
+	}
+
+	if !ok {
+		var err error
+		seg, err = r.createSegment(trackID, now)
+		if err != nil {
+			return err
+		}
+		r.segments[trackID] = seg
+	}
+
+	return seg.muxer.WriteFrame(now, streamType, payload)
+}
+
+func (r *Recorder) mustRotate(seg *segment, now time.Time) bool {
+	if r.conf.MaxSize > 0 && seg.cw.n >= r.conf.MaxSize {
+		return true
+	}
+
+	if r.conf.MaxDuration > 0 && now.Sub(seg.start) >= r.conf.MaxDuration {
+		return true
+	}
+
+	return false
+}
+
+func (r *Recorder) createSegment(trackID int, start time.Time) (*segment, error) {
+	path := r.conf.PathFunc(trackID, start)
+
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cw := &countWriter{w: bufio.NewWriter(f)}
+
+	muxer, err := r.conf.NewMuxer(cw, trackID, start)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &segment{
+		f:     f,
+		cw:    cw,
+		muxer: muxer,
+		start: start,
+	}, nil
+}
@@ -0,0 +1,103 @@
+package recorder
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/majoyz/gortsplib/pkg/base"
+)
+
+func TestRecorder(t *testing.T) {
+	for _, ca := range []struct {
+		name        string
+		maxSize     int64
+		maxDuration time.Duration
+		files       map[string]int
+	}{
+		{
+			"no rotation",
+			0,
+			0,
+			map[string]int{
+				"0_0": 4,
+				"1_0": 2,
+			},
+		},
+		{
+			"size",
+			70,
+			0,
+			map[string]int{
+				"0_0": 3,
+				"0_3": 1,
+				"1_0": 2,
+			},
+		},
+		{
+			"duration",
+			0,
+			2 * time.Second,
+			map[string]int{
+				"0_0": 2,
+				"0_2": 2,
+				"1_0": 1,
+				"1_3": 1,
+			},
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "recorder")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			start := time.Date(2008, 05, 20, 22, 15, 20, 0, time.UTC)
+
+			r := New(Conf{
+				PathFunc: func(trackID int, ts time.Time) string {
+					return filepath.Join(dir, "sub",
+						fmt.Sprintf("%d_%d", trackID, ts.Sub(start)/time.Second))
+				},
+				MaxSize:     ca.maxSize,
+				MaxDuration: ca.maxDuration,
+			})
+
+			for _, frame := range []struct {
+				offset  time.Duration
+				trackID int
+			}{
+				{0, 0},
+				{0, 1},
+				{1 * time.Second, 0},
+				{2 * time.Second, 0},
+				{3 * time.Second, 0},
+				{3 * time.Second, 1},
+			} {
+				r.timeNow = func() time.Time { return start.Add(frame.offset) }
+				err := r.WriteFrame(frame.trackID, base.StreamTypeRTP, []byte{0x80, 0x60, 0x01, 0x02})
+				require.NoError(t, err)
+			}
+
+			err = r.Close()
+			require.NoError(t, err)
+
+			entries, err := ioutil.ReadDir(filepath.Join(dir, "sub"))
+			require.NoError(t, err)
+
+			files := make(map[string]int)
+			for _, e := range entries {
+				// header is 39 bytes, each packet is 12 bytes
+				files[e.Name()] = int((e.Size() - 39) / 12)
+			}
+			require.Equal(t, ca.files, files)
+
+			err = r.WriteFrame(0, base.StreamTypeRTP, []byte{0x80, 0x60, 0x01, 0x02})
+			require.EqualError(t, err, "terminated")
+		})
+	}
+}
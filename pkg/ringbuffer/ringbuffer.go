@@ -44,11 +44,31 @@ func (r *RingBuffer) Reset() {
 }
 
 // Push pushes some data at the end of the buffer.
-func (r *RingBuffer) Push(data interface{}) {
+// It returns true if the buffer was full and some data,
+// that was not pulled yet, has been overwritten.
+func (r *RingBuffer) Push(data interface{}) bool {
 	writeIndex := atomic.AddUint64(&r.writeIndex, 1)
 	i := writeIndex % r.bufferSize
-	atomic.SwapPointer(&r.buffer[i], unsafe.Pointer(&data))
+	prev := atomic.SwapPointer(&r.buffer[i], unsafe.Pointer(&data))
 	r.event.signal()
+	return prev != nil
+}
+
+// TryPull pulls some data from the beginning of the buffer, if available,
+// without waiting for data to be pushed.
+func (r *RingBuffer) TryPull() (interface{}, bool) {
+	if atomic.LoadInt64(&r.closed) == 1 {
+		return nil, false
+	}
+
+	i := r.readIndex % r.bufferSize
+	res := (*interface{})(atomic.SwapPointer(&r.buffer[i], nil))
+	if res == nil {
+		return nil, false
+	}
+
+	r.readIndex++
+	return *res, true
 }
 
 // Pull pulls some data from the beginning of the buffer.
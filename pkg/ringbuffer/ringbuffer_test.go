@@ -42,6 +42,42 @@ func TestPullBeforePush(t *testing.T) {
 	<-done
 }
 
+func TestPushOverwrite(t *testing.T) {
+	r := New(4)
+	defer r.Close()
+
+	for i := 0; i < 4; i++ {
+		require.Equal(t, false, r.Push(i))
+	}
+	require.Equal(t, true, r.Push(4))
+
+	ret, ok := r.Pull()
+	require.Equal(t, true, ok)
+	require.Equal(t, 4, ret)
+}
+
+func TestTryPull(t *testing.T) {
+	r := New(4)
+	defer r.Close()
+
+	_, ok := r.TryPull()
+	require.Equal(t, false, ok)
+
+	r.Push(1)
+	r.Push(2)
+
+	ret, ok := r.TryPull()
+	require.Equal(t, true, ok)
+	require.Equal(t, 1, ret)
+
+	ret, ok = r.TryPull()
+	require.Equal(t, true, ok)
+	require.Equal(t, 2, ret)
+
+	_, ok = r.TryPull()
+	require.Equal(t, false, ok)
+}
+
 func TestClose(t *testing.T) {
 	r := New(1024)
 
@@ -0,0 +1,84 @@
+// Package rtcpcompound contains functions to build and parse compound RTCP packets.
+package rtcpcompound
+
+import (
+	"github.com/pion/rtcp"
+)
+
+func sourceDescription(ssrc uint32, cname string) *rtcp.SourceDescription {
+	return &rtcp.SourceDescription{
+		Chunks: []rtcp.SourceDescriptionChunk{{
+			Source: ssrc,
+			Items: []rtcp.SourceDescriptionItem{{
+				Type: rtcp.SDESCNAME,
+				Text: cname,
+			}},
+		}},
+	}
+}
+
+// SenderReport builds a compound packet made of a sender report and
+// a source description that contains the CNAME of the sender.
+func SenderReport(sr *rtcp.SenderReport, cname string) ([]byte, error) {
+	return rtcp.Marshal([]rtcp.Packet{
+		sr,
+		sourceDescription(sr.SSRC, cname),
+	})
+}
+
+// ReceiverReport builds a compound packet made of a receiver report and
+// a source description that contains the CNAME of the receiver.
+func ReceiverReport(rr *rtcp.ReceiverReport, cname string) ([]byte, error) {
+	return rtcp.Marshal([]rtcp.Packet{
+		rr,
+		sourceDescription(rr.SSRC, cname),
+	})
+}
+
+// Bye builds a compound packet that notifies that a source is leaving.
+// Since compound packets must begin with a report (RFC3550, section 6.1),
+// the BYE packet is preceded by an empty receiver report and by a source
+// description.
+func Bye(ssrc uint32, cname string, reason string) ([]byte, error) {
+	return rtcp.Marshal([]rtcp.Packet{
+		&rtcp.ReceiverReport{SSRC: ssrc},
+		sourceDescription(ssrc, cname),
+		&rtcp.Goodbye{
+			Sources: []uint32{ssrc},
+			Reason:  reason,
+		},
+	})
+}
+
+// Unmarshal decodes a compound packet.
+func Unmarshal(buf []byte) ([]rtcp.Packet, error) {
+	return rtcp.Unmarshal(buf)
+}
+
+// ByeSources returns the sources that are leaving, listed in the BYE
+// packets contained in a compound packet.
+func ByeSources(pkts []rtcp.Packet) []uint32 {
+	var ret []uint32
+	for _, pkt := range pkts {
+		if bye, ok := pkt.(*rtcp.Goodbye); ok {
+			ret = append(ret, bye.Sources...)
+		}
+	}
+	return ret
+}
+
+// IsBye checks whether a compound packet contains a BYE packet.
+// Invalid packets are reported as not containing a BYE packet.
+func IsBye(buf []byte) bool {
+	pkts, err := rtcp.Unmarshal(buf)
+	if err != nil {
+		return false
+	}
+
+	for _, pkt := range pkts {
+		if _, ok := pkt.(*rtcp.Goodbye); ok {
+			return true
+		}
+	}
+	return false
+}
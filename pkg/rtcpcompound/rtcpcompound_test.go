@@ -0,0 +1,70 @@
+package rtcpcompound
+
+import (
+	"testing"
+
+	"github.com/pion/rtcp"
+	"github.com/stretchr/testify/require"
+)
+
+var sdes = []byte{
+	0x81, 0xca, 0x00, 0x03, 0x01, 0x02, 0x03, 0x04,
+	0x01, 0x04, 0x68, 0x6f, 0x73, 0x74, 0x00, 0x00,
+}
+
+func TestSenderReport(t *testing.T) {
+	byts, err := SenderReport(&rtcp.SenderReport{
+		SSRC:        0x01020304,
+		NTPTime:     0x0102030405060708,
+		RTPTime:     0x0a0b0c0d,
+		PacketCount: 1,
+		OctetCount:  2,
+	}, "host")
+	require.NoError(t, err)
+	require.Equal(t, append([]byte{
+		0x80, 0xc8, 0x00, 0x06, 0x01, 0x02, 0x03, 0x04,
+		0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08,
+		0x0a, 0x0b, 0x0c, 0x0d, 0x00, 0x00, 0x00, 0x01,
+		0x00, 0x00, 0x00, 0x02,
+	}, sdes...), byts)
+}
+
+func TestReceiverReport(t *testing.T) {
+	byts, err := ReceiverReport(&rtcp.ReceiverReport{
+		SSRC: 0x01020304,
+	}, "host")
+	require.NoError(t, err)
+	require.Equal(t, append([]byte{
+		0x80, 0xc9, 0x00, 0x01, 0x01, 0x02, 0x03, 0x04,
+	}, sdes...), byts)
+}
+
+func TestBye(t *testing.T) {
+	byts, err := Bye(0x01020304, "host", "end")
+	require.NoError(t, err)
+
+	expected := []byte{0x80, 0xc9, 0x00, 0x01, 0x01, 0x02, 0x03, 0x04}
+	expected = append(expected, sdes...)
+	expected = append(expected, []byte{
+		0x81, 0xcb, 0x00, 0x02, 0x01, 0x02, 0x03, 0x04,
+		0x03, 0x65, 0x6e, 0x64,
+	}...)
+	require.Equal(t, expected, byts)
+
+	require.Equal(t, true, IsBye(byts))
+
+	pkts, err := Unmarshal(byts)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(pkts))
+	require.Equal(t, []uint32{0x01020304}, ByeSources(pkts))
+}
+
+func TestIsBye(t *testing.T) {
+	byts, err := ReceiverReport(&rtcp.ReceiverReport{
+		SSRC: 0x01020304,
+	}, "host")
+	require.NoError(t, err)
+	require.Equal(t, false, IsBye(byts))
+
+	require.Equal(t, false, IsBye([]byte{0x01, 0x02}))
+}
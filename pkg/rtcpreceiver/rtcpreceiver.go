@@ -17,21 +17,30 @@ type RTCPReceiver struct {
 	clockRate    float64
 	mutex        sync.Mutex
 
+	// data from the RTP-Info header
+	initialSequenceNumberSet bool
+	initialSequenceNumber    uint16
+
 	// data from rtp packets
 	firstRTPReceived     bool
+	rtpSSRC              uint32
+	lastRTPSSRC          uint32
 	sequenceNumberCycles uint16
 	lastSequenceNumber   uint16
 	lastRTPTimeRTP       uint32
 	lastRTPTimeTime      time.Time
+	totalReceived        uint64
 	totalLost            uint32
 	totalLostSinceReport uint32
 	totalSinceReport     uint32
 	jitter               float64
 
 	// data from rtcp packets
-	senderSSRC           uint32
+	senderReportReceived bool
 	lastSenderReport     uint32
 	lastSenderReportTime time.Time
+	lastSenderReportNTP  time.Time
+	lastSenderReportRTP  uint32
 }
 
 // New allocates a RTCPReceiver.
@@ -47,6 +56,33 @@ func New(receiverSSRC *uint32, clockRate int) *RTCPReceiver {
 	}
 }
 
+// ReceiverSSRC returns the SSRC of the receiver.
+func (rr *RTCPReceiver) ReceiverSSRC() uint32 {
+	return rr.receiverSSRC
+}
+
+// SenderSSRC returns the SSRC of the last received RTP packet, that is the
+// SSRC of the source that is currently sending the stream.
+// It returns false if no RTP packets have been received yet.
+func (rr *RTCPReceiver) SenderSSRC() (uint32, bool) {
+	rr.mutex.Lock()
+	defer rr.mutex.Unlock()
+
+	return rr.lastRTPSSRC, rr.firstRTPReceived
+}
+
+// SetInitialSequenceNumber sets the sequence number of the first RTP packet
+// that is expected to be received, usually provided by the RTP-Info header.
+// Packets missing between this sequence number and the first received one
+// are counted as lost.
+func (rr *RTCPReceiver) SetInitialSequenceNumber(sequenceNumber uint16) {
+	rr.mutex.Lock()
+	defer rr.mutex.Unlock()
+
+	rr.initialSequenceNumberSet = true
+	rr.initialSequenceNumber = sequenceNumber
+}
+
 // ProcessFrame extracts the needed data from RTP or RTCP frames.
 func (rr *RTCPReceiver) ProcessFrame(ts time.Time, streamType base.StreamType, buf []byte) {
 	rr.mutex.Lock()
@@ -54,18 +90,36 @@ func (rr *RTCPReceiver) ProcessFrame(ts time.Time, streamType base.StreamType, b
 
 	if streamType == base.StreamTypeRTP {
 		// do not parse the entire packet, extract only the fields we need
-		if len(buf) >= 8 {
+		if len(buf) >= 12 {
 			sequenceNumber := uint16(buf[2])<<8 | uint16(buf[3])
 			rtpTime := uint32(buf[4])<<24 | uint32(buf[5])<<16 | uint32(buf[6])<<8 | uint32(buf[7])
+			ssrc := uint32(buf[8])<<24 | uint32(buf[9])<<16 | uint32(buf[10])<<8 | uint32(buf[11])
+			rr.lastRTPSSRC = ssrc
 
 			// first frame
 			if !rr.firstRTPReceived {
 				rr.firstRTPReceived = true
+				rr.rtpSSRC = ssrc
+				rr.totalReceived = 1
 				rr.totalSinceReport = 1
 				rr.lastSequenceNumber = sequenceNumber
 				rr.lastRTPTimeRTP = rtpTime
 				rr.lastRTPTimeTime = ts
 
+				// packets sent before the first received one have been lost
+				if rr.initialSequenceNumberSet {
+					diff := int16(sequenceNumber - rr.initialSequenceNumber)
+					if diff > 0 {
+						if sequenceNumber < rr.initialSequenceNumber {
+							rr.sequenceNumberCycles++
+						}
+
+						rr.totalLost = uint32(diff)
+						rr.totalLostSinceReport = uint32(diff)
+						rr.totalSinceReport += uint32(diff)
+					}
+				}
+
 				// subsequent frames
 			} else {
 				diff := int32(sequenceNumber) - int32(rr.lastSequenceNumber)
@@ -100,6 +154,7 @@ func (rr *RTCPReceiver) ProcessFrame(ts time.Time, streamType base.StreamType, b
 					}
 					rr.jitter += (D - rr.jitter) / 16
 
+					rr.totalReceived++
 					rr.totalSinceReport += uint32(uint16(diff))
 					rr.lastSequenceNumber = sequenceNumber
 					rr.lastRTPTimeRTP = rtpTime
@@ -116,40 +171,107 @@ func (rr *RTCPReceiver) ProcessFrame(ts time.Time, streamType base.StreamType, b
 		if err == nil {
 			for _, frame := range frames {
 				if sr, ok := (frame).(*rtcp.SenderReport); ok {
-					rr.senderSSRC = sr.SSRC
+					rr.senderReportReceived = true
 					rr.lastSenderReport = uint32(sr.NTPTime >> 16)
 					rr.lastSenderReportTime = ts
+					rr.lastSenderReportNTP = ntpTimeToTime(sr.NTPTime)
+					rr.lastSenderReportRTP = sr.RTPTime
 				}
 			}
 		}
 	}
 }
 
+// ntpEpoch is the start of the NTP time scale.
+var ntpEpoch = time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func ntpTimeToTime(v uint64) time.Time {
+	secs := int64(v >> 32)
+	nanos := int64((v & 0xFFFFFFFF) * 1e9 >> 32)
+	return ntpEpoch.Add(time.Duration(secs)*time.Second + time.Duration(nanos))
+}
+
+// PacketNTPTime returns the absolute time of a RTP packet, computed by
+// using the NTP and RTP timestamps of the last received sender report.
+// It returns false if no sender reports have been received yet.
+func (rr *RTCPReceiver) PacketNTPTime(rtpTimestamp uint32) (time.Time, bool) {
+	rr.mutex.Lock()
+	defer rr.mutex.Unlock()
+
+	if !rr.senderReportReceived {
+		return time.Time{}, false
+	}
+
+	// the difference is signed, in order to support packets that
+	// precede the sender report, and timestamp overflows.
+	diff := int64(int32(rtpTimestamp - rr.lastSenderReportRTP))
+
+	return rr.lastSenderReportNTP.Add(
+		time.Duration(float64(diff) * float64(time.Second) / rr.clockRate)), true
+}
+
+// Stats are the reception statistics of a RTCPReceiver.
+type Stats struct {
+	// number of received RTP packets.
+	PacketsReceived uint64
+
+	// number of lost RTP packets.
+	PacketsLost uint32
+
+	// interarrival jitter, expressed in RTP timestamp units.
+	Jitter float64
+}
+
+// Stats returns the reception statistics.
+func (rr *RTCPReceiver) Stats() Stats {
+	rr.mutex.Lock()
+	defer rr.mutex.Unlock()
+
+	return Stats{
+		PacketsReceived: rr.totalReceived,
+		PacketsLost:     rr.totalLost,
+		Jitter:          rr.jitter,
+	}
+}
+
 // Report generates a RTCP receiver report.
+// It returns nil if no RTP packets have been received yet.
 func (rr *RTCPReceiver) Report(ts time.Time) []byte {
 	rr.mutex.Lock()
 	defer rr.mutex.Unlock()
 
+	if !rr.firstRTPReceived {
+		return nil
+	}
+
 	report := &rtcp.ReceiverReport{
 		SSRC: rr.receiverSSRC,
 		Reports: []rtcp.ReceptionReport{
 			{
-				SSRC:               rr.senderSSRC,
+				SSRC:               rr.rtpSSRC,
 				LastSequenceNumber: uint32(rr.sequenceNumberCycles)<<16 | uint32(rr.lastSequenceNumber),
-				LastSenderReport:   rr.lastSenderReport,
-				// equivalent to taking the integer part after multiplying the
-				// loss fraction by 256
-				FractionLost: uint8(float64(rr.totalLostSinceReport*256) / float64(rr.totalSinceReport)),
-				TotalLost:    rr.totalLost,
-				// delay, expressed in units of 1/65536 seconds, between
-				// receiving the last SR packet from source SSRC_n and sending this
-				// reception report block
-				Delay:  uint32(ts.Sub(rr.lastSenderReportTime).Seconds() * 65536),
-				Jitter: uint32(rr.jitter),
+				TotalLost:          rr.totalLost,
+				Jitter:             uint32(rr.jitter),
 			},
 		},
 	}
 
+	if rr.totalSinceReport != 0 {
+		// equivalent to taking the integer part after multiplying the
+		// loss fraction by 256
+		report.Reports[0].FractionLost = uint8(float64(rr.totalLostSinceReport*256) /
+			float64(rr.totalSinceReport))
+	}
+
+	if rr.senderReportReceived {
+		report.Reports[0].LastSenderReport = rr.lastSenderReport
+
+		// delay, expressed in units of 1/65536 seconds, between
+		// receiving the last SR packet from source SSRC_n and sending this
+		// reception report block
+		report.Reports[0].Delay = uint32(ts.Sub(rr.lastSenderReportTime).Seconds() * 65536)
+	}
+
 	rr.totalLostSinceReport = 0
 	rr.totalSinceReport = 0
 
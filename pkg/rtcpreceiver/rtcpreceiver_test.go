@@ -387,3 +387,199 @@ func TestRTCPReceiverJitter(t *testing.T) {
 	ts = time.Date(2008, 05, 20, 22, 15, 22, 0, time.UTC)
 	require.Equal(t, expected, rr.Report(ts))
 }
+
+func TestRTCPReceiverNoSenderReport(t *testing.T) {
+	v := uint32(0x65f83afb)
+	rr := New(&v, 90000)
+
+	ts := time.Date(2008, 05, 20, 22, 15, 20, 0, time.UTC)
+	require.Equal(t, []byte(nil), rr.Report(ts))
+
+	rtpPkt := rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			Marker:         true,
+			PayloadType:    96,
+			SequenceNumber: 946,
+			Timestamp:      0xafb45733,
+			SSRC:           0xba9da416,
+		},
+		Payload: []byte("\x00\x00"),
+	}
+	byts, _ := rtpPkt.Marshal()
+	rr.ProcessFrame(ts, base.StreamTypeRTP, byts)
+
+	expectedPkt := rtcp.ReceiverReport{
+		SSRC: 0x65f83afb,
+		Reports: []rtcp.ReceptionReport{
+			{
+				SSRC:               0xba9da416,
+				LastSequenceNumber: 946,
+			},
+		},
+	}
+	expected, _ := expectedPkt.Marshal()
+	ts = time.Date(2008, 05, 20, 22, 15, 22, 0, time.UTC)
+	require.Equal(t, expected, rr.Report(ts))
+
+	// no packets since last report
+	require.Equal(t, expected, rr.Report(ts))
+}
+
+func TestRTCPReceiverStats(t *testing.T) {
+	rr := New(nil, 90000)
+	require.Equal(t, Stats{}, rr.Stats())
+
+	ts := time.Date(2008, 05, 20, 22, 15, 20, 0, time.UTC)
+
+	for _, seq := range []uint16{0x0120, 0x0121, 0x0124} {
+		rtpPkt := rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				Marker:         true,
+				PayloadType:    96,
+				SequenceNumber: seq,
+				Timestamp:      0xafb45733,
+				SSRC:           0xba9da416,
+			},
+			Payload: []byte("\x00\x00"),
+		}
+		byts, _ := rtpPkt.Marshal()
+		rr.ProcessFrame(ts, base.StreamTypeRTP, byts)
+	}
+
+	require.Equal(t, Stats{
+		PacketsReceived: 3,
+		PacketsLost:     2,
+	}, rr.Stats())
+}
+
+func TestRTCPReceiverInitialSequenceNumber(t *testing.T) {
+	for _, ca := range []struct {
+		name    string
+		initial uint16
+		first   uint16
+		lost    uint32
+		lastSeq uint32
+	}{
+		{
+			"no loss",
+			0x0120,
+			0x0120,
+			0,
+			0x0120,
+		},
+		{
+			"loss",
+			0x0120,
+			0x0123,
+			3,
+			0x0123,
+		},
+		{
+			"loss with overflow",
+			0xfffe,
+			0x0001,
+			3,
+			0x10001,
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			v := uint32(0x65f83afb)
+			rr := New(&v, 90000)
+			rr.SetInitialSequenceNumber(ca.initial)
+
+			rtpPkt := rtp.Packet{
+				Header: rtp.Header{
+					Version:        2,
+					Marker:         true,
+					PayloadType:    96,
+					SequenceNumber: ca.first,
+					Timestamp:      0xafb45733,
+					SSRC:           0xba9da416,
+				},
+				Payload: []byte("\x00\x00"),
+			}
+			byts, _ := rtpPkt.Marshal()
+			ts := time.Date(2008, 05, 20, 22, 15, 20, 0, time.UTC)
+			rr.ProcessFrame(ts, base.StreamTypeRTP, byts)
+
+			require.Equal(t, Stats{
+				PacketsReceived: 1,
+				PacketsLost:     ca.lost,
+			}, rr.Stats())
+
+			expectedPkt := rtcp.ReceiverReport{
+				SSRC: 0x65f83afb,
+				Reports: []rtcp.ReceptionReport{
+					{
+						SSRC:               0xba9da416,
+						LastSequenceNumber: ca.lastSeq,
+						FractionLost: func() uint8 {
+							v := float64(ca.lost) / float64(ca.lost+1)
+							return uint8(v * 256)
+						}(),
+						TotalLost: ca.lost,
+					},
+				},
+			}
+			expected, _ := expectedPkt.Marshal()
+			require.Equal(t, expected, rr.Report(ts))
+		})
+	}
+}
+
+func TestRTCPReceiverPacketNTPTime(t *testing.T) {
+	rr := New(nil, 90000)
+
+	_, ok := rr.PacketNTPTime(0xafb45733)
+	require.Equal(t, false, ok)
+
+	srPkt := rtcp.SenderReport{
+		SSRC: 0xba9da416,
+		// 2008-05-20 22:15:20.5 UTC
+		NTPTime:     uint64(1211321720+2208988800)<<32 | 0x80000000,
+		RTPTime:     0xafb45733,
+		PacketCount: 714,
+		OctetCount:  859127,
+	}
+	byts, _ := srPkt.Marshal()
+	rr.ProcessFrame(time.Now(), base.StreamTypeRTCP, byts)
+
+	for _, ca := range []struct {
+		name     string
+		rtpTime  uint32
+		expected time.Time
+	}{
+		{
+			"same",
+			0xafb45733,
+			time.Date(2008, 05, 20, 22, 15, 20, 500000000, time.UTC),
+		},
+		{
+			"after",
+			0xafb45733 + 90000,
+			time.Date(2008, 05, 20, 22, 15, 21, 500000000, time.UTC),
+		},
+		{
+			"before",
+			0xafb45733 - 45000,
+			time.Date(2008, 05, 20, 22, 15, 20, 0, time.UTC),
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			ntp, ok := rr.PacketNTPTime(ca.rtpTime)
+			require.Equal(t, true, ok)
+			require.True(t, ca.expected.Equal(ntp), "%v != %v", ca.expected, ntp)
+		})
+	}
+
+	// timestamp overflow
+	srPkt.RTPTime = 0xFFFFFFFF - 44999
+	byts, _ = srPkt.Marshal()
+	rr.ProcessFrame(time.Now(), base.StreamTypeRTCP, byts)
+
+	ntp, ok := rr.PacketNTPTime(45000)
+	require.Equal(t, true, ok)
+	require.True(t, time.Date(2008, 05, 20, 22, 15, 21, 500000000, time.UTC).Equal(ntp))
+}
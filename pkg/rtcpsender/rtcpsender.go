@@ -11,9 +11,22 @@ import (
 	"github.com/majoyz/gortsplib/pkg/base"
 )
 
+// ntpEpochOffset is the number of seconds between 1st January 1900
+// and 1st January 1970.
+const ntpEpochOffset = 2208988800
+
+// ntpTime converts a time into the NTP format.
+func ntpTime(t time.Time) uint64 {
+	// higher 32 bits are the seconds since 1st January 1900,
+	// lower 32 bits are the fractional part
+	s := uint64(t.Unix() + ntpEpochOffset)
+	frac := (uint64(t.Nanosecond()) << 32) / uint64(time.Second)
+	return s<<32 | frac
+}
+
 // RTCPSender is a utility to generate RTCP sender reports.
 type RTCPSender struct {
-	clockRate float64
+	clockRate int64
 	mutex     sync.Mutex
 
 	// data from rtp packets
@@ -26,12 +39,26 @@ type RTCPSender struct {
 }
 
 // New allocates a RTCPSender.
+// clockRate is the clock rate of the track, that is used to map
+// RTP timestamps to the NTP timestamps of reports. If it is zero,
+// reports are not generated.
 func New(clockRate int) *RTCPSender {
 	return &RTCPSender{
-		clockRate: float64(clockRate),
+		clockRate: int64(clockRate),
 	}
 }
 
+// rtpTimeAt returns the RTP timestamp corresponding to the given time,
+// extrapolated from the last RTP packet.
+func (rs *RTCPSender) rtpTimeAt(ts time.Time) uint32 {
+	// seconds and nanoseconds are converted separately to avoid overflows,
+	// and a signed value is used since ts may precede the last packet.
+	d := ts.Sub(rs.lastRTPTimeTime)
+	delta := int64(d/time.Second)*rs.clockRate +
+		int64(d%time.Second)*rs.clockRate/int64(time.Second)
+	return rs.lastRTPTimeRTP + uint32(delta)
+}
+
 // ProcessFrame extracts the needed data from RTP or RTCP frames.
 func (rs *RTCPSender) ProcessFrame(ts time.Time, streamType base.StreamType, buf []byte) {
 	rs.mutex.Lock()
@@ -56,28 +83,31 @@ func (rs *RTCPSender) ProcessFrame(ts time.Time, streamType base.StreamType, buf
 	}
 }
 
+// SenderSSRC returns the SSRC of the sender, that is the one of the
+// RTP packets passed to ProcessFrame.
+// It returns false if no packets have been passed to ProcessFrame yet.
+func (rs *RTCPSender) SenderSSRC() (uint32, bool) {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	return rs.senderSSRC, rs.firstRTPReceived
+}
+
 // Report generates a RTCP sender report.
-// It returns nil if no packets has been passed to ProcessFrame yet.
+// It returns nil if no packets has been passed to ProcessFrame yet,
+// or if the clock rate is unknown.
 func (rs *RTCPSender) Report(ts time.Time) []byte {
 	rs.mutex.Lock()
 	defer rs.mutex.Unlock()
 
-	if !rs.firstRTPReceived {
+	if !rs.firstRTPReceived || rs.clockRate <= 0 {
 		return nil
 	}
 
 	report := &rtcp.SenderReport{
-		SSRC: rs.senderSSRC,
-		NTPTime: func() uint64 {
-			// seconds since 1st January 1900
-			s := (float64(ts.UnixNano()) / 1000000000) + 2208988800
-
-			// higher 32 bits are the integer part, lower 32 bits are the fractional part
-			integerPart := uint32(s)
-			fractionalPart := uint32((s - float64(integerPart)) * 0xFFFFFFFF)
-			return uint64(integerPart)<<32 | uint64(fractionalPart)
-		}(),
-		RTPTime:     rs.lastRTPTimeRTP + uint32((ts.Sub(rs.lastRTPTimeTime)).Seconds()*rs.clockRate),
+		SSRC:        rs.senderSSRC,
+		NTPTime:     ntpTime(ts),
+		RTPTime:     rs.rtpTimeAt(ts),
 		PacketCount: rs.packetCount,
 		OctetCount:  rs.octetCount,
 	}
@@ -46,7 +46,7 @@ func TestRTCPSender(t *testing.T) {
 
 	expectedPkt := rtcp.SenderReport{
 		SSRC:        0xba9da416,
-		NTPTime:     0xcbddcc34999997ff,
+		NTPTime:     0xcbddcc3499999999,
 		RTPTime:     0x4d185ae8,
 		PacketCount: 2,
 		OctetCount:  4,
@@ -55,3 +55,76 @@ func TestRTCPSender(t *testing.T) {
 	ts = time.Date(2008, 05, 20, 22, 16, 20, 600000000, time.UTC)
 	require.Equal(t, expected, rs.Report(ts))
 }
+
+func TestRTCPSenderClockRate(t *testing.T) {
+	for _, ca := range []struct {
+		name      string
+		clockRate int
+		reportTS  time.Time
+		rtpTime   uint32
+	}{
+		{
+			"aac 44100",
+			44100,
+			time.Date(2008, 05, 20, 22, 15, 21, 0, time.UTC),
+			1287987768 + 44100,
+		},
+		{
+			"opus 48000",
+			48000,
+			time.Date(2008, 05, 20, 22, 15, 20, 250000000, time.UTC),
+			1287987768 + 12000,
+		},
+		{
+			"after a long time",
+			48000,
+			time.Date(2008, 05, 22, 22, 15, 20, 0, time.UTC),
+			(1287987768 + 48000*3600*48) % (1 << 32),
+		},
+		{
+			"before last packet",
+			48000,
+			time.Date(2008, 05, 20, 22, 15, 19, 500000000, time.UTC),
+			1287987768 - 24000,
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			rs := New(ca.clockRate)
+
+			byts, _ := (&rtp.Packet{
+				Header: rtp.Header{
+					Version:        2,
+					PayloadType:    97,
+					SequenceNumber: 946,
+					Timestamp:      1287987768,
+					SSRC:           0xba9da416,
+				},
+				Payload: []byte("\x00\x00"),
+			}).Marshal()
+			rs.ProcessFrame(time.Date(2008, 05, 20, 22, 15, 20, 0, time.UTC), base.StreamTypeRTP, byts)
+
+			var sr rtcp.SenderReport
+			err := sr.Unmarshal(rs.Report(ca.reportTS))
+			require.NoError(t, err)
+			require.Equal(t, ca.rtpTime, sr.RTPTime)
+		})
+	}
+}
+
+func TestRTCPSenderUnknownClockRate(t *testing.T) {
+	rs := New(0)
+
+	byts, _ := (&rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			PayloadType:    97,
+			SequenceNumber: 946,
+			Timestamp:      1287987768,
+			SSRC:           0xba9da416,
+		},
+		Payload: []byte("\x00\x00"),
+	}).Marshal()
+	rs.ProcessFrame(time.Now(), base.StreamTypeRTP, byts)
+
+	require.Nil(t, rs.Report(time.Now()))
+}
@@ -2,17 +2,27 @@ package rtpaac
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/pion/rtp"
 )
 
+// ErrMorePacketsNeeded is returned by Decoder.Decode when more packets are needed.
+var ErrMorePacketsNeeded = errors.New("need more packets")
+
 // Decoder is a RTP/AAC decoder.
 type Decoder struct {
 	clockRate    time.Duration
 	initialTs    uint32
 	initialTsSet bool
+
+	// for fragmented AUs
+	isDecodingFragmented bool
+	fragmentedTs         uint32
+	fragmentedSize       int
+	fragmentedBuf        []byte
 }
 
 // NewDecoder allocates a Decoder.
@@ -27,10 +37,13 @@ func (d *Decoder) decodeTimestamp(ts uint32) time.Duration {
 }
 
 // Decode decodes one or multiple AUs from an RTP/AAC packet.
+// When an AU is fragmented into multiple packets, it returns ErrMorePacketsNeeded
+// until the last fragment is received.
 func (d *Decoder) Decode(byts []byte) ([]*AUAndTimestamp, error) {
 	pkt := rtp.Packet{}
 	err := pkt.Unmarshal(byts)
 	if err != nil {
+		d.isDecodingFragmented = false
 		return nil, err
 	}
 
@@ -39,9 +52,15 @@ func (d *Decoder) Decode(byts []byte) ([]*AUAndTimestamp, error) {
 		d.initialTs = pkt.Timestamp
 	}
 
+	if len(pkt.Payload) < 2 {
+		d.isDecodingFragmented = false
+		return nil, fmt.Errorf("payload is too short")
+	}
+
 	// AU-headers-length
 	headersLen := binary.BigEndian.Uint16(pkt.Payload)
 	if (headersLen % 16) != 0 {
+		d.isDecodingFragmented = false
 		return nil, fmt.Errorf("invalid AU-headers-length (%d)", headersLen)
 	}
 	pkt.Payload = pkt.Payload[2:]
@@ -50,13 +69,19 @@ func (d *Decoder) Decode(byts []byte) ([]*AUAndTimestamp, error) {
 	// AAC headers are 16 bits, where
 	// * 13 bits are data size
 	// * 3 bits are AU index
-	headerCount := headersLen / 16
+	headerCount := int(headersLen / 16)
+	if len(pkt.Payload) < headerCount*2 {
+		d.isDecodingFragmented = false
+		return nil, fmt.Errorf("payload is too short")
+	}
+
 	var dataSizes []uint16
-	for i := 0; i < int(headerCount); i++ {
+	for i := 0; i < headerCount; i++ {
 		header := binary.BigEndian.Uint16(pkt.Payload[i*2:])
 		dataSize := header >> 3
-		auIndex := header & 0x03
+		auIndex := header & 0x07
 		if auIndex != 0 {
+			d.isDecodingFragmented = false
 			return nil, fmt.Errorf("AU-index field must be zero")
 		}
 
@@ -64,6 +89,40 @@ func (d *Decoder) Decode(byts []byte) ([]*AUAndTimestamp, error) {
 	}
 	pkt.Payload = pkt.Payload[headerCount*2:]
 
+	if d.isDecodingFragmented {
+		if headerCount != 1 || int(dataSizes[0]) != d.fragmentedSize ||
+			pkt.Timestamp != d.fragmentedTs {
+			d.isDecodingFragmented = false
+			return nil, fmt.Errorf("received a non-fragmented packet while decoding a fragmented AU")
+		}
+
+		d.fragmentedBuf = append(d.fragmentedBuf, pkt.Payload...)
+
+		if len(d.fragmentedBuf) > d.fragmentedSize {
+			d.isDecodingFragmented = false
+			return nil, fmt.Errorf("fragmented AU is bigger than its declared size")
+		}
+
+		if len(d.fragmentedBuf) < d.fragmentedSize {
+			return nil, ErrMorePacketsNeeded
+		}
+
+		d.isDecodingFragmented = false
+		return []*AUAndTimestamp{{
+			AU:        d.fragmentedBuf,
+			Timestamp: d.decodeTimestamp(pkt.Timestamp),
+		}}, nil
+	}
+
+	// first fragment of a fragmented AU
+	if headerCount == 1 && len(pkt.Payload) < int(dataSizes[0]) && !pkt.Marker {
+		d.isDecodingFragmented = true
+		d.fragmentedTs = pkt.Timestamp
+		d.fragmentedSize = int(dataSizes[0])
+		d.fragmentedBuf = append([]byte(nil), pkt.Payload...)
+		return nil, ErrMorePacketsNeeded
+	}
+
 	ts := d.decodeTimestamp(pkt.Timestamp)
 	rets := make([]*AUAndTimestamp, len(dataSizes))
 
@@ -4,6 +4,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"math/rand"
+	"sync"
 	"time"
 
 	"github.com/pion/rtp"
@@ -21,6 +22,12 @@ type Encoder struct {
 	sequenceNumber uint16
 	ssrc           uint32
 	initialTs      uint32
+	payloadMaxSize int
+
+	// for State()
+	mutex     sync.Mutex
+	lastTs    uint32
+	lastTsSet bool
 }
 
 // NewEncoder allocates an Encoder.
@@ -50,46 +57,217 @@ func NewEncoder(payloadType uint8,
 			}
 			return rand.Uint32()
 		}(),
+		payloadMaxSize: rtpPayloadMaxSize,
 	}
 }
 
+// SetPayloadMaxSize sets the maximum size of RTP payloads.
+// AUs bigger than this are fragmented.
+// It defaults to 1460, that is suitable for a 1500 bytes MTU.
+func (e *Encoder) SetPayloadMaxSize(v int) {
+	e.payloadMaxSize = v
+}
+
 func (e *Encoder) encodeTimestamp(ts time.Duration) uint32 {
 	return e.initialTs + uint32(ts.Seconds()*e.clockRate)
 }
 
-// Encode encodes an AU into an RTP/AAC packet.
-func (e *Encoder) Encode(at *AUAndTimestamp) ([]byte, error) {
-	if len(at.AU) > rtpPayloadMaxSize {
-		return nil, fmt.Errorf("data is too big")
-	}
+// EncoderState is a snapshot of the state of an Encoder.
+type EncoderState struct {
+	// sequence number of the next packet.
+	SequenceNumber uint16
 
-	// AU-headers-length
-	payload := []byte{0x00, 0x10}
+	// SSRC of the packets.
+	SSRC uint32
 
-	// AU-header
-	header := make([]byte, 2)
-	binary.BigEndian.PutUint16(header, uint16(len(at.AU))<<3)
-	payload = append(payload, header...)
+	// timestamp of the last packet, or the initial timestamp
+	// if no packets have been encoded yet.
+	Timestamp uint32
+}
 
-	payload = append(payload, at.AU...)
+// SequenceNumber returns the sequence number of the next packet.
+func (e *Encoder) SequenceNumber() uint16 {
+	return e.State().SequenceNumber
+}
+
+// SSRC returns the SSRC of the packets.
+func (e *Encoder) SSRC() uint32 {
+	return e.ssrc
+}
+
+// Timestamp returns the timestamp of the last packet, or the initial
+// timestamp if no packets have been encoded yet.
+func (e *Encoder) Timestamp() uint32 {
+	return e.State().Timestamp
+}
+
+// State returns the sequence number, the SSRC and the timestamp at once.
+// It can be called while packets are being encoded by another routine,
+// for instance in order to fill the RTP-Info header of a PLAY response.
+func (e *Encoder) State() EncoderState {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	ts := e.initialTs
+	if e.lastTsSet {
+		ts = e.lastTs
+	}
+
+	return EncoderState{
+		SequenceNumber: e.sequenceNumber,
+		SSRC:           e.ssrc,
+		Timestamp:      ts,
+	}
+}
+
+func (e *Encoder) newPacket(ts uint32, payload []byte, marker bool) ([]byte, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
 
 	rpkt := rtp.Packet{
 		Header: rtp.Header{
 			Version:        rtpVersion,
 			PayloadType:    e.payloadType,
 			SequenceNumber: e.sequenceNumber,
-			Timestamp:      e.encodeTimestamp(at.Timestamp),
+			Timestamp:      ts,
 			SSRC:           e.ssrc,
+			Marker:         marker,
 		},
 		Payload: payload,
 	}
 	e.sequenceNumber++
-	rpkt.Header.Marker = true
+	e.lastTs = ts
+	e.lastTsSet = true
+
+	return rpkt.Marshal()
+}
+
+// Encode encodes an AU into RTP/AAC packets.
+// If the AU doesn't fit into a single packet, it is fragmented.
+// It always returns at least one RTP/AAC packet.
+func (e *Encoder) Encode(at *AUAndTimestamp) ([][]byte, error) {
+	return e.EncodeAggregated([]*AUAndTimestamp{at})
+}
+
+// EncodeAggregated encodes consecutive AUs into RTP/AAC packets.
+// AUs are grouped into a single packet when possible,
+// and AUs that don't fit into a single packet are fragmented.
+// The timestamp of each packet is the one of its first AU.
+func (e *Encoder) EncodeAggregated(ats []*AUAndTimestamp) ([][]byte, error) {
+	var ret [][]byte
+	var batch []*AUAndTimestamp
+
+	// AU-headers-length (2 bytes)
+	batchSize := 2
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		pkt, err := e.writeAggregated(batch)
+		if err != nil {
+			return err
+		}
+
+		ret = append(ret, pkt)
+		batch = nil
+		batchSize = 2
+		return nil
+	}
+
+	for _, at := range ats {
+		if len(at.AU) > 0x1FFF {
+			return nil, fmt.Errorf("AU is too big")
+		}
 
-	frame, err := rpkt.Marshal()
+		// AU-headers-length (2 bytes) and AU-header (2 bytes) are added to the AU
+		if (4 + len(at.AU)) > e.payloadMaxSize {
+			err := flush()
+			if err != nil {
+				return nil, err
+			}
+
+			pkts, err := e.writeFragmented(at)
+			if err != nil {
+				return nil, err
+			}
+
+			ret = append(ret, pkts...)
+			continue
+		}
+
+		if (batchSize + 2 + len(at.AU)) > e.payloadMaxSize {
+			err := flush()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		batch = append(batch, at)
+		batchSize += 2 + len(at.AU)
+	}
+
+	err := flush()
 	if err != nil {
 		return nil, err
 	}
 
-	return frame, nil
+	return ret, nil
+}
+
+func (e *Encoder) writeAggregated(ats []*AUAndTimestamp) ([]byte, error) {
+	size := 2
+	for _, at := range ats {
+		size += 2 + len(at.AU)
+	}
+
+	payload := make([]byte, size)
+
+	// AU-headers-length
+	binary.BigEndian.PutUint16(payload, uint16(len(ats)*16))
+	pos := 2
+
+	// AU-headers
+	for _, at := range ats {
+		binary.BigEndian.PutUint16(payload[pos:], uint16(len(at.AU))<<3)
+		pos += 2
+	}
+
+	// AUs
+	for _, at := range ats {
+		pos += copy(payload[pos:], at.AU)
+	}
+
+	return e.newPacket(e.encodeTimestamp(ats[0].Timestamp), payload, true)
+}
+
+func (e *Encoder) writeFragmented(at *AUAndTimestamp) ([][]byte, error) {
+	au := at.AU
+	ts := e.encodeTimestamp(at.Timestamp)
+	var ret [][]byte
+
+	// each fragment has an AU-header containing the size of the entire AU
+	for len(au) > 0 {
+		le := e.payloadMaxSize - 4
+		if le > len(au) {
+			le = len(au)
+		}
+
+		payload := make([]byte, 4+le)
+		binary.BigEndian.PutUint16(payload, 16)
+		binary.BigEndian.PutUint16(payload[2:], uint16(len(at.AU))<<3)
+		copy(payload[4:], au[:le])
+		au = au[le:]
+
+		// the marker bit is set on the last fragment
+		pkt, err := e.newPacket(ts, payload, len(au) == 0)
+		if err != nil {
+			return nil, err
+		}
+
+		ret = append(ret, pkt)
+	}
+
+	return ret, nil
 }
@@ -12,9 +12,38 @@ type MPEG4AudioType int
 
 // standard MPEG-4 Audio types.
 const (
-	MPEG4AudioTypeAACLC MPEG4AudioType = 2
+	MPEG4AudioTypeAACMain MPEG4AudioType = 1
+	MPEG4AudioTypeAACLC   MPEG4AudioType = 2
+	MPEG4AudioTypeAACSSR  MPEG4AudioType = 3
+	MPEG4AudioTypeAACLTP  MPEG4AudioType = 4
 )
 
+var sampleRates = []int{
+	96000,
+	88200,
+	64000,
+	48000,
+	44100,
+	32000,
+	24000,
+	22050,
+	16000,
+	12000,
+	11025,
+	8000,
+	7350,
+}
+
+var channelCounts = []int{
+	1,
+	2,
+	3,
+	4,
+	5,
+	6,
+	8,
+}
+
 // MPEG4AudioConfig is a MPEG-4 Audio configuration.
 type MPEG4AudioConfig struct {
 	Type         MPEG4AudioType
@@ -43,7 +72,7 @@ func (c *MPEG4AudioConfig) Decode(byts []byte) error {
 	}
 
 	switch c.Type {
-	case MPEG4AudioTypeAACLC:
+	case MPEG4AudioTypeAACMain, MPEG4AudioTypeAACLC, MPEG4AudioTypeAACSSR, MPEG4AudioTypeAACLTP:
 	default:
 		return fmt.Errorf("unsupported type: %d", c.Type)
 	}
@@ -53,40 +82,16 @@ func (c *MPEG4AudioConfig) Decode(byts []byte) error {
 		return err
 	}
 
-	switch sampleRateIndex {
-	case 0:
-		c.SampleRate = 96000
-	case 1:
-		c.SampleRate = 88200
-	case 2:
-		c.SampleRate = 64000
-	case 3:
-		c.SampleRate = 48000
-	case 4:
-		c.SampleRate = 44100
-	case 5:
-		c.SampleRate = 32000
-	case 6:
-		c.SampleRate = 24000
-	case 7:
-		c.SampleRate = 22050
-	case 8:
-		c.SampleRate = 16000
-	case 9:
-		c.SampleRate = 12000
-	case 10:
-		c.SampleRate = 11025
-	case 11:
-		c.SampleRate = 8000
-	case 12:
-		c.SampleRate = 7350
-
-	case 15:
-		sampleRateIndex, err := r.ReadBits(24)
+	switch {
+	case sampleRateIndex <= 12:
+		c.SampleRate = sampleRates[sampleRateIndex]
+
+	case sampleRateIndex == 15:
+		sampleRate, err := r.ReadBits(24)
 		if err != nil {
 			return err
 		}
-		c.SampleRate = int(sampleRateIndex)
+		c.SampleRate = int(sampleRate)
 
 	default:
 		return fmt.Errorf("invalid sample rate index: %d", sampleRateIndex)
@@ -97,24 +102,12 @@ func (c *MPEG4AudioConfig) Decode(byts []byte) error {
 		return err
 	}
 
-	switch channelConfig {
-	case 0:
+	switch {
+	case channelConfig == 0:
 		return fmt.Errorf("not yet supported")
 
-	case 1:
-		c.ChannelCount = 1
-	case 2:
-		c.ChannelCount = 2
-	case 3:
-		c.ChannelCount = 3
-	case 4:
-		c.ChannelCount = 4
-	case 5:
-		c.ChannelCount = 5
-	case 6:
-		c.ChannelCount = 6
-	case 7:
-		c.ChannelCount = 8
+	case channelConfig <= 7:
+		c.ChannelCount = channelCounts[channelConfig-1]
 
 	default:
 		return fmt.Errorf("invalid channel configuration: %d", channelConfig)
@@ -122,3 +115,57 @@ func (c *MPEG4AudioConfig) Decode(byts []byte) error {
 
 	return nil
 }
+
+// Encode encodes an MPEG-4 Audio configuration.
+func (c MPEG4AudioConfig) Encode() ([]byte, error) {
+	switch c.Type {
+	case MPEG4AudioTypeAACMain, MPEG4AudioTypeAACLC, MPEG4AudioTypeAACSSR, MPEG4AudioTypeAACLTP:
+	default:
+		return nil, fmt.Errorf("unsupported type: %d", c.Type)
+	}
+
+	sampleRateIndex := 15
+	for i, v := range sampleRates {
+		if v == c.SampleRate {
+			sampleRateIndex = i
+			break
+		}
+	}
+
+	channelConfig := 0
+	for i, v := range channelCounts {
+		if v == c.ChannelCount {
+			channelConfig = i + 1
+			break
+		}
+	}
+	if channelConfig == 0 {
+		return nil, fmt.Errorf("unsupported channel count: %d", c.ChannelCount)
+	}
+
+	var buf bytes.Buffer
+	w := bitio.NewWriter(&buf)
+
+	w.TryWriteBits(uint64(c.Type), 5)
+
+	w.TryWriteBits(uint64(sampleRateIndex), 4)
+	if sampleRateIndex == 15 {
+		w.TryWriteBits(uint64(c.SampleRate), 24)
+	}
+
+	w.TryWriteBits(uint64(channelConfig), 4)
+
+	// GASpecificConfig: frameLengthFlag, dependsOnCoreCoder, extensionFlag
+	w.TryWriteBits(0, 3)
+
+	if w.TryError != nil {
+		return nil, w.TryError
+	}
+
+	err := w.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -50,3 +50,59 @@ func TestConfigDecode(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigEncode(t *testing.T) {
+	for _, ca := range []struct {
+		name string
+		dec  MPEG4AudioConfig
+		enc  []byte
+	}{
+		{
+			"aac-lc 48khz stereo",
+			MPEG4AudioConfig{
+				Type:         MPEG4AudioTypeAACLC,
+				SampleRate:   48000,
+				ChannelCount: 2,
+			},
+			[]byte{17, 144},
+		},
+		{
+			"aac-lc 44.1khz 5.1",
+			MPEG4AudioConfig{
+				Type:         MPEG4AudioTypeAACLC,
+				SampleRate:   44100,
+				ChannelCount: 6,
+			},
+			[]byte{0x12, 0x30},
+		},
+		{
+			"aac-main 8khz 7.1",
+			MPEG4AudioConfig{
+				Type:         MPEG4AudioTypeAACMain,
+				SampleRate:   8000,
+				ChannelCount: 8,
+			},
+			[]byte{0x0d, 0xb8},
+		},
+		{
+			"aac-lc custom sample rate mono",
+			MPEG4AudioConfig{
+				Type:         MPEG4AudioTypeAACLC,
+				SampleRate:   15000,
+				ChannelCount: 1,
+			},
+			[]byte{0x17, 0x80, 0x1d, 0x4c, 0x08},
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			enc, err := ca.dec.Encode()
+			require.NoError(t, err)
+			require.Equal(t, ca.enc, enc)
+
+			var dec MPEG4AudioConfig
+			err = dec.Decode(enc)
+			require.NoError(t, err)
+			require.Equal(t, ca.dec, dec)
+		})
+	}
+}
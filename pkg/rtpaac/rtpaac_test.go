@@ -1,6 +1,7 @@
 package rtpaac
 
 import (
+	"bytes"
 	"testing"
 	"time"
 
@@ -123,7 +124,7 @@ func TestEncode(t *testing.T) {
 			e := NewEncoder(96, 48000, &sequenceNumber, &ssrc, &initialTs)
 			enc, err := e.Encode(ca.dec)
 			require.NoError(t, err)
-			require.Equal(t, ca.enc, enc)
+			require.Equal(t, [][]byte{ca.enc}, enc)
 		})
 	}
 }
@@ -480,3 +481,96 @@ func TestDecodeAggregated(t *testing.T) {
 	}
 	require.Equal(t, exp, dec)
 }
+
+func TestEncodeFragmented(t *testing.T) {
+	sequenceNumber := uint16(0x44ed)
+	ssrc := uint32(0x9dbb7812)
+	initialTs := uint32(0x88776655)
+	e := NewEncoder(96, 48000, &sequenceNumber, &ssrc, &initialTs)
+	e.SetPayloadMaxSize(100)
+
+	au := bytes.Repeat([]byte{0x01, 0x02, 0x03, 0x04}, 60)
+
+	enc, err := e.Encode(&AUAndTimestamp{AU: au})
+	require.NoError(t, err)
+	require.Equal(t, 3, len(enc))
+
+	require.Equal(t, []byte{
+		0x80, 0x60, 0x44, 0xed, 0x88, 0x77, 0x66, 0x55,
+		0x9d, 0xbb, 0x78, 0x12, 0x00, 0x10, 0x07, 0x80,
+	}, enc[0][:16])
+	require.Equal(t, 12+100, len(enc[0]))
+	require.Equal(t, uint8(0x60), enc[1][1])
+	require.Equal(t, uint8(0xe0), enc[2][1])
+
+	d := NewDecoder(48000)
+
+	for _, pkt := range enc[:2] {
+		_, err := d.Decode(pkt)
+		require.Equal(t, ErrMorePacketsNeeded, err)
+	}
+
+	dec, err := d.Decode(enc[2])
+	require.NoError(t, err)
+	require.Equal(t, []*AUAndTimestamp{{AU: au}}, dec)
+}
+
+func TestEncodeAggregated(t *testing.T) {
+	e := NewEncoder(96, 48000, nil, nil, nil)
+	e.SetPayloadMaxSize(100)
+
+	ats := []*AUAndTimestamp{
+		{AU: bytes.Repeat([]byte{0x01}, 30)},
+		{AU: bytes.Repeat([]byte{0x02}, 30)},
+		{AU: bytes.Repeat([]byte{0x03}, 30)},
+		{AU: bytes.Repeat([]byte{0x04}, 200)},
+	}
+
+	enc, err := e.EncodeAggregated(ats)
+	require.NoError(t, err)
+	require.Equal(t, 4, len(enc))
+
+	d := NewDecoder(48000)
+
+	// first three AUs are aggregated
+	dec, err := d.Decode(enc[0])
+	require.NoError(t, err)
+	require.Equal(t, 3, len(dec))
+	for i := 0; i < 3; i++ {
+		require.Equal(t, ats[i].AU, dec[i].AU)
+	}
+
+	// last AU is fragmented
+	for _, pkt := range enc[1:3] {
+		_, err := d.Decode(pkt)
+		require.Equal(t, ErrMorePacketsNeeded, err)
+	}
+
+	dec, err = d.Decode(enc[3])
+	require.NoError(t, err)
+	require.Equal(t, 1, len(dec))
+	require.Equal(t, ats[3].AU, dec[0].AU)
+}
+
+func TestEncoderState(t *testing.T) {
+	sequenceNumber := uint16(0x44ed)
+	ssrc := uint32(0x9dbb7812)
+	initialTs := uint32(0x88776655)
+	e := NewEncoder(96, 48000, &sequenceNumber, &ssrc, &initialTs)
+
+	require.Equal(t, EncoderState{
+		SequenceNumber: 0x44ed,
+		SSRC:           0x9dbb7812,
+		Timestamp:      0x88776655,
+	}, e.State())
+
+	_, err := e.Encode(&AUAndTimestamp{
+		Timestamp: 20 * time.Millisecond,
+		AU:        []byte{0x01, 0x02, 0x03, 0x04},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, uint16(0x44ee), e.SequenceNumber())
+	require.Equal(t, uint32(0x9dbb7812), e.SSRC())
+	require.Equal(t, uint32(0x88776655+960), e.Timestamp())
+}
@@ -0,0 +1,268 @@
+package rtpdump
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/majoyz/gortsplib/pkg/base"
+)
+
+const (
+	pcapFileHeaderLen   = 24
+	pcapPacketHeaderLen = 16
+
+	pcapLinkTypeNull     = 0
+	pcapLinkTypeEthernet = 1
+	pcapLinkTypeRaw      = 101
+	pcapLinkTypeLinuxSLL = 113
+
+	etherTypeIPv4 = 0x0800
+	etherTypeIPv6 = 0x86DD
+	etherTypeVLAN = 0x8100
+
+	ipProtocolUDP = 17
+)
+
+// PCAPPacket is a packet read from a pcap file.
+type PCAPPacket struct {
+	Packet
+
+	// addresses of the UDP datagram that contained the packet.
+	Source      *net.UDPAddr
+	Destination *net.UDPAddr
+}
+
+// PCAPReader reads RTP and RTCP packets from a pcap file.
+// Packets are detected with heuristics, since pcap files do not contain
+// information about the protocol of UDP payloads:
+// UDP payloads with RTP version 2 are returned, all other packets are skipped.
+type PCAPReader struct {
+	r         *bufio.Reader
+	byteOrder binary.ByteOrder
+	nanosec   bool
+	linkType  uint32
+
+	start     time.Time
+	startRecv bool
+}
+
+// NewPCAPReader allocates a PCAPReader, and reads the header of the file.
+func NewPCAPReader(r io.Reader) (*PCAPReader, error) {
+	br := bufio.NewReader(r)
+
+	buf := make([]byte, pcapFileHeaderLen)
+	_, err := io.ReadFull(br, buf)
+	if err != nil {
+		return nil, err
+	}
+
+	pr := &PCAPReader{
+		r: br,
+	}
+
+	switch binary.LittleEndian.Uint32(buf) {
+	case 0xa1b2c3d4:
+		pr.byteOrder = binary.LittleEndian
+	case 0xd4c3b2a1:
+		pr.byteOrder = binary.BigEndian
+	case 0xa1b23c4d:
+		pr.byteOrder = binary.LittleEndian
+		pr.nanosec = true
+	case 0x4d3cb2a1:
+		pr.byteOrder = binary.BigEndian
+		pr.nanosec = true
+	default:
+		return nil, fmt.Errorf("invalid pcap magic number")
+	}
+
+	pr.linkType = pr.byteOrder.Uint32(buf[20:]) & 0xFFFF
+
+	switch pr.linkType {
+	case pcapLinkTypeNull, pcapLinkTypeEthernet, pcapLinkTypeRaw, pcapLinkTypeLinuxSLL:
+	default:
+		return nil, fmt.Errorf("unsupported link type (%d)", pr.linkType)
+	}
+
+	return pr, nil
+}
+
+// ReadPacket reads the next RTP or RTCP packet.
+// The offset of the packet is relative to the first packet of the file.
+// It returns io.EOF when the end of the file has been reached.
+func (r *PCAPReader) ReadPacket() (*PCAPPacket, error) {
+	for {
+		var header [pcapPacketHeaderLen]byte
+		_, err := io.ReadFull(r.r, header[:])
+		if err != nil {
+			return nil, err
+		}
+
+		sec := int64(r.byteOrder.Uint32(header[0:]))
+		frac := int64(r.byteOrder.Uint32(header[4:]))
+		if !r.nanosec {
+			frac *= 1000
+		}
+		ts := time.Unix(sec, frac)
+
+		data := make([]byte, r.byteOrder.Uint32(header[8:]))
+		_, err = io.ReadFull(r.r, data)
+		if err != nil {
+			if err == io.EOF {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+
+		if !r.startRecv {
+			r.startRecv = true
+			r.start = ts
+		}
+
+		pkt := r.decode(data)
+		if pkt == nil {
+			continue
+		}
+
+		pkt.Offset = ts.Sub(r.start)
+		return pkt, nil
+	}
+}
+
+func (r *PCAPReader) decode(data []byte) *PCAPPacket {
+	var etherType uint16
+
+	switch r.linkType {
+	case pcapLinkTypeNull:
+		if len(data) < 4 {
+			return nil
+		}
+		// the address family is in the byte order of the capturing host
+		family := binary.LittleEndian.Uint32(data)
+		if family > 0xFFFF {
+			family = binary.BigEndian.Uint32(data)
+		}
+		switch family {
+		case 2:
+			etherType = etherTypeIPv4
+		case 10, 24, 28, 30:
+			etherType = etherTypeIPv6
+		}
+		data = data[4:]
+
+	case pcapLinkTypeEthernet:
+		if len(data) < 14 {
+			return nil
+		}
+		etherType = binary.BigEndian.Uint16(data[12:])
+		data = data[14:]
+
+		if etherType == etherTypeVLAN {
+			if len(data) < 4 {
+				return nil
+			}
+			etherType = binary.BigEndian.Uint16(data[2:])
+			data = data[4:]
+		}
+
+	case pcapLinkTypeRaw:
+		if len(data) < 1 {
+			return nil
+		}
+		switch data[0] >> 4 {
+		case 4:
+			etherType = etherTypeIPv4
+		case 6:
+			etherType = etherTypeIPv6
+		}
+
+	case pcapLinkTypeLinuxSLL:
+		if len(data) < 16 {
+			return nil
+		}
+		etherType = binary.BigEndian.Uint16(data[14:])
+		data = data[16:]
+	}
+
+	var srcIP, dstIP net.IP
+
+	switch etherType {
+	case etherTypeIPv4:
+		if len(data) < 20 {
+			return nil
+		}
+		headerLen := int(data[0]&0x0F) * 4
+		totalLen := int(binary.BigEndian.Uint16(data[2:]))
+		fragment := binary.BigEndian.Uint16(data[6:]) & 0x3FFF
+		if data[9] != ipProtocolUDP || fragment != 0 ||
+			headerLen < 20 || totalLen < headerLen || totalLen > len(data) {
+			return nil
+		}
+		srcIP = append(net.IP(nil), data[12:16]...)
+		dstIP = append(net.IP(nil), data[16:20]...)
+		data = data[headerLen:totalLen]
+
+	case etherTypeIPv6:
+		if len(data) < 40 {
+			return nil
+		}
+		payloadLen := int(binary.BigEndian.Uint16(data[4:]))
+		if data[6] != ipProtocolUDP || 40+payloadLen > len(data) {
+			return nil
+		}
+		srcIP = append(net.IP(nil), data[8:24]...)
+		dstIP = append(net.IP(nil), data[24:40]...)
+		data = data[40 : 40+payloadLen]
+
+	default:
+		return nil
+	}
+
+	if len(data) < 8 {
+		return nil
+	}
+
+	srcPort := binary.BigEndian.Uint16(data[0:])
+	dstPort := binary.BigEndian.Uint16(data[2:])
+	udpLen := int(binary.BigEndian.Uint16(data[4:]))
+	if udpLen < 8 || udpLen > len(data) {
+		return nil
+	}
+	payload := data[8:udpLen]
+
+	streamType, ok := detectStreamType(payload)
+	if !ok {
+		return nil
+	}
+
+	return &PCAPPacket{
+		Packet: Packet{
+			StreamType: streamType,
+			Payload:    payload,
+		},
+		Source:      &net.UDPAddr{IP: srcIP, Port: int(srcPort)},
+		Destination: &net.UDPAddr{IP: dstIP, Port: int(dstPort)},
+	}
+}
+
+func detectStreamType(payload []byte) (base.StreamType, bool) {
+	if len(payload) < 4 || (payload[0]>>6) != 2 {
+		return 0, false
+	}
+
+	// RTCP packet types are between 200 (SR) and 207 (XR),
+	// that correspond to RTP payload types 72-79 with the marker bit,
+	// that are reserved in order to avoid this conflict.
+	if payload[1] >= 200 && payload[1] <= 207 {
+		return base.StreamTypeRTCP, true
+	}
+
+	if len(payload) < 12 {
+		return 0, false
+	}
+
+	return base.StreamTypeRTP, true
+}
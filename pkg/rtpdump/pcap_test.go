@@ -0,0 +1,106 @@
+package rtpdump
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/majoyz/gortsplib/pkg/base"
+)
+
+func pcapUDPFrame(protocol byte, payload []byte) []byte {
+	udp := make([]byte, 8)
+	binary.BigEndian.PutUint16(udp[0:], 5004)
+	binary.BigEndian.PutUint16(udp[2:], 6004)
+	binary.BigEndian.PutUint16(udp[4:], uint16(8+len(payload)))
+	udp = append(udp, payload...)
+
+	ip := []byte{
+		0x45, 0x00, 0x00, 0x00, 0x00, 0x00, 0x40, 0x00,
+		0x40, protocol, 0x00, 0x00, 192, 168, 1, 2,
+		192, 168, 1, 3,
+	}
+	binary.BigEndian.PutUint16(ip[2:], uint16(len(ip)+len(udp)))
+
+	eth := []byte{
+		0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x01, 0x02,
+		0x03, 0x04, 0x05, 0x07, 0x08, 0x00,
+	}
+
+	return append(append(eth, ip...), udp...)
+}
+
+func pcapFile(frames [][]byte) []byte {
+	var buf bytes.Buffer
+
+	header := make([]byte, 24)
+	binary.LittleEndian.PutUint32(header[0:], 0xa1b2c3d4)
+	binary.LittleEndian.PutUint16(header[4:], 2)
+	binary.LittleEndian.PutUint16(header[6:], 4)
+	binary.LittleEndian.PutUint32(header[16:], 65535)
+	binary.LittleEndian.PutUint32(header[20:], pcapLinkTypeEthernet)
+	buf.Write(header)
+
+	for i, frame := range frames {
+		header := make([]byte, 16)
+		binary.LittleEndian.PutUint32(header[0:], 1211321720)
+		binary.LittleEndian.PutUint32(header[4:], uint32(i*250000))
+		binary.LittleEndian.PutUint32(header[8:], uint32(len(frame)))
+		binary.LittleEndian.PutUint32(header[12:], uint32(len(frame)))
+		buf.Write(header)
+		buf.Write(frame)
+	}
+
+	return buf.Bytes()
+}
+
+func TestPCAPReader(t *testing.T) {
+	rtpPkt := []byte{
+		0x80, 0x60, 0x12, 0x34, 0x55, 0x66, 0x77, 0x88,
+		0x9d, 0xbb, 0x78, 0x12, 0x01, 0x02, 0x03, 0x04,
+	}
+	rtcpPkt := []byte{0x81, 0xc9, 0x00, 0x01, 0x9d, 0xbb, 0x78, 0x12}
+
+	r, err := NewPCAPReader(bytes.NewReader(pcapFile([][]byte{
+		pcapUDPFrame(ipProtocolUDP, rtpPkt),
+		pcapUDPFrame(6, rtpPkt),
+		pcapUDPFrame(ipProtocolUDP, []byte("not a RTP packet")),
+		pcapUDPFrame(ipProtocolUDP, rtcpPkt),
+	})))
+	require.NoError(t, err)
+
+	pkt, err := r.ReadPacket()
+	require.NoError(t, err)
+	require.Equal(t, &PCAPPacket{
+		Packet: Packet{
+			StreamType: base.StreamTypeRTP,
+			Payload:    rtpPkt,
+		},
+		Source:      &net.UDPAddr{IP: net.IP{192, 168, 1, 2}, Port: 5004},
+		Destination: &net.UDPAddr{IP: net.IP{192, 168, 1, 3}, Port: 6004},
+	}, pkt)
+
+	pkt, err = r.ReadPacket()
+	require.NoError(t, err)
+	require.Equal(t, 750*time.Millisecond, pkt.Offset)
+	require.Equal(t, base.StreamTypeRTCP, pkt.StreamType)
+	require.Equal(t, rtcpPkt, pkt.Payload)
+
+	_, err = r.ReadPacket()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestPCAPReaderErrors(t *testing.T) {
+	_, err := NewPCAPReader(bytes.NewReader(make([]byte, 24)))
+	require.EqualError(t, err, "invalid pcap magic number")
+
+	byts := pcapFile(nil)
+	byts[20] = 105
+	_, err = NewPCAPReader(bytes.NewReader(byts))
+	require.EqualError(t, err, "unsupported link type (105)")
+}
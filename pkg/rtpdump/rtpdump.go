@@ -0,0 +1,187 @@
+// Package rtpdump contains utilities to read and write RTP and RTCP packets
+// in the rtpdump format of rtptools, and to read them from pcap files.
+package rtpdump
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/majoyz/gortsplib/pkg/base"
+)
+
+const (
+	fileMagic       = "#!rtpplay1.0"
+	fileHeaderLen   = 16
+	packetHeaderLen = 8
+)
+
+// Header is the header of a rtpdump file.
+type Header struct {
+	// time of the beginning of the recording.
+	Start time.Time
+
+	// address and port from which packets have been received.
+	Source net.IP
+	Port   uint16
+}
+
+// Packet is a packet of a rtpdump file.
+type Packet struct {
+	// time elapsed since the beginning of the recording,
+	// with millisecond precision.
+	Offset time.Duration
+
+	// type of the packet.
+	StreamType base.StreamType
+
+	// content of the packet.
+	Payload []byte
+}
+
+// Writer writes packets in the rtpdump format.
+type Writer struct {
+	w   io.Writer
+	buf []byte
+}
+
+// NewWriter allocates a Writer, and writes the header of the file.
+func NewWriter(w io.Writer, h Header) (*Writer, error) {
+	source := h.Source.To4()
+	if source == nil {
+		source = net.IPv4zero.To4()
+	}
+
+	_, err := io.WriteString(w, fmt.Sprintf(fileMagic+" %s/%d\n", source, h.Port))
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, fileHeaderLen)
+	binary.BigEndian.PutUint32(buf[0:], uint32(h.Start.Unix()))
+	binary.BigEndian.PutUint32(buf[4:], uint32(h.Start.Nanosecond()/1000))
+	copy(buf[8:], source)
+	binary.BigEndian.PutUint16(buf[12:], h.Port)
+
+	_, err = w.Write(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Writer{
+		w: w,
+	}, nil
+}
+
+// WritePacket writes a packet.
+func (w *Writer) WritePacket(pkt Packet) error {
+	le := packetHeaderLen + len(pkt.Payload)
+	if le > 0xFFFF {
+		return fmt.Errorf("packet is too big (%d)", len(pkt.Payload))
+	}
+
+	if pkt.Offset < 0 {
+		return fmt.Errorf("negative offset (%v)", pkt.Offset)
+	}
+
+	w.buf = append(w.buf[:0], make([]byte, packetHeaderLen)...)
+	binary.BigEndian.PutUint16(w.buf[0:], uint16(le))
+
+	// the length of the packet is zero for RTCP packets
+	if pkt.StreamType == base.StreamTypeRTP {
+		binary.BigEndian.PutUint16(w.buf[2:], uint16(len(pkt.Payload)))
+	}
+
+	binary.BigEndian.PutUint32(w.buf[4:], uint32(pkt.Offset/time.Millisecond))
+	w.buf = append(w.buf, pkt.Payload...)
+
+	_, err := w.w.Write(w.buf)
+	return err
+}
+
+// Reader reads packets in the rtpdump format.
+type Reader struct {
+	r      *bufio.Reader
+	header Header
+}
+
+// NewReader allocates a Reader, and reads the header of the file.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+
+	line, err := br.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.HasPrefix(line, fileMagic) {
+		return nil, fmt.Errorf("invalid file header")
+	}
+
+	buf := make([]byte, fileHeaderLen)
+	_, err = io.ReadFull(br, buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Reader{
+		r: br,
+		header: Header{
+			Start: time.Unix(int64(binary.BigEndian.Uint32(buf[0:])),
+				int64(binary.BigEndian.Uint32(buf[4:]))*1000),
+			Source: net.IP(buf[8:12]),
+			Port:   binary.BigEndian.Uint16(buf[12:]),
+		},
+	}, nil
+}
+
+// Header returns the header of the file.
+func (r *Reader) Header() Header {
+	return r.header
+}
+
+// ReadPacket reads a packet.
+// It returns io.EOF when the end of the file has been reached.
+func (r *Reader) ReadPacket() (*Packet, error) {
+	var buf [packetHeaderLen]byte
+	_, err := io.ReadFull(r.r, buf[:])
+	if err != nil {
+		return nil, err
+	}
+
+	le := int(binary.BigEndian.Uint16(buf[0:]))
+	if le < packetHeaderLen {
+		return nil, fmt.Errorf("invalid packet length (%d)", le)
+	}
+
+	plen := binary.BigEndian.Uint16(buf[2:])
+
+	pkt := &Packet{
+		Offset:  time.Duration(binary.BigEndian.Uint32(buf[4:])) * time.Millisecond,
+		Payload: make([]byte, le-packetHeaderLen),
+	}
+
+	_, err = io.ReadFull(r.r, pkt.Payload)
+	if err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	// the length of the packet is zero for RTCP packets
+	if plen == 0 {
+		pkt.StreamType = base.StreamTypeRTCP
+	} else {
+		pkt.StreamType = base.StreamTypeRTP
+		if int(plen) < len(pkt.Payload) {
+			pkt.Payload = pkt.Payload[:plen]
+		}
+	}
+
+	return pkt, nil
+}
@@ -0,0 +1,152 @@
+package rtpdump
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/majoyz/gortsplib/pkg/base"
+)
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+
+	w, err := NewWriter(&buf, Header{
+		Start:  time.Date(2008, 05, 20, 22, 15, 20, 500000000, time.UTC),
+		Source: net.ParseIP("192.168.1.2"),
+		Port:   5004,
+	})
+	require.NoError(t, err)
+
+	err = w.WritePacket(Packet{
+		Offset:     1500 * time.Millisecond,
+		StreamType: base.StreamTypeRTP,
+		Payload:    []byte{0x80, 0x60, 0x01, 0x02},
+	})
+	require.NoError(t, err)
+
+	err = w.WritePacket(Packet{
+		Offset:     2 * time.Second,
+		StreamType: base.StreamTypeRTCP,
+		Payload:    []byte{0x80, 0xc8, 0x00, 0x00},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, append([]byte("#!rtpplay1.0 192.168.1.2/5004\n"),
+		0x48, 0x33, 0x4d, 0x78, 0x00, 0x07, 0xa1, 0x20,
+		0xc0, 0xa8, 0x01, 0x02, 0x13, 0x8c, 0x00, 0x00,
+
+		0x00, 0x0c, 0x00, 0x04, 0x00, 0x00, 0x05, 0xdc,
+		0x80, 0x60, 0x01, 0x02,
+
+		0x00, 0x0c, 0x00, 0x00, 0x00, 0x00, 0x07, 0xd0,
+		0x80, 0xc8, 0x00, 0x00,
+	), buf.Bytes())
+}
+
+func TestWriterErrors(t *testing.T) {
+	w, err := NewWriter(&bytes.Buffer{}, Header{})
+	require.NoError(t, err)
+
+	err = w.WritePacket(Packet{
+		Payload: make([]byte, 0xFFFF),
+	})
+	require.EqualError(t, err, "packet is too big (65535)")
+
+	err = w.WritePacket(Packet{
+		Offset: -1,
+	})
+	require.EqualError(t, err, "negative offset (-1ns)")
+}
+
+func TestReader(t *testing.T) {
+	var buf bytes.Buffer
+
+	header := Header{
+		Start:  time.Date(2008, 05, 20, 22, 15, 20, 500000000, time.UTC),
+		Source: net.ParseIP("192.168.1.2"),
+		Port:   5004,
+	}
+
+	pkts := []Packet{
+		{
+			Offset:     1500 * time.Millisecond,
+			StreamType: base.StreamTypeRTP,
+			Payload:    []byte{0x80, 0x60, 0x01, 0x02},
+		},
+		{
+			Offset:     2 * time.Second,
+			StreamType: base.StreamTypeRTCP,
+			Payload:    []byte{0x80, 0xc8, 0x00, 0x00},
+		},
+	}
+
+	w, err := NewWriter(&buf, header)
+	require.NoError(t, err)
+
+	for _, pkt := range pkts {
+		err := w.WritePacket(pkt)
+		require.NoError(t, err)
+	}
+
+	r, err := NewReader(&buf)
+	require.NoError(t, err)
+
+	require.True(t, header.Start.Equal(r.Header().Start))
+	require.True(t, header.Source.Equal(r.Header().Source))
+	require.Equal(t, header.Port, r.Header().Port)
+
+	for _, pkt := range pkts {
+		recv, err := r.ReadPacket()
+		require.NoError(t, err)
+		require.Equal(t, pkt, *recv)
+	}
+
+	_, err = r.ReadPacket()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestReaderErrors(t *testing.T) {
+	for _, ca := range []struct {
+		name string
+		byts []byte
+		err  string
+	}{
+		{
+			"invalid header",
+			[]byte("#!rtpplay2.0 0.0.0.0/0\n"),
+			"invalid file header",
+		},
+		{
+			"missing binary header",
+			[]byte("#!rtpplay1.0 0.0.0.0/0\n\x00\x00"),
+			"unexpected EOF",
+		},
+		{
+			"invalid packet length",
+			append([]byte("#!rtpplay1.0 0.0.0.0/0\n"),
+				0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+				0x00, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00),
+			"invalid packet length (4)",
+		},
+		{
+			"truncated packet",
+			append([]byte("#!rtpplay1.0 0.0.0.0/0\n"),
+				0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+				0x00, 0x0c, 0x00, 0x04, 0x00, 0x00, 0x00, 0x00, 0x80),
+			"unexpected EOF",
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			r, err := NewReader(bytes.NewReader(ca.byts))
+			if err == nil {
+				_, err = r.ReadPacket()
+			}
+			require.EqualError(t, err, ca.err)
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package rtph264
 
 import (
+	"bytes"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -9,6 +10,8 @@ import (
 	"time"
 
 	"github.com/pion/rtp"
+
+	"github.com/majoyz/gortsplib/pkg/rtpunwrapper"
 )
 
 // ErrMorePacketsNeeded is returned by Decoder.Read when more packets are needed.
@@ -36,129 +39,184 @@ const (
 type Decoder struct {
 	initialTs    uint32
 	initialTsSet bool
+	unwrapper    *rtpunwrapper.Unwrapper
 
 	// for Decode() and FU-A
 	state         decoderState
 	fragmentedBuf []byte
 
+	// for DecodeUntilMarker()
+	naluBuffer []*NALUAndTimestamp
+
+	// for SPS and PPS injection
+	sps       []byte
+	pps       []byte
+	auTs      time.Duration
+	auTsSet   bool
+	auHasSPS  bool
+	auHasPPS  bool
+	injecting bool
+
+	// for DecodeAccessUnits()
+	auBuffer      [][]byte
+	auBufferTs    time.Duration
+	auBufferRTPTs uint32
+
 	// for Read()
 	nalusQueue []*NALUAndTimestamp
+
+	// for ReadAccessUnit()
+	ausQueue []*AccessUnit
 }
 
 // NewDecoder allocates a Decoder.
 func NewDecoder() *Decoder {
-	return &Decoder{}
+	return &Decoder{
+		unwrapper: rtpunwrapper.New(),
+	}
+}
+
+// SetSPSPPS enables the injection of SPS and PPS before IDR NALUs that are not
+// preceded by them in the same access unit, as required by most decoders.
+// The initial SPS and PPS are usually taken from the SDP, and are
+// replaced by the ones received in-band.
+func (d *Decoder) SetSPSPPS(sps []byte, pps []byte) {
+	d.sps = sps
+	d.pps = pps
+	d.injecting = true
 }
 
 func (d *Decoder) decodeTimestamp(ts uint32) time.Duration {
-	return (time.Duration(ts) - time.Duration(d.initialTs)) * time.Second / rtpClockRate
+	// timestamps are unwrapped in order to avoid jumps when they overflow,
+	// and the conversion is split to avoid overflowing time.Duration.
+	v := d.unwrapper.Unwrap(ts) - int64(d.initialTs)
+	return time.Duration(v/rtpClockRate)*time.Second +
+		time.Duration(v%rtpClockRate)*time.Second/rtpClockRate
 }
 
 // Decode decodes NALUs from RTP/H264 packets.
 // It can return:
 // * no NALUs and ErrMorePacketsNeeded
 // * one NALU (in case of FU-A)
-// * multiple NALUs (in case of STAP-A)
+// * multiple NALUs (in case of STAP-A, or of NALUs separated by start codes)
 func (d *Decoder) Decode(byts []byte) ([]*NALUAndTimestamp, error) {
-	switch d.state {
-	case decoderStateInitial:
-		pkt := rtp.Packet{}
-		err := pkt.Unmarshal(byts)
-		if err != nil {
-			return nil, err
-		}
+	nalus, _, err := d.decode(byts)
+	return nalus, err
+}
 
-		if !d.initialTsSet {
-			d.initialTsSet = true
-			d.initialTs = pkt.Timestamp
+// DecodeUntilMarker decodes NALUs from RTP/H264 packets and puts them in a buffer.
+// When a packet has the marker flag, meaning that all the NALUs of an
+// access unit have been received, the buffer is returned.
+// Otherwise, ErrMorePacketsNeeded is returned.
+func (d *Decoder) DecodeUntilMarker(byts []byte) ([]*NALUAndTimestamp, error) {
+	nalus, pkt, err := d.decode(byts)
+	if err != nil {
+		if err != ErrMorePacketsNeeded {
+			d.naluBuffer = nil
 		}
+		return nil, err
+	}
 
-		typ := NALUType(pkt.Payload[0] & 0x1F)
+	d.naluBuffer = append(d.naluBuffer, nalus...)
 
-		switch typ {
-		case NALUTypeNonIDR, NALUTypeDataPartitionA, NALUTypeDataPartitionB,
-			NALUTypeDataPartitionC, NALUTypeIDR, NALUTypeSei, NALUTypeSPS,
-			NALUTypePPS, NALUTypeAccessUnitDelimiter, NALUTypeEndOfSequence,
-			NALUTypeEndOfStream, NALUTypeFillerData, NALUTypeSPSExtension,
-			NALUTypePrefix, NALUTypeSubsetSPS, NALUTypeReserved16, NALUTypeReserved17,
-			NALUTypeReserved18, NALUTypeSliceLayerWithoutPartitioning,
-			NALUTypeSliceExtension, NALUTypeSliceExtensionDepth, NALUTypeReserved22,
-			NALUTypeReserved23:
-			return []*NALUAndTimestamp{{
-				NALU:      pkt.Payload,
-				Timestamp: d.decodeTimestamp(pkt.Timestamp),
-			}}, nil
+	if !pkt.Marker {
+		return nil, ErrMorePacketsNeeded
+	}
 
-		case NALUTypeStapA:
-			var ret []*NALUAndTimestamp
-			pkt.Payload = pkt.Payload[1:]
+	ret := d.naluBuffer
+	d.naluBuffer = nil
 
-			for len(pkt.Payload) > 0 {
-				if len(pkt.Payload) < 2 {
-					return nil, fmt.Errorf("Invalid STAP-A packet")
-				}
+	return ret, nil
+}
 
-				size := binary.BigEndian.Uint16(pkt.Payload)
-				pkt.Payload = pkt.Payload[2:]
+// DecodeAccessUnits decodes NALUs from RTP/H264 packets and groups them
+// into access units.
+// An access unit is complete when a packet has the marker flag, or when a
+// packet with a different timestamp is received, in case the marker flag
+// has been lost or is not set by the sender.
+// It can return:
+// * no access units and ErrMorePacketsNeeded
+// * one access unit
+// * two access units (in case the previous one lacks the marker flag)
+func (d *Decoder) DecodeAccessUnits(byts []byte) ([]*AccessUnit, error) {
+	nalus, pkt, err := d.decode(byts)
+	if err != nil {
+		if err != ErrMorePacketsNeeded {
+			d.auBuffer = nil
+		}
+		return nil, err
+	}
 
-				// avoid final padding
-				if size == 0 {
-					break
-				}
+	var ret []*AccessUnit
 
-				if int(size) > len(pkt.Payload) {
-					return nil, fmt.Errorf("Invalid STAP-A packet")
-				}
+	for _, nt := range nalus {
+		if d.auBuffer != nil && nt.Timestamp != d.auBufferTs {
+			ret = append(ret, &AccessUnit{
+				Timestamp:    d.auBufferTs,
+				RTPTimestamp: d.auBufferRTPTs,
+				NALUs:        d.auBuffer,
+			})
+			d.auBuffer = nil
+		}
 
-				ret = append(ret, &NALUAndTimestamp{
-					NALU:      pkt.Payload[:size],
-					Timestamp: d.decodeTimestamp(pkt.Timestamp),
-				})
-				pkt.Payload = pkt.Payload[size:]
-			}
+		if d.auBuffer == nil {
+			d.auBufferTs = nt.Timestamp
+			d.auBufferRTPTs = pkt.Timestamp
+		}
+		d.auBuffer = append(d.auBuffer, nt.NALU)
+	}
 
-			if len(ret) == 0 {
-				return nil, fmt.Errorf("STAP-A packet doesn't contain any NALU")
-			}
+	if pkt.Marker && d.auBuffer != nil {
+		ret = append(ret, &AccessUnit{
+			Timestamp:    d.auBufferTs,
+			RTPTimestamp: d.auBufferRTPTs,
+			NALUs:        d.auBuffer,
+		})
+		d.auBuffer = nil
+	}
 
-			return ret, nil
+	if ret == nil {
+		return nil, ErrMorePacketsNeeded
+	}
 
-		case NALUTypeFuA: // first packet of a fragmented NALU
-			start := pkt.Payload[1] >> 7
-			if start != 1 {
-				return nil, fmt.Errorf("first NALU does not contain the start bit")
-			}
+	return ret, nil
+}
 
-			nri := (pkt.Payload[0] >> 5) & 0x03
-			typ := pkt.Payload[1] & 0x1F
-			d.fragmentedBuf = append([]byte{(nri << 5) | typ}, pkt.Payload[2:]...)
+func (d *Decoder) decode(byts []byte) ([]*NALUAndTimestamp, *rtp.Packet, error) {
+	pkt := rtp.Packet{}
+	err := pkt.Unmarshal(byts)
+	if err != nil {
+		d.state = decoderStateInitial
+		return nil, nil, err
+	}
 
-			d.state = decoderStateReadingFragmented
-			return nil, ErrMorePacketsNeeded
+	if !d.initialTsSet {
+		d.initialTsSet = true
+		d.initialTs = pkt.Timestamp
+	}
 
-		case NALUTypeStapB, NALUTypeMtap16, NALUTypeMtap24, NALUTypeFuB:
-			return nil, fmt.Errorf("NALU type not supported (%v)", typ)
-		}
+	nalus, err := d.decodePacket(&pkt)
+	if err != nil {
+		return nil, nil, err
+	}
 
-		return nil, fmt.Errorf("invalid NALU type (%v)", typ)
+	return d.processNALUs(nalus), &pkt, nil
+}
 
-	default: // decoderStateReadingFragmented
-		pkt := rtp.Packet{}
-		err := pkt.Unmarshal(byts)
-		if err != nil {
-			d.state = decoderStateInitial
-			return nil, err
-		}
+func (d *Decoder) decodePacket(pkt *rtp.Packet) ([]*NALUAndTimestamp, error) {
+	if len(pkt.Payload) < 1 {
+		d.state = decoderStateInitial
+		return nil, fmt.Errorf("payload is too short")
+	}
 
-		if len(pkt.Payload) < 2 {
-			d.state = decoderStateInitial
-			return nil, fmt.Errorf("Invalid FU-A packet")
-		}
+	typ := NALUType(pkt.Payload[0] & 0x1F)
 
-		typ := NALUType(pkt.Payload[0] & 0x1F)
-		if typ != NALUTypeFuA {
+	if d.state == decoderStateReadingFragmented {
+		// a packet that is not the continuation of the fragmented NALU means that
+		// the final fragment has been lost. The fragmented NALU is discarded.
+		if typ != NALUTypeFuA || len(pkt.Payload) < 2 || (pkt.Payload[1]>>7) == 1 {
 			d.state = decoderStateInitial
-			return nil, fmt.Errorf("non-starting NALU is not FU-A")
+			return d.decodePacket(pkt)
 		}
 
 		end := (pkt.Payload[1] >> 6) & 0x01
@@ -175,6 +233,194 @@ func (d *Decoder) Decode(byts []byte) ([]*NALUAndTimestamp, error) {
 			Timestamp: d.decodeTimestamp(pkt.Timestamp),
 		}}, nil
 	}
+
+	// some non-compliant encoders send NALUs with annex-B start codes,
+	// that are split by processNALUs()
+	if hasStartCode(pkt.Payload) {
+		return []*NALUAndTimestamp{{
+			NALU:      pkt.Payload,
+			Timestamp: d.decodeTimestamp(pkt.Timestamp),
+		}}, nil
+	}
+
+	switch typ {
+	case NALUTypeNonIDR, NALUTypeDataPartitionA, NALUTypeDataPartitionB,
+		NALUTypeDataPartitionC, NALUTypeIDR, NALUTypeSei, NALUTypeSPS,
+		NALUTypePPS, NALUTypeAccessUnitDelimiter, NALUTypeEndOfSequence,
+		NALUTypeEndOfStream, NALUTypeFillerData, NALUTypeSPSExtension,
+		NALUTypePrefix, NALUTypeSubsetSPS, NALUTypeReserved16, NALUTypeReserved17,
+		NALUTypeReserved18, NALUTypeSliceLayerWithoutPartitioning,
+		NALUTypeSliceExtension, NALUTypeSliceExtensionDepth, NALUTypeReserved22,
+		NALUTypeReserved23:
+		return []*NALUAndTimestamp{{
+			NALU:      pkt.Payload,
+			Timestamp: d.decodeTimestamp(pkt.Timestamp),
+		}}, nil
+
+	case NALUTypeStapA:
+		var ret []*NALUAndTimestamp
+		payload := pkt.Payload[1:]
+
+		for len(payload) > 0 {
+			if len(payload) < 2 {
+				return nil, fmt.Errorf("Invalid STAP-A packet")
+			}
+
+			size := binary.BigEndian.Uint16(payload)
+			payload = payload[2:]
+
+			// avoid final padding
+			if size == 0 {
+				break
+			}
+
+			if int(size) > len(payload) {
+				return nil, fmt.Errorf("Invalid STAP-A packet")
+			}
+
+			ret = append(ret, &NALUAndTimestamp{
+				NALU:      payload[:size],
+				Timestamp: d.decodeTimestamp(pkt.Timestamp),
+			})
+			payload = payload[size:]
+		}
+
+		if len(ret) == 0 {
+			return nil, fmt.Errorf("STAP-A packet doesn't contain any NALU")
+		}
+
+		return ret, nil
+
+	case NALUTypeFuA: // first packet of a fragmented NALU
+		if len(pkt.Payload) < 2 {
+			return nil, fmt.Errorf("Invalid FU-A packet")
+		}
+
+		start := pkt.Payload[1] >> 7
+		if start != 1 {
+			return nil, fmt.Errorf("first NALU does not contain the start bit")
+		}
+
+		nri := (pkt.Payload[0] >> 5) & 0x03
+		typ := pkt.Payload[1] & 0x1F
+		nalu := append([]byte{(nri << 5) | typ}, pkt.Payload[2:]...)
+
+		// some encoders send NALUs with start and end bits in the same packet
+		end := (pkt.Payload[1] >> 6) & 0x01
+		if end == 1 {
+			return []*NALUAndTimestamp{{
+				NALU:      nalu,
+				Timestamp: d.decodeTimestamp(pkt.Timestamp),
+			}}, nil
+		}
+
+		d.fragmentedBuf = nalu
+		d.state = decoderStateReadingFragmented
+		return nil, ErrMorePacketsNeeded
+
+	case NALUTypeStapB, NALUTypeMtap16, NALUTypeMtap24, NALUTypeFuB:
+		return nil, fmt.Errorf("NALU type not supported (%v)", typ)
+	}
+
+	return nil, fmt.Errorf("invalid NALU type (%v)", typ)
+}
+
+// processNALUs splits NALUs that contain start codes and injects SPS and PPS.
+func (d *Decoder) processNALUs(nalus []*NALUAndTimestamp) []*NALUAndTimestamp {
+	var split []*NALUAndTimestamp
+	for _, nt := range nalus {
+		if !hasStartCode(nt.NALU) {
+			split = append(split, nt)
+			continue
+		}
+
+		for _, nalu := range splitAnnexB(nt.NALU) {
+			split = append(split, &NALUAndTimestamp{
+				NALU:      nalu,
+				Timestamp: nt.Timestamp,
+			})
+		}
+	}
+
+	if !d.injecting {
+		return split
+	}
+
+	var ret []*NALUAndTimestamp
+
+	for _, nt := range split {
+		if !d.auTsSet || nt.Timestamp != d.auTs {
+			d.auTsSet = true
+			d.auTs = nt.Timestamp
+			d.auHasSPS = false
+			d.auHasPPS = false
+		}
+
+		switch NALUType(nt.NALU[0] & 0x1F) {
+		case NALUTypeSPS:
+			d.sps = append([]byte(nil), nt.NALU...)
+			d.auHasSPS = true
+
+		case NALUTypePPS:
+			d.pps = append([]byte(nil), nt.NALU...)
+			d.auHasPPS = true
+
+		case NALUTypeIDR:
+			if !d.auHasSPS && d.sps != nil {
+				ret = append(ret, &NALUAndTimestamp{
+					NALU:      d.sps,
+					Timestamp: nt.Timestamp,
+				})
+				d.auHasSPS = true
+			}
+
+			if !d.auHasPPS && d.pps != nil {
+				ret = append(ret, &NALUAndTimestamp{
+					NALU:      d.pps,
+					Timestamp: nt.Timestamp,
+				})
+				d.auHasPPS = true
+			}
+		}
+
+		ret = append(ret, nt)
+	}
+
+	return ret
+}
+
+func hasStartCode(buf []byte) bool {
+	return bytes.HasPrefix(buf, []byte{0x00, 0x00, 0x01}) ||
+		bytes.HasPrefix(buf, []byte{0x00, 0x00, 0x00, 0x01})
+}
+
+// splitAnnexB splits a buffer that contains NALUs separated by start codes.
+func splitAnnexB(buf []byte) [][]byte {
+	var ret [][]byte
+	start := 0
+	zeros := 0
+
+	for i, b := range buf {
+		if b == 0x00 {
+			zeros++
+			continue
+		}
+
+		if b == 0x01 && zeros >= 2 {
+			if nalu := buf[start : i-zeros]; len(nalu) > 0 {
+				ret = append(ret, nalu)
+			}
+			start = i + 1
+		}
+
+		zeros = 0
+	}
+
+	if nalu := buf[start : len(buf)-zeros]; len(nalu) > 0 {
+		ret = append(ret, nalu)
+	}
+
+	return ret
 }
 
 // Read reads RTP/H264 packets from a reader until a NALU is decoded.
@@ -207,6 +453,39 @@ func (d *Decoder) Read(r io.Reader) (*NALUAndTimestamp, error) {
 	}
 }
 
+// ReadAccessUnit reads RTP/H264 packets from a reader until an access unit
+// is decoded.
+func (d *Decoder) ReadAccessUnit(r io.Reader) (*AccessUnit, error) {
+	if len(d.ausQueue) > 0 {
+		au := d.ausQueue[0]
+		d.ausQueue = d.ausQueue[1:]
+		return au, nil
+	}
+
+	for {
+		// NALUs of an access unit can point to different packets,
+		// therefore a new buffer is allocated for each packet
+		buf := make([]byte, 2048)
+		n, err := r.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+
+		aus, err := d.DecodeAccessUnits(buf[:n])
+		if err != nil {
+			if err == ErrMorePacketsNeeded {
+				continue
+			}
+			return nil, err
+		}
+
+		au := aus[0]
+		d.ausQueue = aus[1:]
+
+		return au, nil
+	}
+}
+
 // ReadSPSPPS reads RTP/H264 packets from a reader until SPS and PPS are
 // found, and returns them.
 func (d *Decoder) ReadSPSPPS(r io.Reader) ([]byte, []byte, error) {
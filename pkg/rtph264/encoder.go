@@ -1,7 +1,10 @@
 package rtph264
 
 import (
+	"encoding/binary"
+	"fmt"
 	"math/rand"
+	"sync"
 	"time"
 
 	"github.com/pion/rtp"
@@ -19,6 +22,12 @@ type Encoder struct {
 	sequenceNumber uint16
 	ssrc           uint32
 	initialTs      uint32
+	payloadMaxSize int
+
+	// for State()
+	mutex     sync.Mutex
+	lastTs    uint32
+	lastTsSet bool
 }
 
 // NewEncoder allocates an Encoder.
@@ -46,41 +55,200 @@ func NewEncoder(payloadType uint8,
 			}
 			return rand.Uint32()
 		}(),
+		payloadMaxSize: rtpPayloadMaxSize,
 	}
 }
 
+// SetPayloadMaxSize sets the maximum size of RTP payloads.
+// NALUs bigger than this are fragmented.
+// It defaults to 1460, that is suitable for a 1500 bytes MTU.
+func (e *Encoder) SetPayloadMaxSize(v int) {
+	e.payloadMaxSize = v
+}
+
 func (e *Encoder) encodeTimestamp(ts time.Duration) uint32 {
 	return e.initialTs + uint32(ts.Seconds()*rtpClockRate)
 }
 
-// Encode encodes a NALU into RTP/H264 packets.
-// It always returns at least one RTP/H264 packet.
-func (e *Encoder) Encode(nt *NALUAndTimestamp) ([][]byte, error) {
-	// if the NALU fits into a single RTP packet, use a single payload
-	if len(nt.NALU) < rtpPayloadMaxSize {
-		return e.writeSingle(nt)
+// EncoderState is a snapshot of the state of an Encoder.
+type EncoderState struct {
+	// sequence number of the next packet.
+	SequenceNumber uint16
+
+	// SSRC of the packets.
+	SSRC uint32
+
+	// timestamp of the last packet, or the initial timestamp
+	// if no packets have been encoded yet.
+	Timestamp uint32
+}
+
+// SequenceNumber returns the sequence number of the next packet.
+func (e *Encoder) SequenceNumber() uint16 {
+	return e.State().SequenceNumber
+}
+
+// SSRC returns the SSRC of the packets.
+func (e *Encoder) SSRC() uint32 {
+	return e.ssrc
+}
+
+// Timestamp returns the timestamp of the last packet, or the initial
+// timestamp if no packets have been encoded yet.
+func (e *Encoder) Timestamp() uint32 {
+	return e.State().Timestamp
+}
+
+// State returns the sequence number, the SSRC and the timestamp at once.
+// It can be called while packets are being encoded by another routine,
+// for instance in order to fill the RTP-Info header of a PLAY response.
+func (e *Encoder) State() EncoderState {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	ts := e.initialTs
+	if e.lastTsSet {
+		ts = e.lastTs
 	}
 
-	// otherwise, split the NALU into multiple fragmentation payloads
-	return e.writeFragmented(nt)
+	return EncoderState{
+		SequenceNumber: e.sequenceNumber,
+		SSRC:           e.ssrc,
+		Timestamp:      ts,
+	}
 }
 
-func (e *Encoder) writeSingle(nt *NALUAndTimestamp) ([][]byte, error) {
+func (e *Encoder) newPacket(ts uint32, payload []byte, marker bool) ([]byte, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
 	rpkt := rtp.Packet{
 		Header: rtp.Header{
 			Version:        rtpVersion,
 			PayloadType:    e.payloadType,
 			SequenceNumber: e.sequenceNumber,
-			Timestamp:      e.encodeTimestamp(nt.Timestamp),
+			Timestamp:      ts,
 			SSRC:           e.ssrc,
+			Marker:         marker,
 		},
-		Payload: nt.NALU,
+		Payload: payload,
 	}
 	e.sequenceNumber++
+	e.lastTs = ts
+	e.lastTsSet = true
+
+	return rpkt.Marshal()
+}
+
+// Encode encodes a NALU into RTP/H264 packets.
+// It always returns at least one RTP/H264 packet.
+func (e *Encoder) Encode(nt *NALUAndTimestamp) ([][]byte, error) {
+	if len(nt.NALU) < 1 {
+		return nil, fmt.Errorf("NALU is too short")
+	}
+
+	// if the NALU fits into a single RTP packet, use a single payload
+	if len(nt.NALU) < e.payloadMaxSize {
+		return e.writeSingle(nt)
+	}
+
+	// otherwise, split the NALU into multiple fragmentation payloads
+	return e.writeFragmented(nt)
+}
+
+// EncodeAggregated encodes the NALUs of an access unit, that share the same
+// timestamp, into RTP/H264 packets.
+// Small NALUs (like SPS, PPS and SEI) are grouped into STAP-A packets,
+// big NALUs are fragmented into FU-A packets.
+// The marker bit is set on the last packet only.
+func (e *Encoder) EncodeAggregated(nts []*NALUAndTimestamp) ([][]byte, error) {
+	var ret [][]byte
+	var batch []*NALUAndTimestamp
+	batchSize := 1
+
+	flush := func(marker bool) error {
+		var pkts [][]byte
+		var err error
+
+		switch len(batch) {
+		case 0:
+			return nil
+
+		case 1:
+			pkts, err = e.writeSingle(batch[0])
+			if err == nil && !marker {
+				unsetMarker(pkts[0])
+			}
+
+		default:
+			var pkt []byte
+			pkt, err = e.writeAggregated(batch, marker)
+			pkts = [][]byte{pkt}
+		}
+
+		if err != nil {
+			return err
+		}
+
+		ret = append(ret, pkts...)
+		batch = nil
+		batchSize = 1
+		return nil
+	}
 
-	rpkt.Header.Marker = true
+	for i, nt := range nts {
+		if len(nt.NALU) < 1 {
+			return nil, fmt.Errorf("NALU is too short")
+		}
+
+		isLast := (i == len(nts)-1)
+
+		if len(nt.NALU) >= e.payloadMaxSize {
+			err := flush(false)
+			if err != nil {
+				return nil, err
+			}
+
+			pkts, err := e.writeFragmented(nt)
+			if err != nil {
+				return nil, err
+			}
 
-	frame, err := rpkt.Marshal()
+			if !isLast {
+				unsetMarker(pkts[len(pkts)-1])
+			}
+
+			ret = append(ret, pkts...)
+			continue
+		}
+
+		if (batchSize + 2 + len(nt.NALU)) > e.payloadMaxSize {
+			err := flush(false)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		batch = append(batch, nt)
+		batchSize += 2 + len(nt.NALU)
+	}
+
+	err := flush(true)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// unsetMarker removes the marker bit from a packet, since
+// writeSingle() and writeFragmented() always set it.
+func unsetMarker(pkt []byte) {
+	pkt[1] &= 0x7F
+}
+
+func (e *Encoder) writeSingle(nt *NALUAndTimestamp) ([][]byte, error) {
+	frame, err := e.newPacket(e.encodeTimestamp(nt.Timestamp), nt.NALU, true)
 	if err != nil {
 		return nil, err
 	}
@@ -93,10 +261,13 @@ func (e *Encoder) writeFragmented(nt *NALUAndTimestamp) ([][]byte, error) {
 
 	// use only FU-A, not FU-B, since we always use non-interleaved mode
 	// (packetization-mode=1)
-	frameCount := (len(nalu) - 1) / (rtpPayloadMaxSize - 2)
-	lastFrameSize := (len(nalu) - 1) % (rtpPayloadMaxSize - 2)
+	frameMaxSize := e.payloadMaxSize - 2
+	frameCount := (len(nalu) - 1) / frameMaxSize
+	lastFrameSize := (len(nalu) - 1) % frameMaxSize
 	if lastFrameSize > 0 {
 		frameCount++
+	} else {
+		lastFrameSize = frameMaxSize
 	}
 	ret := make([][]byte, frameCount)
 
@@ -114,7 +285,7 @@ func (e *Encoder) writeFragmented(nt *NALUAndTimestamp) ([][]byte, error) {
 			start = 1
 		}
 		end := uint8(0)
-		le := rtpPayloadMaxSize - 2
+		le := frameMaxSize
 		if i == (frameCount - 1) {
 			end = 1
 			le = lastFrameSize
@@ -124,23 +295,7 @@ func (e *Encoder) writeFragmented(nt *NALUAndTimestamp) ([][]byte, error) {
 		data := append([]byte{indicator, header}, nalu[:le]...)
 		nalu = nalu[le:]
 
-		rpkt := rtp.Packet{
-			Header: rtp.Header{
-				Version:        rtpVersion,
-				PayloadType:    e.payloadType,
-				SequenceNumber: e.sequenceNumber,
-				Timestamp:      ts,
-				SSRC:           e.ssrc,
-			},
-			Payload: data,
-		}
-		e.sequenceNumber++
-
-		if i == (frameCount - 1) {
-			rpkt.Header.Marker = true
-		}
-
-		frame, err := rpkt.Marshal()
+		frame, err := e.newPacket(ts, data, i == (frameCount-1))
 		if err != nil {
 			return nil, err
 		}
@@ -150,3 +305,31 @@ func (e *Encoder) writeFragmented(nt *NALUAndTimestamp) ([][]byte, error) {
 
 	return ret, nil
 }
+
+func (e *Encoder) writeAggregated(nts []*NALUAndTimestamp, marker bool) ([]byte, error) {
+	// F bit is the OR of all F bits,
+	// NRI is the highest of all NALUs
+	f := uint8(0)
+	nri := uint8(0)
+	size := 1
+
+	for _, nt := range nts {
+		f |= nt.NALU[0] >> 7
+		if v := (nt.NALU[0] >> 5) & 0x03; v > nri {
+			nri = v
+		}
+		size += 2 + len(nt.NALU)
+	}
+
+	payload := make([]byte, size)
+	payload[0] = (f << 7) | (nri << 5) | uint8(NALUTypeStapA)
+	pos := 1
+
+	for _, nt := range nts {
+		binary.BigEndian.PutUint16(payload[pos:], uint16(len(nt.NALU)))
+		pos += 2
+		pos += copy(payload[pos:], nt.NALU)
+	}
+
+	return e.newPacket(e.encodeTimestamp(nts[0].Timestamp), payload, marker)
+}
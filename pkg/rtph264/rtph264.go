@@ -10,3 +10,15 @@ type NALUAndTimestamp struct {
 	Timestamp time.Duration
 	NALU      []byte
 }
+
+// AccessUnit is a group of NALUs that share the same timestamp, that
+// compose a frame.
+type AccessUnit struct {
+	// timestamp, relative to the first received packet.
+	Timestamp time.Duration
+
+	// raw RTP timestamp, that can be unwrapped with rtpunwrapper.
+	RTPTimestamp uint32
+
+	NALUs [][]byte
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/pion/rtp"
 	"github.com/stretchr/testify/require"
 )
 
@@ -203,3 +204,360 @@ func TestDecodeAggregated(t *testing.T) {
 	_, err = d.Read(r)
 	require.Equal(t, io.EOF, err)
 }
+
+func testPacket(seq uint16, ts uint32, marker bool, payload []byte) []byte {
+	byts, _ := (&rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			Marker:         marker,
+			PayloadType:    96,
+			SequenceNumber: seq,
+			Timestamp:      ts,
+			SSRC:           0x9dbb7812,
+		},
+		Payload: payload,
+	}).Marshal()
+	return byts
+}
+
+func TestDecodeAnnexB(t *testing.T) {
+	d := NewDecoder()
+
+	nalus, err := d.Decode(testPacket(0x44ed, 0x88776655, true, []byte{
+		0x00, 0x00, 0x00, 0x01, 0x09, 0xf0,
+		0x00, 0x00, 0x01, 0x05, 0x01, 0x02, 0x00,
+	}))
+	require.NoError(t, err)
+	require.Equal(t, []*NALUAndTimestamp{
+		{NALU: []byte{0x09, 0xf0}},
+		{NALU: []byte{0x05, 0x01, 0x02}},
+	}, nalus)
+}
+
+func TestDecodeSPSPPSInjection(t *testing.T) {
+	d := NewDecoder()
+	d.SetSPSPPS([]byte{0x67, 0x01}, []byte{0x68, 0x01})
+
+	// IDR without SPS and PPS
+	nalus, err := d.Decode(testPacket(0x44ed, 0x88776655, true, []byte{0x05, 0x01}))
+	require.NoError(t, err)
+	require.Equal(t, []*NALUAndTimestamp{
+		{NALU: []byte{0x67, 0x01}},
+		{NALU: []byte{0x68, 0x01}},
+		{NALU: []byte{0x05, 0x01}},
+	}, nalus)
+
+	// non-IDR
+	nalus, err = d.Decode(testPacket(0x44ee, 0x88776655+3000, true, []byte{0x01, 0x01}))
+	require.NoError(t, err)
+	require.Equal(t, []*NALUAndTimestamp{
+		{NALU: []byte{0x01, 0x01}, Timestamp: 33333333},
+	}, nalus)
+
+	// IDR preceded by an in-band SPS, that replaces the previous one
+	nalus, err = d.Decode(testPacket(0x44ef, 0x88776655+6000, false, []byte{0x67, 0x02}))
+	require.NoError(t, err)
+	require.Equal(t, []*NALUAndTimestamp{
+		{NALU: []byte{0x67, 0x02}, Timestamp: 66666666},
+	}, nalus)
+
+	nalus, err = d.Decode(testPacket(0x44f0, 0x88776655+6000, true, []byte{0x05, 0x02}))
+	require.NoError(t, err)
+	require.Equal(t, []*NALUAndTimestamp{
+		{NALU: []byte{0x68, 0x01}, Timestamp: 66666666},
+		{NALU: []byte{0x05, 0x02}, Timestamp: 66666666},
+	}, nalus)
+
+	nalus, err = d.Decode(testPacket(0x44f1, 0x88776655+9000, true, []byte{0x05, 0x03}))
+	require.NoError(t, err)
+	require.Equal(t, []*NALUAndTimestamp{
+		{NALU: []byte{0x67, 0x02}, Timestamp: 100 * time.Millisecond},
+		{NALU: []byte{0x68, 0x01}, Timestamp: 100 * time.Millisecond},
+		{NALU: []byte{0x05, 0x03}, Timestamp: 100 * time.Millisecond},
+	}, nalus)
+}
+
+func TestDecodeUntilMarker(t *testing.T) {
+	d := NewDecoder()
+
+	_, err := d.DecodeUntilMarker(testPacket(0x44ed, 0x88776655, false, []byte{0x09, 0xf0}))
+	require.Equal(t, ErrMorePacketsNeeded, err)
+
+	_, err = d.DecodeUntilMarker(testPacket(0x44ee, 0x88776655, false, []byte{0x7c, 0x85, 0x01}))
+	require.Equal(t, ErrMorePacketsNeeded, err)
+
+	nalus, err := d.DecodeUntilMarker(testPacket(0x44ef, 0x88776655, true, []byte{0x7c, 0x45, 0x02}))
+	require.NoError(t, err)
+	require.Equal(t, []*NALUAndTimestamp{
+		{NALU: []byte{0x09, 0xf0}},
+		{NALU: []byte{0x65, 0x01, 0x02}},
+	}, nalus)
+}
+
+func TestDecodeFragmentedLoss(t *testing.T) {
+	d := NewDecoder()
+
+	// start of a fragmented NALU, whose end is lost
+	_, err := d.Decode(testPacket(0x44ed, 0x88776655, false, []byte{0x7c, 0x85, 0x01}))
+	require.Equal(t, ErrMorePacketsNeeded, err)
+
+	// a new fragmented NALU restarts the decoding
+	_, err = d.Decode(testPacket(0x44ef, 0x88776655, false, []byte{0x7c, 0x85, 0x03}))
+	require.Equal(t, ErrMorePacketsNeeded, err)
+
+	nalus, err := d.Decode(testPacket(0x44f0, 0x88776655, true, []byte{0x7c, 0x45, 0x04}))
+	require.NoError(t, err)
+	require.Equal(t, []*NALUAndTimestamp{
+		{NALU: []byte{0x65, 0x03, 0x04}},
+	}, nalus)
+
+	// a single NALU following an incomplete fragmented NALU is decoded
+	_, err = d.Decode(testPacket(0x44f1, 0x88776655, false, []byte{0x7c, 0x85, 0x05}))
+	require.Equal(t, ErrMorePacketsNeeded, err)
+
+	nalus, err = d.Decode(testPacket(0x44f3, 0x88776655, true, []byte{0x01, 0x06}))
+	require.NoError(t, err)
+	require.Equal(t, []*NALUAndTimestamp{
+		{NALU: []byte{0x01, 0x06}},
+	}, nalus)
+}
+
+func TestEncodeAggregated(t *testing.T) {
+	sps := []byte{0x67, 0x64, 0x00, 0x0c}
+	pps := []byte{0x68, 0xee, 0x3c, 0x80}
+
+	sequenceNumber := uint16(0x44ed)
+	ssrc := uint32(0x9dbb7812)
+	initialTs := uint32(0x88776655)
+	e := NewEncoder(96, &sequenceNumber, &ssrc, &initialTs)
+
+	enc, err := e.EncodeAggregated([]*NALUAndTimestamp{
+		{NALU: sps},
+		{NALU: pps},
+	})
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{
+		{
+			0x80, 0xe0, 0x44, 0xed, 0x88, 0x77, 0x66, 0x55,
+			0x9d, 0xbb, 0x78, 0x12, 0x78, 0x00, 0x04, 0x67,
+			0x64, 0x00, 0x0c, 0x00, 0x04, 0x68, 0xee, 0x3c,
+			0x80,
+		},
+	}, enc)
+
+	i := 0
+	r := readerFunc(func(p []byte) (int, error) {
+		if i == len(enc) {
+			return 0, io.EOF
+		}
+
+		i++
+		return copy(p, enc[i-1]), nil
+	})
+
+	d := NewDecoder()
+	v1, v2, err := d.ReadSPSPPS(r)
+	require.NoError(t, err)
+	require.Equal(t, sps, v1)
+	require.Equal(t, pps, v2)
+}
+
+func TestEncodeAggregatedMixed(t *testing.T) {
+	nts := []*NALUAndTimestamp{
+		{NALU: []byte{0x67, 0x64, 0x00, 0x0c}},
+		{NALU: []byte{0x68, 0xee, 0x3c, 0x80}},
+		{NALU: mergeBytes(
+			[]byte{0x65},
+			bytes.Repeat([]byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07}, 256),
+		)},
+		{NALU: []byte{0x06, 0x01}},
+	}
+
+	e := NewEncoder(96, nil, nil, nil)
+	e.SetPayloadMaxSize(1000)
+
+	enc, err := e.EncodeAggregated(nts)
+	require.NoError(t, err)
+	require.Equal(t, 5, len(enc))
+
+	// only the last packet has the marker bit
+	for _, pkt := range enc[:4] {
+		require.Equal(t, uint8(0x60), pkt[1])
+		require.LessOrEqual(t, len(pkt)-12, 1000)
+	}
+	require.Equal(t, uint8(0xe0), enc[4][1])
+
+	d := NewDecoder()
+	var dec []*NALUAndTimestamp
+	for _, pkt := range enc {
+		nts, err := d.Decode(pkt)
+		if err == ErrMorePacketsNeeded {
+			continue
+		}
+		require.NoError(t, err)
+		dec = append(dec, nts...)
+	}
+
+	require.Equal(t, len(nts), len(dec))
+	for i := range nts {
+		require.Equal(t, nts[i].NALU, dec[i].NALU)
+	}
+}
+
+func TestEncodeFragmentedExactSize(t *testing.T) {
+	e := NewEncoder(96, nil, nil, nil)
+	e.SetPayloadMaxSize(10)
+
+	// 16 bytes of payload, that fill exactly two FU-A packets
+	nalu := mergeBytes([]byte{0x65}, bytes.Repeat([]byte{0x01}, 16))
+
+	enc, err := e.Encode(&NALUAndTimestamp{NALU: nalu})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(enc))
+
+	d := NewDecoder()
+	_, err = d.Decode(enc[0])
+	require.Equal(t, ErrMorePacketsNeeded, err)
+	dec, err := d.Decode(enc[1])
+	require.NoError(t, err)
+	require.Equal(t, nalu, dec[0].NALU)
+}
+
+func TestDecodeAccessUnits(t *testing.T) {
+	d := NewDecoder()
+
+	_, err := d.DecodeAccessUnits(testPacket(0x44ed, 0x88776655, false, []byte{0x09, 0xf0}))
+	require.Equal(t, ErrMorePacketsNeeded, err)
+
+	_, err = d.DecodeAccessUnits(testPacket(0x44ee, 0x88776655, false, []byte{0x7c, 0x85, 0x01}))
+	require.Equal(t, ErrMorePacketsNeeded, err)
+
+	aus, err := d.DecodeAccessUnits(testPacket(0x44ef, 0x88776655, true, []byte{0x7c, 0x45, 0x02}))
+	require.NoError(t, err)
+	require.Equal(t, []*AccessUnit{{
+		RTPTimestamp: 0x88776655,
+		NALUs: [][]byte{
+			{0x09, 0xf0},
+			{0x65, 0x01, 0x02},
+		},
+	}}, aus)
+
+	// the marker of the second access unit is lost
+	_, err = d.DecodeAccessUnits(testPacket(0x44f0, 0x88776655+9000, false, []byte{0x01, 0x03}))
+	require.Equal(t, ErrMorePacketsNeeded, err)
+
+	aus, err = d.DecodeAccessUnits(testPacket(0x44f1, 0x88776655+18000, true, []byte{0x01, 0x04}))
+	require.NoError(t, err)
+	require.Equal(t, []*AccessUnit{
+		{
+			Timestamp:    100 * time.Millisecond,
+			RTPTimestamp: 0x88776655 + 9000,
+			NALUs:        [][]byte{{0x01, 0x03}},
+		},
+		{
+			Timestamp:    200 * time.Millisecond,
+			RTPTimestamp: 0x88776655 + 18000,
+			NALUs:        [][]byte{{0x01, 0x04}},
+		},
+	}, aus)
+}
+
+func TestReadAccessUnit(t *testing.T) {
+	pkts := [][]byte{
+		testPacket(0x44ed, 0x88776655, false, []byte{0x09, 0xf0}),
+		testPacket(0x44ee, 0x88776655, true, []byte{0x05, 0x01}),
+		testPacket(0x44ef, 0x88776655+9000, false, []byte{0x01, 0x02}),
+		testPacket(0x44f0, 0x88776655+18000, true, []byte{0x01, 0x03}),
+	}
+
+	i := 0
+	r := readerFunc(func(p []byte) (int, error) {
+		if i == len(pkts) {
+			return 0, io.EOF
+		}
+
+		i++
+		return copy(p, pkts[i-1]), nil
+	})
+
+	d := NewDecoder()
+
+	au, err := d.ReadAccessUnit(r)
+	require.NoError(t, err)
+	require.Equal(t, &AccessUnit{
+		RTPTimestamp: 0x88776655,
+		NALUs:        [][]byte{{0x09, 0xf0}, {0x05, 0x01}},
+	}, au)
+
+	au, err = d.ReadAccessUnit(r)
+	require.NoError(t, err)
+	require.Equal(t, &AccessUnit{
+		Timestamp:    100 * time.Millisecond,
+		RTPTimestamp: 0x88776655 + 9000,
+		NALUs:        [][]byte{{0x01, 0x02}},
+	}, au)
+
+	au, err = d.ReadAccessUnit(r)
+	require.NoError(t, err)
+	require.Equal(t, &AccessUnit{
+		Timestamp:    200 * time.Millisecond,
+		RTPTimestamp: 0x88776655 + 18000,
+		NALUs:        [][]byte{{0x01, 0x03}},
+	}, au)
+
+	_, err = d.ReadAccessUnit(r)
+	require.Equal(t, io.EOF, err)
+}
+
+func TestDecodeTimestampOverflow(t *testing.T) {
+	d := NewDecoder()
+
+	nalus, err := d.Decode(testPacket(0x44ed, 0xffffdcd8, true, []byte{0x01, 0x01}))
+	require.NoError(t, err)
+	require.Equal(t, []*NALUAndTimestamp{
+		{NALU: []byte{0x01, 0x01}},
+	}, nalus)
+
+	nalus, err = d.Decode(testPacket(0x44ee, 0x00002328, true, []byte{0x01, 0x02}))
+	require.NoError(t, err)
+	require.Equal(t, []*NALUAndTimestamp{
+		{NALU: []byte{0x01, 0x02}, Timestamp: 200 * time.Millisecond},
+	}, nalus)
+
+	// after 2^32 ticks, timestamps keep growing
+	for i := uint32(1); i <= 4; i++ {
+		_, err = d.Decode(testPacket(uint16(0x44ee+i), 0x00002328+i*0x40000000, true, []byte{0x01, 0x03}))
+		require.NoError(t, err)
+	}
+
+	nalus, err = d.Decode(testPacket(0x44f3, 0x00004650, true, []byte{0x01, 0x04}))
+	require.NoError(t, err)
+	require.Equal(t, []*NALUAndTimestamp{
+		{NALU: []byte{0x01, 0x04}, Timestamp: (0x100000000 + 27000) * time.Second / 90000},
+	}, nalus)
+}
+
+func TestEncoderState(t *testing.T) {
+	sequenceNumber := uint16(0x44ed)
+	ssrc := uint32(0x9dbb7812)
+	initialTs := uint32(0x88776655)
+	e := NewEncoder(96, &sequenceNumber, &ssrc, &initialTs)
+
+	require.Equal(t, EncoderState{
+		SequenceNumber: 0x44ed,
+		SSRC:           0x9dbb7812,
+		Timestamp:      0x88776655,
+	}, e.State())
+
+	e.SetPayloadMaxSize(4)
+
+	_, err := e.Encode(&NALUAndTimestamp{
+		Timestamp: 100 * time.Millisecond,
+		NALU:      []byte{0x05, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, uint16(0x44ed+3), e.SequenceNumber())
+	require.Equal(t, uint32(0x9dbb7812), e.SSRC())
+	require.Equal(t, uint32(0x88776655+9000), e.Timestamp())
+}
@@ -0,0 +1,278 @@
+package rtph265
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+const (
+	rtpVersion        = 0x02
+	rtpPayloadMaxSize = 1460  // 1500 (mtu) - 20 (ip header) - 8 (udp header) - 12 (rtp header)
+	rtpClockRate      = 90000 // h265 always uses 90khz
+)
+
+// Encoder is a RTP/H265 encoder.
+type Encoder struct {
+	payloadType    uint8
+	sequenceNumber uint16
+	ssrc           uint32
+	initialTs      uint32
+	payloadMaxSize int
+}
+
+// NewEncoder allocates an Encoder.
+func NewEncoder(payloadType uint8,
+	sequenceNumber *uint16,
+	ssrc *uint32,
+	initialTs *uint32) *Encoder {
+	return &Encoder{
+		payloadType: payloadType,
+		sequenceNumber: func() uint16 {
+			if sequenceNumber != nil {
+				return *sequenceNumber
+			}
+			return uint16(rand.Uint32())
+		}(),
+		ssrc: func() uint32 {
+			if ssrc != nil {
+				return *ssrc
+			}
+			return rand.Uint32()
+		}(),
+		initialTs: func() uint32 {
+			if initialTs != nil {
+				return *initialTs
+			}
+			return rand.Uint32()
+		}(),
+		payloadMaxSize: rtpPayloadMaxSize,
+	}
+}
+
+// SetPayloadMaxSize sets the maximum size of RTP payloads.
+// NALUs bigger than this are fragmented.
+// It defaults to 1460, that is suitable for a 1500 bytes MTU.
+func (e *Encoder) SetPayloadMaxSize(v int) {
+	e.payloadMaxSize = v
+}
+
+func (e *Encoder) encodeTimestamp(ts time.Duration) uint32 {
+	return e.initialTs + uint32(ts.Seconds()*rtpClockRate)
+}
+
+func (e *Encoder) newPacket(ts uint32, payload []byte, marker bool) ([]byte, error) {
+	rpkt := rtp.Packet{
+		Header: rtp.Header{
+			Version:        rtpVersion,
+			PayloadType:    e.payloadType,
+			SequenceNumber: e.sequenceNumber,
+			Timestamp:      ts,
+			SSRC:           e.ssrc,
+			Marker:         marker,
+		},
+		Payload: payload,
+	}
+	e.sequenceNumber++
+
+	return rpkt.Marshal()
+}
+
+// Encode encodes a NALU into RTP/H265 packets.
+// It always returns at least one RTP/H265 packet.
+func (e *Encoder) Encode(nt *NALUAndTimestamp) ([][]byte, error) {
+	if len(nt.NALU) < 2 {
+		return nil, fmt.Errorf("NALU is too short")
+	}
+
+	// if the NALU fits into a single RTP packet, use a single payload
+	if len(nt.NALU) < e.payloadMaxSize {
+		return e.writeSingle(nt)
+	}
+
+	// otherwise, split the NALU into multiple fragmentation units
+	return e.writeFragmented(nt)
+}
+
+// EncodeAggregated encodes NALUs that share the same timestamp
+// into RTP/H265 packets.
+// NALUs are grouped into aggregation packets when possible.
+func (e *Encoder) EncodeAggregated(nts []*NALUAndTimestamp) ([][]byte, error) {
+	var ret [][]byte
+	var batch []*NALUAndTimestamp
+	batchSize := 2
+
+	flush := func(marker bool) error {
+		var pkts [][]byte
+		var err error
+
+		switch len(batch) {
+		case 0:
+			return nil
+
+		case 1:
+			pkts, err = e.writeSingle(batch[0])
+			if err == nil && !marker {
+				unsetMarker(pkts[0])
+			}
+
+		default:
+			var pkt []byte
+			pkt, err = e.writeAggregated(batch, marker)
+			pkts = [][]byte{pkt}
+		}
+
+		if err != nil {
+			return err
+		}
+
+		ret = append(ret, pkts...)
+		batch = nil
+		batchSize = 2
+		return nil
+	}
+
+	for i, nt := range nts {
+		if len(nt.NALU) < 2 {
+			return nil, fmt.Errorf("NALU is too short")
+		}
+
+		isLast := (i == len(nts)-1)
+
+		if len(nt.NALU) >= e.payloadMaxSize {
+			err := flush(false)
+			if err != nil {
+				return nil, err
+			}
+
+			pkts, err := e.writeFragmented(nt)
+			if err != nil {
+				return nil, err
+			}
+
+			if !isLast {
+				unsetMarker(pkts[len(pkts)-1])
+			}
+
+			ret = append(ret, pkts...)
+			continue
+		}
+
+		if (batchSize + 2 + len(nt.NALU)) > e.payloadMaxSize {
+			err := flush(false)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		batch = append(batch, nt)
+		batchSize += 2 + len(nt.NALU)
+	}
+
+	err := flush(true)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// unsetMarker removes the marker bit from a packet, since
+// writeSingle() and writeFragmented() always set it.
+func unsetMarker(pkt []byte) {
+	pkt[1] &= 0x7F
+}
+
+func (e *Encoder) writeSingle(nt *NALUAndTimestamp) ([][]byte, error) {
+	frame, err := e.newPacket(e.encodeTimestamp(nt.Timestamp), nt.NALU, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return [][]byte{frame}, nil
+}
+
+func (e *Encoder) writeFragmented(nt *NALUAndTimestamp) ([][]byte, error) {
+	nalu := nt.NALU
+
+	// the NALU header (2 bytes) is replaced by the payload header (2 bytes)
+	// and the FU header (1 byte)
+	frameMaxSize := e.payloadMaxSize - 3
+	frameCount := (len(nalu) - 2) / frameMaxSize
+	lastFrameSize := (len(nalu) - 2) % frameMaxSize
+	if lastFrameSize > 0 {
+		frameCount++
+	} else {
+		lastFrameSize = frameMaxSize
+	}
+	ret := make([][]byte, frameCount)
+
+	header0 := (nalu[0] & 0x81) | (uint8(NALUTypeFragmentationUnit) << 1)
+	header1 := nalu[1]
+	typ := (nalu[0] >> 1) & 0x3F
+	nalu = nalu[2:] // remove header
+
+	ts := e.encodeTimestamp(nt.Timestamp)
+
+	for i := 0; i < frameCount; i++ {
+		start := uint8(0)
+		if i == 0 {
+			start = 1
+		}
+		end := uint8(0)
+		le := frameMaxSize
+		if i == (frameCount - 1) {
+			end = 1
+			le = lastFrameSize
+		}
+		fuHeader := (start << 7) | (end << 6) | typ
+
+		data := append([]byte{header0, header1, fuHeader}, nalu[:le]...)
+		nalu = nalu[le:]
+
+		frame, err := e.newPacket(ts, data, i == (frameCount-1))
+		if err != nil {
+			return nil, err
+		}
+
+		ret[i] = frame
+	}
+
+	return ret, nil
+}
+
+func (e *Encoder) writeAggregated(nts []*NALUAndTimestamp, marker bool) ([]byte, error) {
+	// F bit is the OR of all F bits,
+	// LayerId and TID are the lowest of all NALUs
+	f := uint8(0)
+	layerID := uint8(0x3F)
+	tid := uint8(0x07)
+	size := 2
+
+	for _, nt := range nts {
+		f |= nt.NALU[0] >> 7
+		if v := ((nt.NALU[0] & 0x01) << 5) | (nt.NALU[1] >> 3); v < layerID {
+			layerID = v
+		}
+		if v := nt.NALU[1] & 0x07; v < tid {
+			tid = v
+		}
+		size += 2 + len(nt.NALU)
+	}
+
+	payload := make([]byte, size)
+	payload[0] = (f << 7) | (uint8(NALUTypeAggregationUnit) << 1) | (layerID >> 5)
+	payload[1] = (layerID << 3) | tid
+	pos := 2
+
+	for _, nt := range nts {
+		binary.BigEndian.PutUint16(payload[pos:], uint16(len(nt.NALU)))
+		pos += 2
+		pos += copy(payload[pos:], nt.NALU)
+	}
+
+	return e.newPacket(e.encodeTimestamp(nts[0].Timestamp), payload, marker)
+}
@@ -0,0 +1,99 @@
+package rtph265
+
+// NALUType is the type of a NALU.
+type NALUType uint8
+
+// standard NALU types.
+const (
+	NALUTypeTrailN              NALUType = 0
+	NALUTypeTrailR              NALUType = 1
+	NALUTypeTsaN                NALUType = 2
+	NALUTypeTsaR                NALUType = 3
+	NALUTypeStsaN               NALUType = 4
+	NALUTypeStsaR               NALUType = 5
+	NALUTypeRadlN               NALUType = 6
+	NALUTypeRadlR               NALUType = 7
+	NALUTypeRaslN               NALUType = 8
+	NALUTypeRaslR               NALUType = 9
+	NALUTypeBlaWLp              NALUType = 16
+	NALUTypeBlaWRadl            NALUType = 17
+	NALUTypeBlaNLp              NALUType = 18
+	NALUTypeIdrWRadl            NALUType = 19
+	NALUTypeIdrNLp              NALUType = 20
+	NALUTypeCraNut              NALUType = 21
+	NALUTypeVPS                 NALUType = 32
+	NALUTypeSPS                 NALUType = 33
+	NALUTypePPS                 NALUType = 34
+	NALUTypeAccessUnitDelimiter NALUType = 35
+	NALUTypeEndOfSequence       NALUType = 36
+	NALUTypeEndOfBitstream      NALUType = 37
+	NALUTypeFillerData          NALUType = 38
+	NALUTypePrefixSei           NALUType = 39
+	NALUTypeSuffixSei           NALUType = 40
+	NALUTypeAggregationUnit     NALUType = 48
+	NALUTypeFragmentationUnit   NALUType = 49
+	NALUTypePACI                NALUType = 50
+)
+
+// String implements fmt.Stringer.
+func (nt NALUType) String() string {
+	switch nt {
+	case NALUTypeTrailN:
+		return "TrailN"
+	case NALUTypeTrailR:
+		return "TrailR"
+	case NALUTypeTsaN:
+		return "TsaN"
+	case NALUTypeTsaR:
+		return "TsaR"
+	case NALUTypeStsaN:
+		return "StsaN"
+	case NALUTypeStsaR:
+		return "StsaR"
+	case NALUTypeRadlN:
+		return "RadlN"
+	case NALUTypeRadlR:
+		return "RadlR"
+	case NALUTypeRaslN:
+		return "RaslN"
+	case NALUTypeRaslR:
+		return "RaslR"
+	case NALUTypeBlaWLp:
+		return "BlaWLp"
+	case NALUTypeBlaWRadl:
+		return "BlaWRadl"
+	case NALUTypeBlaNLp:
+		return "BlaNLp"
+	case NALUTypeIdrWRadl:
+		return "IdrWRadl"
+	case NALUTypeIdrNLp:
+		return "IdrNLp"
+	case NALUTypeCraNut:
+		return "CraNut"
+	case NALUTypeVPS:
+		return "VPS"
+	case NALUTypeSPS:
+		return "SPS"
+	case NALUTypePPS:
+		return "PPS"
+	case NALUTypeAccessUnitDelimiter:
+		return "AccessUnitDelimiter"
+	case NALUTypeEndOfSequence:
+		return "EndOfSequence"
+	case NALUTypeEndOfBitstream:
+		return "EndOfBitstream"
+	case NALUTypeFillerData:
+		return "FillerData"
+	case NALUTypePrefixSei:
+		return "PrefixSei"
+	case NALUTypeSuffixSei:
+		return "SuffixSei"
+	case NALUTypeAggregationUnit:
+		return "AggregationUnit"
+	case NALUTypeFragmentationUnit:
+		return "FragmentationUnit"
+	case NALUTypePACI:
+		return "PACI"
+	}
+	return "unknown"
+}
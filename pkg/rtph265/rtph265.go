@@ -0,0 +1,12 @@
+// Package rtph265 contains a RTP/H265 decoder and encoder.
+package rtph265
+
+import (
+	"time"
+)
+
+// NALUAndTimestamp is a Network Abstraction Layer Unit and its timestamp.
+type NALUAndTimestamp struct {
+	Timestamp time.Duration
+	NALU      []byte
+}
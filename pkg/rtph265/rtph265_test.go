@@ -0,0 +1,212 @@
+package rtph265
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func mergeBytes(vals ...[]byte) []byte {
+	size := 0
+	for _, v := range vals {
+		size += len(v)
+	}
+	res := make([]byte, size)
+
+	pos := 0
+	for _, v := range vals {
+		n := copy(res[pos:], v)
+		pos += n
+	}
+
+	return res
+}
+
+type readerFunc func(p []byte) (int, error)
+
+func (f readerFunc) Read(p []byte) (int, error) {
+	return f(p)
+}
+
+var cases = []struct {
+	name string
+	dec  *NALUAndTimestamp
+	enc  [][]byte
+}{
+	{
+		"single",
+		&NALUAndTimestamp{
+			Timestamp: 25 * time.Millisecond,
+			NALU: mergeBytes(
+				[]byte{0x26, 0x01},
+				bytes.Repeat([]byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07}, 8),
+			),
+		},
+		[][]byte{
+			mergeBytes(
+				[]byte{
+					0x80, 0xe0, 0x44, 0xed, 0x88, 0x77, 0x6f, 0x1f,
+					0x9d, 0xbb, 0x78, 0x12, 0x26, 0x01,
+				},
+				bytes.Repeat([]byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07}, 8),
+			),
+		},
+	},
+	{
+		"fragmented",
+		&NALUAndTimestamp{
+			Timestamp: 55 * time.Millisecond,
+			NALU: mergeBytes(
+				[]byte{0x26, 0x01},
+				bytes.Repeat([]byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07}, 256),
+			),
+		},
+		[][]byte{
+			mergeBytes(
+				[]byte{
+					0x80, 0x60, 0x44, 0xed, 0x88, 0x77, 0x79, 0xab,
+					0x9d, 0xbb, 0x78, 0x12, 0x62, 0x01, 0x93,
+				},
+				bytes.Repeat([]byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07}, 182),
+				[]byte{0x00},
+			),
+			mergeBytes(
+				[]byte{
+					0x80, 0xe0, 0x44, 0xee, 0x88, 0x77, 0x79, 0xab,
+					0x9d, 0xbb, 0x78, 0x12, 0x62, 0x01, 0x53, 0x01,
+					0x02, 0x03, 0x04, 0x05, 0x06, 0x07,
+				},
+				bytes.Repeat([]byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07}, 73),
+			),
+		},
+	},
+}
+
+func TestEncode(t *testing.T) {
+	for _, ca := range cases {
+		t.Run(ca.name, func(t *testing.T) {
+			sequenceNumber := uint16(0x44ed)
+			ssrc := uint32(0x9dbb7812)
+			initialTs := uint32(0x88776655)
+			e := NewEncoder(96, &sequenceNumber, &ssrc, &initialTs)
+			enc, err := e.Encode(ca.dec)
+			require.NoError(t, err)
+			require.Equal(t, ca.enc, enc)
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	for _, ca := range cases {
+		t.Run(ca.name, func(t *testing.T) {
+			i := 0
+			r := readerFunc(func(p []byte) (int, error) {
+				if i == len(ca.enc) {
+					return 0, io.EOF
+				}
+
+				i++
+				return copy(p, ca.enc[i-1]), nil
+			})
+
+			d := NewDecoder()
+
+			// send an initial packet downstream
+			// in order to correctly compute the timestamp
+			_, err := d.Decode([]byte{
+				0x80, 0xe0, 0x44, 0xed, 0x88, 0x77, 0x66, 0x55,
+				0x9d, 0xbb, 0x78, 0x12, 0x4e, 0x01,
+			})
+			require.NoError(t, err)
+
+			dec, err := d.Read(r)
+			require.NoError(t, err)
+			require.Equal(t, ca.dec, dec)
+
+			_, err = d.Read(r)
+			require.Equal(t, io.EOF, err)
+		})
+	}
+}
+
+func TestEncodeAggregated(t *testing.T) {
+	vps := []byte{0x40, 0x01, 0x0c, 0x01}
+	sps := []byte{0x42, 0x01, 0x01, 0x01}
+	pps := []byte{0x44, 0x01, 0xc1, 0x72}
+
+	sequenceNumber := uint16(0x44ed)
+	ssrc := uint32(0x9dbb7812)
+	initialTs := uint32(0x88776655)
+	e := NewEncoder(96, &sequenceNumber, &ssrc, &initialTs)
+
+	enc, err := e.EncodeAggregated([]*NALUAndTimestamp{
+		{NALU: vps},
+		{NALU: sps},
+		{NALU: pps},
+	})
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{
+		{
+			0x80, 0xe0, 0x44, 0xed, 0x88, 0x77, 0x66, 0x55,
+			0x9d, 0xbb, 0x78, 0x12, 0x60, 0x01, 0x00, 0x04,
+			0x40, 0x01, 0x0c, 0x01, 0x00, 0x04, 0x42, 0x01,
+			0x01, 0x01, 0x00, 0x04, 0x44, 0x01, 0xc1, 0x72,
+		},
+	}, enc)
+
+	i := 0
+	r := readerFunc(func(p []byte) (int, error) {
+		if i == len(enc) {
+			return 0, io.EOF
+		}
+
+		i++
+		return copy(p, enc[i-1]), nil
+	})
+
+	d := NewDecoder()
+	v1, v2, v3, err := d.ReadVPSSPSPPS(r)
+	require.NoError(t, err)
+	require.Equal(t, vps, v1)
+	require.Equal(t, sps, v2)
+	require.Equal(t, pps, v3)
+}
+
+func TestEncodeAggregatedMixed(t *testing.T) {
+	nts := []*NALUAndTimestamp{
+		{NALU: []byte{0x44, 0x01, 0xc1, 0x72}},
+		{NALU: mergeBytes(
+			[]byte{0x26, 0x01},
+			bytes.Repeat([]byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07}, 256),
+		)},
+	}
+
+	e := NewEncoder(96, nil, nil, nil)
+	enc, err := e.EncodeAggregated(nts)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(enc))
+
+	// only the last packet has the marker bit
+	require.Equal(t, uint8(0x60), enc[0][1])
+	require.Equal(t, uint8(0x60), enc[1][1])
+	require.Equal(t, uint8(0xe0), enc[2][1])
+
+	d := NewDecoder()
+	var dec []*NALUAndTimestamp
+	for _, pkt := range enc {
+		nts, err := d.Decode(pkt)
+		if err == ErrMorePacketsNeeded {
+			continue
+		}
+		require.NoError(t, err)
+		dec = append(dec, nts...)
+	}
+
+	require.Equal(t, len(nts), len(dec))
+	for i := range nts {
+		require.Equal(t, nts[i].NALU, dec[i].NALU)
+	}
+}
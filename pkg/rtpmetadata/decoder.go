@@ -0,0 +1,36 @@
+package rtpmetadata
+
+import (
+	"github.com/majoyz/gortsplib/pkg/rtpreassembler"
+)
+
+// ErrMorePacketsNeeded is returned by Decoder.Decode when more packets are needed.
+var ErrMorePacketsNeeded = rtpreassembler.ErrMorePacketsNeeded
+
+// Decoder is a RTP decoder for ONVIF metadata.
+// Documents are split into packets with the same timestamp, and the last
+// packet of each document has the marker bit set.
+type Decoder struct {
+	r *rtpreassembler.Reassembler
+}
+
+// NewDecoder allocates a Decoder.
+func NewDecoder() *Decoder {
+	return &Decoder{
+		r: rtpreassembler.New("document", documentMaxSize, rtpClockRate),
+	}
+}
+
+// Decode decodes a XML document from RTP packets.
+// It returns ErrMorePacketsNeeded until the last packet of the document is received.
+func (d *Decoder) Decode(byts []byte) (*DocumentAndTimestamp, error) {
+	doc, ts, err := d.r.Process(byts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DocumentAndTimestamp{
+		Document:  doc,
+		Timestamp: ts,
+	}, nil
+}
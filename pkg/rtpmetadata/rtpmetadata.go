@@ -0,0 +1,21 @@
+// Package rtpmetadata contains a RTP decoder for ONVIF metadata
+// (application/vnd.onvif.metadata), that are XML documents split
+// into RTP packets.
+package rtpmetadata
+
+import (
+	"time"
+)
+
+const (
+	rtpClockRate = 90000 // metadata always uses 90khz
+
+	// maximum size of a document
+	documentMaxSize = 1 * 1024 * 1024
+)
+
+// DocumentAndTimestamp is a XML document and its timestamp.
+type DocumentAndTimestamp struct {
+	Timestamp time.Duration
+	Document  []byte
+}
@@ -0,0 +1,66 @@
+package rtpmetadata
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+)
+
+func packet(seq uint16, ts uint32, marker bool, payload string) []byte {
+	byts, _ := (&rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			Marker:         marker,
+			PayloadType:    107,
+			SequenceNumber: seq,
+			Timestamp:      ts,
+			SSRC:           0x9dbb7812,
+		},
+		Payload: []byte(payload),
+	}).Marshal()
+	return byts
+}
+
+func TestDecode(t *testing.T) {
+	d := NewDecoder()
+
+	dec, err := d.Decode(packet(100, 90000, true, `<tt:MetadataStream/>`))
+	require.NoError(t, err)
+	require.Equal(t, &DocumentAndTimestamp{
+		Timestamp: 0,
+		Document:  []byte(`<tt:MetadataStream/>`),
+	}, dec)
+
+	_, err = d.Decode(packet(101, 180000, false, `<tt:MetadataStream>`))
+	require.Equal(t, ErrMorePacketsNeeded, err)
+
+	_, err = d.Decode(packet(102, 180000, false, `<tt:Event/>`))
+	require.Equal(t, ErrMorePacketsNeeded, err)
+
+	dec, err = d.Decode(packet(103, 180000, true, `</tt:MetadataStream>`))
+	require.NoError(t, err)
+	require.Equal(t, &DocumentAndTimestamp{
+		Timestamp: 1 * time.Second,
+		Document:  []byte(`<tt:MetadataStream><tt:Event/></tt:MetadataStream>`),
+	}, dec)
+}
+
+func TestDecodeErrors(t *testing.T) {
+	d := NewDecoder()
+
+	_, err := d.Decode([]byte{0x80, 0x6b})
+	require.EqualError(t, err, "RTP header size insufficient: 2 < 4")
+
+	_, err = d.Decode(packet(100, 90000, false, `<tt:MetadataStream>`))
+	require.Equal(t, ErrMorePacketsNeeded, err)
+
+	_, err = d.Decode(packet(102, 90000, true, `</tt:MetadataStream>`))
+	require.EqualError(t, err, "discarding document since a RTP packet is missing")
+
+	// the decoder recovers with the next document
+	dec, err := d.Decode(packet(103, 180000, true, `<tt:MetadataStream/>`))
+	require.NoError(t, err)
+	require.Equal(t, []byte(`<tt:MetadataStream/>`), dec.Document)
+}
@@ -0,0 +1,189 @@
+package rtpmjpeg
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+const (
+	rtpClockRate = 90000 // mjpeg always uses 90khz
+)
+
+// ErrMorePacketsNeeded is returned by Decoder.Decode when more packets are needed.
+var ErrMorePacketsNeeded = errors.New("need more packets")
+
+type decoderState int
+
+const (
+	decoderStateInitial decoderState = iota
+	decoderStateReadingFragmented
+)
+
+// Decoder is a RTP/JPEG decoder.
+type Decoder struct {
+	initialTs    uint32
+	initialTsSet bool
+
+	// tables sent in-band, indexed by Q
+	qtCache map[uint8][]byte
+
+	state         decoderState
+	fragmentedTs  uint32
+	fragmentedHdr []byte
+	fragmentedBuf []byte
+}
+
+// NewDecoder allocates a Decoder.
+func NewDecoder() *Decoder {
+	return &Decoder{
+		qtCache: make(map[uint8][]byte),
+	}
+}
+
+func (d *Decoder) decodeTimestamp(ts uint32) time.Duration {
+	return (time.Duration(ts) - time.Duration(d.initialTs)) * time.Second / rtpClockRate
+}
+
+// Decode decodes a JPEG image from RTP/JPEG packets.
+// It returns ErrMorePacketsNeeded until the last packet of the image is received.
+func (d *Decoder) Decode(byts []byte) (*ImageAndTimestamp, error) {
+	pkt := rtp.Packet{}
+	err := pkt.Unmarshal(byts)
+	if err != nil {
+		d.state = decoderStateInitial
+		return nil, err
+	}
+
+	if !d.initialTsSet {
+		d.initialTsSet = true
+		d.initialTs = pkt.Timestamp
+	}
+
+	// main JPEG header
+	// type-specific (1), fragment offset (3), type (1), Q (1), width (1), height (1)
+	if len(pkt.Payload) < 8 {
+		d.state = decoderStateInitial
+		return nil, fmt.Errorf("payload is too short")
+	}
+
+	offset := int(binary.BigEndian.Uint32(pkt.Payload) & 0xFFFFFF)
+	typ := pkt.Payload[4]
+	q := pkt.Payload[5]
+	width := int(pkt.Payload[6]) * 8
+	height := int(pkt.Payload[7]) * 8
+	pl := pkt.Payload[8:]
+
+	if (typ & 0x3F) > 1 {
+		d.state = decoderStateInitial
+		return nil, fmt.Errorf("unsupported type (%d)", typ)
+	}
+
+	var dri uint16
+	if typ >= 64 {
+		// restart marker header
+		if len(pl) < 4 {
+			d.state = decoderStateInitial
+			return nil, fmt.Errorf("payload is too short")
+		}
+		dri = binary.BigEndian.Uint16(pl)
+		pl = pl[4:]
+	}
+
+	if offset == 0 {
+		var qt []byte
+
+		if q >= 128 {
+			// quantization table header
+			// MBZ (1), precision (1), length (2)
+			if len(pl) < 4 {
+				d.state = decoderStateInitial
+				return nil, fmt.Errorf("payload is too short")
+			}
+
+			precision := pl[1]
+			length := int(binary.BigEndian.Uint16(pl[2:]))
+			pl = pl[4:]
+
+			if length == 0 {
+				if q == 255 {
+					d.state = decoderStateInitial
+					return nil, fmt.Errorf("quantization tables not provided")
+				}
+
+				var ok bool
+				qt, ok = d.qtCache[q]
+				if !ok {
+					d.state = decoderStateInitial
+					return nil, fmt.Errorf("quantization tables not received yet")
+				}
+			} else {
+				if precision != 0 || length != 128 {
+					d.state = decoderStateInitial
+					return nil, fmt.Errorf("unsupported quantization tables (precision %d, length %d)",
+						precision, length)
+				}
+
+				if len(pl) < length {
+					d.state = decoderStateInitial
+					return nil, fmt.Errorf("payload is too short")
+				}
+
+				qt = append([]byte(nil), pl[:length]...)
+				pl = pl[length:]
+
+				if q != 255 {
+					d.qtCache[q] = qt
+				}
+			}
+
+		} else {
+			qt = makeTables(q)
+		}
+
+		d.state = decoderStateReadingFragmented
+		d.fragmentedTs = pkt.Timestamp
+		d.fragmentedHdr = makeHeaders(typ, width, height, qt, dri)
+		d.fragmentedBuf = append([]byte(nil), pl...)
+
+	} else {
+		if d.state != decoderStateReadingFragmented {
+			return nil, fmt.Errorf("received a non-starting fragment without any previous starting fragment")
+		}
+
+		if pkt.Timestamp != d.fragmentedTs {
+			d.state = decoderStateInitial
+			return nil, fmt.Errorf("received a fragment with a different timestamp")
+		}
+
+		if offset != len(d.fragmentedBuf) {
+			d.state = decoderStateInitial
+			return nil, fmt.Errorf("received a fragment with a wrong offset (expected %d, got %d)",
+				len(d.fragmentedBuf), offset)
+		}
+
+		d.fragmentedBuf = append(d.fragmentedBuf, pl...)
+	}
+
+	if !pkt.Marker {
+		return nil, ErrMorePacketsNeeded
+	}
+
+	d.state = decoderStateInitial
+
+	image := append(d.fragmentedHdr, d.fragmentedBuf...)
+
+	// append EOI if not present
+	n := len(image)
+	if n < 2 || image[n-2] != 0xff || image[n-1] != 0xd9 {
+		image = append(image, 0xff, 0xd9)
+	}
+
+	return &ImageAndTimestamp{
+		Image:     image,
+		Timestamp: d.decodeTimestamp(pkt.Timestamp),
+	}, nil
+}
@@ -0,0 +1,12 @@
+// Package rtpmjpeg contains a RTP/JPEG decoder.
+package rtpmjpeg
+
+import (
+	"time"
+)
+
+// ImageAndTimestamp is a JPEG image and its timestamp.
+type ImageAndTimestamp struct {
+	Timestamp time.Duration
+	Image     []byte
+}
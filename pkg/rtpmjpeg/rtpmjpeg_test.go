@@ -0,0 +1,181 @@
+package rtpmjpeg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+)
+
+func testImage() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for y := 0; y < 48; y++ {
+		for x := 0; x < 64; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 4), uint8(y * 5), uint8((x * y * 7) % 256), 255})
+		}
+	}
+	return img
+}
+
+// encodeRTP splits a baseline JPEG image, generated by image/jpeg, into RTP/JPEG packets.
+func encodeRTP(t *testing.T, byts []byte, q uint8, maxSize int) [][]byte {
+	var qt []byte
+	var width, height int
+	var scan []byte
+
+	pos := 2 // SOI
+outer:
+	for {
+		require.Equal(t, byte(0xff), byts[pos])
+		marker := byts[pos+1]
+		size := int(binary.BigEndian.Uint16(byts[pos+2:]))
+		seg := byts[pos+4 : pos+2+size]
+
+		switch marker {
+		case 0xdb: // DQT
+			for len(seg) > 0 {
+				qt = append(qt, seg[1:65]...)
+				seg = seg[65:]
+			}
+
+		case 0xc0: // SOF0
+			height = int(binary.BigEndian.Uint16(seg[1:]))
+			width = int(binary.BigEndian.Uint16(seg[3:]))
+
+		case 0xda: // SOS
+			scan = byts[pos+2+size : len(byts)-2]
+			break outer
+		}
+
+		pos += 2 + size
+	}
+
+	var ret [][]byte
+	offset := 0
+
+	for i := 0; len(scan) > 0; i++ {
+		payload := []byte{
+			0, byte(offset >> 16), byte(offset >> 8), byte(offset),
+			1, q, byte(width / 8), byte(height / 8),
+		}
+
+		if offset == 0 && q >= 128 {
+			payload = append(payload, 0, 0, 0, 128)
+			payload = append(payload, qt...)
+		}
+
+		le := maxSize - len(payload)
+		if le > len(scan) {
+			le = len(scan)
+		}
+		payload = append(payload, scan[:le]...)
+		scan = scan[le:]
+		offset += le
+
+		pkt, err := (&rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				PayloadType:    26,
+				SequenceNumber: uint16(i),
+				Timestamp:      0x88776655 + 2250,
+				SSRC:           0x9dbb7812,
+				Marker:         len(scan) == 0,
+			},
+			Payload: payload,
+		}).Marshal()
+		require.NoError(t, err)
+
+		ret = append(ret, pkt)
+	}
+
+	return ret
+}
+
+func TestDecode(t *testing.T) {
+	for _, ca := range []struct {
+		name    string
+		quality int
+		q       uint8
+	}{
+		{
+			"standard tables",
+			50,
+			50,
+		},
+		{
+			"in-band tables",
+			80,
+			255,
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := jpeg.Encode(&buf, testImage(), &jpeg.Options{Quality: ca.quality})
+			require.NoError(t, err)
+
+			expected, err := jpeg.Decode(bytes.NewReader(buf.Bytes()))
+			require.NoError(t, err)
+
+			pkts := encodeRTP(t, buf.Bytes(), ca.q, 200)
+			require.Greater(t, len(pkts), 1)
+
+			d := NewDecoder()
+
+			// send an initial packet downstream
+			// in order to correctly compute the timestamp
+			_, err = d.Decode([]byte{
+				0x80, 0x1a, 0x44, 0xed, 0x88, 0x77, 0x66, 0x55,
+				0x9d, 0xbb, 0x78, 0x12, 0x00, 0x00, 0x00, 0x00,
+				0x01, 0x32, 0x08, 0x06,
+			})
+			require.Equal(t, ErrMorePacketsNeeded, err)
+
+			var dec *ImageAndTimestamp
+			for i, pkt := range pkts {
+				dec, err = d.Decode(pkt)
+				if i != len(pkts)-1 {
+					require.Equal(t, ErrMorePacketsNeeded, err)
+				} else {
+					require.NoError(t, err)
+				}
+			}
+
+			require.Equal(t, 25*time.Millisecond, dec.Timestamp)
+
+			img, err := jpeg.Decode(bytes.NewReader(dec.Image))
+			require.NoError(t, err)
+			require.Equal(t, expected, img)
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	d := NewDecoder()
+
+	_, err := d.Decode([]byte{
+		0x80, 0x1a, 0x44, 0xed, 0x88, 0x77, 0x66, 0x55,
+		0x9d, 0xbb, 0x78, 0x12, 0x00, 0x00, 0x00, 0x10,
+		0x01, 0x32, 0x08, 0x06, 0x01, 0x02,
+	})
+	require.EqualError(t, err, "received a non-starting fragment without any previous starting fragment")
+
+	_, err = d.Decode([]byte{
+		0x80, 0x1a, 0x44, 0xed, 0x88, 0x77, 0x66, 0x55,
+		0x9d, 0xbb, 0x78, 0x12, 0x00, 0x00, 0x00, 0x00,
+		0x05, 0x32, 0x08, 0x06,
+	})
+	require.EqualError(t, err, "unsupported type (5)")
+
+	_, err = d.Decode([]byte{
+		0x80, 0x1a, 0x44, 0xed, 0x88, 0x77, 0x66, 0x55,
+		0x9d, 0xbb, 0x78, 0x12, 0x00, 0x00, 0x00, 0x00,
+		0x01, 0x80, 0x08, 0x06, 0x00, 0x00, 0x00, 0x00,
+	})
+	require.EqualError(t, err, "quantization tables not received yet")
+}
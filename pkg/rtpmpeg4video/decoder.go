@@ -0,0 +1,35 @@
+package rtpmpeg4video
+
+import (
+	"github.com/majoyz/gortsplib/pkg/rtpreassembler"
+)
+
+// ErrMorePacketsNeeded is returned by Decoder.Decode when more packets are needed.
+var ErrMorePacketsNeeded = rtpreassembler.ErrMorePacketsNeeded
+
+// Decoder is a RTP/MPEG-4 Visual decoder.
+type Decoder struct {
+	r *rtpreassembler.Reassembler
+}
+
+// NewDecoder allocates a Decoder.
+func NewDecoder() *Decoder {
+	return &Decoder{
+		r: rtpreassembler.New("frame", frameMaxSize, rtpClockRate),
+	}
+}
+
+// Decode decodes a frame from RTP packets.
+// It returns ErrMorePacketsNeeded until the last packet of the frame,
+// that has the marker bit set, is received.
+func (d *Decoder) Decode(byts []byte) (*FrameAndTimestamp, error) {
+	frame, ts, err := d.r.Process(byts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FrameAndTimestamp{
+		Frame:     frame,
+		Timestamp: ts,
+	}, nil
+}
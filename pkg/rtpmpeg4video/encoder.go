@@ -0,0 +1,99 @@
+package rtpmpeg4video
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+// Encoder is a RTP/MPEG-4 Visual encoder.
+type Encoder struct {
+	payloadType    uint8
+	sequenceNumber uint16
+	ssrc           uint32
+	initialTs      uint32
+	payloadMaxSize int
+}
+
+// NewEncoder allocates an Encoder.
+func NewEncoder(payloadType uint8,
+	sequenceNumber *uint16,
+	ssrc *uint32,
+	initialTs *uint32) *Encoder {
+	return &Encoder{
+		payloadType: payloadType,
+		sequenceNumber: func() uint16 {
+			if sequenceNumber != nil {
+				return *sequenceNumber
+			}
+			return uint16(rand.Uint32())
+		}(),
+		ssrc: func() uint32 {
+			if ssrc != nil {
+				return *ssrc
+			}
+			return rand.Uint32()
+		}(),
+		initialTs: func() uint32 {
+			if initialTs != nil {
+				return *initialTs
+			}
+			return rand.Uint32()
+		}(),
+		payloadMaxSize: rtpPayloadMaxSize,
+	}
+}
+
+// SetPayloadMaxSize sets the maximum size of RTP payloads.
+// Frames bigger than this are split into multiple packets.
+// It defaults to 1460, that is suitable for a 1500 bytes MTU.
+func (e *Encoder) SetPayloadMaxSize(v int) {
+	e.payloadMaxSize = v
+}
+
+func (e *Encoder) encodeTimestamp(ts time.Duration) uint32 {
+	return e.initialTs + uint32(ts.Seconds()*rtpClockRate)
+}
+
+// Encode encodes a frame into RTP packets.
+// Frames bigger than the maximum payload size are split into multiple packets
+// with the same timestamp, and the marker bit is set on the last one.
+func (e *Encoder) Encode(ft *FrameAndTimestamp) ([][]byte, error) {
+	frame := ft.Frame
+	ts := e.encodeTimestamp(ft.Timestamp)
+	var ret [][]byte
+
+	for {
+		le := len(frame)
+		if le > e.payloadMaxSize {
+			le = e.payloadMaxSize
+		}
+
+		rpkt := rtp.Packet{
+			Header: rtp.Header{
+				Version:        rtpVersion,
+				PayloadType:    e.payloadType,
+				SequenceNumber: e.sequenceNumber,
+				Timestamp:      ts,
+				SSRC:           e.ssrc,
+				Marker:         le == len(frame),
+			},
+			Payload: frame[:le],
+		}
+		e.sequenceNumber++
+
+		byts, err := rpkt.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, byts)
+
+		frame = frame[le:]
+		if len(frame) == 0 {
+			break
+		}
+	}
+
+	return ret, nil
+}
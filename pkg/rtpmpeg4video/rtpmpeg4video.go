@@ -0,0 +1,24 @@
+// Package rtpmpeg4video contains a RTP decoder and encoder for
+// MPEG-4 Visual (RFC 6416).
+package rtpmpeg4video
+
+import (
+	"time"
+)
+
+const (
+	rtpVersion        = 0x02
+	rtpClockRate      = 90000 // MPEG-4 Visual always uses 90khz
+	rtpPayloadMaxSize = 1460  // 1500 (mtu) - 20 (ip header) - 8 (udp header) - 12 (rtp header)
+
+	// maximum size of a frame
+	frameMaxSize = 1 * 1024 * 1024
+)
+
+// FrameAndTimestamp is a MPEG-4 Visual frame and its timestamp.
+// A frame usually contains a single VOP (Video Object Plane), optionally
+// preceded by configuration headers (VOS, VO, VOL).
+type FrameAndTimestamp struct {
+	Timestamp time.Duration
+	Frame     []byte
+}
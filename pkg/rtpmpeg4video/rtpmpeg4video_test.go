@@ -0,0 +1,146 @@
+package rtpmpeg4video
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func mergeBytes(vals ...[]byte) []byte {
+	size := 0
+	for _, v := range vals {
+		size += len(v)
+	}
+	res := make([]byte, size)
+
+	pos := 0
+	for _, v := range vals {
+		n := copy(res[pos:], v)
+		pos += n
+	}
+
+	return res
+}
+
+var cases = []struct {
+	name string
+	dec  *FrameAndTimestamp
+	enc  [][]byte
+}{
+	{
+		"single",
+		&FrameAndTimestamp{
+			Timestamp: 20 * time.Millisecond,
+			Frame: mergeBytes(
+				[]byte{0x00, 0x00, 0x01, 0xb6},
+				bytes.Repeat([]byte{0x01, 0x02, 0x03, 0x04}, 100),
+			),
+		},
+		[][]byte{
+			mergeBytes(
+				[]byte{
+					0x80, 0xe0, 0x44, 0xed, 0x88, 0x77, 0x6d, 0x5d,
+					0x9d, 0xbb, 0x78, 0x12, 0x00, 0x00, 0x01, 0xb6,
+				},
+				bytes.Repeat([]byte{0x01, 0x02, 0x03, 0x04}, 100),
+			),
+		},
+	},
+	{
+		"split",
+		&FrameAndTimestamp{
+			Timestamp: 20 * time.Millisecond,
+			Frame: mergeBytes(
+				[]byte{0x00, 0x00, 0x01, 0xb6},
+				bytes.Repeat([]byte{0x01, 0x02, 0x03, 0x04}, 499),
+			),
+		},
+		[][]byte{
+			mergeBytes(
+				[]byte{
+					0x80, 0x60, 0x44, 0xed, 0x88, 0x77, 0x6d, 0x5d,
+					0x9d, 0xbb, 0x78, 0x12, 0x00, 0x00, 0x01, 0xb6,
+				},
+				bytes.Repeat([]byte{0x01, 0x02, 0x03, 0x04}, 364),
+			),
+			mergeBytes(
+				[]byte{
+					0x80, 0xe0, 0x44, 0xee, 0x88, 0x77, 0x6d, 0x5d,
+					0x9d, 0xbb, 0x78, 0x12,
+				},
+				bytes.Repeat([]byte{0x01, 0x02, 0x03, 0x04}, 135),
+			),
+		},
+	},
+}
+
+func TestEncode(t *testing.T) {
+	for _, ca := range cases {
+		t.Run(ca.name, func(t *testing.T) {
+			sequenceNumber := uint16(0x44ed)
+			ssrc := uint32(0x9dbb7812)
+			initialTs := uint32(0x88776655)
+			e := NewEncoder(96, &sequenceNumber, &ssrc, &initialTs)
+			enc, err := e.Encode(ca.dec)
+			require.NoError(t, err)
+			require.Equal(t, ca.enc, enc)
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	for _, ca := range cases {
+		t.Run(ca.name, func(t *testing.T) {
+			d := NewDecoder()
+
+			// send an initial packet downstream
+			// in order to correctly compute the timestamp
+			_, err := d.Decode([]byte{
+				0x80, 0xe0, 0x44, 0xed, 0x88, 0x77, 0x66, 0x55,
+				0x9d, 0xbb, 0x78, 0x12, 0x01,
+			})
+			require.NoError(t, err)
+
+			var dec *FrameAndTimestamp
+			for i, byts := range ca.enc {
+				dec, err = d.Decode(byts)
+				if i != len(ca.enc)-1 {
+					require.Equal(t, ErrMorePacketsNeeded, err)
+				} else {
+					require.NoError(t, err)
+				}
+			}
+
+			require.Equal(t, ca.dec, dec)
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	d := NewDecoder()
+
+	_, err := d.Decode([]byte{0x80, 0xe0})
+	require.EqualError(t, err, "RTP header size insufficient: 2 < 4")
+
+	_, err = d.Decode(cases[1].enc[0])
+	require.Equal(t, ErrMorePacketsNeeded, err)
+
+	_, err = d.Decode(cases[0].enc[0])
+	require.EqualError(t, err, "discarding frame since a RTP packet is missing")
+}
+
+func TestEncodePayloadMaxSize(t *testing.T) {
+	e := NewEncoder(96, nil, nil, nil)
+	e.SetPayloadMaxSize(100)
+
+	enc, err := e.Encode(&FrameAndTimestamp{
+		Frame: bytes.Repeat([]byte{0x01}, 250),
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, len(enc))
+	require.Equal(t, 12+100, len(enc[0]))
+	require.Equal(t, 12+100, len(enc[1]))
+	require.Equal(t, 12+50, len(enc[2]))
+}
@@ -0,0 +1,58 @@
+package rtpmpegts
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+// Decoder is a RTP/MPEG-TS decoder.
+type Decoder struct {
+	initialTs    uint32
+	initialTsSet bool
+}
+
+// NewDecoder allocates a Decoder.
+func NewDecoder() *Decoder {
+	return &Decoder{}
+}
+
+func (d *Decoder) decodeTimestamp(ts uint32) time.Duration {
+	return (time.Duration(ts) - time.Duration(d.initialTs)) * time.Second / rtpClockRate
+}
+
+// Decode extracts MPEG-TS packets from a RTP packet.
+// Each RTP packet contains an integral number of MPEG-TS packets.
+func (d *Decoder) Decode(byts []byte) (*PacketsAndTimestamp, error) {
+	pkt := rtp.Packet{}
+	err := pkt.Unmarshal(byts)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pkt.Payload) == 0 || (len(pkt.Payload)%PacketSize) != 0 {
+		return nil, fmt.Errorf("payload size (%d) is not a multiple of %d", len(pkt.Payload), PacketSize)
+	}
+
+	n := len(pkt.Payload) / PacketSize
+	packets := make([][]byte, n)
+
+	for i := 0; i < n; i++ {
+		p := pkt.Payload[i*PacketSize : (i+1)*PacketSize]
+		if p[0] != syncByte {
+			return nil, fmt.Errorf("invalid sync byte (0x%.2x)", p[0])
+		}
+		packets[i] = p
+	}
+
+	if !d.initialTsSet {
+		d.initialTsSet = true
+		d.initialTs = pkt.Timestamp
+	}
+
+	return &PacketsAndTimestamp{
+		Packets:   packets,
+		Timestamp: d.decodeTimestamp(pkt.Timestamp),
+	}, nil
+}
@@ -0,0 +1,21 @@
+// Package rtpmpegts contains a RTP decoder for MPEG-TS (RFC 2250).
+package rtpmpegts
+
+import (
+	"time"
+)
+
+const (
+	rtpClockRate = 90000 // MPEG-TS always uses 90khz
+
+	// PacketSize is the size of a MPEG-TS packet.
+	PacketSize = 188
+
+	syncByte = 0x47
+)
+
+// PacketsAndTimestamp is a group of MPEG-TS packets and their timestamp.
+type PacketsAndTimestamp struct {
+	Timestamp time.Duration
+	Packets   [][]byte
+}
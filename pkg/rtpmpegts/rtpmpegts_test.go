@@ -0,0 +1,59 @@
+package rtpmpegts
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+)
+
+func tsPacket(b byte) []byte {
+	return append([]byte{0x47}, bytes.Repeat([]byte{b}, PacketSize-1)...)
+}
+
+func packet(ts uint32, payload []byte) []byte {
+	byts, _ := (&rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			PayloadType:    33,
+			SequenceNumber: 100,
+			Timestamp:      ts,
+			SSRC:           0x9dbb7812,
+		},
+		Payload: payload,
+	}).Marshal()
+	return byts
+}
+
+func TestDecode(t *testing.T) {
+	d := NewDecoder()
+
+	dec, err := d.Decode(packet(90000, tsPacket(1)))
+	require.NoError(t, err)
+	require.Equal(t, &PacketsAndTimestamp{
+		Timestamp: 0,
+		Packets:   [][]byte{tsPacket(1)},
+	}, dec)
+
+	dec, err = d.Decode(packet(90000+45000, append(tsPacket(2), tsPacket(3)...)))
+	require.NoError(t, err)
+	require.Equal(t, &PacketsAndTimestamp{
+		Timestamp: 500 * time.Millisecond,
+		Packets:   [][]byte{tsPacket(2), tsPacket(3)},
+	}, dec)
+}
+
+func TestDecodeErrors(t *testing.T) {
+	d := NewDecoder()
+
+	_, err := d.Decode([]byte{0x80, 0x21})
+	require.EqualError(t, err, "RTP header size insufficient: 2 < 4")
+
+	_, err = d.Decode(packet(0, []byte{0x47, 0x01, 0x02}))
+	require.EqualError(t, err, "payload size (3) is not a multiple of 188")
+
+	_, err = d.Decode(packet(0, append(tsPacket(1), bytes.Repeat([]byte{0x01}, PacketSize)...)))
+	require.EqualError(t, err, "invalid sync byte (0x01)")
+}
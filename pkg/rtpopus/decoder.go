@@ -0,0 +1,46 @@
+package rtpopus
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+// Decoder is a RTP/Opus decoder.
+type Decoder struct {
+	initialTs    uint32
+	initialTsSet bool
+}
+
+// NewDecoder allocates a Decoder.
+func NewDecoder() *Decoder {
+	return &Decoder{}
+}
+
+func (d *Decoder) decodeTimestamp(ts uint32) time.Duration {
+	return (time.Duration(ts) - time.Duration(d.initialTs)) * time.Second / rtpClockRate
+}
+
+// Decode decodes an Opus packet from a RTP/Opus packet.
+func (d *Decoder) Decode(byts []byte) (*PacketAndTimestamp, error) {
+	pkt := rtp.Packet{}
+	err := pkt.Unmarshal(byts)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pkt.Payload) == 0 {
+		return nil, fmt.Errorf("payload is empty")
+	}
+
+	if !d.initialTsSet {
+		d.initialTsSet = true
+		d.initialTs = pkt.Timestamp
+	}
+
+	return &PacketAndTimestamp{
+		Packet:    pkt.Payload,
+		Timestamp: d.decodeTimestamp(pkt.Timestamp),
+	}, nil
+}
@@ -0,0 +1,90 @@
+package rtpopus
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+const (
+	rtpVersion        = 0x02
+	rtpPayloadMaxSize = 1460  // 1500 (mtu) - 20 (ip header) - 8 (udp header) - 12 (rtp header)
+	rtpClockRate      = 48000 // opus always uses 48khz
+)
+
+// Encoder is a RTP/Opus encoder.
+type Encoder struct {
+	payloadType    uint8
+	sequenceNumber uint16
+	ssrc           uint32
+	initialTs      uint32
+	payloadMaxSize int
+}
+
+// NewEncoder allocates an Encoder.
+func NewEncoder(payloadType uint8,
+	sequenceNumber *uint16,
+	ssrc *uint32,
+	initialTs *uint32) *Encoder {
+	return &Encoder{
+		payloadType: payloadType,
+		sequenceNumber: func() uint16 {
+			if sequenceNumber != nil {
+				return *sequenceNumber
+			}
+			return uint16(rand.Uint32())
+		}(),
+		ssrc: func() uint32 {
+			if ssrc != nil {
+				return *ssrc
+			}
+			return rand.Uint32()
+		}(),
+		initialTs: func() uint32 {
+			if initialTs != nil {
+				return *initialTs
+			}
+			return rand.Uint32()
+		}(),
+		payloadMaxSize: rtpPayloadMaxSize,
+	}
+}
+
+// SetPayloadMaxSize sets the maximum size of RTP payloads.
+// Packets bigger than this can't be encoded.
+// It defaults to 1460, that is suitable for a 1500 bytes MTU.
+func (e *Encoder) SetPayloadMaxSize(v int) {
+	e.payloadMaxSize = v
+}
+
+func (e *Encoder) encodeTimestamp(ts time.Duration) uint32 {
+	return e.initialTs + uint32(ts.Seconds()*rtpClockRate)
+}
+
+// Encode encodes an Opus packet into a RTP/Opus packet.
+func (e *Encoder) Encode(pt *PacketAndTimestamp) ([]byte, error) {
+	if len(pt.Packet) > e.payloadMaxSize {
+		return nil, fmt.Errorf("data is too big")
+	}
+
+	rpkt := rtp.Packet{
+		Header: rtp.Header{
+			Version:        rtpVersion,
+			PayloadType:    e.payloadType,
+			SequenceNumber: e.sequenceNumber,
+			Timestamp:      e.encodeTimestamp(pt.Timestamp),
+			SSRC:           e.ssrc,
+		},
+		Payload: pt.Packet,
+	}
+	e.sequenceNumber++
+
+	frame, err := rpkt.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	return frame, nil
+}
@@ -0,0 +1,12 @@
+// Package rtpopus contains a RTP/Opus decoder and encoder.
+package rtpopus
+
+import (
+	"time"
+)
+
+// PacketAndTimestamp is an Opus packet and its timestamp.
+type PacketAndTimestamp struct {
+	Timestamp time.Duration
+	Packet    []byte
+}
@@ -0,0 +1,61 @@
+package rtpopus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var cases = []struct {
+	name string
+	dec  *PacketAndTimestamp
+	enc  []byte
+}{
+	{
+		"single",
+		&PacketAndTimestamp{
+			Timestamp: 20 * time.Millisecond,
+			Packet:    []byte{0xfc, 0xff, 0xfe, 0x01, 0x02, 0x03, 0x04},
+		},
+		[]byte{
+			0x80, 0x60, 0x44, 0xed, 0x88, 0x77, 0x6a, 0x15,
+			0x9d, 0xbb, 0x78, 0x12, 0xfc, 0xff, 0xfe, 0x01,
+			0x02, 0x03, 0x04,
+		},
+	},
+}
+
+func TestEncode(t *testing.T) {
+	for _, ca := range cases {
+		t.Run(ca.name, func(t *testing.T) {
+			sequenceNumber := uint16(0x44ed)
+			ssrc := uint32(0x9dbb7812)
+			initialTs := uint32(0x88776655)
+			e := NewEncoder(96, &sequenceNumber, &ssrc, &initialTs)
+			enc, err := e.Encode(ca.dec)
+			require.NoError(t, err)
+			require.Equal(t, ca.enc, enc)
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	for _, ca := range cases {
+		t.Run(ca.name, func(t *testing.T) {
+			d := NewDecoder()
+
+			// send an initial packet downstream
+			// in order to correctly compute the timestamp
+			_, err := d.Decode([]byte{
+				0x80, 0x60, 0x44, 0xed, 0x88, 0x77, 0x66, 0x55,
+				0x9d, 0xbb, 0x78, 0x12, 0xfc,
+			})
+			require.NoError(t, err)
+
+			dec, err := d.Decode(ca.enc)
+			require.NoError(t, err)
+			require.Equal(t, ca.dec, dec)
+		})
+	}
+}
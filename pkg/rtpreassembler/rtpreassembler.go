@@ -0,0 +1,102 @@
+// Package rtpreassembler contains a utility to reassemble payloads that are
+// split into consecutive RTP packets with the same timestamp, in which the
+// last packet of each payload has the marker bit set.
+package rtpreassembler
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+// ErrMorePacketsNeeded is returned by Reassembler.Process when more packets are needed.
+var ErrMorePacketsNeeded = errors.New("need more packets")
+
+// Reassembler is a utility that receives RTP packets and returns
+// the payloads they contain, with their timestamps.
+type Reassembler struct {
+	name      string
+	maxSize   int
+	clockRate int
+
+	initialTs    uint32
+	initialTsSet bool
+
+	fragmented    bool
+	fragmentedTs  uint32
+	fragmentedSeq uint16
+	fragmentedBuf []byte
+}
+
+// New allocates a Reassembler.
+// name is the name of the payload (i.e. "frame") and is used in errors.
+func New(name string, maxSize int, clockRate int) *Reassembler {
+	return &Reassembler{
+		name:      name,
+		maxSize:   maxSize,
+		clockRate: clockRate,
+	}
+}
+
+func (r *Reassembler) decodeTimestamp(ts uint32) time.Duration {
+	return (time.Duration(ts) - time.Duration(r.initialTs)) * time.Second / time.Duration(r.clockRate)
+}
+
+func (r *Reassembler) reset() {
+	r.fragmented = false
+	r.fragmentedBuf = nil
+}
+
+// Process processes a RTP packet.
+// It returns ErrMorePacketsNeeded until the last packet of the payload,
+// that has the marker bit set, is received.
+func (r *Reassembler) Process(byts []byte) ([]byte, time.Duration, error) {
+	pkt := rtp.Packet{}
+	err := pkt.Unmarshal(byts)
+	if err != nil {
+		r.reset()
+		return nil, 0, err
+	}
+
+	if !r.initialTsSet {
+		r.initialTsSet = true
+		r.initialTs = pkt.Timestamp
+	}
+
+	if r.fragmented {
+		if pkt.Timestamp != r.fragmentedTs || pkt.SequenceNumber != r.fragmentedSeq+1 {
+			r.reset()
+			return nil, 0, fmt.Errorf("discarding %s since a RTP packet is missing", r.name)
+		}
+	}
+
+	if len(r.fragmentedBuf)+len(pkt.Payload) > r.maxSize {
+		r.reset()
+		return nil, 0, fmt.Errorf("%s size exceeds maximum allowed (%d)", r.name, r.maxSize)
+	}
+
+	if !pkt.Marker {
+		if !r.fragmented {
+			r.fragmented = true
+			r.fragmentedTs = pkt.Timestamp
+		}
+
+		r.fragmentedSeq = pkt.SequenceNumber
+		r.fragmentedBuf = append(r.fragmentedBuf, pkt.Payload...)
+		return nil, 0, ErrMorePacketsNeeded
+	}
+
+	var payload []byte
+	if r.fragmented {
+		payload = append(r.fragmentedBuf, pkt.Payload...)
+	} else {
+		// copy the payload, since the input buffer may be reused
+		payload = append([]byte(nil), pkt.Payload...)
+	}
+
+	r.reset()
+
+	return payload, r.decodeTimestamp(pkt.Timestamp), nil
+}
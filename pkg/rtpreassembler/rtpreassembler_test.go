@@ -0,0 +1,65 @@
+package rtpreassembler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+)
+
+func packet(seq uint16, ts uint32, marker bool, payload []byte) []byte {
+	byts, _ := (&rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			Marker:         marker,
+			PayloadType:    96,
+			SequenceNumber: seq,
+			Timestamp:      ts,
+			SSRC:           0x9dbb7812,
+		},
+		Payload: payload,
+	}).Marshal()
+	return byts
+}
+
+func TestProcess(t *testing.T) {
+	r := New("frame", 1024, 90000)
+
+	byts := packet(100, 90000, true, []byte{0x01, 0x02})
+	payload, ts, err := r.Process(byts)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x01, 0x02}, payload)
+	require.Equal(t, time.Duration(0), ts)
+
+	// the payload is copied
+	byts[len(byts)-1] = 0x05
+	require.Equal(t, []byte{0x01, 0x02}, payload)
+
+	_, _, err = r.Process(packet(101, 180000, false, []byte{0x03}))
+	require.Equal(t, ErrMorePacketsNeeded, err)
+
+	payload, ts, err = r.Process(packet(102, 180000, true, []byte{0x04}))
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x03, 0x04}, payload)
+	require.Equal(t, 1*time.Second, ts)
+}
+
+func TestProcessErrors(t *testing.T) {
+	r := New("frame", 4, 90000)
+
+	_, _, err := r.Process([]byte{0x80, 0x60})
+	require.EqualError(t, err, "RTP header size insufficient: 2 < 4")
+
+	_, _, err = r.Process(packet(100, 90000, false, []byte{0x01}))
+	require.Equal(t, ErrMorePacketsNeeded, err)
+
+	_, _, err = r.Process(packet(102, 90000, true, []byte{0x02}))
+	require.EqualError(t, err, "discarding frame since a RTP packet is missing")
+
+	_, _, err = r.Process(packet(103, 90000, false, []byte{0x01, 0x02, 0x03}))
+	require.Equal(t, ErrMorePacketsNeeded, err)
+
+	_, _, err = r.Process(packet(104, 90000, true, []byte{0x04, 0x05}))
+	require.EqualError(t, err, "frame size exceeds maximum allowed (4)")
+}
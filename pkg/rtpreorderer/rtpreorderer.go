@@ -0,0 +1,188 @@
+// Package rtpreorderer contains a utility to reorder RTP packets.
+package rtpreorderer
+
+import (
+	"time"
+)
+
+// Reorderer is a utility that receives RTP packets in any order
+// and returns them sorted by sequence number.
+// Packets that arrive too late, or duplicated, are discarded.
+type Reorderer struct {
+	size   int
+	maxAge time.Duration
+	now    func() time.Time
+
+	initialized bool
+	expected    uint16
+	buffer      [][]byte
+	times       []time.Time
+	absPos      int
+	negative    int
+}
+
+// New allocates a Reorderer that is able to buffer up to size packets.
+// Buffered packets are released when the missing ones are received
+// or when the buffer is full.
+func New(size int) *Reorderer {
+	return NewWithMaxAge(size, 0)
+}
+
+// NewWithMaxAge allocates a Reorderer that is able to buffer up to size packets
+// and that, in addition, releases buffered packets when they have been waiting
+// for maxAge, skipping the missing ones.
+// If maxAge is zero, packets can wait indefinitely.
+func NewWithMaxAge(size int, maxAge time.Duration) *Reorderer {
+	return &Reorderer{
+		size:   size,
+		maxAge: maxAge,
+		now:    time.Now,
+		buffer: make([][]byte, size),
+		times:  make([]time.Time, size),
+	}
+}
+
+// Process processes a RTP packet.
+// It returns a sequence of ordered packets, that is empty when the packet
+// has been buffered or discarded.
+// The packet content is copied when buffered, therefore the caller can reuse it.
+func (r *Reorderer) Process(pkt []byte) [][]byte {
+	ret := r.Flush()
+	return append(ret, r.process(pkt)...)
+}
+
+// Flush returns the buffered packets that have been waiting for more than the
+// maximum age, together with the ones that follow them, skipping missing packets.
+// Since Process releases these packets only when a new packet is received,
+// Flush must be called periodically by the caller.
+func (r *Reorderer) Flush() [][]byte {
+	if r.maxAge == 0 {
+		return nil
+	}
+
+	now := r.now()
+	var ret [][]byte
+
+	for {
+		oldest, ok := r.oldestTime()
+		if !ok || now.Sub(oldest) < r.maxAge {
+			return ret
+		}
+
+		// skip missing packets
+		for r.buffer[r.absPos] == nil {
+			r.absPos = (r.absPos + 1) % r.size
+			r.expected++
+		}
+
+		ret = append(ret, r.releaseConsecutive()...)
+	}
+}
+
+func (r *Reorderer) oldestTime() (time.Time, bool) {
+	var ret time.Time
+	found := false
+
+	for i, buf := range r.buffer {
+		if buf != nil && (!found || r.times[i].Before(ret)) {
+			ret = r.times[i]
+			found = true
+		}
+	}
+
+	return ret, found
+}
+
+// releaseConsecutive returns the buffered packets that follow the expected one.
+func (r *Reorderer) releaseConsecutive() [][]byte {
+	var ret [][]byte
+
+	for r.buffer[r.absPos] != nil {
+		ret = append(ret, r.buffer[r.absPos])
+		r.buffer[r.absPos] = nil
+		r.absPos = (r.absPos + 1) % r.size
+		r.expected++
+	}
+
+	return ret
+}
+
+func (r *Reorderer) process(pkt []byte) [][]byte {
+	// do not parse the entire packet, extract only the sequence number
+	if len(pkt) < 12 {
+		return [][]byte{pkt}
+	}
+	seq := uint16(pkt[2])<<8 | uint16(pkt[3])
+
+	if !r.initialized {
+		r.initialized = true
+		r.expected = seq + 1
+		return [][]byte{pkt}
+	}
+
+	relPos := int(int16(seq - r.expected))
+
+	// packet is a duplicate or has been already skipped
+	if relPos < 0 {
+		r.negative++
+
+		// stream has probably been restarted
+		if r.negative > r.size {
+			r.reset()
+			r.expected = seq + 1
+			return [][]byte{pkt}
+		}
+
+		return nil
+	}
+	r.negative = 0
+
+	// there's a missing packet and the buffer is full.
+	// return all buffered packets and purge the buffer.
+	if relPos >= r.size {
+		var ret [][]byte
+
+		for i := 0; i < r.size; i++ {
+			p := (r.absPos + i) % r.size
+			if r.buffer[p] != nil {
+				ret = append(ret, r.buffer[p])
+			}
+		}
+		ret = append(ret, pkt)
+
+		r.reset()
+		r.expected = seq + 1
+		return ret
+	}
+
+	// there's a missing packet
+	if relPos != 0 {
+		p := (r.absPos + relPos) % r.size
+
+		// packet is a duplicate
+		if r.buffer[p] != nil {
+			return nil
+		}
+
+		r.buffer[p] = append([]byte(nil), pkt...)
+		r.times[p] = r.now()
+		return nil
+	}
+
+	// packet is the expected one.
+	// return it together with all the following buffered packets.
+	ret := [][]byte{pkt}
+	r.buffer[r.absPos] = nil
+	r.absPos = (r.absPos + 1) % r.size
+	r.expected++
+
+	return append(ret, r.releaseConsecutive()...)
+}
+
+func (r *Reorderer) reset() {
+	for i := range r.buffer {
+		r.buffer[i] = nil
+	}
+	r.absPos = 0
+	r.negative = 0
+}
@@ -0,0 +1,112 @@
+package rtpreorderer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+)
+
+func packet(seq uint16) []byte {
+	byts, _ := (&rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			PayloadType:    96,
+			SequenceNumber: seq,
+			Timestamp:      0x88776655,
+			SSRC:           0x9dbb7812,
+		},
+		Payload: []byte{0x01, 0x02},
+	}).Marshal()
+	return byts
+}
+
+func TestProcess(t *testing.T) {
+	for _, ca := range []struct {
+		name string
+		in   []uint16
+		out  [][]uint16
+	}{
+		{
+			"ordered",
+			[]uint16{65534, 65535, 0, 1},
+			[][]uint16{{65534}, {65535}, {0}, {1}},
+		},
+		{
+			"reordered",
+			[]uint16{100, 102, 103, 101, 104},
+			[][]uint16{{100}, nil, nil, {101, 102, 103}, {104}},
+		},
+		{
+			"reordered with overflow",
+			[]uint16{65534, 0, 65535, 1},
+			[][]uint16{{65534}, nil, {65535, 0}, {1}},
+		},
+		{
+			"duplicated",
+			[]uint16{100, 100, 102, 102, 101},
+			[][]uint16{{100}, nil, nil, nil, {101, 102}},
+		},
+		{
+			"buffer full",
+			[]uint16{100, 102, 104, 105},
+			[][]uint16{{100}, nil, nil, {102, 104, 105}},
+		},
+		{
+			"stream restarted",
+			[]uint16{1000, 10, 11, 12, 13, 14, 15},
+			[][]uint16{{1000}, nil, nil, nil, nil, {14}, {15}},
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			r := New(4)
+
+			for i, seq := range ca.in {
+				out := r.Process(packet(seq))
+
+				var expected [][]byte
+				for _, seq := range ca.out[i] {
+					expected = append(expected, packet(seq))
+				}
+				require.Equal(t, expected, out)
+			}
+		})
+	}
+}
+
+func TestFlush(t *testing.T) {
+	now := time.Date(2008, 5, 20, 22, 15, 20, 0, time.UTC)
+
+	r := NewWithMaxAge(64, 500*time.Millisecond)
+	r.now = func() time.Time { return now }
+
+	require.Equal(t, [][]byte{packet(100)}, r.Process(packet(100)))
+	require.Equal(t, [][]byte(nil), r.Process(packet(102)))
+
+	now = now.Add(300 * time.Millisecond)
+	require.Equal(t, [][]byte(nil), r.Process(packet(103)))
+	require.Equal(t, [][]byte(nil), r.Process(packet(106)))
+	require.Equal(t, [][]byte(nil), r.Flush())
+
+	// 102 and 103 are older than the maximum age, 101 is skipped
+	now = now.Add(200 * time.Millisecond)
+	require.Equal(t, [][]byte{packet(102), packet(103)}, r.Flush())
+
+	// 106 is not expired yet, 104 can still be received
+	require.Equal(t, [][]byte{packet(104)}, r.Process(packet(104)))
+
+	// 106 is released by Process, 105 is skipped
+	now = now.Add(300 * time.Millisecond)
+	require.Equal(t, [][]byte{packet(106), packet(107)}, r.Process(packet(107)))
+	require.Equal(t, [][]byte(nil), r.Flush())
+}
+
+func TestFlushDisabled(t *testing.T) {
+	r := New(64)
+	r.now = func() time.Time { return time.Now().Add(time.Hour) }
+
+	require.Equal(t, [][]byte{packet(100)}, r.Process(packet(100)))
+	require.Equal(t, [][]byte(nil), r.Process(packet(102)))
+	require.Equal(t, [][]byte(nil), r.Flush())
+}
@@ -0,0 +1,89 @@
+// Package rtprewriter contains a utility to keep a RTP stream continuous
+// when its source restarts.
+package rtprewriter
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/majoyz/gortsplib/pkg/rtpseeker"
+)
+
+const (
+	// maximum forward jump of the sequence number that is considered
+	// a packet loss instead of a restart (RFC 3550, appendix A.1).
+	maxDropout = 3000
+
+	// maximum backward jump of the sequence number that is considered
+	// a reordering instead of a restart (RFC 3550, appendix A.1).
+	maxMisorder = 100
+)
+
+// Rewriter is a utility that rewrites the SSRC, the sequence number and the
+// timestamp of RTP packets coming from a source that may restart, like a camera
+// that reboots, in order to produce a continuous stream.
+// A restart is detected when the SSRC changes, or when the sequence number
+// jumps forward or backward by a large amount.
+type Rewriter struct {
+	ssrc   uint32
+	seeker *rtpseeker.Seeker
+
+	initialized bool
+	lastSSRC    uint32
+	lastSeq     uint16
+}
+
+// New allocates a Rewriter.
+// clockRate is the clock rate of the track.
+func New(clockRate int) *Rewriter {
+	return &Rewriter{
+		ssrc:   rand.Uint32(),
+		seeker: rtpseeker.New(clockRate),
+	}
+}
+
+// SSRC returns the SSRC of outgoing packets.
+func (r *Rewriter) SSRC() uint32 {
+	return r.ssrc
+}
+
+// Process rewrites a RTP packet.
+// ts is the time at which the packet has been received.
+// It returns true if the source has restarted.
+// The packet is edited in place.
+func (r *Rewriter) Process(ts time.Time, pkt []byte) bool {
+	// do not parse the entire packet, edit only the needed fields
+	if len(pkt) < 12 {
+		return false
+	}
+
+	seq := uint16(pkt[2])<<8 | uint16(pkt[3])
+	ssrc := uint32(pkt[8])<<24 | uint32(pkt[9])<<16 | uint32(pkt[10])<<8 | uint32(pkt[11])
+
+	restarted := false
+
+	if r.initialized {
+		diff := seq - r.lastSeq
+		if ssrc != r.lastSSRC || (diff > maxDropout && diff < (0x10000-maxMisorder)) {
+			restarted = true
+			r.seeker.Seek(ts)
+		}
+	}
+
+	// do not move backwards in case of reordered packets
+	if !r.initialized || restarted || int16(seq-r.lastSeq) > 0 {
+		r.lastSeq = seq
+	}
+
+	r.initialized = true
+	r.lastSSRC = ssrc
+
+	r.seeker.Process(ts, pkt)
+
+	pkt[8] = byte(r.ssrc >> 24)
+	pkt[9] = byte(r.ssrc >> 16)
+	pkt[10] = byte(r.ssrc >> 8)
+	pkt[11] = byte(r.ssrc)
+
+	return restarted
+}
@@ -0,0 +1,98 @@
+package rtprewriter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+)
+
+type packetData struct {
+	seq  uint16
+	ts   uint32
+	ssrc uint32
+}
+
+func packet(d packetData) []byte {
+	byts, _ := (&rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			PayloadType:    96,
+			SequenceNumber: d.seq,
+			Timestamp:      d.ts,
+			SSRC:           d.ssrc,
+		},
+		Payload: []byte{0x01, 0x02},
+	}).Marshal()
+	return byts
+}
+
+func TestRewriter(t *testing.T) {
+	for _, ca := range []struct {
+		name      string
+		in        []packetData
+		restarted []bool
+		// sequence numbers and timestamps relative to the first output packet
+		out []packetData
+	}{
+		{
+			"continuous",
+			[]packetData{{100, 1000, 1}, {101, 4000, 1}, {102, 7000, 1}},
+			[]bool{false, false, false},
+			[]packetData{{0, 0, 0}, {1, 3000, 0}, {2, 6000, 0}},
+		},
+		{
+			"ssrc changed",
+			[]packetData{{100, 1000, 1}, {101, 4000, 1}, {20, 50, 2}, {21, 3050, 2}},
+			[]bool{false, false, true, false},
+			[]packetData{{0, 0, 0}, {1, 3000, 0}, {2, 3000 + 9000, 0}, {3, 3000 + 9000 + 3000, 0}},
+		},
+		{
+			"sequence number jump",
+			[]packetData{{100, 1000, 1}, {101, 4000, 1}, {30000, 50, 1}, {30001, 3050, 1}},
+			[]bool{false, false, true, false},
+			[]packetData{{0, 0, 0}, {1, 3000, 0}, {2, 3000 + 9000, 0}, {3, 3000 + 9000 + 3000, 0}},
+		},
+		{
+			"reordered",
+			[]packetData{{100, 1000, 1}, {102, 7000, 1}, {101, 4000, 1}, {103, 10000, 1}},
+			[]bool{false, false, false, false},
+			[]packetData{{0, 0, 0}, {2, 6000, 0}, {1, 3000, 0}, {3, 9000, 0}},
+		},
+		{
+			"lost packets",
+			[]packetData{{65534, 1000, 1}, {10, 4000, 1}},
+			[]bool{false, false},
+			[]packetData{{0, 0, 0}, {12, 3000, 0}},
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			r := New(90000)
+
+			t0 := time.Date(2008, 05, 20, 22, 15, 20, 0, time.UTC)
+			var firstSeq uint16
+			var firstTS uint32
+
+			for i, in := range ca.in {
+				pkt := packet(in)
+				// packets are received every 100ms
+				restarted := r.Process(t0.Add(time.Duration(i)*100*time.Millisecond), pkt)
+				require.Equal(t, ca.restarted[i], restarted)
+
+				var p rtp.Packet
+				err := p.Unmarshal(pkt)
+				require.NoError(t, err)
+
+				if i == 0 {
+					firstSeq = p.SequenceNumber
+					firstTS = p.Timestamp
+				}
+
+				require.Equal(t, r.SSRC(), p.SSRC)
+				require.Equal(t, ca.out[i].seq, p.SequenceNumber-firstSeq)
+				require.Equal(t, ca.out[i].ts, p.Timestamp-firstTS)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+// Package rtpseeker contains a utility to keep RTP sequence numbers and
+// timestamps continuous across seeks.
+package rtpseeker
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Seeker is a utility that rewrites the sequence number and the timestamp
+// of outgoing RTP packets, in order to keep them continuous when the
+// position of an on-demand stream changes.
+type Seeker struct {
+	clockRate int64
+
+	// data of the last written packet
+	initialized bool
+	lastSeq     uint16
+	lastTS      uint32
+	lastTime    time.Time
+
+	// data of the next packet, when a seek is pending
+	pending bool
+	nextSeq uint16
+	nextTS  uint32
+
+	seqOffset uint16
+	tsOffset  uint32
+}
+
+// New allocates a Seeker.
+// clockRate is the clock rate of the track.
+func New(clockRate int) *Seeker {
+	return &Seeker{
+		clockRate: int64(clockRate),
+		pending:   true,
+		nextSeq:   uint16(rand.Uint32()),
+		nextTS:    rand.Uint32(),
+	}
+}
+
+// Seek must be called when playback starts or when the position of the stream
+// changes, before the first packet of the new position is processed.
+// ts is the time at which playback restarts.
+// It returns the sequence number and the timestamp that will be assigned to
+// the next packet, that can be used to fill the RTP-Info header.
+func (s *Seeker) Seek(ts time.Time) (uint16, uint32) {
+	if s.initialized {
+		s.nextSeq = s.lastSeq + 1
+
+		// the timestamp advances with the time elapsed since the last packet
+		d := ts.Sub(s.lastTime)
+		if d < 0 {
+			d = 0
+		}
+		s.nextTS = s.lastTS + uint32(int64(d/time.Second)*s.clockRate+
+			int64(d%time.Second)*s.clockRate/int64(time.Second))
+	}
+
+	s.pending = true
+	return s.nextSeq, s.nextTS
+}
+
+// Process rewrites the sequence number and the timestamp of a RTP packet.
+// ts is the time at which the packet is sent.
+// The packet is edited in place.
+func (s *Seeker) Process(ts time.Time, pkt []byte) {
+	// do not parse the entire packet, edit only the needed fields
+	if len(pkt) < 12 {
+		return
+	}
+
+	seq := uint16(pkt[2])<<8 | uint16(pkt[3])
+	rtpTS := uint32(pkt[4])<<24 | uint32(pkt[5])<<16 | uint32(pkt[6])<<8 | uint32(pkt[7])
+
+	if s.pending {
+		s.pending = false
+		s.seqOffset = s.nextSeq - seq
+		s.tsOffset = s.nextTS - rtpTS
+	}
+
+	seq += s.seqOffset
+	rtpTS += s.tsOffset
+
+	pkt[2] = byte(seq >> 8)
+	pkt[3] = byte(seq)
+	pkt[4] = byte(rtpTS >> 24)
+	pkt[5] = byte(rtpTS >> 16)
+	pkt[6] = byte(rtpTS >> 8)
+	pkt[7] = byte(rtpTS)
+
+	// do not move backwards in case of reordered packets
+	if !s.initialized || int16(seq-s.lastSeq) > 0 {
+		s.initialized = true
+		s.lastSeq = seq
+		s.lastTS = rtpTS
+		s.lastTime = ts
+	}
+}
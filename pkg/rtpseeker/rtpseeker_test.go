@@ -0,0 +1,66 @@
+package rtpseeker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+)
+
+func packet(seq uint16, ts uint32) []byte {
+	byts, _ := (&rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			PayloadType:    96,
+			SequenceNumber: seq,
+			Timestamp:      ts,
+			SSRC:           0x9dbb7812,
+		},
+		Payload: []byte{0x01, 0x02},
+	}).Marshal()
+	return byts
+}
+
+func TestSeeker(t *testing.T) {
+	s := New(90000)
+
+	t0 := time.Date(2008, 05, 20, 22, 15, 20, 0, time.UTC)
+
+	seq, ts := s.Seek(t0)
+
+	pkt := packet(100, 1000)
+	s.Process(t0, pkt)
+	require.Equal(t, packet(seq, ts), pkt)
+
+	pkt = packet(101, 1000+9000)
+	s.Process(t0.Add(100*time.Millisecond), pkt)
+	require.Equal(t, packet(seq+1, ts+9000), pkt)
+
+	// seek backwards, playback restarts 500ms after the last packet
+	nextSeq, nextTS := s.Seek(t0.Add(600 * time.Millisecond))
+	require.Equal(t, seq+2, nextSeq)
+	require.Equal(t, ts+9000+45000, nextTS)
+
+	pkt = packet(50, 500)
+	s.Process(t0.Add(600*time.Millisecond), pkt)
+	require.Equal(t, packet(seq+2, ts+9000+45000), pkt)
+
+	pkt = packet(51, 500+3000)
+	s.Process(t0.Add(700*time.Millisecond), pkt)
+	require.Equal(t, packet(seq+3, ts+9000+45000+3000), pkt)
+}
+
+func TestSeekerProcessWithoutSeek(t *testing.T) {
+	s := New(90000)
+
+	pkt := packet(100, 1000)
+	s.Process(time.Now(), pkt)
+
+	var p rtp.Packet
+	err := p.Unmarshal(pkt)
+	require.NoError(t, err)
+
+	seq, _ := s.Seek(time.Now())
+	require.Equal(t, p.SequenceNumber+1, seq)
+}
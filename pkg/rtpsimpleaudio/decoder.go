@@ -0,0 +1,44 @@
+package rtpsimpleaudio
+
+import (
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+// Decoder is a RTP decoder for simple audio codecs.
+type Decoder struct {
+	clockRate    time.Duration
+	initialTs    uint32
+	initialTsSet bool
+}
+
+// NewDecoder allocates a Decoder.
+func NewDecoder(clockRate int) *Decoder {
+	return &Decoder{
+		clockRate: time.Duration(clockRate),
+	}
+}
+
+func (d *Decoder) decodeTimestamp(ts uint32) time.Duration {
+	return (time.Duration(ts) - time.Duration(d.initialTs)) * time.Second / d.clockRate
+}
+
+// Decode decodes a frame from a RTP packet.
+func (d *Decoder) Decode(byts []byte) (*FrameAndTimestamp, error) {
+	pkt := rtp.Packet{}
+	err := pkt.Unmarshal(byts)
+	if err != nil {
+		return nil, err
+	}
+
+	if !d.initialTsSet {
+		d.initialTsSet = true
+		d.initialTs = pkt.Timestamp
+	}
+
+	return &FrameAndTimestamp{
+		Frame:     pkt.Payload,
+		Timestamp: d.decodeTimestamp(pkt.Timestamp),
+	}, nil
+}
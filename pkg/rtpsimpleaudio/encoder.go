@@ -0,0 +1,110 @@
+package rtpsimpleaudio
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+const (
+	rtpVersion        = 0x02
+	rtpPayloadMaxSize = 1460 // 1500 (mtu) - 20 (ip header) - 8 (udp header) - 12 (rtp header)
+)
+
+// Encoder is a RTP encoder for simple audio codecs.
+type Encoder struct {
+	payloadType    uint8
+	clockRate      float64
+	sequenceNumber uint16
+	ssrc           uint32
+	initialTs      uint32
+	payloadMaxSize int
+}
+
+// NewEncoder allocates an Encoder.
+// G711 uses payload type 0 (PCMU) or 8 (PCMA) and a clock rate of 8000.
+func NewEncoder(payloadType uint8,
+	clockRate int,
+	sequenceNumber *uint16,
+	ssrc *uint32,
+	initialTs *uint32) *Encoder {
+	return &Encoder{
+		payloadType: payloadType,
+		clockRate:   float64(clockRate),
+		sequenceNumber: func() uint16 {
+			if sequenceNumber != nil {
+				return *sequenceNumber
+			}
+			return uint16(rand.Uint32())
+		}(),
+		ssrc: func() uint32 {
+			if ssrc != nil {
+				return *ssrc
+			}
+			return rand.Uint32()
+		}(),
+		initialTs: func() uint32 {
+			if initialTs != nil {
+				return *initialTs
+			}
+			return rand.Uint32()
+		}(),
+		payloadMaxSize: rtpPayloadMaxSize,
+	}
+}
+
+// SetPayloadMaxSize sets the maximum size of RTP payloads.
+// Frames bigger than this are split into multiple packets.
+// It defaults to 1460, that is suitable for a 1500 bytes MTU.
+func (e *Encoder) SetPayloadMaxSize(v int) {
+	e.payloadMaxSize = v
+}
+
+func (e *Encoder) encodeTimestamp(ts time.Duration) uint32 {
+	return e.initialTs + uint32(ts.Seconds()*e.clockRate)
+}
+
+// Encode encodes a frame into RTP packets.
+// Frames bigger than the maximum payload size are split into multiple packets;
+// in this case, samples are assumed to be 1 byte long, as in G711.
+// It always returns at least one RTP packet.
+func (e *Encoder) Encode(ft *FrameAndTimestamp) ([][]byte, error) {
+	frame := ft.Frame
+	ts := e.encodeTimestamp(ft.Timestamp)
+	var ret [][]byte
+
+	for {
+		le := len(frame)
+		if le > e.payloadMaxSize {
+			le = e.payloadMaxSize
+		}
+
+		rpkt := rtp.Packet{
+			Header: rtp.Header{
+				Version:        rtpVersion,
+				PayloadType:    e.payloadType,
+				SequenceNumber: e.sequenceNumber,
+				Timestamp:      ts,
+				SSRC:           e.ssrc,
+			},
+			Payload: frame[:le],
+		}
+		e.sequenceNumber++
+
+		byts, err := rpkt.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, byts)
+
+		frame = frame[le:]
+		ts += uint32(le)
+
+		if len(frame) == 0 {
+			break
+		}
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,13 @@
+// Package rtpsimpleaudio contains a RTP decoder and encoder for audio codecs
+// that fit into a single packet without any additional header, like G711.
+package rtpsimpleaudio
+
+import (
+	"time"
+)
+
+// FrameAndTimestamp is an audio frame and its timestamp.
+type FrameAndTimestamp struct {
+	Timestamp time.Duration
+	Frame     []byte
+}
@@ -0,0 +1,131 @@
+package rtpsimpleaudio
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func mergeBytes(vals ...[]byte) []byte {
+	size := 0
+	for _, v := range vals {
+		size += len(v)
+	}
+	res := make([]byte, size)
+
+	pos := 0
+	for _, v := range vals {
+		n := copy(res[pos:], v)
+		pos += n
+	}
+
+	return res
+}
+
+var cases = []struct {
+	name string
+	dec  *FrameAndTimestamp
+	enc  [][]byte
+}{
+	{
+		"single",
+		&FrameAndTimestamp{
+			Timestamp: 20 * time.Millisecond,
+			Frame:     bytes.Repeat([]byte{0x01, 0x02, 0x03, 0x04}, 40),
+		},
+		[][]byte{
+			mergeBytes(
+				[]byte{
+					0x80, 0x08, 0x44, 0xed, 0x88, 0x77, 0x66, 0xf5,
+					0x9d, 0xbb, 0x78, 0x12,
+				},
+				bytes.Repeat([]byte{0x01, 0x02, 0x03, 0x04}, 40),
+			),
+		},
+	},
+	{
+		"split",
+		&FrameAndTimestamp{
+			Timestamp: 20 * time.Millisecond,
+			Frame:     bytes.Repeat([]byte{0x01, 0x02, 0x03, 0x04}, 400),
+		},
+		[][]byte{
+			mergeBytes(
+				[]byte{
+					0x80, 0x08, 0x44, 0xed, 0x88, 0x77, 0x66, 0xf5,
+					0x9d, 0xbb, 0x78, 0x12,
+				},
+				bytes.Repeat([]byte{0x01, 0x02, 0x03, 0x04}, 365),
+			),
+			mergeBytes(
+				[]byte{
+					0x80, 0x08, 0x44, 0xee, 0x88, 0x77, 0x6c, 0xa9,
+					0x9d, 0xbb, 0x78, 0x12,
+				},
+				bytes.Repeat([]byte{0x01, 0x02, 0x03, 0x04}, 35),
+			),
+		},
+	},
+}
+
+func TestEncode(t *testing.T) {
+	for _, ca := range cases {
+		t.Run(ca.name, func(t *testing.T) {
+			sequenceNumber := uint16(0x44ed)
+			ssrc := uint32(0x9dbb7812)
+			initialTs := uint32(0x88776655)
+			e := NewEncoder(8, 8000, &sequenceNumber, &ssrc, &initialTs)
+			enc, err := e.Encode(ca.dec)
+			require.NoError(t, err)
+			require.Equal(t, ca.enc, enc)
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	for _, ca := range cases {
+		t.Run(ca.name, func(t *testing.T) {
+			d := NewDecoder(8000)
+
+			// send an initial packet downstream
+			// in order to correctly compute the timestamp
+			_, err := d.Decode([]byte{
+				0x80, 0x88, 0x44, 0xed, 0x88, 0x77, 0x66, 0x55,
+				0x9d, 0xbb, 0x78, 0x12, 0x01,
+			})
+			require.NoError(t, err)
+
+			var frame []byte
+			var ts time.Duration
+			for i, byts := range ca.enc {
+				dec, err := d.Decode(byts)
+				require.NoError(t, err)
+				if i == 0 {
+					ts = dec.Timestamp
+				}
+				frame = append(frame, dec.Frame...)
+			}
+
+			require.Equal(t, ca.dec, &FrameAndTimestamp{
+				Timestamp: ts,
+				Frame:     frame,
+			})
+		})
+	}
+}
+
+func TestEncodePayloadMaxSize(t *testing.T) {
+	e := NewEncoder(8, 8000, nil, nil, nil)
+	e.SetPayloadMaxSize(100)
+
+	enc, err := e.Encode(&FrameAndTimestamp{
+		Frame: bytes.Repeat([]byte{0x01}, 250),
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, len(enc))
+	require.Equal(t, 12+100, len(enc[0]))
+	require.Equal(t, 12+100, len(enc[1]))
+	require.Equal(t, 12+50, len(enc[2]))
+}
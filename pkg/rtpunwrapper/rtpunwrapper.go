@@ -0,0 +1,33 @@
+// Package rtpunwrapper contains a utility to unwrap RTP timestamps.
+package rtpunwrapper
+
+// Unwrapper is a utility that converts 32-bit RTP timestamps, that wrap
+// around after 2^32 ticks (about 13 hours with a 90kHz clock rate),
+// into 64-bit timestamps that grow indefinitely.
+// Timestamps that are lower than the previous one, as with B-frames,
+// are supported as long as the difference is lower than 2^31.
+type Unwrapper struct {
+	initialized bool
+	prev        uint32
+	value       int64
+}
+
+// New allocates an Unwrapper.
+func New() *Unwrapper {
+	return &Unwrapper{}
+}
+
+// Unwrap converts a RTP timestamp into a 64-bit timestamp.
+// The first timestamp is returned as is.
+func (u *Unwrapper) Unwrap(ts uint32) int64 {
+	if !u.initialized {
+		u.initialized = true
+		u.prev = ts
+		u.value = int64(ts)
+		return u.value
+	}
+
+	u.value += int64(int32(ts - u.prev))
+	u.prev = ts
+	return u.value
+}
@@ -0,0 +1,46 @@
+package rtpunwrapper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnwrap(t *testing.T) {
+	for _, ca := range []struct {
+		name string
+		in   []uint32
+		out  []int64
+	}{
+		{
+			"increasing",
+			[]uint32{1000, 4000, 7000},
+			[]int64{1000, 4000, 7000},
+		},
+		{
+			"overflow",
+			[]uint32{4294964296, 4294967295, 2999, 6000},
+			[]int64{4294964296, 4294967295, 4294970295, 4294973296},
+		},
+		{
+			"decreasing",
+			[]uint32{1000, 7000, 4000, 10000},
+			[]int64{1000, 7000, 4000, 10000},
+		},
+		{
+			"decreasing before overflow",
+			[]uint32{10, 4294967000, 300},
+			[]int64{10, -296, 300},
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			u := New()
+
+			var out []int64
+			for _, ts := range ca.in {
+				out = append(out, u.Unwrap(ts))
+			}
+			require.Equal(t, ca.out, out)
+		})
+	}
+}
@@ -62,12 +62,12 @@ func (s *SessionDescription) unmarshalSessionName(value string) error {
 
 func (s *SessionDescription) unmarshalOrigin(value string) error {
 	// special case for live reporter app
-	if value[:3] == "-0 " {
+	if strings.HasPrefix(value, "-0 ") {
 		value = "- 0 " + value[3:]
 	}
 
 	// special case for sone onvif2 cameras
-	if value[len(value)-1] == ' ' {
+	if strings.HasSuffix(value, " ") {
 		value += "127.0.0.1"
 	}
 
@@ -116,7 +116,7 @@ func (s *SessionDescription) unmarshalOrigin(value string) error {
 	// Set according to currently registered with IANA
 	// https://tools.ietf.org/html/rfc4566#section-8.2.7
 	if i := indexOf(fields[4], []string{"IP4", "IP6"}); i == -1 {
-		return fmt.Errorf("%w `%v`", errSDPInvalidValue, fields[3])
+		return fmt.Errorf("%w `%v`", errSDPInvalidValue, fields[4])
 	}
 
 	s.Origin = psdp.Origin{
@@ -354,6 +354,9 @@ func (s *SessionDescription) unmarshalRepeatTimes(value string) error {
 		return fmt.Errorf("%w `r=%v`", errSDPInvalidSyntax, fields)
 	}
 
+	if len(s.TimeDescriptions) == 0 {
+		return fmt.Errorf("%w `r=%v`: no preceding timing", errSDPInvalidSyntax, fields)
+	}
 	latestTimeDesc := &s.TimeDescriptions[len(s.TimeDescriptions)-1]
 
 	newRepeatTime := psdp.RepeatTime{}
@@ -482,182 +485,198 @@ func (s *SessionDescription) unmarshalMediaAttribute(value string) error {
 	return nil
 }
 
-// Unmarshal decodes a SessionDescription.
-// This is rewritten from scratch to guarantee compatibility with most RTSP
-// implementations.
-func (s *SessionDescription) Unmarshal(byts []byte) error {
-	str := string(byts)
+type unmarshalState int
 
-	type stateVal int
-
-	const (
-		stateInitial stateVal = iota
-		stateSession
-		stateMedia
-	)
+const (
+	unmarshalStateInitial unmarshalState = iota
+	unmarshalStateSession
+	unmarshalStateMedia
+)
 
-	state := stateInitial
+func (s *SessionDescription) unmarshalLine(state *unmarshalState, key byte, val string) error {
+	switch *state {
+	case unmarshalStateInitial:
+		switch key {
+		case 'v':
+			err := s.unmarshalVersion(val)
+			if err != nil {
+				return err
+			}
+			*state = unmarshalStateSession
 
-	for _, line := range strings.Split(strings.ReplaceAll(str, "\r", ""), "\n") {
-		if line == "" {
-			continue
+		default:
+			return fmt.Errorf("invalid key: %c", key)
 		}
 
-		if len(line) < 2 || line[1] != '=' {
-			return fmt.Errorf("invalid line: (%s)", line)
-		}
+	case unmarshalStateSession:
+		switch key {
+		case 'o':
+			return s.unmarshalOrigin(val)
 
-		key := line[0]
-		val := line[2:]
+		case 's':
+			return s.unmarshalSessionName(val)
 
-		switch state {
-		case stateInitial:
-			switch key {
-			case 'v':
-				err := s.unmarshalVersion(val)
-				if err != nil {
-					return fmt.Errorf("%s (%s)", err, line)
-				}
-				state = stateSession
+		case 'i':
+			return s.unmarshalSessionInformation(val)
+
+		case 'u':
+			return s.unmarshalURI(val)
+
+		case 'e':
+			return s.unmarshalEmail(val)
+
+		case 'p':
+			return s.unmarshalPhone(val)
+
+		case 'c':
+			return s.unmarshalSessionConnectionInformation(val)
+
+		case 'b':
+			return s.unmarshalSessionBandwidth(val)
 
-			default:
-				return fmt.Errorf("invalid key: %c (%s)", key, line)
+		case 'z':
+			return s.unmarshalTimeZones(val)
+
+		case 'k':
+			return s.unmarshalSessionEncryptionKey(val)
+
+		case 'a':
+			return s.unmarshalSessionAttribute(val)
+
+		case 't':
+			return s.unmarshalTiming(val)
+
+		case 'r':
+			return s.unmarshalRepeatTimes(val)
+
+		case 'm':
+			err := s.unmarshalMediaDescription(val)
+			if err != nil {
+				return err
 			}
+			*state = unmarshalStateMedia
 
-		case stateSession:
-			switch key {
-			case 'o':
-				err := s.unmarshalOrigin(val)
-				if err != nil {
-					return fmt.Errorf("%s (%s)", err, line)
-				}
+		default:
+			return fmt.Errorf("invalid key: %c", key)
+		}
 
-			case 's':
-				err := s.unmarshalSessionName(val)
-				if err != nil {
-					return fmt.Errorf("%s (%s)", err, line)
-				}
+	case unmarshalStateMedia:
+		switch key {
+		case 'm':
+			return s.unmarshalMediaDescription(val)
 
-			case 'i':
-				err := s.unmarshalSessionInformation(val)
-				if err != nil {
-					return fmt.Errorf("%s (%s)", err, line)
-				}
+		case 'i':
+			return s.unmarshalMediaTitle(val)
 
-			case 'u':
-				err := s.unmarshalURI(val)
-				if err != nil {
-					return fmt.Errorf("%s (%s)", err, line)
-				}
+		case 'c':
+			return s.unmarshalMediaConnectionInformation(val)
 
-			case 'e':
-				err := s.unmarshalEmail(val)
-				if err != nil {
-					return fmt.Errorf("%s (%s)", err, line)
-				}
+		case 'b':
+			return s.unmarshalMediaBandwidth(val)
 
-			case 'p':
-				err := s.unmarshalPhone(val)
-				if err != nil {
-					return fmt.Errorf("%s (%s)", err, line)
-				}
+		case 'k':
+			return s.unmarshalMediaEncryptionKey(val)
 
-			case 'c':
-				err := s.unmarshalSessionConnectionInformation(val)
-				if err != nil {
-					return fmt.Errorf("%s (%s)", err, line)
-				}
+		case 'a':
+			return s.unmarshalMediaAttribute(val)
 
-			case 'b':
-				err := s.unmarshalSessionBandwidth(val)
-				if err != nil {
-					return fmt.Errorf("%s (%s)", err, line)
-				}
+		default:
+			return fmt.Errorf("invalid key: %c", key)
+		}
+	}
 
-			case 'z':
-				err := s.unmarshalTimeZones(val)
-				if err != nil {
-					return fmt.Errorf("%s (%s)", err, line)
-				}
+	return nil
+}
 
-			case 'k':
-				err := s.unmarshalSessionEncryptionKey(val)
-				if err != nil {
-					return fmt.Errorf("%s (%s)", err, line)
-				}
+func splitLines(byts []byte) []string {
+	return strings.Split(strings.ReplaceAll(string(byts), "\r", ""), "\n")
+}
 
-			case 'a':
-				err := s.unmarshalSessionAttribute(val)
-				if err != nil {
-					return fmt.Errorf("%s (%s)", err, line)
-				}
+// Unmarshal decodes a SessionDescription.
+// This is rewritten from scratch to guarantee compatibility with most RTSP
+// implementations.
+func (s *SessionDescription) Unmarshal(byts []byte) error {
+	state := unmarshalStateInitial
 
-			case 't':
-				err := s.unmarshalTiming(val)
-				if err != nil {
-					return fmt.Errorf("%s (%s)", err, line)
-				}
+	for _, line := range splitLines(byts) {
+		if line == "" {
+			continue
+		}
 
-			case 'r':
-				err := s.unmarshalRepeatTimes(val)
-				if err != nil {
-					return fmt.Errorf("%s (%s)", err, line)
-				}
+		if len(line) < 2 || line[1] != '=' {
+			return fmt.Errorf("invalid line: (%s)", line)
+		}
 
-			case 'm':
-				err := s.unmarshalMediaDescription(val)
-				if err != nil {
-					return fmt.Errorf("%s (%s)", err, line)
-				}
-				state = stateMedia
+		err := s.unmarshalLine(&state, line[0], line[2:])
+		if err != nil {
+			return fmt.Errorf("%s (%s)", err, line)
+		}
+	}
 
-			default:
-				return fmt.Errorf("invalid key: %c (%s)", key, line)
-			}
+	return nil
+}
 
-		case stateMedia:
-			switch key {
-			case 'm':
-				err := s.unmarshalMediaDescription(val)
-				if err != nil {
-					return fmt.Errorf("%s (%s)", err, line)
-				}
+// UnmarshalLenient decodes a SessionDescription produced by a non-compliant
+// implementation, like cheap cameras.
+// Lines that can't be decoded are skipped, a missing version line is tolerated,
+// line types and attribute keys are converted to lowercase, and
+// trailing garbage is discarded. When a media description can't be decoded,
+// all its lines are skipped, up to the next media description.
+// It returns a warning for each line that has been skipped.
+func (s *SessionDescription) UnmarshalLenient(byts []byte) []string {
+	var warnings []string
+	state := unmarshalStateInitial
+	skipMedia := false
+
+	for _, line := range splitLines(byts) {
+		// remove spaces and NUL characters that surround the line
+		line = strings.Trim(line, " \t\x00")
+		if line == "" {
+			continue
+		}
 
-			case 'i':
-				err := s.unmarshalMediaTitle(val)
-				if err != nil {
-					return fmt.Errorf("%s (%s)", err, line)
-				}
+		if len(line) < 2 || line[1] != '=' {
+			warnings = append(warnings, fmt.Sprintf("invalid line: (%s)", line))
+			continue
+		}
 
-			case 'c':
-				err := s.unmarshalMediaConnectionInformation(val)
-				if err != nil {
-					return fmt.Errorf("%s (%s)", err, line)
-				}
+		key := line[0]
+		if key >= 'A' && key <= 'Z' {
+			key += 'a' - 'A'
+		}
 
-			case 'b':
-				err := s.unmarshalMediaBandwidth(val)
-				if err != nil {
-					return fmt.Errorf("%s (%s)", err, line)
-				}
+		val := line[2:]
+		if key == 'a' {
+			if i := strings.IndexByte(val, ':'); i >= 0 {
+				val = strings.ToLower(val[:i]) + val[i:]
+			} else {
+				val = strings.ToLower(val)
+			}
+		}
 
-			case 'k':
-				err := s.unmarshalMediaEncryptionKey(val)
-				if err != nil {
-					return fmt.Errorf("%s (%s)", err, line)
-				}
+		if state == unmarshalStateInitial && key != 'v' {
+			warnings = append(warnings, "version line is missing")
+			state = unmarshalStateSession
+		}
 
-			case 'a':
-				err := s.unmarshalMediaAttribute(val)
-				if err != nil {
-					return fmt.Errorf("%s (%s)", err, line)
-				}
+		if key == 'm' {
+			skipMedia = false
+		} else if skipMedia {
+			warnings = append(warnings, fmt.Sprintf("line of an invalid media description (%s)", line))
+			continue
+		}
 
-			default:
-				return fmt.Errorf("invalid key: %c (%s)", key, line)
+		err := s.unmarshalLine(&state, key, val)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s (%s)", err, line))
+
+			// the following lines would be assigned to the previous media
+			// description or to the session
+			if key == 'm' {
+				skipMedia = true
 			}
 		}
 	}
 
-	return nil
+	return warnings
 }
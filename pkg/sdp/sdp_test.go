@@ -47,6 +47,38 @@ var cases = []struct {
 			},
 		},
 	},
+	{
+		"ipv6",
+		[]byte("v=0\r\n" +
+			"o=- 2890844526 2890842807 IN IP6 2001:db8::1\r\n" +
+			"s=Stream\r\n" +
+			"c=IN IP6 ff15::101/3\r\n" +
+			"t=0 0\r\n"),
+		[]byte("v=0\r\n" +
+			"o=- 2890844526 2890842807 IN IP6 2001:db8::1\r\n" +
+			"s=Stream\r\n" +
+			"c=IN IP6 ff15::101/3\r\n" +
+			"t=0 0\r\n"),
+		SessionDescription{
+			Origin: psdp.Origin{
+				Username:       "-",
+				SessionID:      2890844526,
+				SessionVersion: 2890842807,
+				NetworkType:    "IN",
+				AddressType:    "IP6",
+				UnicastAddress: "2001:db8::1",
+			},
+			SessionName: "Stream",
+			ConnectionInformation: &psdp.ConnectionInformation{
+				NetworkType: "IN",
+				AddressType: "IP6",
+				Address:     &psdp.Address{Address: "ff15::101/3"},
+			},
+			TimeDescriptions: []psdp.TimeDescription{
+				{Timing: psdp.Timing{StartTime: 0, StopTime: 0}},
+			},
+		},
+	},
 	{
 		"unix newlines",
 		[]byte("v=0\n" +
@@ -1202,3 +1234,116 @@ func TestMarshal(t *testing.T) {
 		})
 	}
 }
+
+func TestUnmarshalLenient(t *testing.T) {
+	for _, ca := range []struct {
+		name     string
+		dec      []byte
+		desc     SessionDescription
+		warnings []string
+	}{
+		{
+			"standard",
+			cases[0].dec,
+			cases[0].desc,
+			nil,
+		},
+		{
+			"broken camera",
+			[]byte("s=Camera\r\n" +
+				"c=IN\r\n" +
+				"t=0 0\r\n" +
+				"m=video 0 RTP/AVP 96\r\n" +
+				"A=RTPMAP:96 H264/90000\r\n" +
+				"a=SENDONLY\r\n" +
+				"garbage\r\n" +
+				"\x00\x00"),
+			SessionDescription{
+				SessionName: "Camera",
+				TimeDescriptions: []psdp.TimeDescription{
+					{Timing: psdp.Timing{StartTime: 0, StopTime: 0}},
+				},
+				MediaDescriptions: []*psdp.MediaDescription{
+					{
+						MediaName: psdp.MediaName{
+							Media:   "video",
+							Protos:  []string{"RTP", "AVP"},
+							Formats: []string{"96"},
+						},
+						Attributes: []psdp.Attribute{
+							{
+								Key:   "rtpmap",
+								Value: "96 H264/90000",
+							},
+							{
+								Key: "sendonly",
+							},
+						},
+					},
+				},
+			},
+			[]string{
+				"version line is missing",
+				"sdp: invalid syntax `c=IN` (c=IN)",
+				"invalid line: (garbage)",
+			},
+		},
+		{
+			"invalid media description",
+			[]byte("v=0\r\n" +
+				"s=Camera\r\n" +
+				"t=0 0\r\n" +
+				"m=video 0 RTP/AVP 96\r\n" +
+				"a=rtpmap:96 H264/90000\r\n" +
+				"m=metadata 0 RTP/AVP 107\r\n" +
+				"a=rtpmap:107 vnd.onvif.metadata/90000\r\n" +
+				"m=audio 0 RTP/AVP 0\r\n" +
+				"a=rtpmap:0 PCMU/8000\r\n"),
+			SessionDescription{
+				SessionName: "Camera",
+				TimeDescriptions: []psdp.TimeDescription{
+					{Timing: psdp.Timing{StartTime: 0, StopTime: 0}},
+				},
+				MediaDescriptions: []*psdp.MediaDescription{
+					{
+						MediaName: psdp.MediaName{
+							Media:   "video",
+							Protos:  []string{"RTP", "AVP"},
+							Formats: []string{"96"},
+						},
+						Attributes: []psdp.Attribute{
+							{
+								Key:   "rtpmap",
+								Value: "96 H264/90000",
+							},
+						},
+					},
+					{
+						MediaName: psdp.MediaName{
+							Media:   "audio",
+							Protos:  []string{"RTP", "AVP"},
+							Formats: []string{"0"},
+						},
+						Attributes: []psdp.Attribute{
+							{
+								Key:   "rtpmap",
+								Value: "0 PCMU/8000",
+							},
+						},
+					},
+				},
+			},
+			[]string{
+				"sdp: invalid value `metadata` (m=metadata 0 RTP/AVP 107)",
+				"line of an invalid media description (a=rtpmap:107 vnd.onvif.metadata/90000)",
+			},
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			desc := SessionDescription{}
+			warnings := desc.UnmarshalLenient(ca.dec)
+			require.Equal(t, ca.warnings, warnings)
+			require.Equal(t, ca.desc, desc)
+		})
+	}
+}
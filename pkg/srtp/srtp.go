@@ -0,0 +1,100 @@
+// Package srtp contains a SRTP and SRTCP encryptor and decryptor (RFC 3711)
+// that supports the AES_CM_128_HMAC_SHA1_80 crypto suite.
+// It is a wrapper around github.com/pion/srtp.
+package srtp
+
+import (
+	"fmt"
+	"sync"
+
+	psrtp "github.com/pion/srtp/v2"
+)
+
+const (
+	// KeyLength is the length of a master key.
+	KeyLength = 16
+
+	// SaltLength is the length of a master salt.
+	SaltLength = 14
+
+	// MasterKeyLength is the length of a master key concatenated with a master salt,
+	// as transmitted with SDES.
+	MasterKeyLength = KeyLength + SaltLength
+
+	// RTPOverhead is the number of bytes that are added to RTP packets by encryption.
+	RTPOverhead = 10
+
+	// size of the window used to detect replayed packets (RFC 3711, section 3.3.2).
+	replayWindowSize = 64
+)
+
+// Context is a SRTP/SRTCP context, that allows to encrypt and decrypt
+// packets with the same master key.
+// Packets that are received more than once are refused.
+type Context struct {
+	encryptMutex sync.Mutex
+	encrypt      *psrtp.Context
+
+	decryptMutex sync.Mutex
+	decrypt      *psrtp.Context
+}
+
+// New allocates a Context from a master key concatenated with a master salt.
+func New(masterKey []byte) (*Context, error) {
+	if len(masterKey) != MasterKeyLength {
+		return nil, fmt.Errorf("invalid master key length: expected %d, got %d",
+			MasterKeyLength, len(masterKey))
+	}
+
+	key := masterKey[:KeyLength]
+	salt := masterKey[KeyLength:]
+
+	encrypt, err := psrtp.CreateContext(key, salt, psrtp.ProtectionProfileAes128CmHmacSha1_80)
+	if err != nil {
+		return nil, err
+	}
+
+	decrypt, err := psrtp.CreateContext(key, salt, psrtp.ProtectionProfileAes128CmHmacSha1_80,
+		psrtp.SRTPReplayProtection(replayWindowSize),
+		psrtp.SRTCPReplayProtection(replayWindowSize))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Context{
+		encrypt: encrypt,
+		decrypt: decrypt,
+	}, nil
+}
+
+// EncryptRTP encrypts a RTP packet and returns a SRTP packet.
+func (c *Context) EncryptRTP(pkt []byte) ([]byte, error) {
+	c.encryptMutex.Lock()
+	defer c.encryptMutex.Unlock()
+
+	return c.encrypt.EncryptRTP(nil, pkt, nil)
+}
+
+// DecryptRTP decrypts a SRTP packet and returns a RTP packet.
+func (c *Context) DecryptRTP(pkt []byte) ([]byte, error) {
+	c.decryptMutex.Lock()
+	defer c.decryptMutex.Unlock()
+
+	return c.decrypt.DecryptRTP(nil, pkt, nil)
+}
+
+// EncryptRTCP encrypts a RTCP packet and returns a SRTCP packet.
+func (c *Context) EncryptRTCP(pkt []byte) ([]byte, error) {
+	c.encryptMutex.Lock()
+	defer c.encryptMutex.Unlock()
+
+	return c.encrypt.EncryptRTCP(nil, pkt, nil)
+}
+
+// DecryptRTCP decrypts a SRTCP packet and returns a RTCP packet.
+func (c *Context) DecryptRTCP(pkt []byte) ([]byte, error) {
+	c.decryptMutex.Lock()
+	defer c.decryptMutex.Unlock()
+
+	return c.decrypt.DecryptRTCP(nil, pkt, nil)
+}
@@ -0,0 +1,158 @@
+package srtp
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+)
+
+func mustDecodeHex(s string) []byte {
+	byts, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return byts
+}
+
+func TestRTP(t *testing.T) {
+	masterKey := mustDecodeHex("E1F97A0D3E018BE0D64FA32C06DE4139" +
+		"0EC675AD498AFEEBB6960B3AABE6")
+
+	enc, err := New(masterKey)
+	require.NoError(t, err)
+
+	dec, err := New(masterKey)
+	require.NoError(t, err)
+
+	for _, seq := range []uint16{65534, 65535, 0, 1} {
+		pkt := rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				PayloadType:    96,
+				SequenceNumber: seq,
+				Timestamp:      123456,
+				SSRC:           0x9dbb7c8c,
+			},
+			Payload: []byte{0x01, 0x02, 0x03, 0x04, 0x05},
+		}
+		byts, err := pkt.Marshal()
+		require.NoError(t, err)
+
+		encrypted, err := enc.EncryptRTP(byts)
+		require.NoError(t, err)
+		require.Equal(t, len(byts)+RTPOverhead, len(encrypted))
+		require.Equal(t, byts[:12], encrypted[:12])
+		require.NotEqual(t, byts[12:], encrypted[12:len(byts)])
+
+		decrypted, err := dec.DecryptRTP(encrypted)
+		require.NoError(t, err)
+		require.Equal(t, byts, decrypted)
+	}
+
+}
+
+func TestRTCP(t *testing.T) {
+	masterKey := mustDecodeHex("E1F97A0D3E018BE0D64FA32C06DE4139" +
+		"0EC675AD498AFEEBB6960B3AABE6")
+
+	enc, err := New(masterKey)
+	require.NoError(t, err)
+
+	dec, err := New(masterKey)
+	require.NoError(t, err)
+
+	byts, err := (&rtcp.ReceiverReport{
+		SSRC: 0x9dbb7c8c,
+		Reports: []rtcp.ReceptionReport{{
+			SSRC:               0x12345678,
+			LastSequenceNumber: 946,
+		}},
+	}).Marshal()
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		encrypted, err := enc.EncryptRTCP(byts)
+		require.NoError(t, err)
+		require.Equal(t, len(byts)+4+RTPOverhead, len(encrypted))
+
+		decrypted, err := dec.DecryptRTCP(encrypted)
+		require.NoError(t, err)
+		require.Equal(t, byts, decrypted)
+	}
+}
+
+func TestAuthFailure(t *testing.T) {
+	ctx, err := New(mustDecodeHex("E1F97A0D3E018BE0D64FA32C06DE4139" +
+		"0EC675AD498AFEEBB6960B3AABE6"))
+	require.NoError(t, err)
+
+	byts, err := (&rtp.Packet{
+		Header: rtp.Header{
+			Version: 2,
+			SSRC:    0x9dbb7c8c,
+		},
+		Payload: []byte{0x01, 0x02, 0x03, 0x04},
+	}).Marshal()
+	require.NoError(t, err)
+
+	encrypted, err := ctx.EncryptRTP(byts)
+	require.NoError(t, err)
+	encrypted[13] ^= 0xFF
+
+	_, err = ctx.DecryptRTP(encrypted)
+	require.EqualError(t, err, "failed to verify auth tag")
+
+	encrypted, err = ctx.EncryptRTCP([]byte{0x80, 0xc9, 0x00, 0x01, 0x9d, 0xbb, 0x7c, 0x8c})
+	require.NoError(t, err)
+	encrypted[len(encrypted)-1] ^= 0xFF
+
+	_, err = ctx.DecryptRTCP(encrypted)
+	require.EqualError(t, err, "failed to verify auth tag")
+}
+
+func TestReplay(t *testing.T) {
+	masterKey := mustDecodeHex("E1F97A0D3E018BE0D64FA32C06DE4139" +
+		"0EC675AD498AFEEBB6960B3AABE6")
+
+	enc, err := New(masterKey)
+	require.NoError(t, err)
+
+	dec, err := New(masterKey)
+	require.NoError(t, err)
+
+	byts, err := (&rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			SequenceNumber: 946,
+			SSRC:           0x9dbb7c8c,
+		},
+		Payload: []byte{0x01, 0x02, 0x03, 0x04},
+	}).Marshal()
+	require.NoError(t, err)
+
+	encrypted, err := enc.EncryptRTP(byts)
+	require.NoError(t, err)
+
+	_, err = dec.DecryptRTP(encrypted)
+	require.NoError(t, err)
+
+	_, err = dec.DecryptRTP(encrypted)
+	require.EqualError(t, err, "srtp ssrc=2646310028 index=946: duplicated packet")
+
+	encrypted, err = enc.EncryptRTCP([]byte{0x80, 0xc9, 0x00, 0x01, 0x9d, 0xbb, 0x7c, 0x8c})
+	require.NoError(t, err)
+
+	_, err = dec.DecryptRTCP(encrypted)
+	require.NoError(t, err)
+
+	_, err = dec.DecryptRTCP(encrypted)
+	require.Error(t, err)
+}
+
+func TestNewError(t *testing.T) {
+	_, err := New([]byte{0x01, 0x02})
+	require.EqualError(t, err, "invalid master key length: expected 30, got 2")
+}
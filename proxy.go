@@ -0,0 +1,241 @@
+package gortsplib
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var errTerminated = errors.New("terminated")
+
+// ProxyConf allows to configure a Proxy.
+type ProxyConf struct {
+	// configuration of the client that reads the source and of the client
+	// that publishes to PublishAddress.
+	ClientConf ClientConf
+
+	// (optional) address of a server to which the tracks of the source
+	// are published with ANNOUNCE and RECORD.
+	PublishAddress string
+
+	// time to wait before connecting again after an error.
+	// It defaults to 5 seconds.
+	RetryPause time.Duration
+
+	// (optional) called when the source has been read and the tracks are
+	// being routed. Track IDs are the ones of the republished tracks.
+	OnReady func(tracks Tracks)
+
+	// (optional) called when the source or the destination fail,
+	// before connecting again.
+	OnError func(err error)
+}
+
+// Proxy reads a stream from a server and republishes it to another server,
+// to local readers, or both.
+// If the source or the destination disconnect, the proxy connects again.
+type Proxy struct {
+	conf   ProxyConf
+	source string
+
+	mutex  sync.Mutex
+	stream *ServerStream
+
+	terminate chan struct{}
+	done      chan struct{}
+}
+
+// Start starts a Proxy that reads the source address.
+func (c ProxyConf) Start(source string) *Proxy {
+	if c.RetryPause == 0 {
+		c.RetryPause = 5 * time.Second
+	}
+
+	p := &Proxy{
+		conf:      c,
+		source:    source,
+		terminate: make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+
+	go p.run()
+
+	return p
+}
+
+// Close stops the proxy and closes all its connections.
+func (p *Proxy) Close() {
+	close(p.terminate)
+	<-p.done
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.stream != nil {
+		p.stream.Close()
+	}
+}
+
+// Stream returns a stream that contains the frames read from the source,
+// that can be used to serve local readers.
+// It returns nil until the source has been read for the first time.
+// The stream is preserved between reconnections.
+func (p *Proxy) Stream() *ServerStream {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.stream
+}
+
+func (p *Proxy) run() {
+	defer close(p.done)
+
+	for {
+		err := p.runInner()
+		if err == errTerminated {
+			return
+		}
+
+		if p.conf.OnError != nil {
+			p.conf.OnError(err)
+		}
+
+		select {
+		case <-time.After(p.conf.RetryPause):
+		case <-p.terminate:
+			return
+		}
+	}
+}
+
+// dialInterruptible runs a dial function in a separate routine, in order to
+// allow Close() to interrupt it through the terminate channel.
+func dialInterruptible(terminate <-chan struct{}, fn func() (*ClientConn, error)) (*ClientConn, error) {
+	type dialRes struct {
+		conn *ClientConn
+		err  error
+	}
+
+	done := make(chan dialRes)
+	go func() {
+		conn, err := fn()
+		done <- dialRes{conn, err}
+	}()
+
+	select {
+	case res := <-done:
+		return res.conn, res.err
+
+	case <-terminate:
+		go func() {
+			res := <-done
+			if res.err == nil {
+				res.conn.Close()
+			}
+		}()
+		return nil, errTerminated
+	}
+}
+
+func (p *Proxy) runInner() error {
+	source, err := dialInterruptible(p.terminate, func() (*ClientConn, error) {
+		return p.conf.ClientConf.DialRead(p.source)
+	})
+	if err != nil {
+		return err
+	}
+
+	// back channels are not republished, and the IDs of the remaining
+	// tracks are compacted.
+	trackIDs := make(map[int]int)
+	var tracks Tracks
+	for _, track := range source.Tracks() {
+		if track.IsBackChannel() {
+			continue
+		}
+
+		trackIDs[track.ID] = len(tracks)
+		tracks = append(tracks, cloneTrack(track, len(tracks)))
+	}
+
+	p.mutex.Lock()
+	if p.stream == nil {
+		p.stream = NewServerStream(tracks)
+	} else if len(p.stream.Tracks()) != len(tracks) {
+		n := len(p.stream.Tracks())
+		p.mutex.Unlock()
+		source.Close()
+		return fmt.Errorf("the number of tracks of the source changed (from %d to %d)", n, len(tracks))
+	}
+	stream := p.stream
+	p.mutex.Unlock()
+
+	var dest *ClientConn
+	if p.conf.PublishAddress != "" {
+		// Announce() edits the tracks, therefore they are copied
+		publishTracks := make(Tracks, len(tracks))
+		for i, track := range tracks {
+			publishTracks[i] = cloneTrack(track, i)
+		}
+
+		dest, err = dialInterruptible(p.terminate, func() (*ClientConn, error) {
+			return p.conf.ClientConf.DialPublish(p.conf.PublishAddress, publishTracks)
+		})
+		if err != nil {
+			source.Close()
+			return err
+		}
+		defer dest.Close()
+	}
+
+	if p.conf.OnReady != nil {
+		p.conf.OnReady(tracks)
+	}
+
+	// channel is buffered, since only the first error is used
+	destErr := make(chan error, 1)
+
+	readDone := source.ReadFrames(func(trackID int, streamType StreamType, payload []byte) {
+		id, ok := trackIDs[trackID]
+		if !ok {
+			return
+		}
+
+		stream.WriteFrame(id, streamType, payload)
+
+		// RTCP packets are generated by the publishing client
+		if dest != nil && streamType == StreamTypeRTP {
+			err := dest.WriteFrame(id, streamType, payload)
+			if err != nil {
+				select {
+				case destErr <- err:
+				default:
+				}
+			}
+		}
+	})
+
+	select {
+	case err := <-readDone:
+		source.Close()
+		return err
+
+	case err := <-destErr:
+		source.Close()
+		<-readDone
+		return err
+
+	case <-p.terminate:
+		source.Close()
+		<-readDone
+		return errTerminated
+	}
+}
+
+func cloneTrack(track *Track, id int) *Track {
+	ret := track.Clone()
+	ret.BaseURL = nil
+	ret.ID = id
+	return ret
+}
@@ -0,0 +1,123 @@
+package gortsplib
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/majoyz/gortsplib/pkg/base"
+)
+
+func TestProxy(t *testing.T) {
+	s, err := Serve("127.0.0.1:8554")
+	require.NoError(t, err)
+
+	track, err := NewTrackH264(96, []byte("123456"), []byte("123456"))
+	require.NoError(t, err)
+
+	stream := NewServerStream(Tracks{track})
+	defer stream.Close()
+
+	announced := make(chan Tracks, 1)
+	frameRecv := make(chan []byte, 1)
+
+	handleDone := make(chan struct{})
+	defer func() { <-handleDone }()
+	defer s.Close()
+
+	go func() {
+		defer close(handleDone)
+
+		s.Handle(ServerHandler{
+			OnConnOpen: func(sc *ServerConn) ServerConnReadHandlers {
+				return ServerConnReadHandlers{
+					OnDescribe: func(ctx *ServerConnDescribeCtx) (*base.Response, []byte, error) {
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, stream.Tracks().Write(), nil
+					},
+					OnAnnounce: func(ctx *ServerConnAnnounceCtx) (*base.Response, error) {
+						require.Equal(t, "dest", ctx.Path)
+						announced <- ctx.Tracks
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, nil
+					},
+					OnSetup: func(ctx *ServerConnSetupCtx) (*base.Response, error) {
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, nil
+					},
+					OnPlay: func(ctx *ServerConnPlayCtx) (*base.Response, error) {
+						require.Equal(t, "source", ctx.Path)
+						stream.AddReader(sc)
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, nil
+					},
+					OnRecord: func(ctx *ServerConnRecordCtx) (*base.Response, error) {
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, nil
+					},
+					OnFrame: func(trackID int, streamType StreamType, payload []byte) {
+						if streamType == StreamTypeRTP {
+							require.Equal(t, 0, trackID)
+							select {
+							case frameRecv <- payload:
+							default:
+							}
+						}
+					},
+				}
+			},
+			OnConnClose: func(sc *ServerConn, err error) {
+				stream.RemoveReader(sc)
+			},
+		})
+	}()
+
+	v := StreamProtocolTCP
+	ready := make(chan Tracks, 1)
+
+	p := ProxyConf{
+		ClientConf: ClientConf{
+			StreamProtocol: &v,
+		},
+		PublishAddress: "rtsp://localhost:8554/dest",
+		OnReady: func(tracks Tracks) {
+			ready <- tracks
+		},
+		OnError: func(err error) {
+			t.Errorf("unexpected error: %v", err)
+		},
+	}.Start("rtsp://localhost:8554/source")
+	defer p.Close()
+
+	tracks := <-ready
+	require.Equal(t, 1, len(tracks))
+	require.Equal(t, true, tracks[0].IsH264())
+	require.NotNil(t, p.Stream())
+
+	destTracks := <-announced
+	require.Equal(t, 1, len(destTracks))
+	require.Equal(t, true, destTracks[0].IsH264())
+
+	pkt := []byte{
+		0x80, 0x60, 0x12, 0x34, 0x55, 0x66, 0x77, 0x88,
+		0x9d, 0xbb, 0x78, 0x12, 0x01, 0x02, 0x03, 0x04,
+	}
+
+	// frames are written until the proxy has started publishing
+	for {
+		stream.WriteFrame(0, StreamTypeRTP, pkt)
+
+		select {
+		case recv := <-frameRecv:
+			require.Equal(t, pkt, recv)
+			return
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
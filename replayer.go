@@ -0,0 +1,209 @@
+package gortsplib
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/majoyz/gortsplib/pkg/base"
+	"github.com/majoyz/gortsplib/pkg/rtpdump"
+)
+
+// ReplayerPacket is a packet published by a Replayer.
+type ReplayerPacket struct {
+	TrackID    int
+	StreamType StreamType
+	Payload    []byte
+
+	// time elapsed since the beginning of the recording.
+	Offset time.Duration
+}
+
+// ReplayerSource provides the packets published by a Replayer,
+// sorted by offset.
+type ReplayerSource interface {
+	// ReadPacket returns the next packet, or io.EOF when there are no more packets.
+	ReadPacket() (*ReplayerPacket, error)
+}
+
+type replayerSourceRTPDump struct {
+	readers []*rtpdump.Reader
+	shifts  []time.Duration
+	next    []*rtpdump.Packet
+	err     error
+}
+
+// NewReplayerSourceRTPDump allocates a ReplayerSource that reads packets
+// from rtpdump files, one for each track. Packets of the i-th file
+// are published with track ID i, and files are synchronized with the
+// start time found in their headers.
+func NewReplayerSourceRTPDump(readers ...*rtpdump.Reader) ReplayerSource {
+	var start time.Time
+	for i, r := range readers {
+		if i == 0 || r.Header().Start.Before(start) {
+			start = r.Header().Start
+		}
+	}
+
+	shifts := make([]time.Duration, len(readers))
+	for i, r := range readers {
+		shifts[i] = r.Header().Start.Sub(start)
+	}
+
+	return &replayerSourceRTPDump{
+		readers: readers,
+		shifts:  shifts,
+		next:    make([]*rtpdump.Packet, len(readers)),
+	}
+}
+
+// ReadPacket implements ReplayerSource.
+func (s *replayerSourceRTPDump) ReadPacket() (*ReplayerPacket, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	trackID := -1
+
+	for i, r := range s.readers {
+		if s.next[i] == nil && r != nil {
+			pkt, err := r.ReadPacket()
+			if err != nil {
+				if err != io.EOF {
+					s.err = err
+					return nil, err
+				}
+				s.readers[i] = nil
+				continue
+			}
+			s.next[i] = pkt
+		}
+
+		if s.next[i] != nil && (trackID < 0 ||
+			s.shifts[i]+s.next[i].Offset < s.shifts[trackID]+s.next[trackID].Offset) {
+			trackID = i
+		}
+	}
+
+	if trackID < 0 {
+		s.err = io.EOF
+		return nil, s.err
+	}
+
+	pkt := s.next[trackID]
+	s.next[trackID] = nil
+
+	return &ReplayerPacket{
+		TrackID:    trackID,
+		StreamType: pkt.StreamType,
+		Payload:    pkt.Payload,
+		Offset:     s.shifts[trackID] + pkt.Offset,
+	}, nil
+}
+
+type replayerSourcePCAP struct {
+	r        *rtpdump.PCAPReader
+	trackIDs map[int]int
+}
+
+// NewReplayerSourcePCAP allocates a ReplayerSource that reads packets
+// from a pcap file. trackIDs maps the destination UDP port of packets to
+// track IDs, and packets sent to other ports are skipped.
+func NewReplayerSourcePCAP(r *rtpdump.PCAPReader, trackIDs map[int]int) ReplayerSource {
+	return &replayerSourcePCAP{
+		r:        r,
+		trackIDs: trackIDs,
+	}
+}
+
+// ReadPacket implements ReplayerSource.
+func (s *replayerSourcePCAP) ReadPacket() (*ReplayerPacket, error) {
+	for {
+		pkt, err := s.r.ReadPacket()
+		if err != nil {
+			return nil, err
+		}
+
+		trackID, ok := s.trackIDs[pkt.Destination.Port]
+		if !ok {
+			continue
+		}
+
+		return &ReplayerPacket{
+			TrackID:    trackID,
+			StreamType: pkt.StreamType,
+			Payload:    pkt.Payload,
+			Offset:     pkt.Offset,
+		}, nil
+	}
+}
+
+// ReplayerConf allows to configure a Replayer.
+type ReplayerConf struct {
+	// configuration of the client that publishes the packets.
+	ClientConf ClientConf
+
+	// speed multiplier of the replay, that is applied to the time between packets.
+	// It defaults to 1.
+	Speed float64
+}
+
+// Replay publishes the tracks to the address with ANNOUNCE and RECORD,
+// then writes the packets provided by the source, honoring the time
+// between them. It returns when all the packets have been written.
+// RTCP packets of the source are discarded, since the client generates
+// its own RTCP sender reports.
+func (c ReplayerConf) Replay(address string, tracks Tracks, src ReplayerSource) error {
+	return c.ReplayContext(context.Background(), address, tracks, src)
+}
+
+// ReplayContext is like Replay, but the replay can be canceled through a context.
+func (c ReplayerConf) ReplayContext(ctx context.Context, address string,
+	tracks Tracks, src ReplayerSource) error {
+	if c.Speed == 0 {
+		c.Speed = 1
+	}
+
+	conn, err := dialInterruptible(ctx.Done(), func() (*ClientConn, error) {
+		return c.ClientConf.DialPublish(address, tracks)
+	})
+	if err != nil {
+		if err == errTerminated {
+			return ctx.Err()
+		}
+		return err
+	}
+	defer conn.Close()
+
+	start := time.Now()
+
+	for {
+		pkt, err := src.ReadPacket()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		if pkt.StreamType != base.StreamTypeRTP {
+			continue
+		}
+
+		wait := time.Duration(float64(pkt.Offset)/c.Speed) - time.Since(start)
+		if wait > 0 {
+			t := time.NewTimer(wait)
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				t.Stop()
+				return ctx.Err()
+			}
+		}
+
+		err = conn.WriteFrame(pkt.TrackID, pkt.StreamType, pkt.Payload)
+		if err != nil {
+			return err
+		}
+	}
+}
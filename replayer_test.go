@@ -0,0 +1,144 @@
+package gortsplib
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/majoyz/gortsplib/pkg/base"
+	"github.com/majoyz/gortsplib/pkg/rtpdump"
+)
+
+func writeTestRTPDump(t *testing.T, start time.Time, pkts []rtpdump.Packet) *rtpdump.Reader {
+	var buf bytes.Buffer
+
+	w, err := rtpdump.NewWriter(&buf, rtpdump.Header{
+		Start: start,
+	})
+	require.NoError(t, err)
+
+	for _, pkt := range pkts {
+		err := w.WritePacket(pkt)
+		require.NoError(t, err)
+	}
+
+	r, err := rtpdump.NewReader(&buf)
+	require.NoError(t, err)
+
+	return r
+}
+
+func TestReplayerSourceRTPDump(t *testing.T) {
+	start := time.Date(2008, 05, 20, 22, 15, 20, 0, time.UTC)
+
+	src := NewReplayerSourceRTPDump(
+		writeTestRTPDump(t, start.Add(500*time.Millisecond), []rtpdump.Packet{
+			{Offset: 0, StreamType: base.StreamTypeRTP, Payload: []byte{1}},
+			{Offset: 1 * time.Second, StreamType: base.StreamTypeRTP, Payload: []byte{2}},
+		}),
+		writeTestRTPDump(t, start, []rtpdump.Packet{
+			{Offset: 0, StreamType: base.StreamTypeRTP, Payload: []byte{3}},
+			{Offset: 1 * time.Second, StreamType: base.StreamTypeRTCP, Payload: []byte{4}},
+			{Offset: 2 * time.Second, StreamType: base.StreamTypeRTP, Payload: []byte{5}},
+		}),
+	)
+
+	for _, expected := range []ReplayerPacket{
+		{1, StreamTypeRTP, []byte{3}, 0},
+		{0, StreamTypeRTP, []byte{1}, 500 * time.Millisecond},
+		{1, StreamTypeRTCP, []byte{4}, 1 * time.Second},
+		{0, StreamTypeRTP, []byte{2}, 1500 * time.Millisecond},
+		{1, StreamTypeRTP, []byte{5}, 2 * time.Second},
+	} {
+		pkt, err := src.ReadPacket()
+		require.NoError(t, err)
+		require.Equal(t, expected, *pkt)
+	}
+
+	_, err := src.ReadPacket()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestReplayer(t *testing.T) {
+	s, err := Serve("127.0.0.1:8554")
+	require.NoError(t, err)
+
+	frameRecv := make(chan []byte, 10)
+
+	handleDone := make(chan struct{})
+	defer func() { <-handleDone }()
+	defer s.Close()
+
+	go func() {
+		defer close(handleDone)
+
+		s.Handle(ServerHandler{
+			OnConnOpen: func(sc *ServerConn) ServerConnReadHandlers {
+				return ServerConnReadHandlers{
+					OnAnnounce: func(ctx *ServerConnAnnounceCtx) (*base.Response, error) {
+						require.Equal(t, "teststream", ctx.Path)
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, nil
+					},
+					OnSetup: func(ctx *ServerConnSetupCtx) (*base.Response, error) {
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, nil
+					},
+					OnRecord: func(ctx *ServerConnRecordCtx) (*base.Response, error) {
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, nil
+					},
+					OnFrame: func(trackID int, streamType StreamType, payload []byte) {
+						if streamType == StreamTypeRTP {
+							require.Equal(t, 0, trackID)
+							frameRecv <- append([]byte(nil), payload...)
+						}
+					},
+				}
+			},
+		})
+	}()
+
+	track, err := NewTrackH264(96, []byte("123456"), []byte("123456"))
+	require.NoError(t, err)
+
+	pkt := func(seq byte) []byte {
+		return []byte{
+			0x80, 0x60, 0x00, seq, 0x55, 0x66, 0x77, 0x88,
+			0x9d, 0xbb, 0x78, 0x12, 0x01, 0x02, 0x03, 0x04,
+		}
+	}
+
+	src := NewReplayerSourceRTPDump(writeTestRTPDump(t, time.Now(), []rtpdump.Packet{
+		{Offset: 0, StreamType: base.StreamTypeRTP, Payload: pkt(1)},
+		{Offset: 100 * time.Millisecond, StreamType: base.StreamTypeRTCP, Payload: []byte{0x80, 0xc8, 0x00, 0x00}},
+		{Offset: 200 * time.Millisecond, StreamType: base.StreamTypeRTP, Payload: pkt(2)},
+		{Offset: 400 * time.Millisecond, StreamType: base.StreamTypeRTP, Payload: pkt(3)},
+	}))
+
+	v := StreamProtocolTCP
+	start := time.Now()
+
+	err = ReplayerConf{
+		ClientConf: ClientConf{
+			StreamProtocol: &v,
+		},
+		Speed: 2,
+	}.Replay("rtsp://localhost:8554/teststream", Tracks{track}, src)
+	require.NoError(t, err)
+
+	// packets are written at double speed
+	elapsed := time.Since(start)
+	require.GreaterOrEqual(t, int64(elapsed), int64(200*time.Millisecond))
+	require.Less(t, int64(elapsed), int64(400*time.Millisecond))
+
+	for _, seq := range []byte{1, 2, 3} {
+		require.Equal(t, pkt(seq), <-frameRecv)
+	}
+}
@@ -3,90 +3,374 @@ package gortsplib
 import (
 	"fmt"
 	"net"
+	"sync"
 	"time"
+
+	"github.com/majoyz/gortsplib/pkg/liberrors"
 )
 
+// ServerHandler contains the callbacks used by Server.Handle.
+// All fields are optional.
+type ServerHandler struct {
+	// called when a connection is opened.
+	// It returns the handlers used to read requests and frames of the connection.
+	OnConnOpen func(sc *ServerConn) ServerConnReadHandlers
+
+	// called when a connection is closed.
+	OnConnClose func(sc *ServerConn, err error)
+}
+
+type serverAcceptRes struct {
+	nconn net.Conn
+	isTLS bool
+	err   error
+}
+
 // Server is a RTSP server.
 type Server struct {
 	conf            ServerConf
 	tcpListener     net.Listener
+	tlsListener     net.Listener
 	udpRTPListener  *serverUDPListener
 	udpRTCPListener *serverUDPListener
+	udpPortRange    *serverUDPPortRange
+
+	connMutex      sync.Mutex
+	connCount      int
+	connCountPerIP map[string]int
+
+	// in use when there are both a plain and a TLS listener
+	accepted  chan serverAcceptRes
+	terminate chan struct{}
+
+	closeOnce sync.Once
 }
 
-func newServer(conf ServerConf, address string) (*Server, error) {
+func (conf *ServerConf) setDefaults() {
 	if conf.ReadTimeout == 0 {
 		conf.ReadTimeout = 10 * time.Second
 	}
 	if conf.WriteTimeout == 0 {
 		conf.WriteTimeout = 10 * time.Second
 	}
+	if conf.SessionTimeout == 0 {
+		conf.SessionTimeout = 60 * time.Second
+	}
 	if conf.ReadBufferCount == 0 {
 		conf.ReadBufferCount = 512
 	}
 	if conf.ReadBufferSize == 0 {
 		conf.ReadBufferSize = 2048
 	}
+	if conf.UDPReadBufferSize == 0 {
+		conf.UDPReadBufferSize = udpKernelReadBufferSize
+	}
+	if conf.WriteBufferCount == 0 {
+		conf.WriteBufferCount = 512
+	}
 	if conf.Listen == nil {
 		conf.Listen = net.Listen
 	}
+	if conf.Logger == nil {
+		conf.Logger = nilLogger{}
+	}
+}
 
-	if conf.TLSConfig != nil && conf.UDPRTPAddress != "" {
+func newServer(conf ServerConf, address string, tcpListener net.Listener) (*Server, error) {
+	conf.setDefaults()
+
+	if conf.TLSAddress != "" && conf.TLSConfig == nil {
+		return nil, fmt.Errorf("TLSAddress requires TLSConfig")
+	}
+
+	if conf.TLSAddress == "" && conf.TLSConfig != nil &&
+		(conf.UDPRTPAddress != "" || conf.UDPPortRange != nil) {
 		return nil, fmt.Errorf("TLS can't be used together with UDP")
 	}
 
+	if conf.UDPRTPAddress != "" && conf.UDPPortRange != nil {
+		return nil, fmt.Errorf("UDPPortRange can't be used together with UDPRTPAddress and UDPRTCPAddress")
+	}
+
 	if (conf.UDPRTPAddress != "" && conf.UDPRTCPAddress == "") ||
 		(conf.UDPRTPAddress == "" && conf.UDPRTCPAddress != "") {
 		return nil, fmt.Errorf("UDPRTPAddress and UDPRTCPAddress must be used together")
 	}
 
 	s := &Server{
-		conf: conf,
+		conf:           conf,
+		connCountPerIP: make(map[string]int),
 	}
 
 	if conf.UDPRTPAddress != "" {
 		var err error
-		s.udpRTPListener, err = newServerUDPListener(conf, conf.UDPRTPAddress, StreamTypeRTP)
+		s.udpRTPListener, err = newServerUDPListener(conf, conf.UDPRTPAddress, StreamTypeRTP, false)
+		if err != nil {
+			return nil, err
+		}
+
+		s.udpRTCPListener, err = newServerUDPListener(conf, conf.UDPRTCPAddress, StreamTypeRTCP, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if conf.UDPPortRange != nil {
+		var err error
+		s.udpPortRange, err = newServerUDPPortRange(conf, address)
 		if err != nil {
 			return nil, err
 		}
+	}
 
-		s.udpRTCPListener, err = newServerUDPListener(conf, conf.UDPRTCPAddress, StreamTypeRTCP)
+	if tcpListener != nil {
+		s.tcpListener = tcpListener
+	} else {
+		var err error
+		s.tcpListener, err = conf.Listen(conf.AddressFamily.network("tcp"), address)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	var err error
-	s.tcpListener, err = conf.Listen("tcp", address)
-	if err != nil {
-		return nil, err
+	if conf.TLSAddress != "" {
+		var err error
+		s.tlsListener, err = conf.Listen(conf.AddressFamily.network("tcp"), conf.TLSAddress)
+		if err != nil {
+			s.tcpListener.Close()
+			return nil, err
+		}
+
+		s.accepted = make(chan serverAcceptRes)
+		s.terminate = make(chan struct{})
+
+		go s.runAccept(s.tcpListener, false)
+		go s.runAccept(s.tlsListener, true)
 	}
 
 	return s, nil
 }
 
+// runAccept routes the connections of a listener to Accept().
+func (s *Server) runAccept(l net.Listener, isTLS bool) {
+	for {
+		nconn, err := l.Accept()
+
+		// errors caused by Close() are not routed
+		if err != nil {
+			select {
+			case <-s.terminate:
+				return
+			default:
+			}
+		}
+
+		select {
+		case s.accepted <- serverAcceptRes{nconn, isTLS, err}:
+		case <-s.terminate:
+			if err == nil {
+				nconn.Close()
+			}
+			return
+		}
+
+		// the server is closed when a listener fails, otherwise the other
+		// listener would keep accepting connections that are never read.
+		if err != nil {
+			s.Close()
+			return
+		}
+	}
+}
+
 // Close closes the server.
+// It can be called multiple times.
 func (s *Server) Close() error {
-	s.tcpListener.Close()
+	s.closeOnce.Do(func() {
+		if s.tlsListener != nil {
+			close(s.terminate)
+			s.tlsListener.Close()
+		}
+
+		s.tcpListener.Close()
+
+		if s.udpRTPListener != nil {
+			s.udpRTPListener.close()
+		}
+
+		if s.udpRTCPListener != nil {
+			s.udpRTCPListener.close()
+		}
+	})
 
-	if s.udpRTPListener != nil {
-		s.udpRTPListener.close()
+	return nil
+}
+
+// UDPBufferSizes returns the kernel read and write buffer sizes of a
+// UDP listener, as reported by the operating system.
+// Some operating systems (like Linux) report twice the configured size.
+func (s *Server) UDPBufferSizes(streamType StreamType) (int, int, error) {
+	if s.udpRTPListener == nil {
+		return 0, 0, fmt.Errorf("UDP listeners are not enabled")
 	}
 
-	if s.udpRTCPListener != nil {
-		s.udpRTCPListener.close()
+	if streamType == StreamTypeRTP {
+		return udpBufferSizes(s.udpRTPListener.pc)
 	}
+	return udpBufferSizes(s.udpRTCPListener.pc)
+}
 
-	return nil
+func connIP(nconn net.Conn) string {
+	if addr, ok := nconn.RemoteAddr().(*net.TCPAddr); ok {
+		return addr.IP.String()
+	}
+	return nconn.RemoteAddr().String()
+}
+
+// acquireConn checks whether a connection can be accepted.
+func (s *Server) acquireConn(nconn net.Conn) bool {
+	if s.conf.RateLimiter != nil && !s.conf.RateLimiter(nconn.RemoteAddr(), nil) {
+		return false
+	}
+
+	s.connMutex.Lock()
+	defer s.connMutex.Unlock()
+
+	ip := connIP(nconn)
+
+	if s.conf.MaxConnections > 0 && s.connCount >= s.conf.MaxConnections {
+		return false
+	}
+
+	if s.conf.MaxConnectionsPerIP > 0 && s.connCountPerIP[ip] >= s.conf.MaxConnectionsPerIP {
+		return false
+	}
+
+	s.connCount++
+	s.connCountPerIP[ip]++
+	return true
+}
+
+func (s *Server) releaseConn(nconn net.Conn) {
+	s.connMutex.Lock()
+	defer s.connMutex.Unlock()
+
+	ip := connIP(nconn)
+
+	s.connCount--
+	s.connCountPerIP[ip]--
+	if s.connCountPerIP[ip] == 0 {
+		delete(s.connCountPerIP, ip)
+	}
+}
+
+func (s *Server) acceptNetConn() (net.Conn, bool, error) {
+	if s.tlsListener == nil {
+		nconn, err := s.tcpListener.Accept()
+		return nconn, s.conf.TLSConfig != nil, err
+	}
+
+	select {
+	case res := <-s.accepted:
+		return res.nconn, res.isTLS, res.err
+
+	case <-s.terminate:
+		return nil, false, liberrors.ErrServerTerminated{}
+	}
 }
 
 // Accept accepts a connection.
+// Connections that exceed MaxConnections, MaxConnectionsPerIP or that are
+// refused by RateLimiter are closed and skipped.
 func (s *Server) Accept() (*ServerConn, error) {
-	nconn, err := s.tcpListener.Accept()
-	if err != nil {
-		return nil, err
+	for {
+		nconn, isTLS, err := s.acceptNetConn()
+		if err != nil {
+			return nil, err
+		}
+
+		if !s.acquireConn(nconn) {
+			nconn.Close()
+			continue
+		}
+
+		conf := s.conf
+		if !isTLS {
+			conf.TLSConfig = nil
+		}
+
+		sc := newServerConn(conf, s.udpRTPListener, s.udpRTCPListener, s.udpPortRange, nconn)
+		sc.onClose = func() {
+			s.releaseConn(nconn)
+		}
+		return sc, nil
 	}
+}
+
+// Handle accepts connections and reads them with the handlers returned
+// by OnConnOpen, until the server is closed.
+// When the server is closed, all connections are closed and the function
+// returns after OnConnClose has been called for each of them.
+// It can't be used together with Accept().
+func (s *Server) Handle(handler ServerHandler) error {
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	conns := make(map[*ServerConn]struct{})
+
+	defer func() {
+		// connections are closed in parallel, since each of them
+		// can wait up to CloseDrainTimeout
+		var closeWG sync.WaitGroup
+
+		mutex.Lock()
+		for sc := range conns {
+			delete(conns, sc)
 
-	return newServerConn(s.conf, s.udpRTPListener, s.udpRTCPListener, nconn), nil
+			closeWG.Add(1)
+			go func(sc *ServerConn) {
+				defer closeWG.Done()
+				sc.Close()
+			}(sc)
+		}
+		mutex.Unlock()
+
+		closeWG.Wait()
+		wg.Wait()
+	}()
+
+	for {
+		sc, err := s.Accept()
+		if err != nil {
+			return err
+		}
+
+		mutex.Lock()
+		conns[sc] = struct{}{}
+		mutex.Unlock()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var readHandlers ServerConnReadHandlers
+			if handler.OnConnOpen != nil {
+				readHandlers = handler.OnConnOpen(sc)
+			}
+
+			err := <-sc.Read(readHandlers)
+
+			mutex.Lock()
+			_, ok := conns[sc]
+			delete(conns, sc)
+			mutex.Unlock()
+
+			if ok {
+				sc.Close()
+			}
+
+			if handler.OnConnClose != nil {
+				handler.OnConnClose(sc, err)
+			}
+		}()
+	}
 }
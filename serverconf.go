@@ -4,8 +4,49 @@ import (
 	"crypto/tls"
 	"net"
 	"time"
+
+	"github.com/majoyz/gortsplib/pkg/base"
+	"github.com/majoyz/gortsplib/pkg/headers"
+)
+
+// ServerWriteQueuePolicy is the policy applied when the write queue of a
+// connection is full.
+type ServerWriteQueuePolicy int
+
+const (
+	// ServerWriteQueuePolicyDropOldest discards the oldest frames in the queue.
+	ServerWriteQueuePolicyDropOldest ServerWriteQueuePolicy = iota
+
+	// ServerWriteQueuePolicyDisconnect closes the connection.
+	ServerWriteQueuePolicyDisconnect
+)
+
+// ServerAddressFamily is the address family of the listeners of a Server.
+type ServerAddressFamily int
+
+const (
+	// ServerAddressFamilyAny allows both IPv4 and IPv6.
+	ServerAddressFamilyAny ServerAddressFamily = iota
+
+	// ServerAddressFamilyIPv4 allows IPv4 only.
+	ServerAddressFamilyIPv4
+
+	// ServerAddressFamilyIPv6 allows IPv6 only.
+	ServerAddressFamilyIPv6
 )
 
+// network returns the network that corresponds to the address family.
+func (f ServerAddressFamily) network(base string) string {
+	switch f {
+	case ServerAddressFamilyIPv4:
+		return base + "4"
+
+	case ServerAddressFamilyIPv6:
+		return base + "6"
+	}
+	return base
+}
+
 // DefaultServerConf is the default ServerConf.
 var DefaultServerConf = ServerConf{}
 
@@ -14,12 +55,29 @@ func Serve(address string) (*Server, error) {
 	return DefaultServerConf.Serve(address)
 }
 
+// ServeListener starts a server that accepts connections from an existing listener.
+func ServeListener(l net.Listener) (*Server, error) {
+	return DefaultServerConf.ServeListener(l)
+}
+
+// NewServerConn allocates a ServerConn that uses an existing connection.
+func NewServerConn(nconn net.Conn) *ServerConn {
+	return DefaultServerConf.NewServerConn(nconn)
+}
+
 // ServerConf allows to configure a Server.
 // All fields are optional.
 type ServerConf struct {
 	// a TLS configuration to accept TLS (RTSPS) connections.
+	// Clients can be authenticated with certificates by setting ClientAuth;
+	// verified certificate chains are then available in the contexts of requests.
 	TLSConfig *tls.Config
 
+	// (optional) address of a second listener that accepts TLS (RTSPS)
+	// connections with TLSConfig. When set, the main address accepts plain
+	// (RTSP) connections, and UDP can be used by plain connections only.
+	TLSAddress string
+
 	// a port to send and receive UDP/RTP packets.
 	// If UDPRTPAddress and UDPRTCPAddress are != "", the server can accept and send UDP streams.
 	UDPRTPAddress string
@@ -28,6 +86,10 @@ type ServerConf struct {
 	// If UDPRTPAddress and UDPRTCPAddress are != "", the server can accept and send UDP streams.
 	UDPRTCPAddress string
 
+	// address family of the TCP and UDP listeners.
+	// It defaults to ServerAddressFamilyAny.
+	AddressFamily ServerAddressFamily
+
 	// timeout of read operations.
 	// It defaults to 10 seconds
 	ReadTimeout time.Duration
@@ -36,6 +98,21 @@ type ServerConf struct {
 	// It defaults to 10 seconds
 	WriteTimeout time.Duration
 
+	// maximum time that ServerConn.Close() and Server.Handle() wait for the
+	// response to a request that is being processed, and for the write queue
+	// to be flushed, before closing a connection.
+	// If the handler doesn't return in time, a 503 Service Unavailable
+	// response is sent in place of its response.
+	// It defaults to zero, that means that connections are closed immediately.
+	CloseDrainTimeout time.Duration
+
+	// timeout of sessions.
+	// Sessions of clients that are reading with UDP are closed when
+	// no requests or RTCP packets are received within this period.
+	// It is advertised to clients in the Session header.
+	// It defaults to 60 seconds.
+	SessionTimeout time.Duration
+
 	// read buffer count.
 	// If greater than 1, allows to pass buffers to routines different than the one
 	// that is reading frames.
@@ -49,12 +126,139 @@ type ServerConf struct {
 	// It defaults to 2048.
 	ReadBufferSize int
 
+	// limits on the size of the requests and responses sent by clients,
+	// that protect the server from memory exhaustion.
+	// Connections that exceed them are closed with a base.ErrLimitExceeded.
+	// Fields that are not set use the defaults of base.ReadLimits.
+	ReadLimits base.ReadLimits
+
+	// size of the queue of outgoing frames of each connection, in frames.
+	// The queue is used with TCP, in order to prevent slow clients
+	// from blocking the routine that is writing frames.
+	// It defaults to 512
+	WriteBufferCount int
+
+	// policy applied when the queue of outgoing frames of a connection is full.
+	// It defaults to ServerWriteQueuePolicyDropOldest.
+	WriteQueuePolicy ServerWriteQueuePolicy
+
+	// size of the buffer used to reorder RTP packets received with UDP, in packets.
+	// If greater than zero, RTP packets received from clients that are publishing
+	// are passed to OnFrame in sequence number order.
+	// It defaults to 0 (disabled), or to 64 if UDPReorderBufferDuration is set.
+	UDPReorderBufferSize int
+
+	// maximum time RTP packets received with UDP can wait in the reorder buffer
+	// for the missing ones. If greater than zero, RTP packets are reordered and
+	// packets that wait for longer are passed to OnFrame, skipping the missing ones;
+	// this limits the latency added by reordering when packets are lost.
+	// It defaults to 0 (packets wait until the buffer is full).
+	UDPReorderBufferDuration time.Duration
+
+	// first and last port of a range used to allocate a distinct couple of UDP ports
+	// (RTP and RTCP) to each track of each session, instead of sharing
+	// the UDP listeners set by UDPRTPAddress and UDPRTCPAddress.
+	// This is needed by clients behind symmetric NATs and by some hardware devices.
+	// It can't be used together with UDPRTPAddress and UDPRTCPAddress.
+	// It defaults to nil (disabled).
+	UDPPortRange *[2]int
+
+	// size of the kernel read buffer (SO_RCVBUF) of the UDP listeners, in bytes.
+	// It must be increased when receiving high-bitrate streams with UDP.
+	// It defaults to 512KiB.
+	UDPReadBufferSize int
+
+	// size of the kernel write buffer (SO_SNDBUF) of the UDP listeners, in bytes.
+	// It defaults to 0 (operating system default).
+	UDPWriteBufferSize int
+
+	// disable sending RTCP receiver reports to clients that are publishing.
+	// It defaults to false.
+	ReceiverReportsDisable bool
+
+	// period of RTCP sender reports sent to clients that are reading.
+	// Sender reports are generated for the tracks of readers that have been
+	// added to a ServerStream, since the clock rate of tracks is needed.
+	// It defaults to 0 (disabled).
+	SenderReportPeriod time.Duration
+
+	// authentication methods offered to clients.
+	// It defaults to Basic and Digest.
+	AuthMethods []headers.AuthMethod
+
+	// function used to retrieve the credentials required to access a path.
+	// If it is not nil and returns a non-empty user, DESCRIBE, ANNOUNCE and SETUP
+	// requests are answered with 401 until the client provides valid credentials,
+	// and only then they are passed to the handlers.
+	// It defaults to nil.
+	AuthCredentials func(method base.Method, path string, query string) (string, string)
+
+	// disable echoing the SSRC provided by publishing clients in the
+	// Transport header of SETUP responses.
+	// It defaults to false.
+	SetupTransportSSRCDisable bool
+
+	// add the source field, containing the local IP of the connection,
+	// to the Transport header of SETUP responses with UDP.
+	// It defaults to false.
+	SetupTransportSource bool
+
+	// add the server_port field, containing the ports of the UDP listeners,
+	// to the Transport header of SETUP responses with TCP.
+	// Some clients refuse responses without it.
+	// It is ignored when UDPRTPAddress and UDPRTCPAddress are not set.
+	// It defaults to false.
+	SetupTransportTCPServerPorts bool
+
+	// maximum number of connections accepted by Server.Accept() at the same time.
+	// Connections beyond the limit are closed immediately.
+	// It defaults to 0 (unlimited).
+	MaxConnections int
+
+	// maximum number of connections from the same IP accepted by Server.Accept()
+	// at the same time.
+	// It defaults to 0 (unlimited).
+	MaxConnectionsPerIP int
+
+	// methods supported by the server, that are advertised in the Public header
+	// of OPTIONS responses. Requests with other methods are rejected with
+	// 405 (Method Not Allowed). OPTIONS requests are always accepted.
+	// It defaults to the methods whose handlers are set, plus GET_PARAMETER and TEARDOWN.
+	Methods []base.Method
+
+	// function used to limit the rate of incoming connections and requests.
+	// It is called with a nil request when a connection is accepted, that is
+	// closed if the function returns false, and when a DESCRIBE or ANNOUNCE request
+	// is received, that is answered with 503 if the function returns false.
+	// It defaults to nil.
+	RateLimiter func(addr net.Addr, req *base.Request) bool
+
 	// function used to initialize the TCP listener.
 	// It defaults to net.Listen
 	Listen func(network string, address string) (net.Listener, error)
+
+	// (optional) logger that receives messages about events that are
+	// handled without closing connections, like discarded packets and frames.
+	// It can be called by multiple routines.
+	Logger Logger
 }
 
 // Serve starts a server on the given address.
 func (c ServerConf) Serve(address string) (*Server, error) {
-	return newServer(c, address)
+	return newServer(c, address, nil)
+}
+
+// ServeListener starts a server that accepts connections from an existing listener,
+// like the ones provided by socket activation, custom TLS wrappers or in-memory listeners.
+// The listener is closed when the server is closed.
+// UDP is supported only when the listener and its connections are TCP ones.
+func (c ServerConf) ServeListener(l net.Listener) (*Server, error) {
+	return newServer(c, l.Addr().String(), l)
+}
+
+// NewServerConn allocates a ServerConn that uses an existing connection,
+// without a Server. Since there are no UDP listeners, only TCP can be used.
+func (c ServerConf) NewServerConn(nconn net.Conn) *ServerConn {
+	c.setDefaults()
+	return newServerConn(c, nil, nil, nil, nconn)
 }
@@ -2,20 +2,31 @@ package gortsplib
 
 import (
 	"bufio"
+	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io"
 	"net"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+
+	"github.com/majoyz/gortsplib/pkg/auth"
 	"github.com/majoyz/gortsplib/pkg/base"
 	"github.com/majoyz/gortsplib/pkg/headers"
 	"github.com/majoyz/gortsplib/pkg/liberrors"
 	"github.com/majoyz/gortsplib/pkg/multibuffer"
 	"github.com/majoyz/gortsplib/pkg/ringbuffer"
 	"github.com/majoyz/gortsplib/pkg/rtcpreceiver"
+	"github.com/majoyz/gortsplib/pkg/rtcpsender"
+	"github.com/majoyz/gortsplib/pkg/srtp"
 )
 
 const (
@@ -23,6 +34,7 @@ const (
 	serverConnWriteBufferSize        = 4096
 	serverConnCheckStreamInterval    = 5 * time.Second
 	serverConnReceiverReportInterval = 10 * time.Second
+	serverConnAuthMaxFailures        = 3
 )
 
 func stringsReverseIndex(s, substr string) int {
@@ -44,15 +56,16 @@ func setupGetTrackIDPathQuery(url *base.URL,
 		return 0, "", "", liberrors.ErrServerNoPath{}
 	}
 
-	if thMode == nil || *thMode == headers.TransportModePlay {
+	// tracks of a reading session are identified by their URL,
+	// even when they are setupped in record mode (back channels)
+	if thMode == nil || *thMode == headers.TransportModePlay || announcedTracks == nil {
 		i := stringsReverseIndex(pathAndQuery, "/trackID=")
 
-		// URL doesn't contain trackID - it's track zero
+		// URL doesn't contain trackID - it's track zero.
+		// this happens with single-track streams without a control attribute
+		// (i.e. MPEG-TS), whose URL may not end with a slash.
 		if i < 0 {
-			if !strings.HasSuffix(pathAndQuery, "/") {
-				return 0, "", "", fmt.Errorf("path must end with a slash (%v)", pathAndQuery)
-			}
-			pathAndQuery = pathAndQuery[:len(pathAndQuery)-1]
+			pathAndQuery = strings.TrimSuffix(pathAndQuery, "/")
 
 			path, query := base.PathSplitQuery(pathAndQuery)
 
@@ -62,7 +75,7 @@ func setupGetTrackIDPathQuery(url *base.URL,
 
 		tmp, err := strconv.ParseInt(pathAndQuery[i+len("/trackID="):], 10, 64)
 		if err != nil || tmp < 0 {
-			return 0, "", "", fmt.Errorf("unable to parse track ID (%v)", pathAndQuery)
+			return 0, "", "", liberrors.ErrServerTrackIDInvalid{Path: pathAndQuery}
 		}
 		trackID := int(tmp)
 		pathAndQuery = pathAndQuery[:i]
@@ -70,7 +83,7 @@ func setupGetTrackIDPathQuery(url *base.URL,
 		path, query := base.PathSplitQuery(pathAndQuery)
 
 		if setupPath != nil && (path != *setupPath || query != *setupQuery) {
-			return 0, "", "", fmt.Errorf("can't setup tracks with different paths")
+			return 0, "", "", liberrors.ErrServerTracksDifferentPaths{}
 		}
 
 		return trackID, path, query, nil
@@ -83,7 +96,7 @@ func setupGetTrackIDPathQuery(url *base.URL,
 		}
 	}
 
-	return 0, "", "", fmt.Errorf("invalid track path (%s)", pathAndQuery)
+	return 0, "", "", liberrors.ErrServerTrackPathInvalid{Path: pathAndQuery}
 }
 
 // ServerConnState is the state of the connection.
@@ -117,14 +130,113 @@ func (s ServerConnState) String() string {
 
 // ServerConnSetuppedTrack is a setupped track of a ServerConn.
 type ServerConnSetuppedTrack struct {
-	rtpPort  int
-	rtcpPort int
+	mode        headers.TransportMode
+	rtpPort     int
+	rtcpPort    int
+	udpRTPAddr  *net.UDPAddr
+	udpRTCPAddr *net.UDPAddr
+	srtpContext *srtp.Context
+
+	// UDP listeners, that are either shared or dedicated to the track.
+	udpRTPListener  *serverUDPListener
+	udpRTCPListener *serverUDPListener
+
+	// used to fill the RTP-Info header.
+	url      *base.URL
+	rtpState *serverRTPState
+
+	// used to generate RTCP sender reports.
+	rtcpSender *rtcpsender.RTCPSender
+
+	// channels of frames, used only with TCP.
+	interleavedIDs *[2]int
+}
+
+// InterleavedIDs returns the channels of the RTP and RTCP frames of the track,
+// or nil if the track is not transmitted with TCP.
+func (t ServerConnSetuppedTrack) InterleavedIDs() *[2]int {
+	return t.interleavedIDs
+}
+
+// serverConnInterleavedChannel is the destination of frames received
+// on a interleaved channel.
+type serverConnInterleavedChannel struct {
+	trackID    int
+	streamType StreamType
+}
+
+// interleavedFrameChannel returns the channel of a frame.
+// base.InterleavedFrame stores the channel into TrackID and StreamType.
+func interleavedFrameChannel(f *base.InterleavedFrame) int {
+	if f.StreamType == StreamTypeRTP {
+		return f.TrackID * 2
+	}
+	return f.TrackID*2 + 1
+}
+
+// setInterleavedFrameChannel sets the channel of a frame.
+func setInterleavedFrameChannel(f *base.InterleavedFrame, channel int) {
+	f.TrackID = channel / 2
+	if (channel % 2) == 0 {
+		f.StreamType = StreamTypeRTP
+	} else {
+		f.StreamType = StreamTypeRTCP
+	}
+}
+
+// serverRTPState contains the sequence number and the timestamp
+// of the last RTP packet written to a track.
+type serverRTPState struct {
+	mutex          sync.Mutex
+	initialized    bool
+	sequenceNumber uint16
+	timestamp      uint32
+	ssrc           uint32
+}
+
+func (s *serverRTPState) update(pkt []byte) {
+	// do not parse the entire packet, extract only the needed fields
+	if len(pkt) < 12 {
+		return
+	}
+
+	s.set(uint16(pkt[2])<<8|uint16(pkt[3]),
+		uint32(pkt[4])<<24|uint32(pkt[5])<<16|uint32(pkt[6])<<8|uint32(pkt[7]))
+
+	s.mutex.Lock()
+	s.ssrc = uint32(pkt[8])<<24 | uint32(pkt[9])<<16 | uint32(pkt[10])<<8 | uint32(pkt[11])
+	s.mutex.Unlock()
+}
+
+func (s *serverRTPState) set(sequenceNumber uint16, timestamp uint32) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.initialized = true
+	s.sequenceNumber = sequenceNumber
+	s.timestamp = timestamp
+}
+
+func (s *serverRTPState) get() (uint16, uint32, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.sequenceNumber, s.timestamp, s.initialized
+}
+
+func (s *serverRTPState) getSSRC() uint32 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.ssrc
 }
 
 // ServerConnAnnouncedTrack is an announced track of a ServerConn.
 type ServerConnAnnouncedTrack struct {
 	track            *Track
 	rtcpReceiver     *rtcpreceiver.RTCPReceiver
+	rtpReorderer     *udpReorderer
+	ssrcFilter       *ssrcFilter
 	udpLastFrameTime *int64
 }
 
@@ -133,6 +245,9 @@ type ServerConnOptionsCtx struct {
 	Req   *base.Request
 	Path  string
 	Query string
+
+	// (optional) certificate chain of the client, verified through mutual TLS.
+	ClientCertificates []*x509.Certificate
 }
 
 // ServerConnDescribeCtx is the context of a DESCRIBE request.
@@ -140,6 +255,9 @@ type ServerConnDescribeCtx struct {
 	Req   *base.Request
 	Path  string
 	Query string
+
+	// (optional) certificate chain of the client, verified through mutual TLS.
+	ClientCertificates []*x509.Certificate
 }
 
 // ServerConnAnnounceCtx is the context of a ANNOUNCE request.
@@ -148,6 +266,9 @@ type ServerConnAnnounceCtx struct {
 	Path   string
 	Query  string
 	Tracks Tracks
+
+	// (optional) certificate chain of the client, verified through mutual TLS.
+	ClientCertificates []*x509.Certificate
 }
 
 // ServerConnSetupCtx is the context of a OPTIONS request.
@@ -157,6 +278,14 @@ type ServerConnSetupCtx struct {
 	Query     string
 	TrackID   int
 	Transport *headers.Transport
+
+	// direction of the track.
+	// When reading, tracks in record mode are back channels,
+	// that are sent by the client to the server.
+	Mode headers.TransportMode
+
+	// (optional) certificate chain of the client, verified through mutual TLS.
+	ClientCertificates []*x509.Certificate
 }
 
 // ServerConnPlayCtx is the context of a PLAY request.
@@ -164,6 +293,12 @@ type ServerConnPlayCtx struct {
 	Req   *base.Request
 	Path  string
 	Query string
+
+	// (optional) the Range header, used to seek the stream
+	Range *headers.Range
+
+	// (optional) certificate chain of the client, verified through mutual TLS.
+	ClientCertificates []*x509.Certificate
 }
 
 // ServerConnRecordCtx is the context of a RECORD request.
@@ -171,6 +306,9 @@ type ServerConnRecordCtx struct {
 	Req   *base.Request
 	Path  string
 	Query string
+
+	// (optional) certificate chain of the client, verified through mutual TLS.
+	ClientCertificates []*x509.Certificate
 }
 
 // ServerConnPauseCtx is the context of a PAUSE request.
@@ -178,6 +316,9 @@ type ServerConnPauseCtx struct {
 	Req   *base.Request
 	Path  string
 	Query string
+
+	// (optional) certificate chain of the client, verified through mutual TLS.
+	ClientCertificates []*x509.Certificate
 }
 
 // ServerConnGetParameterCtx is the context of a GET_PARAMETER request.
@@ -185,6 +326,9 @@ type ServerConnGetParameterCtx struct {
 	Req   *base.Request
 	Path  string
 	Query string
+
+	// (optional) certificate chain of the client, verified through mutual TLS.
+	ClientCertificates []*x509.Certificate
 }
 
 // ServerConnSetParameterCtx is the context of a SET_PARAMETER request.
@@ -192,6 +336,9 @@ type ServerConnSetParameterCtx struct {
 	Req   *base.Request
 	Path  string
 	Query string
+
+	// (optional) certificate chain of the client, verified through mutual TLS.
+	ClientCertificates []*x509.Certificate
 }
 
 // ServerConnTeardownCtx is the context of a TEARDOWN request.
@@ -199,15 +346,20 @@ type ServerConnTeardownCtx struct {
 	Req   *base.Request
 	Path  string
 	Query string
+
+	// (optional) certificate chain of the client, verified through mutual TLS.
+	ClientCertificates []*x509.Certificate
 }
 
 // ServerConnReadHandlers allows to set the handlers required by ServerConn.Read.
 // all fields are optional.
 type ServerConnReadHandlers struct {
-	// called after receiving any request.
+	// called after receiving any request, and before sending a request to the client.
+	// It can be used to log RTSP exchanges or to edit requests.
 	OnRequest func(req *base.Request)
 
-	// called before sending any response.
+	// called before sending any response, and after receiving a response from the client.
+	// It can be used to log RTSP exchanges or to edit responses.
 	OnResponse func(res *base.Response)
 
 	// called after receiving a OPTIONS request.
@@ -225,6 +377,8 @@ type ServerConnReadHandlers struct {
 	OnSetup func(ctx *ServerConnSetupCtx) (*base.Response, error)
 
 	// called after receiving a PLAY request.
+	// If the response doesn't contain a RTP-Info header, it is filled
+	// with the last RTP packets written to the tracks.
 	OnPlay func(ctx *ServerConnPlayCtx) (*base.Response, error)
 
 	// called after receiving a RECORD request.
@@ -246,34 +400,110 @@ type ServerConnReadHandlers struct {
 
 	// called after receiving a frame.
 	OnFrame func(trackID int, streamType StreamType, payload []byte)
+
+	// (optional) called after receiving a RTP frame, with the parsed packet.
+	// It is called after OnFrame. Frames that can't be parsed are passed to OnFrame only.
+	// The payload of the packet is valid only until the callback returns.
+	OnPacketRTP func(trackID int, pkt *rtp.Packet)
+
+	// (optional) called after receiving a RTCP frame, with the parsed packets.
+	// It is called after OnFrame. Frames that can't be parsed are passed to OnFrame only.
+	OnPacketRTCP func(trackID int, pkts []rtcp.Packet)
+
+	// called when a UDP packet of a published track is discarded since it comes from
+	// an unexpected address or, in case of RTP packets, since its SSRC is different
+	// from the one provided by the client or from the one of the stream that is
+	// being received.
+	// It is also called when a UDP packet of any track can't be decrypted with SRTP.
+	// It is called by the routine that reads UDP packets.
+	OnUDPPacketDiscarded func(err error)
+
+	// called when a request can't be processed, before closing the connection.
+	// It is also called when the connection is closed since a request, a response
+	// or a frame can't be decoded; in this case, req is nil or contains the part
+	// of the request that has been decoded.
+	OnRequestError func(req *base.Request, err error)
+
+	// called when the state of the connection changes.
+	OnStateChange func(prev ServerConnState, cur ServerConnState)
+
+	// called when the connection is closed because of a timeout, i.e.
+	// when the client doesn't send requests, UDP packets or keepalives in time.
+	OnTimeout func(err error)
+
+	// (optional) called after receiving any request, in place of all other
+	// request handlers. It enables a low-level mode, in which the connection
+	// doesn't keep any state and the response is sent to the client as is,
+	// apart from the CSeq header. It allows to build proxies that forward
+	// requests to another server.
+	OnRequestPassthrough func(req *base.Request) (*base.Response, error)
+
+	// (optional) called after receiving an interleaved frame when
+	// OnRequestPassthrough is set. Frames are not decoded, and are identified
+	// by their channel, therefore they can be forwarded to another connection.
+	// The payload is valid only until the callback returns.
+	OnInterleavedFrame func(channel int, payload []byte)
 }
 
 // ServerConn is a server-side RTSP connection.
 type ServerConn struct {
 	conf            ServerConf
 	nconn           net.Conn
+	tlsConn         *tls.Conn
 	udpRTPListener  *serverUDPListener
 	udpRTCPListener *serverUDPListener
+	udpPortRange    *serverUDPPortRange
 	br              *bufio.Reader
 	bw              *bufio.Writer
 	state           ServerConnState
 	setuppedTracks  map[int]ServerConnSetuppedTrack
 	setupProtocol   *StreamProtocol
+
+	// destination of frames received with TCP, indexed by channel
+	interleavedChannels map[int]serverConnInterleavedChannel
+
 	setupPath       *string
 	setupQuery      *string
+	authUser        string
+	authPass        string
+	authValidator   *auth.Validator
+	authFailures    int
+	lastActivity    *int64
+	sessionTimedOut int32
+	streamURL       *base.URL
+	writeMutex      sync.Mutex
+	cseq            int
+	onClose         func()
+
+	// request that is being processed, protected by writeMutex
+	inFlightDone      chan struct{}
+	inFlightCSeq      base.HeaderValue
+	inFlightResponded bool
+
+	// server-initiated requests
+	pendingRequests      map[int]chan *base.Response
+	pendingRequestsMutex sync.Mutex
+	backgroundReadDone   chan struct{}
 
 	// frame mode only
 	doEnableFrames      bool
 	framesEnabled       bool
 	readTimeoutEnabled  bool
 	frameRingBuffer     *ringbuffer.RingBuffer
+	droppedFrames       uint64
+	writeQueueLen       int64
+	writeQueueFull      int32
+	writeQueueFlushed   chan struct{}
 	backgroundWriteDone chan struct{}
 
 	// read only
-	readHandlers ServerConnReadHandlers
+	readHandlers            ServerConnReadHandlers
+	backgroundPlayTerminate chan struct{}
+	backgroundPlayDone      chan struct{}
 
 	// publish only
 	announcedTracks           []ServerConnAnnouncedTrack
+	describedTracks           Tracks
 	backgroundRecordTerminate chan struct{}
 	backgroundRecordDone      chan struct{}
 	udpTimeout                int32
@@ -285,34 +515,131 @@ type ServerConn struct {
 func newServerConn(conf ServerConf,
 	udpRTPListener *serverUDPListener,
 	udpRTCPListener *serverUDPListener,
+	udpPortRange *serverUDPPortRange,
 	nconn net.Conn) *ServerConn {
-	conn := func() net.Conn {
-		if conf.TLSConfig != nil {
-			return tls.Server(nconn, conf.TLSConfig)
-		}
-		return nconn
-	}()
+	var tlsConn *tls.Conn
+	conn := nconn
+	if conf.TLSConfig != nil {
+		tlsConn = tls.Server(nconn, conf.TLSConfig)
+		conn = tlsConn
+	}
+
+	lastActivity := time.Now().Unix()
 
 	return &ServerConn{
 		conf:                conf,
+		lastActivity:        &lastActivity,
 		udpRTPListener:      udpRTPListener,
 		udpRTCPListener:     udpRTCPListener,
+		udpPortRange:        udpPortRange,
 		nconn:               nconn,
+		tlsConn:             tlsConn,
 		br:                  bufio.NewReaderSize(conn, serverConnReadBufferSize),
 		bw:                  bufio.NewWriterSize(conn, serverConnWriteBufferSize),
-		frameRingBuffer:     ringbuffer.New(uint64(conf.ReadBufferCount)),
+		frameRingBuffer:     ringbuffer.New(uint64(conf.WriteBufferCount)),
+		writeQueueFlushed:   make(chan struct{}, 1),
 		backgroundWriteDone: make(chan struct{}),
+		pendingRequests:     make(map[int]chan *base.Response),
+		backgroundReadDone:  make(chan struct{}),
 		terminate:           make(chan struct{}),
 	}
 }
 
 // Close closes all the connection resources.
+// If ServerConf.CloseDrainTimeout is set and a request is being processed,
+// Close returns immediately and the connection is closed in background,
+// after the response to the request has been written; this allows handlers
+// to call Close. Otherwise, it waits for the write queue to be flushed.
 func (sc *ServerConn) Close() error {
+	if sc.conf.CloseDrainTimeout > 0 {
+		sc.writeMutex.Lock()
+		inFlight := (sc.inFlightDone != nil)
+		sc.writeMutex.Unlock()
+
+		// the caller may be the handler of the request, that can't
+		// return until Close does.
+		if inFlight {
+			go func() {
+				sc.drain()
+				sc.close()
+			}()
+			return nil
+		}
+
+		sc.drain()
+	}
+
+	return sc.close()
+}
+
+func (sc *ServerConn) close() error {
 	err := sc.nconn.Close()
 	close(sc.terminate)
+
+	if sc.onClose != nil {
+		sc.onClose()
+	}
+
 	return err
 }
 
+// drain waits until the response to the request that is being processed
+// has been written. If the handler doesn't return within CloseDrainTimeout,
+// a 503 response is written in its place.
+func (sc *ServerConn) drain() {
+	deadline := time.Now().Add(sc.conf.CloseDrainTimeout)
+
+	sc.writeMutex.Lock()
+	done := sc.inFlightDone
+	sc.writeMutex.Unlock()
+
+	if done != nil {
+		t := time.NewTimer(time.Until(deadline))
+		select {
+		case <-done:
+		case <-t.C:
+		}
+		t.Stop()
+
+		sc.writeMutex.Lock()
+		if sc.inFlightDone == done {
+			sc.inFlightResponded = true
+
+			res := &base.Response{
+				StatusCode: base.StatusServiceUnavailable,
+				Header: base.Header{
+					"CSeq":   sc.inFlightCSeq,
+					"Server": base.HeaderValue{"gortsplib"},
+				},
+			}
+
+			// the drain timeout has expired, therefore the 503 is given
+			// its own write timeout.
+			deadline = time.Now().Add(sc.conf.WriteTimeout)
+
+			if sc.framesEnabled {
+				sc.pushWrite(res)
+			} else {
+				sc.nconn.SetWriteDeadline(deadline)
+				res.Write(sc.bw)
+			}
+		}
+		sc.writeMutex.Unlock()
+	}
+
+	// wait for the write queue to be flushed
+	t := time.NewTimer(time.Until(deadline))
+	defer t.Stop()
+
+	for sc.WriteQueueLen() > 0 {
+		select {
+		case <-sc.writeQueueFlushed:
+		case <-t.C:
+			return
+		}
+	}
+}
+
 // State returns the state.
 func (sc *ServerConn) State() ServerConnState {
 	return sc.state
@@ -333,6 +660,220 @@ func (sc *ServerConn) AnnouncedTracks() []ServerConnAnnouncedTrack {
 	return sc.announcedTracks
 }
 
+// Stats returns the reception statistics of the announced tracks, indexed by track ID.
+// It can be called only when the connection is publishing.
+func (sc *ServerConn) Stats() map[int]rtcpreceiver.Stats {
+	ret := make(map[int]rtcpreceiver.Stats, len(sc.announcedTracks))
+	for trackID, track := range sc.announcedTracks {
+		ret[trackID] = track.rtcpReceiver.Stats()
+	}
+	return ret
+}
+
+// PacketNTPTime returns the absolute time of a RTP packet of an announced track,
+// computed by using the RTCP sender reports sent by the client.
+// It returns false if no sender reports have been received yet.
+func (sc *ServerConn) PacketNTPTime(trackID int, rtpTimestamp uint32) (time.Time, bool) {
+	if trackID < 0 || trackID >= len(sc.announcedTracks) {
+		return time.Time{}, false
+	}
+	return sc.announcedTracks[trackID].rtcpReceiver.PacketNTPTime(rtpTimestamp)
+}
+
+// writeRequest writes a request to the client and returns its CSeq.
+// If resChan is not nil, the response is written to it.
+func (sc *ServerConn) writeRequest(req *base.Request, resChan chan *base.Response) (int, error) {
+	sc.writeMutex.Lock()
+	defer sc.writeMutex.Unlock()
+
+	if req.URL == nil {
+		req.URL = sc.streamURL
+		if req.URL == nil {
+			return 0, liberrors.ErrServerNoPath{}
+		}
+	}
+
+	if req.Header == nil {
+		req.Header = make(base.Header)
+	}
+
+	sc.cseq++
+	req.Header["CSeq"] = base.HeaderValue{strconv.FormatInt(int64(sc.cseq), 10)}
+
+	if sc.readHandlers.OnRequest != nil {
+		sc.readHandlers.OnRequest(req)
+	}
+
+	if resChan != nil {
+		sc.pendingRequestsMutex.Lock()
+		sc.pendingRequests[sc.cseq] = resChan
+		sc.pendingRequestsMutex.Unlock()
+	}
+
+	if sc.framesEnabled {
+		sc.pushWrite(req)
+		return sc.cseq, nil
+	}
+
+	sc.nconn.SetWriteDeadline(time.Now().Add(sc.conf.WriteTimeout))
+	return sc.cseq, req.Write(sc.bw)
+}
+
+// handleResponse passes a response to the routine that is waiting for it.
+func (sc *ServerConn) handleResponse(res *base.Response) {
+	if sc.readHandlers.OnResponse != nil {
+		sc.readHandlers.OnResponse(res)
+	}
+
+	cseq, ok := res.Header["CSeq"]
+	if !ok || len(cseq) != 1 {
+		return
+	}
+
+	v, err := strconv.ParseInt(cseq[0], 10, 64)
+	if err != nil {
+		return
+	}
+
+	sc.pendingRequestsMutex.Lock()
+	defer sc.pendingRequestsMutex.Unlock()
+
+	resChan, ok := sc.pendingRequests[int(v)]
+	if !ok {
+		return
+	}
+	delete(sc.pendingRequests, int(v))
+
+	resCopy := *res
+	resChan <- &resCopy
+}
+
+// rtpInfo returns a RTP-Info header that describes the RTP packets that are
+// going to be written to the tracks, or nil if no packets have been written yet.
+func (sc *ServerConn) rtpInfo() *headers.RTPInfo {
+	var trackIDs []int
+	for trackID, track := range sc.setuppedTracks {
+		if track.mode == headers.TransportModePlay {
+			trackIDs = append(trackIDs, trackID)
+		}
+	}
+	sort.Ints(trackIDs)
+
+	var ri headers.RTPInfo
+
+	for _, trackID := range trackIDs {
+		track := sc.setuppedTracks[trackID]
+
+		sequenceNumber, timestamp, ok := track.rtpState.get()
+		if !ok {
+			continue
+		}
+
+		ri = append(ri, &headers.RTPInfoEntry{
+			URL:            track.url,
+			SequenceNumber: sequenceNumber + 1,
+			Timestamp:      timestamp,
+		})
+	}
+
+	if ri == nil {
+		return nil
+	}
+	return &ri
+}
+
+// SetTrackStart sets the sequence number and the timestamp of the first RTP
+// packet of a track that is sent after a PLAY request, that are used to
+// fill the RTP-Info header of the response.
+// It is meant for servers that read streams from files and can be called
+// inside OnPlay, after ServerStream.AddReader().
+func (sc *ServerConn) SetTrackStart(trackID int, sequenceNumber uint16, timestamp uint32) {
+	track, ok := sc.setuppedTracks[trackID]
+	if !ok {
+		return
+	}
+
+	// the RTP-Info header contains the packet that follows the last written one
+	track.rtpState.set(sequenceNumber-1, timestamp)
+}
+
+// Request sends a request to the client and waits for a response, that is
+// matched by CSeq. It can be used to send OPTIONS keepalives or ANNOUNCE
+// notifications (RFC 2326, section 10).
+// If the request URL is nil, it is filled with the URL of the stream.
+// It can be called only while Read() is running.
+func (sc *ServerConn) Request(req *base.Request) (*base.Response, error) {
+	resChan := make(chan *base.Response, 1)
+
+	cseq, err := sc.writeRequest(req, resChan)
+	if err != nil {
+		sc.pendingRequestsMutex.Lock()
+		delete(sc.pendingRequests, cseq)
+		sc.pendingRequestsMutex.Unlock()
+		return nil, err
+	}
+
+	t := time.NewTimer(sc.conf.ReadTimeout)
+	defer t.Stop()
+
+	select {
+	case res := <-resChan:
+		return res, nil
+
+	case <-t.C:
+	case <-sc.backgroundReadDone:
+	}
+
+	sc.pendingRequestsMutex.Lock()
+	delete(sc.pendingRequests, cseq)
+	sc.pendingRequestsMutex.Unlock()
+
+	return nil, liberrors.ErrServerNoResponse{}
+}
+
+// WriteInterleavedFrame writes an interleaved frame with the given channel.
+// It can be used only when OnRequestPassthrough is set, in order to
+// forward frames received from another connection.
+func (sc *ServerConn) WriteInterleavedFrame(channel int, payload []byte) error {
+	if sc.readHandlers.OnRequestPassthrough == nil {
+		return liberrors.ErrServerPassthroughDisabled{}
+	}
+
+	sc.writeMutex.Lock()
+	defer sc.writeMutex.Unlock()
+
+	f := base.InterleavedFrame{
+		Payload: payload,
+	}
+	setInterleavedFrameChannel(&f, channel)
+
+	sc.nconn.SetWriteDeadline(time.Now().Add(sc.conf.WriteTimeout))
+	return f.Write(sc.bw)
+}
+
+// Redirect sends a REDIRECT request to the client, that asks the client
+// to connect to another server.
+// The response of the client is not awaited.
+func (sc *ServerConn) Redirect(location *base.URL) error {
+	_, err := sc.writeRequest(&base.Request{
+		Method: base.Redirect,
+		Header: base.Header{
+			"Location": base.HeaderValue{location.String()},
+		},
+	}, nil)
+	return err
+}
+
+// pushWrite pushes a frame, a request or a response into the write queue.
+// It returns true if an entry that was not written yet has been overwritten.
+func (sc *ServerConn) pushWrite(what interface{}) bool {
+	overwritten := sc.frameRingBuffer.Push(what)
+	if !overwritten {
+		atomic.AddInt64(&sc.writeQueueLen, 1)
+	}
+	return overwritten
+}
+
 func (sc *ServerConn) backgroundWrite() {
 	defer close(sc.backgroundWriteDone)
 
@@ -343,13 +884,27 @@ func (sc *ServerConn) backgroundWrite() {
 		}
 
 		switch w := what.(type) {
-		case *base.InterleavedFrame:
+		case *serverFrame:
 			sc.nconn.SetWriteDeadline(time.Now().Add(sc.conf.WriteTimeout))
 			w.Write(sc.bw)
+			w.release()
 
 		case *base.Response:
 			sc.nconn.SetWriteDeadline(time.Now().Add(sc.conf.WriteTimeout))
 			w.Write(sc.bw)
+
+		case *base.Request:
+			sc.nconn.SetWriteDeadline(time.Now().Add(sc.conf.WriteTimeout))
+			w.Write(sc.bw)
+		}
+
+		// the entry is removed from the queue after it has been written,
+		// in order to allow drain() to wait for the last write.
+		if atomic.AddInt64(&sc.writeQueueLen, -1) == 0 {
+			select {
+			case sc.writeQueueFlushed <- struct{}{}:
+			default:
+			}
 		}
 	}
 }
@@ -373,26 +928,132 @@ func (sc *ServerConn) NetConn() net.Conn {
 	return sc.nconn
 }
 
+// RemoteAddr returns the address of the client.
+func (sc *ServerConn) RemoteAddr() net.Addr {
+	return sc.nconn.RemoteAddr()
+}
+
+// LocalAddr returns the address of the server on which the client is connected.
+func (sc *ServerConn) LocalAddr() net.Addr {
+	return sc.nconn.LocalAddr()
+}
+
+func (sc *ServerConn) clientCertificates() []*x509.Certificate {
+	if sc.tlsConn == nil {
+		return nil
+	}
+
+	chains := sc.tlsConn.ConnectionState().VerifiedChains
+	if len(chains) == 0 {
+		return nil
+	}
+	return chains[0]
+}
+
+// TLSConnectionState returns the state of the TLS connection,
+// including the certificates provided by the client when mutual TLS is in use.
+// It returns nil if the connection is not encrypted with TLS.
+func (sc *ServerConn) TLSConnectionState() *tls.ConnectionState {
+	if sc.tlsConn == nil {
+		return nil
+	}
+
+	cs := sc.tlsConn.ConnectionState()
+	return &cs
+}
+
 func (sc *ServerConn) ip() net.IP {
 	return sc.nconn.RemoteAddr().(*net.TCPAddr).IP
 }
 
+// setupTransportSSRC echoes the SSRC provided by a publishing client.
+func (sc *ServerConn) setupTransportSSRC(resTh *headers.Transport, th *headers.Transport,
+	mode headers.TransportMode) {
+	if !sc.conf.SetupTransportSSRCDisable && mode == headers.TransportModeRecord {
+		resTh.SSRC = th.SSRC
+	}
+}
+
+// interleavedIDsAvailable checks whether interleaved IDs can be assigned to a track.
+func (sc *ServerConn) interleavedIDsAvailable(ids [2]int) bool {
+	for _, id := range ids {
+		if id < 0 || id > 255 {
+			return false
+		}
+
+		if _, ok := sc.interleavedChannels[id]; ok {
+			return false
+		}
+	}
+
+	return ids[0] != ids[1]
+}
+
+// chooseTransport picks the first transport, among the ones offered by the
+// client, that can be used with the current session.
+// If none can be used, the first one is returned, in order to produce
+// a meaningful error.
+func (sc *ServerConn) chooseTransport(ths headers.Transports) headers.Transport {
+	for _, th := range ths {
+		if th.Delivery != nil && *th.Delivery == base.StreamDeliveryMulticast {
+			continue
+		}
+
+		if sc.setupProtocol != nil && *sc.setupProtocol != th.Protocol {
+			continue
+		}
+
+		if th.Protocol == StreamProtocolUDP {
+			_, isTCP := sc.nconn.RemoteAddr().(*net.TCPAddr)
+			if (sc.udpRTPListener == nil && sc.udpPortRange == nil) || !isTCP ||
+				sc.tlsConn != nil || th.ClientPorts == nil {
+				continue
+			}
+		} else {
+			if th.InterleavedIDs == nil || !sc.interleavedIDsAvailable(*th.InterleavedIDs) {
+				continue
+			}
+		}
+
+		return th
+	}
+
+	return ths[0]
+}
+
 func (sc *ServerConn) zone() string {
 	return sc.nconn.RemoteAddr().(*net.TCPAddr).Zone
 }
 
+func (sc *ServerConn) setState(state ServerConnState) {
+	prev := sc.state
+	sc.state = state
+
+	if sc.readHandlers.OnStateChange != nil && prev != state {
+		sc.readHandlers.OnStateChange(prev, state)
+	}
+}
+
 func (sc *ServerConn) frameModeEnable() {
 	switch sc.state {
 	case ServerConnStatePlay:
 		if *sc.setupProtocol == StreamProtocolTCP {
 			sc.doEnableFrames = true
 		} else {
-			// readers can send RTCP frames, they cannot sent RTP frames
+			// readers can send RTCP frames, they can send RTP frames
+			// only to back channels
 			for trackID, track := range sc.setuppedTracks {
-				sc.udpRTCPListener.addClient(sc.ip(), track.rtcpPort, sc, trackID, false)
+				if track.mode == headers.TransportModeRecord {
+					track.udpRTPListener.addClient(sc.ip(), track.rtpPort, sc, trackID, false)
+				}
+				track.udpRTCPListener.addClient(sc.ip(), track.rtcpPort, sc, trackID, false)
 			}
 		}
 
+		sc.backgroundPlayTerminate = make(chan struct{})
+		sc.backgroundPlayDone = make(chan struct{})
+		go sc.backgroundPlay()
+
 	case ServerConnStateRecord:
 		if *sc.setupProtocol == StreamProtocolTCP {
 			sc.doEnableFrames = true
@@ -400,8 +1061,8 @@ func (sc *ServerConn) frameModeEnable() {
 
 		} else {
 			for trackID, track := range sc.setuppedTracks {
-				sc.udpRTPListener.addClient(sc.ip(), track.rtpPort, sc, trackID, true)
-				sc.udpRTCPListener.addClient(sc.ip(), track.rtcpPort, sc, trackID, true)
+				track.udpRTPListener.addClient(sc.ip(), track.rtpPort, sc, trackID, true)
+				track.udpRTCPListener.addClient(sc.ip(), track.rtcpPort, sc, trackID, true)
 
 				// open the firewall by sending packets to the counterpart
 				sc.WriteFrame(trackID, StreamTypeRTP,
@@ -420,14 +1081,22 @@ func (sc *ServerConn) frameModeEnable() {
 func (sc *ServerConn) frameModeDisable() {
 	switch sc.state {
 	case ServerConnStatePlay:
+		close(sc.backgroundPlayTerminate)
+		<-sc.backgroundPlayDone
+
 		if *sc.setupProtocol == StreamProtocolTCP {
+			sc.writeMutex.Lock()
 			sc.framesEnabled = false
 			sc.frameRingBuffer.Close()
 			<-sc.backgroundWriteDone
+			sc.writeMutex.Unlock()
 
 		} else {
 			for _, track := range sc.setuppedTracks {
-				sc.udpRTCPListener.removeClient(sc.ip(), track.rtcpPort)
+				if track.mode == headers.TransportModeRecord {
+					track.udpRTPListener.removeClient(sc.ip(), track.rtpPort)
+				}
+				track.udpRTCPListener.removeClient(sc.ip(), track.rtcpPort)
 			}
 		}
 
@@ -439,20 +1108,117 @@ func (sc *ServerConn) frameModeDisable() {
 			sc.readTimeoutEnabled = false
 			sc.nconn.SetReadDeadline(time.Time{})
 
+			sc.writeMutex.Lock()
 			sc.framesEnabled = false
 			sc.frameRingBuffer.Close()
 			<-sc.backgroundWriteDone
+			sc.writeMutex.Unlock()
 
 		} else {
 			for _, track := range sc.setuppedTracks {
-				sc.udpRTPListener.removeClient(sc.ip(), track.rtpPort)
-				sc.udpRTCPListener.removeClient(sc.ip(), track.rtcpPort)
+				track.udpRTPListener.removeClient(sc.ip(), track.rtpPort)
+				track.udpRTCPListener.removeClient(sc.ip(), track.rtcpPort)
 			}
 		}
 	}
 }
 
+// authenticate checks the credentials of a request.
+// It returns a response when the request must not be passed to the handlers.
+func (sc *ServerConn) authenticate(req *base.Request, path string, query string,
+	altURL *base.URL) (*base.Response, error) {
+	if sc.conf.AuthCredentials == nil {
+		return nil, nil
+	}
+
+	user, pass := sc.conf.AuthCredentials(req.Method, path, query)
+	if user == "" {
+		return nil, nil
+	}
+
+	// the validator, and therefore the nonce, is bound to the connection and
+	// is kept as long as credentials don't change. This prevents Authorization
+	// headers generated for other connections from being replayed, while
+	// the validator itself renews the nonce and checks the nonce count.
+	if sc.authValidator == nil || user != sc.authUser || pass != sc.authPass {
+		sc.authUser = user
+		sc.authPass = pass
+		sc.authValidator = auth.NewValidator(user, pass, sc.conf.AuthMethods)
+	}
+
+	if _, ok := req.Header["Authorization"]; !ok {
+		return &base.Response{
+			StatusCode: base.StatusUnauthorized,
+			Header: base.Header{
+				"WWW-Authenticate": sc.authValidator.GenerateHeader(),
+			},
+		}, nil
+	}
+
+	err := sc.authValidator.ValidateHeader(req.Header["Authorization"],
+		req.Method, req.URL, altURL)
+	if err != nil {
+		// an expired nonce is not a failure, the client has to
+		// send the request again with the new nonce.
+		if err != auth.ErrStaleNonce {
+			sc.authFailures++
+		}
+
+		// close the connection after too many failures
+		if sc.authFailures >= serverConnAuthMaxFailures {
+			return &base.Response{
+				StatusCode: base.StatusUnauthorized,
+			}, liberrors.ErrServerAuthFailed{Err: err}
+		}
+
+		return &base.Response{
+			StatusCode: base.StatusUnauthorized,
+			Header: base.Header{
+				"WWW-Authenticate": sc.authValidator.GenerateHeader(),
+			},
+		}, nil
+	}
+
+	sc.authFailures = 0
+	return nil, nil
+}
+
+// srtpContext allocates a SRTP context with the key of the given track,
+// that is taken from the announced or described tracks.
+func (sc *ServerConn) srtpContext(trackID int) *srtp.Context {
+	var track *Track
+	if sc.state == ServerConnStatePreRecord {
+		track = sc.announcedTracks[trackID].track
+	} else if trackID < len(sc.describedTracks) {
+		track = sc.describedTracks[trackID]
+	}
+
+	if track == nil {
+		return nil
+	}
+
+	key, err := track.ExtractSRTPKey()
+	if err != nil {
+		return nil
+	}
+
+	ctx, err := srtp.New(key)
+	if err != nil {
+		return nil
+	}
+
+	return ctx
+}
+
 func (sc *ServerConn) handleRequest(req *base.Request) (*base.Response, error) {
+	if sc.readHandlers.OnRequest != nil {
+		sc.readHandlers.OnRequest(req)
+	}
+
+	if sc.readHandlers.OnRequestPassthrough != nil {
+		return sc.readHandlers.OnRequestPassthrough(req)
+	}
+
 	if cseq, ok := req.Header["CSeq"]; !ok || len(cseq) != 1 {
 		return &base.Response{
 			StatusCode: base.StatusBadRequest,
@@ -460,8 +1226,35 @@ func (sc *ServerConn) handleRequest(req *base.Request) (*base.Response, error) {
 		}, liberrors.ErrServerCSeqMissing{}
 	}
 
-	if sc.readHandlers.OnRequest != nil {
-		sc.readHandlers.OnRequest(req)
+	if v, ok := req.Header["Session"]; ok {
+		var sx headers.Session
+		err := sx.Read(v)
+		if err != nil {
+			return &base.Response{
+				StatusCode: base.StatusBadRequest,
+			}, liberrors.ErrServerSessionHeaderInvalid{Err: err}
+		}
+	}
+
+	if req.Method != base.Options && sc.conf.Methods != nil {
+		allowed := false
+		for _, m := range sc.conf.Methods {
+			if m == req.Method {
+				allowed = true
+				break
+			}
+		}
+
+		if !allowed {
+			return sc.methodNotAllowed(req.Method)
+		}
+	}
+
+	if (req.Method == base.Describe || req.Method == base.Announce) &&
+		sc.conf.RateLimiter != nil && !sc.conf.RateLimiter(sc.nconn.RemoteAddr(), req) {
+		return &base.Response{
+			StatusCode: base.StatusServiceUnavailable,
+		}, nil
 	}
 
 	switch req.Method {
@@ -472,46 +1265,22 @@ func (sc *ServerConn) handleRequest(req *base.Request) (*base.Response, error) {
 				return &base.Response{
 					StatusCode: base.StatusBadRequest,
 				}, liberrors.ErrServerNoPath{}
-			}
-
-			path, query := base.PathSplitQuery(pathAndQuery)
-
-			return sc.readHandlers.OnOptions(&ServerConnOptionsCtx{
-				Req:   req,
-				Path:  path,
-				Query: query,
-			})
-		}
-
-		var methods []string
-		if sc.readHandlers.OnDescribe != nil {
-			methods = append(methods, string(base.Describe))
-		}
-		if sc.readHandlers.OnAnnounce != nil {
-			methods = append(methods, string(base.Announce))
-		}
-		if sc.readHandlers.OnSetup != nil {
-			methods = append(methods, string(base.Setup))
-		}
-		if sc.readHandlers.OnPlay != nil {
-			methods = append(methods, string(base.Play))
-		}
-		if sc.readHandlers.OnRecord != nil {
-			methods = append(methods, string(base.Record))
-		}
-		if sc.readHandlers.OnPause != nil {
-			methods = append(methods, string(base.Pause))
-		}
-		methods = append(methods, string(base.GetParameter))
-		if sc.readHandlers.OnSetParameter != nil {
-			methods = append(methods, string(base.SetParameter))
+			}
+
+			path, query := base.PathSplitQuery(pathAndQuery)
+
+			return sc.readHandlers.OnOptions(&ServerConnOptionsCtx{
+				Req:                req,
+				Path:               path,
+				Query:              query,
+				ClientCertificates: sc.clientCertificates(),
+			})
 		}
-		methods = append(methods, string(base.Teardown))
 
 		return &base.Response{
 			StatusCode: base.StatusOK,
 			Header: base.Header{
-				"Public": base.HeaderValue{strings.Join(methods, ", ")},
+				"Public": sc.methodsHeader(),
 			},
 		}, nil
 
@@ -535,10 +1304,15 @@ func (sc *ServerConn) handleRequest(req *base.Request) (*base.Response, error) {
 
 			path, query := base.PathSplitQuery(pathAndQuery)
 
+			if res, err := sc.authenticate(req, path, query, nil); res != nil {
+				return res, err
+			}
+
 			res, sdp, err := sc.readHandlers.OnDescribe(&ServerConnDescribeCtx{
-				Req:   req,
-				Path:  path,
-				Query: query,
+				Req:                req,
+				Path:               path,
+				Query:              query,
+				ClientCertificates: sc.clientCertificates(),
 			})
 
 			if res.StatusCode == base.StatusOK && sdp != nil {
@@ -549,6 +1323,9 @@ func (sc *ServerConn) handleRequest(req *base.Request) (*base.Response, error) {
 				res.Header["Content-Base"] = base.HeaderValue{req.URL.String() + "/"}
 				res.Header["Content-Type"] = base.HeaderValue{"application/sdp"}
 				res.Body = sdp
+
+				// store tracks in order to retrieve SRTP keys during SETUP
+				sc.describedTracks, _ = ReadTracks(sdp, nil)
 			}
 
 			return res, err
@@ -565,6 +1342,19 @@ func (sc *ServerConn) handleRequest(req *base.Request) (*base.Response, error) {
 				}, err
 			}
 
+			pathAndQuery, ok := req.URL.RTSPPath()
+			if !ok {
+				return &base.Response{
+					StatusCode: base.StatusBadRequest,
+				}, liberrors.ErrServerNoPath{}
+			}
+
+			path, query := base.PathSplitQuery(pathAndQuery)
+
+			if res, err := sc.authenticate(req, path, query, nil); res != nil {
+				return res, err
+			}
+
 			ct, ok := req.Header["Content-Type"]
 			if !ok || len(ct) != 1 {
 				return &base.Response{
@@ -591,50 +1381,45 @@ func (sc *ServerConn) handleRequest(req *base.Request) (*base.Response, error) {
 				}, liberrors.ErrServerSDPNoTracksDefined{}
 			}
 
-			pathAndQuery, ok := req.URL.RTSPPath()
-			if !ok {
-				return &base.Response{
-					StatusCode: base.StatusBadRequest,
-				}, liberrors.ErrServerNoPath{}
-			}
-
-			path, query := base.PathSplitQuery(pathAndQuery)
-
 			for _, track := range tracks {
 				trackURL, err := track.URL()
 				if err != nil {
 					return &base.Response{
 						StatusCode: base.StatusBadRequest,
-					}, fmt.Errorf("unable to generate track URL")
+					}, liberrors.ErrServerTrackURLInvalid{}
 				}
 
 				trackPath, ok := trackURL.RTSPPath()
 				if !ok {
 					return &base.Response{
 						StatusCode: base.StatusBadRequest,
-					}, fmt.Errorf("invalid track URL (%v)", trackURL)
+					}, liberrors.ErrServerTrackURLInvalid{URL: trackURL}
 				}
 
 				if !strings.HasPrefix(trackPath, path) {
 					return &base.Response{
 							StatusCode: base.StatusBadRequest,
-						}, fmt.Errorf("invalid track path: must begin with '%s', but is '%s'",
-							path, trackPath)
+						}, liberrors.ErrServerTrackPathPrefixInvalid{Prefix: path, Path: trackPath}
 				}
 			}
 
 			res, err := sc.readHandlers.OnAnnounce(&ServerConnAnnounceCtx{
-				Req:    req,
-				Path:   path,
-				Query:  query,
-				Tracks: tracks,
+				Req:                req,
+				Path:               path,
+				Query:              query,
+				Tracks:             tracks,
+				ClientCertificates: sc.clientCertificates(),
 			})
 
 			if res.StatusCode == base.StatusOK {
-				sc.state = ServerConnStatePreRecord
+				sc.setState(ServerConnStatePreRecord)
 				sc.setupPath = &path
 				sc.setupQuery = &query
 
+				sc.writeMutex.Lock()
+				sc.streamURL = req.URL
+				sc.writeMutex.Unlock()
+
 				sc.announcedTracks = make([]ServerConnAnnouncedTrack, len(tracks))
 				for trackID, track := range tracks {
 					clockRate, _ := track.ClockRate()
@@ -643,8 +1428,12 @@ func (sc *ServerConn) handleRequest(req *base.Request) (*base.Response, error) {
 					sc.announcedTracks[trackID] = ServerConnAnnouncedTrack{
 						track:            track,
 						rtcpReceiver:     rtcpreceiver.New(nil, clockRate),
+						ssrcFilter:       newSSRCFilter(nil),
 						udpLastFrameTime: &v,
 					}
+
+					sc.announcedTracks[trackID].rtpReorderer = newUDPReorderer(sc.conf.UDPReorderBufferSize,
+						sc.conf.UDPReorderBufferDuration)
 				}
 			}
 
@@ -664,14 +1453,16 @@ func (sc *ServerConn) handleRequest(req *base.Request) (*base.Response, error) {
 				}, err
 			}
 
-			var th headers.Transport
-			err = th.Read(req.Header["Transport"])
+			var ths headers.Transports
+			err = ths.Read(req.Header["Transport"])
 			if err != nil {
 				return &base.Response{
 					StatusCode: base.StatusBadRequest,
 				}, liberrors.ErrServerTransportHeaderInvalid{Err: err}
 			}
 
+			th := sc.chooseTransport(ths)
+
 			if th.Delivery != nil && *th.Delivery == base.StreamDeliveryMulticast {
 				return &base.Response{
 					StatusCode: base.StatusUnsupportedTransport,
@@ -686,30 +1477,41 @@ func (sc *ServerConn) handleRequest(req *base.Request) (*base.Response, error) {
 				}, err
 			}
 
+			// some clients (i.e. VLC) compute the credentials of SETUP requests
+			// with the base URL, without the track part.
+			altURL, _ := base.ParseURL(req.URL.Scheme + "://" + req.URL.Host + "/" + path + query + "/")
+
+			if res, err := sc.authenticate(req, path, query, altURL); res != nil {
+				return res, err
+			}
+
 			if _, ok := sc.setuppedTracks[trackID]; ok {
 				return &base.Response{
 					StatusCode: base.StatusBadRequest,
 				}, liberrors.ErrServerTrackAlreadySetup{TrackID: trackID}
 			}
 
-			switch sc.state {
-			case ServerConnStateInitial, ServerConnStatePrePlay: // play
-				if th.Mode != nil && *th.Mode != headers.TransportModePlay {
-					return &base.Response{
-						StatusCode: base.StatusBadRequest,
-					}, liberrors.ErrServerTransportHeaderWrongMode{Mode: th.Mode}
-				}
+			mode := headers.TransportModePlay
+			if th.Mode != nil {
+				mode = *th.Mode
+			}
 
-			default: // record
-				if th.Mode == nil || *th.Mode != headers.TransportModeRecord {
-					return &base.Response{
-						StatusCode: base.StatusBadRequest,
-					}, liberrors.ErrServerTransportHeaderWrongMode{Mode: th.Mode}
-				}
+			// tracks of a reading session can be setupped in record mode (back channels),
+			// while all tracks of a publishing session must be setupped in record mode.
+			if (sc.state == ServerConnStatePreRecord && mode != headers.TransportModeRecord) ||
+				(sc.state != ServerConnStatePreRecord && mode != headers.TransportModePlay &&
+					mode != headers.TransportModeRecord) {
+				return &base.Response{
+					StatusCode: base.StatusBadRequest,
+				}, liberrors.ErrServerTransportHeaderWrongMode{Mode: th.Mode}
 			}
 
 			if th.Protocol == StreamProtocolUDP {
-				if sc.udpRTPListener == nil {
+				// the address of the client is needed to send UDP packets
+				_, isTCP := sc.nconn.RemoteAddr().(*net.TCPAddr)
+
+				if (sc.udpRTPListener == nil && sc.udpPortRange == nil) || !isTCP ||
+					sc.tlsConn != nil {
 					return &base.Response{
 						StatusCode: base.StatusUnsupportedTransport,
 					}, nil
@@ -728,12 +1530,11 @@ func (sc *ServerConn) handleRequest(req *base.Request) (*base.Response, error) {
 					}, liberrors.ErrServerTransportHeaderNoInterleavedIDs{}
 				}
 
-				if th.InterleavedIDs[0] != (trackID*2) ||
-					th.InterleavedIDs[1] != (1+trackID*2) {
+				// clients can choose any channel, as long as it is not in use
+				if !sc.interleavedIDsAvailable(*th.InterleavedIDs) {
 					return &base.Response{
-							StatusCode: base.StatusBadRequest,
-						}, liberrors.ErrServerTransportHeaderWrongInterleavedIDs{
-							Expected: [2]int{(trackID * 2), (1 + trackID*2)}, Value: *th.InterleavedIDs}
+						StatusCode: base.StatusBadRequest,
+					}, liberrors.ErrServerTransportHeaderInvalidInterleavedIDs{Value: *th.InterleavedIDs}
 				}
 			}
 
@@ -743,14 +1544,46 @@ func (sc *ServerConn) handleRequest(req *base.Request) (*base.Response, error) {
 				}, liberrors.ErrServerTracksDifferentProtocols{}
 			}
 
+			var srtpCtx *srtp.Context
+			if th.Profile == headers.TransportProfileSAVP {
+				srtpCtx = sc.srtpContext(trackID)
+				if srtpCtx == nil {
+					return &base.Response{
+						StatusCode: base.StatusUnsupportedTransport,
+					}, nil
+				}
+			}
+
+			udpRTPListener := sc.udpRTPListener
+			udpRTCPListener := sc.udpRTCPListener
+
+			if th.Protocol == StreamProtocolUDP && sc.udpPortRange != nil {
+				var err error
+				udpRTPListener, udpRTCPListener, err = sc.udpPortRange.listen()
+				if err != nil {
+					// the session can go on with the tracks that have been already setupped
+					return &base.Response{
+						StatusCode: base.StatusNotEnoughBandwidth,
+					}, nil
+				}
+			}
+
 			res, err := sc.readHandlers.OnSetup(&ServerConnSetupCtx{
-				Req:       req,
-				Path:      path,
-				Query:     query,
-				TrackID:   trackID,
-				Transport: &th,
+				Req:                req,
+				Path:               path,
+				Query:              query,
+				TrackID:            trackID,
+				Transport:          &th,
+				Mode:               mode,
+				ClientCertificates: sc.clientCertificates(),
 			})
 
+			if res.StatusCode != base.StatusOK && th.Protocol == StreamProtocolUDP &&
+				sc.udpPortRange != nil {
+				udpRTPListener.close()
+				udpRTCPListener.close()
+			}
+
 			if res.StatusCode == base.StatusOK {
 				sc.setupProtocol = &th.Protocol
 
@@ -759,41 +1592,99 @@ func (sc *ServerConn) handleRequest(req *base.Request) (*base.Response, error) {
 				}
 
 				if th.Protocol == StreamProtocolUDP {
+					// the SSRC provided by the client is used to filter packets
+					if mode == headers.TransportModeRecord && trackID < len(sc.announcedTracks) {
+						sc.announcedTracks[trackID].ssrcFilter = newSSRCFilter(th.SSRC)
+					}
+
 					sc.setuppedTracks[trackID] = ServerConnSetuppedTrack{
+						mode:     mode,
+						url:      req.URL.CloneWithoutCredentials(),
+						rtpState: &serverRTPState{},
 						rtpPort:  th.ClientPorts[0],
 						rtcpPort: th.ClientPorts[1],
+						udpRTPAddr: &net.UDPAddr{
+							IP:   sc.ip(),
+							Zone: sc.zone(),
+							Port: th.ClientPorts[0],
+						},
+						udpRTCPAddr: &net.UDPAddr{
+							IP:   sc.ip(),
+							Zone: sc.zone(),
+							Port: th.ClientPorts[1],
+						},
+						srtpContext:     srtpCtx,
+						udpRTPListener:  udpRTPListener,
+						udpRTCPListener: udpRTCPListener,
 					}
 
 					if res.Header == nil {
 						res.Header = make(base.Header)
 					}
-					res.Header["Transport"] = headers.Transport{
+					resTh := headers.Transport{
 						Protocol: StreamProtocolUDP,
+						Profile:  th.Profile,
 						Delivery: func() *base.StreamDelivery {
 							v := base.StreamDeliveryUnicast
 							return &v
 						}(),
 						ClientPorts: th.ClientPorts,
-						ServerPorts: &[2]int{sc.udpRTPListener.port(), sc.udpRTCPListener.port()},
-					}.Write()
+						ServerPorts: &[2]int{udpRTPListener.port(), udpRTCPListener.port()},
+					}
+
+					if sc.conf.SetupTransportSource {
+						if addr, ok := sc.nconn.LocalAddr().(*net.TCPAddr); ok {
+							v := addr.IP.String()
+							resTh.Source = &v
+						}
+					}
+
+					sc.setupTransportSSRC(&resTh, &th, mode)
+
+					res.Header["Transport"] = resTh.Write()
 
 				} else {
-					sc.setuppedTracks[trackID] = ServerConnSetuppedTrack{}
+					sc.setuppedTracks[trackID] = ServerConnSetuppedTrack{
+						mode:           mode,
+						srtpContext:    srtpCtx,
+						url:            req.URL.CloneWithoutCredentials(),
+						rtpState:       &serverRTPState{},
+						interleavedIDs: th.InterleavedIDs,
+					}
+
+					if sc.interleavedChannels == nil {
+						sc.interleavedChannels = make(map[int]serverConnInterleavedChannel)
+					}
+					sc.interleavedChannels[th.InterleavedIDs[0]] = serverConnInterleavedChannel{trackID, StreamTypeRTP}
+					sc.interleavedChannels[th.InterleavedIDs[1]] = serverConnInterleavedChannel{trackID, StreamTypeRTCP}
 
 					if res.Header == nil {
 						res.Header = make(base.Header)
 					}
-					res.Header["Transport"] = headers.Transport{
+					resTh := headers.Transport{
 						Protocol:       StreamProtocolTCP,
+						Profile:        th.Profile,
 						InterleavedIDs: th.InterleavedIDs,
-					}.Write()
+					}
+
+					if sc.conf.SetupTransportTCPServerPorts && sc.udpRTPListener != nil {
+						resTh.ServerPorts = &[2]int{sc.udpRTPListener.port(), sc.udpRTCPListener.port()}
+					}
+
+					sc.setupTransportSSRC(&resTh, &th, mode)
+
+					res.Header["Transport"] = resTh.Write()
 				}
 			}
 
 			if sc.state == ServerConnStateInitial {
-				sc.state = ServerConnStatePrePlay
+				sc.setState(ServerConnStatePrePlay)
 				sc.setupPath = &path
 				sc.setupQuery = &query
+
+				sc.writeMutex.Lock()
+				sc.streamURL, _ = base.ParseURL(req.URL.Scheme + "://" + req.URL.Host + "/" + path + query)
+				sc.writeMutex.Unlock()
 			}
 
 			// workaround to prevent a bug in rtspclientsink
@@ -842,14 +1733,47 @@ func (sc *ServerConn) handleRequest(req *base.Request) (*base.Response, error) {
 
 			path, query := base.PathSplitQuery(pathAndQuery)
 
+			var ra *headers.Range
+			if v, ok := req.Header["Range"]; ok {
+				ra = &headers.Range{}
+				err := ra.Read(v)
+				if err != nil {
+					return &base.Response{
+						StatusCode: base.StatusInvalidRange,
+					}, liberrors.ErrServerRangeHeaderInvalid{Err: err}
+				}
+			}
+
 			res, err := sc.readHandlers.OnPlay(&ServerConnPlayCtx{
-				Req:   req,
-				Path:  path,
-				Query: query,
+				Req:                req,
+				Path:               path,
+				Query:              query,
+				Range:              ra,
+				ClientCertificates: sc.clientCertificates(),
 			})
 
+			if res.StatusCode == base.StatusOK {
+				if _, ok := res.Header["RTP-Info"]; !ok {
+					if ri := sc.rtpInfo(); ri != nil {
+						if res.Header == nil {
+							res.Header = make(base.Header)
+						}
+						res.Header["RTP-Info"] = ri.Write()
+					}
+				}
+
+				// the requested range is echoed, unless the handler provides
+				// the actual one
+				if _, ok := res.Header["Range"]; !ok && ra != nil {
+					if res.Header == nil {
+						res.Header = make(base.Header)
+					}
+					res.Header["Range"] = ra.Write()
+				}
+			}
+
 			if res.StatusCode == base.StatusOK && sc.state != ServerConnStatePlay {
-				sc.state = ServerConnStatePlay
+				sc.setState(ServerConnStatePlay)
 				sc.frameModeEnable()
 			}
 
@@ -892,13 +1816,14 @@ func (sc *ServerConn) handleRequest(req *base.Request) (*base.Response, error) {
 			path, query := base.PathSplitQuery(pathAndQuery)
 
 			res, err := sc.readHandlers.OnRecord(&ServerConnRecordCtx{
-				Req:   req,
-				Path:  path,
-				Query: query,
+				Req:                req,
+				Path:               path,
+				Query:              query,
+				ClientCertificates: sc.clientCertificates(),
 			})
 
 			if res.StatusCode == base.StatusOK {
-				sc.state = ServerConnStateRecord
+				sc.setState(ServerConnStateRecord)
 				sc.frameModeEnable()
 			}
 
@@ -932,20 +1857,21 @@ func (sc *ServerConn) handleRequest(req *base.Request) (*base.Response, error) {
 			path, query := base.PathSplitQuery(pathAndQuery)
 
 			res, err := sc.readHandlers.OnPause(&ServerConnPauseCtx{
-				Req:   req,
-				Path:  path,
-				Query: query,
+				Req:                req,
+				Path:               path,
+				Query:              query,
+				ClientCertificates: sc.clientCertificates(),
 			})
 
 			if res.StatusCode == base.StatusOK {
 				switch sc.state {
 				case ServerConnStatePlay:
 					sc.frameModeDisable()
-					sc.state = ServerConnStatePrePlay
+					sc.setState(ServerConnStatePrePlay)
 
 				case ServerConnStateRecord:
 					sc.frameModeDisable()
-					sc.state = ServerConnStatePreRecord
+					sc.setState(ServerConnStatePreRecord)
 				}
 			}
 
@@ -964,9 +1890,10 @@ func (sc *ServerConn) handleRequest(req *base.Request) (*base.Response, error) {
 			path, query := base.PathSplitQuery(pathAndQuery)
 
 			return sc.readHandlers.OnGetParameter(&ServerConnGetParameterCtx{
-				Req:   req,
-				Path:  path,
-				Query: query,
+				Req:                req,
+				Path:               path,
+				Query:              query,
+				ClientCertificates: sc.clientCertificates(),
 			})
 		}
 
@@ -991,9 +1918,10 @@ func (sc *ServerConn) handleRequest(req *base.Request) (*base.Response, error) {
 			path, query := base.PathSplitQuery(pathAndQuery)
 
 			return sc.readHandlers.OnSetParameter(&ServerConnSetParameterCtx{
-				Req:   req,
-				Path:  path,
-				Query: query,
+				Req:                req,
+				Path:               path,
+				Query:              query,
+				ClientCertificates: sc.clientCertificates(),
 			})
 		}
 
@@ -1009,9 +1937,10 @@ func (sc *ServerConn) handleRequest(req *base.Request) (*base.Response, error) {
 			path, query := base.PathSplitQuery(pathAndQuery)
 
 			return sc.readHandlers.OnTeardown(&ServerConnTeardownCtx{
-				Req:   req,
-				Path:  path,
-				Query: query,
+				Req:                req,
+				Path:               path,
+				Query:              query,
+				ClientCertificates: sc.clientCertificates(),
 			})
 		}
 
@@ -1020,33 +1949,143 @@ func (sc *ServerConn) handleRequest(req *base.Request) (*base.Response, error) {
 		}, liberrors.ErrServerTeardown{}
 	}
 
+	return sc.methodNotAllowed(req.Method)
+}
+
+// methodNotAllowed returns the response to a request whose method is not supported.
+func (sc *ServerConn) methodNotAllowed(method base.Method) (*base.Response, error) {
 	return &base.Response{
-		StatusCode: base.StatusBadRequest,
-	}, fmt.Errorf("unhandled method: %v", req.Method)
+		StatusCode: base.StatusMethodNotAllowed,
+		Header: base.Header{
+			"Allow": sc.methodsHeader(),
+		},
+	}, liberrors.ErrServerUnhandledMethod{Method: method}
+}
+
+// methods returns the methods supported by the connection.
+func (sc *ServerConn) methods() []base.Method {
+	if sc.conf.Methods != nil {
+		return sc.conf.Methods
+	}
+
+	var methods []base.Method
+	if sc.readHandlers.OnDescribe != nil {
+		methods = append(methods, base.Describe)
+	}
+	if sc.readHandlers.OnAnnounce != nil {
+		methods = append(methods, base.Announce)
+	}
+	if sc.readHandlers.OnSetup != nil {
+		methods = append(methods, base.Setup)
+	}
+	if sc.readHandlers.OnPlay != nil {
+		methods = append(methods, base.Play)
+	}
+	if sc.readHandlers.OnRecord != nil {
+		methods = append(methods, base.Record)
+	}
+	if sc.readHandlers.OnPause != nil {
+		methods = append(methods, base.Pause)
+	}
+	methods = append(methods, base.GetParameter)
+	if sc.readHandlers.OnSetParameter != nil {
+		methods = append(methods, base.SetParameter)
+	}
+	methods = append(methods, base.Teardown)
+
+	return methods
+}
+
+// methodsHeader returns the methods supported by the connection,
+// in the format of the Public and Allow headers.
+func (sc *ServerConn) methodsHeader() base.HeaderValue {
+	methods := sc.methods()
+	strs := make([]string, len(methods))
+	for i, m := range methods {
+		strs[i] = string(m)
+	}
+	return base.HeaderValue{strings.Join(strs, ", ")}
+}
+
+func (sc *ServerConn) onTimeout(err error) {
+	if sc.readHandlers.OnTimeout != nil {
+		sc.readHandlers.OnTimeout(err)
+	}
+}
+
+// onReadError calls OnRequestError when an error returned while reading
+// is caused by data that can't be decoded, and not by the connection.
+func (sc *ServerConn) onReadError(req *base.Request, err error) {
+	if sc.readHandlers.OnRequestError == nil || err == io.EOF {
+		return
+	}
+
+	if _, ok := err.(net.Error); ok {
+		return
+	}
+
+	if req.Method == "" {
+		req = nil
+	}
+
+	sc.readHandlers.OnRequestError(req, err)
 }
 
 func (sc *ServerConn) backgroundRead() error {
 	var tcpFrameBuffer *multibuffer.MultiBuffer
 
 	handleRequestOuter := func(req *base.Request) error {
+		atomic.StoreInt64(sc.lastActivity, time.Now().Unix())
+
+		sc.writeMutex.Lock()
+		sc.inFlightDone = make(chan struct{})
+		sc.inFlightCSeq = req.Header["CSeq"]
+		sc.writeMutex.Unlock()
+
 		res, err := sc.handleRequest(req)
 
 		if res.Header == nil {
 			res.Header = base.Header{}
 		}
 
+		passthrough := sc.readHandlers.OnRequestPassthrough != nil
+
+		// add timeout to session
+		if v, ok := res.Header["Session"]; ok && !passthrough {
+			var sx headers.Session
+			if sx.Read(v) == nil && sx.Timeout == nil {
+				timeout := uint(sc.conf.SessionTimeout / time.Second)
+				sx.Timeout = &timeout
+				res.Header["Session"] = sx.Write()
+			}
+		}
+
 		// add cseq
 		if _, ok := err.(liberrors.ErrServerCSeqMissing); !ok {
 			res.Header["CSeq"] = req.Header["CSeq"]
 		}
 
 		// add server
-		res.Header["Server"] = base.HeaderValue{"gortsplib"}
+		if !passthrough {
+			res.Header["Server"] = base.HeaderValue{"gortsplib"}
+		}
 
 		if sc.readHandlers.OnResponse != nil {
 			sc.readHandlers.OnResponse(res)
 		}
 
+		sc.writeMutex.Lock()
+		defer sc.writeMutex.Unlock()
+
+		close(sc.inFlightDone)
+		sc.inFlightDone = nil
+
+		// a 503 response has already been written by Close()
+		if sc.inFlightResponded {
+			sc.inFlightResponded = false
+			return err
+		}
+
 		// start background write
 		switch {
 		case sc.doEnableFrames:
@@ -1069,12 +2108,13 @@ func (sc *ServerConn) backgroundRead() error {
 
 			// start background write
 			sc.frameRingBuffer.Reset()
+			atomic.StoreInt64(&sc.writeQueueLen, 0)
 			sc.backgroundWriteDone = make(chan struct{})
 			go sc.backgroundWrite()
 
 			// write to background write
 		case sc.framesEnabled:
-			sc.frameRingBuffer.Push(res)
+			sc.pushWrite(res)
 
 			// write directly
 		default:
@@ -1082,37 +2122,84 @@ func (sc *ServerConn) backgroundRead() error {
 			res.Write(sc.bw)
 		}
 
+		if err != nil && sc.readHandlers.OnRequestError != nil {
+			if _, ok := err.(liberrors.ErrServerTeardown); !ok {
+				sc.readHandlers.OnRequestError(req, err)
+			}
+		}
+
 		return err
 	}
 
 	var req base.Request
+	res := base.Response{Limits: &sc.conf.ReadLimits}
 	var frame base.InterleavedFrame
 	var errRet error
 
+	// in passthrough mode, frames can be received at any time
+	passthrough := sc.readHandlers.OnRequestPassthrough != nil
+	if passthrough {
+		tcpFrameBuffer = multibuffer.New(uint64(sc.conf.ReadBufferCount), uint64(sc.conf.ReadBufferSize))
+	}
+
 outer:
 	for {
 		if sc.readTimeoutEnabled {
 			sc.nconn.SetReadDeadline(time.Now().Add(sc.conf.ReadTimeout))
 		}
 
-		if sc.framesEnabled {
+		// requests are decoded from scratch, in order to report partial requests
+		req = base.Request{Limits: &sc.conf.ReadLimits}
+
+		if sc.framesEnabled || passthrough {
 			frame.Payload = tcpFrameBuffer.Next()
-			what, err := base.ReadInterleavedFrameOrRequest(&frame, &req, sc.br)
+			what, err := base.ReadInterleavedFrameOrRequestOrResponse(&frame, &req, &res, sc.br)
 			if err != nil {
-				errRet = err
+				if atomic.LoadInt32(&sc.writeQueueFull) == 1 {
+					errRet = liberrors.ErrServerWriteQueueFull{}
+				} else {
+					errRet = err
+					if nerr, ok := err.(net.Error); ok && nerr.Timeout() {
+						sc.onTimeout(errRet)
+					}
+					sc.onReadError(&req, errRet)
+				}
 				break outer
 			}
 
 			switch what.(type) {
 			case *base.InterleavedFrame:
-				// forward frame only if it has been set up
-				if _, ok := sc.setuppedTracks[frame.TrackID]; ok {
-					if sc.state == ServerConnStateRecord {
-						sc.announcedTracks[frame.TrackID].rtcpReceiver.ProcessFrame(time.Now(),
-							frame.StreamType, frame.Payload)
+				if passthrough {
+					if sc.readHandlers.OnInterleavedFrame != nil {
+						sc.readHandlers.OnInterleavedFrame(interleavedFrameChannel(&frame), frame.Payload)
 					}
-					sc.readHandlers.OnFrame(frame.TrackID, frame.StreamType, frame.Payload)
+					continue
+				}
+
+				// forward frame only if its channel has been set up
+				ch, ok := sc.interleavedChannels[interleavedFrameChannel(&frame)]
+				if !ok {
+					sc.conf.Logger.Debugf("frame of channel %d discarded, channel is not set up",
+						interleavedFrameChannel(&frame))
+					continue
+				}
+
+				track := sc.setuppedTracks[ch.trackID]
+
+				payload, err := srtpDecryptFrame(track.srtpContext, ch.streamType, frame.Payload)
+				if err != nil {
+					sc.conf.Logger.Warnf("frame discarded: %s", liberrors.ErrServerSRTPDecryptFailed{
+						TrackID: ch.trackID,
+						Err:     err,
+					})
+					continue
+				}
+
+				if sc.state == ServerConnStateRecord {
+					sc.announcedTracks[ch.trackID].rtcpReceiver.ProcessFrame(time.Now(),
+						ch.streamType, payload)
 				}
+				sc.readHandlers.OnFrame(ch.trackID, ch.streamType, payload)
 
 			case *base.Request:
 				err := handleRequestOuter(&req)
@@ -1120,19 +2207,35 @@ outer:
 					errRet = err
 					break outer
 				}
+
+			case *base.Response:
+				sc.handleResponse(&res)
 			}
 
 		} else {
-			err := req.Read(sc.br)
+			what, err := base.ReadRequestOrResponse(&req, &res, sc.br)
 			if err != nil {
 				if atomic.LoadInt32(&sc.udpTimeout) == 1 {
 					errRet = liberrors.ErrServerNoUDPPacketsRecently{}
+					sc.onTimeout(errRet)
+				} else if atomic.LoadInt32(&sc.sessionTimedOut) == 1 {
+					errRet = liberrors.ErrServerSessionTimedOut{}
+					sc.onTimeout(errRet)
 				} else {
 					errRet = err
+					if nerr, ok := err.(net.Error); ok && nerr.Timeout() {
+						sc.onTimeout(errRet)
+					}
+					sc.onReadError(&req, errRet)
 				}
 				break outer
 			}
 
+			if _, ok := what.(*base.Response); ok {
+				sc.handleResponse(&res)
+				continue
+			}
+
 			err = handleRequestOuter(&req)
 			if err != nil {
 				errRet = err
@@ -1143,53 +2246,243 @@ outer:
 
 	sc.frameModeDisable()
 
+	for _, track := range sc.setuppedTracks {
+		if track.udpRTPListener != nil && track.udpRTPListener.dedicated {
+			track.udpRTPListener.close()
+			track.udpRTCPListener.close()
+		}
+	}
+
+	close(sc.backgroundReadDone)
+
 	return errRet
 }
 
-// Read starts reading requests and frames.
+// Read starts reading requests, responses and frames.
 // it returns a channel that is written when the reading stops.
+// Protocol errors are returned as one of the liberrors.ErrServer* types,
+// while all other errors come from the underlying connection.
 func (sc *ServerConn) Read(readHandlers ServerConnReadHandlers) chan error {
+	return sc.ReadContext(context.Background(), readHandlers)
+}
+
+// ReadContext is like Read, but reading stops and the connection is closed
+// when the context is canceled.
+func (sc *ServerConn) ReadContext(ctx context.Context, readHandlers ServerConnReadHandlers) chan error {
 	// channel is buffered, since listening to it is not mandatory
 	done := make(chan error, 1)
 
+	readHandlers.OnFrame = wrapOnFrame(sc.conf.Logger, readHandlers.OnFrame,
+		readHandlers.OnPacketRTP, readHandlers.OnPacketRTCP)
+
+	if readHandlers.OnFrame == nil {
+		readHandlers.OnFrame = func(trackID int, streamType StreamType, payload []byte) {}
+	}
+
 	sc.readHandlers = readHandlers
 
 	go func() {
-		done <- sc.backgroundRead()
+		readDone := make(chan error)
+		go func() {
+			readDone <- sc.backgroundRead()
+		}()
+
+		select {
+		case err := <-readDone:
+			done <- err
+
+		case <-ctx.Done():
+			sc.nconn.Close()
+			<-readDone
+			done <- ctx.Err()
+		}
 	}()
 
 	return done
 }
 
 // WriteFrame writes a frame.
+// Frames of tracks that have not been set up are discarded.
+// The payload is copied, therefore it can be reused by the caller.
 func (sc *ServerConn) WriteFrame(trackID int, streamType StreamType, payload []byte) {
-	if *sc.setupProtocol == StreamProtocolUDP {
-		track := sc.setuppedTracks[trackID]
+	sc.writeFrame(trackID, streamType, payload, true)
+}
+
+// WriteFrameNoCopy writes a frame without copying the payload.
+// Frames of tracks that have not been set up are discarded.
+// Since frames are written asynchronously, the payload must not be modified
+// by the caller after the call.
+func (sc *ServerConn) WriteFrameNoCopy(trackID int, streamType StreamType, payload []byte) {
+	sc.writeFrame(trackID, streamType, payload, false)
+}
+
+// TryWriteFrame writes a frame like WriteFrame, but it doesn't discard
+// frames when the write queue is full; it returns liberrors.ErrServerWouldBlock
+// instead, allowing the caller to implement its own policy when a reader
+// is slower than the stream.
+// With UDP, frames are sent immediately and the write queue is never full.
+func (sc *ServerConn) TryWriteFrame(trackID int, streamType StreamType, payload []byte) error {
+	if sc.setupProtocol != nil && *sc.setupProtocol == StreamProtocolTCP &&
+		sc.WriteQueueLen() >= sc.conf.WriteBufferCount {
+		return liberrors.ErrServerWouldBlock{}
+	}
+
+	sc.writeFrame(trackID, streamType, payload, true)
+	return nil
+}
+
+func (sc *ServerConn) writeFrame(trackID int, streamType StreamType, payload []byte, copyPayload bool) {
+	track, ok := sc.setuppedTracks[trackID]
+	if !ok {
+		return
+	}
+
+	if streamType == StreamTypeRTP {
+		track.rtpState.update(payload)
+	}
+
+	if track.rtcpSender != nil {
+		track.rtcpSender.ProcessFrame(time.Now(), streamType, payload)
+	}
+
+	if track.srtpContext != nil {
+		var err error
+		payload, err = srtpEncryptFrame(track.srtpContext, streamType, payload)
+		if err != nil {
+			sc.conf.Logger.Warnf("outgoing frame of track %d discarded: %s", trackID, err)
+			return
+		}
+
+		// encrypted payloads are new buffers
+		copyPayload = false
+	}
+
+	f := newServerFrame(trackID, streamType, payload, copyPayload)
 
+	if *sc.setupProtocol == StreamProtocolUDP {
 		if streamType == StreamTypeRTP {
-			sc.udpRTPListener.write(payload, &net.UDPAddr{
-				IP:   sc.ip(),
-				Zone: sc.zone(),
-				Port: track.rtpPort,
-			})
+			track.udpRTPListener.write(f, track.udpRTPAddr)
 			return
 		}
 
-		sc.udpRTCPListener.write(payload, &net.UDPAddr{
-			IP:   sc.ip(),
-			Zone: sc.zone(),
-			Port: track.rtcpPort,
-		})
+		track.udpRTCPListener.write(f, track.udpRTCPAddr)
 		return
 	}
 
 	// StreamProtocolTCP
 
-	sc.frameRingBuffer.Push(&base.InterleavedFrame{
-		TrackID:    trackID,
-		StreamType: streamType,
-		Payload:    payload,
-	})
+	if streamType == StreamTypeRTP {
+		setInterleavedFrameChannel(&f.InterleavedFrame, track.interleavedIDs[0])
+	} else {
+		setInterleavedFrameChannel(&f.InterleavedFrame, track.interleavedIDs[1])
+	}
+
+	overwritten := sc.pushWrite(f)
+	if overwritten {
+		atomic.AddUint64(&sc.droppedFrames, 1)
+		sc.conf.Logger.Warnf("outgoing frame of track %d discarded, write queue is full", trackID)
+
+		if sc.conf.WriteQueuePolicy == ServerWriteQueuePolicyDisconnect &&
+			atomic.SwapInt32(&sc.writeQueueFull, 1) == 0 {
+			sc.nconn.Close()
+		}
+	}
+}
+
+func (sc *ServerConn) onUDPPacketDiscarded(err error) {
+	sc.conf.Logger.Warnf("UDP packet discarded: %s", err)
+
+	if sc.readHandlers.OnUDPPacketDiscarded != nil {
+		sc.readHandlers.OnUDPPacketDiscarded(err)
+	}
+}
+
+// initRTCPSenders allocates the RTCP sender of the tracks that are read,
+// if sender reports are enabled.
+func (sc *ServerConn) initRTCPSenders(tracks Tracks) {
+	if sc.conf.SenderReportPeriod <= 0 {
+		return
+	}
+
+	for trackID, track := range sc.setuppedTracks {
+		if track.mode != headers.TransportModePlay || track.rtcpSender != nil ||
+			trackID >= len(tracks) {
+			continue
+		}
+
+		clockRate, err := tracks[trackID].ClockRate()
+		if err != nil {
+			continue
+		}
+
+		track.rtcpSender = rtcpsender.New(clockRate)
+		sc.setuppedTracks[trackID] = track
+	}
+}
+
+// DroppedFrames returns the number of outgoing frames that have been discarded
+// because the write queue was full.
+func (sc *ServerConn) DroppedFrames() uint64 {
+	return atomic.LoadUint64(&sc.droppedFrames)
+}
+
+// WriteQueueLen returns the number of frames, requests and responses
+// that are waiting to be written to the connection, including the one
+// that is being written.
+// The write queue can contain up to ServerConf.WriteBufferCount entries,
+// plus the one that is being written.
+func (sc *ServerConn) WriteQueueLen() int {
+	return int(atomic.LoadInt64(&sc.writeQueueLen))
+}
+
+func (sc *ServerConn) backgroundPlay() {
+	defer close(sc.backgroundPlayDone)
+
+	checkSessionTicker := time.NewTicker(serverConnCheckStreamInterval)
+	defer checkSessionTicker.Stop()
+
+	var senderReportTickerC <-chan time.Time
+	if sc.conf.SenderReportPeriod > 0 {
+		senderReportTicker := time.NewTicker(sc.conf.SenderReportPeriod)
+		defer senderReportTicker.Stop()
+		senderReportTickerC = senderReportTicker.C
+	}
+
+	for {
+		select {
+		case <-checkSessionTicker.C:
+			// with TCP, the session is kept alive by the connection
+			if *sc.setupProtocol != StreamProtocolUDP {
+				continue
+			}
+
+			last := time.Unix(atomic.LoadInt64(sc.lastActivity), 0)
+
+			if time.Since(last) >= sc.conf.SessionTimeout {
+				atomic.StoreInt32(&sc.sessionTimedOut, 1)
+				sc.nconn.Close()
+				return
+			}
+
+		case <-senderReportTickerC:
+			now := time.Now()
+			for trackID, track := range sc.setuppedTracks {
+				if track.rtcpSender == nil {
+					continue
+				}
+
+				r := track.rtcpSender.Report(now)
+				if r == nil {
+					continue
+				}
+
+				sc.WriteFrame(trackID, StreamTypeRTCP, r)
+			}
+
+		case <-sc.backgroundPlayTerminate:
+			return
+		}
+	}
 }
 
 func (sc *ServerConn) backgroundRecord() {
@@ -1198,11 +2491,32 @@ func (sc *ServerConn) backgroundRecord() {
 	checkStreamTicker := time.NewTicker(serverConnCheckStreamInterval)
 	defer checkStreamTicker.Stop()
 
-	receiverReportTicker := time.NewTicker(serverConnReceiverReportInterval)
-	defer receiverReportTicker.Stop()
+	var receiverReportTickerC <-chan time.Time
+	if !sc.conf.ReceiverReportsDisable {
+		receiverReportTicker := time.NewTicker(serverConnReceiverReportInterval)
+		defer receiverReportTicker.Stop()
+		receiverReportTickerC = receiverReportTicker.C
+	}
+
+	// release reordered packets that have been waiting for too long
+	var reorderFlushTickerC <-chan time.Time
+	if *sc.setupProtocol == StreamProtocolUDP && sc.conf.UDPReorderBufferDuration > 0 {
+		reorderFlushTicker := time.NewTicker(udpReorderFlushPeriod(sc.conf.UDPReorderBufferDuration))
+		defer reorderFlushTicker.Stop()
+		reorderFlushTickerC = reorderFlushTicker.C
+	}
 
 	for {
 		select {
+		case <-reorderFlushTickerC:
+			for trackID, track := range sc.announcedTracks {
+				trackID := trackID
+				track.rtpReorderer.flush(func(payload []byte) {
+					track.rtcpReceiver.ProcessFrame(time.Now(), StreamTypeRTP, payload)
+					sc.readHandlers.OnFrame(trackID, StreamTypeRTP, payload)
+				})
+			}
+
 		case <-checkStreamTicker.C:
 			if *sc.setupProtocol != StreamProtocolUDP {
 				continue
@@ -1219,11 +2533,15 @@ func (sc *ServerConn) backgroundRecord() {
 				}
 			}
 
-		case <-receiverReportTicker.C:
+		case <-receiverReportTickerC:
 			now := time.Now()
 			for trackID, track := range sc.announcedTracks {
 				r := track.rtcpReceiver.Report(now)
-				sc.WriteFrame(trackID, StreamTypeRTP, r)
+				if r == nil {
+					continue
+				}
+
+				sc.WriteFrame(trackID, StreamTypeRTCP, r)
 			}
 
 		case <-sc.backgroundRecordTerminate:
@@ -2,7 +2,11 @@ package gortsplib
 
 import (
 	"bufio"
+	"context"
+	"crypto/sha256"
 	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"net"
@@ -12,7 +16,9 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/majoyz/gortsplib/pkg/auth"
 	"github.com/majoyz/gortsplib/pkg/base"
+	"github.com/majoyz/gortsplib/pkg/headers"
 	"github.com/majoyz/gortsplib/pkg/liberrors"
 )
 
@@ -452,6 +458,139 @@ func TestServerCSeqNo(t *testing.T) {
 	require.Equal(t, base.StatusBadRequest, res.StatusCode)
 }
 
+func TestServerReadLimits(t *testing.T) {
+	s, err := ServerConf{
+		ReadLimits: base.ReadLimits{
+			MaxBodySize: 10,
+		},
+	}.Serve("127.0.0.1:8554")
+	require.NoError(t, err)
+	defer s.Close()
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		conn, err := s.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+
+		err = <-conn.Read(ServerConnReadHandlers{})
+		require.Equal(t, base.ErrLimitExceeded{
+			What:  "Content-Length",
+			Limit: 10,
+			Value: 20,
+		}, err)
+	}()
+
+	conn, err := net.Dial("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer conn.Close()
+	bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+	err = base.Request{
+		Method: base.SetParameter,
+		URL:    base.MustParseURL("rtsp://localhost:8554/"),
+		Header: base.Header{
+			"CSeq": base.HeaderValue{"1"},
+		},
+		Body: []byte("01234567890123456789"),
+	}.Write(bconn.Writer)
+	require.NoError(t, err)
+}
+
+func TestServerRequestResponseHooks(t *testing.T) {
+	s, err := Serve("127.0.0.1:8554")
+	require.NoError(t, err)
+	defer s.Close()
+
+	var requests []base.Method
+	var responses []base.StatusCode
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		conn, err := s.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+
+		<-conn.Read(ServerConnReadHandlers{
+			OnRequest: func(req *base.Request) {
+				requests = append(requests, req.Method)
+			},
+			OnResponse: func(res *base.Response) {
+				res.Header["X-Custom"] = base.HeaderValue{"value"}
+				responses = append(responses, res.StatusCode)
+			},
+		})
+	}()
+
+	conn, err := net.Dial("tcp", "localhost:8554")
+	require.NoError(t, err)
+	bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+	err = base.Request{
+		Method: base.Options,
+		URL:    base.MustParseURL("rtsp://localhost:8554/"),
+		Header: base.Header{
+			"CSeq": base.HeaderValue{"1"},
+		},
+	}.Write(bconn.Writer)
+	require.NoError(t, err)
+
+	var res base.Response
+	err = res.Read(bconn.Reader)
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+	require.Equal(t, base.HeaderValue{"value"}, res.Header["X-Custom"])
+
+	// requests without CSeq are passed to the hooks too
+	err = base.Request{
+		Method: base.Describe,
+		URL:    base.MustParseURL("rtsp://localhost:8554/"),
+		Header: base.Header{},
+	}.Write(bconn.Writer)
+	require.NoError(t, err)
+
+	err = res.Read(bconn.Reader)
+	require.NoError(t, err)
+	require.Equal(t, base.StatusBadRequest, res.StatusCode)
+
+	conn.Close()
+	<-serverDone
+
+	require.Equal(t, []base.Method{base.Options, base.Describe}, requests)
+	require.Equal(t, []base.StatusCode{base.StatusOK, base.StatusBadRequest}, responses)
+}
+
+func TestServerUDPBufferSizes(t *testing.T) {
+	s, err := ServerConf{
+		UDPRTPAddress:      "127.0.0.1:8000",
+		UDPRTCPAddress:     "127.0.0.1:8001",
+		UDPReadBufferSize:  0x10000,
+		UDPWriteBufferSize: 0x10000,
+	}.Serve("127.0.0.1:8554")
+	require.NoError(t, err)
+	defer s.Close()
+
+	for _, streamType := range []StreamType{StreamTypeRTP, StreamTypeRTCP} {
+		readSize, writeSize, err := s.UDPBufferSizes(streamType)
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, readSize, 0x10000)
+		require.GreaterOrEqual(t, writeSize, 0x10000)
+	}
+
+	s2, err := Serve("127.0.0.1:8555")
+	require.NoError(t, err)
+	defer s2.Close()
+
+	_, _, err = s2.UDPBufferSizes(StreamTypeRTP)
+	require.Error(t, err)
+}
+
 func TestServerTeardownResponse(t *testing.T) {
 	s, err := Serve("127.0.0.1:8554")
 	require.NoError(t, err)
@@ -494,3 +633,1118 @@ func TestServerTeardownResponse(t *testing.T) {
 	_, err = bconn.Read(buf)
 	require.Equal(t, io.EOF, err)
 }
+
+func TestServerReadContext(t *testing.T) {
+	s, err := Serve("127.0.0.1:8554")
+	require.NoError(t, err)
+	defer s.Close()
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		conn, err := s.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		onOptions := func(ctx *ServerConnOptionsCtx) (*base.Response, error) {
+			cancel()
+			return &base.Response{
+				StatusCode: base.StatusOK,
+			}, nil
+		}
+
+		err = <-conn.ReadContext(ctx, ServerConnReadHandlers{
+			OnOptions: onOptions,
+		})
+		require.Equal(t, context.Canceled, err)
+	}()
+
+	conn, err := net.Dial("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer conn.Close()
+	bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+	err = base.Request{
+		Method: base.Options,
+		URL:    base.MustParseURL("rtsp://localhost:8554/"),
+		Header: base.Header{
+			"CSeq": base.HeaderValue{"1"},
+		},
+	}.Write(bconn.Writer)
+	require.NoError(t, err)
+
+	buf := make([]byte, 2048)
+	for {
+		_, err = bconn.Read(buf)
+		if err != nil {
+			break
+		}
+	}
+	require.Equal(t, io.EOF, err)
+}
+
+func TestServerHandle(t *testing.T) {
+	s, err := Serve("127.0.0.1:8554")
+	require.NoError(t, err)
+
+	connOpen := make(chan struct{})
+	connClose := make(chan error, 1)
+
+	handleDone := make(chan error)
+	go func() {
+		handleDone <- s.Handle(ServerHandler{
+			OnConnOpen: func(sc *ServerConn) ServerConnReadHandlers {
+				close(connOpen)
+
+				return ServerConnReadHandlers{
+					OnOptions: func(ctx *ServerConnOptionsCtx) (*base.Response, error) {
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, nil
+					},
+				}
+			},
+			OnConnClose: func(sc *ServerConn, err error) {
+				connClose <- err
+			},
+		})
+	}()
+
+	conn, err := net.Dial("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer conn.Close()
+	bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+	<-connOpen
+
+	err = base.Request{
+		Method: base.Options,
+		URL:    base.MustParseURL("rtsp://localhost:8554/"),
+		Header: base.Header{
+			"CSeq": base.HeaderValue{"1"},
+		},
+	}.Write(bconn.Writer)
+	require.NoError(t, err)
+
+	var res base.Response
+	err = res.Read(bconn.Reader)
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	s.Close()
+
+	err = <-handleDone
+	require.Error(t, err)
+
+	err = <-connClose
+	require.Error(t, err)
+}
+
+func TestServerHandleCloseDrain(t *testing.T) {
+	for _, ca := range []string{
+		"response",
+		"timeout",
+	} {
+		t.Run(ca, func(t *testing.T) {
+			conf := ServerConf{
+				CloseDrainTimeout: 500 * time.Millisecond,
+			}
+
+			s, err := conf.Serve("127.0.0.1:8554")
+			require.NoError(t, err)
+
+			requestRecv := make(chan struct{})
+			handlerRelease := make(chan struct{})
+
+			handleDone := make(chan error)
+			go func() {
+				handleDone <- s.Handle(ServerHandler{
+					OnConnOpen: func(sc *ServerConn) ServerConnReadHandlers {
+						return ServerConnReadHandlers{
+							OnOptions: func(ctx *ServerConnOptionsCtx) (*base.Response, error) {
+								close(requestRecv)
+								<-handlerRelease
+								return &base.Response{
+									StatusCode: base.StatusOK,
+								}, nil
+							},
+						}
+					},
+				})
+			}()
+
+			conn, err := net.Dial("tcp", "localhost:8554")
+			require.NoError(t, err)
+			defer conn.Close()
+			bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+			err = base.Request{
+				Method: base.Options,
+				URL:    base.MustParseURL("rtsp://localhost:8554/"),
+				Header: base.Header{
+					"CSeq": base.HeaderValue{"1"},
+				},
+			}.Write(bconn.Writer)
+			require.NoError(t, err)
+
+			<-requestRecv
+
+			// the server is closed while the request is being processed
+			s.Close()
+
+			if ca == "response" {
+				time.Sleep(100 * time.Millisecond)
+				close(handlerRelease)
+			}
+
+			var res base.Response
+			err = res.Read(bconn.Reader)
+			require.NoError(t, err)
+			require.Equal(t, base.HeaderValue{"1"}, res.Header["CSeq"])
+
+			if ca == "response" {
+				require.Equal(t, base.StatusOK, res.StatusCode)
+			} else {
+				require.Equal(t, base.StatusServiceUnavailable, res.StatusCode)
+				close(handlerRelease)
+			}
+
+			err = <-handleDone
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestServerConnCloseDrainFromHandler(t *testing.T) {
+	conf := ServerConf{
+		CloseDrainTimeout: 2 * time.Second,
+	}
+
+	s, err := conf.Serve("127.0.0.1:8554")
+	require.NoError(t, err)
+	defer s.Close()
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		sc, err := s.Accept()
+		require.NoError(t, err)
+
+		<-sc.Read(ServerConnReadHandlers{
+			OnOptions: func(ctx *ServerConnOptionsCtx) (*base.Response, error) {
+				// the handler is not waited for
+				sc.Close()
+
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil
+			},
+		})
+	}()
+
+	conn, err := net.Dial("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer conn.Close()
+	bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+	start := time.Now()
+
+	err = base.Request{
+		Method: base.Options,
+		URL:    base.MustParseURL("rtsp://localhost:8554/"),
+		Header: base.Header{
+			"CSeq": base.HeaderValue{"1"},
+		},
+	}.Write(bconn.Writer)
+	require.NoError(t, err)
+
+	// the response of the handler is written, then the connection is closed
+	var res base.Response
+	err = res.Read(bconn.Reader)
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	_, err = bconn.Reader.ReadByte()
+	require.Equal(t, io.EOF, err)
+	require.Less(t, int64(time.Since(start)), int64(conf.CloseDrainTimeout))
+}
+
+func TestServerServeListener(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:8554")
+	require.NoError(t, err)
+
+	s, err := ServeListener(l)
+	require.NoError(t, err)
+	defer s.Close()
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		conn, err := s.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+
+		<-conn.Read(ServerConnReadHandlers{})
+	}()
+
+	conn, err := Dial("rtsp", "localhost:8554")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Options(base.MustParseURL("rtsp://localhost:8554/"))
+	require.NoError(t, err)
+}
+
+func TestServerConnPeerInfo(t *testing.T) {
+	for _, encrypted := range []bool{false, true} {
+		encryptedStr := func() string {
+			if encrypted {
+				return "encrypted"
+			}
+			return "plain"
+		}()
+
+		t.Run(encryptedStr, func(t *testing.T) {
+			var conf ServerConf
+			scheme := "rtsp"
+			if encrypted {
+				scheme = "rtsps"
+				cert, err := tls.X509KeyPair(serverCert, serverKey)
+				require.NoError(t, err)
+				conf.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+			}
+
+			s, err := conf.Serve("127.0.0.1:8554")
+			require.NoError(t, err)
+			defer s.Close()
+
+			serverDone := make(chan struct{})
+			defer func() { <-serverDone }()
+			go func() {
+				defer close(serverDone)
+
+				sc, err := s.Accept()
+				require.NoError(t, err)
+				defer sc.Close()
+
+				<-sc.Read(ServerConnReadHandlers{
+					OnOptions: func(ctx *ServerConnOptionsCtx) (*base.Response, error) {
+						require.Equal(t, "127.0.0.1:8554", sc.LocalAddr().String())
+						require.Equal(t, "127.0.0.1", sc.RemoteAddr().(*net.TCPAddr).IP.String())
+
+						cs := sc.TLSConnectionState()
+						if encrypted {
+							require.NotNil(t, cs)
+							require.Equal(t, true, cs.HandshakeComplete)
+						} else {
+							require.Nil(t, cs)
+						}
+
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, nil
+					},
+				})
+			}()
+
+			conn, err := Dial(scheme, "127.0.0.1:8554")
+			require.NoError(t, err)
+			defer conn.Close()
+
+			res, err := conn.Options(base.MustParseURL(scheme + "://127.0.0.1:8554/"))
+			require.NoError(t, err)
+			require.Equal(t, base.StatusOK, res.StatusCode)
+		})
+	}
+}
+
+func TestServerConnMutualTLS(t *testing.T) {
+	cert, err := tls.X509KeyPair(serverCert, serverKey)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	require.Equal(t, true, pool.AppendCertsFromPEM(serverCert))
+
+	s, err := ServerConf{
+		TLSConfig: &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+			ClientCAs:    pool,
+		},
+	}.Serve("127.0.0.1:8554")
+	require.NoError(t, err)
+	defer s.Close()
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		sc, err := s.Accept()
+		require.NoError(t, err)
+		defer sc.Close()
+
+		<-sc.Read(ServerConnReadHandlers{
+			OnOptions: func(ctx *ServerConnOptionsCtx) (*base.Response, error) {
+				require.Equal(t, 1, len(ctx.ClientCertificates))
+				require.Equal(t, "Internet Widgits Pty Ltd",
+					ctx.ClientCertificates[0].Subject.Organization[0])
+
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil
+			},
+		})
+	}()
+
+	h := sha256.Sum256(cert.Certificate[0])
+
+	conn, err := ClientConf{
+		TLSConfig: &tls.Config{
+			Certificates: []tls.Certificate{cert},
+		},
+		TLSFingerprint: hex.EncodeToString(h[:]),
+	}.Dial("rtsps", "127.0.0.1:8554")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	res, err := conn.Options(base.MustParseURL("rtsps://127.0.0.1:8554/"))
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+}
+
+func TestServerTLSAddress(t *testing.T) {
+	cert, err := tls.X509KeyPair(serverCert, serverKey)
+	require.NoError(t, err)
+
+	s, err := ServerConf{
+		TLSConfig:      &tls.Config{Certificates: []tls.Certificate{cert}},
+		TLSAddress:     "127.0.0.1:8322",
+		UDPRTPAddress:  "127.0.0.1:8000",
+		UDPRTCPAddress: "127.0.0.1:8001",
+	}.Serve("127.0.0.1:8554")
+	require.NoError(t, err)
+
+	encrypted := make(chan bool, 2)
+
+	serverDone := make(chan struct{})
+	go func() {
+		defer close(serverDone)
+
+		s.Handle(ServerHandler{
+			OnConnOpen: func(sc *ServerConn) ServerConnReadHandlers {
+				return ServerConnReadHandlers{
+					OnOptions: func(ctx *ServerConnOptionsCtx) (*base.Response, error) {
+						encrypted <- (sc.TLSConnectionState() != nil)
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, nil
+					},
+				}
+			},
+		})
+	}()
+
+	for _, ca := range []struct {
+		scheme    string
+		host      string
+		encrypted bool
+	}{
+		{"rtsp", "127.0.0.1:8554", false},
+		{"rtsps", "127.0.0.1:8322", true},
+	} {
+		conn, err := Dial(ca.scheme, ca.host)
+		require.NoError(t, err)
+
+		_, err = conn.Options(base.MustParseURL(ca.scheme + "://" + ca.host + "/"))
+		require.NoError(t, err)
+		require.Equal(t, ca.encrypted, <-encrypted)
+
+		conn.Close()
+	}
+
+	s.Close()
+	<-serverDone
+
+	_, err = s.Accept()
+	require.Equal(t, liberrors.ErrServerTerminated{}, err)
+
+	// closing the server again has no effect
+	require.NoError(t, s.Close())
+}
+
+func TestServerTLSAddressListenerError(t *testing.T) {
+	cert, err := tls.X509KeyPair(serverCert, serverKey)
+	require.NoError(t, err)
+
+	s, err := ServerConf{
+		TLSConfig:  &tls.Config{Certificates: []tls.Certificate{cert}},
+		TLSAddress: "127.0.0.1:8322",
+	}.Serve("127.0.0.1:8554")
+	require.NoError(t, err)
+	defer s.Close()
+
+	s.tlsListener.Close()
+
+	_, err = s.Accept()
+	require.Error(t, err)
+	require.NotEqual(t, liberrors.ErrServerTerminated{}, err)
+
+	// the plain listener is closed too
+	_, err = s.Accept()
+	require.Equal(t, liberrors.ErrServerTerminated{}, err)
+
+	_, err = net.Dial("tcp", "127.0.0.1:8554")
+	require.Error(t, err)
+}
+
+func TestServerConnFromConn(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+
+	track, err := NewTrackH264(96, []byte("123456"), []byte("123456"))
+	require.NoError(t, err)
+
+	sc := NewServerConn(serverConn)
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+		defer sc.Close()
+
+		<-sc.Read(ServerConnReadHandlers{
+			OnDescribe: func(ctx *ServerConnDescribeCtx) (*base.Response, []byte, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, Tracks{track}.Write(), nil
+			},
+			OnSetup: func(ctx *ServerConnSetupCtx) (*base.Response, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil
+			},
+			OnPlay: func(ctx *ServerConnPlayCtx) (*base.Response, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil
+			},
+		})
+	}()
+
+	conn := NewClientConnFromConn(clientConn)
+
+	u := base.MustParseURL("rtsp://localhost:8554/teststream")
+
+	_, err = conn.Options(u)
+	require.NoError(t, err)
+
+	tracks, _, err := conn.Describe(u)
+	require.NoError(t, err)
+
+	_, err = conn.Setup(headers.TransportModePlay, tracks[0], 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, StreamProtocolTCP, *conn.StreamProtocol())
+
+	_, err = conn.Play(nil)
+	require.NoError(t, err)
+
+	frameRecv := make(chan []byte, 1)
+	done := conn.ReadFrames(func(trackID int, streamType StreamType, payload []byte) {
+		if streamType == StreamTypeRTP {
+			select {
+			case frameRecv <- append([]byte(nil), payload...):
+			default:
+			}
+		}
+	})
+
+	defer func() {
+		conn.Close()
+		<-done
+	}()
+
+	// frames are written until the connection has started writing them
+	for {
+		sc.WriteFrame(0, StreamTypeRTP, []byte{0x01, 0x02, 0x03, 0x04})
+
+		select {
+		case recv := <-frameRecv:
+			require.Equal(t, []byte{0x01, 0x02, 0x03, 0x04}, recv)
+			return
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func TestServerAuth(t *testing.T) {
+	s, err := ServerConf{
+		AuthCredentials: func(method base.Method, path string, query string) (string, string) {
+			return "myuser", "mypass"
+		},
+	}.Serve("127.0.0.1:8554")
+	require.NoError(t, err)
+	defer s.Close()
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		conn, err := s.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+
+		track, err := NewTrackH264(96, []byte("123456"), []byte("123456"))
+		require.NoError(t, err)
+
+		<-conn.Read(ServerConnReadHandlers{
+			OnDescribe: func(ctx *ServerConnDescribeCtx) (*base.Response, []byte, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, Tracks{track}.Write(), nil
+			},
+		})
+	}()
+
+	conn, err := net.Dial("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer conn.Close()
+	bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+	err = base.Request{
+		Method: base.Describe,
+		URL:    base.MustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq": base.HeaderValue{"1"},
+		},
+	}.Write(bconn.Writer)
+	require.NoError(t, err)
+
+	var res base.Response
+	err = res.Read(bconn.Reader)
+	require.NoError(t, err)
+	require.Equal(t, base.StatusUnauthorized, res.StatusCode)
+
+	sender, err := auth.NewSender(res.Header["WWW-Authenticate"], "myuser", "mypass")
+	require.NoError(t, err)
+
+	u := base.MustParseURL("rtsp://localhost:8554/teststream")
+	authorization := sender.GenerateHeader(base.Describe, u)
+
+	err = base.Request{
+		Method: base.Describe,
+		URL:    u,
+		Header: base.Header{
+			"CSeq":          base.HeaderValue{"2"},
+			"Authorization": authorization,
+		},
+	}.Write(bconn.Writer)
+	require.NoError(t, err)
+
+	err = res.Read(bconn.Reader)
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	// a replayed Authorization header is refused
+	err = base.Request{
+		Method: base.Describe,
+		URL:    u,
+		Header: base.Header{
+			"CSeq":          base.HeaderValue{"3"},
+			"Authorization": authorization,
+		},
+	}.Write(bconn.Writer)
+	require.NoError(t, err)
+
+	err = res.Read(bconn.Reader)
+	require.NoError(t, err)
+	require.Equal(t, base.StatusUnauthorized, res.StatusCode)
+}
+
+func TestServerConnLifecycleHooks(t *testing.T) {
+	for _, ca := range []string{
+		"request error",
+		"timeout",
+	} {
+		t.Run(ca, func(t *testing.T) {
+			s, err := ServerConf{
+				ReadTimeout: 500 * time.Millisecond,
+			}.Serve("127.0.0.1:8554")
+			require.NoError(t, err)
+			defer s.Close()
+
+			var states []ServerConnState
+			requestErr := make(chan error, 1)
+			timeoutErr := make(chan error, 1)
+
+			serverDone := make(chan struct{})
+			defer func() { <-serverDone }()
+			go func() {
+				defer close(serverDone)
+
+				conn, err := s.Accept()
+				require.NoError(t, err)
+				defer conn.Close()
+
+				<-conn.Read(ServerConnReadHandlers{
+					OnAnnounce: func(ctx *ServerConnAnnounceCtx) (*base.Response, error) {
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, nil
+					},
+					OnSetup: func(ctx *ServerConnSetupCtx) (*base.Response, error) {
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, nil
+					},
+					OnRecord: func(ctx *ServerConnRecordCtx) (*base.Response, error) {
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, nil
+					},
+					OnRequestError: func(req *base.Request, err error) {
+						require.Equal(t, base.Play, req.Method)
+						requestErr <- err
+					},
+					OnStateChange: func(prev ServerConnState, cur ServerConnState) {
+						states = append(states, cur)
+					},
+					OnTimeout: func(err error) {
+						timeoutErr <- err
+					},
+				})
+
+				require.Equal(t, []ServerConnState{
+					ServerConnStatePreRecord,
+					ServerConnStateRecord,
+				}, states)
+			}()
+
+			conn, err := net.Dial("tcp", "localhost:8554")
+			require.NoError(t, err)
+			defer conn.Close()
+			bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+			track, err := NewTrackH264(96, []byte("123456"), []byte("123456"))
+			require.NoError(t, err)
+			track.SetControl("trackID=0")
+
+			err = base.Request{
+				Method: base.Announce,
+				URL:    base.MustParseURL("rtsp://localhost:8554/teststream"),
+				Header: base.Header{
+					"CSeq":         base.HeaderValue{"1"},
+					"Content-Type": base.HeaderValue{"application/sdp"},
+				},
+				Body: Tracks{track}.Write(),
+			}.Write(bconn.Writer)
+			require.NoError(t, err)
+
+			var res base.Response
+			err = res.Read(bconn.Reader)
+			require.NoError(t, err)
+			require.Equal(t, base.StatusOK, res.StatusCode)
+
+			err = base.Request{
+				Method: base.Setup,
+				URL:    base.MustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
+				Header: base.Header{
+					"CSeq": base.HeaderValue{"2"},
+					"Transport": headers.Transport{
+						Protocol: StreamProtocolTCP,
+						Delivery: func() *base.StreamDelivery {
+							v := base.StreamDeliveryUnicast
+							return &v
+						}(),
+						Mode: func() *headers.TransportMode {
+							v := headers.TransportModeRecord
+							return &v
+						}(),
+						InterleavedIDs: &[2]int{0, 1},
+					}.Write(),
+				},
+			}.Write(bconn.Writer)
+			require.NoError(t, err)
+
+			err = res.Read(bconn.Reader)
+			require.NoError(t, err)
+			require.Equal(t, base.StatusOK, res.StatusCode)
+
+			err = base.Request{
+				Method: base.Record,
+				URL:    base.MustParseURL("rtsp://localhost:8554/teststream"),
+				Header: base.Header{
+					"CSeq": base.HeaderValue{"3"},
+				},
+			}.Write(bconn.Writer)
+			require.NoError(t, err)
+
+			err = res.Read(bconn.Reader)
+			require.NoError(t, err)
+			require.Equal(t, base.StatusOK, res.StatusCode)
+
+			if ca == "request error" {
+				err = base.Request{
+					Method: base.Play,
+					URL:    base.MustParseURL("rtsp://localhost:8554/teststream"),
+					Header: base.Header{
+						"CSeq": base.HeaderValue{"4"},
+					},
+				}.Write(bconn.Writer)
+				require.NoError(t, err)
+
+				err = <-requestErr
+				require.Equal(t, liberrors.ErrServerUnhandledMethod{Method: base.Play}, err)
+			} else {
+				err = <-timeoutErr
+				nerr, ok := err.(net.Error)
+				require.Equal(t, true, ok)
+				require.Equal(t, true, nerr.Timeout())
+			}
+		})
+	}
+}
+
+func TestServerConnDecodeError(t *testing.T) {
+	for _, ca := range []struct {
+		name   string
+		byts   []byte
+		method base.Method
+		err    string
+	}{
+		{
+			"garbage request",
+			[]byte("\x01\x02\x03 \x04\x05\x06 RTSP/1.0\r\n\r\n"),
+			"\x01\x02\x03",
+			"unable to parse url (\x04\x05\x06)",
+		},
+		{
+			"invalid protocol",
+			[]byte("OPTIONS rtsp://localhost:8554/teststream RTSP/2.0\r\n\r\n"),
+			base.Options,
+			"expected 'RTSP/1.0', got 'RTSP/2.0'",
+		},
+		{
+			"garbage response",
+			[]byte("RTSP/1.0 \x01\x02\x03\r\n\r\n"),
+			"",
+			"",
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			s, err := Serve("127.0.0.1:8554")
+			require.NoError(t, err)
+			defer s.Close()
+
+			type requestError struct {
+				req *base.Request
+				err error
+			}
+			requestErr := make(chan requestError, 1)
+
+			serverDone := make(chan struct{})
+			defer func() { <-serverDone }()
+			go func() {
+				defer close(serverDone)
+
+				conn, err := s.Accept()
+				require.NoError(t, err)
+				defer conn.Close()
+
+				<-conn.Read(ServerConnReadHandlers{
+					OnRequestError: func(req *base.Request, err error) {
+						requestErr <- requestError{req, err}
+					},
+				})
+			}()
+
+			conn, err := net.Dial("tcp", "localhost:8554")
+			require.NoError(t, err)
+			defer conn.Close()
+
+			_, err = conn.Write(ca.byts)
+			require.NoError(t, err)
+
+			re := <-requestErr
+			if ca.method == "" {
+				require.Nil(t, re.req)
+				require.Error(t, re.err)
+			} else {
+				require.Equal(t, ca.method, re.req.Method)
+				require.EqualError(t, re.err, ca.err)
+			}
+		})
+	}
+}
+
+func TestServerConnectionLimits(t *testing.T) {
+	s, err := ServerConf{
+		MaxConnectionsPerIP: 1,
+		RateLimiter: func(addr net.Addr, req *base.Request) bool {
+			return req == nil || req.URL.Path != "/limited"
+		},
+	}.Serve("127.0.0.1:8554")
+	require.NoError(t, err)
+
+	connClose := make(chan struct{}, 1)
+
+	handleDone := make(chan error)
+	go func() {
+		handleDone <- s.Handle(ServerHandler{
+			OnConnOpen: func(sc *ServerConn) ServerConnReadHandlers {
+				return ServerConnReadHandlers{
+					OnDescribe: func(ctx *ServerConnDescribeCtx) (*base.Response, []byte, error) {
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, nil, nil
+					},
+				}
+			},
+			OnConnClose: func(sc *ServerConn, err error) {
+				connClose <- struct{}{}
+			},
+		})
+	}()
+
+	describe := func(bconn *bufio.ReadWriter, path string) (*base.Response, error) {
+		err := base.Request{
+			Method: base.Describe,
+			URL:    base.MustParseURL("rtsp://localhost:8554" + path),
+			Header: base.Header{
+				"CSeq": base.HeaderValue{"1"},
+			},
+		}.Write(bconn.Writer)
+		if err != nil {
+			return nil, err
+		}
+
+		var res base.Response
+		err = res.Read(bconn.Reader)
+		return &res, err
+	}
+
+	conn1, err := net.Dial("tcp", "localhost:8554")
+	require.NoError(t, err)
+	bconn1 := bufio.NewReadWriter(bufio.NewReader(conn1), bufio.NewWriter(conn1))
+
+	res, err := describe(bconn1, "/teststream")
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	res, err = describe(bconn1, "/limited")
+	require.NoError(t, err)
+	require.Equal(t, base.StatusServiceUnavailable, res.StatusCode)
+
+	// the second connection from the same IP is closed
+	conn2, err := net.Dial("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer conn2.Close()
+	bconn2 := bufio.NewReadWriter(bufio.NewReader(conn2), bufio.NewWriter(conn2))
+
+	_, err = describe(bconn2, "/teststream")
+	require.Error(t, err)
+
+	// after the first connection is closed, a new one is accepted
+	conn1.Close()
+	<-connClose
+
+	conn3, err := net.Dial("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer conn3.Close()
+	bconn3 := bufio.NewReadWriter(bufio.NewReader(conn3), bufio.NewWriter(conn3))
+
+	res, err = describe(bconn3, "/teststream")
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	s.Close()
+	<-handleDone
+}
+
+func TestServerPassthrough(t *testing.T) {
+	s, err := Serve("127.0.0.1:8554")
+	require.NoError(t, err)
+	defer s.Close()
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		conn, err := s.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+
+		<-conn.Read(ServerConnReadHandlers{
+			// handlers are bypassed
+			OnSetup: func(ctx *ServerConnSetupCtx) (*base.Response, error) {
+				t.Errorf("should not happen")
+				return nil, nil
+			},
+			OnRequestPassthrough: func(req *base.Request) (*base.Response, error) {
+				require.Equal(t, base.Setup, req.Method)
+				return &base.Response{
+					StatusCode: base.StatusOK,
+					Header: base.Header{
+						"Session":   base.HeaderValue{"upstream"},
+						"Transport": req.Header["Transport"],
+					},
+				}, nil
+			},
+			OnInterleavedFrame: func(channel int, payload []byte) {
+				require.Equal(t, 5, channel)
+
+				// frames are echoed with another channel
+				err := conn.WriteInterleavedFrame(6, payload)
+				require.NoError(t, err)
+			},
+		})
+	}()
+
+	conn, err := net.Dial("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer conn.Close()
+	bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+	err = base.Request{
+		Method: base.Setup,
+		URL:    base.MustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
+		Header: base.Header{
+			"CSeq":      base.HeaderValue{"3"},
+			"Transport": base.HeaderValue{"RTP/AVP/TCP;unicast;interleaved=4-5"},
+		},
+	}.Write(bconn.Writer)
+	require.NoError(t, err)
+
+	var res base.Response
+	err = res.Read(bconn.Reader)
+	require.NoError(t, err)
+	require.Equal(t, base.Response{
+		StatusCode:    base.StatusOK,
+		StatusMessage: "OK",
+		Header: base.Header{
+			"CSeq":      base.HeaderValue{"3"},
+			"Session":   base.HeaderValue{"upstream"},
+			"Transport": base.HeaderValue{"RTP/AVP/TCP;unicast;interleaved=4-5"},
+		},
+	}, res)
+
+	// frames are accepted without a PLAY or RECORD request
+	err = base.InterleavedFrame{
+		TrackID:    2,
+		StreamType: StreamTypeRTCP,
+		Payload:    []byte{0x01, 0x02, 0x03, 0x04},
+	}.Write(bconn.Writer)
+	require.NoError(t, err)
+
+	frame := base.InterleavedFrame{
+		Payload: make([]byte, 2048),
+	}
+	err = frame.Read(bconn.Reader)
+	require.NoError(t, err)
+	require.Equal(t, 3, frame.TrackID)
+	require.Equal(t, StreamTypeRTP, frame.StreamType)
+	require.Equal(t, []byte{0x01, 0x02, 0x03, 0x04}, frame.Payload)
+}
+
+func TestServerWriteInterleavedFrameNoPassthrough(t *testing.T) {
+	sc := ServerConf{}.NewServerConn(nil)
+	err := sc.WriteInterleavedFrame(0, []byte{0x01})
+	require.Equal(t, liberrors.ErrServerPassthroughDisabled{}, err)
+}
+
+func TestServerMethods(t *testing.T) {
+	for _, ca := range []struct {
+		name     string
+		methods  []base.Method
+		public   string
+		rejected base.Method
+	}{
+		{
+			"handlers",
+			nil,
+			"DESCRIBE, SETUP, GET_PARAMETER, TEARDOWN",
+			base.Announce,
+		},
+		{
+			"explicit",
+			[]base.Method{base.Describe, base.Teardown},
+			"DESCRIBE, TEARDOWN",
+			base.Setup,
+		},
+	} {
+		t.Run(ca.name, func(t *testing.T) {
+			s, err := ServerConf{
+				Methods: ca.methods,
+			}.Serve("127.0.0.1:8554")
+			require.NoError(t, err)
+			defer s.Close()
+
+			serverDone := make(chan struct{})
+			defer func() { <-serverDone }()
+			go func() {
+				defer close(serverDone)
+
+				conn, err := s.Accept()
+				require.NoError(t, err)
+				defer conn.Close()
+
+				err = <-conn.Read(ServerConnReadHandlers{
+					OnDescribe: func(ctx *ServerConnDescribeCtx) (*base.Response, []byte, error) {
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, nil, nil
+					},
+					OnSetup: func(ctx *ServerConnSetupCtx) (*base.Response, error) {
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, nil
+					},
+				})
+				require.Equal(t, liberrors.ErrServerUnhandledMethod{Method: ca.rejected}, err)
+			}()
+
+			conn, err := net.Dial("tcp", "localhost:8554")
+			require.NoError(t, err)
+			defer conn.Close()
+			bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+			err = base.Request{
+				Method: base.Options,
+				URL:    base.MustParseURL("rtsp://localhost:8554/teststream"),
+				Header: base.Header{
+					"CSeq": base.HeaderValue{"1"},
+				},
+			}.Write(bconn.Writer)
+			require.NoError(t, err)
+
+			var res base.Response
+			err = res.Read(bconn.Reader)
+			require.NoError(t, err)
+			require.Equal(t, base.StatusOK, res.StatusCode)
+			require.Equal(t, base.HeaderValue{ca.public}, res.Header["Public"])
+
+			err = base.Request{
+				Method: ca.rejected,
+				URL:    base.MustParseURL("rtsp://localhost:8554/teststream"),
+				Header: base.Header{
+					"CSeq": base.HeaderValue{"2"},
+				},
+			}.Write(bconn.Writer)
+			require.NoError(t, err)
+
+			err = res.Read(bconn.Reader)
+			require.NoError(t, err)
+			require.Equal(t, base.StatusMethodNotAllowed, res.StatusCode)
+			require.Equal(t, base.HeaderValue{ca.public}, res.Header["Allow"])
+		})
+	}
+}
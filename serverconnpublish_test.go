@@ -2,17 +2,21 @@ package gortsplib
 
 import (
 	"bufio"
+	"errors"
 	"net"
 	"strconv"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
 	psdp "github.com/pion/sdp/v3"
 	"github.com/stretchr/testify/require"
 
 	"github.com/majoyz/gortsplib/pkg/base"
 	"github.com/majoyz/gortsplib/pkg/headers"
+	"github.com/majoyz/gortsplib/pkg/liberrors"
 )
 
 func TestServerPublishSetupPath(t *testing.T) {
@@ -112,10 +116,7 @@ func TestServerPublishSetupPath(t *testing.T) {
 
 			track, err := NewTrackH264(96, []byte("123456"), []byte("123456"))
 			require.NoError(t, err)
-			track.Media.Attributes = append(track.Media.Attributes, psdp.Attribute{
-				Key:   "control",
-				Value: ca.control,
-			})
+			track.SetControl(ca.control)
 
 			sout := &psdp.SessionDescription{
 				SessionName: psdp.SessionName("Stream"),
@@ -230,10 +231,7 @@ func TestServerPublishSetupDifferentPaths(t *testing.T) {
 
 	tracks := Tracks{track}
 	for i, t := range tracks {
-		t.Media.Attributes = append(t.Media.Attributes, psdp.Attribute{
-			Key:   "control",
-			Value: "trackID=" + strconv.FormatInt(int64(i), 10),
-		})
+		t.SetControl("trackID=" + strconv.FormatInt(int64(i), 10))
 	}
 
 	err = base.Request{
@@ -280,7 +278,7 @@ func TestServerPublishSetupDifferentPaths(t *testing.T) {
 	require.Equal(t, base.StatusBadRequest, res.StatusCode)
 
 	err = <-serverErr
-	require.Equal(t, "invalid track path (test2stream/trackID=0)", err.Error())
+	require.Equal(t, liberrors.ErrServerTrackPathInvalid{Path: "test2stream/trackID=0"}, err)
 }
 
 func TestServerPublishSetupDouble(t *testing.T) {
@@ -328,10 +326,7 @@ func TestServerPublishSetupDouble(t *testing.T) {
 
 	tracks := Tracks{track}
 	for i, t := range tracks {
-		t.Media.Attributes = append(t.Media.Attributes, psdp.Attribute{
-			Key:   "control",
-			Value: "trackID=" + strconv.FormatInt(int64(i), 10),
-		})
+		t.SetControl("trackID=" + strconv.FormatInt(int64(i), 10))
 	}
 
 	err = base.Request{
@@ -450,10 +445,7 @@ func TestServerPublishRecordPartialTracks(t *testing.T) {
 
 	tracks := Tracks{track1, track2}
 	for i, t := range tracks {
-		t.Media.Attributes = append(t.Media.Attributes, psdp.Attribute{
-			Key:   "control",
-			Value: "trackID=" + strconv.FormatInt(int64(i), 10),
-		})
+		t.SetControl("trackID=" + strconv.FormatInt(int64(i), 10))
 	}
 
 	err = base.Request{
@@ -594,10 +586,7 @@ func TestServerPublishReceivePackets(t *testing.T) {
 
 			tracks := Tracks{track}
 			for i, t := range tracks {
-				t.Media.Attributes = append(t.Media.Attributes, psdp.Attribute{
-					Key:   "control",
-					Value: "trackID=" + strconv.FormatInt(int64(i), 10),
-				})
+				t.SetControl("trackID=" + strconv.FormatInt(int64(i), 10))
 			}
 
 			err = base.Request{
@@ -708,3 +697,420 @@ func TestServerPublishReceivePackets(t *testing.T) {
 		})
 	}
 }
+
+func TestServerPublishSRTP(t *testing.T) {
+	for _, proto := range []string{
+		"udp",
+		"tcp",
+	} {
+		t.Run(proto, func(t *testing.T) {
+			pkt, err := (&rtp.Packet{
+				Header: rtp.Header{
+					Version:        2,
+					PayloadType:    96,
+					SequenceNumber: 946,
+					Timestamp:      54352,
+					SSRC:           753621,
+				},
+				Payload: []byte{0x01, 0x02, 0x03, 0x04},
+			}).Marshal()
+			require.NoError(t, err)
+
+			packetReceived := make(chan struct{})
+			packetDiscarded := make(chan error, 1)
+
+			conf := ServerConf{}
+
+			if proto == "udp" {
+				conf.UDPRTPAddress = "127.0.0.1:8000"
+				conf.UDPRTCPAddress = "127.0.0.1:8001"
+			}
+
+			s, err := conf.Serve("127.0.0.1:8554")
+			require.NoError(t, err)
+			defer s.Close()
+
+			serverDone := make(chan struct{})
+			defer func() { <-serverDone }()
+			go func() {
+				defer close(serverDone)
+
+				conn, err := s.Accept()
+				require.NoError(t, err)
+				defer conn.Close()
+
+				onAnnounce := func(ctx *ServerConnAnnounceCtx) (*base.Response, error) {
+					return &base.Response{
+						StatusCode: base.StatusOK,
+					}, nil
+				}
+
+				onSetup := func(ctx *ServerConnSetupCtx) (*base.Response, error) {
+					require.Equal(t, headers.TransportProfileSAVP, ctx.Transport.Profile)
+					return &base.Response{
+						StatusCode: base.StatusOK,
+					}, nil
+				}
+
+				onRecord := func(ctx *ServerConnRecordCtx) (*base.Response, error) {
+					return &base.Response{
+						StatusCode: base.StatusOK,
+					}, nil
+				}
+
+				onFrame := func(trackID int, typ StreamType, buf []byte) {
+					if typ == StreamTypeRTP {
+						require.Equal(t, pkt, buf)
+						close(packetReceived)
+					}
+				}
+
+				<-conn.Read(ServerConnReadHandlers{
+					OnAnnounce: onAnnounce,
+					OnSetup:    onSetup,
+					OnRecord:   onRecord,
+					OnFrame:    onFrame,
+					OnUDPPacketDiscarded: func(err error) {
+						select {
+						case packetDiscarded <- err:
+						default:
+						}
+					},
+				})
+			}()
+
+			track, err := NewTrackH264(96, []byte("123456"), []byte("123456"))
+			require.NoError(t, err)
+
+			err = track.SetSRTPKey([]byte("0123456789abcdef0123456789abcd"))
+			require.NoError(t, err)
+
+			cconf := ClientConf{
+				StreamProtocol: func() *StreamProtocol {
+					if proto == "udp" {
+						v := StreamProtocolUDP
+						return &v
+					}
+					v := StreamProtocolTCP
+					return &v
+				}(),
+			}
+
+			conn, err := cconf.DialPublish("rtsp://localhost:8554/teststream",
+				Tracks{track})
+			require.NoError(t, err)
+			defer conn.Close()
+
+			if proto == "udp" {
+				time.Sleep(500 * time.Millisecond)
+			}
+
+			err = conn.WriteFrame(track.ID, StreamTypeRTP, pkt)
+			require.NoError(t, err)
+
+			<-packetReceived
+
+			if proto == "udp" {
+				// a packet with the same sequence number is a replay
+				err = conn.WriteFrame(track.ID, StreamTypeRTP, pkt)
+				require.NoError(t, err)
+
+				err = <-packetDiscarded
+				var decryptErr liberrors.ErrServerSRTPDecryptFailed
+				require.Equal(t, true, errors.As(err, &decryptErr))
+				require.Equal(t, 0, decryptErr.TrackID)
+			}
+		})
+	}
+}
+
+func TestServerPublishSetupTransportFields(t *testing.T) {
+	for _, proto := range []string{
+		"udp",
+		"tcp",
+	} {
+		t.Run(proto, func(t *testing.T) {
+			conf := ServerConf{
+				UDPRTPAddress:                "127.0.0.1:8000",
+				UDPRTCPAddress:               "127.0.0.1:8001",
+				SetupTransportSource:         true,
+				SetupTransportTCPServerPorts: true,
+			}
+
+			s, err := conf.Serve("127.0.0.1:8554")
+			require.NoError(t, err)
+			defer s.Close()
+
+			serverDone := make(chan struct{})
+			defer func() { <-serverDone }()
+			go func() {
+				defer close(serverDone)
+
+				conn, err := s.Accept()
+				require.NoError(t, err)
+				defer conn.Close()
+
+				<-conn.Read(ServerConnReadHandlers{
+					OnAnnounce: func(ctx *ServerConnAnnounceCtx) (*base.Response, error) {
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, nil
+					},
+					OnSetup: func(ctx *ServerConnSetupCtx) (*base.Response, error) {
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, nil
+					},
+				})
+			}()
+
+			conn, err := net.Dial("tcp", "localhost:8554")
+			require.NoError(t, err)
+			defer conn.Close()
+			bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+			track, err := NewTrackH264(96, []byte("123456"), []byte("123456"))
+			require.NoError(t, err)
+			track.SetControl("trackID=0")
+
+			err = base.Request{
+				Method: base.Announce,
+				URL:    base.MustParseURL("rtsp://localhost:8554/teststream"),
+				Header: base.Header{
+					"CSeq":         base.HeaderValue{"1"},
+					"Content-Type": base.HeaderValue{"application/sdp"},
+				},
+				Body: Tracks{track}.Write(),
+			}.Write(bconn.Writer)
+			require.NoError(t, err)
+
+			var res base.Response
+			err = res.Read(bconn.Reader)
+			require.NoError(t, err)
+			require.Equal(t, base.StatusOK, res.StatusCode)
+
+			inTH := &headers.Transport{
+				Delivery: func() *base.StreamDelivery {
+					v := base.StreamDeliveryUnicast
+					return &v
+				}(),
+				Mode: func() *headers.TransportMode {
+					v := headers.TransportModeRecord
+					return &v
+				}(),
+				SSRC: func() *uint32 {
+					v := uint32(0x38F27A2F)
+					return &v
+				}(),
+			}
+
+			if proto == "udp" {
+				inTH.Protocol = StreamProtocolUDP
+				inTH.ClientPorts = &[2]int{35466, 35467}
+			} else {
+				inTH.Protocol = StreamProtocolTCP
+				inTH.InterleavedIDs = &[2]int{0, 1}
+			}
+
+			err = base.Request{
+				Method: base.Setup,
+				URL:    base.MustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
+				Header: base.Header{
+					"CSeq":      base.HeaderValue{"2"},
+					"Transport": inTH.Write(),
+				},
+			}.Write(bconn.Writer)
+			require.NoError(t, err)
+
+			err = res.Read(bconn.Reader)
+			require.NoError(t, err)
+			require.Equal(t, base.StatusOK, res.StatusCode)
+
+			var th headers.Transport
+			err = th.Read(res.Header["Transport"])
+			require.NoError(t, err)
+			require.Equal(t, inTH.SSRC, th.SSRC)
+			require.Equal(t, &[2]int{8000, 8001}, th.ServerPorts)
+
+			if proto == "udp" {
+				require.NotNil(t, th.Source)
+				require.Equal(t, "127.0.0.1", *th.Source)
+			} else {
+				require.Nil(t, th.Source)
+			}
+		})
+	}
+}
+
+func TestServerPublishParsedPackets(t *testing.T) {
+	rtpPkt := &rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			Marker:         true,
+			PayloadType:    96,
+			SequenceNumber: 946,
+			Timestamp:      54352,
+			SSRC:           753621,
+		},
+		Payload: []byte{0x01, 0x02, 0x03, 0x04},
+	}
+	rtcpPkt := &rtcp.PictureLossIndication{
+		SenderSSRC: 1234,
+		MediaSSRC:  753621,
+	}
+
+	rtpRecv := make(chan *rtp.Packet, 1)
+	rtcpRecv := make(chan []rtcp.Packet, 1)
+	framesRecv := make(chan StreamType, 2)
+
+	s, err := Serve("127.0.0.1:8554")
+	require.NoError(t, err)
+	defer s.Close()
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		conn, err := s.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+
+		<-conn.Read(ServerConnReadHandlers{
+			OnAnnounce: func(ctx *ServerConnAnnounceCtx) (*base.Response, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil
+			},
+			OnSetup: func(ctx *ServerConnSetupCtx) (*base.Response, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil
+			},
+			OnRecord: func(ctx *ServerConnRecordCtx) (*base.Response, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil
+			},
+			OnFrame: func(trackID int, typ StreamType, buf []byte) {
+				select {
+				case framesRecv <- typ:
+				default:
+				}
+			},
+			OnPacketRTP: func(trackID int, pkt *rtp.Packet) {
+				require.Equal(t, 0, trackID)
+				// copy the packet, since the payload is valid only inside the callback
+				cpy := *pkt
+				cpy.Payload = append([]byte(nil), pkt.Payload...)
+				select {
+				case rtpRecv <- &cpy:
+				default:
+				}
+			},
+			OnPacketRTCP: func(trackID int, pkts []rtcp.Packet) {
+				require.Equal(t, 0, trackID)
+				select {
+				case rtcpRecv <- pkts:
+				default:
+				}
+			},
+		})
+	}()
+
+	track, err := NewTrackH264(96, []byte("123456"), []byte("123456"))
+	require.NoError(t, err)
+
+	v := StreamProtocolTCP
+	conf := ClientConf{
+		StreamProtocol: &v,
+	}
+
+	conn, err := conf.DialPublish("rtsp://localhost:8554/teststream",
+		Tracks{track})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	byts, err := rtpPkt.Marshal()
+	require.NoError(t, err)
+	err = conn.WriteFrame(track.ID, StreamTypeRTP, byts)
+	require.NoError(t, err)
+
+	pkt := <-rtpRecv
+	require.Equal(t, rtpPkt.Header.Marker, pkt.Header.Marker)
+	require.Equal(t, rtpPkt.Header.SequenceNumber, pkt.Header.SequenceNumber)
+	require.Equal(t, rtpPkt.Header.Timestamp, pkt.Header.Timestamp)
+	require.Equal(t, rtpPkt.Payload, pkt.Payload)
+
+	byts, err = rtcpPkt.Marshal()
+	require.NoError(t, err)
+	err = conn.WriteFrame(track.ID, StreamTypeRTCP, byts)
+	require.NoError(t, err)
+
+	pkts := <-rtcpRecv
+	require.Equal(t, []rtcp.Packet{rtcpPkt}, pkts)
+
+	// OnFrame is still called
+	require.Equal(t, StreamTypeRTP, <-framesRecv)
+}
+
+func TestServerPublishLogger(t *testing.T) {
+	logger := &testLogger{warnings: make(chan string, 1)}
+
+	s, err := ServerConf{
+		Logger: logger,
+	}.Serve("127.0.0.1:8554")
+	require.NoError(t, err)
+	defer s.Close()
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		conn, err := s.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+
+		<-conn.Read(ServerConnReadHandlers{
+			OnAnnounce: func(ctx *ServerConnAnnounceCtx) (*base.Response, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil
+			},
+			OnSetup: func(ctx *ServerConnSetupCtx) (*base.Response, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil
+			},
+			OnRecord: func(ctx *ServerConnRecordCtx) (*base.Response, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil
+			},
+			OnPacketRTP: func(trackID int, pkt *rtp.Packet) {
+				t.Errorf("should not happen")
+			},
+		})
+	}()
+
+	track, err := NewTrackH264(96, []byte("123456"), []byte("123456"))
+	require.NoError(t, err)
+
+	v := StreamProtocolTCP
+	conf := ClientConf{
+		StreamProtocol: &v,
+	}
+
+	conn, err := conf.DialPublish("rtsp://localhost:8554/teststream",
+		Tracks{track})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// RTP packets that can't be parsed are reported to the logger
+	err = conn.WriteFrame(track.ID, StreamTypeRTP, []byte{0x80, 0x60})
+	require.NoError(t, err)
+
+	require.Equal(t, "unable to parse RTP packet of track 0: RTP header size insufficient: 2 < 4",
+		<-logger.warnings)
+}
@@ -2,14 +2,19 @@ package gortsplib
 
 import (
 	"bufio"
+	"bytes"
 	"net"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/pion/rtcp"
 	"github.com/stretchr/testify/require"
 
 	"github.com/majoyz/gortsplib/pkg/base"
 	"github.com/majoyz/gortsplib/pkg/headers"
+	"github.com/majoyz/gortsplib/pkg/liberrors"
 )
 
 func TestServerReadSetupPath(t *testing.T) {
@@ -17,18 +22,21 @@ func TestServerReadSetupPath(t *testing.T) {
 		name    string
 		url     string
 		path    string
+		query   string
 		trackID int
 	}{
 		{
 			"normal",
 			"rtsp://localhost:8554/teststream/trackID=2",
 			"teststream",
+			"",
 			2,
 		},
 		{
 			"with query",
 			"rtsp://localhost:8554/teststream?testing=123/trackID=4",
 			"teststream",
+			"?testing=123",
 			4,
 		},
 		{
@@ -36,33 +44,59 @@ func TestServerReadSetupPath(t *testing.T) {
 			"without track id",
 			"rtsp://localhost:8554/teststream/",
 			"teststream",
+			"",
+			0,
+		},
+		{
+			"without track id and slash",
+			"rtsp://localhost:8554/teststream",
+			"teststream",
+			"",
+			0,
+		},
+		{
+			"without track id, with query",
+			"rtsp://localhost:8554/teststream?testing=123/",
+			"teststream",
+			"?testing=123",
+			0,
+		},
+		{
+			"without track id and slash, with query",
+			"rtsp://localhost:8554/teststream?testing=123",
+			"teststream",
+			"?testing=123",
 			0,
 		},
 		{
 			"subpath",
 			"rtsp://localhost:8554/test/stream/trackID=0",
 			"test/stream",
+			"",
 			0,
 		},
 		{
 			"subpath without track id",
 			"rtsp://localhost:8554/test/stream/",
 			"test/stream",
+			"",
 			0,
 		},
 		{
 			"subpath with query",
 			"rtsp://localhost:8554/test/stream?testing=123/trackID=4",
 			"test/stream",
+			"?testing=123",
 			4,
 		},
 	} {
 		t.Run(ca.name, func(t *testing.T) {
-			type pathTrackIDPair struct {
+			type setupInfo struct {
 				path    string
+				query   string
 				trackID int
 			}
-			setupDone := make(chan pathTrackIDPair)
+			setupDone := make(chan setupInfo)
 
 			s, err := Serve("127.0.0.1:8554")
 			require.NoError(t, err)
@@ -78,7 +112,7 @@ func TestServerReadSetupPath(t *testing.T) {
 				defer conn.Close()
 
 				onSetup := func(ctx *ServerConnSetupCtx) (*base.Response, error) {
-					setupDone <- pathTrackIDPair{ctx.Path, ctx.TrackID}
+					setupDone <- setupInfo{ctx.Path, ctx.Query, ctx.TrackID}
 					return &base.Response{
 						StatusCode: base.StatusOK,
 					}, nil
@@ -117,9 +151,10 @@ func TestServerReadSetupPath(t *testing.T) {
 			}.Write(bconn.Writer)
 			require.NoError(t, err)
 
-			pair := <-setupDone
-			require.Equal(t, ca.path, pair.path)
-			require.Equal(t, ca.trackID, pair.trackID)
+			info := <-setupDone
+			require.Equal(t, ca.path, info.path)
+			require.Equal(t, ca.query, info.query)
+			require.Equal(t, ca.trackID, info.trackID)
 
 			var res base.Response
 			err = res.Read(bconn.Reader)
@@ -207,7 +242,7 @@ func TestServerReadSetupDifferentPaths(t *testing.T) {
 	require.Equal(t, base.StatusBadRequest, res.StatusCode)
 
 	err = <-serverErr
-	require.Equal(t, "can't setup tracks with different paths", err.Error())
+	require.Equal(t, liberrors.ErrServerTracksDifferentPaths{}, err)
 }
 
 func TestServerReadSetupDouble(t *testing.T) {
@@ -777,3 +812,1657 @@ func TestServerReadPauseMultiple(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, base.StatusOK, res.StatusCode)
 }
+
+func TestServerReadBackChannel(t *testing.T) {
+	for _, proto := range []string{
+		"udp",
+		"tcp",
+	} {
+		t.Run(proto, func(t *testing.T) {
+			packetsReceived := make(chan struct{})
+
+			conf := ServerConf{
+				UDPRTPAddress:  "127.0.0.1:8000",
+				UDPRTCPAddress: "127.0.0.1:8001",
+			}
+
+			s, err := conf.Serve("127.0.0.1:8554")
+			require.NoError(t, err)
+			defer s.Close()
+
+			serverDone := make(chan struct{})
+			defer func() { <-serverDone }()
+			go func() {
+				defer close(serverDone)
+
+				conn, err := s.Accept()
+				require.NoError(t, err)
+				defer conn.Close()
+
+				onSetup := func(ctx *ServerConnSetupCtx) (*base.Response, error) {
+					if ctx.TrackID == 0 {
+						require.Equal(t, headers.TransportModePlay, ctx.Mode)
+					} else {
+						require.Equal(t, headers.TransportModeRecord, ctx.Mode)
+					}
+
+					return &base.Response{
+						StatusCode: base.StatusOK,
+					}, nil
+				}
+
+				onPlay := func(ctx *ServerConnPlayCtx) (*base.Response, error) {
+					return &base.Response{
+						StatusCode: base.StatusOK,
+					}, nil
+				}
+
+				onFrame := func(trackID int, typ StreamType, buf []byte) {
+					require.Equal(t, 1, trackID)
+					require.Equal(t, StreamTypeRTP, typ)
+					require.Equal(t, []byte("\x01\x02\x03\x04"), buf)
+					close(packetsReceived)
+				}
+
+				<-conn.Read(ServerConnReadHandlers{
+					OnSetup: onSetup,
+					OnPlay:  onPlay,
+					OnFrame: onFrame,
+				})
+			}()
+
+			conn, err := net.Dial("tcp", "localhost:8554")
+			require.NoError(t, err)
+			defer conn.Close()
+			bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+			var res base.Response
+			var th headers.Transport
+
+			for trackID, mode := range []headers.TransportMode{
+				headers.TransportModePlay,
+				headers.TransportModeRecord,
+			} {
+				inTH := &headers.Transport{
+					Delivery: func() *base.StreamDelivery {
+						v := base.StreamDeliveryUnicast
+						return &v
+					}(),
+					Mode: &mode,
+				}
+
+				if proto == "udp" {
+					inTH.Protocol = StreamProtocolUDP
+					inTH.ClientPorts = &[2]int{35466 + trackID*2, 35467 + trackID*2}
+				} else {
+					inTH.Protocol = StreamProtocolTCP
+					inTH.InterleavedIDs = &[2]int{trackID * 2, trackID*2 + 1}
+				}
+
+				err = base.Request{
+					Method: base.Setup,
+					URL:    base.MustParseURL("rtsp://localhost:8554/teststream/trackID=" + strconv.FormatInt(int64(trackID), 10)),
+					Header: base.Header{
+						"CSeq":      base.HeaderValue{strconv.FormatInt(int64(trackID+1), 10)},
+						"Transport": inTH.Write(),
+					},
+				}.Write(bconn.Writer)
+				require.NoError(t, err)
+
+				err = res.Read(bconn.Reader)
+				require.NoError(t, err)
+				require.Equal(t, base.StatusOK, res.StatusCode)
+
+				err = th.Read(res.Header["Transport"])
+				require.NoError(t, err)
+			}
+
+			err = base.Request{
+				Method: base.Play,
+				URL:    base.MustParseURL("rtsp://localhost:8554/teststream"),
+				Header: base.Header{
+					"CSeq": base.HeaderValue{"3"},
+				},
+			}.Write(bconn.Writer)
+			require.NoError(t, err)
+
+			err = res.Read(bconn.Reader)
+			require.NoError(t, err)
+			require.Equal(t, base.StatusOK, res.StatusCode)
+
+			if proto == "udp" {
+				time.Sleep(1 * time.Second)
+
+				l1, err := net.ListenPacket("udp", "localhost:35468")
+				require.NoError(t, err)
+				defer l1.Close()
+
+				l1.WriteTo([]byte("\x01\x02\x03\x04"), &net.UDPAddr{
+					IP:   net.ParseIP("127.0.0.1"),
+					Port: th.ServerPorts[0],
+				})
+			} else {
+				err = base.InterleavedFrame{
+					TrackID:    1,
+					StreamType: StreamTypeRTP,
+					Payload:    []byte("\x01\x02\x03\x04"),
+				}.Write(bconn.Writer)
+				require.NoError(t, err)
+			}
+
+			<-packetsReceived
+		})
+	}
+}
+
+func TestServerReadSessionTimeout(t *testing.T) {
+	conf := ServerConf{
+		UDPRTPAddress:  "127.0.0.1:8000",
+		UDPRTCPAddress: "127.0.0.1:8001",
+		SessionTimeout: 1 * time.Second,
+	}
+
+	s, err := conf.Serve("127.0.0.1:8554")
+	require.NoError(t, err)
+	defer s.Close()
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		conn, err := s.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+
+		onSetup := func(ctx *ServerConnSetupCtx) (*base.Response, error) {
+			return &base.Response{
+				StatusCode: base.StatusOK,
+				Header: base.Header{
+					"Session": base.HeaderValue{"12345678"},
+				},
+			}, nil
+		}
+
+		onPlay := func(ctx *ServerConnPlayCtx) (*base.Response, error) {
+			return &base.Response{
+				StatusCode: base.StatusOK,
+			}, nil
+		}
+
+		err = <-conn.Read(ServerConnReadHandlers{
+			OnSetup: onSetup,
+			OnPlay:  onPlay,
+		})
+		require.Equal(t, liberrors.ErrServerSessionTimedOut{}, err)
+	}()
+
+	conn, err := net.Dial("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer conn.Close()
+	bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+	err = base.Request{
+		Method: base.Setup,
+		URL:    base.MustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
+		Header: base.Header{
+			"CSeq": base.HeaderValue{"1"},
+			"Transport": headers.Transport{
+				Protocol: StreamProtocolUDP,
+				Delivery: func() *base.StreamDelivery {
+					v := base.StreamDeliveryUnicast
+					return &v
+				}(),
+				ClientPorts: &[2]int{35466, 35467},
+			}.Write(),
+		},
+	}.Write(bconn.Writer)
+	require.NoError(t, err)
+
+	var res base.Response
+	err = res.Read(bconn.Reader)
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+	require.Equal(t, base.HeaderValue{"12345678;timeout=1"}, res.Header["Session"])
+
+	err = base.Request{
+		Method: base.Play,
+		URL:    base.MustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq":    base.HeaderValue{"2"},
+			"Session": base.HeaderValue{"12345678"},
+		},
+	}.Write(bconn.Writer)
+	require.NoError(t, err)
+
+	err = res.Read(bconn.Reader)
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	<-serverDone
+}
+
+func TestServerReadRedirect(t *testing.T) {
+	for _, proto := range []string{
+		"udp",
+		"tcp",
+	} {
+		t.Run(proto, func(t *testing.T) {
+			conf := ServerConf{
+				UDPRTPAddress:  "127.0.0.1:8000",
+				UDPRTCPAddress: "127.0.0.1:8001",
+			}
+
+			s, err := conf.Serve("127.0.0.1:8554")
+			require.NoError(t, err)
+			defer s.Close()
+
+			track, err := NewTrackH264(96, []byte("123456"), []byte("123456"))
+			require.NoError(t, err)
+
+			playing := make(chan struct{})
+
+			serverDone := make(chan struct{})
+			defer func() { <-serverDone }()
+			go func() {
+				defer close(serverDone)
+
+				conn, err := s.Accept()
+				require.NoError(t, err)
+				defer conn.Close()
+
+				onDescribe := func(ctx *ServerConnDescribeCtx) (*base.Response, []byte, error) {
+					return &base.Response{
+						StatusCode: base.StatusOK,
+					}, Tracks{track}.Write(), nil
+				}
+
+				onSetup := func(ctx *ServerConnSetupCtx) (*base.Response, error) {
+					return &base.Response{
+						StatusCode: base.StatusOK,
+					}, nil
+				}
+
+				onPlay := func(ctx *ServerConnPlayCtx) (*base.Response, error) {
+					return &base.Response{
+						StatusCode: base.StatusOK,
+					}, nil
+				}
+
+				readDone := conn.Read(ServerConnReadHandlers{
+					OnDescribe: onDescribe,
+					OnSetup:    onSetup,
+					OnPlay:     onPlay,
+				})
+
+				<-playing
+
+				err = conn.Redirect(base.MustParseURL("rtsp://localhost:8555/teststream"))
+				require.NoError(t, err)
+
+				<-readDone
+			}()
+
+			var redirectedTo *base.URL
+
+			cconf := ClientConf{
+				StreamProtocol: func() *StreamProtocol {
+					if proto == "udp" {
+						v := StreamProtocolUDP
+						return &v
+					}
+					v := StreamProtocolTCP
+					return &v
+				}(),
+				OnRedirect: func(location *base.URL) {
+					redirectedTo = location
+				},
+			}
+
+			conn, err := cconf.DialRead("rtsp://localhost:8554/teststream")
+			require.NoError(t, err)
+
+			done := conn.ReadFrames(func(id int, typ StreamType, payload []byte) {
+			})
+			close(playing)
+
+			err = <-done
+			require.Equal(t, liberrors.ErrClientRedirected{
+				Location: base.MustParseURL("rtsp://localhost:8555/teststream"),
+			}, err)
+			require.Equal(t, base.MustParseURL("rtsp://localhost:8555/teststream"), redirectedTo)
+
+			conn.Close()
+		})
+	}
+}
+
+func TestServerReadRequest(t *testing.T) {
+	for _, proto := range []string{
+		"udp",
+		"tcp",
+	} {
+		t.Run(proto, func(t *testing.T) {
+			conf := ServerConf{
+				UDPRTPAddress:  "127.0.0.1:8000",
+				UDPRTCPAddress: "127.0.0.1:8001",
+			}
+
+			s, err := conf.Serve("127.0.0.1:8554")
+			require.NoError(t, err)
+			defer s.Close()
+
+			track, err := NewTrackH264(96, []byte("123456"), []byte("123456"))
+			require.NoError(t, err)
+
+			playing := make(chan struct{})
+			announced := make(chan *base.Request, 1)
+
+			serverDone := make(chan struct{})
+			defer func() { <-serverDone }()
+			go func() {
+				defer close(serverDone)
+
+				conn, err := s.Accept()
+				require.NoError(t, err)
+				defer conn.Close()
+
+				onDescribe := func(ctx *ServerConnDescribeCtx) (*base.Response, []byte, error) {
+					return &base.Response{
+						StatusCode: base.StatusOK,
+					}, Tracks{track}.Write(), nil
+				}
+
+				onSetup := func(ctx *ServerConnSetupCtx) (*base.Response, error) {
+					return &base.Response{
+						StatusCode: base.StatusOK,
+					}, nil
+				}
+
+				onPlay := func(ctx *ServerConnPlayCtx) (*base.Response, error) {
+					return &base.Response{
+						StatusCode: base.StatusOK,
+					}, nil
+				}
+
+				readDone := conn.Read(ServerConnReadHandlers{
+					OnDescribe: onDescribe,
+					OnSetup:    onSetup,
+					OnPlay:     onPlay,
+				})
+
+				<-playing
+
+				res, err := conn.Request(&base.Request{
+					Method: base.Options,
+				})
+				require.NoError(t, err)
+				require.Equal(t, base.StatusOK, res.StatusCode)
+				require.Equal(t, base.HeaderValue{"OPTIONS, ANNOUNCE, REDIRECT"}, res.Header["Public"])
+
+				res, err = conn.Request(&base.Request{
+					Method: base.Announce,
+					Header: base.Header{
+						"Content-Type": base.HeaderValue{"application/sdp"},
+					},
+					Body: Tracks{track}.Write(),
+				})
+				require.NoError(t, err)
+				require.Equal(t, base.StatusOK, res.StatusCode)
+
+				<-readDone
+
+				_, err = conn.Request(&base.Request{
+					Method: base.Options,
+				})
+				require.Error(t, err)
+			}()
+
+			cconf := ClientConf{
+				StreamProtocol: func() *StreamProtocol {
+					if proto == "udp" {
+						v := StreamProtocolUDP
+						return &v
+					}
+					v := StreamProtocolTCP
+					return &v
+				}(),
+				OnAnnounce: func(req *base.Request) {
+					announced <- req
+				},
+			}
+
+			conn, err := cconf.DialRead("rtsp://localhost:8554/teststream")
+			require.NoError(t, err)
+
+			done := conn.ReadFrames(func(id int, typ StreamType, payload []byte) {
+			})
+			close(playing)
+
+			req := <-announced
+			require.Equal(t, base.Announce, req.Method)
+			require.Equal(t, base.MustParseURL("rtsp://localhost:8554/teststream"), req.URL)
+			require.Equal(t, []byte(Tracks{track}.Write()), req.Body)
+
+			conn.Close()
+			<-done
+		})
+	}
+}
+
+func TestServerReadPlayRange(t *testing.T) {
+	conf := ServerConf{}
+
+	s, err := conf.Serve("127.0.0.1:8554")
+	require.NoError(t, err)
+	defer s.Close()
+
+	track, err := NewTrackH264(96, []byte("123456"), []byte("123456"))
+	require.NoError(t, err)
+
+	ranges := make(chan *headers.Range, 2)
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		conn, err := s.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+
+		onDescribe := func(ctx *ServerConnDescribeCtx) (*base.Response, []byte, error) {
+			return &base.Response{
+				StatusCode: base.StatusOK,
+			}, Tracks{track}.Write(), nil
+		}
+
+		onSetup := func(ctx *ServerConnSetupCtx) (*base.Response, error) {
+			return &base.Response{
+				StatusCode: base.StatusOK,
+			}, nil
+		}
+
+		onPlay := func(ctx *ServerConnPlayCtx) (*base.Response, error) {
+			ranges <- ctx.Range
+			if ctx.Range != nil {
+				conn.SetTrackStart(0, 0x1234, 0x55667788)
+			}
+			return &base.Response{
+				StatusCode: base.StatusOK,
+			}, nil
+		}
+
+		onPause := func(ctx *ServerConnPauseCtx) (*base.Response, error) {
+			return &base.Response{
+				StatusCode: base.StatusOK,
+			}, nil
+		}
+
+		<-conn.Read(ServerConnReadHandlers{
+			OnDescribe: onDescribe,
+			OnSetup:    onSetup,
+			OnPlay:     onPlay,
+			OnPause:    onPause,
+		})
+	}()
+
+	cconf := ClientConf{
+		StreamProtocol: func() *StreamProtocol {
+			v := StreamProtocolTCP
+			return &v
+		}(),
+	}
+
+	conn, err := cconf.DialRead("rtsp://localhost:8554/teststream")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.Equal(t, (*headers.Range)(nil), <-ranges)
+
+	done := conn.ReadFrames(func(id int, typ StreamType, payload []byte) {
+	})
+
+	_, err = conn.Pause()
+	require.NoError(t, err)
+	<-done
+
+	ra := &headers.Range{
+		Value: &headers.RangeNPT{
+			Start: 5500 * time.Millisecond,
+		},
+	}
+
+	res, err := conn.Play(ra)
+	require.NoError(t, err)
+
+	require.Equal(t, ra, <-ranges)
+
+	require.Equal(t, base.HeaderValue{"npt=5.5-"}, res.Header["Range"])
+
+	var ri headers.RTPInfo
+	err = ri.Read(res.Header["RTP-Info"])
+	require.NoError(t, err)
+	require.Equal(t, headers.RTPInfo{
+		&headers.RTPInfoEntry{
+			URL:            base.MustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
+			SequenceNumber: 0x1234,
+			Timestamp:      0x55667788,
+		},
+	}, ri)
+}
+
+func TestServerReadStream(t *testing.T) {
+	conf := ServerConf{
+		UDPRTPAddress:  "127.0.0.1:8000",
+		UDPRTCPAddress: "127.0.0.1:8001",
+	}
+
+	s, err := conf.Serve("127.0.0.1:8554")
+	require.NoError(t, err)
+
+	track, err := NewTrackH264(96, []byte("123456"), []byte("123456"))
+	require.NoError(t, err)
+
+	stream := NewServerStream(Tracks{track})
+	defer stream.Close()
+
+	handleDone := make(chan struct{})
+	defer func() { <-handleDone }()
+	defer s.Close()
+
+	go func() {
+		defer close(handleDone)
+
+		s.Handle(ServerHandler{
+			OnConnOpen: func(sc *ServerConn) ServerConnReadHandlers {
+				return ServerConnReadHandlers{
+					OnDescribe: func(ctx *ServerConnDescribeCtx) (*base.Response, []byte, error) {
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, stream.Tracks().Write(), nil
+					},
+					OnSetup: func(ctx *ServerConnSetupCtx) (*base.Response, error) {
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, nil
+					},
+					OnPlay: func(ctx *ServerConnPlayCtx) (*base.Response, error) {
+						stream.AddReader(sc)
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, nil
+					},
+				}
+			},
+			OnConnClose: func(sc *ServerConn, err error) {
+				stream.RemoveReader(sc)
+			},
+		})
+	}()
+
+	var frameRecvs []chan struct{}
+
+	for _, proto := range []StreamProtocol{
+		StreamProtocolUDP,
+		StreamProtocolTCP,
+	} {
+		proto := proto
+		cconf := ClientConf{
+			StreamProtocol: &proto,
+		}
+
+		conn, err := cconf.DialRead("rtsp://localhost:8554/teststream")
+		require.NoError(t, err)
+		defer conn.Close()
+
+		frameRecv := make(chan struct{})
+		frameRecvs = append(frameRecvs, frameRecv)
+
+		var once sync.Once
+		conn.ReadFrames(func(id int, typ StreamType, payload []byte) {
+			if id == 0 && typ == StreamTypeRTP && bytes.Equal(payload, []byte{0x01, 0x02, 0x03, 0x04}) {
+				once.Do(func() { close(frameRecv) })
+			}
+		})
+	}
+
+	require.Equal(t, 2, stream.ReadersLen())
+
+	// write until all readers have received a frame, since the first
+	// frames can be discarded while readers are starting
+	writerTerminate := make(chan struct{})
+	writerDone := make(chan struct{})
+	go func() {
+		defer close(writerDone)
+
+		t := time.NewTicker(50 * time.Millisecond)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-t.C:
+				stream.WriteFrame(0, StreamTypeRTP, []byte{0x01, 0x02, 0x03, 0x04})
+			case <-writerTerminate:
+				return
+			}
+		}
+	}()
+
+	for _, frameRecv := range frameRecvs {
+		<-frameRecv
+	}
+
+	close(writerTerminate)
+	<-writerDone
+}
+
+func TestServerReadEndOfStream(t *testing.T) {
+	conf := ServerConf{
+		UDPRTPAddress:  "127.0.0.1:8000",
+		UDPRTCPAddress: "127.0.0.1:8001",
+	}
+
+	s, err := conf.Serve("127.0.0.1:8554")
+	require.NoError(t, err)
+
+	track, err := NewTrackH264(96, []byte("123456"), []byte("123456"))
+	require.NoError(t, err)
+
+	stream := NewServerStream(Tracks{track})
+	defer stream.Close()
+
+	handleDone := make(chan struct{})
+	defer func() { <-handleDone }()
+	defer s.Close()
+
+	go func() {
+		defer close(handleDone)
+
+		s.Handle(ServerHandler{
+			OnConnOpen: func(sc *ServerConn) ServerConnReadHandlers {
+				return ServerConnReadHandlers{
+					OnDescribe: func(ctx *ServerConnDescribeCtx) (*base.Response, []byte, error) {
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, stream.Tracks().Write(), nil
+					},
+					OnSetup: func(ctx *ServerConnSetupCtx) (*base.Response, error) {
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, nil
+					},
+					OnPlay: func(ctx *ServerConnPlayCtx) (*base.Response, error) {
+						stream.AddReader(sc)
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, nil
+					},
+				}
+			},
+			OnConnClose: func(sc *ServerConn, err error) {
+				stream.RemoveReader(sc)
+			},
+		})
+	}()
+
+	var dones []chan error
+	var endOfStreams []chan struct{}
+
+	for _, proto := range []StreamProtocol{
+		StreamProtocolUDP,
+		StreamProtocolTCP,
+	} {
+		proto := proto
+		endOfStream := make(chan struct{})
+		endOfStreams = append(endOfStreams, endOfStream)
+
+		cconf := ClientConf{
+			StreamProtocol: &proto,
+			OnEndOfStream: func() {
+				close(endOfStream)
+			},
+		}
+
+		conn, err := cconf.DialRead("rtsp://localhost:8554/teststream")
+		require.NoError(t, err)
+		defer conn.Close()
+
+		dones = append(dones, conn.ReadFrames(func(id int, typ StreamType, payload []byte) {
+		}))
+	}
+
+	require.Equal(t, 2, stream.ReadersLen())
+
+	// UDP listeners of the clients need some time to start
+	time.Sleep(500 * time.Millisecond)
+
+	stream.WriteFrame(0, StreamTypeRTP, []byte{
+		0x80, 0x60, 0x12, 0x34, 0x55, 0x66, 0x77, 0x88,
+		0x9d, 0xbb, 0x78, 0x12, 0x01, 0x02, 0x03, 0x04,
+	})
+	stream.EndOfStream()
+	require.Equal(t, 0, stream.ReadersLen())
+
+	for i, done := range dones {
+		<-endOfStreams[i]
+		require.Equal(t, liberrors.ErrClientEndOfStream{}, <-done)
+	}
+}
+
+func TestServerReadWriteQueueFull(t *testing.T) {
+	conf := ServerConf{
+		WriteBufferCount: 4,
+		WriteQueuePolicy: ServerWriteQueuePolicyDisconnect,
+	}
+
+	s, err := conf.Serve("127.0.0.1:8554")
+	require.NoError(t, err)
+	defer s.Close()
+
+	playDone := make(chan struct{})
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		conn, err := s.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+
+		writerDone := make(chan struct{})
+		defer func() { <-writerDone }()
+		writerTerminate := make(chan struct{})
+		defer close(writerTerminate)
+
+		onSetup := func(ctx *ServerConnSetupCtx) (*base.Response, error) {
+			return &base.Response{
+				StatusCode: base.StatusOK,
+			}, nil
+		}
+
+		onPlay := func(ctx *ServerConnPlayCtx) (*base.Response, error) {
+			go func() {
+				defer close(writerDone)
+
+				<-playDone
+
+				// the client is not reading, therefore the queue fills up
+				for {
+					select {
+					case <-writerTerminate:
+						return
+					default:
+					}
+
+					conn.WriteFrame(0, StreamTypeRTP, make([]byte, 60000))
+				}
+			}()
+
+			return &base.Response{
+				StatusCode: base.StatusOK,
+			}, nil
+		}
+
+		err = <-conn.Read(ServerConnReadHandlers{
+			OnSetup: onSetup,
+			OnPlay:  onPlay,
+		})
+		require.Equal(t, liberrors.ErrServerWriteQueueFull{}, err)
+		require.NotEqual(t, uint64(0), conn.DroppedFrames())
+	}()
+
+	conn, err := net.Dial("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer conn.Close()
+	bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+	err = base.Request{
+		Method: base.Setup,
+		URL:    base.MustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
+		Header: base.Header{
+			"CSeq": base.HeaderValue{"1"},
+			"Transport": headers.Transport{
+				Protocol: StreamProtocolTCP,
+				Delivery: func() *base.StreamDelivery {
+					v := base.StreamDeliveryUnicast
+					return &v
+				}(),
+				Mode: func() *headers.TransportMode {
+					v := headers.TransportModePlay
+					return &v
+				}(),
+				InterleavedIDs: &[2]int{0, 1},
+			}.Write(),
+		},
+	}.Write(bconn.Writer)
+	require.NoError(t, err)
+
+	var res base.Response
+	err = res.Read(bconn.Reader)
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	err = base.Request{
+		Method: base.Play,
+		URL:    base.MustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq": base.HeaderValue{"2"},
+		},
+	}.Write(bconn.Writer)
+	require.NoError(t, err)
+
+	err = res.Read(bconn.Reader)
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	close(playDone)
+
+	// do not read frames and wait for the server to close the connection
+	<-serverDone
+}
+
+func TestServerReadTryWriteFrame(t *testing.T) {
+	conf := ServerConf{
+		WriteBufferCount: 4,
+	}
+
+	s, err := conf.Serve("127.0.0.1:8554")
+	require.NoError(t, err)
+	defer s.Close()
+
+	playDone := make(chan struct{})
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		conn, err := s.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+
+		writerDone := make(chan struct{})
+
+		onSetup := func(ctx *ServerConnSetupCtx) (*base.Response, error) {
+			return &base.Response{
+				StatusCode: base.StatusOK,
+			}, nil
+		}
+
+		onPlay := func(ctx *ServerConnPlayCtx) (*base.Response, error) {
+			go func() {
+				defer close(writerDone)
+
+				<-playDone
+
+				// the client is not reading, therefore the queue fills up
+				for {
+					err := conn.TryWriteFrame(0, StreamTypeRTP, make([]byte, 60000))
+					if err != nil {
+						require.Equal(t, liberrors.ErrServerWouldBlock{}, err)
+						break
+					}
+				}
+
+				require.Equal(t, 4, conn.WriteQueueLen())
+				require.Equal(t, uint64(0), conn.DroppedFrames())
+				conn.NetConn().Close()
+			}()
+
+			return &base.Response{
+				StatusCode: base.StatusOK,
+			}, nil
+		}
+
+		<-conn.Read(ServerConnReadHandlers{
+			OnSetup: onSetup,
+			OnPlay:  onPlay,
+		})
+		<-writerDone
+	}()
+
+	conn, err := net.Dial("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer conn.Close()
+	bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+	err = base.Request{
+		Method: base.Setup,
+		URL:    base.MustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
+		Header: base.Header{
+			"CSeq": base.HeaderValue{"1"},
+			"Transport": headers.Transport{
+				Protocol: StreamProtocolTCP,
+				Delivery: func() *base.StreamDelivery {
+					v := base.StreamDeliveryUnicast
+					return &v
+				}(),
+				Mode: func() *headers.TransportMode {
+					v := headers.TransportModePlay
+					return &v
+				}(),
+				InterleavedIDs: &[2]int{0, 1},
+			}.Write(),
+		},
+	}.Write(bconn.Writer)
+	require.NoError(t, err)
+
+	var res base.Response
+	err = res.Read(bconn.Reader)
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	err = base.Request{
+		Method: base.Play,
+		URL:    base.MustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq": base.HeaderValue{"2"},
+		},
+	}.Write(bconn.Writer)
+	require.NoError(t, err)
+
+	err = res.Read(bconn.Reader)
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	close(playDone)
+
+	// do not read frames and wait for the writer to give up
+	<-serverDone
+}
+
+func TestServerReadWriteFrameCopy(t *testing.T) {
+	s, err := Serve("127.0.0.1:8554")
+	require.NoError(t, err)
+	defer s.Close()
+
+	playDone := make(chan struct{})
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		conn, err := s.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+
+		onSetup := func(ctx *ServerConnSetupCtx) (*base.Response, error) {
+			return &base.Response{
+				StatusCode: base.StatusOK,
+			}, nil
+		}
+
+		onPlay := func(ctx *ServerConnPlayCtx) (*base.Response, error) {
+			go func() {
+				<-playDone
+
+				// the payload can be reused after WriteFrame()
+				payload := []byte{0x01, 0x02, 0x03, 0x04}
+				conn.WriteFrame(0, StreamTypeRTP, payload)
+				copy(payload, []byte{0x05, 0x06, 0x07, 0x08})
+			}()
+
+			return &base.Response{
+				StatusCode: base.StatusOK,
+			}, nil
+		}
+
+		<-conn.Read(ServerConnReadHandlers{
+			OnSetup: onSetup,
+			OnPlay:  onPlay,
+		})
+	}()
+
+	conn, err := net.Dial("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer conn.Close()
+	bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+	err = base.Request{
+		Method: base.Setup,
+		URL:    base.MustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
+		Header: base.Header{
+			"CSeq": base.HeaderValue{"1"},
+			"Transport": headers.Transport{
+				Protocol: StreamProtocolTCP,
+				Delivery: func() *base.StreamDelivery {
+					v := base.StreamDeliveryUnicast
+					return &v
+				}(),
+				Mode: func() *headers.TransportMode {
+					v := headers.TransportModePlay
+					return &v
+				}(),
+				InterleavedIDs: &[2]int{0, 1},
+			}.Write(),
+		},
+	}.Write(bconn.Writer)
+	require.NoError(t, err)
+
+	var res base.Response
+	err = res.Read(bconn.Reader)
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	err = base.Request{
+		Method: base.Play,
+		URL:    base.MustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq": base.HeaderValue{"2"},
+		},
+	}.Write(bconn.Writer)
+	require.NoError(t, err)
+
+	err = res.Read(bconn.Reader)
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	close(playDone)
+
+	var fr base.InterleavedFrame
+	fr.Payload = make([]byte, 2048)
+	err = fr.Read(bconn.Reader)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x01, 0x02, 0x03, 0x04}, fr.Payload)
+}
+
+func TestServerReadUDPPortRange(t *testing.T) {
+	packetsReceived := make(chan struct{})
+
+	conf := ServerConf{
+		UDPPortRange: &[2]int{40000, 40003},
+	}
+
+	s, err := conf.Serve("127.0.0.1:8554")
+	require.NoError(t, err)
+	defer s.Close()
+
+	serverDone := make(chan struct{})
+	go func() {
+		defer close(serverDone)
+
+		conn, err := s.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+
+		onSetup := func(ctx *ServerConnSetupCtx) (*base.Response, error) {
+			return &base.Response{
+				StatusCode: base.StatusOK,
+			}, nil
+		}
+
+		onPlay := func(ctx *ServerConnPlayCtx) (*base.Response, error) {
+			return &base.Response{
+				StatusCode: base.StatusOK,
+			}, nil
+		}
+
+		onFrame := func(trackID int, typ StreamType, buf []byte) {
+			require.Equal(t, 1, trackID)
+			require.Equal(t, StreamTypeRTCP, typ)
+			require.Equal(t, []byte("\x01\x02\x03\x04"), buf)
+			close(packetsReceived)
+		}
+
+		<-conn.Read(ServerConnReadHandlers{
+			OnSetup: onSetup,
+			OnPlay:  onPlay,
+			OnFrame: onFrame,
+		})
+	}()
+
+	conn, err := net.Dial("tcp", "localhost:8554")
+	require.NoError(t, err)
+	bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+	var serverPorts [][2]int
+
+	for trackID := 0; trackID < 3; trackID++ {
+		inTH := &headers.Transport{
+			Protocol: StreamProtocolUDP,
+			Delivery: func() *base.StreamDelivery {
+				v := base.StreamDeliveryUnicast
+				return &v
+			}(),
+			Mode: func() *headers.TransportMode {
+				v := headers.TransportModePlay
+				return &v
+			}(),
+			ClientPorts: &[2]int{35466 + trackID*2, 35467 + trackID*2},
+		}
+
+		err = base.Request{
+			Method: base.Setup,
+			URL:    base.MustParseURL("rtsp://localhost:8554/teststream/trackID=" + strconv.FormatInt(int64(trackID), 10)),
+			Header: base.Header{
+				"CSeq":      base.HeaderValue{strconv.FormatInt(int64(trackID+1), 10)},
+				"Transport": inTH.Write(),
+			},
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		var res base.Response
+		err = res.Read(bconn.Reader)
+		require.NoError(t, err)
+
+		// the range contains only two couples of ports
+		if trackID == 2 {
+			require.Equal(t, base.StatusNotEnoughBandwidth, res.StatusCode)
+			break
+		}
+
+		require.Equal(t, base.StatusOK, res.StatusCode)
+
+		var th headers.Transport
+		err = th.Read(res.Header["Transport"])
+		require.NoError(t, err)
+		serverPorts = append(serverPorts, *th.ServerPorts)
+	}
+
+	require.Equal(t, [][2]int{{40000, 40001}, {40002, 40003}}, serverPorts)
+
+	err = base.Request{
+		Method: base.Play,
+		URL:    base.MustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq": base.HeaderValue{"4"},
+		},
+	}.Write(bconn.Writer)
+	require.NoError(t, err)
+
+	var res base.Response
+	err = res.Read(bconn.Reader)
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	// packets are accepted from any port of the client, like the ones
+	// that are rewritten by a symmetric NAT
+	l1, err := net.ListenPacket("udp", "localhost:35500")
+	require.NoError(t, err)
+	defer l1.Close()
+
+	l1.WriteTo([]byte("\x01\x02\x03\x04"), &net.UDPAddr{
+		IP:   net.ParseIP("127.0.0.1"),
+		Port: 40003,
+	})
+
+	<-packetsReceived
+
+	conn.Close()
+	<-serverDone
+
+	// ports are released when the connection is closed
+	l2, err := net.ListenPacket("udp", "127.0.0.1:40000")
+	require.NoError(t, err)
+	l2.Close()
+}
+
+func TestServerReadRTPInfo(t *testing.T) {
+	s, err := Serve("127.0.0.1:8554")
+	require.NoError(t, err)
+
+	track, err := NewTrackH264(96, []byte("123456"), []byte("123456"))
+	require.NoError(t, err)
+
+	stream := NewServerStream(Tracks{track})
+	defer stream.Close()
+
+	handleDone := make(chan struct{})
+	defer func() { <-handleDone }()
+	defer s.Close()
+
+	go func() {
+		defer close(handleDone)
+
+		s.Handle(ServerHandler{
+			OnConnOpen: func(sc *ServerConn) ServerConnReadHandlers {
+				return ServerConnReadHandlers{
+					OnDescribe: func(ctx *ServerConnDescribeCtx) (*base.Response, []byte, error) {
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, stream.Tracks().Write(), nil
+					},
+					OnSetup: func(ctx *ServerConnSetupCtx) (*base.Response, error) {
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, nil
+					},
+					OnPlay: func(ctx *ServerConnPlayCtx) (*base.Response, error) {
+						stream.AddReader(sc)
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, nil
+					},
+				}
+			},
+			OnConnClose: func(sc *ServerConn, err error) {
+				stream.RemoveReader(sc)
+			},
+		})
+	}()
+
+	stream.WriteFrame(0, StreamTypeRTP, []byte{
+		0x80, 0x60, 0x12, 0x34, 0x55, 0x66, 0x77, 0x88,
+		0x9d, 0xbb, 0x78, 0x12, 0x01, 0x02, 0x03, 0x04,
+	})
+
+	v := StreamProtocolTCP
+	cconf := ClientConf{
+		StreamProtocol: &v,
+	}
+
+	conn, err := cconf.DialRead("rtsp://localhost:8554/teststream")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.Equal(t, &headers.RTPInfo{
+		&headers.RTPInfoEntry{
+			URL:            base.MustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
+			SequenceNumber: 0x1235,
+			Timestamp:      0x55667788,
+		},
+	}, conn.RTPInfo())
+}
+
+func TestServerReadIPv6(t *testing.T) {
+	s, err := ServerConf{
+		UDPRTPAddress:  "[::1]:8000",
+		UDPRTCPAddress: "[::1]:8001",
+		AddressFamily:  ServerAddressFamilyIPv6,
+	}.Serve("[::1]:8554")
+	require.NoError(t, err)
+
+	track, err := NewTrackH264(96, []byte("123456"), []byte("123456"))
+	require.NoError(t, err)
+
+	stream := NewServerStream(Tracks{track})
+	defer stream.Close()
+
+	handleDone := make(chan struct{})
+	defer func() { <-handleDone }()
+	defer s.Close()
+
+	go func() {
+		defer close(handleDone)
+
+		s.Handle(ServerHandler{
+			OnConnOpen: func(sc *ServerConn) ServerConnReadHandlers {
+				return ServerConnReadHandlers{
+					OnDescribe: func(ctx *ServerConnDescribeCtx) (*base.Response, []byte, error) {
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, stream.Tracks().Write(), nil
+					},
+					OnSetup: func(ctx *ServerConnSetupCtx) (*base.Response, error) {
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, nil
+					},
+					OnPlay: func(ctx *ServerConnPlayCtx) (*base.Response, error) {
+						stream.AddReader(sc)
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, nil
+					},
+				}
+			},
+			OnConnClose: func(sc *ServerConn, err error) {
+				stream.RemoveReader(sc)
+			},
+		})
+	}()
+
+	v := StreamProtocolUDP
+	conn, err := ClientConf{
+		StreamProtocol: &v,
+	}.DialRead("rtsp://[::1]:8554/teststream")
+	require.NoError(t, err)
+
+	frameRecv := make(chan []byte, 1)
+	done := conn.ReadFrames(func(trackID int, streamType StreamType, payload []byte) {
+		if streamType == StreamTypeRTP {
+			select {
+			case frameRecv <- append([]byte(nil), payload...):
+			default:
+			}
+		}
+	})
+
+	defer func() {
+		conn.Close()
+		<-done
+	}()
+
+	pkt := []byte{
+		0x80, 0x60, 0x12, 0x34, 0x55, 0x66, 0x77, 0x88,
+		0x9d, 0xbb, 0x78, 0x12, 0x01, 0x02, 0x03, 0x04,
+	}
+
+	// frames are written until the reader has been added to the stream
+	for {
+		stream.WriteFrame(0, StreamTypeRTP, pkt)
+
+		select {
+		case recv := <-frameRecv:
+			require.Equal(t, pkt, recv)
+			return
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func TestServerReadSenderReports(t *testing.T) {
+	conf := ServerConf{
+		SenderReportPeriod: 200 * time.Millisecond,
+	}
+
+	s, err := conf.Serve("127.0.0.1:8554")
+	require.NoError(t, err)
+
+	track, err := NewTrackH264(96, []byte("123456"), []byte("123456"))
+	require.NoError(t, err)
+
+	stream := NewServerStream(Tracks{track})
+	defer stream.Close()
+
+	handleDone := make(chan struct{})
+	defer func() { <-handleDone }()
+	defer s.Close()
+
+	go func() {
+		defer close(handleDone)
+
+		s.Handle(ServerHandler{
+			OnConnOpen: func(sc *ServerConn) ServerConnReadHandlers {
+				return ServerConnReadHandlers{
+					OnDescribe: func(ctx *ServerConnDescribeCtx) (*base.Response, []byte, error) {
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, stream.Tracks().Write(), nil
+					},
+					OnSetup: func(ctx *ServerConnSetupCtx) (*base.Response, error) {
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, nil
+					},
+					OnPlay: func(ctx *ServerConnPlayCtx) (*base.Response, error) {
+						stream.AddReader(sc)
+						return &base.Response{
+							StatusCode: base.StatusOK,
+						}, nil
+					},
+				}
+			},
+			OnConnClose: func(sc *ServerConn, err error) {
+				stream.RemoveReader(sc)
+			},
+		})
+	}()
+
+	v := StreamProtocolTCP
+	cconf := ClientConf{
+		StreamProtocol: &v,
+	}
+
+	conn, err := cconf.DialRead("rtsp://localhost:8554/teststream")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	reportRecv := make(chan *rtcp.SenderReport, 1)
+	conn.ReadPackets(nil, func(trackID int, pkts []rtcp.Packet) {
+		if sr, ok := pkts[0].(*rtcp.SenderReport); ok {
+			select {
+			case reportRecv <- sr:
+			default:
+			}
+		}
+	})
+
+	stream.WriteFrame(0, StreamTypeRTP, []byte{
+		0x80, 0x60, 0x12, 0x34, 0x55, 0x66, 0x77, 0x88,
+		0x9d, 0xbb, 0x78, 0x12, 0x01, 0x02, 0x03, 0x04,
+	})
+
+	sr := <-reportRecv
+	require.Equal(t, uint32(0x9dbb7812), sr.SSRC)
+	require.Equal(t, uint32(1), sr.PacketCount)
+	require.Equal(t, uint32(4), sr.OctetCount)
+}
+
+func TestServerReadTCPInterleavedIDs(t *testing.T) {
+	s, err := Serve("127.0.0.1:8554")
+	require.NoError(t, err)
+	defer s.Close()
+
+	frameRecv := make(chan struct{})
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		for i := 0; i < 2; i++ {
+			conn, err := s.Accept()
+			require.NoError(t, err)
+
+			onSetup := func(ctx *ServerConnSetupCtx) (*base.Response, error) {
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil
+			}
+
+			onPlay := func(ctx *ServerConnPlayCtx) (*base.Response, error) {
+				require.Equal(t, &[2]int{6, 7}, conn.SetuppedTracks()[0].InterleavedIDs())
+				require.Equal(t, &[2]int{0, 1}, conn.SetuppedTracks()[1].InterleavedIDs())
+
+				go func() {
+					time.Sleep(500 * time.Millisecond)
+					conn.WriteFrame(0, StreamTypeRTP, []byte("\x00\x00\x00\x00"))
+					conn.WriteFrame(1, StreamTypeRTCP, []byte("\x01\x01\x01\x01"))
+				}()
+
+				return &base.Response{
+					StatusCode: base.StatusOK,
+				}, nil
+			}
+
+			onFrame := func(trackID int, typ StreamType, buf []byte) {
+				require.Equal(t, 0, trackID)
+				require.Equal(t, StreamTypeRTCP, typ)
+				require.Equal(t, []byte("\x01\x02\x03\x04"), buf)
+				close(frameRecv)
+			}
+
+			err = <-conn.Read(ServerConnReadHandlers{
+				OnSetup: onSetup,
+				OnPlay:  onPlay,
+				OnFrame: onFrame,
+			})
+			if i == 0 {
+				require.Equal(t, liberrors.ErrServerTransportHeaderInvalidInterleavedIDs{
+					Value: [2]int{7, 8}}, err)
+			}
+
+			conn.Close()
+		}
+	}()
+
+	setup := func(bconn *bufio.ReadWriter, cseq int, trackID int,
+		ids [2]int, session base.HeaderValue) *base.Response {
+		header := base.Header{
+			"CSeq": base.HeaderValue{strconv.FormatInt(int64(cseq), 10)},
+			"Transport": headers.Transport{
+				Protocol: StreamProtocolTCP,
+				Delivery: func() *base.StreamDelivery {
+					v := base.StreamDeliveryUnicast
+					return &v
+				}(),
+				Mode: func() *headers.TransportMode {
+					v := headers.TransportModePlay
+					return &v
+				}(),
+				InterleavedIDs: &ids,
+			}.Write(),
+		}
+		if session != nil {
+			header["Session"] = session
+		}
+
+		err := base.Request{
+			Method: base.Setup,
+			URL: base.MustParseURL("rtsp://localhost:8554/teststream/trackID=" +
+				strconv.FormatInt(int64(trackID), 10)),
+			Header: header,
+		}.Write(bconn.Writer)
+		require.NoError(t, err)
+
+		var res base.Response
+		err = res.Read(bconn.Reader)
+		require.NoError(t, err)
+		return &res
+	}
+
+	func() {
+		conn, err := net.Dial("tcp", "localhost:8554")
+		require.NoError(t, err)
+		defer conn.Close()
+		bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+		res := setup(bconn, 1, 0, [2]int{6, 7}, nil)
+		require.Equal(t, base.StatusOK, res.StatusCode)
+
+		// IDs in use by another track
+		res = setup(bconn, 2, 1, [2]int{7, 8}, res.Header["Session"])
+		require.Equal(t, base.StatusBadRequest, res.StatusCode)
+	}()
+
+	conn, err := net.Dial("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer conn.Close()
+	bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+	res := setup(bconn, 1, 0, [2]int{6, 7}, nil)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	var th headers.Transport
+	err = th.Read(res.Header["Transport"])
+	require.NoError(t, err)
+	require.Equal(t, &[2]int{6, 7}, th.InterleavedIDs)
+
+	session := res.Header["Session"]
+
+	res = setup(bconn, 2, 1, [2]int{0, 1}, session)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	err = base.Request{
+		Method: base.Play,
+		URL:    base.MustParseURL("rtsp://localhost:8554/teststream"),
+		Header: base.Header{
+			"CSeq":    base.HeaderValue{"3"},
+			"Session": session,
+		},
+	}.Write(bconn.Writer)
+	require.NoError(t, err)
+
+	err = res.Read(bconn.Reader)
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	// frames are written to the channels chosen by the client.
+	// InterleavedFrame stores the channel into TrackID and StreamType.
+	var fr base.InterleavedFrame
+	fr.Payload = make([]byte, 2048)
+	err = fr.Read(bconn.Reader)
+	require.NoError(t, err)
+	require.Equal(t, 3, fr.TrackID)
+	require.Equal(t, StreamTypeRTP, fr.StreamType)
+
+	fr.Payload = make([]byte, 2048)
+	err = fr.Read(bconn.Reader)
+	require.NoError(t, err)
+	require.Equal(t, 0, fr.TrackID)
+	require.Equal(t, StreamTypeRTCP, fr.StreamType)
+
+	err = base.InterleavedFrame{
+		TrackID:    3,
+		StreamType: StreamTypeRTCP,
+		Payload:    []byte("\x01\x02\x03\x04"),
+	}.Write(bconn.Writer)
+	require.NoError(t, err)
+
+	<-frameRecv
+}
+
+func TestServerReadSetupTransportAlternatives(t *testing.T) {
+	s, err := Serve("127.0.0.1:8554")
+	require.NoError(t, err)
+	defer s.Close()
+
+	serverDone := make(chan struct{})
+	defer func() { <-serverDone }()
+	go func() {
+		defer close(serverDone)
+
+		conn, err := s.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+
+		onSetup := func(ctx *ServerConnSetupCtx) (*base.Response, error) {
+			require.Equal(t, StreamProtocolTCP, ctx.Transport.Protocol)
+			return &base.Response{
+				StatusCode: base.StatusOK,
+			}, nil
+		}
+
+		<-conn.Read(ServerConnReadHandlers{
+			OnSetup: onSetup,
+		})
+	}()
+
+	conn, err := net.Dial("tcp", "localhost:8554")
+	require.NoError(t, err)
+	defer conn.Close()
+	bconn := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+	// UDP is not available, therefore the server chooses TCP
+	err = base.Request{
+		Method: base.Setup,
+		URL:    base.MustParseURL("rtsp://localhost:8554/teststream/trackID=0"),
+		Header: base.Header{
+			"CSeq": base.HeaderValue{"1"},
+			"Transport": headers.Transports{
+				{
+					Protocol: StreamProtocolUDP,
+					Delivery: func() *base.StreamDelivery {
+						v := base.StreamDeliveryUnicast
+						return &v
+					}(),
+					ClientPorts: &[2]int{35466, 35467},
+				},
+				{
+					Protocol: StreamProtocolTCP,
+					Delivery: func() *base.StreamDelivery {
+						v := base.StreamDeliveryUnicast
+						return &v
+					}(),
+					InterleavedIDs: &[2]int{0, 1},
+				},
+			}.Write(),
+		},
+	}.Write(bconn.Writer)
+	require.NoError(t, err)
+
+	var res base.Response
+	err = res.Read(bconn.Reader)
+	require.NoError(t, err)
+	require.Equal(t, base.StatusOK, res.StatusCode)
+
+	var th headers.Transport
+	err = th.Read(res.Header["Transport"])
+	require.NoError(t, err)
+	require.Equal(t, StreamProtocolTCP, th.Protocol)
+	require.Equal(t, &[2]int{0, 1}, th.InterleavedIDs)
+}
@@ -0,0 +1,51 @@
+package gortsplib
+
+import (
+	"net"
+	"sync"
+
+	"github.com/majoyz/gortsplib/pkg/base"
+)
+
+// serverFrame is an outgoing frame.
+// Frames are recycled through serverFramePool, in order to avoid
+// allocating memory for every frame written to clients.
+type serverFrame struct {
+	base.InterleavedFrame
+
+	// destination, used only with UDP
+	addr *net.UDPAddr
+
+	// buffer that contains a copy of the payload, reused between frames
+	buf []byte
+}
+
+var serverFramePool = sync.Pool{
+	New: func() interface{} {
+		return &serverFrame{}
+	},
+}
+
+// newServerFrame gets a frame from the pool.
+// If copyPayload is true, the payload is copied into a buffer owned by the frame.
+func newServerFrame(trackID int, streamType StreamType, payload []byte, copyPayload bool) *serverFrame {
+	f := serverFramePool.Get().(*serverFrame)
+	f.TrackID = trackID
+	f.StreamType = streamType
+
+	if copyPayload {
+		f.buf = append(f.buf[:0], payload...)
+		f.Payload = f.buf
+	} else {
+		f.Payload = payload
+	}
+
+	return f
+}
+
+// release puts the frame back into the pool.
+func (f *serverFrame) release() {
+	f.Payload = nil
+	f.addr = nil
+	serverFramePool.Put(f)
+}
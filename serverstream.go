@@ -0,0 +1,126 @@
+package gortsplib
+
+import (
+	"sync"
+
+	"github.com/majoyz/gortsplib/pkg/rtcpcompound"
+)
+
+// ServerStream is a stream that can be read by multiple clients.
+// It routes frames to all the readers that have been added to it,
+// with the protocol chosen by each reader (UDP or TCP).
+// Frames written to readers that are using TCP are queued and, if a reader
+// is too slow, the oldest ones are discarded, in order not to block the
+// other readers.
+type ServerStream struct {
+	tracks    Tracks
+	rtpStates []*serverRTPState
+
+	mutex   sync.RWMutex
+	readers map[*ServerConn]struct{}
+}
+
+// NewServerStream allocates a ServerStream.
+func NewServerStream(tracks Tracks) *ServerStream {
+	rtpStates := make([]*serverRTPState, len(tracks))
+	for i := range rtpStates {
+		rtpStates[i] = &serverRTPState{}
+	}
+
+	return &ServerStream{
+		tracks:    tracks,
+		rtpStates: rtpStates,
+		readers:   make(map[*ServerConn]struct{}),
+	}
+}
+
+// Tracks returns the tracks of the stream.
+func (st *ServerStream) Tracks() Tracks {
+	return st.tracks
+}
+
+// AddReader adds a reader to the stream.
+// It must be called after the reader has set up the tracks,
+// usually inside OnPlay, in order to fill the RTP-Info header
+// of the PLAY response with the state of the stream.
+func (st *ServerStream) AddReader(sc *ServerConn) {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+
+	for trackID, track := range sc.setuppedTracks {
+		if trackID >= len(st.rtpStates) {
+			continue
+		}
+
+		if sequenceNumber, timestamp, ok := st.rtpStates[trackID].get(); ok {
+			track.rtpState.set(sequenceNumber, timestamp)
+		}
+	}
+
+	sc.initRTCPSenders(st.tracks)
+
+	st.readers[sc] = struct{}{}
+}
+
+// RemoveReader removes a reader from the stream.
+// It must be called when the reader pauses or disconnects.
+func (st *ServerStream) RemoveReader(sc *ServerConn) {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+
+	delete(st.readers, sc)
+}
+
+// ReadersLen returns the number of readers of the stream.
+func (st *ServerStream) ReadersLen() int {
+	st.mutex.RLock()
+	defer st.mutex.RUnlock()
+
+	return len(st.readers)
+}
+
+// Close removes all the readers of the stream.
+func (st *ServerStream) Close() {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+
+	st.readers = make(map[*ServerConn]struct{})
+}
+
+// EndOfStream notifies the readers that the stream has ended, by sending
+// them a RTCP BYE packet for each track, and removes all the readers.
+// It is usually called when the publisher of the stream sends a RTCP BYE
+// packet or tears down the session.
+// Readers implemented with ClientConn stop reading and return
+// liberrors.ErrClientEndOfStream.
+func (st *ServerStream) EndOfStream() {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+
+	for trackID, s := range st.rtpStates {
+		byts, err := rtcpcompound.Bye(s.getSSRC(), "", "end of stream")
+		if err != nil {
+			continue
+		}
+
+		for sc := range st.readers {
+			sc.WriteFrame(trackID, StreamTypeRTCP, byts)
+		}
+	}
+
+	st.readers = make(map[*ServerConn]struct{})
+}
+
+// WriteFrame writes a frame to all the readers of the stream that have set up the track.
+func (st *ServerStream) WriteFrame(trackID int, streamType StreamType, payload []byte) {
+	st.mutex.RLock()
+	defer st.mutex.RUnlock()
+
+	if streamType == StreamTypeRTP && trackID < len(st.rtpStates) {
+		st.rtpStates[trackID].update(payload)
+	}
+
+	for sc := range st.readers {
+		sc.WriteFrame(trackID, streamType, payload)
+	}
+}
@@ -1,25 +1,20 @@
 package gortsplib
 
 import (
+	"fmt"
 	"net"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/majoyz/gortsplib/pkg/liberrors"
 	"github.com/majoyz/gortsplib/pkg/multibuffer"
 	"github.com/majoyz/gortsplib/pkg/ringbuffer"
 )
 
-const (
-	serverConnUDPListenerKernelReadBufferSize = 0x80000 // same as gstreamer's rtspsrc
-)
-
-type bufAddrPair struct {
-	buf  []byte
-	addr *net.UDPAddr
-}
-
 type clientData struct {
+	ip           net.IP
 	sc           *ServerConn
 	trackID      int
 	isPublishing bool
@@ -43,13 +38,21 @@ func (p *clientAddr) fill(ip net.IP, port int) {
 
 type serverUDPListener struct {
 	pc           *net.UDPConn
+	batchConn    *udpBatchConn
 	streamType   StreamType
 	writeTimeout time.Duration
+	logger       Logger
 	readBuf      *multibuffer.MultiBuffer
+	readBatch    int
 	clientsMutex sync.RWMutex
 	clients      map[clientAddr]*clientData
 	ringBuffer   *ringbuffer.RingBuffer
 
+	// dedicated listeners serve a single track of a single client,
+	// and accept packets from any port of the client.
+	dedicated       bool
+	dedicatedClient *clientData
+
 	// out
 	done chan struct{}
 }
@@ -57,28 +60,45 @@ type serverUDPListener struct {
 func newServerUDPListener(
 	conf ServerConf,
 	address string,
-	streamType StreamType) (*serverUDPListener, error) {
+	streamType StreamType,
+	dedicated bool) (*serverUDPListener, error) {
 
-	tmp, err := net.ListenPacket("udp", address)
+	tmp, err := net.ListenPacket(conf.AddressFamily.network("udp"), address)
 	if err != nil {
 		return nil, err
 	}
 	pc := tmp.(*net.UDPConn)
 
-	err = pc.SetReadBuffer(serverConnUDPListenerKernelReadBufferSize)
+	err = setUDPBufferSizes(pc, conf.UDPReadBufferSize, conf.UDPWriteBufferSize)
+	if err != nil {
+		pc.Close()
+		return nil, err
+	}
+
+	batchConn, err := newUDPBatchConn(pc)
 	if err != nil {
+		pc.Close()
 		return nil, err
 	}
 
 	s := &serverUDPListener{
-		pc:      pc,
-		clients: make(map[clientAddr]*clientData),
-		done:    make(chan struct{}),
+		pc:        pc,
+		batchConn: batchConn,
+		clients:   make(map[clientAddr]*clientData),
+		dedicated: dedicated,
+		done:      make(chan struct{}),
 	}
 
 	s.streamType = streamType
 	s.writeTimeout = conf.WriteTimeout
+	s.logger = conf.Logger
 	s.readBuf = multibuffer.New(uint64(conf.ReadBufferCount), uint64(conf.ReadBufferSize))
+
+	// packets read together must use different buffers
+	s.readBatch = udpBatchSize
+	if conf.ReadBufferCount < s.readBatch {
+		s.readBatch = conf.ReadBufferCount
+	}
 	s.ringBuffer = ringbuffer.New(uint64(conf.ReadBufferCount))
 
 	go s.run()
@@ -101,32 +121,23 @@ func (s *serverUDPListener) run() {
 	go func() {
 		defer wg.Done()
 
+		msgs := make([]udpMessage, s.readBatch)
+		for i := range msgs {
+			msgs[i].buf = s.readBuf.Next()
+		}
+
 		for {
-			buf := s.readBuf.Next()
-			n, addr, err := s.pc.ReadFromUDP(buf)
+			n, err := s.batchConn.readBatch(msgs)
 			if err != nil {
 				break
 			}
 
-			func() {
-				s.clientsMutex.RLock()
-				defer s.clientsMutex.RUnlock()
-
-				var clientAddr clientAddr
-				clientAddr.fill(addr.IP, addr.Port)
-				clientData, ok := s.clients[clientAddr]
-				if !ok {
-					return
-				}
-
-				if clientData.isPublishing {
-					now := time.Now()
-					atomic.StoreInt64(clientData.sc.announcedTracks[clientData.trackID].udpLastFrameTime, now.Unix())
-					clientData.sc.announcedTracks[clientData.trackID].rtcpReceiver.ProcessFrame(now, s.streamType, buf[:n])
-				}
+			for i := 0; i < n; i++ {
+				s.processPacket(msgs[i].buf[:msgs[i].n], &msgs[i].addr)
 
-				clientData.sc.readHandlers.OnFrame(clientData.trackID, s.streamType, buf[:n])
-			}()
+				// the buffer may still be in use by OnFrame, get a new one
+				msgs[i].buf = s.readBuf.Next()
+			}
 		}
 	}()
 
@@ -134,49 +145,237 @@ func (s *serverUDPListener) run() {
 	go func() {
 		defer wg.Done()
 
+		frames := make([]*serverFrame, 0, udpBatchSize)
+		msgs := make([]udpMessage, udpBatchSize)
+
 		for {
 			tmp, ok := s.ringBuffer.Pull()
 			if !ok {
 				return
 			}
-			pair := tmp.(bufAddrPair)
+			frames = append(frames[:0], tmp.(*serverFrame))
+
+			// write together all the frames that are already available
+			for len(frames) < udpBatchSize {
+				tmp, ok := s.ringBuffer.TryPull()
+				if !ok {
+					break
+				}
+				frames = append(frames, tmp.(*serverFrame))
+			}
+
+			for i, f := range frames {
+				msgs[i].buf = f.Payload
+				msgs[i].addr = *f.addr
+			}
 
 			s.pc.SetWriteDeadline(time.Now().Add(s.writeTimeout))
-			s.pc.WriteTo(pair.buf, pair.addr)
+			err := s.batchConn.writeBatch(msgs[:len(frames)])
+			if err != nil {
+				s.logger.Errorf("unable to write UDP packets: %s", err)
+			}
+
+			for i, f := range frames {
+				msgs[i].buf = nil
+				f.release()
+			}
 		}
 	}()
 
 	wg.Wait()
 }
 
+func (s *serverUDPListener) processPacket(buf []byte, addr *net.UDPAddr) {
+	s.clientsMutex.RLock()
+	defer s.clientsMutex.RUnlock()
+
+	var clientData *clientData
+
+	if s.dedicated {
+		clientData = s.dedicatedClient
+		if clientData == nil {
+			return
+		}
+
+		if !clientData.ip.Equal(addr.IP) {
+			if clientData.isPublishing {
+				clientData.sc.onUDPPacketDiscarded(liberrors.ErrServerUDPSourceUnexpected{
+					TrackID: clientData.trackID,
+					Address: addr.String(),
+				})
+			}
+			return
+		}
+
+	} else {
+		var clientAddr clientAddr
+		clientAddr.fill(addr.IP, addr.Port)
+		var ok bool
+		clientData, ok = s.clients[clientAddr]
+		if !ok {
+			return
+		}
+	}
+
+	payload, err := srtpDecryptFrame(clientData.sc.setuppedTracks[clientData.trackID].srtpContext,
+		s.streamType, buf)
+	if err != nil {
+		clientData.sc.onUDPPacketDiscarded(liberrors.ErrServerSRTPDecryptFailed{
+			TrackID: clientData.trackID,
+			Err:     err,
+		})
+		return
+	}
+
+	if !clientData.isPublishing {
+		atomic.StoreInt64(clientData.sc.lastActivity, time.Now().Unix())
+		clientData.sc.readHandlers.OnFrame(clientData.trackID, s.streamType, payload)
+		return
+	}
+
+	track := clientData.sc.announcedTracks[clientData.trackID]
+
+	if s.streamType == StreamTypeRTP {
+		if expected, ssrc, ok := track.ssrcFilter.check(payload); !ok {
+			clientData.sc.onUDPPacketDiscarded(liberrors.ErrServerRTPSSRCUnexpected{
+				TrackID:  clientData.trackID,
+				Expected: expected,
+				Value:    ssrc,
+			})
+			return
+		}
+	}
+
+	now := time.Now()
+	atomic.StoreInt64(track.udpLastFrameTime, now.Unix())
+
+	if s.streamType == StreamTypeRTP && track.rtpReorderer != nil {
+		track.rtpReorderer.process(payload, func(payload []byte) {
+			track.rtcpReceiver.ProcessFrame(now, s.streamType, payload)
+			clientData.sc.readHandlers.OnFrame(clientData.trackID, s.streamType, payload)
+		})
+		return
+	}
+
+	track.rtcpReceiver.ProcessFrame(now, s.streamType, payload)
+	clientData.sc.readHandlers.OnFrame(clientData.trackID, s.streamType, payload)
+}
+
 func (s *serverUDPListener) port() int {
 	return s.pc.LocalAddr().(*net.UDPAddr).Port
 }
 
-func (s *serverUDPListener) write(buf []byte, addr *net.UDPAddr) {
-	s.ringBuffer.Push(bufAddrPair{buf, addr})
+func (s *serverUDPListener) write(f *serverFrame, addr *net.UDPAddr) {
+	f.addr = addr
+	s.ringBuffer.Push(f)
 }
 
 func (s *serverUDPListener) addClient(ip net.IP, port int, sc *ServerConn, trackID int, isPublishing bool) {
 	s.clientsMutex.Lock()
 	defer s.clientsMutex.Unlock()
 
-	var addr clientAddr
-	addr.fill(ip, port)
-
-	s.clients[addr] = &clientData{
+	cd := &clientData{
+		ip:           ip,
 		sc:           sc,
 		trackID:      trackID,
 		isPublishing: isPublishing,
 	}
+
+	if s.dedicated {
+		s.dedicatedClient = cd
+		return
+	}
+
+	var addr clientAddr
+	addr.fill(ip, port)
+
+	s.clients[addr] = cd
 }
 
 func (s *serverUDPListener) removeClient(ip net.IP, port int) {
 	s.clientsMutex.Lock()
 	defer s.clientsMutex.Unlock()
 
+	if s.dedicated {
+		s.dedicatedClient = nil
+		return
+	}
+
 	var addr clientAddr
 	addr.fill(ip, port)
 
 	delete(s.clients, addr)
 }
+
+// serverUDPPortRange allocates couples of dedicated UDP listeners
+// from a port range.
+type serverUDPPortRange struct {
+	conf  ServerConf
+	host  string
+	first int
+	last  int
+
+	mutex sync.Mutex
+	next  int
+}
+
+func newServerUDPPortRange(conf ServerConf, address string) (*serverUDPPortRange, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	// RTP ports must be even
+	first := conf.UDPPortRange[0]
+	if (first % 2) != 0 {
+		first++
+	}
+	last := conf.UDPPortRange[1]
+
+	if first <= 0 || last > 65535 || (last-first) < 1 {
+		return nil, fmt.Errorf("invalid UDP port range (%d-%d)",
+			conf.UDPPortRange[0], conf.UDPPortRange[1])
+	}
+
+	return &serverUDPPortRange{
+		conf:  conf,
+		host:  host,
+		first: first,
+		last:  last,
+		next:  first,
+	}, nil
+}
+
+// listen allocates a RTP and a RTCP listener on two consecutive free ports.
+func (r *serverUDPPortRange) listen() (*serverUDPListener, *serverUDPListener, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	count := (r.last - r.first + 1) / 2
+
+	for i := 0; i < count; i++ {
+		rtpPort := r.next
+
+		r.next += 2
+		if (r.next + 1) > r.last {
+			r.next = r.first
+		}
+
+		rtpListener, err := newServerUDPListener(r.conf,
+			net.JoinHostPort(r.host, strconv.FormatInt(int64(rtpPort), 10)), StreamTypeRTP, true)
+		if err != nil {
+			continue
+		}
+
+		rtcpListener, err := newServerUDPListener(r.conf,
+			net.JoinHostPort(r.host, strconv.FormatInt(int64(rtpPort+1), 10)), StreamTypeRTCP, true)
+		if err != nil {
+			rtpListener.close()
+			continue
+		}
+
+		return rtpListener, rtcpListener, nil
+	}
+
+	return nil, nil, fmt.Errorf("no UDP ports available")
+}
@@ -0,0 +1,31 @@
+package gortsplib
+
+import (
+	"github.com/majoyz/gortsplib/pkg/srtp"
+)
+
+// srtpEncryptFrame encrypts a frame with SRTP or SRTCP.
+// If ctx is nil, the frame is returned untouched.
+func srtpEncryptFrame(ctx *srtp.Context, streamType StreamType, payload []byte) ([]byte, error) {
+	if ctx == nil {
+		return payload, nil
+	}
+
+	if streamType == StreamTypeRTP {
+		return ctx.EncryptRTP(payload)
+	}
+	return ctx.EncryptRTCP(payload)
+}
+
+// srtpDecryptFrame decrypts a SRTP or SRTCP frame.
+// If ctx is nil, the frame is returned untouched.
+func srtpDecryptFrame(ctx *srtp.Context, streamType StreamType, payload []byte) ([]byte, error) {
+	if ctx == nil {
+		return payload, nil
+	}
+
+	if streamType == StreamTypeRTP {
+		return ctx.DecryptRTP(payload)
+	}
+	return ctx.DecryptRTCP(payload)
+}
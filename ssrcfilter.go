@@ -0,0 +1,81 @@
+package gortsplib
+
+const (
+	// number of consecutive packets with a new SSRC after which
+	// a learned SSRC is replaced.
+	ssrcFilterRelearnCount = 8
+)
+
+// ssrcFilter checks the SSRC of RTP packets received with UDP, in order to
+// discard packets of other streams that are sent to the same port.
+// When the expected SSRC is provided by the Transport header, packets with
+// a different SSRC are always discarded. Otherwise, the SSRC is learned
+// from the first received packet, and it is learned again when several
+// consecutive packets come from another source, that happens when the
+// counterpart restarts the stream.
+type ssrcFilter struct {
+	fromHeader bool
+	set        bool
+	ssrc       uint32
+
+	newSSRC      uint32
+	newSSRCCount int
+}
+
+func newSSRCFilter(ssrc *uint32) *ssrcFilter {
+	f := &ssrcFilter{}
+	if ssrc != nil {
+		f.fromHeader = true
+		f.set = true
+		f.ssrc = *ssrc
+	}
+	return f
+}
+
+// reset forgets the learned SSRC.
+func (f *ssrcFilter) reset() {
+	if !f.fromHeader {
+		f.set = false
+	}
+	f.newSSRCCount = 0
+}
+
+// check returns the expected SSRC, the SSRC of the packet and
+// false if the packet must be discarded.
+func (f *ssrcFilter) check(pkt []byte) (uint32, uint32, bool) {
+	// do not parse the entire packet, extract only the SSRC
+	if len(pkt) < 12 {
+		return 0, 0, true
+	}
+	ssrc := uint32(pkt[8])<<24 | uint32(pkt[9])<<16 | uint32(pkt[10])<<8 | uint32(pkt[11])
+
+	if !f.set {
+		f.set = true
+		f.ssrc = ssrc
+		return 0, 0, true
+	}
+
+	if ssrc == f.ssrc {
+		f.newSSRCCount = 0
+		return f.ssrc, ssrc, true
+	}
+
+	if f.fromHeader {
+		return f.ssrc, ssrc, false
+	}
+
+	if f.newSSRCCount == 0 || ssrc != f.newSSRC {
+		f.newSSRC = ssrc
+		f.newSSRCCount = 0
+	}
+	f.newSSRCCount++
+
+	if f.newSSRCCount >= ssrcFilterRelearnCount {
+		expected := f.ssrc
+		f.ssrc = ssrc
+		f.newSSRCCount = 0
+		return expected, ssrc, true
+	}
+
+	return f.ssrc, ssrc, false
+}
@@ -0,0 +1,66 @@
+package gortsplib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func ssrcFilterPacket(ssrc uint32) []byte {
+	return []byte{
+		0x80, 0x60, 0x00, 0x01,
+		0x00, 0x00, 0x00, 0x00,
+		byte(ssrc >> 24), byte(ssrc >> 16), byte(ssrc >> 8), byte(ssrc),
+	}
+}
+
+func TestSSRCFilterLearned(t *testing.T) {
+	f := newSSRCFilter(nil)
+
+	_, _, ok := f.check(ssrcFilterPacket(0x01))
+	require.Equal(t, true, ok)
+
+	expected, ssrc, ok := f.check(ssrcFilterPacket(0x02))
+	require.Equal(t, false, ok)
+	require.Equal(t, uint32(0x01), expected)
+	require.Equal(t, uint32(0x02), ssrc)
+
+	// packets of the current source interrupt the learning
+	_, _, ok = f.check(ssrcFilterPacket(0x01))
+	require.Equal(t, true, ok)
+
+	// the source is replaced after consecutive packets with a new SSRC
+	for i := 0; i < ssrcFilterRelearnCount-1; i++ {
+		_, _, ok = f.check(ssrcFilterPacket(0x02))
+		require.Equal(t, false, ok)
+	}
+
+	_, _, ok = f.check(ssrcFilterPacket(0x02))
+	require.Equal(t, true, ok)
+
+	_, _, ok = f.check(ssrcFilterPacket(0x01))
+	require.Equal(t, false, ok)
+
+	f.reset()
+
+	_, _, ok = f.check(ssrcFilterPacket(0x03))
+	require.Equal(t, true, ok)
+}
+
+func TestSSRCFilterFromHeader(t *testing.T) {
+	v := uint32(0x01)
+	f := newSSRCFilter(&v)
+
+	for i := 0; i < ssrcFilterRelearnCount*2; i++ {
+		_, _, ok := f.check(ssrcFilterPacket(0x02))
+		require.Equal(t, false, ok)
+	}
+
+	f.reset()
+
+	_, _, ok := f.check(ssrcFilterPacket(0x02))
+	require.Equal(t, false, ok)
+
+	_, _, ok = f.check(ssrcFilterPacket(0x01))
+	require.Equal(t, true, ok)
+}
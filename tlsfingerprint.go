@@ -0,0 +1,43 @@
+package gortsplib
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"strings"
+
+	"github.com/majoyz/gortsplib/pkg/liberrors"
+)
+
+func normalizeFingerprint(fingerprint string) string {
+	return strings.ToLower(strings.ReplaceAll(fingerprint, ":", ""))
+}
+
+// tlsConfigWithFingerprint returns a copy of a TLS configuration that
+// verifies the server certificate by comparing its fingerprint, instead of
+// using the certificate authorities.
+func tlsConfigWithFingerprint(conf *tls.Config, fingerprint string) *tls.Config {
+	expected := normalizeFingerprint(fingerprint)
+	verify := conf.VerifyPeerCertificate
+
+	ret := conf.Clone()
+	ret.InsecureSkipVerify = true
+	ret.VerifyPeerCertificate = func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return liberrors.ErrClientTLSFingerprintMismatch{Expected: expected}
+		}
+
+		h := sha256.Sum256(rawCerts[0])
+		value := hex.EncodeToString(h[:])
+		if value != expected {
+			return liberrors.ErrClientTLSFingerprintMismatch{Expected: expected, Value: value}
+		}
+
+		if verify != nil {
+			return verify(rawCerts, verifiedChains)
+		}
+		return nil
+	}
+	return ret
+}
@@ -1,6 +1,7 @@
 package gortsplib
 
 import (
+	"bytes"
 	"encoding/base64"
 	"encoding/hex"
 	"fmt"
@@ -12,8 +13,11 @@ import (
 	"github.com/majoyz/gortsplib/pkg/base"
 	"github.com/majoyz/gortsplib/pkg/rtpaac"
 	"github.com/majoyz/gortsplib/pkg/sdp"
+	"github.com/majoyz/gortsplib/pkg/srtp"
 )
 
+const srtpCryptoSuite = "AES_CM_128_HMAC_SHA1_80"
+
 // Track is a track available in a certain URL.
 type Track struct {
 	// base URL
@@ -24,6 +28,41 @@ type Track struct {
 
 	// codec and info in SDP format
 	Media *psdp.MediaDescription
+
+	backChannel bool
+}
+
+// fmtpParams returns the parameters of the fmtp attribute, with lowercase keys.
+func (t *Track) fmtpParams() (map[string]string, error) {
+	v, ok := t.Media.Attribute("fmtp")
+	if !ok {
+		return nil, fmt.Errorf("unable to find fmtp")
+	}
+
+	tmp := strings.SplitN(v, " ", 2)
+	if len(tmp) != 2 {
+		return nil, fmt.Errorf("unable to parse fmtp (%v)", v)
+	}
+
+	ret := make(map[string]string)
+
+	for _, kv := range strings.Split(tmp[1], ";") {
+		kv = strings.Trim(kv, " ")
+
+		// some implementations add a trailing semicolon
+		if kv == "" {
+			continue
+		}
+
+		tmp := strings.SplitN(kv, "=", 2)
+		if len(tmp) != 2 {
+			return nil, fmt.Errorf("unable to parse fmtp (%v)", v)
+		}
+
+		ret[strings.ToLower(tmp[0])] = tmp[1]
+	}
+
+	return ret, nil
 }
 
 // NewTrackH264 initializes an H264 track from a SPS and PPS.
@@ -78,79 +117,214 @@ func (t *Track) IsH264() bool {
 
 // ExtractDataH264 extracts the SPS and PPS from an H264 track.
 func (t *Track) ExtractDataH264() ([]byte, []byte, error) {
+	fmtp, err := t.fmtpParams()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	v, ok := fmtp["sprop-parameter-sets"]
+	if !ok {
+		return nil, nil, fmt.Errorf("unable to find SPS or PPS")
+	}
+
+	// additional parameter sets are ignored
+	tmp := strings.Split(v, ",")
+	if len(tmp) < 2 {
+		return nil, nil, fmt.Errorf("unable to parse sprop-parameter-sets (%v)", v)
+	}
+
+	sps, err := base64.StdEncoding.DecodeString(tmp[0])
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to parse sprop-parameter-sets (%v)", v)
+	}
+
+	pps, err := base64.StdEncoding.DecodeString(tmp[1])
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to parse sprop-parameter-sets (%v)", v)
+	}
+
+	return sps, pps, nil
+}
+
+// TrackConfH264 is the configuration of an H264 track.
+type TrackConfH264 struct {
+	SPS []byte
+	PPS []byte
+
+	// profile_idc, constraint flags and level_idc.
+	// When not provided, they are taken from the SPS.
+	ProfileLevelID []byte
+
+	// packetization mode. It is zero when not provided.
+	PacketizationMode int
+}
+
+// ExtractConfH264 extracts the configuration from an H264 track.
+func (t *Track) ExtractConfH264() (*TrackConfH264, error) {
+	sps, pps, err := t.ExtractDataH264()
+	if err != nil {
+		return nil, err
+	}
+
+	// fmtp has already been parsed by ExtractDataH264()
+	fmtp, _ := t.fmtpParams()
+
+	conf := &TrackConfH264{
+		SPS: sps,
+		PPS: pps,
+	}
+
+	if v, ok := fmtp["profile-level-id"]; ok {
+		tmp, err := hex.DecodeString(v)
+		if err != nil || len(tmp) != 3 {
+			return nil, fmt.Errorf("unable to parse profile-level-id (%v)", v)
+		}
+		conf.ProfileLevelID = tmp
+	} else if len(sps) >= 4 {
+		conf.ProfileLevelID = sps[1:4]
+	}
+
+	if v, ok := fmtp["packetization-mode"]; ok {
+		tmp, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse packetization-mode (%v)", v)
+		}
+		conf.PacketizationMode = int(tmp)
+	}
+
+	return conf, nil
+}
+
+// NewTrackH265 initializes an H265 track from a VPS, SPS and PPS.
+func NewTrackH265(payloadType uint8, vps []byte, sps []byte, pps []byte) (*Track, error) {
+	typ := strconv.FormatInt(int64(payloadType), 10)
+
+	return &Track{
+		Media: &psdp.MediaDescription{
+			MediaName: psdp.MediaName{
+				Media:   "video",
+				Protos:  []string{"RTP", "AVP"},
+				Formats: []string{typ},
+			},
+			Attributes: []psdp.Attribute{
+				{
+					Key:   "rtpmap",
+					Value: typ + " H265/90000",
+				},
+				{
+					Key: "fmtp",
+					Value: typ + " sprop-vps=" + base64.StdEncoding.EncodeToString(vps) + "; " +
+						"sprop-sps=" + base64.StdEncoding.EncodeToString(sps) + "; " +
+						"sprop-pps=" + base64.StdEncoding.EncodeToString(pps),
+				},
+			},
+		},
+	}, nil
+}
+
+// IsH265 checks whether the track is a H265 track.
+func (t *Track) IsH265() bool {
+	if t.Media.MediaName.Media != "video" {
+		return false
+	}
+
+	v, ok := t.Media.Attribute("rtpmap")
+	if !ok {
+		return false
+	}
+
+	vals := strings.Split(v, " ")
+	if len(vals) != 2 {
+		return false
+	}
+
+	return vals[1] == "H265/90000"
+}
+
+// ExtractDataH265 extracts the VPS, SPS and PPS from an H265 track.
+func (t *Track) ExtractDataH265() ([]byte, []byte, []byte, error) {
 	v, ok := t.Media.Attribute("fmtp")
 	if !ok {
-		return nil, nil, fmt.Errorf("unable to find fmtp")
+		return nil, nil, nil, fmt.Errorf("unable to find fmtp")
 	}
 
 	tmp := strings.SplitN(v, " ", 2)
 	if len(tmp) != 2 {
-		return nil, nil, fmt.Errorf("unable to parse fmtp (%v)", v)
+		return nil, nil, nil, fmt.Errorf("unable to parse fmtp (%v)", v)
 	}
 
+	var vps []byte
 	var sps []byte
 	var pps []byte
 
 	for _, kv := range strings.Split(tmp[1], ";") {
 		kv = strings.Trim(kv, " ")
 
+		if len(kv) == 0 {
+			continue
+		}
+
 		tmp := strings.SplitN(kv, "=", 2)
 		if len(tmp) != 2 {
-			return nil, nil, fmt.Errorf("unable to parse fmtp (%v)", v)
+			return nil, nil, nil, fmt.Errorf("unable to parse fmtp (%v)", v)
 		}
 
-		if tmp[0] == "sprop-parameter-sets" {
-			tmp := strings.SplitN(tmp[1], ",", 2)
-			if len(tmp) != 2 {
-				return nil, nil, fmt.Errorf("unable to parse sprop-parameter-sets (%v)", v)
-			}
-
-			var err error
-			sps, err = base64.StdEncoding.DecodeString(tmp[0])
+		switch tmp[0] {
+		case "sprop-vps", "sprop-sps", "sprop-pps":
+			byts, err := base64.StdEncoding.DecodeString(tmp[1])
 			if err != nil {
-				return nil, nil, fmt.Errorf("unable to parse sprop-parameter-sets (%v)", v)
+				return nil, nil, nil, fmt.Errorf("unable to parse %s (%v)", tmp[0], v)
 			}
 
-			pps, err = base64.StdEncoding.DecodeString(tmp[1])
-			if err != nil {
-				return nil, nil, fmt.Errorf("unable to parse sprop-parameter-sets (%v)", v)
+			switch tmp[0] {
+			case "sprop-vps":
+				vps = byts
+			case "sprop-sps":
+				sps = byts
+			default:
+				pps = byts
 			}
 		}
 	}
 
-	if sps == nil || pps == nil {
-		return nil, nil, fmt.Errorf("unable to find SPS or PPS (%v)", v)
+	if vps == nil || sps == nil || pps == nil {
+		return nil, nil, nil, fmt.Errorf("unable to find VPS, SPS or PPS (%v)", v)
 	}
 
-	return sps, pps, nil
+	return vps, sps, pps, nil
 }
 
-// NewTrackAAC initializes an AAC track from a configuration.
-func NewTrackAAC(payloadType uint8, config []byte) (*Track, error) {
-	var conf rtpaac.MPEG4AudioConfig
-	err := conf.Decode(config)
+// NewTrackAAC initializes an AAC track, generating its MPEG-4 Audio configuration.
+func NewTrackAAC(payloadType uint8, typ rtpaac.MPEG4AudioType, sampleRate int, channelCount int) (*Track, error) {
+	conf := rtpaac.MPEG4AudioConfig{
+		Type:         typ,
+		SampleRate:   sampleRate,
+		ChannelCount: channelCount,
+	}
+
+	config, err := conf.Encode()
 	if err != nil {
 		return nil, err
 	}
 
-	typ := strconv.FormatInt(int64(payloadType), 10)
+	pt := strconv.FormatInt(int64(payloadType), 10)
 
 	return &Track{
 		Media: &psdp.MediaDescription{
 			MediaName: psdp.MediaName{
 				Media:   "audio",
 				Protos:  []string{"RTP", "AVP"},
-				Formats: []string{typ},
+				Formats: []string{pt},
 			},
 			Attributes: []psdp.Attribute{
 				{
 					Key: "rtpmap",
-					Value: typ + " MPEG4-GENERIC/" + strconv.FormatInt(int64(conf.SampleRate), 10) +
-						"/" + strconv.FormatInt(int64(conf.ChannelCount), 10),
+					Value: pt + " MPEG4-GENERIC/" + strconv.FormatInt(int64(sampleRate), 10) +
+						"/" + strconv.FormatInt(int64(channelCount), 10),
 				},
 				{
 					Key: "fmtp",
-					Value: typ + " profile-level-id=1; " +
+					Value: pt + " profile-level-id=1; " +
 						"mode=AAC-hbr; " +
 						"sizelength=13; " +
 						"indexlength=3; " +
@@ -181,45 +355,431 @@ func (t *Track) IsAAC() bool {
 	return strings.HasPrefix(vals[1], "MPEG4-GENERIC/")
 }
 
+// TrackConfAAC is the configuration of an AAC track.
+type TrackConfAAC struct {
+	Type         rtpaac.MPEG4AudioType
+	SampleRate   int
+	ChannelCount int
+
+	// parameters of the AU headers, that are zero when not provided.
+	SizeLength       int
+	IndexLength      int
+	IndexDeltaLength int
+}
+
 // ExtractDataAAC extracts the config from an AAC track.
 func (t *Track) ExtractDataAAC() ([]byte, error) {
-	v, ok := t.Media.Attribute("fmtp")
+	fmtp, err := t.fmtpParams()
+	if err != nil {
+		return nil, err
+	}
+
+	v, ok := fmtp["config"]
 	if !ok {
-		return nil, fmt.Errorf("unable to find fmtp")
+		return nil, fmt.Errorf("unable to find config")
 	}
 
-	tmp := strings.SplitN(v, " ", 2)
-	if len(tmp) != 2 {
-		return nil, fmt.Errorf("unable to parse fmtp (%v)", v)
+	config, err := hex.DecodeString(v)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse config (%v)", v)
 	}
 
-	var config []byte
+	return config, nil
+}
 
-	for _, kv := range strings.Split(tmp[1], ";") {
-		kv = strings.Trim(kv, " ")
+// ExtractConfAAC extracts the configuration from an AAC track.
+func (t *Track) ExtractConfAAC() (*TrackConfAAC, error) {
+	config, err := t.ExtractDataAAC()
+	if err != nil {
+		return nil, err
+	}
 
-		tmp := strings.SplitN(kv, "=", 2)
-		if len(tmp) != 2 {
-			return nil, fmt.Errorf("unable to parse fmtp (%v)", v)
+	var mpegConf rtpaac.MPEG4AudioConfig
+	err = mpegConf.Decode(config)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse config: %s", err)
+	}
+
+	// fmtp has already been parsed by ExtractDataAAC()
+	fmtp, _ := t.fmtpParams()
+
+	conf := &TrackConfAAC{
+		Type:         mpegConf.Type,
+		SampleRate:   mpegConf.SampleRate,
+		ChannelCount: mpegConf.ChannelCount,
+	}
+
+	for key, dest := range map[string]*int{
+		"sizelength":       &conf.SizeLength,
+		"indexlength":      &conf.IndexLength,
+		"indexdeltalength": &conf.IndexDeltaLength,
+	} {
+		v, ok := fmtp[key]
+		if !ok {
+			continue
 		}
 
-		if tmp[0] == "config" {
-			var err error
-			config, err = hex.DecodeString(tmp[1])
-			if err != nil {
-				return nil, fmt.Errorf("unable to parse config (%v)", v)
-			}
-			break
+		tmp, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse %s (%v)", key, v)
 		}
+		*dest = int(tmp)
+	}
+
+	return conf, nil
+}
+
+// NewTrackMPEG4Video initializes a MPEG-4 Visual track (RFC 6416).
+// config contains the configuration headers (VOS, VO, VOL) of the stream.
+func NewTrackMPEG4Video(payloadType uint8, config []byte) (*Track, error) {
+	if len(config) == 0 {
+		return nil, fmt.Errorf("config is empty")
 	}
 
-	if config == nil {
-		return nil, fmt.Errorf("unable to find config (%v)", v)
+	// profile-level-id can be read from the VOS header, otherwise
+	// it defaults to 1 (Simple Profile/Level 1)
+	profileLevelID := 1
+	if len(config) >= 5 && bytes.HasPrefix(config, []byte{0x00, 0x00, 0x01, 0xB0}) {
+		profileLevelID = int(config[4])
+	}
+
+	pt := strconv.FormatInt(int64(payloadType), 10)
+
+	return &Track{
+		Media: &psdp.MediaDescription{
+			MediaName: psdp.MediaName{
+				Media:   "video",
+				Protos:  []string{"RTP", "AVP"},
+				Formats: []string{pt},
+			},
+			Attributes: []psdp.Attribute{
+				{
+					Key:   "rtpmap",
+					Value: pt + " MP4V-ES/90000",
+				},
+				{
+					Key: "fmtp",
+					Value: pt + " profile-level-id=" + strconv.FormatInt(int64(profileLevelID), 10) +
+						"; config=" + strings.ToUpper(hex.EncodeToString(config)),
+				},
+			},
+		},
+	}, nil
+}
+
+// IsMPEG4Video checks whether the track is a MPEG-4 Visual track.
+func (t *Track) IsMPEG4Video() bool {
+	return t.Media.MediaName.Media == "video" &&
+		strings.ToUpper(t.Codec()) == "MP4V-ES"
+}
+
+// ExtractDataMPEG4Video extracts the configuration headers (VOS, VO, VOL)
+// from a MPEG-4 Visual track.
+func (t *Track) ExtractDataMPEG4Video() ([]byte, error) {
+	fmtp, err := t.fmtpParams()
+	if err != nil {
+		return nil, err
+	}
+
+	v, ok := fmtp["config"]
+	if !ok {
+		return nil, fmt.Errorf("unable to find config")
+	}
+
+	config, err := hex.DecodeString(v)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse config (%v)", v)
 	}
 
 	return config, nil
 }
 
+// NewTrackOpus initializes an Opus track.
+func NewTrackOpus(payloadType uint8, channelCount int) (*Track, error) {
+	if channelCount != 1 && channelCount != 2 {
+		return nil, fmt.Errorf("invalid channel count (%d)", channelCount)
+	}
+
+	typ := strconv.FormatInt(int64(payloadType), 10)
+
+	return &Track{
+		Media: &psdp.MediaDescription{
+			MediaName: psdp.MediaName{
+				Media:   "audio",
+				Protos:  []string{"RTP", "AVP"},
+				Formats: []string{typ},
+			},
+			Attributes: []psdp.Attribute{
+				{
+					// RFC 7587: the rtpmap must always contain 2 channels
+					Key:   "rtpmap",
+					Value: typ + " opus/48000/2",
+				},
+				{
+					Key: "fmtp",
+					Value: typ + " sprop-stereo=" + func() string {
+						if channelCount == 2 {
+							return "1"
+						}
+						return "0"
+					}(),
+				},
+			},
+		},
+	}, nil
+}
+
+// IsOpus checks whether the track is an Opus track.
+func (t *Track) IsOpus() bool {
+	if t.Media.MediaName.Media != "audio" {
+		return false
+	}
+
+	v, ok := t.Media.Attribute("rtpmap")
+	if !ok {
+		return false
+	}
+
+	vals := strings.Split(v, " ")
+	if len(vals) != 2 {
+		return false
+	}
+
+	return strings.HasPrefix(strings.ToLower(vals[1]), "opus/")
+}
+
+// NewTrackPCMA initializes a G711 A-law (PCMA) track.
+func NewTrackPCMA() *Track {
+	return &Track{
+		Media: &psdp.MediaDescription{
+			MediaName: psdp.MediaName{
+				Media:   "audio",
+				Protos:  []string{"RTP", "AVP"},
+				Formats: []string{"8"},
+			},
+			Attributes: []psdp.Attribute{
+				{
+					Key:   "rtpmap",
+					Value: "8 PCMA/8000",
+				},
+			},
+		},
+	}
+}
+
+// IsPCMA checks whether the track is a G711 A-law (PCMA) track.
+func (t *Track) IsPCMA() bool {
+	return t.Media.MediaName.Media == "audio" &&
+		len(t.Media.MediaName.Formats) == 1 &&
+		t.Media.MediaName.Formats[0] == "8"
+}
+
+// NewTrackPCMU initializes a G711 mu-law (PCMU) track.
+func NewTrackPCMU() *Track {
+	return &Track{
+		Media: &psdp.MediaDescription{
+			MediaName: psdp.MediaName{
+				Media:   "audio",
+				Protos:  []string{"RTP", "AVP"},
+				Formats: []string{"0"},
+			},
+			Attributes: []psdp.Attribute{
+				{
+					Key:   "rtpmap",
+					Value: "0 PCMU/8000",
+				},
+			},
+		},
+	}
+}
+
+// IsPCMU checks whether the track is a G711 mu-law (PCMU) track.
+func (t *Track) IsPCMU() bool {
+	return t.Media.MediaName.Media == "audio" &&
+		len(t.Media.MediaName.Formats) == 1 &&
+		t.Media.MediaName.Formats[0] == "0"
+}
+
+// NewTrackMetadata initializes an ONVIF metadata track, that carries
+// XML documents with analytics events and other data.
+func NewTrackMetadata(payloadType uint8) *Track {
+	typ := strconv.FormatInt(int64(payloadType), 10)
+
+	return &Track{
+		Media: &psdp.MediaDescription{
+			MediaName: psdp.MediaName{
+				Media:   "application",
+				Protos:  []string{"RTP", "AVP"},
+				Formats: []string{typ},
+			},
+			Attributes: []psdp.Attribute{
+				{
+					Key:   "rtpmap",
+					Value: typ + " vnd.onvif.metadata/90000",
+				},
+			},
+		},
+	}
+}
+
+// IsMetadata checks whether the track is an ONVIF metadata track.
+func (t *Track) IsMetadata() bool {
+	return t.Media.MediaName.Media == "application" &&
+		strings.ToLower(t.Codec()) == "vnd.onvif.metadata"
+}
+
+// NewTrackMPEGTS initializes a MPEG-TS track, that carries multiple
+// elementary streams muxed together (RFC 2250).
+func NewTrackMPEGTS() *Track {
+	return &Track{
+		Media: &psdp.MediaDescription{
+			MediaName: psdp.MediaName{
+				Media:   "video",
+				Protos:  []string{"RTP", "AVP"},
+				Formats: []string{"33"},
+			},
+			Attributes: []psdp.Attribute{
+				{
+					Key:   "rtpmap",
+					Value: "33 MP2T/90000",
+				},
+			},
+		},
+	}
+}
+
+// IsMPEGTS checks whether the track is a MPEG-TS track.
+func (t *Track) IsMPEGTS() bool {
+	return strings.ToUpper(t.Codec()) == "MP2T"
+}
+
+// IsVideo checks whether the track is a video track.
+func (t *Track) IsVideo() bool {
+	return t.Media.MediaName.Media == "video"
+}
+
+// IsAudio checks whether the track is an audio track.
+func (t *Track) IsAudio() bool {
+	return t.Media.MediaName.Media == "audio"
+}
+
+// IsBackChannel checks whether the track is an ONVIF back channel,
+// i.e. a track that is sent by the client to the server.
+// Only tracks returned by a client that requested back channels
+// (ClientConf.BackChannelEnable) can be back channels; in any other case,
+// a track with the sendonly attribute is a regular track.
+func (t *Track) IsBackChannel() bool {
+	return t.backChannel
+}
+
+// Attribute returns the value of the first media attribute with the given key.
+// The boolean is false if the attribute is not present.
+func (t *Track) Attribute(key string) (string, bool) {
+	for _, attr := range t.Media.Attributes {
+		if attr.Key == key {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+// SetAttribute sets a media attribute, replacing all the existing attributes
+// with the same key.
+func (t *Track) SetAttribute(key string, value string) {
+	t.RemoveAttribute(key)
+	t.Media.Attributes = append(t.Media.Attributes, psdp.Attribute{
+		Key:   key,
+		Value: value,
+	})
+}
+
+// RemoveAttribute removes all the media attributes with the given key.
+func (t *Track) RemoveAttribute(key string) {
+	var attrs []psdp.Attribute
+	for _, attr := range t.Media.Attributes {
+		if attr.Key != key {
+			attrs = append(attrs, attr)
+		}
+	}
+	t.Media.Attributes = attrs
+}
+
+// Control returns the control attribute of the track, that can be
+// a relative path, an absolute URL or empty.
+func (t *Track) Control() string {
+	v, _ := t.Attribute("control")
+	return v
+}
+
+// SetControl sets the control attribute of the track.
+func (t *Track) SetControl(control string) {
+	t.SetAttribute("control", control)
+}
+
+// PayloadType returns the payload type of the track.
+func (t *Track) PayloadType() (uint8, error) {
+	if len(t.Media.MediaName.Formats) != 1 {
+		return 0, fmt.Errorf("invalid format (%v)", t.Media.MediaName.Formats)
+	}
+
+	v, err := strconv.ParseUint(t.Media.MediaName.Formats[0], 10, 8)
+	if err != nil || v > 127 {
+		return 0, fmt.Errorf("invalid payload type (%v)", t.Media.MediaName.Formats[0])
+	}
+
+	return uint8(v), nil
+}
+
+// static payload types
+// https://tools.ietf.org/html/rfc3551#section-6
+var staticPayloadTypeCodecs = map[string]string{
+	"0":  "PCMU",
+	"3":  "GSM",
+	"4":  "G723",
+	"5":  "DVI4",
+	"6":  "DVI4",
+	"7":  "LPC",
+	"8":  "PCMA",
+	"9":  "G722",
+	"10": "L16",
+	"11": "L16",
+	"12": "QCELP",
+	"13": "CN",
+	"14": "MPA",
+	"15": "G728",
+	"16": "DVI4",
+	"17": "DVI4",
+	"18": "G729",
+	"25": "CelB",
+	"26": "JPEG",
+	"28": "nv",
+	"31": "H261",
+	"32": "MPV",
+	"33": "MP2T",
+	"34": "H263",
+}
+
+// Codec returns the encoding name of the track, as written in the rtpmap
+// attribute (i.e. "H264", "MPEG4-GENERIC", "opus"), or, in case of static
+// payload types, as defined by RFC 3551.
+// It returns an empty string if the encoding name can't be found.
+func (t *Track) Codec() string {
+	if len(t.Media.MediaName.Formats) != 1 {
+		return ""
+	}
+
+	// rtpmap has precedence over static payload types
+	v, ok := t.Media.Attribute("rtpmap")
+	if ok {
+		tmp := strings.Split(strings.TrimSpace(v), " ")
+		if len(tmp) >= 2 {
+			return strings.Split(tmp[1], "/")[0]
+		}
+	}
+
+	return staticPayloadTypeCodecs[t.Media.MediaName.Formats[0]]
+}
+
 // ClockRate returns the clock rate of the track.
 func (t *Track) ClockRate() (int, error) {
 	if len(t.Media.MediaName.Formats) != 1 {
@@ -272,28 +832,68 @@ func (t *Track) ClockRate() (int, error) {
 	return 0, fmt.Errorf("attribute 'rtpmap' not found")
 }
 
+// SetSRTPKey enables SRTP on the track and sets its master key (key and salt),
+// that is advertised in SDP with SDES (RFC 4568).
+func (t *Track) SetSRTPKey(key []byte) error {
+	if len(key) != srtp.MasterKeyLength {
+		return fmt.Errorf("invalid key length: expected %d, got %d", srtp.MasterKeyLength, len(key))
+	}
+
+	t.SetAttribute("crypto", "1 "+srtpCryptoSuite+" inline:"+base64.StdEncoding.EncodeToString(key))
+	t.Media.MediaName.Protos = []string{"RTP", "SAVP"}
+	return nil
+}
+
+// ExtractSRTPKey extracts the SRTP master key (key and salt) from a track.
+func (t *Track) ExtractSRTPKey() ([]byte, error) {
+	// a=crypto:<tag> <crypto-suite> inline:<key||salt>[|<lifetime>][|<MKI>:<length>]
+	for _, attr := range t.Media.Attributes {
+		if attr.Key != "crypto" {
+			continue
+		}
+
+		tmp := strings.Split(attr.Value, " ")
+		if len(tmp) < 3 {
+			return nil, fmt.Errorf("invalid crypto attribute (%v)", attr.Value)
+		}
+
+		if tmp[1] != srtpCryptoSuite {
+			continue
+		}
+
+		if !strings.HasPrefix(tmp[2], "inline:") {
+			return nil, fmt.Errorf("invalid crypto attribute (%v)", attr.Value)
+		}
+
+		v := strings.SplitN(tmp[2][len("inline:"):], "|", 2)[0]
+
+		key, err := base64.StdEncoding.DecodeString(v)
+		if err != nil || len(key) != srtp.MasterKeyLength {
+			return nil, fmt.Errorf("invalid crypto attribute (%v)", attr.Value)
+		}
+
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unable to find a supported crypto attribute")
+}
+
 // URL returns the track url.
 func (t *Track) URL() (*base.URL, error) {
 	if t.BaseURL == nil {
 		return nil, fmt.Errorf("empty base url")
 	}
 
-	controlAttr := func() string {
-		for _, attr := range t.Media.Attributes {
-			if attr.Key == "control" {
-				return attr.Value
-			}
-		}
-		return ""
-	}()
+	controlAttr := t.Control()
 
-	// no control attribute, use base URL
-	if controlAttr == "" {
+	// no control attribute or aggregate control, use base URL
+	if controlAttr == "" || controlAttr == "*" {
 		return t.BaseURL, nil
 	}
 
 	// control attribute contains an absolute path
-	if strings.HasPrefix(controlAttr, "rtsp://") {
+	if strings.HasPrefix(controlAttr, "rtsp://") ||
+		strings.HasPrefix(controlAttr, "rtsps://") {
 		ur, err := base.ParseURL(controlAttr)
 		if err != nil {
 			return nil, err
@@ -305,15 +905,66 @@ func (t *Track) URL() (*base.URL, error) {
 		return ur, nil
 	}
 
+	// control attribute contains an absolute path, that replaces the path
+	// and the query of the base URL
+	if strings.HasPrefix(controlAttr, "/") {
+		path, query := base.PathSplitQuery(controlAttr)
+
+		ur := t.BaseURL.Clone()
+		ur.Path = path
+		ur.RawPath = ""
+		ur.SetQuery(query)
+		return ur, nil
+	}
+
 	// control attribute contains a relative control attribute
 	ur := t.BaseURL.Clone()
 	ur.AddControlAttribute(controlAttr)
 	return ur, nil
 }
 
+// Clone returns a copy of the track, whose media description
+// can be edited without affecting the original track.
+func (t *Track) Clone() *Track {
+	media := *t.Media
+	media.MediaName.Formats = append([]string(nil), t.Media.MediaName.Formats...)
+	media.MediaName.Protos = append([]string(nil), t.Media.MediaName.Protos...)
+	media.Bandwidth = append([]psdp.Bandwidth(nil), t.Media.Bandwidth...)
+	media.Attributes = append([]psdp.Attribute(nil), t.Media.Attributes...)
+
+	return &Track{
+		BaseURL:     t.BaseURL,
+		ID:          t.ID,
+		Media:       &media,
+		backChannel: t.backChannel,
+	}
+}
+
 // Tracks is a list of tracks.
 type Tracks []*Track
 
+// Clone returns a copy of the tracks.
+func (ts Tracks) Clone() Tracks {
+	ret := make(Tracks, len(ts))
+	for i, track := range ts {
+		ret[i] = track.Clone()
+	}
+	return ret
+}
+
+// Filter returns the tracks for which fn returns true.
+// Tracks are not copied and their IDs are preserved, therefore
+// they can still be used to route frames and to map SETUP requests.
+func (ts Tracks) Filter(fn func(track *Track) bool) Tracks {
+	var ret Tracks
+	for _, track := range ts {
+		if fn(track) {
+			ret = append(ret, track)
+		}
+	}
+	return ret
+}
+
 // ReadTracks decodes tracks from SDP.
 func ReadTracks(byts []byte, baseURL *base.URL) (Tracks, error) {
 	desc := sdp.SessionDescription{}
@@ -322,38 +973,113 @@ func ReadTracks(byts []byte, baseURL *base.URL) (Tracks, error) {
 		return nil, err
 	}
 
-	tracks := make(Tracks, len(desc.MediaDescriptions))
+	return readTracks(&desc, baseURL)
+}
+
+// ReadTracksLenient decodes tracks from a SDP produced by a non-compliant
+// implementation. Lines that can't be decoded are skipped,
+// and a warning is returned for each of them.
+func ReadTracksLenient(byts []byte, baseURL *base.URL) (Tracks, []string, error) {
+	desc := sdp.SessionDescription{}
+	warnings := desc.UnmarshalLenient(byts)
+
+	tracks, err := readTracks(&desc, baseURL)
+	if err != nil {
+		return nil, warnings, err
+	}
+
+	return tracks, warnings, nil
+}
+
+func readTracks(desc *sdp.SessionDescription, baseURL *base.URL) (Tracks, error) {
+	tracks, skipped, errs := readTracksPartial(desc, baseURL)
+
+	// since ReadTracks is used to handle ANNOUNCE and SETUP requests,
+	// all tracks must have a valid clock rate.
+	if len(skipped) != 0 {
+		return nil, fmt.Errorf("unable to get clock rate of track %d: %s", skipped[0].ID, errs[0])
+	}
+
+	return tracks, nil
+}
+
+// readTracksPartial decodes the tracks of a session description, and
+// separates the ones whose clock rate can't be read, together with the
+// reason. Tracks keep their position as ID in both cases.
+func readTracksPartial(desc *sdp.SessionDescription, baseURL *base.URL) (Tracks, Tracks, []error) {
+	var tracks Tracks
+	var skipped Tracks
+	var errs []error
 
 	for i, media := range desc.MediaDescriptions {
-		tracks[i] = &Track{
+		track := &Track{
 			BaseURL: baseURL,
 			ID:      i,
 			Media:   media,
 		}
-	}
 
-	// since ReadTracks is used to handle ANNOUNCE and SETUP requests,
-	// all tracks must have a valid clock rate.
-	for i, track := range tracks {
 		_, err := track.ClockRate()
 		if err != nil {
-			return nil, fmt.Errorf("unable to get clock rate of track %d: %s", i, err)
+			skipped = append(skipped, track)
+			errs = append(errs, err)
+			continue
 		}
+
+		tracks = append(tracks, track)
 	}
 
-	return tracks, nil
+	return tracks, skipped, errs
 }
 
 // Write encodes tracks into SDP.
+// Control attributes are replaced with "trackID=" followed by the position
+// of the track, and only attributes that describe the codec are kept.
 func (ts Tracks) Write() []byte {
-	sout := &sdp.SessionDescription{
-		SessionName: psdp.SessionName("Stream"),
-		Origin: psdp.Origin{
+	return TracksWriteConf{}.Write(ts)
+}
+
+// TracksWriteConf allows to configure the SDP produced from tracks,
+// for instance in order to serve tracks announced by a publisher to readers.
+type TracksWriteConf struct {
+	// session name.
+	// It defaults to "Stream".
+	SessionName string
+
+	// origin of the session.
+	// It defaults to "- 0 0 IN IP4 127.0.0.1".
+	Origin *psdp.Origin
+
+	// function that returns the control attribute of a track,
+	// given its position and the track.
+	// It defaults to a function that returns "trackID=" followed by the position.
+	// To serve tracks returned by Tracks.Filter with their original IDs,
+	// it can return "trackID=" followed by track.ID.
+	Control func(i int, track *Track) string
+}
+
+// Write encodes tracks into SDP.
+// Only attributes of the tracks that describe the codec are kept.
+func (c TracksWriteConf) Write(ts Tracks) []byte {
+	if c.SessionName == "" {
+		c.SessionName = "Stream"
+	}
+	if c.Origin == nil {
+		c.Origin = &psdp.Origin{
 			Username:       "-",
 			NetworkType:    "IN",
 			AddressType:    "IP4",
 			UnicastAddress: "127.0.0.1",
-		},
+		}
+	}
+	if c.Control == nil {
+		c.Control = func(i int, track *Track) string {
+			return "trackID=" + strconv.FormatInt(int64(i), 10)
+		}
+	}
+
+	sout := &sdp.SessionDescription{
+		SessionName: psdp.SessionName(c.SessionName),
+		Origin:      *c.Origin,
 		// required by Darwin Streaming Server
 		ConnectionInformation: &psdp.ConnectionInformation{
 			NetworkType: "IN",
@@ -366,10 +1092,15 @@ func (ts Tracks) Write() []byte {
 	}
 
 	for i, track := range ts {
+		protos := []string{"RTP", "AVP"} // override protocol
+		if _, err := track.ExtractSRTPKey(); err == nil {
+			protos = []string{"RTP", "SAVP"}
+		}
+
 		mout := &psdp.MediaDescription{
 			MediaName: psdp.MediaName{
 				Media:   track.Media.MediaName.Media,
-				Protos:  []string{"RTP", "AVP"}, // override protocol
+				Protos:  protos,
 				Formats: track.Media.MediaName.Formats,
 			},
 			Bandwidth: track.Media.Bandwidth,
@@ -377,7 +1108,8 @@ func (ts Tracks) Write() []byte {
 				var ret []psdp.Attribute
 
 				for _, attr := range track.Media.Attributes {
-					if attr.Key == "rtpmap" || attr.Key == "fmtp" {
+					if attr.Key == "rtpmap" || attr.Key == "fmtp" || attr.Key == "crypto" ||
+						attr.Key == "sendonly" {
 						ret = append(ret, attr)
 					}
 				}
@@ -386,7 +1118,7 @@ func (ts Tracks) Write() []byte {
 				// to the stream path in SETUP
 				ret = append(ret, psdp.Attribute{
 					Key:   "control",
-					Value: "trackID=" + strconv.FormatInt(int64(i), 10),
+					Value: c.Control(i, track),
 				})
 
 				return ret
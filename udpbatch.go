@@ -0,0 +1,24 @@
+package gortsplib
+
+import (
+	"net"
+)
+
+const (
+	// maximum number of UDP packets that are read or written with a single system call.
+	udpBatchSize = 8
+)
+
+// udpMessage is a UDP packet read or written by a udpBatchConn.
+type udpMessage struct {
+	// when reading, the buffer where the packet is written to.
+	// when writing, the packet.
+	buf []byte
+
+	// when reading, the size of the packet.
+	n int
+
+	// when reading, the source address.
+	// when writing, the destination address.
+	addr net.UDPAddr
+}
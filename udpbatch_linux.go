@@ -0,0 +1,113 @@
+package gortsplib
+
+import (
+	"errors"
+	"net"
+	"os"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// batchPacketConn is implemented by ipv4.PacketConn and ipv6.PacketConn.
+type batchPacketConn interface {
+	ReadBatch([]ipv4.Message, int) (int, error)
+	WriteBatch([]ipv4.Message, int) (int, error)
+}
+
+// udpBatchConn reads and writes multiple UDP packets with a single system call,
+// by using recvmmsg() and sendmmsg() through golang.org/x/net.
+type udpBatchConn struct {
+	pc batchPacketConn
+
+	readMsgs  []ipv4.Message
+	writeMsgs []ipv4.Message
+}
+
+func newUDPBatchConn(pc *net.UDPConn) (*udpBatchConn, error) {
+	var bpc batchPacketConn
+	if pc.LocalAddr().(*net.UDPAddr).IP.To4() != nil {
+		bpc = ipv4.NewPacketConn(pc)
+	} else {
+		bpc = ipv6.NewPacketConn(pc)
+	}
+
+	c := &udpBatchConn{
+		pc:        bpc,
+		readMsgs:  make([]ipv4.Message, udpBatchSize),
+		writeMsgs: make([]ipv4.Message, udpBatchSize),
+	}
+
+	for i := 0; i < udpBatchSize; i++ {
+		c.readMsgs[i].Buffers = make([][]byte, 1)
+		c.writeMsgs[i].Buffers = make([][]byte, 1)
+	}
+
+	return c, nil
+}
+
+// readBatch reads up to len(msgs) packets, waiting until at least one is available.
+// It returns the number of packets read.
+func (c *udpBatchConn) readBatch(msgs []udpMessage) (int, error) {
+	if len(msgs) > udpBatchSize {
+		msgs = msgs[:udpBatchSize]
+	}
+
+	for i := range msgs {
+		c.readMsgs[i].Buffers[0] = msgs[i].buf
+	}
+
+	n, err := c.pc.ReadBatch(c.readMsgs[:len(msgs)], 0)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := 0; i < n; i++ {
+		msgs[i].n = c.readMsgs[i].N
+
+		if addr, ok := c.readMsgs[i].Addr.(*net.UDPAddr); ok {
+			msgs[i].addr = *addr
+		} else {
+			msgs[i].addr = net.UDPAddr{}
+		}
+	}
+
+	return n, nil
+}
+
+// writeBatch writes all the packets.
+// Packets that can't be written are skipped, and the last error is returned.
+// IPv6 zones of destination addresses are converted into interface indexes
+// by golang.org/x/net, that caches them.
+func (c *udpBatchConn) writeBatch(msgs []udpMessage) error {
+	var errRet error
+
+	for len(msgs) > 0 {
+		cur := msgs
+		if len(cur) > udpBatchSize {
+			cur = cur[:udpBatchSize]
+		}
+
+		for i := range cur {
+			c.writeMsgs[i].Buffers[0] = cur[i].buf
+			c.writeMsgs[i].Addr = &cur[i].addr
+		}
+
+		n, err := c.pc.WriteBatch(c.writeMsgs[:len(cur)], 0)
+		if err != nil {
+			// the connection can't be used anymore
+			var serr *os.SyscallError
+			if !errors.As(err, &serr) {
+				return err
+			}
+
+			// the first packet that was not written can't be written, skip it
+			errRet = err
+			n++
+		}
+
+		msgs = msgs[n:]
+	}
+
+	return errRet
+}
@@ -0,0 +1,48 @@
+//go:build !linux
+// +build !linux
+
+package gortsplib
+
+import (
+	"net"
+)
+
+// udpBatchConn reads and writes UDP packets.
+// On this platform, packets are read and written one at a time.
+type udpBatchConn struct {
+	pc *net.UDPConn
+}
+
+func newUDPBatchConn(pc *net.UDPConn) (*udpBatchConn, error) {
+	return &udpBatchConn{
+		pc: pc,
+	}, nil
+}
+
+// readBatch reads a single packet.
+// It returns the number of packets read.
+func (c *udpBatchConn) readBatch(msgs []udpMessage) (int, error) {
+	n, addr, err := c.pc.ReadFromUDP(msgs[0].buf)
+	if err != nil {
+		return 0, err
+	}
+
+	msgs[0].n = n
+	msgs[0].addr = *addr
+	return 1, nil
+}
+
+// writeBatch writes all the packets.
+// Packets that can't be written are skipped, and the last error is returned.
+func (c *udpBatchConn) writeBatch(msgs []udpMessage) error {
+	var errRet error
+
+	for i := range msgs {
+		_, err := c.pc.WriteTo(msgs[i].buf, &msgs[i].addr)
+		if err != nil {
+			errRet = err
+		}
+	}
+
+	return errRet
+}
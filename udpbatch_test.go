@@ -0,0 +1,78 @@
+package gortsplib
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUDPBatchConn(t *testing.T) {
+	pc1, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	require.NoError(t, err)
+	defer pc1.Close()
+
+	pc2, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	require.NoError(t, err)
+	defer pc2.Close()
+
+	c1, err := newUDPBatchConn(pc1)
+	require.NoError(t, err)
+
+	c2, err := newUDPBatchConn(pc2)
+	require.NoError(t, err)
+
+	dest := *pc2.LocalAddr().(*net.UDPAddr)
+
+	out := make([]udpMessage, 3)
+	for i := range out {
+		out[i].buf = []byte{0x01, 0x02, byte(i)}
+		out[i].addr = dest
+	}
+
+	err = c1.writeBatch(out)
+	require.NoError(t, err)
+
+	in := make([]udpMessage, udpBatchSize)
+	for i := range in {
+		in[i].buf = make([]byte, 2048)
+	}
+
+	received := 0
+	for received < len(out) {
+		n, err := c2.readBatch(in)
+		require.NoError(t, err)
+
+		for i := 0; i < n; i++ {
+			require.Equal(t, []byte{0x01, 0x02, byte(received)}, in[i].buf[:in[i].n])
+			require.Equal(t, pc1.LocalAddr().(*net.UDPAddr).Port, in[i].addr.Port)
+			require.True(t, in[i].addr.IP.Equal(net.ParseIP("127.0.0.1")))
+			received++
+		}
+	}
+}
+
+func TestUDPBatchConnDualStack(t *testing.T) {
+	pc1, err := net.ListenUDP("udp", &net.UDPAddr{})
+	require.NoError(t, err)
+	defer pc1.Close()
+
+	pc2, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	require.NoError(t, err)
+	defer pc2.Close()
+
+	c1, err := newUDPBatchConn(pc1)
+	require.NoError(t, err)
+
+	err = c1.writeBatch([]udpMessage{{
+		buf:  []byte{0x01, 0x02, 0x03},
+		addr: *pc2.LocalAddr().(*net.UDPAddr),
+	}})
+	require.NoError(t, err)
+
+	buf := make([]byte, 2048)
+	n, addr, err := pc2.ReadFromUDP(buf)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x01, 0x02, 0x03}, buf[:n])
+	require.Equal(t, pc1.LocalAddr().(*net.UDPAddr).Port, addr.Port)
+}
@@ -0,0 +1,30 @@
+package gortsplib
+
+import (
+	"net"
+)
+
+const (
+	// use the same read buffer size as gstreamer's rtspsrc
+	udpKernelReadBufferSize = 0x80000
+)
+
+// setUDPBufferSizes sets the kernel buffer sizes of a UDP socket.
+// Sizes equal to zero leave the operating system default in place.
+func setUDPBufferSizes(pc *net.UDPConn, readSize int, writeSize int) error {
+	if readSize > 0 {
+		err := pc.SetReadBuffer(readSize)
+		if err != nil {
+			return err
+		}
+	}
+
+	if writeSize > 0 {
+		err := pc.SetWriteBuffer(writeSize)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,15 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris && !windows
+// +build !aix,!darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris,!windows
+
+package gortsplib
+
+import (
+	"fmt"
+	"net"
+)
+
+// udpBufferSizes returns the kernel buffer sizes of a UDP socket.
+// It is not supported on this platform.
+func udpBufferSizes(pc *net.UDPConn) (int, int, error) {
+	return 0, 0, fmt.Errorf("unsupported on this platform")
+}
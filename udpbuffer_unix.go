@@ -0,0 +1,38 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package gortsplib
+
+import (
+	"net"
+	"syscall"
+)
+
+// udpBufferSizes returns the kernel buffer sizes of a UDP socket,
+// as reported by the operating system.
+func udpBufferSizes(pc *net.UDPConn) (int, int, error) {
+	rc, err := pc.SyscallConn()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var readSize, writeSize int
+	var errSockopt error
+
+	err = rc.Control(func(fd uintptr) {
+		readSize, errSockopt = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_RCVBUF)
+		if errSockopt != nil {
+			return
+		}
+
+		writeSize, errSockopt = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_SNDBUF)
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	if errSockopt != nil {
+		return 0, 0, errSockopt
+	}
+
+	return readSize, writeSize, nil
+}
@@ -0,0 +1,43 @@
+package gortsplib
+
+import (
+	"net"
+	"syscall"
+	"unsafe"
+)
+
+func getsockoptInt(fd syscall.Handle, opt int32) (int, error) {
+	var v int32
+	l := int32(unsafe.Sizeof(v))
+	err := syscall.Getsockopt(fd, syscall.SOL_SOCKET, opt, (*byte)(unsafe.Pointer(&v)), &l)
+	return int(v), err
+}
+
+// udpBufferSizes returns the kernel buffer sizes of a UDP socket,
+// as reported by the operating system.
+func udpBufferSizes(pc *net.UDPConn) (int, int, error) {
+	rc, err := pc.SyscallConn()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var readSize, writeSize int
+	var errSockopt error
+
+	err = rc.Control(func(fd uintptr) {
+		readSize, errSockopt = getsockoptInt(syscall.Handle(fd), syscall.SO_RCVBUF)
+		if errSockopt != nil {
+			return
+		}
+
+		writeSize, errSockopt = getsockoptInt(syscall.Handle(fd), syscall.SO_SNDBUF)
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	if errSockopt != nil {
+		return 0, 0, errSockopt
+	}
+
+	return readSize, writeSize, nil
+}
@@ -0,0 +1,81 @@
+package gortsplib
+
+import (
+	"sync"
+	"time"
+
+	"github.com/majoyz/gortsplib/pkg/rtpreorderer"
+)
+
+const (
+	// size of the reorder buffer when only its duration is set.
+	udpReorderBufferDefaultSize = 64
+
+	udpReorderFlushMinPeriod = 1 * time.Millisecond
+)
+
+// udpReorderer reorders RTP packets received with UDP.
+// Packets are released by the UDP listener when new packets are received,
+// and by a periodic flush when they have been waiting for too long;
+// a mutex serializes the two, therefore packets of a track are passed
+// to the callback by one routine at a time.
+type udpReorderer struct {
+	size   int
+	maxAge time.Duration
+
+	mutex sync.Mutex
+	r     *rtpreorderer.Reorderer
+}
+
+// newUDPReorderer allocates a udpReorderer, or returns nil
+// if reordering is disabled.
+func newUDPReorderer(size int, maxAge time.Duration) *udpReorderer {
+	if size <= 0 {
+		if maxAge <= 0 {
+			return nil
+		}
+		size = udpReorderBufferDefaultSize
+	}
+
+	return &udpReorderer{
+		size:   size,
+		maxAge: maxAge,
+		r:      rtpreorderer.NewWithMaxAge(size, maxAge),
+	}
+}
+
+// udpReorderFlushPeriod returns the period of flushes, that allows to release packets
+// shortly after they reach the maximum age.
+func udpReorderFlushPeriod(maxAge time.Duration) time.Duration {
+	period := maxAge / 4
+	if period < udpReorderFlushMinPeriod {
+		period = udpReorderFlushMinPeriod
+	}
+	return period
+}
+
+// reset discards buffered packets.
+func (u *udpReorderer) reset() {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	u.r = rtpreorderer.NewWithMaxAge(u.size, u.maxAge)
+}
+
+func (u *udpReorderer) process(pkt []byte, cb func([]byte)) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	for _, pkt := range u.r.Process(pkt) {
+		cb(pkt)
+	}
+}
+
+func (u *udpReorderer) flush(cb func([]byte)) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	for _, pkt := range u.r.Flush() {
+		cb(pkt)
+	}
+}